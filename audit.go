@@ -0,0 +1,111 @@
+package regclient
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/scheme"
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/ref"
+)
+
+// auditMsg is the message used for every audit record.
+const auditMsg = "audit"
+
+// WithAuditLogger emits a structured record to the logger for every mutating operation.
+// This includes pushing blobs and manifests, tagging, and deleting content.
+// Each record contains the action, user, reference, and digest, and whether the action succeeded.
+func WithAuditLogger(log *slog.Logger) Opt {
+	return func(rc *RegClient) {
+		rc.auditLog = log
+	}
+}
+
+// schemeAudit wraps a [scheme.API] to log any changes to the audit logger.
+type schemeAudit struct {
+	schemeWrap
+	name  string
+	log   *slog.Logger
+	hosts map[string]*config.Host
+}
+
+func (sa *schemeAudit) record(ctx context.Context, action string, r ref.Ref, err error, attrs ...slog.Attr) {
+	attrs = append([]slog.Attr{
+		slog.String("action", action),
+		slog.String("scheme", sa.name),
+		slog.String("ref", r.CommonName()),
+		slog.String("registry", r.Registry),
+		slog.String("repository", r.Repository),
+	}, attrs...)
+	if r.Tag != "" {
+		attrs = append(attrs, slog.String("tag", r.Tag))
+	}
+	// the user may come from a credential helper or docker login
+	if h, ok := sa.hosts[r.Registry]; ok {
+		if user := h.GetCred().User; user != "" {
+			attrs = append(attrs, slog.String("user", user))
+		}
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("status", "failed"), slog.String("err", err.Error()))
+	} else {
+		attrs = append(attrs, slog.String("status", "success"))
+	}
+	sa.log.LogAttrs(ctx, slog.LevelInfo, auditMsg, attrs...)
+}
+
+func (sa *schemeAudit) BlobDelete(ctx context.Context, r ref.Ref, d descriptor.Descriptor) error {
+	err := sa.API.BlobDelete(ctx, r, d)
+	sa.record(ctx, "blob-delete", r, err, slog.String("digest", d.Digest.String()))
+	return err
+}
+
+func (sa *schemeAudit) BlobMount(ctx context.Context, refSrc ref.Ref, refTgt ref.Ref, d descriptor.Descriptor) error {
+	err := sa.API.BlobMount(ctx, refSrc, refTgt, d)
+	sa.record(ctx, "blob-mount", refTgt, err,
+		slog.String("source", refSrc.CommonName()),
+		slog.String("digest", d.Digest.String()))
+	return err
+}
+
+func (sa *schemeAudit) BlobPut(ctx context.Context, r ref.Ref, d descriptor.Descriptor, rdr io.Reader) (descriptor.Descriptor, error) {
+	dOut, err := sa.API.BlobPut(ctx, r, d, rdr)
+	sa.record(ctx, "blob-put", r, err,
+		slog.String("digest", dOut.Digest.String()),
+		slog.Int64("size", dOut.Size))
+	return dOut, err
+}
+
+func (sa *schemeAudit) ManifestDelete(ctx context.Context, r ref.Ref, opts ...scheme.ManifestOpts) error {
+	err := sa.API.ManifestDelete(ctx, r, opts...)
+	sa.record(ctx, "manifest-delete", r, err, slog.String("digest", r.Digest))
+	return err
+}
+
+func (sa *schemeAudit) ManifestPut(ctx context.Context, r ref.Ref, m manifest.Manifest, opts ...scheme.ManifestOpts) error {
+	err := sa.API.ManifestPut(ctx, r, m, opts...)
+	action := "manifest-put"
+	if r.Tag != "" {
+		action = "tag"
+	}
+	d := m.GetDescriptor()
+	sa.record(ctx, action, r, err,
+		slog.String("digest", d.Digest.String()),
+		slog.String("mediaType", d.MediaType))
+	return err
+}
+
+func (sa *schemeAudit) TagDelete(ctx context.Context, r ref.Ref) error {
+	err := sa.API.TagDelete(ctx, r)
+	sa.record(ctx, "tag-delete", r, err)
+	return err
+}
+
+func (sa *schemeAudit) TagDeleteWithOpts(ctx context.Context, r ref.Ref, opts ...scheme.TagDeleteOpts) error {
+	err := sa.schemeWrap.TagDeleteWithOpts(ctx, r, opts...)
+	sa.record(ctx, "tag-delete", r, err)
+	return err
+}
@@ -0,0 +1,194 @@
+package regclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/olareg/olareg"
+	oConfig "github.com/olareg/olareg/config"
+
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/scheme"
+	"github.com/regclient/regclient/types/ref"
+)
+
+func TestAudit(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	buf := &bytes.Buffer{}
+	rc := New(WithAuditLogger(slog.New(slog.NewJSONHandler(buf, nil))))
+	rSrc, err := ref.New("ocidir://./testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rTgt, err := ref.New("ocidir://" + tempDir + "/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	// reads should not generate any records
+	_, err = rc.ManifestGet(ctx, rSrc)
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("unexpected audit record for a read: %s", buf.String())
+	}
+	err = rc.ImageCopy(ctx, rSrc, rTgt)
+	if err != nil {
+		t.Fatalf("failed to copy: %v", err)
+	}
+	err = rc.TagDelete(ctx, rTgt)
+	if err != nil {
+		t.Fatalf("failed to delete tag: %v", err)
+	}
+	actions := map[string]int{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		rec := map[string]any{}
+		err = dec.Decode(&rec)
+		if err != nil {
+			t.Fatalf("failed to parse audit record: %v", err)
+		}
+		if rec["msg"] != auditMsg || rec["scheme"] != "ocidir" || rec["status"] != "success" {
+			t.Errorf("unexpected audit record: %v", rec)
+		}
+		action, _ := rec["action"].(string)
+		if rec["digest"] == nil && action != "tag-delete" {
+			t.Errorf("audit record is missing the digest: %v", rec)
+		}
+		actions[action]++
+	}
+	for _, expect := range []string{"blob-put", "manifest-put", "tag", "tag-delete"} {
+		if actions[expect] == 0 {
+			t.Errorf("missing audit action %s in %v", expect, actions)
+		}
+	}
+}
+
+func TestAuditUser(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+		},
+	})
+	ts := httptest.NewServer(regHandler)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+	credHelper, err := filepath.Abs("./config/testdata/docker-credential-test")
+	if err != nil {
+		t.Fatalf("failed to find cred helper: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	rc := New(
+		WithConfigHost(config.Host{
+			Name:       tsHost,
+			Hostname:   tsHost,
+			TLS:        config.TLSDisabled,
+			CredHelper: credHelper,
+			CredHost:   "testhost.example.com",
+		}),
+		WithAuditLogger(slog.New(slog.NewJSONHandler(buf, nil))),
+	)
+	rSrc, err := ref.New("ocidir://./testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rTgt, err := ref.New(tsHost + "/audit:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	err = rc.ImageCopy(ctx, rSrc, rTgt)
+	if err != nil {
+		t.Fatalf("failed to copy: %v", err)
+	}
+	dec := json.NewDecoder(buf)
+	count := 0
+	for dec.More() {
+		rec := map[string]any{}
+		err = dec.Decode(&rec)
+		if err != nil {
+			t.Fatalf("failed to parse audit record: %v", err)
+		}
+		if rec["user"] != "hello" {
+			t.Errorf("unexpected user in audit record: %v", rec)
+		}
+		count++
+	}
+	if count == 0 {
+		t.Errorf("no audit records")
+	}
+}
+
+func TestSchemeAs(t *testing.T) {
+	t.Parallel()
+	rc := New(
+		WithAuditLogger(slog.New(slog.NewJSONHandler(io.Discard, nil))),
+		WithTracerProvider(&testTracer{}),
+	)
+	for _, name := range []string{"reg", "ocidir"} {
+		if _, ok := rc.schemes[name].(*schemeTrace); !ok {
+			t.Fatalf("scheme %s is not wrapped", name)
+		}
+	}
+	tt := []struct {
+		name   string
+		check  func(api scheme.API) bool
+		reg    bool
+		ocidir bool
+	}{
+		{
+			name:   "BlobURLGetter",
+			check:  func(api scheme.API) bool { _, ok := schemeAs[scheme.BlobURLGetter](api); return ok },
+			reg:    true,
+			ocidir: false,
+		},
+		{
+			name:   "GCLocker",
+			check:  func(api scheme.API) bool { _, ok := schemeAs[scheme.GCLocker](api); return ok },
+			reg:    false,
+			ocidir: true,
+		},
+		{
+			name:   "ManifestConditional",
+			check:  func(api scheme.API) bool { _, ok := schemeAs[scheme.ManifestConditional](api); return ok },
+			reg:    true,
+			ocidir: false,
+		},
+		{
+			name:   "repoLister",
+			check:  func(api scheme.API) bool { _, ok := schemeAs[repoLister](api); return ok },
+			reg:    true,
+			ocidir: false,
+		},
+		{
+			name:   "TagDeleter",
+			check:  func(api scheme.API) bool { _, ok := schemeAs[scheme.TagDeleter](api); return ok },
+			reg:    true,
+			ocidir: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if ok := tc.check(rc.schemes["reg"]); ok != tc.reg {
+				t.Errorf("unexpected result for reg, expected %t, received %t", tc.reg, ok)
+			}
+			if ok := tc.check(rc.schemes["ocidir"]); ok != tc.ocidir {
+				t.Errorf("unexpected result for ocidir, expected %t, received %t", tc.ocidir, ok)
+			}
+		})
+	}
+}
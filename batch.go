@@ -0,0 +1,263 @@
+package regclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/regclient/regclient/internal/pqueue"
+	"github.com/regclient/regclient/scheme"
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/ref"
+)
+
+// BatchKind is the type of operation in a [BatchOp].
+type BatchKind string
+
+const (
+	// BatchHead runs a [RegClient.ManifestHead].
+	BatchHead BatchKind = "head"
+	// BatchBlobHead runs a [RegClient.BlobHead].
+	BatchBlobHead BatchKind = "blob-head"
+	// BatchCopy runs a [RegClient.ImageCopy].
+	BatchCopy BatchKind = "copy"
+	// BatchManifestDelete runs a [RegClient.ManifestDelete].
+	BatchManifestDelete BatchKind = "manifest-delete"
+	// BatchTagDelete runs a [RegClient.TagDelete].
+	BatchTagDelete BatchKind = "tag-delete"
+	// BatchFunc runs the function from [BatchOpFunc].
+	BatchFunc BatchKind = "func"
+)
+
+// BatchOp is a single operation run by [RegClient.Batch].
+// Use [BatchOpHead], [BatchOpBlobHead], [BatchOpCopy], [BatchOpManifestDelete], [BatchOpTagDelete], or [BatchOpFunc] to create an operation.
+type BatchOp struct {
+	Kind   BatchKind // Kind of operation.
+	Ref    ref.Ref   // Ref is the source or only reference of the operation.
+	Target ref.Ref   // Target is the destination of a copy.
+
+	desc         descriptor.Descriptor
+	manifestOpts []ManifestOpts
+	imageOpts    []ImageOpts
+	tagOpts      []scheme.TagDeleteOpts
+	fn           func(context.Context) error
+}
+
+// BatchOpHead creates an operation to query a manifest with a head request.
+func BatchOpHead(r ref.Ref, opts ...ManifestOpts) BatchOp {
+	return BatchOp{Kind: BatchHead, Ref: r, manifestOpts: opts}
+}
+
+// BatchOpBlobHead creates an operation to check if a blob exists in a repository.
+func BatchOpBlobHead(r ref.Ref, d descriptor.Descriptor) BatchOp {
+	return BatchOp{Kind: BatchBlobHead, Ref: r, desc: d}
+}
+
+// BatchOpCopy creates an operation to copy an image.
+func BatchOpCopy(src, tgt ref.Ref, opts ...ImageOpts) BatchOp {
+	return BatchOp{Kind: BatchCopy, Ref: src, Target: tgt, imageOpts: opts}
+}
+
+// BatchOpManifestDelete creates an operation to delete a manifest by digest.
+func BatchOpManifestDelete(r ref.Ref, opts ...ManifestOpts) BatchOp {
+	return BatchOp{Kind: BatchManifestDelete, Ref: r, manifestOpts: opts}
+}
+
+// BatchOpTagDelete creates an operation to delete a tag.
+func BatchOpTagDelete(r ref.Ref, opts ...scheme.TagDeleteOpts) BatchOp {
+	return BatchOp{Kind: BatchTagDelete, Ref: r, tagOpts: opts}
+}
+
+// BatchOpFunc creates an operation that runs a function.
+// The reference is used to apply the host limit and is not closed by the batch.
+func BatchOpFunc(r ref.Ref, fn func(ctx context.Context) error) BatchOp {
+	return BatchOp{Kind: BatchFunc, Ref: r, fn: fn}
+}
+
+// BatchResult is the result of a single operation.
+// Err is the error returned by the operation, see [BatchReport.Err] for errors that include the operation.
+type BatchResult struct {
+	Op       BatchOp           // Op is the requested operation.
+	Manifest manifest.Manifest // Manifest is returned by a head operation.
+	Err      error             // Err is set when the operation failed or was not run.
+	Start    time.Time         // Start is the time the operation began.
+	Duration time.Duration     // Duration is the time taken by the operation.
+}
+
+// BatchReport contains the results of [RegClient.Batch], in the same order as the requested operations.
+type BatchReport struct {
+	Results []BatchResult
+}
+
+// Errors returns the number of failed operations.
+func (br BatchReport) Errors() int {
+	count := 0
+	for _, res := range br.Results {
+		if res.Err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Err returns the joined errors of all failed operations, or nil if every operation succeeded.
+func (br BatchReport) Err() error {
+	errList := []error{}
+	for _, res := range br.Results {
+		if res.Err != nil {
+			errList = append(errList, fmt.Errorf("batch %s %s failed: %w", res.Op.Kind, res.Op.Ref.CommonName(), res.Err))
+		}
+	}
+	return errors.Join(errList...)
+}
+
+type batchOpt struct {
+	workers     int
+	hostLimit   int
+	stopOnError bool
+}
+
+// BatchOpts define options for [RegClient.Batch].
+type BatchOpts func(*batchOpt)
+
+// BatchWithWorkers sets the number of operations that may run concurrently, defaults to 4.
+func BatchWithWorkers(n int) BatchOpts {
+	return func(opts *batchOpt) {
+		opts.workers = n
+	}
+}
+
+// BatchWithHostLimit sets the number of concurrent operations for each registry host or OCI Layout directory.
+// Copies count against both the source and target. The default is no limit beyond the number of workers.
+func BatchWithHostLimit(n int) BatchOpts {
+	return func(opts *batchOpt) {
+		opts.hostLimit = n
+	}
+}
+
+// BatchWithStopOnError skips any operations that have not started after the first failure.
+// Skipped operations return an error wrapping [errs.ErrCanceled].
+func BatchWithStopOnError() BatchOpts {
+	return func(opts *batchOpt) {
+		opts.stopOnError = true
+	}
+}
+
+// Batch runs a list of operations with a shared pool of workers.
+// Every operation is attempted unless [BatchWithStopOnError] is set or the context is canceled.
+// The returned report includes the result of each operation, and the error is the joined errors of any failed operations.
+// References used by the batch are closed when all operations complete, other than the references of a [BatchFunc].
+func (rc *RegClient) Batch(ctx context.Context, ops []BatchOp, opts ...BatchOpts) (BatchReport, error) {
+	opt := batchOpt{
+		workers: 4,
+	}
+	for _, optFn := range opts {
+		optFn(&opt)
+	}
+	report := BatchReport{
+		Results: make([]BatchResult, len(ops)),
+	}
+	workers := pqueue.New(pqueue.Opts[struct{}]{Max: max(opt.workers, 1)})
+	hosts := map[string]*pqueue.Queue[struct{}]{}
+	hostQueue := func(r ref.Ref) *pqueue.Queue[struct{}] {
+		if opt.hostLimit <= 0 {
+			return nil
+		}
+		key := batchHostKey(r)
+		if _, ok := hosts[key]; !ok {
+			hosts[key] = pqueue.New(pqueue.Opts[struct{}]{Max: opt.hostLimit})
+		}
+		return hosts[key]
+	}
+	// operations are started with acqCtx, canceling it skips the remaining operations without interrupting active ones
+	acqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i, op := range ops {
+		report.Results[i].Op = op
+		qList := []*pqueue.Queue[struct{}]{workers, hostQueue(op.Ref)}
+		if op.Kind == BatchCopy {
+			qList = append(qList, hostQueue(op.Target))
+		}
+		// the context returned by AcquireMulti is not passed to the operation since requests use their own queues
+		_, done, err := pqueue.AcquireMulti(acqCtx, struct{}{}, qList...)
+		if err == nil && acqCtx.Err() != nil {
+			done()
+			err = acqCtx.Err()
+		}
+		if err != nil {
+			if ctx.Err() == nil && opt.stopOnError {
+				err = fmt.Errorf("skipped after a failure%.0w", errs.ErrCanceled)
+			}
+			report.Results[i].Err = err
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer done()
+			res := &report.Results[i]
+			res.Start = time.Now()
+			res.Manifest, res.Err = rc.batchRun(ctx, op)
+			res.Duration = time.Since(res.Start)
+			if res.Err != nil && opt.stopOnError {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	// close each reference once, flushing any changes to an OCI Layout
+	closed := map[string]bool{}
+	for _, op := range ops {
+		if op.Kind == BatchFunc {
+			continue
+		}
+		for _, r := range []ref.Ref{op.Ref, op.Target} {
+			if !r.IsSetRepo() || closed[r.CommonName()] {
+				continue
+			}
+			closed[r.CommonName()] = true
+			_ = rc.Close(ctx, r)
+		}
+	}
+	return report, report.Err()
+}
+
+// batchRun runs a single operation.
+func (rc *RegClient) batchRun(ctx context.Context, op BatchOp) (manifest.Manifest, error) {
+	switch op.Kind {
+	case BatchHead:
+		return rc.ManifestHead(ctx, op.Ref, op.manifestOpts...)
+	case BatchBlobHead:
+		b, err := rc.BlobHead(ctx, op.Ref, op.desc)
+		if err == nil {
+			_ = b.Close()
+		}
+		return nil, err
+	case BatchCopy:
+		return nil, rc.ImageCopy(ctx, op.Ref, op.Target, op.imageOpts...)
+	case BatchManifestDelete:
+		return nil, rc.ManifestDelete(ctx, op.Ref, op.manifestOpts...)
+	case BatchTagDelete:
+		return nil, rc.TagDelete(ctx, op.Ref, op.tagOpts...)
+	case BatchFunc:
+		if op.fn == nil {
+			return nil, fmt.Errorf("batch function is not set%.0w", errs.ErrUnsupported)
+		}
+		return nil, op.fn(ctx)
+	default:
+		return nil, fmt.Errorf("unknown batch operation %q%.0w", op.Kind, errs.ErrUnsupported)
+	}
+}
+
+// batchHostKey returns the key used to throttle operations for a reference.
+func batchHostKey(r ref.Ref) string {
+	if r.Scheme == "reg" {
+		return r.Scheme + "://" + r.Registry
+	}
+	return r.Scheme + "://" + r.Path
+}
@@ -0,0 +1,146 @@
+package regclient
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ref"
+)
+
+func TestBatch(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := New()
+	tempDir := t.TempDir()
+	rV1, err := ref.New("ocidir://./testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rV2 := rV1.SetTag("v2")
+	mV1, err := rc.ManifestHead(ctx, rV1, WithManifestRequireDigest())
+	if err != nil {
+		t.Fatalf("failed to head manifest: %v", err)
+	}
+	rMissing := rV1.SetTag("missing")
+	rTgt, err := ref.New("ocidir://" + tempDir + "/testrepo")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	tt := []struct {
+		name      string
+		ops       []BatchOp
+		opts      []BatchOpts
+		expectErr []error // errors for each result
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "head",
+			ops: []BatchOp{
+				BatchOpHead(rV1),
+				BatchOpHead(rMissing),
+				BatchOpHead(rV2),
+			},
+			expectErr: []error{nil, errs.ErrNotFound, nil},
+		},
+		{
+			name: "blob head",
+			ops: []BatchOp{
+				BatchOpBlobHead(rV1, descriptor.Descriptor{Digest: mV1.GetDescriptor().Digest}),
+				BatchOpBlobHead(rV1, descriptor.Descriptor{Digest: digest.FromString("missing blob")}),
+			},
+			expectErr: []error{nil, fs.ErrNotExist},
+		},
+		{
+			name: "copy",
+			ops: []BatchOp{
+				BatchOpCopy(rV1, rTgt.SetTag("v1")),
+				BatchOpCopy(rV2, rTgt.SetTag("v2")),
+				BatchOpCopy(rV2, rTgt.SetTag("v2-copy")),
+			},
+			opts:      []BatchOpts{BatchWithWorkers(2), BatchWithHostLimit(1)},
+			expectErr: []error{nil, nil, nil},
+		},
+		{
+			name: "tag delete",
+			ops: []BatchOp{
+				BatchOpTagDelete(rTgt.SetTag("v2-copy")),
+				BatchOpHead(rTgt.SetTag("v2")),
+			},
+			expectErr: []error{nil, nil},
+		},
+		{
+			name: "stop on error",
+			ops: []BatchOp{
+				BatchOpHead(rMissing),
+				BatchOpHead(rV1),
+				BatchOpHead(rV2),
+			},
+			opts:      []BatchOpts{BatchWithWorkers(1), BatchWithStopOnError()},
+			expectErr: []error{errs.ErrNotFound, errs.ErrCanceled, errs.ErrCanceled},
+		},
+		{
+			name: "func",
+			ops: []BatchOp{
+				BatchOpFunc(rV1, func(ctx context.Context) error { return nil }),
+				BatchOpFunc(rV1, func(ctx context.Context) error { return fs.ErrNotExist }),
+				{Kind: BatchFunc, Ref: rV1},
+			},
+			expectErr: []error{nil, fs.ErrNotExist, errs.ErrUnsupported},
+		},
+		{
+			name: "unknown op",
+			ops: []BatchOp{
+				{Kind: "unknown", Ref: rV1},
+			},
+			expectErr: []error{errs.ErrUnsupported},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			report, err := rc.Batch(ctx, tc.ops, tc.opts...)
+			if len(report.Results) != len(tc.ops) {
+				t.Fatalf("unexpected number of results, expected %d, received %d", len(tc.ops), len(report.Results))
+			}
+			failed := 0
+			for i, res := range report.Results {
+				if res.Op.Kind != tc.ops[i].Kind || res.Op.Ref.CommonName() != tc.ops[i].Ref.CommonName() {
+					t.Errorf("result %d does not match the requested operation", i)
+				}
+				if tc.expectErr[i] == nil {
+					if res.Err != nil {
+						t.Errorf("result %d unexpected error: %v", i, res.Err)
+					}
+					if res.Op.Kind == BatchHead && (res.Manifest == nil || res.Manifest.GetDescriptor().Digest == "") {
+						t.Errorf("result %d missing manifest", i)
+					}
+					continue
+				}
+				failed++
+				if !errors.Is(res.Err, tc.expectErr[i]) {
+					t.Errorf("result %d unexpected error, expected %v, received %v", i, tc.expectErr[i], res.Err)
+				}
+			}
+			if report.Errors() != failed {
+				t.Errorf("unexpected error count, expected %d, received %d", failed, report.Errors())
+			}
+			if (failed == 0) != (err == nil) {
+				t.Errorf("unexpected batch error: %v", err)
+			}
+		})
+	}
+	// verify the copies were written
+	if _, err := rc.ManifestHead(ctx, rTgt.SetTag("v1")); err != nil {
+		t.Errorf("copy was not found: %v", err)
+	}
+	if _, err := rc.ManifestHead(ctx, rTgt.SetTag("v2-copy")); !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("deleted tag was found: %v", err)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net/url"
+	"sync"
 	"time"
 
 	"github.com/regclient/regclient/internal/pqueue"
@@ -17,6 +19,7 @@ import (
 	"github.com/regclient/regclient/types/descriptor"
 	"github.com/regclient/regclient/types/errs"
 	"github.com/regclient/regclient/types/ref"
+	"github.com/regclient/regclient/types/trace"
 	"github.com/regclient/regclient/types/warning"
 )
 
@@ -24,12 +27,45 @@ const blobCBFreq = time.Millisecond * 100
 
 type blobOpt struct {
 	callback   func(kind types.CallbackKind, instance string, state types.CallbackState, cur, total int64)
+	copyMethod *blobCopyMethod
 	readerHook func(*blob.BReader) (*blob.BReader, error)
 }
 
+// blobCopyMethod indicates how [RegClient.BlobCopy] transferred a blob.
+type blobCopyMethod int
+
+const (
+	blobCopySkipped blobCopyMethod = iota // blob already existed in the target
+	blobCopyMounted                       // blob was mounted from the source repository
+	blobCopyPulled                        // blob was pulled from the source and pushed to the target
+)
+
+// blobFlight is a blob copy in progress, concurrent copies of the same blob wait for the result.
+type blobFlight struct {
+	done chan struct{}
+	err  error
+}
+
+// blobFlightList tracks the blob copies in progress, it is shared by every copy using the same [RegClient].
+type blobFlightList struct {
+	mu      sync.Mutex
+	entries map[string]*blobFlight
+}
+
+func newBlobFlightList() *blobFlightList {
+	return &blobFlightList{entries: map[string]*blobFlight{}}
+}
+
 // BlobOpts define options for the Image* commands.
 type BlobOpts func(*blobOpt)
 
+// blobWithCopyMethod returns the method used by [RegClient.BlobCopy] to transfer the blob.
+func blobWithCopyMethod(m *blobCopyMethod) BlobOpts {
+	return func(opts *blobOpt) {
+		opts.copyMethod = m
+	}
+}
+
 // BlobWithCallback provides progress data to a callback function.
 func BlobWithCallback(callback func(kind types.CallbackKind, instance string, state types.CallbackState, cur, total int64)) BlobOpts {
 	return func(opts *blobOpt) {
@@ -49,7 +85,14 @@ func BlobWithReaderHook(hook func(*blob.BReader) (*blob.BReader, error)) BlobOpt
 // BlobCopy copies a blob between two locations.
 // If the blob already exists in the target, the copy is skipped.
 // A server side cross repository blob mount is attempted.
-func (rc *RegClient) BlobCopy(ctx context.Context, refSrc ref.Ref, refTgt ref.Ref, d descriptor.Descriptor, opts ...BlobOpts) error {
+// Concurrent copies of the same blob to the same repository with this client only transfer the blob once,
+// other copies wait and are skipped when the first copy succeeds.
+func (rc *RegClient) BlobCopy(ctx context.Context, refSrc ref.Ref, refTgt ref.Ref, d descriptor.Descriptor, opts ...BlobOpts) (err error) {
+	ctx, span := rc.traceStart(ctx, "BlobCopy",
+		slog.String("source", refSrc.CommonName()),
+		slog.String("target", refTgt.CommonName()),
+		slog.String("digest", d.Digest.String()))
+	defer func() { trace.End(span, err) }()
 	if !refSrc.IsSetRepo() {
 		return fmt.Errorf("refSrc is not set: %s%.0w", refSrc.CommonName(), errs.ErrInvalidReference)
 	}
@@ -80,11 +123,33 @@ func (rc *RegClient) BlobCopy(ctx context.Context, refSrc ref.Ref, refTgt ref.Re
 			slog.String("digest", string(d.Digest)))
 		return nil
 	}
+	// wait for a concurrent copy of the blob to the same repository
+	finish, err := rc.blobFlightStart(ctx, refTgt.SetTag("").CommonName()+"@"+d.Digest.String())
+	if err != nil {
+		return err
+	}
+	if finish == nil {
+		if opt.callback != nil {
+			opt.callback(types.CallbackBlob, d.Digest.String(), types.CallbackSkipped, 0, d.Size)
+		}
+		if opt.copyMethod != nil {
+			*opt.copyMethod = blobCopySkipped
+		}
+		rc.slog.Debug("Blob copy skipped, copied concurrently",
+			slog.String("src", refSrc.Reference),
+			slog.String("tgt", refTgt.Reference),
+			slog.String("digest", string(d.Digest)))
+		return nil
+	}
+	defer func() { finish(err) }()
 	// check if layer already exists
 	if _, err := rc.BlobHead(ctx, refTgt, tDesc); err == nil {
 		if opt.callback != nil {
 			opt.callback(types.CallbackBlob, d.Digest.String(), types.CallbackSkipped, 0, d.Size)
 		}
+		if opt.copyMethod != nil {
+			*opt.copyMethod = blobCopySkipped
+		}
 		rc.slog.Debug("Blob copy skipped, already exists",
 			slog.String("src", refSrc.Reference),
 			slog.String("tgt", refTgt.Reference),
@@ -101,10 +166,10 @@ func (rc *RegClient) BlobCopy(ctx context.Context, refSrc ref.Ref, refTgt ref.Re
 	if err != nil {
 		return err
 	}
-	if tSrc, ok := schemeSrcAPI.(scheme.Throttler); ok {
+	if tSrc, ok := schemeAs[scheme.Throttler](schemeSrcAPI); ok {
 		tList = append(tList, tSrc.Throttle(refSrc, false)...)
 	}
-	if tTgt, ok := schemeTgtAPI.(scheme.Throttler); ok {
+	if tTgt, ok := schemeAs[scheme.Throttler](schemeTgtAPI); ok {
 		tList = append(tList, tTgt.Throttle(refTgt, true)...)
 	}
 	if len(tList) > 0 {
@@ -125,6 +190,9 @@ func (rc *RegClient) BlobCopy(ctx context.Context, refSrc ref.Ref, refTgt ref.Re
 			if opt.callback != nil {
 				opt.callback(types.CallbackBlob, d.Digest.String(), types.CallbackSkipped, 0, d.Size)
 			}
+			if opt.copyMethod != nil {
+				*opt.copyMethod = blobCopyMounted
+			}
 			rc.slog.Debug("Blob copy performed server side with registry mount",
 				slog.String("src", refSrc.Reference),
 				slog.String("tgt", refTgt.Reference),
@@ -136,6 +204,9 @@ func (rc *RegClient) BlobCopy(ctx context.Context, refSrc ref.Ref, refTgt ref.Re
 			slog.String("tgt", refTgt.Reference),
 			slog.String("err", err.Error()))
 	}
+	if opt.copyMethod != nil {
+		*opt.copyMethod = blobCopyPulled
+	}
 	// fast options failed, download layer from source and push to target
 	blobIO, err := rc.BlobGet(ctx, refSrc, d)
 	if err != nil {
@@ -194,6 +265,38 @@ func (rc *RegClient) BlobCopy(ctx context.Context, refSrc ref.Ref, refTgt ref.Re
 	return nil
 }
 
+// blobFlightStart returns a function to report the result when the caller should copy the blob.
+// When another copy of the same key is running, this waits for that copy, and returns a nil function if it succeeded.
+// A failed copy is retried by one of the waiting callers.
+func (rc *RegClient) blobFlightStart(ctx context.Context, key string) (func(error), error) {
+	fl := rc.blobFlights
+	for {
+		fl.mu.Lock()
+		f, ok := fl.entries[key]
+		if !ok {
+			f = &blobFlight{done: make(chan struct{})}
+			fl.entries[key] = f
+			fl.mu.Unlock()
+			return func(err error) {
+				f.err = err
+				fl.mu.Lock()
+				delete(fl.entries, key)
+				fl.mu.Unlock()
+				close(f.done)
+			}, nil
+		}
+		fl.mu.Unlock()
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if f.err == nil {
+			return nil, nil
+		}
+	}
+}
+
 // BlobDelete removes a blob from the registry.
 // This method should only be used to repair a damaged registry.
 // Typically a server side garbage collection should be used to purge unused blobs.
@@ -205,6 +308,7 @@ func (rc *RegClient) BlobDelete(ctx context.Context, r ref.Ref, d descriptor.Des
 	if err != nil {
 		return err
 	}
+	defer rc.memo.blobDrop(r, d)
 	return schemeAPI.BlobDelete(ctx, r, d)
 }
 
@@ -237,6 +341,25 @@ func (rc *RegClient) BlobGetOCIConfig(ctx context.Context, r ref.Ref, d descript
 	return b.ToOCIConfig()
 }
 
+// BlobGetURL returns the URL a registry redirects to for downloading a blob, e.g. a presigned URL on a CDN or object storage.
+// The blob is not downloaded, allowing large downloads to be handed off to another client.
+// The URL may expire and should not be logged when it includes credentials.
+// An error wrapping [errs.ErrUnsupported] is returned when the registry serves the blob directly.
+func (rc *RegClient) BlobGetURL(ctx context.Context, r ref.Ref, d descriptor.Descriptor) (*url.URL, error) {
+	if !r.IsSetRepo() {
+		return nil, fmt.Errorf("ref is not set: %s%.0w", r.CommonName(), errs.ErrInvalidReference)
+	}
+	schemeAPI, err := rc.schemeGet(r.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	su, ok := schemeAs[scheme.BlobURLGetter](schemeAPI)
+	if !ok {
+		return nil, fmt.Errorf("blob url is not supported by scheme %s%.0w", r.Scheme, errs.ErrUnsupported)
+	}
+	return su.BlobGetURL(ctx, r, d)
+}
+
 // BlobHead is used to verify if a blob exists and is accessible.
 func (rc *RegClient) BlobHead(ctx context.Context, r ref.Ref, d descriptor.Descriptor) (blob.Reader, error) {
 	if !r.IsSetRepo() {
@@ -246,7 +369,15 @@ func (rc *RegClient) BlobHead(ctx context.Context, r ref.Ref, d descriptor.Descr
 	if err != nil {
 		return nil, err
 	}
-	return schemeAPI.BlobHead(ctx, r, d)
+	if bd, ok := rc.memo.blobGet(r, d); ok {
+		return blob.NewReader(blob.WithRef(r), blob.WithDesc(bd)), nil
+	}
+	br, err := schemeAPI.BlobHead(ctx, r, d)
+	if err != nil {
+		return br, err
+	}
+	rc.memo.blobSet(r, br.GetDescriptor())
+	return br, nil
 }
 
 // BlobMount attempts to perform a server side copy/mount of the blob between repositories.
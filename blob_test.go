@@ -11,9 +11,13 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/olareg/olareg"
+	oConfig "github.com/olareg/olareg/config"
 	"github.com/opencontainers/go-digest"
 
 	"github.com/regclient/regclient/config"
@@ -1317,3 +1321,169 @@ func TestBlobCopy(t *testing.T) {
 		}
 	})
 }
+
+func TestBlobGetURL(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := New()
+	d := descriptor.Descriptor{Digest: digest.FromString("blob url test")}
+	r, err := ref.New("ocidir://testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	_, err = rc.BlobGetURL(ctx, r, d)
+	if !errors.Is(err, errs.ErrUnsupported) {
+		t.Errorf("unexpected error, expected %v, received %v", errs.ErrUnsupported, err)
+	}
+	_, err = rc.BlobGetURL(ctx, ref.Ref{}, d)
+	if !errors.Is(err, errs.ErrInvalidReference) {
+		t.Errorf("unexpected error, expected %v, received %v", errs.ErrInvalidReference, err)
+	}
+	// the url is returned when the scheme is wrapped for auditing or tracing
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/proj/repo/blobs/"+d.Digest.String() {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Location", "/storage/blob")
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	}))
+	t.Cleanup(ts.Close)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	rcHost := WithConfigHost(config.Host{
+		Name:     tsHost,
+		Hostname: tsHost,
+		TLS:      config.TLSDisabled,
+	})
+	rReg, err := ref.New(tsHost + "/proj/repo")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	tracer := &testTracer{}
+	rcList := map[string]*RegClient{
+		"plain": New(rcHost),
+		"audit": New(rcHost, WithAuditLogger(slog.New(slog.NewJSONHandler(io.Discard, nil)))),
+		"trace": New(rcHost, WithTracerProvider(tracer)),
+	}
+	for name, rc := range rcList {
+		t.Run(name, func(t *testing.T) {
+			u, err := rc.BlobGetURL(ctx, rReg, d)
+			if err != nil {
+				t.Fatalf("failed to get blob url: %v", err)
+			}
+			if u.String() != ts.URL+"/storage/blob" {
+				t.Errorf("unexpected url, expected %s/storage/blob, received %s", ts.URL, u.String())
+			}
+		})
+	}
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	if len(tracer.spans) != 1 || tracer.spans[0].name != "regclient.scheme.BlobGetURL" || !tracer.spans[0].ended {
+		t.Errorf("unexpected trace spans: %v", tracer.spans)
+	}
+}
+
+func TestBlobCopyConcurrent(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+		},
+	})
+	var mu sync.Mutex
+	uploads := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/blobs/uploads/") {
+			mu.Lock()
+			uploads++
+			mu.Unlock()
+			// slow the upload so the copies overlap
+			time.Sleep(time.Millisecond * 50)
+		}
+		regHandler.ServeHTTP(w, r)
+	}))
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+	rc := New(
+		WithConfigHost(config.Host{
+			Name:     tsHost,
+			Hostname: tsHost,
+			TLS:      config.TLSDisabled,
+		}),
+		WithRegOpts(reg.WithDelay(time.Millisecond*10, time.Millisecond*100)),
+	)
+	rSrc, err := ref.New("ocidir://" + t.TempDir() + "/src")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rTgt, err := ref.New(tsHost + "/tgt")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	dig, blobData := reqresp.NewRandomBlob(4096, time.Now().UTC().Unix())
+	d := descriptor.Descriptor{MediaType: "application/octet-stream", Digest: dig, Size: int64(len(blobData))}
+	if _, err := rc.BlobPut(ctx, rSrc, d, bytes.NewReader(blobData)); err != nil {
+		t.Fatalf("failed to put source blob: %v", err)
+	}
+
+	count := 5
+	errList := make([]error, count)
+	var wg sync.WaitGroup
+	for i := range count {
+		wg.Go(func() {
+			errList[i] = rc.BlobCopy(ctx, rSrc, rTgt, d)
+		})
+	}
+	wg.Wait()
+	for i, err := range errList {
+		if err != nil {
+			t.Errorf("copy %d failed: %v", i, err)
+		}
+	}
+	if uploads != 1 {
+		t.Errorf("unexpected number of uploads, expected 1, received %d", uploads)
+	}
+
+	t.Run("retry after failure", func(t *testing.T) {
+		finish, err := rc.blobFlightStart(ctx, "retry")
+		if err != nil || finish == nil {
+			t.Fatalf("first copy did not start: %v", err)
+		}
+		started := make(chan func(error))
+		go func() {
+			f, _ := rc.blobFlightStart(ctx, "retry")
+			started <- f
+		}()
+		finish(errs.ErrNotFound)
+		f := <-started
+		if f == nil {
+			t.Fatalf("waiting copy did not retry after a failure")
+		}
+		f(nil)
+		// a new copy after completion is not waiting
+		f, err = rc.blobFlightStart(ctx, "retry")
+		if err != nil || f == nil {
+			t.Fatalf("copy after completion did not start: %v", err)
+		}
+		f(nil)
+	})
+	t.Run("canceled", func(t *testing.T) {
+		finish, err := rc.blobFlightStart(ctx, "cancel")
+		if err != nil || finish == nil {
+			t.Fatalf("first copy did not start: %v", err)
+		}
+		defer finish(nil)
+		ctxCancel, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = rc.blobFlightStart(ctxCancel, "cancel")
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected canceled, received %v", err)
+		}
+	})
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"time"
 
@@ -22,10 +24,11 @@ type Config struct {
 
 // ConfigDefaults is uses for general options and defaults for ConfigScript entries
 type ConfigDefaults struct {
-	Interval time.Duration `yaml:"interval" json:"interval"`
-	Schedule string        `yaml:"schedule" json:"schedule"`
-	Parallel int           `yaml:"parallel" json:"parallel"`
-	Timeout  time.Duration `yaml:"timeout" json:"timeout"`
+	Interval  time.Duration `yaml:"interval" json:"interval"`
+	Schedule  string        `yaml:"schedule" json:"schedule"`
+	Parallel  int           `yaml:"parallel" json:"parallel"`
+	Timeout   time.Duration `yaml:"timeout" json:"timeout"`
+	HTTPAllow []string      `yaml:"httpAllow" json:"httpAllow"` // URL prefixes scripts may request with the http module
 	// general options
 	BlobLimit      int64  `yaml:"blobLimit" json:"blobLimit"`
 	SkipDockerConf bool   `yaml:"skipDockerConfig" json:"skipDockerConfig"`
@@ -34,11 +37,12 @@ type ConfigDefaults struct {
 
 // ConfigScript defines a source/target repository to sync
 type ConfigScript struct {
-	Name     string        `yaml:"name" json:"name"`
-	Script   string        `yaml:"script" json:"script"`
-	Interval time.Duration `yaml:"interval" json:"interval"`
-	Schedule string        `yaml:"schedule" json:"schedule"`
-	Timeout  time.Duration `yaml:"timeout" json:"timeout"`
+	Name      string        `yaml:"name" json:"name"`
+	Script    string        `yaml:"script" json:"script"`
+	Interval  time.Duration `yaml:"interval" json:"interval"`
+	Schedule  string        `yaml:"schedule" json:"schedule"`
+	Timeout   time.Duration `yaml:"timeout" json:"timeout"`
+	HTTPAllow []string      `yaml:"httpAllow" json:"httpAllow"` // URL prefixes scripts may request with the http module
 }
 
 // ConfigNew creates an empty configuration
@@ -66,6 +70,12 @@ func ConfigLoadReader(r io.Reader) (*Config, error) {
 	// apply defaults to each step
 	for i := range c.Scripts {
 		scriptSetDefaults(&c.Scripts[i], c.Defaults)
+		for _, prefix := range c.Scripts[i].HTTPAllow {
+			u, err := url.Parse(prefix)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("script %s: http allow entry must be an http or https URL: %s%.0w", c.Scripts[i].Name, prefix, ErrInvalidInput)
+			}
+		}
 	}
 	err := configExpandTemplates(c)
 	if err != nil {
@@ -137,4 +147,7 @@ func scriptSetDefaults(s *ConfigScript, d ConfigDefaults) {
 	if s.Timeout == 0 && d.Timeout != 0 {
 		s.Timeout = d.Timeout
 	}
+	if s.HTTPAllow == nil {
+		s.HTTPAllow = d.HTTPAllow
+	}
 }
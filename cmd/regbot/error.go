@@ -15,6 +15,8 @@ var (
 	ErrNotFound = errors.New("not found")
 	// ErrScriptFailed when the script fails to run
 	ErrScriptFailed = errors.New("failure in user script")
+	// ErrScriptTimeout when the script does not finish before the timeout
+	ErrScriptTimeout = errors.New("script timeout exceeded")
 	// ErrUnsupportedConfigVersion happens when config file version is greater than this command supports
 	ErrUnsupportedConfigVersion = errors.New("unsupported config version")
 )
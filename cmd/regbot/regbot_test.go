@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
 	"log/slog"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
@@ -55,6 +57,31 @@ func TestRegbot(t *testing.T) {
 			TLS:      config.TLSDisabled,
 		},
 	}
+	// api called by scripts with the http module
+	tsAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/keep":
+			if r.Header.Get("Accept") != "text/plain" {
+				w.WriteHeader(http.StatusNotAcceptable)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("v1"))
+		case "/api/echo":
+			body, _ := io.ReadAll(r.Body)
+			_, _ = w.Write(body)
+		case "/api/redirect":
+			http.Redirect(w, r, "/private", http.StatusFound)
+		case "/api/redirect-dot":
+			http.Redirect(w, r, "http://"+r.Host+"/api/%2e%2e/private", http.StatusFound)
+		case "/private":
+			_, _ = w.Write([]byte("private"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(tsAPI.Close)
+	apiAllow := []string{tsAPI.URL + "/api/"}
 	// replace regclient with one configured for test hosts
 	rc := regclient.New(
 		regclient.WithConfigHost(rcHosts...),
@@ -125,6 +152,117 @@ defaults:
 			},
 			exists: []string{"registry.example.org/testcopy:latest"},
 		},
+		{
+			name: "CopyReferrers",
+			script: ConfigScript{
+				Name: "CopyReferrers",
+				Script: `
+				image.copy("registry.example.org/testrepo:v2", "registry.example.org/testcopyref:v2", {
+					digestTags = true,
+					fastCheck = true,
+					referrers = true,
+					referrerFilters = {{artifactType = "application/example.sbom"}},
+				})
+				`,
+			},
+			exists: []string{"registry.example.org/testcopyref:v2"},
+		},
+		{
+			name: "Mod",
+			script: ConfigScript{
+				Name: "Mod",
+				Script: `
+				out = image.mod("registry.example.org/testrepo:v1", {
+					annotations = {["org.example.promoted"] = "true"},
+					baseRef = "registry.example.org/testrepo:b1",
+					target = "registry.example.org/testmod:promoted",
+				})
+				if out:tag() ~= "promoted" then
+					error("unexpected output reference: " .. tostring(out))
+				end
+				m = manifest.getList(out)
+				if m.Annotations["org.example.promoted"] ~= "true" or m.Annotations["org.opencontainers.image.base.name"] == nil then
+					error("annotations missing from modified image")
+				end
+				`,
+			},
+			exists: []string{"registry.example.org/testmod:promoted"},
+		},
+		{
+			name: "ModConfig",
+			script: ConfigScript{
+				Name: "ModConfig",
+				Script: `
+				out = image.mod("registry.example.org/testrepo:v1", {
+					user = "1000:1000",
+					workDir = "/app",
+					exposeAdd = {"8080/tcp"},
+					layerCompress = "zstd",
+					target = "registry.example.org/testmod:config",
+				})
+				m = manifest.get(out, "linux/amd64")
+				ic = image.config(m)
+				if ic.Config.User ~= "1000:1000" or ic.Config.WorkingDir ~= "/app" or not string.find(tostring(ic), "8080/tcp", 1, true) then
+					error("config not modified: " .. tostring(ic))
+				end
+				if m.Layers[1].MediaType ~= "application/vnd.oci.image.layer.v1.tar+zstd" then
+					error("layer not recompressed: " .. m.Layers[1].MediaType)
+				end
+				`,
+			},
+			exists: []string{"registry.example.org/testmod:config"},
+		},
+		{
+			name: "ModTimestamp",
+			script: ConfigScript{
+				Name: "ModTimestamp",
+				Script: `
+				out = image.mod("registry.example.org/testrepo:v1", {
+					timestampMax = "2020-01-01T00:00:00Z",
+					reproducible = true,
+					target = "registry.example.org/testmod:timestamp",
+				})
+				ic = image.config(manifest.get(out, "linux/amd64"))
+				if not string.find(tostring(ic.Created), "2020-01-01", 1, true) then
+					error("timestamp not modified: " .. tostring(ic.Created))
+				end
+				`,
+			},
+			exists: []string{"registry.example.org/testmod:timestamp"},
+		},
+		{
+			name: "ModInvalidCompress",
+			script: ConfigScript{
+				Name:   "ModInvalidCompress",
+				Script: `image.mod("registry.example.org/testrepo:v1", {layerCompressLevel = 5})`,
+			},
+			expErr: ErrScriptFailed,
+		},
+		{
+			name:   "ModDryRun",
+			dryrun: true,
+			script: ConfigScript{
+				Name: "ModDryRun",
+				Script: `
+				out = image.mod("registry.example.org/testrepo:v1", {
+					labels = {version = "2"},
+					target = "registry.example.org/testmoddryrun:latest",
+				})
+				if tostring(out) ~= "registry.example.org/testrepo:v1" then
+					error("unexpected output reference: " .. tostring(out))
+				end
+				`,
+			},
+			missing: []string{"registry.example.org/testmoddryrun:latest"},
+		},
+		{
+			name: "ModInvalid",
+			script: ConfigScript{
+				Name:   "ModInvalid",
+				Script: `image.mod("registry.example.org/testrepo:v1", {baseDigest = "sha256:1234"})`,
+			},
+			expErr: ErrScriptFailed,
+		},
 		{
 			name: "DeleteCopy",
 			script: ConfigScript{
@@ -149,6 +287,103 @@ defaults:
 			missing: []string{"registry.example.org/testdryrun:latest"},
 			expErr:  nil,
 		},
+		{
+			name: "HTTPGet",
+			script: ConfigScript{
+				Name:      "HTTPGet",
+				HTTPAllow: apiAllow,
+				Script: `
+				resp = http.get("` + tsAPI.URL + `/api/keep", {Accept = "text/plain"})
+				if resp.status ~= 200 or resp.body ~= "v1" or resp.headers["Content-Type"] ~= "text/plain" then
+					error("unexpected response: " .. resp.status .. " " .. resp.body)
+				end
+				`,
+			},
+		},
+		{
+			name: "HTTPPost",
+			script: ConfigScript{
+				Name:      "HTTPPost",
+				HTTPAllow: apiAllow,
+				Script: `
+				resp = http.post("` + tsAPI.URL + `/api/echo", "hello")
+				if resp.body ~= "hello" then
+					error("unexpected response: " .. resp.body)
+				end
+				`,
+			},
+		},
+		{
+			name: "HTTPDenied",
+			script: ConfigScript{
+				Name:      "HTTPDenied",
+				HTTPAllow: apiAllow,
+				Script:    `http.get("` + tsAPI.URL + `/private")`,
+			},
+			expErr: ErrScriptFailed,
+		},
+		{
+			name: "HTTPDeniedPrefix",
+			script: ConfigScript{
+				Name:      "HTTPDeniedPrefix",
+				HTTPAllow: []string{tsAPI.URL + "/api"},
+				Script:    `http.get("` + tsAPI.URL + `/api2/keep")`,
+			},
+			expErr: ErrScriptFailed,
+		},
+		{
+			name: "HTTPDeniedRedirect",
+			script: ConfigScript{
+				Name:      "HTTPDeniedRedirect",
+				HTTPAllow: apiAllow,
+				Script:    `http.get("` + tsAPI.URL + `/api/redirect")`,
+			},
+			expErr: ErrScriptFailed,
+		},
+		{
+			name: "HTTPDeniedDotSegment",
+			script: ConfigScript{
+				Name:      "HTTPDeniedDotSegment",
+				HTTPAllow: apiAllow,
+				Script:    `http.get("` + tsAPI.URL + `/api/../private")`,
+			},
+			expErr: ErrScriptFailed,
+		},
+		{
+			name: "HTTPDeniedEscapedDotSegment",
+			script: ConfigScript{
+				Name:      "HTTPDeniedEscapedDotSegment",
+				HTTPAllow: apiAllow,
+				Script:    `http.get("` + tsAPI.URL + `/api/%2e%2e/private")`,
+			},
+			expErr: ErrScriptFailed,
+		},
+		{
+			name: "HTTPDeniedEscapedSlash",
+			script: ConfigScript{
+				Name:      "HTTPDeniedEscapedSlash",
+				HTTPAllow: apiAllow,
+				Script:    `http.get("` + tsAPI.URL + `/api/..%2fprivate")`,
+			},
+			expErr: ErrScriptFailed,
+		},
+		{
+			name: "HTTPDeniedRedirectDotSegment",
+			script: ConfigScript{
+				Name:      "HTTPDeniedRedirectDotSegment",
+				HTTPAllow: apiAllow,
+				Script:    `http.get("` + tsAPI.URL + `/api/redirect-dot")`,
+			},
+			expErr: ErrScriptFailed,
+		},
+		{
+			name: "HTTPWithoutAllow",
+			script: ConfigScript{
+				Name:   "HTTPWithoutAllow",
+				Script: `http.get("` + tsAPI.URL + `/api/keep")`,
+			},
+			expErr: ErrScriptFailed,
+		},
 		{
 			name: "Timeout",
 			script: ConfigScript{
@@ -162,6 +397,20 @@ defaults:
 			},
 			expErr: ErrScriptFailed,
 		},
+		{
+			name: "TimeoutLoop",
+			script: ConfigScript{
+				Name: "TimeoutLoop",
+				Script: `
+				i = 0
+				while true do
+					i = i + 1
+				end
+				`,
+				Timeout: shortTime,
+			},
+			expErr: ErrScriptTimeout,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -297,3 +546,38 @@ func TestConfigRead(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigHTTPAllow(t *testing.T) {
+	t.Parallel()
+	c, err := ConfigLoadReader(bytes.NewReader([]byte(`
+defaults:
+  httpAllow:
+    - https://inventory.example.org/api/
+scripts:
+  - name: default
+    script: ""
+  - name: override
+    script: ""
+    httpAllow:
+      - https://tickets.example.org/
+`)))
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	expect := [][]string{{"https://inventory.example.org/api/"}, {"https://tickets.example.org/"}}
+	for i, s := range c.Scripts {
+		if !reflect.DeepEqual(s.HTTPAllow, expect[i]) {
+			t.Errorf("script %s unexpected http allow, expected %v, received %v", s.Name, expect[i], s.HTTPAllow)
+		}
+	}
+	_, err = ConfigLoadReader(bytes.NewReader([]byte(`
+scripts:
+  - name: invalid
+    script: ""
+    httpAllow:
+      - inventory.example.org
+`)))
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("unexpected error, expected %v, received %v", ErrInvalidInput, err)
+	}
+}
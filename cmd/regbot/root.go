@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"runtime/debug"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/robfig/cron/v3"
 	"github.com/spf13/cobra"
@@ -29,6 +32,9 @@ More details at <https://github.com/regclient/regclient>`
 	UserAgent = "regclient/regbot"
 )
 
+// scriptStopDelay is how long to wait for a script to stop after it is canceled.
+var scriptStopDelay = time.Second * 5
+
 type rootOpts struct {
 	confFile  string
 	dryRun    bool
@@ -56,9 +62,10 @@ func NewRootCmd() (*cobra.Command, *rootOpts) {
 	serverCmd := &cobra.Command{
 		Use:   "server",
 		Short: "run the regbot server",
-		Long:  `Runs the various scripts according to their schedule.`,
-		Args:  cobra.RangeArgs(0, 0),
-		RunE:  opts.runServer,
+		Long: `Runs the various scripts according to their schedule.
+Scripts that exceed their timeout are canceled and reported as a failure without blocking other scripts.`,
+		Args: cobra.RangeArgs(0, 0),
+		RunE: opts.runServer,
 	}
 	onceCmd := &cobra.Command{
 		Use:   "once",
@@ -292,35 +299,72 @@ func (opts *rootOpts) loadConf() error {
 	return nil
 }
 
-// process a sync step
+// process runs a script in a separate goroutine.
+// Panics are returned as a script failure, and the script is abandoned if it does not stop after the timeout.
 func (opts *rootOpts) process(ctx context.Context, s ConfigScript) error {
 	opts.log.Debug("Starting script",
 		slog.String("script", s.Name))
+	start := time.Now()
+	parentCtx := ctx
 	// add a timeout to the context
 	if s.Timeout > 0 {
 		ctxTimeout, cancel := context.WithTimeout(ctx, s.Timeout)
 		ctx = ctxTimeout
 		defer cancel()
 	}
-	sbOpts := []sandbox.Opt{
-		sandbox.WithContext(ctx),
-		sandbox.WithRegClient(opts.rc),
-		sandbox.WithSlog(opts.log),
-		sandbox.WithThrottle(opts.throttle),
-	}
-	if opts.dryRun {
-		sbOpts = append(sbOpts, sandbox.WithDryRun())
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				opts.log.Error("Panic running script",
+					slog.String("script", s.Name),
+					slog.Any("panic", r),
+					slog.String("stack", string(debug.Stack())))
+				errCh <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		sbOpts := []sandbox.Opt{
+			sandbox.WithContext(ctx),
+			sandbox.WithRegClient(opts.rc),
+			sandbox.WithSlog(opts.log),
+			sandbox.WithThrottle(opts.throttle),
+			sandbox.WithHTTPAllow(s.HTTPAllow...),
+		}
+		if opts.dryRun {
+			sbOpts = append(sbOpts, sandbox.WithDryRun())
+		}
+		sb := sandbox.New(s.Name, sbOpts...)
+		defer sb.Close()
+		errCh <- sb.RunScript(s.Script)
+	}()
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		// give the script time to stop before abandoning it
+		select {
+		case err = <-errCh:
+		case <-time.After(scriptStopDelay):
+			opts.log.Warn("Script did not stop after cancel",
+				slog.String("script", s.Name))
+			err = ctx.Err()
+		}
 	}
-	sb := sandbox.New(s.Name, sbOpts...)
-	defer sb.Close()
-	err := sb.RunScript(s.Script)
 	if err != nil {
+		if s.Timeout > 0 && parentCtx.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			opts.log.Error("Script timed out",
+				slog.String("script", s.Name),
+				slog.Duration("timeout", s.Timeout),
+				slog.Duration("duration", time.Since(start)))
+			return fmt.Errorf("script %s exceeded the timeout of %s%.0w%.0w", s.Name, s.Timeout, ErrScriptTimeout, ErrScriptFailed)
+		}
 		opts.log.Warn("Error running script",
 			slog.String("script", s.Name),
 			slog.String("error", err.Error()))
 		return fmt.Errorf("%w%.0w", err, ErrScriptFailed)
 	}
 	opts.log.Debug("Finished script",
-		slog.String("script", s.Name))
+		slog.String("script", s.Name),
+		slog.Duration("duration", time.Since(start)))
 	return nil
 }
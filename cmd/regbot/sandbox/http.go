@@ -0,0 +1,156 @@
+package sandbox
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// httpMaxBody limits the size of a response returned to a script.
+const httpMaxBody = 10 * 1024 * 1024
+
+func setupHTTP(s *Sandbox) {
+	s.setupMod(
+		luaHTTPName,
+		map[string]lua.LGFunction{
+			"get":  s.httpGet,
+			"post": s.httpPost,
+		},
+		map[string]map[string]lua.LGFunction{
+			"__index": {},
+		},
+	)
+}
+
+// WithHTTPAllow sets the URL prefixes that scripts may request with the http module.
+// Requests are denied when no prefixes are set.
+func WithHTTPAllow(prefixes ...string) Opt {
+	return func(s *Sandbox) {
+		s.httpAllow = prefixes
+	}
+}
+
+// httpAllowed returns true when the URL matches the scheme, host, and path prefix of any allowed prefix.
+// A path prefix only matches complete path segments unless the prefix ends with a "/".
+// Paths with dot segments, including escaped dots, are denied since the server may resolve them outside of the prefix.
+func httpAllowed(allow []string, u *url.URL) bool {
+	if u == nil || u.User != nil || httpDotSegment(u.Path) {
+		return false
+	}
+	for _, prefix := range allow {
+		pu, err := url.Parse(prefix)
+		if err != nil || pu.Host == "" {
+			continue
+		}
+		if !strings.EqualFold(pu.Scheme, u.Scheme) || !strings.EqualFold(pu.Host, u.Host) {
+			continue
+		}
+		path, pPath := u.EscapedPath(), pu.EscapedPath()
+		if pPath == "" || pPath == "/" || path == pPath ||
+			(strings.HasPrefix(path, pPath) && (strings.HasSuffix(pPath, "/") || path[len(pPath)] == '/')) {
+			return true
+		}
+	}
+	return false
+}
+
+// httpDotSegment returns true when the unescaped path contains a "." or ".." segment.
+// Backslashes are treated as a separator since some servers accept them.
+func httpDotSegment(p string) bool {
+	for _, seg := range strings.FieldsFunc(p, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if seg == "." || seg == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Sandbox) httpGet(ls *lua.LState) int {
+	return s.httpDo(ls, http.MethodGet, ls.CheckString(1), nil, ls.OptTable(2, nil))
+}
+
+func (s *Sandbox) httpPost(ls *lua.LState) int {
+	body := ls.CheckString(2)
+	return s.httpDo(ls, http.MethodPost, ls.CheckString(1), strings.NewReader(body), ls.OptTable(3, nil))
+}
+
+// httpDo sends a request to an allowed URL and pushes a table with the status, headers, and body of the response.
+func (s *Sandbox) httpDo(ls *lua.LState, method, reqURL string, body io.Reader, lHeaders *lua.LTable) int {
+	err := s.ctx.Err()
+	if err != nil {
+		ls.RaiseError("Context error: %v", err)
+	}
+	u, err := url.Parse(reqURL)
+	if err != nil {
+		ls.ArgError(1, fmt.Sprintf("Failed to parse url: %v", err))
+	}
+	if !httpAllowed(s.httpAllow, u) {
+		ls.RaiseError("URL is not in the http allow list: %s", u.Redacted())
+	}
+	// only GET requests are sent in dry-run, others return an empty response
+	if s.dryRun && method != http.MethodGet {
+		s.log.Info("Skipping HTTP request",
+			slog.String("script", s.name),
+			slog.String("method", method),
+			slog.String("url", u.Redacted()),
+			slog.Bool("dry-run", s.dryRun))
+		lResp := ls.NewTable()
+		lResp.RawSetString("status", lua.LNumber(0))
+		lResp.RawSetString("body", lua.LString(""))
+		lResp.RawSetString("headers", ls.NewTable())
+		ls.Push(lResp)
+		return 1
+	}
+	req, err := http.NewRequestWithContext(s.ctx, method, u.String(), body)
+	if err != nil {
+		ls.RaiseError("Failed to create request: %v", err)
+	}
+	if lHeaders != nil {
+		lHeaders.ForEach(func(k, v lua.LValue) {
+			req.Header.Add(k.String(), v.String())
+		})
+	}
+	s.log.Debug("HTTP request",
+		slog.String("script", s.name),
+		slog.String("method", method),
+		slog.String("url", u.Redacted()))
+	client := &http.Client{
+		// redirects must also be allowed
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !httpAllowed(s.httpAllow, req.URL) {
+				return fmt.Errorf("redirect is not in the http allow list: %s", req.URL.Redacted())
+			}
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		ls.RaiseError("Failed to send request to %s: %v", u.Redacted(), err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, httpMaxBody+1))
+	if err != nil {
+		ls.RaiseError("Failed to read response from %s: %v", u.Redacted(), err)
+	}
+	if len(respBody) > httpMaxBody {
+		ls.RaiseError("Response from %s exceeds %d bytes", u.Redacted(), httpMaxBody)
+	}
+	lResp := ls.NewTable()
+	lResp.RawSetString("status", lua.LNumber(resp.StatusCode))
+	lResp.RawSetString("body", lua.LString(respBody))
+	lRespHeaders := ls.NewTable()
+	for k := range resp.Header {
+		lRespHeaders.RawSetString(k, lua.LString(resp.Header.Get(k)))
+	}
+	lResp.RawSetString("headers", lRespHeaders)
+	ls.Push(lResp)
+	return 1
+}
@@ -0,0 +1,63 @@
+package sandbox
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHTTPDryRun(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	var reqCount atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqCount.Add(1)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(ts.Close)
+	log := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	tt := []struct {
+		name      string
+		script    string
+		expectReq int32
+	}{
+		{
+			name: "post skipped",
+			script: `
+				resp = http.post("` + ts.URL + `/api/echo", "hello")
+				if resp.status ~= 0 or resp.body ~= "" then
+					error("unexpected response: " .. resp.status)
+				end
+			`,
+			expectReq: 0,
+		},
+		{
+			name: "get sent",
+			script: `
+				resp = http.get("` + ts.URL + `/api/keep")
+				if resp.status ~= 200 or resp.body ~= "ok" then
+					error("unexpected response: " .. resp.status)
+				end
+			`,
+			expectReq: 1,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			reqCount.Store(0)
+			s := New(tc.name, WithContext(ctx), WithSlog(log), WithDryRun(), WithHTTPAllow(ts.URL+"/api/"))
+			defer s.Close()
+			err := s.RunScript(tc.script)
+			if err != nil {
+				t.Fatalf("failed to run script: %v", err)
+			}
+			if reqCount.Load() != tc.expectReq {
+				t.Errorf("unexpected request count, expected %d, received %d", tc.expectReq, reqCount.Load())
+			}
+		})
+	}
+}
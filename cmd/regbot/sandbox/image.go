@@ -3,15 +3,24 @@ package sandbox
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"regexp"
+	"slices"
 	"time"
 
+	"github.com/opencontainers/go-digest"
 	lua "github.com/yuin/gopher-lua"
 
 	"github.com/regclient/regclient"
 	"github.com/regclient/regclient/cmd/regbot/internal/go2lua"
+	"github.com/regclient/regclient/mod"
+	"github.com/regclient/regclient/pkg/archive"
+	"github.com/regclient/regclient/scheme"
 	"github.com/regclient/regclient/types/blob"
+	"github.com/regclient/regclient/types/descriptor"
 	"github.com/regclient/regclient/types/manifest"
 	v1 "github.com/regclient/regclient/types/oci/v1"
 	"github.com/regclient/regclient/types/ref"
@@ -34,6 +43,7 @@ func setupImage(s *Sandbox) {
 			"manifest":      s.manifestGet,
 			"manifestHead":  s.manifestHead,
 			"manifestList":  s.manifestGetList,
+			"mod":           s.imageMod,
 			"ratelimitWait": s.imageRateLimitWait,
 		},
 		map[string]map[string]lua.LGFunction{
@@ -178,10 +188,16 @@ func (s *Sandbox) imageCopy(ls *lua.LState) int {
 	tgt := s.checkReference(ls, 2)
 	opts := []regclient.ImageOpts{}
 	lOpts := struct {
-		DigestTags      bool     `json:"digestTags"`
-		ForceRecursive  bool     `json:"forceRecursive"`
-		IncludeExternal bool     `json:"includeExternal"`
-		Platforms       []string `json:"platforms"`
+		DigestTags        bool                `json:"digestTags"`
+		DigestTagPatterns []string            `json:"digestTagPatterns"`
+		FastCheck         bool                `json:"fastCheck"`
+		ForceRecursive    bool                `json:"forceRecursive"`
+		IncludeExternal   bool                `json:"includeExternal"`
+		Platforms         []string            `json:"platforms"`
+		Referrers         bool                `json:"referrers"`
+		ReferrerFilters   []imageCopyReferrer `json:"referrerFilters"`
+		ReferrerSrc       string              `json:"referrerSource"`
+		ReferrerTgt       string              `json:"referrerTarget"`
 	}{}
 	if ls.GetTop() == 3 {
 		err := go2lua.Import(ls, ls.Get(3), &lOpts, lOpts)
@@ -190,6 +206,12 @@ func (s *Sandbox) imageCopy(ls *lua.LState) int {
 		}
 		if lOpts.DigestTags {
 			opts = append(opts, regclient.ImageWithDigestTags())
+			if len(lOpts.DigestTagPatterns) > 0 {
+				opts = append(opts, regclient.ImageWithDigestTagPatterns(lOpts.DigestTagPatterns...))
+			}
+		}
+		if lOpts.FastCheck {
+			opts = append(opts, regclient.ImageWithFastCheck())
 		}
 		if lOpts.ForceRecursive {
 			opts = append(opts, regclient.ImageWithForceRecursive())
@@ -200,6 +222,31 @@ func (s *Sandbox) imageCopy(ls *lua.LState) int {
 		if len(lOpts.Platforms) > 0 {
 			opts = append(opts, regclient.ImageWithPlatforms(lOpts.Platforms))
 		}
+		if lOpts.Referrers {
+			if len(lOpts.ReferrerFilters) == 0 {
+				opts = append(opts, regclient.ImageWithReferrers())
+			}
+			for _, filter := range lOpts.ReferrerFilters {
+				opts = append(opts, regclient.ImageWithReferrers(scheme.WithReferrerMatchOpt(descriptor.MatchOpt{
+					ArtifactType: filter.ArtifactType,
+					Annotations:  filter.Annotations,
+				})))
+			}
+			if lOpts.ReferrerSrc != "" {
+				rSrc, err := ref.New(lOpts.ReferrerSrc)
+				if err != nil {
+					ls.RaiseError("Failed to parse referrer source \"%s\": %v", lOpts.ReferrerSrc, err)
+				}
+				opts = append(opts, regclient.ImageWithReferrerSrc(rSrc))
+			}
+			if lOpts.ReferrerTgt != "" {
+				rTgt, err := ref.New(lOpts.ReferrerTgt)
+				if err != nil {
+					ls.RaiseError("Failed to parse referrer target \"%s\": %v", lOpts.ReferrerTgt, err)
+				}
+				opts = append(opts, regclient.ImageWithReferrerTgt(rTgt))
+			}
+		}
 	}
 	if s.throttle != nil {
 		done, err := s.throttle.Acquire(s.ctx, struct{}{})
@@ -215,6 +262,8 @@ func (s *Sandbox) imageCopy(ls *lua.LState) int {
 		slog.Bool("digestTags", lOpts.DigestTags),
 		slog.Bool("forceRecursive", lOpts.ForceRecursive),
 		slog.Bool("includeExternal", lOpts.IncludeExternal),
+		slog.Bool("referrers", lOpts.Referrers),
+		slog.Any("platforms", lOpts.Platforms),
 		slog.Bool("dry-run", s.dryRun),
 	)
 	if s.dryRun {
@@ -231,6 +280,206 @@ func (s *Sandbox) imageCopy(ls *lua.LState) int {
 	return 0
 }
 
+// imageCopyReferrer filters the referrers included with an image copy.
+type imageCopyReferrer struct {
+	ArtifactType string            `json:"artifactType"`
+	Annotations  map[string]string `json:"annotations"`
+}
+
+// imageMod applies modifications to an image, returning the reference to the modified image.
+// The modified image is pushed by digest to the source repository unless a target is set.
+func (s *Sandbox) imageMod(ls *lua.LState) int {
+	err := s.ctx.Err()
+	if err != nil {
+		ls.RaiseError("Context error: %v", err)
+	}
+	src := s.checkReference(ls, 1)
+	lMods := struct {
+		Annotations         map[string]string `json:"annotations"`
+		BaseDigest          string            `json:"baseDigest"`
+		BaseRef             string            `json:"baseRef"`
+		Env                 map[string]string `json:"env"`
+		ExposeAdd           []string          `json:"exposeAdd"`
+		ExposeRm            []string          `json:"exposeRm"`
+		ExternalURLsRm      bool              `json:"externalURLsRm"`
+		ForceRecompress     bool              `json:"forceRecompress"`
+		Labels              map[string]string `json:"labels"`
+		LabelsToAnnotations bool              `json:"labelsToAnnotations"`
+		LayerCompress       string            `json:"layerCompress"`
+		LayerCompressLevel  int               `json:"layerCompressLevel"`
+		LayerRmCreatedBy    string            `json:"layerRmCreatedBy"`
+		Reproducible        bool              `json:"reproducible"`
+		Target              string            `json:"target"`
+		Timestamp           string            `json:"timestamp"`
+		TimestampMax        string            `json:"timestampMax"`
+		ToDocker            bool              `json:"toDocker"`
+		ToOCI               bool              `json:"toOCI"`
+		ToOCIReferrers      bool              `json:"toOCIReferrers"`
+		User                string            `json:"user"`
+		VolumeAdd           []string          `json:"volumeAdd"`
+		VolumeRm            []string          `json:"volumeRm"`
+		WorkDir             string            `json:"workDir"`
+	}{}
+	err = go2lua.Import(ls, ls.CheckTable(2), &lMods, lMods)
+	if err != nil {
+		ls.ArgError(2, fmt.Sprintf("Failed to parse mods: %v", err))
+	}
+	mods := []mod.Opts{}
+	// sort keys so changes are applied in a consistent order
+	for _, name := range slices.Sorted(maps.Keys(lMods.Annotations)) {
+		mods = append(mods, mod.WithAnnotation(name, lMods.Annotations[name]))
+	}
+	for _, name := range slices.Sorted(maps.Keys(lMods.Labels)) {
+		mods = append(mods, mod.WithLabel(name, lMods.Labels[name]))
+	}
+	for _, name := range slices.Sorted(maps.Keys(lMods.Env)) {
+		mods = append(mods, mod.WithEnv(name, lMods.Env[name]))
+	}
+	if lMods.LabelsToAnnotations {
+		mods = append(mods, mod.WithLabelToAnnotation())
+	}
+	// user and workDir are only changed when set, an empty value is not used to delete the field
+	if lMods.User != "" {
+		mods = append(mods, mod.WithConfigUser(lMods.User))
+	}
+	if lMods.WorkDir != "" {
+		mods = append(mods, mod.WithConfigWorkingDir(lMods.WorkDir))
+	}
+	for _, port := range lMods.ExposeAdd {
+		mods = append(mods, mod.WithExposeAdd(port))
+	}
+	for _, port := range lMods.ExposeRm {
+		mods = append(mods, mod.WithExposeRm(port))
+	}
+	for _, vol := range lMods.VolumeAdd {
+		mods = append(mods, mod.WithVolumeAdd(vol))
+	}
+	for _, vol := range lMods.VolumeRm {
+		mods = append(mods, mod.WithVolumeRm(vol))
+	}
+	if lMods.BaseDigest != "" && lMods.BaseRef == "" {
+		ls.ArgError(2, "baseDigest requires a baseRef")
+	}
+	if lMods.BaseRef != "" {
+		rBase, err := ref.New(lMods.BaseRef)
+		if err != nil {
+			ls.RaiseError("Failed to parse base \"%s\": %v", lMods.BaseRef, err)
+		}
+		var dBase digest.Digest
+		if lMods.BaseDigest != "" {
+			dBase, err = digest.Parse(lMods.BaseDigest)
+			if err != nil {
+				ls.RaiseError("Failed to parse base digest \"%s\": %v", lMods.BaseDigest, err)
+			}
+		} else {
+			// lookup the digest of the base image
+			mBase, err := s.rc.ManifestHead(s.ctx, rBase, regclient.WithManifestRequireDigest())
+			if err != nil {
+				ls.RaiseError("Failed looking up base \"%s\": %v", rBase.CommonName(), err)
+			}
+			dBase = mBase.GetDescriptor().Digest
+		}
+		mods = append(mods, mod.WithAnnotationOCIBase(rBase, dBase))
+	}
+	if lMods.ExternalURLsRm {
+		mods = append(mods, mod.WithExternalURLsRm())
+	}
+	if lMods.LayerRmCreatedBy != "" {
+		re, err := regexp.Compile(lMods.LayerRmCreatedBy)
+		if err != nil {
+			ls.RaiseError("Failed to parse layerRmCreatedBy \"%s\": %v", lMods.LayerRmCreatedBy, err)
+		}
+		mods = append(mods, mod.WithLayerRmCreatedBy(*re))
+	}
+	if lMods.Timestamp != "" {
+		t, err := time.Parse(time.RFC3339, lMods.Timestamp)
+		if err != nil {
+			ls.RaiseError("Failed to parse timestamp \"%s\": %v", lMods.Timestamp, err)
+		}
+		mods = append(mods,
+			mod.WithConfigTimestamp(mod.OptTime{Set: t}),
+			mod.WithLayerTimestamp(mod.OptTime{Set: t}),
+		)
+	}
+	if lMods.TimestampMax != "" {
+		t, err := time.Parse(time.RFC3339, lMods.TimestampMax)
+		if err != nil {
+			ls.RaiseError("Failed to parse timestampMax \"%s\": %v", lMods.TimestampMax, err)
+		}
+		mods = append(mods,
+			mod.WithConfigTimestamp(mod.OptTime{Set: t, After: t}),
+			mod.WithLayerTimestamp(mod.OptTime{Set: t, After: t}),
+		)
+	}
+	if lMods.LayerCompress != "" {
+		var algo archive.CompressType
+		err := algo.UnmarshalText([]byte(lMods.LayerCompress))
+		if err != nil {
+			ls.RaiseError("Failed to parse layerCompress \"%s\": %v", lMods.LayerCompress, err)
+		}
+		err = archive.CompressLevelValidate(algo, lMods.LayerCompressLevel)
+		if err != nil {
+			ls.RaiseError("Invalid layerCompressLevel %d: %v", lMods.LayerCompressLevel, err)
+		}
+		if lMods.ForceRecompress {
+			mods = append(mods, mod.WithLayerRecompress(algo, lMods.LayerCompressLevel))
+		} else {
+			mods = append(mods, mod.WithLayerCompressionLevel(algo, lMods.LayerCompressLevel))
+		}
+	} else if lMods.LayerCompressLevel != 0 || lMods.ForceRecompress {
+		ls.ArgError(2, "layerCompressLevel and forceRecompress require a layerCompress")
+	}
+	if lMods.Reproducible {
+		mods = append(mods, mod.WithLayerReproducible())
+	}
+	if lMods.ToDocker {
+		mods = append(mods, mod.WithManifestToDocker())
+	}
+	if lMods.ToOCI {
+		mods = append(mods, mod.WithManifestToOCI())
+	}
+	if lMods.ToOCIReferrers {
+		mods = append(mods, mod.WithManifestToOCIReferrers())
+	}
+	tgt := src.r
+	if lMods.Target != "" {
+		tgt, err = ref.New(lMods.Target)
+		if err != nil {
+			ls.RaiseError("Failed to parse target \"%s\": %v", lMods.Target, err)
+		}
+		mods = append(mods, mod.WithRefTgt(tgt))
+	}
+	if s.throttle != nil {
+		done, err := s.throttle.Acquire(s.ctx, struct{}{})
+		if err != nil {
+			ls.RaiseError("Failed to acquire throttle: %v", err)
+		}
+		defer done()
+	}
+	s.log.Info("Modify image",
+		slog.String("script", s.name),
+		slog.String("source", src.r.CommonName()),
+		slog.String("target", tgt.CommonName()),
+		slog.Bool("dry-run", s.dryRun),
+	)
+	rOut := src.r
+	if !s.dryRun {
+		rOut, err = mod.Apply(s.ctx, s.rc, src.r, mods...)
+		if err != nil {
+			ls.RaiseError("Failed modifying \"%s\": %v", src.r.CommonName(), err)
+		}
+		err = s.rc.Close(s.ctx, rOut)
+		if err != nil {
+			ls.RaiseError("Failed closing reference \"%s\": %v", rOut.CommonName(), err)
+		}
+	}
+	ud := ls.NewUserData()
+	ud.Value = &reference{r: rOut}
+	ls.SetMetatable(ud, ls.GetTypeMetatable(luaReferenceName))
+	ls.Push(ud)
+	return 1
+}
+
 func (s *Sandbox) imageExportTar(ls *lua.LState) int {
 	err := s.ctx.Err()
 	if err != nil {
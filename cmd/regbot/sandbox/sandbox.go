@@ -22,17 +22,19 @@ const (
 	luaImageName       = "image"
 	luaImageConfigName = "imageconfig"
 	luaBlobName        = "blob"
+	luaHTTPName        = "http"
 )
 
 // Sandbox defines a lua sandbox
 type Sandbox struct {
-	name     string
-	ctx      context.Context
-	log      *slog.Logger
-	ls       *lua.LState
-	rc       *regclient.RegClient
-	throttle *pqueue.Queue[struct{}]
-	dryRun   bool
+	name      string
+	ctx       context.Context
+	log       *slog.Logger
+	ls        *lua.LState
+	rc        *regclient.RegClient
+	throttle  *pqueue.Queue[struct{}]
+	dryRun    bool
+	httpAllow []string
 }
 
 // LuaMod defines a mod to add to Lua's sandbox
@@ -45,6 +47,7 @@ var luaMods = []LuaMod{
 	setupImage,
 	setupManifest,
 	setupBlob,
+	setupHTTP,
 }
 
 // Opt function to process options on sandbox
@@ -67,6 +70,8 @@ func New(name string, opts ...Opt) *Sandbox {
 	if s.ctx == nil {
 		s.ctx = context.Background()
 	}
+	// stop long running scripts when the context is canceled
+	s.ls.SetContext(s.ctx)
 	if s.log == nil {
 		s.log = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
 	}
@@ -142,7 +147,11 @@ func (s *Sandbox) RunScript(script string) (err error) {
 			err = fmt.Errorf("%w: %v", ErrScriptFailed, r)
 		}
 	}()
-	return s.ls.DoString(script)
+	err = s.ls.DoString(script)
+	if err != nil && s.ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", ErrScriptFailed, s.ctx.Err())
+	}
+	return err
 }
 
 // Close is use to stop the sandbox
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"fmt"
 	"io"
@@ -11,6 +12,7 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+	"text/tabwriter"
 
 	// crypto libraries included for go-digest
 	_ "crypto/sha256"
@@ -20,6 +22,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/internal/units"
 	"github.com/regclient/regclient/pkg/archive"
 	"github.com/regclient/regclient/pkg/template"
 	"github.com/regclient/regclient/scheme"
@@ -61,6 +64,7 @@ var configKnownTypes = []string{
 
 type artifactOpts struct {
 	rootOpts         *rootOpts
+	allPlatforms     bool
 	annotations      []string
 	artifactMT       string
 	artifactType     string
@@ -81,10 +85,12 @@ type artifactOpts struct {
 	outputDir        string
 	platform         string
 	refers           string
+	sort             string
 	sortAnnot        string
 	sortDesc         bool
 	stripDirs        bool
 	subject          string
+	subjectPlatforms []string
 }
 
 func NewArtifactCmd(rOpts *rootOpts) *cobra.Command {
@@ -160,6 +166,9 @@ func newArtifactListCmd(rOpts *rootOpts) *cobra.Command {
 # list all referrers of the regsync package for the local platform
 regctl artifact list ghcr.io/regclient/regctl --platform local
 
+# list the newest referrers first
+regctl artifact list registry.example.com/repo:v1 --sort created --sort-desc
+
 # return the original referrers response
 regctl artifact list registry.example.com/repo:v1 --format body
 
@@ -173,11 +182,15 @@ regctl artifact list registry.example.com/repo:v1 --format '{{jsonPretty .Manife
 	cmd.Flags().StringVar(&opts.externalRepo, "external", "", "Query referrers from a separate source")
 	cmd.Flags().StringVar(&opts.filterAT, "filter-artifact-type", "", "Filter descriptors by artifactType")
 	cmd.Flags().StringArrayVar(&opts.filterAnnot, "filter-annotation", []string{}, "Filter descriptors by annotation (key=value)")
-	cmd.Flags().StringVar(&opts.format, "format", "{{printPretty .}}", "Format output with go template syntax")
+	cmd.Flags().StringVar(&opts.format, "format", "table", "Format output with go template syntax, or \"table\" for a list of referrers")
 	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
 	cmd.Flags().BoolVar(&opts.latest, "latest", false, "Sort using the OCI created annotation")
 	cmd.Flags().StringVarP(&opts.platform, "platform", "p", "", "Specify platform (e.g. linux/amd64 or local)")
 	_ = cmd.RegisterFlagCompletionFunc("platform", completeArgPlatform)
+	cmd.Flags().StringVar(&opts.sort, "sort", "", "Sort results by created, artifactType, or size")
+	_ = cmd.RegisterFlagCompletionFunc("sort", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"created", "artifactType", "size"}, cobra.ShellCompDirectiveNoFileComp
+	})
 	cmd.Flags().StringVar(&opts.sortAnnot, "sort-annotation", "", "Annotation used for sorting results")
 	cmd.Flags().BoolVar(&opts.sortDesc, "sort-desc", false, "Sort in descending order")
 	return cmd
@@ -189,9 +202,12 @@ func newArtifactPutCmd(rOpts *rootOpts) *cobra.Command {
 	}
 	cmd := &cobra.Command{
 		Use:     "put <reference>",
-		Aliases: []string{"create", "push"},
+		Aliases: []string{"attach", "create", "push"},
 		Short:   "upload artifacts",
-		Long:    `Upload artifacts to the registry.`,
+		Long: `Upload artifacts to the registry.
+When the subject is an index, --subject-platform attaches the artifact to the image for each listed platform,
+and --all-platforms attaches the artifact to every image in the index.
+A separate artifact manifest is pushed for each subject, sharing the same blobs.`,
 		Example: `
 # push a simple artifact by name
 regctl artifact put \
@@ -210,11 +226,24 @@ regctl artifact put \
 regctl artifact put \
   --artifact-type application/spdx+json \
   --subject registry.example.com/repo:v1 \
-  < spdx.json`,
+  < spdx.json
+
+# attach an SBOM to the arm64 image of a multi-platform index
+regctl artifact attach \
+  --artifact-type application/spdx+json \
+  --subject registry.example.com/repo:v1 --subject-platform linux/arm64 \
+  --file spdx-arm64.json
+
+# attach the same artifact to every platform in an index
+regctl artifact attach \
+  --artifact-type application/vnd.example.scan+json \
+  --subject registry.example.com/repo:v1 --all-platforms \
+  --file scan.json`,
 		Args:      cobra.RangeArgs(0, 1),
 		ValidArgs: []string{}, // do not auto complete repository/tag
 		RunE:      opts.runArtifactPut,
 	}
+	cmd.Flags().BoolVar(&opts.allPlatforms, "all-platforms", false, "Attach the artifact to every platform of the subject index")
 	cmd.Flags().StringArrayVar(&opts.annotations, "annotation", []string{}, "Annotation to include on manifest")
 	cmd.Flags().StringVar(&opts.artifactType, "artifact-type", "", "Artifact type (recommended)")
 	_ = cmd.RegisterFlagCompletionFunc("artifact-type", completeArgNone)
@@ -245,6 +274,9 @@ regctl artifact put \
 	_ = cmd.Flags().MarkHidden("refers")
 	cmd.Flags().BoolVar(&opts.stripDirs, "strip-dirs", false, "Strip directories from filenames in file-title")
 	cmd.Flags().StringVar(&opts.subject, "subject", "", "Set the subject to a reference (used for referrer queries)")
+	cmd.Flags().StringArrayVar(&opts.subjectPlatforms, "subject-platform", []string{}, "Attach the artifact to the platform of the subject index, may be repeated")
+	_ = cmd.RegisterFlagCompletionFunc("subject-platform", completeArgPlatform)
+	cmd.MarkFlagsMutuallyExclusive("all-platforms", "platform", "subject-platform")
 	return cmd
 }
 
@@ -578,6 +610,14 @@ func (opts *artifactOpts) runArtifactList(cmd *cobra.Command, args []string) err
 	if opts.latest && opts.sortAnnot != "" {
 		return fmt.Errorf("--latest cannot be used with --sort-annotation")
 	}
+	if opts.sort != "" {
+		if opts.latest || opts.sortAnnot != "" {
+			return fmt.Errorf("--sort cannot be used with --latest or --sort-annotation")
+		}
+		if !slices.Contains([]string{"created", "artifactType", "size"}, opts.sort) {
+			return fmt.Errorf("unsupported sort %q, must be one of created, artifactType, or size", opts.sort)
+		}
+	}
 	// dedup warnings
 	if w := warning.FromContext(ctx); w == nil {
 		ctx = warning.NewContext(ctx, &warning.Warning{Hook: warning.DefaultHook()})
@@ -660,7 +700,13 @@ func (opts *artifactOpts) runArtifactList(cmd *cobra.Command, args []string) err
 		}
 	}
 
+	if opts.sort != "" {
+		artifactListSort(rl.Descriptors, opts.sort, opts.sortDesc)
+	}
+
 	switch opts.format {
+	case "table":
+		return artifactListTable(cmd.OutOrStdout(), rl.Descriptors)
 	case "raw":
 		opts.format = "{{ range $key,$vals := .Manifest.RawHeaders}}{{range $val := $vals}}{{printf \"%s: %s\\n\" $key $val }}{{end}}{{end}}{{printf \"\\n%s\" .Manifest.RawBody}}"
 	case "rawBody", "raw-body", "body":
@@ -671,6 +717,49 @@ func (opts *artifactOpts) runArtifactList(cmd *cobra.Command, args []string) err
 	return template.Writer(cmd.OutOrStdout(), opts.format, rl)
 }
 
+// artifactListSort sorts descriptors by the created annotation, artifactType, or size.
+// Descriptors without a created annotation are sorted last.
+func artifactListSort(dl []descriptor.Descriptor, sortBy string, desc bool) {
+	slices.SortStableFunc(dl, func(a, b descriptor.Descriptor) int {
+		var c int
+		switch sortBy {
+		case "created":
+			aCreated, bCreated := a.Annotations[types.AnnotationCreated], b.Annotations[types.AnnotationCreated]
+			if aCreated == "" || bCreated == "" {
+				// missing annotations are always last, regardless of the direction
+				return strings.Compare(bCreated, aCreated)
+			}
+			c = strings.Compare(aCreated, bCreated)
+		case "artifactType":
+			c = strings.Compare(artifactListType(a), artifactListType(b))
+		case "size":
+			c = cmp.Compare(a.Size, b.Size)
+		}
+		if desc {
+			return -c
+		}
+		return c
+	})
+}
+
+// artifactListTable outputs a row for each descriptor with the digest, type, size, and created annotation.
+func artifactListTable(out io.Writer, dl []descriptor.Descriptor) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Digest\tType\tSize\tCreated\n")
+	for _, d := range dl {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", d.Digest.String(), artifactListType(d), units.HumanSize(float64(d.Size)), d.Annotations[types.AnnotationCreated])
+	}
+	return tw.Flush()
+}
+
+// artifactListType returns the artifactType of a descriptor, falling back to the media type.
+func artifactListType(d descriptor.Descriptor) string {
+	if d.ArtifactType != "" {
+		return d.ArtifactType
+	}
+	return d.MediaType
+}
+
 func (opts *artifactOpts) runArtifactPut(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	hasConfig := false
@@ -734,6 +823,9 @@ func (opts *artifactOpts) runArtifactPut(cmd *cobra.Command, args []string) erro
 	if !rArt.IsSet() && !rSubject.IsSet() {
 		return fmt.Errorf("either a reference or subject must be provided")
 	}
+	if (opts.allPlatforms || len(opts.subjectPlatforms) > 0) && !rSubject.IsSet() {
+		return fmt.Errorf("selecting the platforms of a subject requires a subject%.0w", errs.ErrUnsupported)
+	}
 
 	// validate/set artifactType and config.mediaType
 	if opts.artifactConfigMT != "" && !mediatype.Valid(opts.artifactConfigMT) {
@@ -798,22 +890,12 @@ func (opts *artifactOpts) runArtifactPut(cmd *cobra.Command, args []string) erro
 	rc := opts.rootOpts.newRegClient()
 	defer rc.Close(ctx, r)
 
-	var subjectDesc *descriptor.Descriptor
-	if rSubject.IsSet() {
-		mOpts := []regclient.ManifestOpts{regclient.WithManifestRequireDigest()}
-		if opts.platform != "" {
-			p, err := platform.Parse(opts.platform)
-			if err != nil {
-				return fmt.Errorf("failed to parse platform %s: %w", opts.platform, err)
-			}
-			mOpts = append(mOpts, regclient.WithManifestPlatform(p))
-		}
-		smh, err := rc.ManifestHead(ctx, rSubject, mOpts...)
-		if err != nil {
-			return fmt.Errorf("unable to find subject manifest: %w", err)
-		}
-		d := smh.GetDescriptor()
-		subjectDesc = &descriptor.Descriptor{MediaType: d.MediaType, Digest: d.Digest, Size: d.Size}
+	subjectDescs, err := opts.artifactSubjects(ctx, rc, rSubject)
+	if err != nil {
+		return err
+	}
+	if len(subjectDescs) > 1 && rArt.IsSet() {
+		return fmt.Errorf("pushing an artifact for multiple subjects to a reference is not supported%.0w", errs.ErrUnsupported)
 	}
 
 	// read config, or initialize to an empty json config
@@ -945,112 +1027,183 @@ func (opts *artifactOpts) runArtifactPut(cmd *cobra.Command, args []string) erro
 		blobs = append(blobs, d)
 	}
 
-	mOpts := []manifest.Opts{}
-	switch opts.artifactMT {
-	case mediatype.OCI1Artifact:
-		m := v1.ArtifactManifest{
-			MediaType:    mediatype.OCI1Artifact,
-			ArtifactType: opts.artifactType,
-			Blobs:        blobs,
-			Annotations:  annotations,
-			Subject:      subjectDesc,
-		}
-		mOpts = append(mOpts, manifest.WithOrig(m))
-	case "", mediatype.OCI1Manifest:
-		m := v1.Manifest{
-			Versioned:    v1.ManifestSchemaVersion,
-			MediaType:    mediatype.OCI1Manifest,
-			ArtifactType: opts.artifactType,
-			Config:       confDesc,
-			Layers:       blobs,
-			Annotations:  annotations,
-			Subject:      subjectDesc,
-		}
-		mOpts = append(mOpts, manifest.WithOrig(m))
-	default:
-		return fmt.Errorf("unsupported manifest media type: %s", opts.artifactMT)
+	if opts.byDigest && opts.format == "" {
+		opts.format = "{{ printf \"%s\\n\" .Manifest.GetDescriptor.Digest }}"
 	}
+	for _, subjectDesc := range subjectDescs {
+		rPut := r
+		mOpts := []manifest.Opts{}
+		switch opts.artifactMT {
+		case mediatype.OCI1Artifact:
+			m := v1.ArtifactManifest{
+				MediaType:    mediatype.OCI1Artifact,
+				ArtifactType: opts.artifactType,
+				Blobs:        blobs,
+				Annotations:  annotations,
+				Subject:      subjectDesc,
+			}
+			mOpts = append(mOpts, manifest.WithOrig(m))
+		case "", mediatype.OCI1Manifest:
+			m := v1.Manifest{
+				Versioned:    v1.ManifestSchemaVersion,
+				MediaType:    mediatype.OCI1Manifest,
+				ArtifactType: opts.artifactType,
+				Config:       confDesc,
+				Layers:       blobs,
+				Annotations:  annotations,
+				Subject:      subjectDesc,
+			}
+			mOpts = append(mOpts, manifest.WithOrig(m))
+		default:
+			return fmt.Errorf("unsupported manifest media type: %s", opts.artifactMT)
+		}
 
-	// generate manifest
-	mm, err := manifest.New(mOpts...)
-	if err != nil {
-		return err
-	}
+		// generate manifest
+		mm, err := manifest.New(mOpts...)
+		if err != nil {
+			return err
+		}
 
-	if opts.byDigest || opts.index || rArt.IsZero() {
-		r = r.SetDigest(mm.GetDescriptor().Digest.String())
-	}
+		if opts.byDigest || opts.index || rArt.IsZero() {
+			rPut = rPut.SetDigest(mm.GetDescriptor().Digest.String())
+		}
 
-	// push manifest
-	putOpts := []regclient.ManifestOpts{}
-	if rArt.IsZero() || opts.index {
-		putOpts = append(putOpts, regclient.WithManifestChild())
-	}
-	err = rc.ManifestPut(ctx, r, mm, putOpts...)
-	if err != nil {
-		return err
-	}
+		// push manifest
+		putOpts := []regclient.ManifestOpts{}
+		if rArt.IsZero() || opts.index {
+			putOpts = append(putOpts, regclient.WithManifestChild())
+		}
+		err = rc.ManifestPut(ctx, rPut, mm, putOpts...)
+		if err != nil {
+			return err
+		}
 
-	// create/append to index
-	if opts.index && rArt.IsSet() {
-		// create a descriptor to add
-		d := mm.GetDescriptor()
-		d.ArtifactType = opts.artifactType
-		d.Annotations = annotations
-		if opts.platform != "" {
-			p, err := platform.Parse(opts.platform)
-			if err != nil {
-				return fmt.Errorf("failed to parse platform: %w", err)
-			}
-			d.Platform = &p
-		}
-		mi, err := rc.ManifestGet(ctx, rArt)
-		if err == nil && mi.IsList() {
-			// append to existing index
-			mii, ok := mi.(manifest.Indexer)
-			if !ok {
-				return fmt.Errorf("index to append to is a list but not an Indexer?")
-			}
-			dl, err := mii.GetManifestList()
-			if err != nil {
-				return err
-			}
-			dl = append(dl, d)
-			err = mii.SetManifestList(dl)
-			if err != nil {
-				return err
-			}
-			err = rc.ManifestPut(ctx, rArt, mi)
-			if err != nil {
-				return err
-			}
-		} else {
-			// create a new index
-			mii := v1.Index{
-				Versioned: v1.IndexSchemaVersion,
-				MediaType: mediatype.OCI1ManifestList,
-				Manifests: []descriptor.Descriptor{d},
-			}
-			mi, err := manifest.New(manifest.WithOrig(mii))
-			if err != nil {
-				return err
+		// create/append to index
+		if opts.index && rArt.IsSet() {
+			// create a descriptor to add
+			d := mm.GetDescriptor()
+			d.ArtifactType = opts.artifactType
+			d.Annotations = annotations
+			if opts.platform != "" {
+				p, err := platform.Parse(opts.platform)
+				if err != nil {
+					return fmt.Errorf("failed to parse platform: %w", err)
+				}
+				d.Platform = &p
 			}
-			err = rc.ManifestPut(ctx, rArt, mi)
-			if err != nil {
-				return err
+			mi, err := rc.ManifestGet(ctx, rArt)
+			if err == nil && mi.IsList() {
+				// append to existing index
+				mii, ok := mi.(manifest.Indexer)
+				if !ok {
+					return fmt.Errorf("index to append to is a list but not an Indexer?")
+				}
+				dl, err := mii.GetManifestList()
+				if err != nil {
+					return err
+				}
+				dl = append(dl, d)
+				err = mii.SetManifestList(dl)
+				if err != nil {
+					return err
+				}
+				err = rc.ManifestPut(ctx, rArt, mi)
+				if err != nil {
+					return err
+				}
+			} else {
+				// create a new index
+				mii := v1.Index{
+					Versioned: v1.IndexSchemaVersion,
+					MediaType: mediatype.OCI1ManifestList,
+					Manifests: []descriptor.Descriptor{d},
+				}
+				mi, err := manifest.New(manifest.WithOrig(mii))
+				if err != nil {
+					return err
+				}
+				err = rc.ManifestPut(ctx, rArt, mi)
+				if err != nil {
+					return err
+				}
 			}
 		}
+
+		result := struct {
+			Manifest manifest.Manifest
+		}{
+			Manifest: mm,
+		}
+		err = template.Writer(cmd.OutOrStdout(), opts.format, result)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	result := struct {
-		Manifest manifest.Manifest
-	}{
-		Manifest: mm,
+// artifactSubjects returns the subject descriptors for a put, resolving the selected platforms of an index.
+// A single nil descriptor is returned when there is no subject.
+func (opts *artifactOpts) artifactSubjects(ctx context.Context, rc *regclient.RegClient, rSubject ref.Ref) ([]*descriptor.Descriptor, error) {
+	if !rSubject.IsSet() {
+		return []*descriptor.Descriptor{nil}, nil
 	}
-	if opts.byDigest && opts.format == "" {
-		opts.format = "{{ printf \"%s\\n\" .Manifest.GetDescriptor.Digest }}"
+	subjectDesc := func(d descriptor.Descriptor) *descriptor.Descriptor {
+		return &descriptor.Descriptor{MediaType: d.MediaType, Digest: d.Digest, Size: d.Size}
+	}
+	if opts.allPlatforms {
+		m, err := rc.ManifestGet(ctx, rSubject)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find subject manifest: %w", err)
+		}
+		mi, ok := m.(manifest.Indexer)
+		if !ok || !m.IsList() {
+			return []*descriptor.Descriptor{subjectDesc(m.GetDescriptor())}, nil
+		}
+		dl, err := mi.GetManifestList()
+		if err != nil {
+			return nil, err
+		}
+		descs := []*descriptor.Descriptor{}
+		for _, d := range dl {
+			// skip attestations and other entries without a platform
+			if d.Platform == nil || d.Platform.OS == "unknown" || d.Annotations[dockerReferenceType] == dockerReferenceTypeAttestation {
+				continue
+			}
+			descs = append(descs, subjectDesc(d))
+		}
+		if len(descs) == 0 {
+			return nil, fmt.Errorf("no platforms found in subject %s%.0w", rSubject.CommonName(), errs.ErrNotFound)
+		}
+		return descs, nil
+	}
+	platforms := opts.subjectPlatforms
+	if opts.platform != "" {
+		platforms = []string{opts.platform}
+	}
+	if len(platforms) == 0 {
+		smh, err := rc.ManifestHead(ctx, rSubject, regclient.WithManifestRequireDigest())
+		if err != nil {
+			return nil, fmt.Errorf("unable to find subject manifest: %w", err)
+		}
+		return []*descriptor.Descriptor{subjectDesc(smh.GetDescriptor())}, nil
+	}
+	descs := []*descriptor.Descriptor{}
+	for _, pStr := range platforms {
+		p, err := platform.Parse(pStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse platform %s: %w", pStr, err)
+		}
+		smh, err := rc.ManifestHead(ctx, rSubject, regclient.WithManifestRequireDigest(), regclient.WithManifestPlatform(p))
+		if err != nil {
+			return nil, fmt.Errorf("unable to find subject manifest for platform %s: %w", pStr, err)
+		}
+		d := subjectDesc(smh.GetDescriptor())
+		if !slices.ContainsFunc(descs, func(cur *descriptor.Descriptor) bool { return cur.Digest == d.Digest }) {
+			descs = append(descs, d)
+		}
 	}
-	return template.Writer(cmd.OutOrStdout(), opts.format, result)
+	return descs, nil
 }
 
 func (opts *artifactOpts) runArtifactTree(cmd *cobra.Command, args []string) error {
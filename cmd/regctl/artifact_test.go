@@ -128,19 +128,51 @@ func TestArtifactList(t *testing.T) {
 		{
 			name:        "No referrers",
 			args:        []string{"artifact", "list", "ocidir://../../testdata/testrepo:v1"},
-			expectOut:   "Referrers:",
+			expectOut:   "Digest",
 			outContains: true,
 		},
 		{
 			name:        "Referrers",
 			args:        []string{"artifact", "list", "ocidir://../../testdata/testrepo:v2"},
+			expectOut:   "application/example.signature",
+			outContains: true,
+		},
+		{
+			name:        "Referrers pretty",
+			args:        []string{"artifact", "list", "ocidir://../../testdata/testrepo:v2", "--format", "{{printPretty .}}"},
 			expectOut:   "Referrers:",
 			outContains: true,
 		},
+		{
+			name:      "Sort size",
+			args:      []string{"artifact", "list", "ocidir://../../testdata/testrepo:v2", "--sort", "size", "--format", "{{ range .Descriptors }}{{ .ArtifactType }} {{ end }}"},
+			expectOut: "application/example.sbom application/example.signature",
+		},
+		{
+			name:      "Sort artifactType desc",
+			args:      []string{"artifact", "list", "ocidir://../../testdata/testrepo:v2", "--sort", "artifactType", "--sort-desc", "--format", "{{ range .Descriptors }}{{ .ArtifactType }} {{ end }}"},
+			expectOut: "application/example.signature application/example.sbom",
+		},
+		{
+			name:      "Sort invalid",
+			args:      []string{"artifact", "list", "ocidir://../../testdata/testrepo:v2", "--sort", "name"},
+			expectErr: fmt.Errorf(`unsupported sort "name", must be one of created, artifactType, or size`),
+		},
+		{
+			name:      "Sort with latest",
+			args:      []string{"artifact", "list", "ocidir://../../testdata/testrepo:v2", "--sort", "size", "--latest"},
+			expectErr: fmt.Errorf("--sort cannot be used with --latest or --sort-annotation"),
+		},
+		{
+			name:        "Table filter",
+			args:        []string{"artifact", "list", "ocidir://../../testdata/testrepo:v2", "--filter-artifact-type", "application/example.sbom"},
+			expectOut:   "application/example.sbom",
+			outContains: true,
+		},
 		{
 			name:        "With Digest Tags",
 			args:        []string{"artifact", "list", "ocidir://../../testdata/testrepo:v2", "--digest-tags"},
-			expectOut:   "Referrers:",
+			expectOut:   "application/example.signature",
 			outContains: true,
 		},
 		{
@@ -157,7 +189,7 @@ func TestArtifactList(t *testing.T) {
 		{
 			name:        "External referrers",
 			args:        []string{"artifact", "list", "ocidir://../../testdata/testrepo:v2", "--external", "ocidir://../../testdata/external"},
-			expectOut:   "Referrers:",
+			expectOut:   "application/example.sbom",
 			outContains: true,
 		},
 	}
@@ -416,3 +448,86 @@ func TestArtifactTree(t *testing.T) {
 		})
 	}
 }
+
+func TestArtifactAttach(t *testing.T) {
+	testDir := t.TempDir()
+	srcRef := "ocidir://../../testdata/testrepo:v2"
+	tgtRef := fmt.Sprintf("ocidir://%s/repo:v2", testDir)
+	testFileName := filepath.Join(testDir, "exFile")
+	err := os.WriteFile(testFileName, []byte(`example scan`), 0o600)
+	if err != nil {
+		t.Fatalf("failed creating test file: %v", err)
+	}
+	_, err = cobraTest(t, nil, "image", "copy", srcRef, tgtRef)
+	if err != nil {
+		t.Fatalf("failed to copy image: %v", err)
+	}
+	listArgs := func(p string) []string {
+		return []string{"artifact", "list", tgtRef, "--platform", p, "--filter-artifact-type", "application/example.scan", "--format", "{{len .Descriptors}}"}
+	}
+
+	tt := []struct {
+		name      string
+		args      []string
+		expectErr error
+		expectOut string
+		expect    map[string]string
+	}{
+		{
+			name:   "subject-platform",
+			args:   []string{"--subject-platform", "linux/arm64"},
+			expect: map[string]string{"linux/amd64": "0", "linux/arm64": "1", "linux/arm/v7": "0"},
+		},
+		{
+			name:      "all-platforms",
+			args:      []string{"--all-platforms", "--by-digest"},
+			expectOut: "sha256:",
+			expect:    map[string]string{"linux/amd64": "1", "linux/arm64": "1", "linux/arm/v7": "1"},
+		},
+		{
+			name:      "missing platform",
+			args:      []string{"--subject-platform", "linux/s390x"},
+			expectErr: errs.ErrNotFound,
+		},
+		{
+			name:      "multiple subjects with reference",
+			args:      []string{"--all-platforms", fmt.Sprintf("ocidir://%s/repo:scan", testDir)},
+			expectErr: errs.ErrUnsupported,
+		},
+		{
+			name:      "platform conflict",
+			args:      []string{"--all-platforms", "--platform", "linux/arm64"},
+			expectErr: fmt.Errorf("if any flags in the group [all-platforms platform subject-platform] are set none of the others can be; [all-platforms platform] were all set"),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			args := append([]string{"artifact", "attach", "--artifact-type", "application/example.scan", "--subject", tgtRef, "--file", testFileName}, tc.args...)
+			out, err := cobraTest(t, nil, args...)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Fatalf("command did not fail")
+				}
+				if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
+					t.Fatalf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to attach artifact: %v", err)
+			}
+			if !strings.HasPrefix(out, tc.expectOut) {
+				t.Errorf("unexpected output: %s", out)
+			}
+			for p, count := range tc.expect {
+				out, err := cobraTest(t, nil, listArgs(p)...)
+				if err != nil {
+					t.Fatalf("failed to list artifacts: %v", err)
+				}
+				if out != count {
+					t.Errorf("unexpected referrer count for %s, expected %s, received %s", p, count, out)
+				}
+			}
+		})
+	}
+}
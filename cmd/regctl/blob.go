@@ -2,14 +2,23 @@ package main
 
 import (
 	"archive/tar"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log/slog"
+	"maps"
 	"math"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	// crypto libraries included for go-digest
@@ -19,9 +28,15 @@ import (
 	"github.com/opencontainers/go-digest"
 	"github.com/spf13/cobra"
 
+	"github.com/regclient/regclient"
 	"github.com/regclient/regclient/internal/diff"
+	"github.com/regclient/regclient/pkg/archive"
 	"github.com/regclient/regclient/pkg/template"
+	"github.com/regclient/regclient/types/blob"
 	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/platform"
 	"github.com/regclient/regclient/types/ref"
 	"github.com/regclient/regclient/types/warning"
 )
@@ -31,9 +46,15 @@ type blopOpts struct {
 	diffCtx        int
 	diffFullCtx    bool
 	diffIgnoreTime bool
+	diffSummary    bool
 	format         string
 	mt             string
 	digest         string
+	outputDir      string
+	parallel       int
+	platform       string
+	repos          []string
+	whiteout       string
 }
 
 func NewBlobCmd(rOpts *rootOpts) *cobra.Command {
@@ -46,6 +67,8 @@ func NewBlobCmd(rOpts *rootOpts) *cobra.Command {
 	cmd.AddCommand(newBlobDeleteCmd(rOpts))
 	cmd.AddCommand(newBlobDiffConfigCmd(rOpts))
 	cmd.AddCommand(newBlobDiffLayerCmd(rOpts))
+	cmd.AddCommand(newBlobExistsCmd(rOpts))
+	cmd.AddCommand(newBlobExtractCmd(rOpts))
 	cmd.AddCommand(newBlobGetCmd(rOpts))
 	cmd.AddCommand(newBlobGetFileCmd(rOpts))
 	cmd.AddCommand(newBlobHeadCmd(rOpts))
@@ -127,12 +150,22 @@ func newBlobDiffLayerCmd(rOpts *rootOpts) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "diff-layer <repository> <digest> <repository> <digest>",
 		Short: "diff two tar layers",
-		Long:  `This returns the difference between two layers, comparing the contents of each tar.`,
+		Long: `This returns the difference between two layers, comparing the contents of each tar.
+Each file is listed with the mode, owner, size, timestamp, and digest of the content.
+Symlink and hardlink targets, and extended attributes, are included with the file.
+The --summary flag outputs the number of files with each type of change:
+  added, removed, type, content, link, mode, owner, xattr, timestamp
+A file may be counted in more than one type of change.`,
 		Example: `
 # compare two versions of busybox, ignoring timestamp changes
 regctl blob diff-layer \
   busybox sha256:2354422721e449fa3fa83b84465b9d5bb65ac5415ec93c06f598854312e8957e \
-  busybox sha256:9ad63333ebc97e32b987ae66aa3cff81300e4c2e6d2f2395cef8a3ae18b249fe --ignore-timestamp`,
+  busybox sha256:9ad63333ebc97e32b987ae66aa3cff81300e4c2e6d2f2395cef8a3ae18b249fe --ignore-timestamp
+
+# count the changes between two versions of busybox
+regctl blob diff-layer --summary \
+  busybox sha256:2354422721e449fa3fa83b84465b9d5bb65ac5415ec93c06f598854312e8957e \
+  busybox sha256:9ad63333ebc97e32b987ae66aa3cff81300e4c2e6d2f2395cef8a3ae18b249fe`,
 		Args:      cobra.ExactArgs(4),
 		ValidArgs: []string{}, // do not auto complete repository or digest
 		RunE:      opts.runBlobDiffLayer,
@@ -140,6 +173,79 @@ regctl blob diff-layer \
 	cmd.Flags().IntVarP(&opts.diffCtx, "context", "", 3, "Lines of context")
 	cmd.Flags().BoolVarP(&opts.diffFullCtx, "context-full", "", false, "Show all lines of context")
 	cmd.Flags().BoolVarP(&opts.diffIgnoreTime, "ignore-timestamp", "", false, "Ignore timestamps on files")
+	cmd.Flags().BoolVarP(&opts.diffSummary, "summary", "", false, "Output the number of files for each type of change")
+	return cmd
+}
+
+func newBlobExistsCmd(rOpts *rootOpts) *cobra.Command {
+	opts := blopOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "exists <digest>",
+		Short: "check which repositories contain a blob",
+		Long: `Check if a blob exists in each repository with concurrent head requests.
+The output lists each repository with found, missing, or the error of the request.
+Missing blobs are not an error, the command only fails when a request fails.`,
+		Example: `
+# check which repositories contain a layer
+regctl blob exists \
+  --repos registry.example.org/repo1,registry.example.org/repo2 \
+  sha256:9123ac7c32f74759e6283f04dbf571f18246abe5bb2c779efcb32cd50f3ff13c
+
+# output only the repositories with the blob
+regctl blob exists \
+  --repos registry.example.org/repo1 --repos registry.example.org/repo2 \
+  --format '{{ if .Exists }}{{ .Repo }}{{ end }}' \
+  sha256:9123ac7c32f74759e6283f04dbf571f18246abe5bb2c779efcb32cd50f3ff13c`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{}, // do not auto complete digest
+		RunE:      opts.runBlobExists,
+	}
+	cmd.Flags().StringVarP(&opts.format, "format", "", "", "Format output of each repository with go template syntax")
+	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	cmd.Flags().IntVar(&opts.parallel, "parallel", 4, "Number of concurrent requests")
+	_ = cmd.RegisterFlagCompletionFunc("parallel", completeArgNone)
+	cmd.Flags().StringSliceVar(&opts.repos, "repos", []string{}, "Comma separated list of repositories to check, may be repeated")
+	_ = cmd.RegisterFlagCompletionFunc("repos", completeArgNone)
+	_ = cmd.MarkFlagRequired("repos")
+	return cmd
+}
+
+func newBlobExtractCmd(rOpts *rootOpts) *cobra.Command {
+	opts := blopOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "extract <image_ref> <layer-index|digest>",
+		Short: "extract a layer to a directory",
+		Long: `Extract the contents of a layer to a directory.
+The layer may be selected by digest, or by the index of the layer in the image manifest.
+The layer is streamed from the registry and uncompressed while extracting.
+Whiteout files may be kept as regular files, skipped, or applied to delete
+files in the output directory from previously extracted layers.
+This command is typically run as "regctl layer extract" using the layer alias
+of the blob command.`,
+		Example: `
+# extract the first layer of the local platform image
+regctl layer extract alpine 0 --output-dir ./rootfs
+
+# extract a layer by digest, applying whiteouts to an existing directory
+regctl layer extract alpine \
+  sha256:9123ac7c32f74759e6283f04dbf571f18246abe5bb2c779efcb32cd50f3ff13c \
+  --output-dir ./rootfs --whiteout apply`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: rOpts.completeArgTag,
+		RunE:              opts.runBlobExtract,
+	}
+	cmd.Flags().StringVar(&opts.outputDir, "output-dir", "", "Directory to extract the layer")
+	_ = cmd.MarkFlagRequired("output-dir")
+	cmd.Flags().StringVarP(&opts.platform, "platform", "p", "", "Specify platform (e.g. linux/amd64 or local)")
+	_ = cmd.RegisterFlagCompletionFunc("platform", completeArgPlatform)
+	cmd.Flags().StringVar(&opts.whiteout, "whiteout", "keep", "Whiteout handling: keep, skip, or apply")
+	_ = cmd.RegisterFlagCompletionFunc("whiteout", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"keep", "skip", "apply"}, cobra.ShellCompDirectiveNoFileComp
+	})
 	return cmd
 }
 
@@ -183,18 +289,26 @@ func newBlobGetFileCmd(rOpts *rootOpts) *cobra.Command {
 		Use:     "get-file <repository> <digest> <file> [out-file]",
 		Aliases: []string{"cat"},
 		Short:   "get a file from a layer",
-		Long:    `This returns a requested file from a layer.`,
+		Long: `This returns a requested file from a layer.
+The file may be a glob pattern (e.g. "/etc/*.conf") to extract multiple files with "--output-dir".
+Files are written to the output directory using their path in the layer.`,
 		Example: `
 # retrieve the contents of /etc/alpine-release
 regctl blob get-file alpine \
   sha256:9123ac7c32f74759e6283f04dbf571f18246abe5bb2c779efcb32cd50f3ff13c \
-  /etc/alpine-release`,
+  /etc/alpine-release
+
+# extract all files in /etc/apk to the local directory
+regctl blob get-file alpine \
+  sha256:9123ac7c32f74759e6283f04dbf571f18246abe5bb2c779efcb32cd50f3ff13c \
+  '/etc/apk/*' --output-dir .`,
 		Args:      cobra.RangeArgs(3, 4),
 		ValidArgs: []string{}, // do not auto complete repository, digest, or filenames
 		RunE:      opts.runBlobGetFile,
 	}
 	cmd.Flags().StringVarP(&opts.format, "format", "", "", "Format output with go template syntax")
 	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	cmd.Flags().StringVar(&opts.outputDir, "output-dir", "", "Directory to extract matching files")
 	return cmd
 }
 
@@ -381,62 +495,101 @@ func (opts *blopOpts) runBlobDiffLayer(cmd *cobra.Command, args []string) error
 	rc := opts.rootOpts.newRegClient()
 
 	// open both blobs, and generate reports of each content
-	d1, err := digest.Parse(args[1])
-	if err != nil {
-		return err
-	}
-	b1, err := rc.BlobGet(ctx, r1, descriptor.Descriptor{Digest: d1})
+	entries1, err := blobLayerLoad(ctx, rc, r1, args[1])
 	if err != nil {
 		return err
 	}
-	defer b1.Close()
-	btr1, err := b1.ToTarReader()
+	entries2, err := blobLayerLoad(ctx, rc, r2, args[3])
 	if err != nil {
 		return err
 	}
-	tr1, err := btr1.GetTarReader()
-	if err != nil {
-		return err
-	}
-	rep1, err := opts.blobReportLayer(tr1)
-	if err != nil {
-		return err
-	}
-	err = btr1.Close()
-	if err != nil {
-		return err
+
+	if opts.diffSummary {
+		counts := blobLayerSummary(entries1, entries2, opts.diffIgnoreTime)
+		tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		for _, change := range blobLayerChanges {
+			fmt.Fprintf(tw, "%s\t%d\n", change, counts[change])
+		}
+		return tw.Flush()
 	}
 
-	d2, err := digest.Parse(args[3])
-	if err != nil {
-		return err
+	// run diff and output result
+	rep1 := []string{}
+	for _, e := range entries1 {
+		rep1 = append(rep1, e.report(opts.diffIgnoreTime))
 	}
-	b2, err := rc.BlobGet(ctx, r2, descriptor.Descriptor{Digest: d2})
-	if err != nil {
-		return err
+	rep2 := []string{}
+	for _, e := range entries2 {
+		rep2 = append(rep2, e.report(opts.diffIgnoreTime))
 	}
-	defer b2.Close()
-	btr2, err := b2.ToTarReader()
+	lDiff := diff.Diff(rep1, rep2, diffOpts...)
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), strings.Join(lDiff, "\n"))
+	return err
+}
+
+type blobExistsResult struct {
+	Repo   string
+	Exists bool
+	Error  string
+}
+
+func (opts *blopOpts) runBlobExists(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	d, err := digest.Parse(args[0])
 	if err != nil {
-		return err
+		return fmt.Errorf("invalid digest %s: %w%.0w", args[0], err, ErrInvalidInput)
 	}
-	tr2, err := btr2.GetTarReader()
-	if err != nil {
-		return err
+	ops := []regclient.BatchOp{}
+	results := []*blobExistsResult{}
+	for _, repo := range opts.repos {
+		r, err := ref.New(repo)
+		if err != nil {
+			return err
+		}
+		r = r.SetTag("")
+		ops = append(ops, regclient.BatchOpBlobHead(r, descriptor.Descriptor{Digest: d}))
+		results = append(results, &blobExistsResult{Repo: r.CommonName()})
+	}
+	opts.rootOpts.log.Debug("Blob exists",
+		slog.String("digest", d.String()),
+		slog.Int("repos", len(ops)),
+		slog.Int("parallel", opts.parallel))
+	rc := opts.rootOpts.newRegClient()
+	report, _ := rc.Batch(ctx, ops, regclient.BatchWithWorkers(opts.parallel))
+	failed := 0
+	for i, res := range results {
+		batchErr := report.Results[i].Err
+		switch {
+		case batchErr == nil:
+			res.Exists = true
+		case errors.Is(batchErr, errs.ErrNotFound) || errors.Is(batchErr, fs.ErrNotExist):
+		default:
+			failed++
+			res.Error = batchErr.Error()
+		}
 	}
-	rep2, err := opts.blobReportLayer(tr2)
-	if err != nil {
-		return err
+
+	format := opts.format
+	if format == "" {
+		format = `{{ .Repo }} {{ if .Error }}error: {{ .Error }}{{ else if .Exists }}found{{ else }}missing{{ end }}`
+	}
+	buf := &bytes.Buffer{}
+	for _, res := range results {
+		buf.Reset()
+		if err := template.Writer(buf, format, res); err != nil {
+			return err
+		}
+		// each result is output on a single line, skipping empty results
+		line := strings.TrimSuffix(buf.String(), "\n")
+		if line == "" {
+			continue
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), line)
 	}
-	err = btr2.Close()
-	if err != nil {
-		return err
+	if failed > 0 {
+		return fmt.Errorf("failed to check %d of %d repositories", failed, len(results))
 	}
-
-	// run diff and output result
-	lDiff := diff.Diff(rep1, rep2, diffOpts...)
-	_, err = fmt.Fprintln(cmd.OutOrStdout(), strings.Join(lDiff, "\n"))
-	return err
+	return nil
 }
 
 func (opts *blopOpts) runBlobGet(cmd *cobra.Command, args []string) error {
@@ -481,6 +634,136 @@ func (opts *blopOpts) runBlobGet(cmd *cobra.Command, args []string) error {
 	return template.Writer(cmd.OutOrStdout(), opts.format, blob)
 }
 
+func (opts *blopOpts) runBlobExtract(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	r, err := ref.New(args[0])
+	if err != nil {
+		return err
+	}
+	tarOpts := []archive.TarOpts{}
+	switch opts.whiteout {
+	case "", "keep":
+	case "skip":
+		tarOpts = append(tarOpts, archive.TarWhiteout(archive.WhiteoutSkip))
+	case "apply":
+		tarOpts = append(tarOpts, archive.TarWhiteout(archive.WhiteoutApply))
+	default:
+		return fmt.Errorf("unknown whiteout handling %s%.0w", opts.whiteout, errs.ErrUnsupported)
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, r)
+
+	// lookup the layer descriptor
+	var d descriptor.Descriptor
+	if dig, err := digest.Parse(args[1]); err == nil {
+		d = descriptor.Descriptor{Digest: dig}
+	} else {
+		i, err := strconv.Atoi(args[1])
+		if err != nil || i < 0 {
+			return fmt.Errorf("layer must be an index or digest: %s", args[1])
+		}
+		mOpts := []regclient.ManifestOpts{}
+		if opts.platform != "" {
+			p, err := platform.Parse(opts.platform)
+			if err != nil {
+				return err
+			}
+			mOpts = append(mOpts, regclient.WithManifestPlatform(p))
+		}
+		m, err := rc.ManifestGet(ctx, r, mOpts...)
+		if err != nil {
+			return err
+		}
+		if m.IsList() {
+			p, err := platform.Parse("local")
+			if err != nil {
+				return err
+			}
+			m, err = rc.ManifestGet(ctx, r, regclient.WithManifestPlatform(p))
+			if err != nil {
+				return err
+			}
+		}
+		mi, ok := m.(manifest.Imager)
+		if !ok {
+			return fmt.Errorf("manifest does not contain layers: %s%.0w", r.CommonName(), errs.ErrUnsupportedMediaType)
+		}
+		layers, err := mi.GetLayers()
+		if err != nil {
+			return err
+		}
+		if i >= len(layers) {
+			return fmt.Errorf("layer index %d exceeds the %d layers in %s", i, len(layers), r.CommonName())
+		}
+		d = layers[i]
+	}
+
+	opts.rootOpts.log.Debug("Extract layer",
+		slog.String("ref", r.CommonName()),
+		slog.String("digest", d.Digest.String()),
+		slog.String("dir", opts.outputDir))
+	err = os.MkdirAll(opts.outputDir, 0o755)
+	if err != nil {
+		return err
+	}
+	br, err := rc.BlobGet(ctx, r, d)
+	if err != nil {
+		return err
+	}
+	defer br.Close()
+	return archive.Extract(ctx, opts.outputDir, br, tarOpts...)
+}
+
+// blobExtractFiles writes each file in the tar matching a glob pattern to the output directory.
+func blobExtractFiles(btr *blob.BTarReader, pattern, outputDir string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %s: %w", pattern, err)
+	}
+	tr, err := btr.GetTarReader()
+	if err != nil {
+		return err
+	}
+	found := false
+	for {
+		th, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(path.Clean("/"+th.Name), "/")
+		if th.Typeflag != tar.TypeReg || strings.HasPrefix(path.Base(name), ".wh.") {
+			continue
+		}
+		if match, _ := path.Match(pattern, name); !match {
+			continue
+		}
+		found = true
+		fn := filepath.Join(outputDir, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(fn), 0o755)
+		if err != nil {
+			return err
+		}
+		//#nosec G304 filename is limited to the output directory
+		fh, err := os.Create(fn)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(fh, tr)
+		errC := fh.Close()
+		if err != nil {
+			return err
+		}
+		if errC != nil {
+			return errC
+		}
+	}
+	if !found {
+		return fmt.Errorf("no files matched %s%.0w", pattern, errs.ErrFileNotFound)
+	}
+	return nil
+}
+
 func (opts *blopOpts) runBlobGetFile(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	r, err := ref.New(args[0])
@@ -509,6 +792,18 @@ func (opts *blopOpts) runBlobGetFile(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if opts.outputDir != "" {
+		err = blobExtractFiles(tr, filename, opts.outputDir)
+		if err != nil {
+			return err
+		}
+		if err := tr.Close(); err != nil {
+			return err
+		}
+		return blob.Close()
+	} else if strings.ContainsAny(filename, "*?[") {
+		return fmt.Errorf("output-dir is required for a glob pattern: %s", args[2])
+	}
 	th, rdr, err := tr.ReadFile(filename)
 	if err != nil {
 		return err
@@ -608,10 +903,57 @@ func (opts *blopOpts) runBlobPut(cmd *cobra.Command, args []string) error {
 	return template.Writer(cmd.OutOrStdout(), opts.format, result)
 }
 
-func (opts *blopOpts) blobReportLayer(tr *tar.Reader) ([]string, error) {
-	report := []string{}
+// blobLayerChanges are the types of changes counted by the diff-layer summary, in the output order.
+var blobLayerChanges = []string{"added", "removed", "type", "content", "link", "mode", "owner", "xattr", "timestamp"}
+
+// blobLayerEntry is a file in a layer.
+type blobLayerEntry struct {
+	name     string
+	typeflag byte
+	mode     fs.FileMode
+	uid, gid int
+	size     int64
+	modTime  time.Time
+	linkname string
+	xattrs   map[string]string
+	digest   digest.Digest
+}
+
+// blobLayerLoad pulls a layer and returns the entries from the tar.
+func blobLayerLoad(ctx context.Context, rc *regclient.RegClient, r ref.Ref, dig string) ([]blobLayerEntry, error) {
+	d, err := digest.Parse(dig)
+	if err != nil {
+		return nil, err
+	}
+	b, err := rc.BlobGet(ctx, r, descriptor.Descriptor{Digest: d})
+	if err != nil {
+		return nil, err
+	}
+	defer b.Close()
+	btr, err := b.ToTarReader()
+	if err != nil {
+		return nil, err
+	}
+	tr, err := btr.GetTarReader()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := blobLayerEntries(tr)
+	if err != nil {
+		return nil, err
+	}
+	err = btr.Close()
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// blobLayerEntries reads each header in a tar and the digest of the content.
+func blobLayerEntries(tr *tar.Reader) ([]blobLayerEntry, error) {
+	entries := []blobLayerEntry{}
 	if tr == nil {
-		return report, nil
+		return entries, nil
 	}
 	for {
 		th, err := tr.Next()
@@ -619,28 +961,111 @@ func (opts *blopOpts) blobReportLayer(tr *tar.Reader) ([]string, error) {
 			if err == io.EOF {
 				break
 			}
-			return report, err
+			return entries, err
 		}
 		if th.Mode < 0 || th.Mode > math.MaxUint32 {
-			return report, fmt.Errorf("integer conversion overflow/underflow (file mode = %d)", th.Mode)
+			return entries, fmt.Errorf("integer conversion overflow/underflow (file mode = %d)", th.Mode)
+		}
+		e := blobLayerEntry{
+			name:     th.Name,
+			typeflag: th.Typeflag,
+			mode:     th.FileInfo().Mode(),
+			uid:      th.Uid,
+			gid:      th.Gid,
+			size:     th.Size,
+			modTime:  th.ModTime,
+			xattrs:   map[string]string{},
 		}
-		line := fmt.Sprintf("%s %d/%d %8d", fs.FileMode(th.Mode).String(), th.Uid, th.Gid, th.Size)
-		if !opts.diffIgnoreTime {
-			line += " " + th.ModTime.Format(time.RFC3339)
+		if th.Typeflag == tar.TypeSymlink || th.Typeflag == tar.TypeLink {
+			e.linkname = th.Linkname
+		}
+		for k, v := range th.PAXRecords {
+			if name, ok := strings.CutPrefix(k, "SCHILY.xattr."); ok {
+				e.xattrs[name] = v
+			}
 		}
-		line += fmt.Sprintf(" %-40s", th.Name)
 		if th.Size > 0 {
 			d := digest.Canonical.Digester()
 			size, err := io.Copy(d.Hash(), tr)
 			if err != nil {
-				return report, fmt.Errorf("failed to read %s: %w", th.Name, err)
+				return entries, fmt.Errorf("failed to read %s: %w", th.Name, err)
 			}
 			if size != th.Size {
-				return report, fmt.Errorf("size mismatch for %s, expected %d, read %d", th.Name, th.Size, size)
+				return entries, fmt.Errorf("size mismatch for %s, expected %d, read %d", th.Name, th.Size, size)
 			}
-			line += " " + d.Digest().String()
+			e.digest = d.Digest()
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// report returns a single line describing the entry for the diff output.
+func (e blobLayerEntry) report(ignoreTime bool) string {
+	line := fmt.Sprintf("%s %d/%d %8d", e.mode.String(), e.uid, e.gid, e.size)
+	if !ignoreTime {
+		line += " " + e.modTime.Format(time.RFC3339)
+	}
+	line += fmt.Sprintf(" %-40s", e.name)
+	switch e.typeflag {
+	case tar.TypeSymlink:
+		line += " -> " + e.linkname
+	case tar.TypeLink:
+		line += " link to " + e.linkname
+	}
+	if e.digest != "" {
+		line += " " + e.digest.String()
+	}
+	for _, k := range slices.Sorted(maps.Keys(e.xattrs)) {
+		line += fmt.Sprintf(" xattr:%s=%q", k, e.xattrs[k])
+	}
+	return line
+}
+
+// blobLayerSummary counts the files for each type of change between two layers.
+// When a name is repeated in a layer, the last entry is compared.
+func blobLayerSummary(entries1, entries2 []blobLayerEntry, ignoreTime bool) map[string]int {
+	counts := map[string]int{}
+	byName1 := map[string]blobLayerEntry{}
+	for _, e := range entries1 {
+		byName1[path.Clean(e.name)] = e
+	}
+	byName2 := map[string]blobLayerEntry{}
+	for _, e := range entries2 {
+		byName2[path.Clean(e.name)] = e
+	}
+	for name, e1 := range byName1 {
+		e2, ok := byName2[name]
+		if !ok {
+			counts["removed"]++
+			continue
+		}
+		if e1.mode.Type() != e2.mode.Type() || e1.typeflag != e2.typeflag {
+			counts["type"]++
+		}
+		if e1.digest != e2.digest || e1.size != e2.size {
+			counts["content"]++
+		}
+		if e1.linkname != e2.linkname {
+			counts["link"]++
+		}
+		if e1.mode.Perm() != e2.mode.Perm() || e1.mode&(fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky) != e2.mode&(fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky) {
+			counts["mode"]++
+		}
+		if e1.uid != e2.uid || e1.gid != e2.gid {
+			counts["owner"]++
+		}
+		if !maps.Equal(e1.xattrs, e2.xattrs) {
+			counts["xattr"]++
+		}
+		if !ignoreTime && !e1.modTime.Equal(e2.modTime) {
+			counts["timestamp"]++
+		}
+	}
+	for name := range byName2 {
+		if _, ok := byName1[name]; !ok {
+			counts["added"]++
 		}
-		report = append(report, line)
 	}
-	return report, nil
+	return counts
 }
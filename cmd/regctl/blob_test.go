@@ -1,8 +1,17 @@
 package main
 
 import (
+	"archive/tar"
 	"bytes"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/regclient/regclient/types/errs"
 )
 
 func TestBlob(t *testing.T) {
@@ -51,6 +60,56 @@ func TestBlob(t *testing.T) {
 		}
 	})
 
+	t.Run("Extract", func(t *testing.T) {
+		// get files matching a glob
+		dir := t.TempDir()
+		_, err := cobraTest(t, nil, "blob", "get-file", repo, digBaseA, "*.txt", "--output-dir", dir)
+		if err != nil {
+			t.Fatalf("failed to blob get-file: %v", err)
+		}
+		b, err := os.ReadFile(filepath.Join(dir, "base.txt"))
+		if err != nil {
+			t.Fatalf("failed to read extracted file: %v", err)
+		}
+		if strings.TrimSpace(string(b)) != "A" {
+			t.Errorf("unexpected extracted file, expected A, received %s", string(b))
+		}
+		// glob without an output dir
+		_, err = cobraTest(t, nil, "blob", "get-file", repo, digBaseA, "*.txt")
+		if err == nil {
+			t.Errorf("get-file with a glob did not fail without an output dir")
+		}
+		// glob with no matches
+		_, err = cobraTest(t, nil, "blob", "get-file", repo, digBaseA, "missing*", "--output-dir", t.TempDir())
+		if !errors.Is(err, errs.ErrFileNotFound) {
+			t.Errorf("unexpected error for missing glob: %v", err)
+		}
+		// extract a layer by index
+		dir = t.TempDir()
+		_, err = cobraTest(t, nil, "layer", "extract", repo+":b1", "0", "--platform", "linux/amd64", "--output-dir", dir)
+		if err != nil {
+			t.Fatalf("failed to layer extract: %v", err)
+		}
+		b, err = os.ReadFile(filepath.Join(dir, "base.txt"))
+		if err != nil {
+			t.Fatalf("failed to read extracted file: %v", err)
+		}
+		if strings.TrimSpace(string(b)) != "A" {
+			t.Errorf("unexpected extracted file, expected A, received %s", string(b))
+		}
+		// extract a layer by digest
+		dir = t.TempDir()
+		_, err = cobraTest(t, nil, "blob", "extract", repo, digBaseB, "--output-dir", dir, "--whiteout", "apply")
+		if err != nil {
+			t.Fatalf("failed to blob extract: %v", err)
+		}
+		// layer index out of range
+		_, err = cobraTest(t, nil, "blob", "extract", repo+":b1", "99", "--platform", "linux/amd64", "--output-dir", t.TempDir())
+		if err == nil {
+			t.Errorf("blob extract did not fail with an invalid index")
+		}
+	})
+
 	t.Run("Put and Delete", func(t *testing.T) {
 		dir := t.TempDir()
 		bufStr := "hello world"
@@ -108,6 +167,14 @@ func TestBlob(t *testing.T) {
 		if out == "" {
 			t.Errorf("no output received from diff-layer")
 		}
+		// summarize the changes
+		out, err = cobraTest(t, nil, "blob", "diff-layer", "--summary", repo, digBaseA, repo, digBaseB)
+		if err != nil {
+			t.Fatalf("failed to diff layers: %v", err)
+		}
+		if !strings.HasPrefix(out, "added ") || !strings.Contains(out, "\ntimestamp ") {
+			t.Errorf("unexpected summary: %s", out)
+		}
 		// diff the config between two images
 		out, err = cobraTest(t, nil, "blob", "diff-config", repo, digConf1, repo, digConf3)
 		if err != nil {
@@ -118,3 +185,158 @@ func TestBlob(t *testing.T) {
 		}
 	})
 }
+
+func TestBlobLayerSummary(t *testing.T) {
+	t.Parallel()
+	tm := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	type file struct {
+		hdr  tar.Header
+		body string
+	}
+	layer := func(files []file) []blobLayerEntry {
+		t.Helper()
+		buf := &bytes.Buffer{}
+		tw := tar.NewWriter(buf)
+		for _, f := range files {
+			f.hdr.Size = int64(len(f.body))
+			if f.hdr.ModTime.IsZero() {
+				f.hdr.ModTime = tm
+			}
+			if f.hdr.Mode == 0 {
+				f.hdr.Mode = 0o644
+			}
+			if err := tw.WriteHeader(&f.hdr); err != nil {
+				t.Fatalf("failed to write header: %v", err)
+			}
+			if _, err := tw.Write([]byte(f.body)); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatalf("failed to close tar: %v", err)
+		}
+		entries, err := blobLayerEntries(tar.NewReader(buf))
+		if err != nil {
+			t.Fatalf("failed to read entries: %v", err)
+		}
+		return entries
+	}
+	entries1 := layer([]file{
+		{hdr: tar.Header{Name: "unchanged", Typeflag: tar.TypeReg}, body: "same"},
+		{hdr: tar.Header{Name: "removed", Typeflag: tar.TypeReg}, body: "old"},
+		{hdr: tar.Header{Name: "content", Typeflag: tar.TypeReg}, body: "v1"},
+		{hdr: tar.Header{Name: "mode", Typeflag: tar.TypeReg}, body: "x"},
+		{hdr: tar.Header{Name: "owner", Typeflag: tar.TypeReg}, body: "x"},
+		{hdr: tar.Header{Name: "xattr", Typeflag: tar.TypeReg, PAXRecords: map[string]string{"SCHILY.xattr.security.capability": "a"}}, body: "x"},
+		{hdr: tar.Header{Name: "time", Typeflag: tar.TypeReg}, body: "x"},
+		{hdr: tar.Header{Name: "symlink", Typeflag: tar.TypeSymlink, Linkname: "unchanged"}},
+		{hdr: tar.Header{Name: "hardlink", Typeflag: tar.TypeLink, Linkname: "unchanged"}},
+		{hdr: tar.Header{Name: "type", Typeflag: tar.TypeSymlink, Linkname: "unchanged"}},
+	})
+	entries2 := layer([]file{
+		{hdr: tar.Header{Name: "unchanged", Typeflag: tar.TypeReg}, body: "same"},
+		{hdr: tar.Header{Name: "added", Typeflag: tar.TypeReg}, body: "new"},
+		{hdr: tar.Header{Name: "content", Typeflag: tar.TypeReg}, body: "v2"},
+		{hdr: tar.Header{Name: "mode", Typeflag: tar.TypeReg, Mode: 0o755}, body: "x"},
+		{hdr: tar.Header{Name: "owner", Typeflag: tar.TypeReg, Uid: 1000, Gid: 1000}, body: "x"},
+		{hdr: tar.Header{Name: "xattr", Typeflag: tar.TypeReg, PAXRecords: map[string]string{"SCHILY.xattr.security.capability": "b"}}, body: "x"},
+		{hdr: tar.Header{Name: "time", Typeflag: tar.TypeReg, ModTime: tm.Add(time.Hour)}, body: "x"},
+		{hdr: tar.Header{Name: "symlink", Typeflag: tar.TypeSymlink, Linkname: "content"}},
+		{hdr: tar.Header{Name: "hardlink", Typeflag: tar.TypeLink, Linkname: "content"}},
+		{hdr: tar.Header{Name: "type", Typeflag: tar.TypeReg}, body: "x"},
+	})
+	tt := []struct {
+		name       string
+		ignoreTime bool
+		expect     map[string]int
+	}{
+		{
+			name:   "all",
+			expect: map[string]int{"added": 1, "removed": 1, "type": 1, "content": 2, "link": 3, "mode": 1, "owner": 1, "xattr": 1, "timestamp": 1},
+		},
+		{
+			name:       "ignore time",
+			ignoreTime: true,
+			expect:     map[string]int{"added": 1, "removed": 1, "type": 1, "content": 2, "link": 3, "mode": 1, "owner": 1, "xattr": 1},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			counts := blobLayerSummary(entries1, entries2, tc.ignoreTime)
+			if !maps.Equal(counts, tc.expect) {
+				t.Errorf("unexpected summary, expected %v, received %v", tc.expect, counts)
+			}
+		})
+	}
+	t.Run("report", func(t *testing.T) {
+		for _, e := range entries2 {
+			line := e.report(false)
+			switch e.name {
+			case "symlink":
+				if !strings.Contains(line, " -> content") || !strings.HasPrefix(line, "L") {
+					t.Errorf("unexpected symlink report: %s", line)
+				}
+			case "hardlink":
+				if !strings.Contains(line, " link to content") {
+					t.Errorf("unexpected hardlink report: %s", line)
+				}
+			case "xattr":
+				if !strings.Contains(line, ` xattr:security.capability="b"`) {
+					t.Errorf("unexpected xattr report: %s", line)
+				}
+			}
+		}
+	})
+}
+
+func TestBlobExists(t *testing.T) {
+	repoA := "ocidir://../../testdata/testrepo"
+	repoB := "ocidir://../../testdata/external"
+	dig := "sha256:01399f08c7986d71d9b739a0899cb5b76eb2aa711d07dfe66b8f143b8a34b2f3"
+	tt := []struct {
+		name      string
+		args      []string
+		expectErr error
+		expectOut string
+	}{
+		{
+			name:      "found and missing",
+			args:      []string{"blob", "exists", "--repos", repoA + "," + repoB, dig},
+			expectOut: repoA + " found\n" + repoB + " missing",
+		},
+		{
+			name:      "format",
+			args:      []string{"blob", "exists", "--repos", repoB, "--repos", repoA, "--format", "{{ if .Exists }}{{ .Repo }}{{ end }}", dig},
+			expectOut: repoA,
+		},
+		{
+			name:      "invalid digest",
+			args:      []string{"blob", "exists", "--repos", repoA, "sha256:invalid"},
+			expectErr: ErrInvalidInput,
+		},
+		{
+			name:      "missing repos",
+			args:      []string{"blob", "exists", dig},
+			expectErr: errors.New(`required flag(s) "repos" not set`),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := cobraTest(t, nil, tc.args...)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Errorf("did not receive expected error: %v", tc.expectErr)
+				} else if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
+					t.Errorf("unexpected error, received %v, expected %v", err, tc.expectErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("returned unexpected error: %v", err)
+			}
+			if out != tc.expectOut {
+				t.Errorf("unexpected output, expected %s, received %s", tc.expectOut, out)
+			}
+		})
+	}
+}
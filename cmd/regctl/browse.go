@@ -0,0 +1,674 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/internal/ascii"
+	"github.com/regclient/regclient/internal/units"
+	"github.com/regclient/regclient/pkg/archive"
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/mediatype"
+	"github.com/regclient/regclient/types/ref"
+)
+
+type browseOpts struct {
+	rootOpts *rootOpts
+}
+
+func NewBrowseCmd(rOpts *rootOpts) *cobra.Command {
+	opts := browseOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "browse <registry|repository>",
+		Short: "browse a registry in a terminal UI",
+		Long: `Browse the repositories, tags, manifests, layers, and referrers of a registry in an interactive terminal UI.
+Each view is loaded when it is opened, so large registries can be explored without listing everything.
+A registry lists the repositories, a repository lists the tags, and a reference with a tag or digest opens the manifest.
+Keys:
+  up/down, k/j: move
+  pgup/pgdn, home/end: move a page or to the first and last entry
+  enter, right, l: open the selected entry
+  left, h, backspace: return to the previous view
+  /: filter the entries, enter to keep the filter, esc to clear it
+  q, ctrl-c: quit`,
+		Example: `
+# browse the repositories of a registry
+regctl browse registry.example.org
+
+# browse the tags of a repository
+regctl browse registry.example.org/repo
+
+# browse an image in an OCI Layout
+regctl browse ocidir://repo:v1`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: rOpts.completeArgTag,
+		RunE:              opts.runBrowse,
+	}
+	return cmd
+}
+
+func (opts *browseOpts) runBrowse(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	in, out := cmd.InOrStdin(), cmd.OutOrStdout()
+	if !ascii.IsReaderTerminal(in) || !ascii.IsWriterTerminal(out) {
+		return fmt.Errorf("browse requires an interactive terminal%.0w", ErrInvalidInput)
+	}
+	rc := opts.rootOpts.newRegClient()
+	opts.rootOpts.log.Debug("Browse",
+		slog.String("arg", args[0]))
+	// load the first views before switching the terminal to raw mode so errors are displayed normally
+	b := &browser{
+		rc:  rc,
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+	views, closeRef, err := browseStart(ctx, rc, args[0])
+	if err != nil {
+		return err
+	}
+	if closeRef.IsSet() {
+		defer rc.Close(ctx, closeRef)
+	}
+	b.stack = views
+
+	//#nosec G115 false positive
+	fdIn := int(in.(interface{ Fd() uintptr }).Fd())
+	//#nosec G115 false positive
+	fdOut := int(out.(interface{ Fd() uintptr }).Fd())
+	state, err := term.MakeRaw(fdIn)
+	if err != nil {
+		return fmt.Errorf("failed to configure terminal: %w", err)
+	}
+	defer func() { _ = term.Restore(fdIn, state) }()
+	b.size = func() (int, int) {
+		w, h, err := term.GetSize(fdOut)
+		if err != nil || w <= 0 || h <= 0 {
+			return 80, 24
+		}
+		return w, h
+	}
+	// use the alternate screen and hide the cursor
+	fmt.Fprint(out, "\033[?1049h\033[?25l")
+	defer fmt.Fprint(out, "\033[?25h\033[?1049l")
+	return b.run(ctx)
+}
+
+// browseStart returns the initial views for a registry, repository, or image reference.
+// The returned ref is set for repositories that should be closed when the browser exits.
+func browseStart(ctx context.Context, rc *regclient.RegClient, arg string) ([]*browseView, ref.Ref, error) {
+	if !strings.Contains(arg, "/") {
+		v, err := browseRegistry(ctx, rc, arg)
+		if err != nil {
+			return nil, ref.Ref{}, err
+		}
+		return []*browseView{v}, ref.Ref{}, nil
+	}
+	r, err := ref.New(arg)
+	if err != nil {
+		return nil, ref.Ref{}, err
+	}
+	vRepo, err := browseRepo(ctx, rc, r)
+	if err != nil {
+		return nil, r, err
+	}
+	views := []*browseView{vRepo}
+	// an explicit tag or digest opens the manifest
+	if strings.ContainsAny(arg[strings.LastIndex(arg, "/")+1:], ":@") {
+		vManifest, err := browseManifest(ctx, rc, r)
+		if err != nil {
+			return nil, r, err
+		}
+		views = append(views, vManifest)
+	}
+	return views, r, nil
+}
+
+// browseItem is an entry in a view, entries with an open function load another view when selected.
+type browseItem struct {
+	label string
+	open  func(ctx context.Context) (*browseView, error)
+}
+
+// browseView is a list of entries with the current selection.
+type browseView struct {
+	title  string
+	items  []browseItem
+	cursor int    // cursor is the index of the selected entry in the filtered list.
+	offset int    // offset is the index of the first displayed entry in the filtered list.
+	filter string // filter limits the entries to labels containing the string.
+}
+
+// visible returns the entries matching the filter.
+func (v *browseView) visible() []browseItem {
+	if v.filter == "" {
+		return v.items
+	}
+	items := []browseItem{}
+	for _, item := range v.items {
+		if strings.Contains(item.label, v.filter) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// move changes the selected entry by delta, staying within the filtered list.
+func (v *browseView) move(delta int) {
+	v.cursor = max(min(v.cursor+delta, len(v.visible())-1), 0)
+}
+
+type browseKey int
+
+const (
+	browseKeyNone browseKey = iota
+	browseKeyRune
+	browseKeyUp
+	browseKeyDown
+	browseKeyPageUp
+	browseKeyPageDown
+	browseKeyHome
+	browseKeyEnd
+	browseKeyOpen
+	browseKeyBack
+	browseKeyBackspace
+	browseKeyEscape
+	browseKeyQuit
+)
+
+// browser displays a stack of views, reading keys from in and drawing the current view to out.
+type browser struct {
+	rc        *regclient.RegClient
+	in        *bufio.Reader
+	out       io.Writer
+	size      func() (int, int) // size returns the width and height of the terminal.
+	stack     []*browseView
+	status    string // status is a message displayed until the next key, typically an error.
+	filtering bool   // filtering is true when keys are added to the filter of the current view.
+}
+
+// run handles keys until the user quits or the input is closed.
+func (b *browser) run(ctx context.Context) error {
+	if b.size == nil {
+		b.size = func() (int, int) { return 80, 24 }
+	}
+	for len(b.stack) > 0 {
+		if err := b.draw(); err != nil {
+			return err
+		}
+		key, r, err := b.readKey()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		b.status = ""
+		v := b.stack[len(b.stack)-1]
+		_, height := b.size()
+		page := max(height-2, 1)
+		if b.filtering {
+			switch key {
+			case browseKeyRune:
+				v.filter += string(r)
+				v.cursor, v.offset = 0, 0
+			case browseKeyBackspace, browseKeyBack:
+				if v.filter != "" {
+					fr := []rune(v.filter)
+					v.filter = string(fr[:len(fr)-1])
+					v.cursor, v.offset = 0, 0
+				}
+			case browseKeyEscape:
+				v.filter = ""
+				v.cursor, v.offset = 0, 0
+				b.filtering = false
+			case browseKeyOpen:
+				b.filtering = false
+			case browseKeyQuit:
+				return nil
+			}
+			continue
+		}
+		switch key {
+		case browseKeyQuit:
+			return nil
+		case browseKeyUp:
+			v.move(-1)
+		case browseKeyDown:
+			v.move(1)
+		case browseKeyPageUp:
+			v.move(-page)
+		case browseKeyPageDown:
+			v.move(page)
+		case browseKeyHome:
+			v.move(-len(v.items))
+		case browseKeyEnd:
+			v.move(len(v.items))
+		case browseKeyBack, browseKeyBackspace:
+			if len(b.stack) > 1 {
+				b.stack = b.stack[:len(b.stack)-1]
+			}
+		case browseKeyEscape:
+			v.filter = ""
+		case browseKeyRune:
+			if r == '/' {
+				b.filtering = true
+			}
+		case browseKeyOpen:
+			items := v.visible()
+			if v.cursor >= len(items) || items[v.cursor].open == nil {
+				continue
+			}
+			b.status = "loading..."
+			if err := b.draw(); err != nil {
+				return err
+			}
+			next, err := items[v.cursor].open(ctx)
+			if err != nil {
+				b.status = "error: " + strings.Join(strings.Fields(err.Error()), " ")
+				continue
+			}
+			b.status = ""
+			b.stack = append(b.stack, next)
+		}
+	}
+	return nil
+}
+
+// readKey reads a key from the input, parsing the escape sequences for the arrow and page keys.
+func (b *browser) readKey() (browseKey, rune, error) {
+	r, _, err := b.in.ReadRune()
+	if err != nil {
+		return browseKeyNone, 0, err
+	}
+	switch r {
+	case 3, 4: // ctrl-c, ctrl-d
+		return browseKeyQuit, r, nil
+	case '\r', '\n':
+		return browseKeyOpen, r, nil
+	case 127, 8:
+		return browseKeyBackspace, r, nil
+	case 27:
+		// a lone escape is not followed by more input
+		if b.in.Buffered() == 0 {
+			return browseKeyEscape, r, nil
+		}
+		seq := []rune{}
+		for b.in.Buffered() > 0 {
+			sr, _, err := b.in.ReadRune()
+			if err != nil {
+				return browseKeyNone, 0, err
+			}
+			seq = append(seq, sr)
+			// sequences end with a letter or "~"
+			if len(seq) > 1 && (sr == '~' || (sr >= 'A' && sr <= 'Z') || (sr >= 'a' && sr <= 'z')) {
+				break
+			}
+		}
+		switch strings.TrimLeft(string(seq), "[O") {
+		case "A":
+			return browseKeyUp, r, nil
+		case "B":
+			return browseKeyDown, r, nil
+		case "C":
+			return browseKeyOpen, r, nil
+		case "D":
+			return browseKeyBack, r, nil
+		case "5~":
+			return browseKeyPageUp, r, nil
+		case "6~":
+			return browseKeyPageDown, r, nil
+		case "H", "1~", "7~":
+			return browseKeyHome, r, nil
+		case "F", "4~", "8~":
+			return browseKeyEnd, r, nil
+		}
+		return browseKeyNone, r, nil
+	}
+	if b.filtering {
+		return browseKeyRune, r, nil
+	}
+	switch r {
+	case 'q':
+		return browseKeyQuit, r, nil
+	case 'k':
+		return browseKeyUp, r, nil
+	case 'j':
+		return browseKeyDown, r, nil
+	case 'l':
+		return browseKeyOpen, r, nil
+	case 'h':
+		return browseKeyBack, r, nil
+	case 'g':
+		return browseKeyHome, r, nil
+	case 'G':
+		return browseKeyEnd, r, nil
+	}
+	return browseKeyRune, r, nil
+}
+
+// draw clears the screen and outputs the current view.
+// Lines end with "\r\n" since the terminal is in raw mode.
+func (b *browser) draw() error {
+	width, height := b.size()
+	v := b.stack[len(b.stack)-1]
+	items := v.visible()
+	rows := max(height-2, 1)
+	v.cursor = max(min(v.cursor, len(items)-1), 0)
+	if v.cursor < v.offset {
+		v.offset = v.cursor
+	} else if v.cursor >= v.offset+rows {
+		v.offset = v.cursor - rows + 1
+	}
+	buf := &bytes.Buffer{}
+	buf.WriteString("\033[H\033[2J")
+	title := v.title
+	if len(items) != len(v.items) {
+		title = fmt.Sprintf("%s (%d of %d)", title, len(items), len(v.items))
+	}
+	fmt.Fprintf(buf, "\033[1m%s\033[0m\r\n", browseTrunc(title, width))
+	for i := v.offset; i < len(items) && i < v.offset+rows; i++ {
+		prefix := "  "
+		if items[i].open != nil {
+			prefix = "> "
+		}
+		line := browseTrunc(prefix+items[i].label, width)
+		if i == v.cursor {
+			fmt.Fprintf(buf, "\033[7m%s\033[0m\r\n", line)
+		} else {
+			fmt.Fprintf(buf, "%s\r\n", line)
+		}
+	}
+	for i := len(items) - v.offset; i < rows; i++ {
+		buf.WriteString("\r\n")
+	}
+	status := b.status
+	switch {
+	case b.filtering:
+		status = "/" + v.filter
+	case status == "" && v.filter != "":
+		status = fmt.Sprintf("filter: %s (esc to clear)", v.filter)
+	case status == "":
+		status = "enter: open, left: back, /: filter, q: quit"
+	}
+	buf.WriteString(browseTrunc(status, width))
+	_, err := b.out.Write(buf.Bytes())
+	return err
+}
+
+// browseTrunc limits a string to the width of the terminal.
+func browseTrunc(s string, width int) string {
+	r := []rune(s)
+	if width <= 0 || len(r) <= width {
+		return s
+	}
+	if width <= 3 {
+		return string(r[:width])
+	}
+	return string(r[:width-3]) + "..."
+}
+
+// browseRegistry lists the repositories in a registry.
+func browseRegistry(ctx context.Context, rc *regclient.RegClient, host string) (*browseView, error) {
+	rl, err := rc.RepoList(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories in %s: %w", host, err)
+	}
+	repos, err := rl.GetRepos()
+	if err != nil {
+		return nil, err
+	}
+	v := &browseView{
+		title: fmt.Sprintf("%s: %d repositories", host, len(repos)),
+		items: make([]browseItem, len(repos)),
+	}
+	for i, repo := range repos {
+		v.items[i] = browseItem{
+			label: repo,
+			open: func(ctx context.Context) (*browseView, error) {
+				r, err := ref.New(host + "/" + repo)
+				if err != nil {
+					return nil, err
+				}
+				return browseRepo(ctx, rc, r)
+			},
+		}
+	}
+	return v, nil
+}
+
+// browseRepo lists the tags in a repository.
+func browseRepo(ctx context.Context, rc *regclient.RegClient, r ref.Ref) (*browseView, error) {
+	r = r.SetTag("")
+	tl, err := rc.TagList(ctx, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags in %s: %w", r.CommonName(), err)
+	}
+	tags, err := tl.GetTags()
+	if err != nil {
+		return nil, err
+	}
+	v := &browseView{
+		title: fmt.Sprintf("%s: %d tags", r.CommonName(), len(tags)),
+		items: make([]browseItem, len(tags)),
+	}
+	for i, tag := range tags {
+		v.items[i] = browseItem{
+			label: tag,
+			open: func(ctx context.Context) (*browseView, error) {
+				return browseManifest(ctx, rc, r.SetTag(tag))
+			},
+		}
+	}
+	return v, nil
+}
+
+// browseManifest shows the details of a manifest with entries to open the child manifests, config, layers, and referrers.
+func browseManifest(ctx context.Context, rc *regclient.RegClient, r ref.Ref) (*browseView, error) {
+	m, err := rc.ManifestGet(ctx, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest %s: %w", r.CommonName(), err)
+	}
+	desc := m.GetDescriptor()
+	rDig := r.SetDigest(desc.Digest.String())
+	v := &browseView{
+		title: r.CommonName(),
+		items: []browseItem{
+			{label: "Media Type: " + desc.MediaType},
+			{label: "Digest:     " + desc.Digest.String()},
+			{label: "Size:       " + units.HumanSize(float64(desc.Size))},
+		},
+	}
+	body, err := m.RawBody()
+	if err == nil {
+		v.items = append(v.items, browseItem{
+			label: "Manifest body",
+			open: func(ctx context.Context) (*browseView, error) {
+				return browseJSON(r.CommonName()+" manifest", body), nil
+			},
+		})
+	}
+	if mi, ok := m.(manifest.Indexer); ok {
+		dl, err := mi.GetManifestList()
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range dl {
+			p := "unknown platform"
+			if d.Platform != nil {
+				p = d.Platform.String()
+			}
+			v.items = append(v.items, browseItem{
+				label: fmt.Sprintf("Manifest:   %-16s %s", p, d.Digest.String()),
+				open: func(ctx context.Context) (*browseView, error) {
+					return browseManifest(ctx, rc, rDig.SetDigest(d.Digest.String()))
+				},
+			})
+		}
+	}
+	if mi, ok := m.(manifest.Imager); ok {
+		cd, err := mi.GetConfig()
+		if err == nil && cd.Digest != "" {
+			v.items = append(v.items, browseItem{
+				label: fmt.Sprintf("Config:     %s %8s %s", cd.Digest.String(), units.HumanSize(float64(cd.Size)), cd.MediaType),
+				open: func(ctx context.Context) (*browseView, error) {
+					return browseBlob(ctx, rc, rDig, cd)
+				},
+			})
+		}
+		layers, err := mi.GetLayers()
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range layers {
+			item := browseItem{
+				label: fmt.Sprintf("Layer:      %s %8s %s", l.Digest.String(), units.HumanSize(float64(l.Size)), l.MediaType),
+			}
+			if slices.Contains(browseLayerTypes, l.MediaType) && len(l.URLs) == 0 {
+				item.open = func(ctx context.Context) (*browseView, error) {
+					return browseLayer(ctx, rc, rDig, l)
+				}
+			}
+			v.items = append(v.items, item)
+		}
+	}
+	if ma, ok := m.(manifest.Annotator); ok {
+		annot, err := ma.GetAnnotations()
+		if err == nil {
+			for _, k := range slices.Sorted(maps.Keys(annot)) {
+				v.items = append(v.items, browseItem{label: fmt.Sprintf("Annotation: %s=%s", k, annot[k])})
+			}
+		}
+	}
+	v.items = append(v.items, browseItem{
+		label: "Referrers",
+		open: func(ctx context.Context) (*browseView, error) {
+			return browseReferrers(ctx, rc, rDig)
+		},
+	})
+	return v, nil
+}
+
+// browseLayerTypes are the layer media types listed as a tar file.
+var browseLayerTypes = []string{
+	mediatype.Docker2Layer,
+	mediatype.Docker2LayerGzip,
+	mediatype.Docker2LayerZstd,
+	mediatype.OCI1Layer,
+	mediatype.OCI1LayerGzip,
+	mediatype.OCI1LayerZstd,
+}
+
+// browseLayer lists the files in a layer.
+func browseLayer(ctx context.Context, rc *regclient.RegClient, r ref.Ref, d descriptor.Descriptor) (*browseView, error) {
+	br, err := rc.BlobGet(ctx, r, d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull layer %s: %w", d.Digest.String(), err)
+	}
+	defer br.Close()
+	dr, err := archive.Decompress(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress layer %s: %w", d.Digest.String(), err)
+	}
+	v := &browseView{
+		items: []browseItem{},
+	}
+	tr := tar.NewReader(dr)
+	for {
+		th, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read layer %s: %w", d.Digest.String(), err)
+		}
+		name := th.Name
+		switch th.Typeflag {
+		case tar.TypeSymlink:
+			name += " -> " + th.Linkname
+		case tar.TypeLink:
+			name += " link to " + th.Linkname
+		}
+		v.items = append(v.items, browseItem{
+			label: fmt.Sprintf("%s %5d:%-5d %8s %s", th.FileInfo().Mode().String(), th.Uid, th.Gid, units.HumanSize(float64(th.Size)), name),
+		})
+	}
+	v.title = fmt.Sprintf("%d files in layer %s", len(v.items), d.Digest.String())
+	return v, nil
+}
+
+// browseBlob shows the content of a JSON blob, like an image config.
+func browseBlob(ctx context.Context, rc *regclient.RegClient, r ref.Ref, d descriptor.Descriptor) (*browseView, error) {
+	br, err := rc.BlobGet(ctx, r, d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull blob %s: %w", d.Digest.String(), err)
+	}
+	defer br.Close()
+	// limit the size of blobs loaded into memory
+	body, err := io.ReadAll(io.LimitReader(br, browseBlobMax))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %w", d.Digest.String(), err)
+	}
+	return browseJSON("Blob "+d.Digest.String(), body), nil
+}
+
+// browseBlobMax is the maximum size of a blob displayed by the browser.
+const browseBlobMax = 4 * 1024 * 1024
+
+// browseJSON shows each line of a JSON document, indenting the content when it is valid JSON.
+func browseJSON(title string, body []byte) *browseView {
+	buf := &bytes.Buffer{}
+	if err := json.Indent(buf, body, "", "  "); err == nil {
+		body = buf.Bytes()
+	}
+	v := &browseView{
+		title: title,
+		items: []browseItem{},
+	}
+	for line := range strings.SplitSeq(strings.TrimRight(string(body), "\n"), "\n") {
+		v.items = append(v.items, browseItem{label: strings.ReplaceAll(line, "\t", "  ")})
+	}
+	return v
+}
+
+// browseReferrers lists the referrers of a manifest.
+func browseReferrers(ctx context.Context, rc *regclient.RegClient, r ref.Ref) (*browseView, error) {
+	rl, err := rc.ReferrerList(ctx, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list referrers of %s: %w", r.CommonName(), err)
+	}
+	v := &browseView{
+		title: fmt.Sprintf("%d referrers to %s", len(rl.Descriptors), r.CommonName()),
+		items: make([]browseItem, len(rl.Descriptors)),
+	}
+	for i, d := range rl.Descriptors {
+		artifactType := d.ArtifactType
+		if artifactType == "" {
+			artifactType = d.MediaType
+		}
+		v.items[i] = browseItem{
+			label: fmt.Sprintf("%s %8s %s", d.Digest.String(), units.HumanSize(float64(d.Size)), artifactType),
+			open: func(ctx context.Context) (*browseView, error) {
+				return browseManifest(ctx, rc, r.SetDigest(d.Digest.String()))
+			},
+		}
+	}
+	return v, nil
+}
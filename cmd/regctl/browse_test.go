@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/olareg/olareg"
+	oConfig "github.com/olareg/olareg/config"
+
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/types/errs"
+)
+
+func TestBrowse(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "../../testdata",
+		},
+	})
+	// olareg does not implement the _catalog API
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/_catalog" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"repositories":["testrepo"]}`))
+			return
+		}
+		regHandler.ServeHTTP(w, r)
+	}))
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+	rc := regclient.New(regclient.WithConfigHost(config.Host{
+		Name: tsHost,
+		TLS:  config.TLSDisabled,
+	}))
+
+	t.Run("terminal required", func(t *testing.T) {
+		_, err := cobraTest(t, &cobraTestOpts{stdin: strings.NewReader("q")}, "browse", "ocidir://../../testdata/testrepo")
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("expected invalid input, received %v", err)
+		}
+	})
+
+	tt := []struct {
+		name      string
+		arg       string
+		keys      string
+		expectErr error
+		expectOut []string
+	}{
+		{
+			name:      "registry",
+			arg:       tsHost,
+			keys:      "/testrepo\r\rq",
+			expectOut: []string{"repositories", "testrepo", "a-docker", "v3"},
+		},
+		{
+			name:      "layer",
+			arg:       "ocidir://../../testdata/testrepo",
+			keys:      "/v1\r\rjjjj\rjjjjj\rhq",
+			expectOut: []string{"v1", "linux/amd64", "linux/arm64", "Config:", "Layer:", "files"},
+		},
+		{
+			name:      "config",
+			arg:       "ocidir://../../testdata/testrepo:v1",
+			keys:      "jjjj\rjjjj\r",
+			expectOut: []string{"Manifest:", "Config:", `"architecture": "amd64"`},
+		},
+		{
+			name:      "manifest body",
+			arg:       "ocidir://../../testdata/testrepo:v1",
+			keys:      "\x1b[B\x1b[B\x1b[B\x1b[C\x1b[D",
+			expectOut: []string{`"schemaVersion": 2`},
+		},
+		{
+			name:      "filter clear",
+			arg:       "ocidir://../../testdata/testrepo",
+			keys:      "/v2\x1b",
+			expectOut: []string{"(1 of ", "a-docker"},
+		},
+		{
+			name:      "referrers",
+			arg:       "ocidir://../../testdata/testrepo:v2",
+			keys:      "G\r",
+			expectOut: []string{"Referrers", "referrers"},
+		},
+		{
+			name:      "missing tag",
+			arg:       "ocidir://../../testdata/testrepo:missing",
+			expectErr: errs.ErrNotFound,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			views, _, err := browseStart(ctx, rc, tc.arg)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to start: %v", err)
+			}
+			out := &bytes.Buffer{}
+			b := &browser{
+				rc:    rc,
+				in:    bufio.NewReader(strings.NewReader(tc.keys)),
+				out:   out,
+				stack: views,
+			}
+			err = b.run(ctx)
+			if err != nil {
+				t.Fatalf("failed to run: %v", err)
+			}
+			for _, exp := range tc.expectOut {
+				if !strings.Contains(out.String(), exp) {
+					t.Errorf("missing expected output: %s", exp)
+				}
+			}
+		})
+	}
+
+	t.Run("open error", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		b := &browser{
+			rc:  rc,
+			in:  bufio.NewReader(strings.NewReader("\rj\r")),
+			out: out,
+			stack: []*browseView{{
+				title: "test",
+				items: []browseItem{
+					{label: "fails", open: func(ctx context.Context) (*browseView, error) {
+						return nil, errs.ErrNotFound
+					}},
+					{label: "info"},
+				},
+			}},
+		}
+		err := b.run(ctx)
+		if err != nil {
+			t.Fatalf("failed to run: %v", err)
+		}
+		if !strings.Contains(out.String(), "error: "+errs.ErrNotFound.Error()) {
+			t.Errorf("error not displayed")
+		}
+		if len(b.stack) != 1 || b.stack[0].cursor != 1 {
+			t.Errorf("unexpected view after error, stack %d, cursor %d", len(b.stack), b.stack[0].cursor)
+		}
+	})
+}
@@ -52,6 +52,10 @@ func completeArgMediaTypeManifest(cmd *cobra.Command, args []string, toComplete
 	}, cobra.ShellCompDirectiveNoFileComp
 }
 
+func completeArgLayerCompress(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return []string{"gzip", "none", "zstd"}, cobra.ShellCompDirectiveNoFileComp
+}
+
 func (opts *rootOpts) completeArgTag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	result := []string{}
 	// TODO: is it possible to expand registry, then repo, then tag?
@@ -35,6 +35,8 @@ type Config struct {
 	BlobLimit     int64                   `json:"blobLimit,omitempty"`
 	IncDockerCert *bool                   `json:"incDockerCert,omitempty"`
 	IncDockerCred *bool                   `json:"incDockerCred,omitempty"`
+	Keyring       *bool                   `json:"keyring,omitempty"`
+	DeviceLogins  map[string]*DeviceLogin `json:"deviceLogins,omitempty"`
 }
 
 type configOpts struct {
@@ -44,6 +46,7 @@ type configOpts struct {
 	dockerCert    bool
 	dockerCred    bool
 	format        string
+	keyring       bool
 }
 
 func NewConfigCmd(rOpts *rootOpts) *cobra.Command {
@@ -95,7 +98,10 @@ func newConfigSetCmd(rOpts *rootOpts) *cobra.Command {
 regctl config set --docker-cred=false
 
 # enable loading credentials from docker
-regctl config set --docker-cred`,
+regctl config set --docker-cred
+
+# store logins in the config file instead of the OS keyring
+regctl config set --keyring=false`,
 		Args: cobra.ExactArgs(0),
 		RunE: opts.runConfigSet,
 	}
@@ -103,6 +109,7 @@ regctl config set --docker-cred`,
 	cmd.Flags().StringVar(&opts.defCredHelper, "default-cred-helper", "", "default credential helper")
 	cmd.Flags().BoolVar(&opts.dockerCert, "docker-cert", false, "load certificates from docker")
 	cmd.Flags().BoolVar(&opts.dockerCred, "docker-cred", false, "load credentials from docker")
+	cmd.Flags().BoolVar(&opts.keyring, "keyring", false, "store logins in the OS keyring when a credential helper for it is installed")
 	return cmd
 }
 
@@ -115,6 +122,9 @@ func (opts *configOpts) runConfigGet(cmd *cobra.Command, args []string) error {
 		c.Hosts[i].Pass = ""
 		c.Hosts[i].Token = ""
 	}
+	for i := range c.DeviceLogins {
+		c.DeviceLogins[i].RefreshToken = ""
+	}
 
 	return template.Writer(cmd.OutOrStdout(), opts.format, c)
 }
@@ -152,6 +162,13 @@ func (opts *configOpts) runConfigSet(cmd *cobra.Command, args []string) error {
 			c.IncDockerCred = nil
 		}
 	}
+	if flagChanged(cmd, "keyring") {
+		if !opts.keyring {
+			c.Keyring = &opts.keyring
+		} else {
+			c.Keyring = nil
+		}
+	}
 
 	if c.HostDefault != nil && c.HostDefault.IsZero() {
 		c.HostDefault = nil
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+	// deviceExpireMargin refreshes access tokens before they expire to allow for clock skew and long requests.
+	deviceExpireMargin = time.Minute
+	// deviceRespLimit limits the size of responses from the authorization server.
+	deviceRespLimit = 1024 * 1024
+)
+
+var (
+	// deviceIntervalDefault is the polling interval when the authorization server does not provide one.
+	deviceIntervalDefault = time.Second * 5
+	// deviceSlowDown is added to the polling interval when the authorization server requests it.
+	deviceSlowDown = time.Second * 5
+)
+
+// DeviceLogin contains the settings to refresh a login from an OAuth device authorization flow.
+// These are stored in the regctl config rather than the host config since they are only used by regctl.
+// The refresh token is stored in the OS keyring with the host credentials,
+// and is only saved in the config file when the keyring cannot be used.
+type DeviceLogin struct {
+	ClientID     string    `json:"clientID"`
+	TokenURL     string    `json:"tokenURL"`
+	Scope        string    `json:"scope,omitempty"`
+	RefreshToken string    `json:"refreshToken,omitempty"` //#nosec G117 only set when the keyring is not available, the config file is restricted to the user
+	Expires      time.Time `json:"expires,omitzero"`
+}
+
+// devicePreset contains the known OAuth endpoints for registries supporting a device authorization flow.
+type devicePreset struct {
+	deviceURL string
+	tokenURL  string
+	issuer    string
+	scope     string
+	user      string
+}
+
+var devicePresets = map[string]devicePreset{
+	"ghcr.io": {
+		deviceURL: "https://github.com/login/device/code",
+		tokenURL:  "https://github.com/login/oauth/access_token",
+		scope:     "read:packages write:packages",
+		user:      "oauth2",
+	},
+	"registry.gitlab.com": {
+		issuer: "https://gitlab.com",
+		scope:  "read_registry write_registry",
+		user:   "oauth2",
+	},
+}
+
+// deviceAuth is the response from a device authorization request (RFC 8628 section 3.2).
+type deviceAuth struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	VerificationURL         string `json:"verification_url"` // used by some providers instead of verification_uri
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// deviceToken is the response from a token request, including errors (RFC 6749 section 5).
+type deviceToken struct {
+	AccessToken  string `json:"access_token"`  //#nosec G117 struct intentionally holds secrets
+	RefreshToken string `json:"refresh_token"` //#nosec G117 struct intentionally holds secrets
+	ExpiresIn    int    `json:"expires_in"`
+	Error        string `json:"error"`
+	ErrorDesc    string `json:"error_description"`
+}
+
+// deviceDiscover returns the device authorization and token endpoints from the OIDC discovery document of an issuer.
+func deviceDiscover(ctx context.Context, issuer string) (string, string, error) {
+	u := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to query %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to query %s: status %d", u, resp.StatusCode)
+	}
+	disc := struct {
+		DeviceURL string `json:"device_authorization_endpoint"`
+		TokenURL  string `json:"token_endpoint"`
+	}{}
+	err = json.NewDecoder(io.LimitReader(resp.Body, deviceRespLimit)).Decode(&disc)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse %s: %w", u, err)
+	}
+	if disc.DeviceURL == "" || disc.TokenURL == "" {
+		return "", "", fmt.Errorf("issuer %s does not support the device authorization flow", issuer)
+	}
+	return disc.DeviceURL, disc.TokenURL, nil
+}
+
+// devicePost sends a form to an OAuth endpoint and parses the JSON response into out.
+// The status is returned so token errors in the response body can be handled by the caller.
+func devicePost(ctx context.Context, u string, form url.Values, out any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// GitHub returns a form encoded response unless JSON is requested
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request to %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	err = json.NewDecoder(io.LimitReader(resp.Body, deviceRespLimit)).Decode(out)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to parse response from %s (status %d): %w", u, resp.StatusCode, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// deviceAuthorize starts a device authorization flow.
+func deviceAuthorize(ctx context.Context, deviceURL, clientID, scope string) (deviceAuth, error) {
+	da := deviceAuth{}
+	form := url.Values{}
+	form.Set("client_id", clientID)
+	if scope != "" {
+		form.Set("scope", scope)
+	}
+	status, err := devicePost(ctx, deviceURL, form, &da)
+	if err != nil {
+		return da, err
+	}
+	if status != http.StatusOK || da.DeviceCode == "" {
+		return da, fmt.Errorf("device authorization request failed: status %d", status)
+	}
+	if da.VerificationURI == "" {
+		da.VerificationURI = da.VerificationURL
+	}
+	return da, nil
+}
+
+// devicePoll polls the token endpoint until the user approves or denies the request, or the device code expires.
+func devicePoll(ctx context.Context, tokenURL, clientID string, da deviceAuth) (deviceToken, error) {
+	interval := deviceIntervalDefault
+	if da.Interval > 0 {
+		interval = time.Duration(da.Interval) * time.Second
+	}
+	if da.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(da.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+	form := url.Values{}
+	form.Set("grant_type", deviceGrantType)
+	form.Set("device_code", da.DeviceCode)
+	form.Set("client_id", clientID)
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return deviceToken{}, fmt.Errorf("device code expired before the login was approved")
+			}
+			return deviceToken{}, ctx.Err()
+		case <-time.After(interval):
+		}
+		tok := deviceToken{}
+		_, err := devicePost(ctx, tokenURL, form, &tok)
+		if err != nil {
+			return tok, err
+		}
+		switch tok.Error {
+		case "":
+			if tok.AccessToken == "" {
+				return tok, fmt.Errorf("token response did not include an access token")
+			}
+			return tok, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += deviceSlowDown
+		case "access_denied":
+			return tok, fmt.Errorf("login was denied")
+		case "expired_token":
+			return tok, fmt.Errorf("device code expired before the login was approved")
+		default:
+			return tok, tokenErr(tok)
+		}
+	}
+}
+
+// refresh requests a new access token using the refresh token.
+func (dl *DeviceLogin) refresh(ctx context.Context) (deviceToken, error) {
+	tok := deviceToken{}
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", dl.RefreshToken)
+	form.Set("client_id", dl.ClientID)
+	if dl.Scope != "" {
+		form.Set("scope", dl.Scope)
+	}
+	status, err := devicePost(ctx, dl.TokenURL, form, &tok)
+	if err != nil {
+		return tok, err
+	}
+	if tok.Error != "" {
+		return tok, tokenErr(tok)
+	}
+	if status != http.StatusOK || tok.AccessToken == "" {
+		return tok, fmt.Errorf("token refresh failed: status %d", status)
+	}
+	return tok, nil
+}
+
+// update stores the refresh token and expiration from a token response.
+func (dl *DeviceLogin) update(tok deviceToken) {
+	if tok.RefreshToken != "" {
+		dl.RefreshToken = tok.RefreshToken
+	}
+	if tok.ExpiresIn > 0 {
+		dl.Expires = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second).UTC()
+	} else {
+		dl.Expires = time.Time{}
+	}
+}
+
+func tokenErr(tok deviceToken) error {
+	if tok.ErrorDesc != "" {
+		return fmt.Errorf("token request failed: %s: %s", tok.Error, tok.ErrorDesc)
+	}
+	return fmt.Errorf("token request failed: %s", tok.Error)
+}
+
+// deviceRefresh refreshes expired access tokens from device logins and saves the config when any are updated.
+// Failures are logged, leaving the existing credentials in place.
+func (c *Config) deviceRefresh(ctx context.Context, log *slog.Logger) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+	changed := false
+	for name, dl := range c.DeviceLogins {
+		h, ok := c.Hosts[name]
+		if !ok || dl.Expires.IsZero() || time.Now().Add(deviceExpireMargin).Before(dl.Expires) {
+			continue
+		}
+		if dl.RefreshToken == "" {
+			dl.RefreshToken = deviceTokenGet(h)
+			if dl.RefreshToken == "" {
+				continue
+			}
+		}
+		tok, err := dl.refresh(ctx)
+		if err != nil {
+			log.Warn("Failed to refresh login, run \"regctl registry login --device-code\" to login again",
+				slog.String("registry", name),
+				slog.String("err", err.Error()))
+			continue
+		}
+		if h.CredHelper != "" {
+			// load the username from the keyring
+			h.GetCred()
+		}
+		h.Pass = tok.AccessToken
+		dl.update(tok)
+		c.credSave(h, log)
+		changed = true
+		log.Debug("Refreshed login",
+			slog.String("registry", name))
+	}
+	if changed {
+		if err := c.ConfigSave(); err != nil {
+			log.Warn("Failed to save refreshed login",
+				slog.String("err", err.Error()))
+		}
+	}
+}
@@ -1,6 +1,21 @@
 package main
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/regclient/regclient/types/errs"
+)
+
+// exit codes returned for common registry failures, allowing scripts to branch on the cause
+const (
+	exitCodeFailure         = 1 // generic failure
+	exitCodeNotFound        = 3 // content not found without a more specific registry error code
+	exitCodeNameUnknown     = 4 // repository not found
+	exitCodeManifestUnknown = 5 // manifest or tag not found
+	exitCodeBlobUnknown     = 6 // blob not found
+	exitCodeDenied          = 7 // authentication or authorization failed
+	exitCodeRateLimit       = 8 // registry rate limit exceeded
+)
 
 var (
 	// ErrCredsNotFound returned when creds needed and cannot be found
@@ -19,3 +34,35 @@ var (
 	// ErrUnsupportedConfigVersion happens when config file version is greater than this command supports
 	ErrUnsupportedConfigVersion = errors.New("unsupported config version")
 )
+
+// errExitCode returns the exit code for an error returned by a command.
+func errExitCode(err error) int {
+	var ec exitCodeError
+	if errors.As(err, &ec) {
+		return ec.code
+	}
+	var re *errs.RegistryError
+	if errors.As(err, &re) {
+		switch {
+		case re.HasCode(errs.ErrorCodeTooManyRequests):
+			return exitCodeRateLimit
+		case re.HasCode(errs.ErrorCodeDenied, errs.ErrorCodeUnauthorized):
+			return exitCodeDenied
+		case re.HasCode(errs.ErrorCodeNameUnknown):
+			return exitCodeNameUnknown
+		case re.HasCode(errs.ErrorCodeManifestUnknown):
+			return exitCodeManifestUnknown
+		case re.HasCode(errs.ErrorCodeBlobUnknown):
+			return exitCodeBlobUnknown
+		}
+	}
+	switch {
+	case errors.Is(err, errs.ErrHTTPRateLimit):
+		return exitCodeRateLimit
+	case errors.Is(err, errs.ErrHTTPUnauthorized):
+		return exitCodeDenied
+	case errors.Is(err, errs.ErrNotFound):
+		return exitCodeNotFound
+	}
+	return exitCodeFailure
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/olareg/olareg"
+	oConfig "github.com/olareg/olareg/config"
+
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/scheme/reg"
+	"github.com/regclient/regclient/types/errs"
+)
+
+func TestErrExitCode(t *testing.T) {
+	t.Parallel()
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "../../testdata",
+		},
+	})
+	ts := httptest.NewServer(regHandler)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+	rcOpts := []regclient.Opt{
+		regclient.WithConfigHost(
+			config.Host{
+				Name: tsHost,
+				TLS:  config.TLSDisabled,
+			},
+		),
+		regclient.WithRegOpts(reg.WithDelay(time.Millisecond*10, time.Millisecond*100), reg.WithRetryLimit(2)),
+	}
+
+	tt := []struct {
+		name   string
+		args   []string
+		err    error
+		expect int
+	}{
+		{
+			name:   "missing repo",
+			err:    &errs.RegistryError{StatusCode: 404, Errors: []errs.RegistryErrorDetail{{Code: errs.ErrorCodeNameUnknown}}, Err: errs.ErrNotFound},
+			expect: exitCodeNameUnknown,
+		},
+		{
+			name:   "missing tag",
+			args:   []string{"manifest", "get", tsHost + "/testrepo:missing"},
+			expect: exitCodeManifestUnknown,
+		},
+		{
+			name:   "missing ocidir tag",
+			args:   []string{"manifest", "get", "ocidir://../../testdata/testrepo:missing"},
+			expect: exitCodeNotFound,
+		},
+		{
+			name:   "rate limit",
+			err:    fmt.Errorf("failed: %w", &errs.RegistryError{StatusCode: 429, Errors: []errs.RegistryErrorDetail{{Code: errs.ErrorCodeTooManyRequests}}, Err: errs.ErrHTTPRateLimit}),
+			expect: exitCodeRateLimit,
+		},
+		{
+			name:   "denied",
+			err:    &errs.RegistryError{StatusCode: 403, Errors: []errs.RegistryErrorDetail{{Code: errs.ErrorCodeDenied}}, Err: errs.ErrHTTPUnauthorized},
+			expect: exitCodeDenied,
+		},
+		{
+			name:   "unauthorized without code",
+			err:    fmt.Errorf("login failed: %w", errs.ErrHTTPUnauthorized),
+			expect: exitCodeDenied,
+		},
+		{
+			name:   "command exit code",
+			err:    exitCodeError{code: 2, err: errs.ErrNotFound},
+			expect: 2,
+		},
+		{
+			name:   "generic",
+			err:    errors.New("failure"),
+			expect: exitCodeFailure,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.err
+			if len(tc.args) > 0 {
+				_, err = cobraTest(t, &cobraTestOpts{rcOpts: rcOpts}, tc.args...)
+				if err == nil {
+					t.Fatalf("did not receive expected error")
+				}
+			}
+			if code := errExitCode(err); code != tc.expect {
+				t.Errorf("unexpected exit code, expected %d, received %d, err %v", tc.expect, code, err)
+			}
+		})
+	}
+}
@@ -9,13 +9,17 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
+	"maps"
 	"os"
+	"path"
 	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"text/tabwriter"
 	"time"
 
 	"github.com/opencontainers/go-digest"
@@ -38,36 +42,72 @@ import (
 	v1 "github.com/regclient/regclient/types/oci/v1"
 	"github.com/regclient/regclient/types/platform"
 	"github.com/regclient/regclient/types/ref"
+	"github.com/regclient/regclient/types/referrer"
 	"github.com/regclient/regclient/types/warning"
 )
 
 type imageOpts struct {
-	rootOpts        *rootOpts
-	annotations     []string
-	byDigest        bool
-	checkBaseRef    string
-	checkBaseDigest string
-	checkSkipConfig bool
-	create          string
-	created         string
-	digestTags      bool
-	exportCompress  bool
-	exportRef       string
-	fastCheck       bool
-	forceRecursive  bool
-	format          string
-	importName      string
-	includeExternal bool
-	labels          []string
-	mediaType       string
-	modOpts         []mod.Opts
-	platform        string
-	platforms       []string
-	quiet           bool
-	referrers       bool
-	referrerSrc     string
-	referrerTgt     string
-	replace         bool
+	rootOpts           *rootOpts
+	annotations        []string
+	authCheck          bool
+	byDigest           bool
+	checkBaseRef       string
+	checkBaseDigest    string
+	checkSkipConfig    bool
+	create             string
+	created            string
+	digestTags         bool
+	digestTagPatterns  []string
+	dryRun             bool
+	copyAnnotate       bool
+	copyAnnotations    []string
+	exportCompress     bool
+	exportRef          string
+	externalHosts      []string
+	externalPolicy     string
+	fastCheck          bool
+	fileScrubLog       bool
+	force              bool
+	forceRecompress    bool
+	forceRecursive     bool
+	format             string
+	importName         string
+	includeExternal    bool
+	labels             []string
+	layerCompress      string
+	layerCompressLevel int
+	layerKeepTypes     []string
+	layerRmDigests     []string
+	layerRmTypes       []string
+	lintFailOn         string
+	lintMaxLayerSize   string
+	lintMaxLayers      int
+	lintRequireLabels  []string
+	lintSecretPatterns []string
+	lintSkip           []string
+	mediaType          string
+	modOpts            []mod.Opts
+	noTrunc            bool
+	parallel           int
+	platform           string
+	platforms          []string
+	provenance         bool
+	promoteDigest      string
+	promoteForce       bool
+	promoteRecord      bool
+	query              string
+	quiet              bool
+	referrers          bool
+	referrerSrc        string
+	referrerTgt        string
+	replace            bool
+	revision           string
+	seekableVerify     bool
+	source             string
+	tagRegex           bool
+	uncompressed       bool
+	verify             bool
+	verifySample       int
 }
 
 var imageKnownTypes = []string{
@@ -84,14 +124,21 @@ func NewImageCmd(rOpts *rootOpts) *cobra.Command {
 	cmd.AddCommand(newImageCopyCmd(rOpts))
 	cmd.AddCommand(newImageCreateCmd(rOpts))
 	cmd.AddCommand(newImageDeleteCmd(rOpts))
+	cmd.AddCommand(newImageDiffConfigCmd(rOpts))
 	cmd.AddCommand(newImageDigestCmd(rOpts))
 	cmd.AddCommand(newImageExportCmd(rOpts))
 	cmd.AddCommand(newImageGetFileCmd(rOpts))
+	cmd.AddCommand(newImageHistoryCmd(rOpts))
 	cmd.AddCommand(newImageImportCmd(rOpts))
 	cmd.AddCommand(newImageInspectCmd(rOpts))
+	cmd.AddCommand(newImageLintCmd(rOpts))
 	cmd.AddCommand(newImageManifestCmd(rOpts))
 	cmd.AddCommand(newImageModCmd(rOpts))
+	cmd.AddCommand(newImagePromoteCmd(rOpts))
 	cmd.AddCommand(newImageRateLimitCmd(rOpts))
+	cmd.AddCommand(newImageSetBaseAnnotationsCmd(rOpts))
+	cmd.AddCommand(newImageSizeCmd(rOpts))
+	cmd.AddCommand(newImageVerifyDigestsCmd(rOpts))
 	return cmd
 }
 
@@ -108,7 +155,9 @@ If the base name is not provided, annotations will be checked in the image.
 If the digest is available, this checks if that matches the base name.
 If the digest is not available, layers of each manifest are compared.
 If the layers match, the config (history and roots) are optionally compared.
-If the base image does not match, the command exits with a non-zero status.`,
+If the base image does not match, the command exits with a status of 1.
+Any other error exits with a status of 2.
+The "--format" flag outputs the report, including each layer that diverges from the base image.`,
 		Example: `
 # report if base image has changed using annotations
 regctl image check-base ghcr.io/regclient/regctl:alpine
@@ -116,13 +165,18 @@ regctl image check-base ghcr.io/regclient/regctl:alpine
 # suppress the normal output with --quiet for scripts
 if ! regctl image check-base ghcr.io/regclient/regctl:alpine --quiet; then
   echo build a new image here
-fi`,
+fi
+
+# output a JSON report for CI jobs
+regctl image check-base ghcr.io/regclient/regctl:alpine --format '{{json .}}'`,
 		Args:              cobra.ExactArgs(1),
 		ValidArgsFunction: rOpts.completeArgTag,
 		RunE:              opts.runImageCheckBase,
 	}
 	cmd.Flags().StringVar(&opts.checkBaseRef, "base", "", "Base image reference (including tag)")
 	cmd.Flags().StringVar(&opts.checkBaseDigest, "digest", "", "Base image digest (checks if digest matches base)")
+	cmd.Flags().StringVar(&opts.format, "format", "", "Format output with go template syntax (use \"{{json .}}\" for a JSON report)")
+	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
 	cmd.Flags().BoolVar(&opts.checkSkipConfig, "no-config", false, "Skip check of config history")
 	cmd.Flags().StringVarP(&opts.platform, "platform", "p", "", "Specify platform (e.g. linux/amd64 or local)")
 	cmd.Flags().BoolVar(&opts.quiet, "quiet", false, "Do not output to stdout")
@@ -140,7 +194,18 @@ func newImageCopyCmd(rOpts *rootOpts) *cobra.Command {
 		Long: `Copy or retag an image. This works between registries and only pulls layers
 that do not exist at the target. In the same registry it attempts to mount
 the layers between repositories. And within the same repository it only
-sends the manifest with the new tag.`,
+sends the manifest with the new tag.
+A target tag matching the immutable tag patterns of the registry config is not
+replaced unless "--force" is set.
+A source tag with a glob ("*", "?", or "[...]"), or any source tag with
+"--tag-regex", copies every matching tag to the same tag in the target
+repository. The target must not include a tag or digest.
+With "--copy-annotations", the source is copied by digest and the target tag
+points to a copy of the manifest annotated with the source and time of the copy.
+With "--provenance", a record of the source registry, source digest, time of the
+copy, and regctl version is pushed as a referrer of the copied image.
+Changing the layer compression with "--layer-compress" creates a new image with
+a different digest, so options that depend on the source digest are not supported.`,
 		Example: `
 # copy an image
 regctl image copy \
@@ -157,31 +222,105 @@ regctl image copy --platform local \
 # retag an image
 regctl image copy registry.example.org/repo:v1.2.3 registry.example.org/repo:v1
 
+# copy an image and verify the target, pulling 2 blobs to check the digest
+regctl image copy --verify --verify-sample 2 \
+  registry.example.org/repo:v1.2.3 registry.example.org/repo:v1
+
+# show the manifests and blobs that would be copied
+regctl image copy --dry-run \
+  registry.example.org/repo:v1.2.3 registry.example.org/repo:v1
+
+# copy an image and capture the digest for a pipeline
+digest=$(regctl image copy --quiet \
+  registry.example.org/repo:v1.2.3 registry.example.org/repo:v1)
+
+# copy an image, recompressing every layer with zstd level 9
+regctl image copy --layer-compress zstd --layer-compress-level 9 --force-recompress \
+  registry.example.org/repo:v1.2.3 registry.example.org/repo:v1.2.3-zstd
+
+# copy an image without the model weight layers, this changes the image digest
+regctl image copy --layer-rm-media-type application/vnd.example.model.weights \
+  registry.example.org/repo:v1.2.3 registry.example.org/repo:v1.2.3-app
+
 # copy an image to an OCI Layout including referrers
 regctl image copy --referrers \
   ghcr.io/regclient/regctl:edge ocidir://regctl:edge
 
 # copy a windows image, including foreign layers
 regctl image copy --platform windows/amd64,osver=10.0.17763.4974 --include-external \
-  golang:latest registry.example.org/library/golang:windows`,
+  golang:latest registry.example.org/library/golang:windows
+
+# copy a windows image, only including foreign layers hosted by mcr.microsoft.com
+regctl image copy --platform windows/amd64 \
+  --external-policy copy --external-host mcr.microsoft.com \
+  golang:latest registry.example.org/library/golang:windows
+
+# mirror an image, annotating the target with the source and time of the copy
+regctl image copy --copy-annotations --copy-annotation org.example.mirror=primary \
+  ghcr.io/regclient/regctl:edge registry.example.org/regclient/regctl:edge
+
+# mirror an image with a provenance record
+regctl image copy --provenance \
+  ghcr.io/regclient/regctl:edge registry.example.org/regclient/regctl:edge
+
+# verify the login can push to the target before copying
+regctl image copy --auth-check \
+  alpine:latest registry.example.org/library/alpine:latest
+
+# copy every v1.x tag to the same tags in another repository
+regctl image copy 'registry.example.org/repo:v1.*' registry.example.org/mirror:
+
+# copy tags matching a regular expression
+regctl image copy --tag-regex 'registry.example.org/repo:v1\.[0-9]+' registry.example.org/mirror`,
 		Args:              cobra.ExactArgs(2),
 		ValidArgsFunction: rOpts.completeArgTag,
 		RunE:              opts.runImageCopy,
 	}
+	cmd.Flags().BoolVar(&opts.authCheck, "auth-check", false, "Verify pull access to the source and push access to the target before copying")
+	cmd.Flags().BoolVar(&opts.copyAnnotate, "copy-annotations", false, "Annotate the target tag with the source and time of the copy, this changes the digest of the tag")
+	cmd.Flags().StringArrayVar(&opts.copyAnnotations, "copy-annotation", []string{}, "Additional annotation on the target tag (name=value), implies --copy-annotations, may be repeated")
+	_ = cmd.RegisterFlagCompletionFunc("copy-annotation", completeArgNone)
 	cmd.Flags().BoolVar(&opts.digestTags, "digest-tags", false, "Include digest tags (\"sha256-<digest>.*\") when copying manifests")
+	cmd.Flags().StringArrayVar(&opts.digestTagPatterns, "digest-tag-pattern", []string{}, "Regexp of the digest tag suffixes to copy (e.g. \"^\\.(sig|att|sbom)$\"), implies --digest-tags, may be repeated")
+	_ = cmd.RegisterFlagCompletionFunc("digest-tag-pattern", completeArgNone)
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Output the manifests and blobs that would be copied without pushing to the target")
 	cmd.Flags().BoolVar(&opts.fastCheck, "fast", false, "Fast check, skip referrers and digest tag checks when image exists, overrides force-recursive")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "Replace a target tag matching the immutable tag patterns of the registry")
+	cmd.Flags().BoolVar(&opts.forceRecompress, "force-recompress", false, "Recompress layers already using the layer-compress algorithm")
 	cmd.Flags().BoolVar(&opts.forceRecursive, "force-recursive", false, "Force recursive copy of image, repairs missing nested blobs and manifests")
 	cmd.Flags().StringVar(&opts.format, "format", "", "Format output with go template syntax")
 	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
-	cmd.Flags().BoolVar(&opts.includeExternal, "include-external", false, "Include external layers")
+	cmd.Flags().StringArrayVar(&opts.externalHosts, "external-host", []string{}, "Only copy external layers with a URL on this host, may be repeated")
+	_ = cmd.RegisterFlagCompletionFunc("external-host", completeArgNone)
+	cmd.Flags().StringVar(&opts.externalPolicy, "external-policy", "", "Handling of external layers (skip, copy)")
+	_ = cmd.RegisterFlagCompletionFunc("external-policy", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{string(regclient.ExternalSkip), string(regclient.ExternalCopy)}, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.Flags().BoolVar(&opts.includeExternal, "include-external", false, "Include external layers, same as \"--external-policy copy\"")
+	cmd.Flags().StringVar(&opts.layerCompress, "layer-compress", "", "Change the layer compression (gzip, none, zstd), this changes the image digest")
+	_ = cmd.RegisterFlagCompletionFunc("layer-compress", completeArgLayerCompress)
+	cmd.Flags().IntVar(&opts.layerCompressLevel, "layer-compress-level", 0, "Compression level for layer-compress (gzip 1-9, zstd 1-22)")
+	_ = cmd.RegisterFlagCompletionFunc("layer-compress-level", completeArgNone)
+	imageLayerFilterFlags(cmd, &opts)
 	cmd.Flags().StringVarP(&opts.platform, "platform", "p", "", "Specify platform (e.g. linux/amd64 or local)")
 	_ = cmd.RegisterFlagCompletionFunc("platform", completeArgPlatform)
 	cmd.Flags().StringArrayVar(&opts.platforms, "platforms", []string{}, "Copy only specific platforms, registry validation must be disabled")
 	// platforms should be treated as experimental since it will break many registries
 	_ = cmd.Flags().MarkHidden("platforms")
+	cmd.Flags().IntVar(&opts.parallel, "parallel", 4, "Number of concurrent copies when the source tag is a pattern")
+	_ = cmd.RegisterFlagCompletionFunc("parallel", completeArgNone)
+	cmd.Flags().BoolVar(&opts.provenance, "provenance", false, "Push a provenance record with the source, time, and regctl version as a referrer of the target")
+	cmd.Flags().BoolVarP(&opts.quiet, "quiet", "q", false, "Only output the digest of the target image, and hide the progress")
 	cmd.Flags().BoolVar(&opts.referrers, "referrers", false, "Include referrers")
 	cmd.Flags().StringVar(&opts.referrerSrc, "referrers-src", "", "External source for referrers")
 	cmd.Flags().StringVar(&opts.referrerTgt, "referrers-tgt", "", "External target for referrers")
+	cmd.Flags().BoolVar(&opts.tagRegex, "tag-regex", false, "Source tag is a regexp of tags to copy (expression is bound to beginning and ending of tag)")
+	cmd.Flags().BoolVar(&opts.verify, "verify", false, "Verify the manifests and blobs on the target after the copy")
+	cmd.Flags().IntVar(&opts.verifySample, "verify-sample", 0, "Number of blobs pulled to verify the digest with --verify, -1 for all blobs")
+	_ = cmd.RegisterFlagCompletionFunc("verify-sample", completeArgNone)
+	cmd.Flags().BoolVar(&opts.seekableVerify, "verify-seekable", false, "Verify the eStargz TOC and zstd:chunked checksums of copied layers")
+	cmd.MarkFlagsMutuallyExclusive("format", "quiet")
+	cmd.MarkFlagsMutuallyExclusive("dry-run", "quiet")
 	return cmd
 }
 
@@ -223,6 +362,40 @@ func newImageDeleteCmd(rOpts *rootOpts) *cobra.Command {
 	return cmd
 }
 
+func newImageDiffConfigCmd(rOpts *rootOpts) *cobra.Command {
+	opts := imageOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "diff-config <image_ref> <image_ref>",
+		Short: "compare the config of two images",
+		Long: `Compare the config of two images, reporting the changes to each setting rather than a text diff.
+Environment variables, labels, exposed ports, and volumes are compared by name.
+The entrypoint, command, user, working directory, and other settings are compared by value.
+The history is compared by the command of each step, reporting where the histories diverge.
+The output defaults to a table, use "--format '{{json .}}'" for a JSON report.`,
+		Example: `
+# compare two versions of an image
+regctl image diff-config registry.example.org/repo:v1 registry.example.org/repo:v2
+
+# compare the linux/arm64 platform of two images
+regctl image diff-config --platform linux/arm64 \
+  registry.example.org/repo:v1 registry.example.org/repo:v2
+
+# list the names of changed environment variables
+regctl image diff-config registry.example.org/repo:v1 registry.example.org/repo:v2 \
+  --format '{{ range .Changes }}{{ if eq .Field "Env" }}{{ println .Key }}{{ end }}{{ end }}'`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: rOpts.completeArgTag,
+		RunE:              opts.runImageDiffConfig,
+	}
+	cmd.Flags().StringVar(&opts.format, "format", "table", "Format output with go template syntax (use \"table\" for a summary)")
+	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	cmd.Flags().StringVarP(&opts.platform, "platform", "p", "", "Specify platform (e.g. linux/amd64 or local)")
+	_ = cmd.RegisterFlagCompletionFunc("platform", completeArgPlatform)
+	return cmd
+}
+
 func newImageDigestCmd(rOpts *rootOpts) *cobra.Command {
 	cmd := newManifestHeadCmd(rOpts)
 	cmd.Use = "digest <image_ref>"
@@ -244,12 +417,17 @@ engine with "docker load". The tar file is output to stdout by default.
 Compression is typically not useful since layers are already compressed.`,
 		Example: `
 # export an image
-regctl image export registry.example.org/repo:v1 >image-v1.tar`,
+regctl image export registry.example.org/repo:v1 >image-v1.tar
+
+# export an image without a large layer
+regctl image export --layer-rm-digest sha256:50d858e0985ecc7f60418aaf0cc5ab587f42c2570a884095a9e8ccacd0f6545c \
+  registry.example.org/repo:v1 >image-v1.tar`,
 		Args:              cobra.RangeArgs(1, 2),
 		ValidArgsFunction: rOpts.completeArgTag,
 		RunE:              opts.runImageExport,
 	}
 	cmd.Flags().BoolVar(&opts.exportCompress, "compress", false, "Compress output with gzip")
+	imageLayerFilterFlags(cmd, &opts)
 	cmd.Flags().StringVar(&opts.exportRef, "name", "", "Name of image to embed for docker load")
 	cmd.Flags().StringVarP(&opts.platform, "platform", "p", "", "Specify platform (e.g. linux/amd64 or local)")
 	_ = cmd.RegisterFlagCompletionFunc("platform", completeArgPlatform)
@@ -264,10 +442,16 @@ func newImageGetFileCmd(rOpts *rootOpts) *cobra.Command {
 		Use:     "get-file <image_ref> <filename> [out-file]",
 		Aliases: []string{"cat"},
 		Short:   "get a file from an image",
-		Long:    `Go through each of the image layers searching for the requested file.`,
+		Long: `Go through each of the image layers, from the top down, searching for the requested file.
+The file is returned from the merged filesystem of the image.
+Whiteouts in upper layers hide the file in lower layers,
+and symlinks, including symlinked parent directories, are followed.`,
 		Example: `
 # get the alpine-release file from the latest alpine image
-regctl image get-file --platform local alpine /etc/alpine-release`,
+regctl image get-file --platform local alpine /etc/alpine-release
+
+# get the os-release file, following the symlink to /usr/lib/os-release
+regctl image get-file --platform local debian /etc/os-release`,
 		Args:              cobra.RangeArgs(2, 3),
 		ValidArgsFunction: completeArgList([]completeFunc{rOpts.completeArgTag, completeArgNone, completeArgNone}),
 		RunE:              opts.runImageGetFile,
@@ -279,6 +463,40 @@ regctl image get-file --platform local alpine /etc/alpine-release`,
 	return cmd
 }
 
+func newImageHistoryCmd(rOpts *rootOpts) *cobra.Command {
+	opts := imageOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "history <image_ref>",
+		Short: "show the build history of an image",
+		Long: `Shows the history entries from the image config, aligned with the layer digests and sizes.
+Entries that did not create a filesystem change are listed without a layer.
+Build provenance attestations are also reported, including attestations in a Docker index
+(created by buildkit) and OCI referrers with an in-toto provenance predicate.
+The output defaults to a table, use "--format '{{json .}}'" for a JSON report.`,
+		Example: `
+# show the history of the local platform
+regctl image history registry.example.org/repo:v1
+
+# show the full commands for the linux/arm64 platform
+regctl image history --platform linux/arm64 --no-trunc registry.example.org/repo:v1
+
+# list the digests of the provenance attestations
+regctl image history registry.example.org/repo:v1 \
+  --format '{{ range .Provenance }}{{ println .Digest }}{{ end }}'`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: rOpts.completeArgTag,
+		RunE:              opts.runImageHistory,
+	}
+	cmd.Flags().StringVar(&opts.format, "format", "table", "Format output with go template syntax (use \"table\" for a summary)")
+	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	cmd.Flags().BoolVar(&opts.noTrunc, "no-trunc", false, "Do not truncate the commands and digests in the table output")
+	cmd.Flags().StringVarP(&opts.platform, "platform", "p", "", "Specify platform (e.g. linux/amd64 or local)")
+	_ = cmd.RegisterFlagCompletionFunc("platform", completeArgPlatform)
+	return cmd
+}
+
 func newImageImportCmd(rOpts *rootOpts) *cobra.Command {
 	opts := imageOpts{
 		rootOpts: rOpts,
@@ -309,10 +527,20 @@ func newImageInspectCmd(rOpts *rootOpts) *cobra.Command {
 		Aliases: []string{"config"},
 		Short:   "inspect image",
 		Long: `Shows the config json for an image and is equivalent to pulling the image
-in docker, and inspecting it, but without pulling any of the image layers.`,
+in docker, and inspecting it, but without pulling any of the image layers.
+Layers with external URLs, commonly found in Windows images, are reported with a warning
+and are available in the "--format" template as ".ExternalLayers".`,
 		Example: `
 # return the image config for the nginx image
-regctl image inspect --platform local nginx`,
+regctl image inspect --platform local nginx
+
+# show the entrypoint of the image
+regctl image inspect --platform local nginx --query .config.Entrypoint
+
+# list the external layer URLs of a Windows image
+regctl image inspect --platform windows/amd64 \
+  registry.example.org/windows-app:v1 \
+  --format '{{ range .ExternalLayers }}{{ println .URLs }}{{ end }}'`,
 		Args:              cobra.ExactArgs(1),
 		ValidArgsFunction: rOpts.completeArgTag,
 		RunE:              opts.runImageInspect,
@@ -321,6 +549,68 @@ regctl image inspect --platform local nginx`,
 	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
 	cmd.Flags().StringVarP(&opts.platform, "platform", "p", "", "Specify platform (e.g. linux/amd64 or local)")
 	_ = cmd.RegisterFlagCompletionFunc("platform", completeArgPlatform)
+	cmd.Flags().StringVar(&opts.query, "query", "", "Output fields selected with a jq or JSONPath style query (e.g. .config.Env)")
+	_ = cmd.RegisterFlagCompletionFunc("query", completeArgNone)
+	cmd.MarkFlagsMutuallyExclusive("format", "query")
+	return cmd
+}
+
+func newImageLintCmd(rOpts *rootOpts) *cobra.Command {
+	opts := imageOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "lint <image_ref>",
+		Short: "check an image against a policy",
+		Long: fmt.Sprintf(`Checks each platform of an image against a policy, reporting the findings.
+The checks include:
+  user: the image runs as root
+  labels: a label from --require-label is missing
+  latest: the image or base image annotation uses the "latest" tag
+  layer-size: a layer is larger than --max-layer-size
+  layer-count: the image has more than --max-layers layers
+  secrets: a layer includes a file name that commonly contains a secret, this pulls every layer
+  sbom: the image has no SBOM referrer
+  signature: the image has no signature referrer or cosign signature tag
+Checks are skipped with --skip. Findings for "labels" and "secrets" are errors, and the others are warnings.
+The command exits with 1 when a finding is at or above the --fail-on severity, and 2 when the image cannot be checked.
+The output defaults to a table, use "--format '{{json .}}'" for a JSON report.
+Default secret patterns: %s`, strings.Join(imageLintSecretPatterns, ", ")),
+		Example: `
+# lint an image with the default checks
+regctl image lint registry.example.org/repo:v1
+
+# require labels and fail on warnings, without pulling layers to look for secrets
+regctl image lint registry.example.org/repo:v1 --fail-on warning --skip secrets \
+  --require-label org.opencontainers.image.source \
+  --require-label org.opencontainers.image.revision
+
+# output each finding as JSON
+regctl image lint registry.example.org/repo:v1 --format '{{ range .Findings }}{{ json . }}{{ println }}{{ end }}'`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: rOpts.completeArgTag,
+		RunE:              opts.runImageLint,
+	}
+	cmd.Flags().StringVar(&opts.lintFailOn, "fail-on", "error", "Minimum severity of a finding to fail (error, warning, none)")
+	_ = cmd.RegisterFlagCompletionFunc("fail-on", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"error", "warning", "none"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.Flags().StringVar(&opts.format, "format", "table", "Format output with go template syntax (use \"table\" for a summary)")
+	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	cmd.Flags().StringVar(&opts.lintMaxLayerSize, "max-layer-size", "1GB", "Maximum compressed size of a layer")
+	_ = cmd.RegisterFlagCompletionFunc("max-layer-size", completeArgNone)
+	cmd.Flags().IntVar(&opts.lintMaxLayers, "max-layers", 50, "Maximum number of layers in an image")
+	_ = cmd.RegisterFlagCompletionFunc("max-layers", completeArgNone)
+	cmd.Flags().StringArrayVarP(&opts.platforms, "platform", "p", []string{}, "Limit to specific platforms (e.g. linux/amd64 or local), may be repeated")
+	_ = cmd.RegisterFlagCompletionFunc("platform", completeArgPlatform)
+	cmd.Flags().StringArrayVar(&opts.lintRequireLabels, "require-label", []string{}, "Label required in the image config, may be repeated")
+	_ = cmd.RegisterFlagCompletionFunc("require-label", completeArgNone)
+	cmd.Flags().StringArrayVar(&opts.lintSecretPatterns, "secret-pattern", []string{}, "Additional file pattern for the secrets check (e.g. \"*.tfstate\" or \".config/gh/hosts.yml\"), may be repeated")
+	_ = cmd.RegisterFlagCompletionFunc("secret-pattern", completeArgNone)
+	cmd.Flags().StringArrayVar(&opts.lintSkip, "skip", []string{}, "Check to skip, may be repeated")
+	_ = cmd.RegisterFlagCompletionFunc("skip", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return imageLintChecks, cobra.ShellCompDirectiveNoFileComp
+	})
 	return cmd
 }
 
@@ -348,7 +638,20 @@ func newImageModCmd(rOpts *rootOpts) *cobra.Command {
   after=${time_in_rfc3339}: adjust any time after this
   base-ref=${image}: image to lookup base layers, which are skipped
   base-layers=${count}: number of layers to skip changing (from the base image)
-  Note: set or from-label is required in the time options`,
+  Note: set or from-label is required in the time options
+
+  Referrers of a modified image in the same repository are updated to the new digest by default.
+  The --referrers option selects which referrers follow the modified image, including to another repository.
+  Signatures are only valid for the original digest, and attestations include the original digest in the statement.
+  Digest tags, like the cosign "sha256-<digest>.sig" tags, are copied to the new digest with --digest-tags,
+  where the ".sig" suffix is a signature and ".att" is an attestation for the referrers policy.
+
+  Layer compression is applied after the other layer changes, and only layers using a different
+  compression are changed unless --force-recompress is set.
+
+  The --label-add and --env-add values are Go templates expanded for each image config, only the config is changed.
+  The template data includes .Name, the current .Value, the .Labels and .Env maps, and the .Platform of the config.
+  An empty result deletes the label or environment variable.`,
 		Example: `
 # add an annotation to all images, replacing the v1 tag with the new image
 regctl image mod registry.example.org/repo:v1 \
@@ -357,6 +660,19 @@ regctl image mod registry.example.org/repo:v1 \
 # convert an image to the OCI media types, copying to local registry
 regctl image mod alpine:3.5 --to-oci --create registry.example.org/alpine:3.5
 
+# copy an image without the build attestations and SBOMs
+regctl image mod registry.example.org/repo:v1 --attestations-rm \
+  --create registry.example.org/public/repo:v1
+
+# run every platform as a non-root user with a single exposed port
+regctl image mod registry.example.org/repo:v1 --create v1-hardened \
+  --config-user 65534:65534 --config-workdir /app --config-expose 8080/tcp
+
+# update labels and env variables in every platform using the existing values
+regctl image mod registry.example.org/repo:v1 --replace \
+  --label-add 'org.opencontainers.image.version={{ .Value }}-patch1' \
+  --env-add 'PATH={{ .Value }}:/opt/app/bin' --env-rm DEBUG
+
 # append a layer to only the linux/amd64 image using the file.tar contents
 regctl image mod registry.example.org/repo:v1 --create v1-extended \
   --layer-add "tar=file.tar,platform=linux/amd64"
@@ -377,6 +693,26 @@ regctl image mod registry.example.org/repo:v1 --create v1-bash \
 regctl image mod registry.example.org/repo:v1 --create v1-env \
   --env "[linux/arm64]LD_PRELOAD="
 
+# embed the foreign layers of a windows image hosted by mcr.microsoft.com
+regctl image mod registry.example.org/repo:windows --create windows-embed \
+  --external-layers "embed,host=mcr.microsoft.com"
+
+# add an annotation, moving the SBOMs and attestations to the new image but not the signatures
+regctl image mod registry.example.org/repo:v1 --replace \
+  --annotation "org.example.reviewed=true" --referrers attestations
+
+# redact a token accidentally included in a config file of every layer
+regctl image mod registry.example.org/repo:v1 --create v1-scrubbed \
+  --file-scrub 'file=etc/app/*.conf,regex=token=[a-z0-9]+,replace=token=REDACTED'
+
+# split the filesystem into a layer per top-level directory, with python packages in a separate layer
+regctl image mod registry.example.org/repo:v1 --create v1-reslice \
+  --layer-reslice "usr/lib/python3/site-packages=python"
+
+# recompress all layers with gzip at the maximum level
+regctl image mod registry.example.org/repo:v1 --create v1-gzip9 \
+  --layer-compress gzip --layer-compress-level 9 --force-recompress
+
 # Rebase an older regctl image, copying to the local registry.
 # This uses annotations that were included in the original image build.
 regctl image mod registry.example.org/regctl:v0.5.1-alpine \
@@ -450,6 +786,20 @@ regctl image mod registry.example.org/regctl:v0.5.1-alpine \
 		},
 	}, "annotation-promote", "", `promote common annotations from child images to index`)
 	flagAnnotationPromote.NoOptDefVal = "true"
+	flagAttestationsRm := cmd.Flags().VarPF(&modFlagFunc{
+		t: "bool",
+		f: func(val string) error {
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return fmt.Errorf("unable to parse value %s: %w", val, err)
+			}
+			if b {
+				opts.modOpts = append(opts.modOpts, mod.WithAttestationsRm())
+			}
+			return nil
+		},
+	}, "attestations-rm", "", `remove attestations and SBOMs from an index and the referrers of the image`)
+	flagAttestationsRm.NoOptDefVal = "true"
 	cmd.Flags().Var(&modFlagFunc{
 		t: "string",
 		f: func(val string) error {
@@ -506,6 +856,19 @@ regctl image mod registry.example.org/regctl:v0.5.1-alpine \
 			return nil
 		},
 	}, "config-entrypoint", `set entrypoint in the config (json array or string, empty string to delete)`)
+	cmd.Flags().Var(&modFlagFunc{
+		t: "string",
+		f: func(val string) error {
+			ports := []string{}
+			for port := range strings.SplitSeq(val, ",") {
+				if port = strings.TrimSpace(port); port != "" {
+					ports = append(ports, port)
+				}
+			}
+			opts.modOpts = append(opts.modOpts, mod.WithConfigExpose(ports))
+			return nil
+		},
+	}, "config-expose", `replace the exposed ports in the config (comma separated list, e.g. 8080/tcp,53/udp, empty string to delete)`)
 	cmd.Flags().Var(&modFlagFunc{
 		t: "string",
 		f: func(val string) error {
@@ -555,6 +918,20 @@ regctl image mod registry.example.org/regctl:v0.5.1-alpine \
 		},
 	}, "config-time-max", `max timestamp for a config`)
 	_ = cmd.Flags().MarkHidden("config-time-max") // TODO: deprecate config-time-max in favor of config-time
+	cmd.Flags().Var(&modFlagFunc{
+		t: "string",
+		f: func(val string) error {
+			opts.modOpts = append(opts.modOpts, mod.WithConfigUser(val))
+			return nil
+		},
+	}, "config-user", `set the user in the config (user, uid, or uid:gid, empty string to run as root)`)
+	cmd.Flags().Var(&modFlagFunc{
+		t: "string",
+		f: func(val string) error {
+			opts.modOpts = append(opts.modOpts, mod.WithConfigWorkingDir(val))
+			return nil
+		},
+	}, "config-workdir", `set the working directory in the config (absolute path, empty string to delete)`)
 	cmd.Flags().Var(&modFlagFunc{
 		t: "stringArray",
 		f: func(val string) error {
@@ -573,6 +950,20 @@ regctl image mod registry.example.org/regctl:v0.5.1-alpine \
 			return nil
 		},
 	}, "digest-algo", `change the digest algorithm (sha256, sha512)`)
+	flagDigestTags := cmd.Flags().VarPF(&modFlagFunc{
+		t: "bool",
+		f: func(val string) error {
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return fmt.Errorf("unable to parse value %s: %w", val, err)
+			}
+			if b {
+				opts.modOpts = append(opts.modOpts, mod.WithDigestTags())
+			}
+			return nil
+		},
+	}, "digest-tags", "", `copy digest tags ("sha256-<digest>.*") to the new digest, selected with the referrers policy`)
+	flagDigestTags.NoOptDefVal = "true"
 	cmd.Flags().Var(&modFlagFunc{
 		t: "stringArray",
 		f: func(val string) error {
@@ -587,6 +978,27 @@ regctl image mod registry.example.org/regctl:v0.5.1-alpine \
 			return nil
 		},
 	}, "env", `set an environment variable (name=value, omit value to delete, prefix with platform list [p1,p2] for subset of images)`)
+	cmd.Flags().Var(&modFlagFunc{
+		t: "stringArray",
+		f: func(val string) error {
+			name, tmpl, ok := strings.Cut(val, "=")
+			if !ok || name == "" {
+				return fmt.Errorf("env-add must be name=template")
+			}
+			opts.modOpts = append(opts.modOpts, mod.WithEnvTemplate(name, tmpl))
+			return nil
+		},
+	}, "env-add", `set an environment variable from a template (name=template, prefix with platform list [p1,p2] for subset of images)`)
+	cmd.Flags().Var(&modFlagFunc{
+		t: "stringArray",
+		f: func(val string) error {
+			if val == "" {
+				return fmt.Errorf("env-rm requires a name")
+			}
+			opts.modOpts = append(opts.modOpts, mod.WithEnv(val, ""))
+			return nil
+		},
+	}, "env-rm", `delete an environment variable (prefix with platform list [p1,p2] for subset of images)`)
 	cmd.Flags().Var(&modFlagFunc{
 		t: "stringArray",
 		f: func(val string) error {
@@ -615,6 +1027,60 @@ regctl image mod registry.example.org/regctl:v0.5.1-alpine \
 		},
 	}, "external-urls-rm", "", `remove external url references from layers (first copy image with "--include-external")`)
 	flagExtURLsRm.NoOptDefVal = "true"
+	cmd.Flags().Var(&modFlagFunc{
+		t: "string",
+		f: func(val string) error {
+			policyStr, hostList, _ := strings.Cut(val, ",")
+			policy, err := regclient.ExternalPolicyParse(policyStr)
+			if err != nil {
+				return err
+			}
+			hosts := []string{}
+			if hostList != "" {
+				for kv := range strings.SplitSeq(hostList, ",") {
+					k, v, ok := strings.Cut(kv, "=")
+					if !ok || k != "host" || v == "" {
+						return fmt.Errorf("invalid external layer option %q, expected host=<name>", kv)
+					}
+					hosts = append(hosts, v)
+				}
+			}
+			opts.modOpts = append(opts.modOpts, mod.WithExternalLayers(policy, hosts...))
+			return nil
+		},
+	}, "external-layers", `handling of external layers (skip, copy, embed), optionally followed by allowed hosts (e.g. "embed,host=mcr.microsoft.com")`)
+	cmd.Flags().Var(&modFlagFunc{
+		t: "stringArray",
+		f: func(val string) error {
+			kvSplit, err := strparse.SplitCSKV(val)
+			if err != nil {
+				return fmt.Errorf("failed to parse file-scrub options %s", val)
+			}
+			for k := range kvSplit {
+				if k != "file" && k != "regex" && k != "replace" {
+					return fmt.Errorf("unknown file-scrub option: %s", k)
+				}
+			}
+			if kvSplit["file"] == "" || kvSplit["regex"] == "" {
+				return fmt.Errorf("file and regex must be included in file-scrub")
+			}
+			re, err := regexp.Compile(kvSplit["regex"])
+			if err != nil {
+				return fmt.Errorf("regexp value is invalid: %w", err)
+			}
+			if !opts.fileScrubLog {
+				opts.fileScrubLog = true
+				opts.modOpts = append(opts.modOpts, mod.WithFileContentScrubCallback(func(fs mod.FileScrub) {
+					opts.rootOpts.log.Info("Scrubbed file",
+						slog.String("layer", fs.Layer.String()),
+						slog.String("file", fs.Name),
+						slog.Int("matches", fs.Matches))
+				}))
+			}
+			opts.modOpts = append(opts.modOpts, mod.WithFileContentScrub(kvSplit["file"], re, kvSplit["replace"]))
+			return nil
+		},
+	}, "file-scrub", `replace content in files within the layers, set file=${glob},regex=${expr},replace=${value}`)
 	cmd.Flags().Var(&modFlagFunc{
 		t: "stringArray",
 		f: func(val string) error {
@@ -671,6 +1137,27 @@ regctl image mod registry.example.org/regctl:v0.5.1-alpine \
 			return nil
 		},
 	}, "label", `set an label (name=value, omit value to delete, prefix with platform list [p1,p2] for subset of images)`)
+	cmd.Flags().Var(&modFlagFunc{
+		t: "stringArray",
+		f: func(val string) error {
+			name, tmpl, ok := strings.Cut(val, "=")
+			if !ok || name == "" {
+				return fmt.Errorf("label-add must be name=template")
+			}
+			opts.modOpts = append(opts.modOpts, mod.WithLabelTemplate(name, tmpl))
+			return nil
+		},
+	}, "label-add", `set a label from a template (name=template, prefix with platform list [p1,p2] for subset of images)`)
+	cmd.Flags().Var(&modFlagFunc{
+		t: "stringArray",
+		f: func(val string) error {
+			if val == "" {
+				return fmt.Errorf("label-rm requires a name")
+			}
+			opts.modOpts = append(opts.modOpts, mod.WithLabel(val, ""))
+			return nil
+		},
+	}, "label-rm", `delete a label (prefix with platform list [p1,p2] for subset of images)`)
 	flagLabelAnnot := cmd.Flags().VarPF(&modFlagFunc{
 		t: "bool",
 		f: func(val string) error {
@@ -741,19 +1228,45 @@ regctl image mod registry.example.org/regctl:v0.5.1-alpine \
 			return nil
 		},
 	}, "layer-add", `add a new layer (tar=file,dir=directory,platform=val)`)
+	cmd.Flags().StringVar(&opts.layerCompress, "layer-compress", "", `change layer compression (gzip, none, zstd)`)
+	_ = cmd.RegisterFlagCompletionFunc("layer-compress", completeArgLayerCompress)
+	cmd.Flags().IntVar(&opts.layerCompressLevel, "layer-compress-level", 0, `compression level for layer-compress (gzip 1-9, zstd 1-22)`)
+	_ = cmd.RegisterFlagCompletionFunc("layer-compress-level", completeArgNone)
+	cmd.Flags().BoolVar(&opts.forceRecompress, "force-recompress", false, `recompress layers already using the layer-compress algorithm`)
+	flagEStargz := cmd.Flags().VarPF(&modFlagFunc{
+		t: "bool",
+		f: func(val string) error {
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return fmt.Errorf("unable to parse value %s: %w", val, err)
+			}
+			if b {
+				opts.modOpts = append(opts.modOpts, mod.WithLayerEStargz())
+			}
+			return nil
+		},
+	}, "layer-estargz", "", `convert layers to eStargz for lazy pulling`)
+	flagEStargz.NoOptDefVal = "true"
 	cmd.Flags().Var(&modFlagFunc{
 		t: "string",
 		f: func(val string) error {
-			var algo archive.CompressType
-			err := algo.UnmarshalText([]byte(val))
-			if err != nil {
-				return fmt.Errorf("unknown layer compression %s", val)
+			mapping := map[string]string{}
+			if val != "dir" {
+				kvSplit, err := strparse.SplitCSKV(val)
+				if err != nil {
+					return fmt.Errorf("failed to parse layer-reslice options %s", val)
+				}
+				for k, v := range kvSplit {
+					if strings.Trim(k, "/") == "" && v == "" {
+						return fmt.Errorf("layer-reslice must be dir or path=layer")
+					}
+					mapping[k] = v
+				}
 			}
-			opts.modOpts = append(opts.modOpts,
-				mod.WithLayerCompression(algo))
+			opts.modOpts = append(opts.modOpts, mod.WithLayerReslice(mapping))
 			return nil
 		},
-	}, "layer-compress", `change layer compression (gzip, none, zstd)`)
+	}, "layer-reslice", `replace layers with a layer per top-level directory, optionally mapping paths to named layers (dir, path=layer)`)
 	cmd.Flags().Var(&modFlagFunc{
 		t: "string",
 		f: func(val string) error {
@@ -870,6 +1383,20 @@ regctl image mod registry.example.org/regctl:v0.5.1-alpine \
 		},
 	}, "reproducible", "", `fix tar headers for reproducibility`)
 	flagReproducible.NoOptDefVal = "true"
+	cmd.Flags().Var(&modFlagFunc{
+		t: "string",
+		f: func(val string) error {
+			policy, err := mod.ReferrerPolicyParse(val)
+			if err != nil {
+				return err
+			}
+			opts.modOpts = append(opts.modOpts, mod.WithReferrers(policy))
+			return nil
+		},
+	}, "referrers", `referrers to update with the modified image (all, attestations, unsigned, none), signatures are skipped by attestations and unsigned`)
+	_ = cmd.RegisterFlagCompletionFunc("referrers", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"all", "attestations", "unsigned", "none"}, cobra.ShellCompDirectiveNoFileComp
+	})
 	cmd.Flags().Var(&modFlagFunc{
 		t: "string",
 		f: func(val string) error {
@@ -971,6 +1498,45 @@ regctl image mod registry.example.org/regctl:v0.5.1-alpine \
 	return cmd
 }
 
+func newImagePromoteCmd(rOpts *rootOpts) *cobra.Command {
+	opts := imageOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "promote <src_image_ref> <dst_image_ref>",
+		Short: "promote an image to an immutable tag",
+		Long: `Promote an image by copying it to a target tag.
+The source is resolved to a digest before the copy.
+If the target tag already exists with a different digest, the promotion fails unless "--force" is set.
+A promotion record may be pushed as a referrer to the image in the target repository.`,
+		Example: `
+# promote an image from staging to production
+regctl image promote \
+  registry.example.org/staging/app:v1.2.3 registry.example.org/prod/app:v1.2.3
+
+# promote only if the source matches a digest
+regctl image promote --digest sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855 \
+  registry.example.org/staging/app:v1.2.3 registry.example.org/prod/app:v1.2.3
+
+# push a promotion record with an annotation
+regctl image promote --record --annotation org.example.approver=alice \
+  registry.example.org/staging/app:v1.2.3 registry.example.org/prod/app:v1.2.3`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: rOpts.completeArgTag,
+		RunE:              opts.runImagePromote,
+	}
+	cmd.Flags().StringArrayVar(&opts.annotations, "annotation", []string{}, "Annotation to set on the promotion record (name=value)")
+	cmd.Flags().StringVar(&opts.promoteDigest, "digest", "", "Require the source to match a digest")
+	_ = cmd.RegisterFlagCompletionFunc("digest", completeArgNone)
+	cmd.Flags().BoolVar(&opts.digestTags, "digest-tags", false, "Include digest tags (\"sha256-<digest>.*\") when copying manifests")
+	cmd.Flags().BoolVar(&opts.promoteForce, "force", false, "Replace the target tag if it points to a different digest")
+	cmd.Flags().StringVar(&opts.format, "format", "", "Format output with go template syntax")
+	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	cmd.Flags().BoolVar(&opts.promoteRecord, "record", false, "Push a promotion record as a referrer to the image")
+	cmd.Flags().BoolVar(&opts.referrers, "referrers", false, "Include referrers")
+	return cmd
+}
+
 func newImageRateLimitCmd(rOpts *rootOpts) *cobra.Command {
 	opts := imageOpts{
 		rootOpts: rOpts,
@@ -997,49 +1563,156 @@ regctl image ratelimit alpine --format '{{.Remain}}'`,
 	return cmd
 }
 
-func imageParseOptTime(s string) (mod.OptTime, map[string]string, error) {
-	ot := mod.OptTime{}
-	otherFields := map[string]string{}
-	for ss := range strings.SplitSeq(s, ",") {
-		kv := strings.SplitN(ss, "=", 2)
-		if len(kv) != 2 {
-			return ot, otherFields, fmt.Errorf("parameter without a value: %s", ss)
-		}
-		switch kv[0] {
-		case "set":
-			t, err := time.Parse(time.RFC3339, kv[1])
-			if err != nil {
-				return ot, otherFields, fmt.Errorf("set time must be formatted %s: %w", time.RFC3339, err)
-			}
-			ot.Set = t
-		case "after":
-			t, err := time.Parse(time.RFC3339, kv[1])
-			if err != nil {
-				return ot, otherFields, fmt.Errorf("after time must be formatted %s: %w", time.RFC3339, err)
-			}
-			ot.After = t
-		case "from-label":
-			ot.FromLabel = kv[1]
-		case "base-ref":
-			r, err := ref.New(kv[1])
-			if err != nil {
-				return ot, otherFields, fmt.Errorf("failed to parse base ref: %w", err)
-			}
-			ot.BaseRef = r
-		case "base-layers":
-			i, err := strconv.Atoi(kv[1])
-			if err != nil {
-				return ot, otherFields, fmt.Errorf("unable to parse base layer count: %w", err)
-			}
-			ot.BaseLayers = i
-		default:
-			otherFields[kv[0]] = kv[1]
-		}
+func newImageSetBaseAnnotationsCmd(rOpts *rootOpts) *cobra.Command {
+	opts := imageOpts{
+		rootOpts: rOpts,
 	}
-	return ot, otherFields, nil
-}
+	cmd := &cobra.Command{
+		Use:     "set-base-annotations <image_ref>",
+		Aliases: []string{"set-base"},
+		Short:   "set the base image and provenance annotations",
+		Long: `Sets the well-known OCI annotations for the base image, created time, source, and revision on an image.
+The base image digest is looked up from the base image when it is not provided.
+Only the top level manifest is annotated, the platform manifests of an index are unchanged.
+Annotations are validated before the image is pushed, and an empty value removes an annotation.
+The modified image is pushed by digest unless "--replace" or "--create" is used.`,
+		Example: `
+# annotate the base image, replacing the tag
+regctl image set-base-annotations --replace   --base docker.io/library/alpine:3 registry.example.org/repo:v1
 
-func (opts *imageOpts) runImageCheckBase(cmd *cobra.Command, args []string) error {
+# annotate the build provenance to a new tag
+regctl image set-base-annotations --create v1-annotated   --created now --source https://github.com/example/repo --revision "$(git rev-parse HEAD)"   registry.example.org/repo:v1`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: rOpts.completeArgTag,
+		RunE:              opts.runImageSetBaseAnnotations,
+	}
+	cmd.Flags().StringVar(&opts.checkBaseRef, "base", "", "Base image reference (including tag)")
+	cmd.Flags().StringVar(&opts.checkBaseDigest, "digest", "", "Base image digest (defaults to the current digest of the base image)")
+	_ = cmd.RegisterFlagCompletionFunc("digest", completeArgNone)
+	cmd.Flags().StringVar(&opts.create, "create", "", "Create image or tag")
+	cmd.Flags().StringVar(&opts.created, "created", "", "Created timestamp to set (use \"now\" or RFC3339 syntax)")
+	_ = cmd.RegisterFlagCompletionFunc("created", completeArgNone)
+	cmd.Flags().BoolVar(&opts.replace, "replace", false, "Replace tag (ignored when \"create\" is used)")
+	cmd.Flags().StringVar(&opts.revision, "revision", "", "Source control revision of the image")
+	_ = cmd.RegisterFlagCompletionFunc("revision", completeArgNone)
+	cmd.Flags().StringVar(&opts.source, "source", "", "URL of the source code for the image")
+	_ = cmd.RegisterFlagCompletionFunc("source", completeArgNone)
+	return cmd
+}
+
+func newImageSizeCmd(rOpts *rootOpts) *cobra.Command {
+	opts := imageOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "size <image_ref>",
+		Short: "show the size of an image",
+		Long: `Shows the size of each platform in an image, and the total size of the unique blobs.
+Sizes include the config and layers, using the compressed size from the manifest.
+The uncompressed size uses the layer annotations when available, and otherwise pulls and decompresses each layer.
+The output defaults to a table, use "--format '{{json .}}'" for a JSON report.`,
+		Example: `
+# show the compressed size of each platform
+regctl image size registry.example.org/repo:v1
+
+# include the uncompressed size of the linux/amd64 platform
+regctl image size --uncompressed --platform linux/amd64 registry.example.org/repo:v1
+
+# output the total size in bytes
+regctl image size registry.example.org/repo:v1 --format '{{ .Size }}'`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: rOpts.completeArgTag,
+		RunE:              opts.runImageSize,
+	}
+	cmd.Flags().StringVar(&opts.format, "format", "table", "Format output with go template syntax (use \"table\" for a summary)")
+	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	cmd.Flags().StringArrayVarP(&opts.platforms, "platform", "p", []string{}, "Limit to specific platforms (e.g. linux/amd64 or local), may be repeated")
+	_ = cmd.RegisterFlagCompletionFunc("platform", completeArgPlatform)
+	cmd.Flags().BoolVar(&opts.uncompressed, "uncompressed", false, "Include the uncompressed size, may pull layers")
+	return cmd
+}
+
+func newImageVerifyDigestsCmd(rOpts *rootOpts) *cobra.Command {
+	opts := imageOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:     "verify-digests <image_ref>",
+		Aliases: []string{"verify"},
+		Short:   "verify the digests of an image",
+		Long: `Pulls every manifest and blob of an image, including each platform of an index,
+and recalculates the digest to verify the stored content.
+This is useful for auditing a registry or OCI Layout suspected of corrupting content.
+Referrers and external layers are not verified unless requested.
+The output includes each manifest and blob, and the command fails if any digest cannot be verified.`,
+		Example: `
+# verify every blob in an image
+regctl image verify-digests registry.example.org/repo:v1
+
+# verify the linux/amd64 and linux/arm64 platforms in an OCI Layout
+regctl image verify-digests --platform linux/amd64 --platform linux/arm64 \
+  ocidir://repo:v1
+
+# output only the failures
+regctl image verify-digests registry.example.org/repo:v1 \
+  --format '{{ range .Entries }}{{ if .Err }}{{ println .Descriptor.Digest .Err }}{{ end }}{{ end }}'`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: rOpts.completeArgTag,
+		RunE:              opts.runImageVerifyDigests,
+	}
+	cmd.Flags().StringVar(&opts.format, "format", "", "Format output with go template syntax")
+	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	cmd.Flags().BoolVar(&opts.includeExternal, "include-external", false, "Include external layers")
+	cmd.Flags().IntVar(&opts.parallel, "parallel", 4, "Number of concurrent blob downloads")
+	_ = cmd.RegisterFlagCompletionFunc("parallel", completeArgNone)
+	cmd.Flags().StringArrayVarP(&opts.platforms, "platform", "p", []string{}, "Verify only specific platforms (e.g. linux/amd64 or local), may be repeated")
+	_ = cmd.RegisterFlagCompletionFunc("platform", completeArgPlatform)
+	return cmd
+}
+
+func imageParseOptTime(s string) (mod.OptTime, map[string]string, error) {
+	ot := mod.OptTime{}
+	otherFields := map[string]string{}
+	for ss := range strings.SplitSeq(s, ",") {
+		kv := strings.SplitN(ss, "=", 2)
+		if len(kv) != 2 {
+			return ot, otherFields, fmt.Errorf("parameter without a value: %s", ss)
+		}
+		switch kv[0] {
+		case "set":
+			t, err := time.Parse(time.RFC3339, kv[1])
+			if err != nil {
+				return ot, otherFields, fmt.Errorf("set time must be formatted %s: %w", time.RFC3339, err)
+			}
+			ot.Set = t
+		case "after":
+			t, err := time.Parse(time.RFC3339, kv[1])
+			if err != nil {
+				return ot, otherFields, fmt.Errorf("after time must be formatted %s: %w", time.RFC3339, err)
+			}
+			ot.After = t
+		case "from-label":
+			ot.FromLabel = kv[1]
+		case "base-ref":
+			r, err := ref.New(kv[1])
+			if err != nil {
+				return ot, otherFields, fmt.Errorf("failed to parse base ref: %w", err)
+			}
+			ot.BaseRef = r
+		case "base-layers":
+			i, err := strconv.Atoi(kv[1])
+			if err != nil {
+				return ot, otherFields, fmt.Errorf("unable to parse base layer count: %w", err)
+			}
+			ot.BaseLayers = i
+		default:
+			otherFields[kv[0]] = kv[1]
+		}
+	}
+	return ot, otherFields, nil
+}
+
+func (opts *imageOpts) runImageCheckBase(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	r, err := ref.New(args[0])
 	if err != nil {
@@ -1062,26 +1735,40 @@ func (opts *imageOpts) runImageCheckBase(cmd *cobra.Command, args []string) erro
 		rcOpts = append(rcOpts, regclient.ImageWithPlatform(opts.platform))
 	}
 
-	err = rc.ImageCheckBase(ctx, r, rcOpts...)
-	if err == nil {
+	report, err := rc.ImageCheckBaseReport(ctx, r, rcOpts...)
+	if err != nil {
+		return exitCodeError{code: 2, err: err}
+	}
+	if report.Changed {
+		opts.rootOpts.log.Info("base image mismatch",
+			slog.String("reason", report.Reason))
+	} else {
 		opts.rootOpts.log.Info("base image matches")
-		if !opts.quiet {
-			fmt.Fprintf(cmd.OutOrStdout(), "base image matches\n")
+	}
+	if opts.format != "" {
+		err = template.Writer(cmd.OutOrStdout(), opts.format, report)
+		if err != nil {
+			return exitCodeError{code: 2, err: err}
 		}
-	} else if errors.Is(err, errs.ErrMismatch) {
-		opts.rootOpts.log.Info("base image mismatch",
-			slog.String("err", err.Error()))
-		// return empty error message
-		err = fmt.Errorf("%.0w", err)
-		if !opts.quiet {
+	} else if !opts.quiet {
+		if report.Changed {
 			fmt.Fprintf(cmd.OutOrStdout(), "base image has changed\n")
+		} else {
+			fmt.Fprintf(cmd.OutOrStdout(), "base image matches\n")
 		}
 	}
-	return err
+	if report.Changed {
+		// return empty error message
+		return exitCodeError{code: 1, err: fmt.Errorf("%.0w", errs.ErrMismatch)}
+	}
+	return nil
 }
 
 func (opts *imageOpts) runImageCopy(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
+	if repo, pattern, ok := imageCopyTagPattern(args[0], opts.tagRegex); ok {
+		return opts.imageCopyTags(cmd, repo, pattern, args[1])
+	}
 	rSrc, err := ref.New(args[0])
 	if err != nil {
 		return err
@@ -1107,75 +1794,297 @@ func (opts *imageOpts) runImageCopy(cmd *cobra.Command, args []string) error {
 		}
 		rSrc = rSrc.AddDigest(m.GetDescriptor().Digest.String())
 	}
+	compressOpt, err := opts.layerCompressOpt()
+	if err != nil {
+		return err
+	}
+	filterOpt, err := opts.layerFilterOpt()
+	if err != nil {
+		return err
+	}
+	if compressOpt != nil || filterOpt != nil {
+		return opts.imageCopyMod(cmd, rc, rSrc, rTgt, compressOpt, filterOpt)
+	}
 	opts.rootOpts.log.Debug("Image copy",
 		slog.String("source", rSrc.CommonName()),
 		slog.String("target", rTgt.CommonName()),
 		slog.Bool("recursive", opts.forceRecursive),
 		slog.Bool("digest-tags", opts.digestTags))
+	rcOpts, err := opts.imageCopyOpts()
+	if err != nil {
+		return err
+	}
+	// check for a tty and attach progress reporter
+	done := make(chan bool)
+	var progress *imageProgress
+	if !opts.quiet && !opts.dryRun && !flagChanged(cmd, "verbosity") && ascii.IsWriterTerminal(cmd.ErrOrStderr()) {
+		progress = &imageProgress{
+			start:    time.Now(),
+			entries:  map[string]*imageProgressEntry{},
+			asciiOut: ascii.NewLines(cmd.ErrOrStderr()),
+			bar:      ascii.NewProgressBar(cmd.ErrOrStderr()),
+		}
+		ticker := time.NewTicker(progressFreq)
+		defer ticker.Stop()
+		go func() {
+			for {
+				select {
+				case <-done:
+					ticker.Stop()
+					return
+				case <-ticker.C:
+					progress.display(false)
+				}
+			}
+		}()
+		rcOpts = append(rcOpts, regclient.ImageWithCallback(progress.callback))
+	}
+	report, err := rc.ImageCopyWithReport(ctx, rSrc, rTgt, rcOpts...)
+	if progress != nil {
+		close(done)
+		progress.display(true)
+	}
+	if err != nil {
+		return err
+	}
+	opts.rootOpts.log.Info("Image copy complete",
+		slog.String("source", rSrc.CommonName()),
+		slog.String("target", rTgt.CommonName()),
+		slog.Bool("manifest-only", report.ManifestOnly()),
+		slog.Int("manifests-pushed", report.ManifestsPushed),
+		slog.Int("manifests-skipped", report.ManifestsSkipped),
+		slog.Int("referrers", report.ReferrersCopied),
+		slog.Int("blobs-mounted", report.BlobsMounted),
+		slog.Int("blobs-pulled", report.BlobsPulled),
+		slog.Int("blobs-skipped", report.BlobsSkipped),
+		slog.String("bytes-pulled", units.HumanSize(float64(report.BytesPulled))),
+		slog.Bool("dry-run", report.DryRun),
+		slog.Duration("duration", report.Duration.Round(time.Millisecond)))
+	if opts.dryRun {
+		if !flagChanged(cmd, "format") {
+			opts.format = `{{ range .Plan }}{{ .Action }} {{ .Target }} {{ .Descriptor.Size }}
+{{ end }}`
+		}
+		return template.Writer(cmd.OutOrStdout(), opts.format, report)
+	}
+	return opts.imageCopyOutput(cmd, rc, rTgt)
+}
+
+// imageCopyTagPattern returns the repository and tag pattern when the source tag should be expanded to a list of tags.
+// The pattern is always a regexp, globs are converted.
+func imageCopyTagPattern(arg string, tagRegex bool) (string, string, bool) {
+	i := strings.LastIndex(arg, "/") + 1
+	j := strings.LastIndex(arg[i:], ":")
+	if j < 0 || strings.Contains(arg[i:], "@") {
+		return "", "", false
+	}
+	repo, tag := arg[:i+j], arg[i+j+1:]
+	if tagRegex {
+		return repo, tag, true
+	}
+	if !strings.ContainsAny(tag, "*?[") {
+		return "", "", false
+	}
+	// convert the glob to a regexp, escaping everything outside of a character class
+	pattern := strings.Builder{}
+	inClass := false
+	for _, c := range tag {
+		switch {
+		case inClass:
+			pattern.WriteRune(c)
+			inClass = c != ']'
+		case c == '*':
+			pattern.WriteString(".*")
+		case c == '?':
+			pattern.WriteString(".")
+		case c == '[':
+			pattern.WriteRune(c)
+			inClass = true
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return repo, pattern.String(), true
+}
+
+// imageCopyTags copies each tag in the source repository matching the pattern to the same tag in the target repository.
+func (opts *imageOpts) imageCopyTags(cmd *cobra.Command, repo, pattern, tgt string) error {
+	ctx := cmd.Context()
+	re, err := regexp.Compile("^" + pattern + "$")
+	if err != nil {
+		return fmt.Errorf("failed to parse tag pattern \"%s\": %w", pattern, err)
+	}
+	for _, name := range []string{"layer-compress", "layer-rm-digest", "layer-rm-media-type", "layer-keep-media-type", "platform"} {
+		if flagChanged(cmd, name) {
+			return fmt.Errorf("--%s cannot be used with a tag pattern%.0w", name, errs.ErrUnsupported)
+		}
+	}
+	// the target may end with a ":" to match the source syntax
+	tgt = strings.TrimSuffix(tgt, ":")
+	if strings.ContainsAny(tgt[strings.LastIndex(tgt, "/")+1:], ":@") {
+		return fmt.Errorf("target %s must be a repository without a tag when copying a tag pattern%.0w", tgt, ErrInvalidInput)
+	}
+	rSrc, err := ref.New(repo)
+	if err != nil {
+		return err
+	}
+	rTgt, err := ref.New(tgt)
+	if err != nil {
+		return err
+	}
+	if (opts.referrerSrc != "" || opts.referrerTgt != "") && !opts.referrers {
+		return fmt.Errorf("referrers must be enabled to specify an external referrers source or target%.0w", errs.ErrUnsupported)
+	}
+	rcOpts, err := opts.imageCopyOpts()
+	if err != nil {
+		return err
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, rSrc)
+	defer rc.Close(ctx, rTgt)
+	tl, err := rc.TagList(ctx, rSrc)
+	if err != nil {
+		return fmt.Errorf("failed to list tags in %s: %w", rSrc.CommonName(), err)
+	}
+	tags, err := tl.GetTags()
+	if err != nil {
+		return fmt.Errorf("failed to list tags in %s: %w", rSrc.CommonName(), err)
+	}
+	ops := []regclient.BatchOp{}
+	for _, tag := range tags {
+		if re.MatchString(tag) {
+			ops = append(ops, regclient.BatchOpCopy(rSrc.SetTag(tag), rTgt.SetTag(tag), rcOpts...))
+		}
+	}
+	if len(ops) == 0 {
+		return fmt.Errorf("no tags in %s match %s%.0w", rSrc.CommonName(), pattern, errs.ErrNotFound)
+	}
+	opts.rootOpts.log.Debug("Image copy tags",
+		slog.String("source", rSrc.CommonName()),
+		slog.String("target", rTgt.CommonName()),
+		slog.String("pattern", pattern),
+		slog.Int("tags", len(ops)),
+		slog.Int("parallel", opts.parallel))
+	if opts.dryRun {
+		for _, op := range ops {
+			fmt.Fprintf(cmd.OutOrStdout(), "copy %s %s\n", op.Ref.CommonName(), op.Target.CommonName())
+		}
+		return nil
+	}
+	report, err := rc.Batch(ctx, ops, regclient.BatchWithWorkers(opts.parallel))
+	for _, res := range report.Results {
+		if res.Err != nil {
+			continue
+		}
+		opts.rootOpts.log.Info("Image copy complete",
+			slog.String("source", res.Op.Ref.CommonName()),
+			slog.String("target", res.Op.Target.CommonName()),
+			slog.Duration("duration", res.Duration.Round(time.Millisecond)))
+		if err := opts.imageCopyOutput(cmd, rc, res.Op.Target); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy %d of %d tags: %w", report.Errors(), len(ops), err)
+	}
+	return nil
+}
+
+// imageCopyOpts returns the options for an image copy from the flags.
+func (opts *imageOpts) imageCopyOpts() ([]regclient.ImageOpts, error) {
+	var err error
 	rcOpts := []regclient.ImageOpts{}
+	if opts.authCheck {
+		rcOpts = append(rcOpts, regclient.ImageWithAuthCheck())
+	}
 	if opts.fastCheck {
 		rcOpts = append(rcOpts, regclient.ImageWithFastCheck())
 	}
+	if opts.force {
+		rcOpts = append(rcOpts, regclient.ImageWithForce())
+	}
 	if opts.forceRecursive {
 		rcOpts = append(rcOpts, regclient.ImageWithForceRecursive())
 	}
-	if opts.includeExternal {
+	if opts.externalPolicy != "" || len(opts.externalHosts) > 0 {
+		policy := regclient.ExternalCopy
+		if opts.externalPolicy != "" {
+			policy, err = regclient.ExternalPolicyParse(opts.externalPolicy)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if policy == regclient.ExternalEmbed {
+			return nil, fmt.Errorf("external policy %s changes the image digest, use \"regctl image mod --external-layers embed\"%.0w", policy, errs.ErrUnsupported)
+		}
+		if opts.includeExternal && policy != regclient.ExternalCopy {
+			return nil, fmt.Errorf("--include-external cannot be used with --external-policy %s%.0w", policy, errs.ErrUnsupported)
+		}
+		rcOpts = append(rcOpts, regclient.ImageWithExternalPolicy(policy, opts.externalHosts...))
+	} else if opts.includeExternal {
 		rcOpts = append(rcOpts, regclient.ImageWithIncludeExternal())
 	}
+	if opts.seekableVerify {
+		rcOpts = append(rcOpts, regclient.ImageWithSeekableVerify())
+	}
+	if opts.verify {
+		rcOpts = append(rcOpts, regclient.ImageWithVerify(opts.verifySample, nil))
+	}
 	if opts.digestTags {
 		rcOpts = append(rcOpts, regclient.ImageWithDigestTags())
 	}
+	if len(opts.digestTagPatterns) > 0 {
+		rcOpts = append(rcOpts, regclient.ImageWithDigestTagPatterns(opts.digestTagPatterns...))
+	}
+	if opts.dryRun {
+		rcOpts = append(rcOpts, regclient.ImageWithDryRun())
+	}
 	if opts.referrers {
 		rcOpts = append(rcOpts, regclient.ImageWithReferrers())
 	}
 	if opts.referrerSrc != "" {
 		referrerSrc, err := ref.New(opts.referrerSrc)
 		if err != nil {
-			return fmt.Errorf("failed parsing referrer external source: %w", err)
+			return nil, fmt.Errorf("failed parsing referrer external source: %w", err)
 		}
 		rcOpts = append(rcOpts, regclient.ImageWithReferrerSrc(referrerSrc))
 	}
 	if opts.referrerTgt != "" {
 		referrerTgt, err := ref.New(opts.referrerTgt)
 		if err != nil {
-			return fmt.Errorf("failed parsing referrer external target: %w", err)
+			return nil, fmt.Errorf("failed parsing referrer external target: %w", err)
 		}
 		rcOpts = append(rcOpts, regclient.ImageWithReferrerTgt(referrerTgt))
 	}
 	if len(opts.platforms) > 0 {
 		rcOpts = append(rcOpts, regclient.ImageWithPlatforms(opts.platforms))
 	}
-	// check for a tty and attach progress reporter
-	done := make(chan bool)
-	var progress *imageProgress
-	if !flagChanged(cmd, "verbosity") && ascii.IsWriterTerminal(cmd.ErrOrStderr()) {
-		progress = &imageProgress{
-			start:    time.Now(),
-			entries:  map[string]*imageProgressEntry{},
-			asciiOut: ascii.NewLines(cmd.ErrOrStderr()),
-			bar:      ascii.NewProgressBar(cmd.ErrOrStderr()),
-		}
-		ticker := time.NewTicker(progressFreq)
-		defer ticker.Stop()
-		go func() {
-			for {
-				select {
-				case <-done:
-					ticker.Stop()
-					return
-				case <-ticker.C:
-					progress.display(false)
-				}
+	if opts.copyAnnotate || len(opts.copyAnnotations) > 0 {
+		annotations := map[string]string{}
+		for _, a := range opts.copyAnnotations {
+			aSplit := strings.SplitN(a, "=", 2)
+			if len(aSplit) == 1 {
+				annotations[aSplit[0]] = ""
+			} else {
+				annotations[aSplit[0]] = aSplit[1]
 			}
-		}()
-		rcOpts = append(rcOpts, regclient.ImageWithCallback(progress.callback))
+		}
+		rcOpts = append(rcOpts, regclient.ImageWithCopyAnnotations(annotations))
 	}
-	err = rc.ImageCopy(ctx, rSrc, rTgt, rcOpts...)
-	if progress != nil {
-		close(done)
-		progress.display(true)
+	if opts.provenance {
+		rcOpts = append(rcOpts, regclient.ImageWithProvenance())
 	}
-	if err != nil {
+	return rcOpts, nil
+}
+
+// imageCopyOutput writes the target of a copy, or only the digest in quiet mode.
+func (opts *imageOpts) imageCopyOutput(cmd *cobra.Command, rc *regclient.RegClient, rTgt ref.Ref) error {
+	if opts.quiet {
+		m, err := rc.ManifestHead(cmd.Context(), rTgt, regclient.WithManifestRequireDigest())
+		if err != nil {
+			return fmt.Errorf("failed to get digest of %s: %w", rTgt.CommonName(), err)
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), m.GetDescriptor().Digest.String())
 		return err
 	}
 	if !flagChanged(cmd, "format") {
@@ -1184,6 +2093,41 @@ func (opts *imageOpts) runImageCopy(cmd *cobra.Command, args []string) error {
 	return template.Writer(cmd.OutOrStdout(), opts.format, rTgt)
 }
 
+// imageCopyMod copies an image while changing the layer compression or removing layers, which modifies the image digest.
+func (opts *imageOpts) imageCopyMod(cmd *cobra.Command, rc *regclient.RegClient, rSrc, rTgt ref.Ref, compressOpt, filterOpt mod.Opts) error {
+	ctx := cmd.Context()
+	modFlag := "layer-compress"
+	modOpts := []mod.Opts{mod.WithRefTgt(rTgt)}
+	if compressOpt != nil {
+		modOpts = append(modOpts, compressOpt)
+	}
+	if filterOpt != nil {
+		if compressOpt == nil {
+			modFlag = "layer-rm-*"
+		}
+		modOpts = append(modOpts, filterOpt)
+	}
+	for _, name := range []string{"copy-annotation", "copy-annotations", "digest-tag-pattern", "digest-tags", "dry-run", "external-host", "external-policy", "fast", "force", "force-recursive", "include-external", "platforms", "provenance", "referrers", "referrers-src", "referrers-tgt", "verify", "verify-seekable"} {
+		if flagChanged(cmd, name) {
+			return fmt.Errorf("--%s cannot be used with --%s%.0w", name, modFlag, errs.ErrUnsupported)
+		}
+	}
+	opts.rootOpts.log.Debug("Image copy with modified layers",
+		slog.String("source", rSrc.CommonName()),
+		slog.String("target", rTgt.CommonName()),
+		slog.String("compress", opts.layerCompress),
+		slog.Int("level", opts.layerCompressLevel),
+		slog.Bool("recompress", opts.forceRecompress),
+		slog.Any("rm-digests", opts.layerRmDigests),
+		slog.Any("rm-media-types", opts.layerRmTypes),
+		slog.Any("keep-media-types", opts.layerKeepTypes))
+	rOut, err := mod.Apply(ctx, rc, rSrc, modOpts...)
+	if err != nil {
+		return err
+	}
+	return opts.imageCopyOutput(cmd, rc, rOut)
+}
+
 type imageProgress struct {
 	mu       sync.Mutex
 	start    time.Time
@@ -1479,10 +2423,37 @@ func (opts *imageOpts) runImageExport(cmd *cobra.Command, args []string) error {
 	if opts.exportCompress {
 		rcOpts = append(rcOpts, regclient.ImageWithExportCompress())
 	}
-	if opts.exportRef != "" {
-		eRef, err := ref.New(opts.exportRef)
-		if err != nil {
-			return fmt.Errorf("cannot parse %s: %w", opts.exportRef, err)
+	filterOpt, err := opts.layerFilterOpt()
+	if err != nil {
+		return err
+	}
+	if filterOpt != nil {
+		// layers are removed in a temporary OCI Layout that is exported with the original name
+		tempDir, err := os.MkdirTemp("", "regctl-export-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tempDir)
+		rTmp, err := ref.New("ocidir://" + tempDir + "/export")
+		if err != nil {
+			return err
+		}
+		if r.Tag != "" {
+			rTmp = rTmp.SetTag(r.Tag)
+		}
+		rOut, err := mod.Apply(ctx, rc, r, mod.WithRefTgt(rTmp), filterOpt)
+		if err != nil {
+			return err
+		}
+		if opts.exportRef == "" {
+			rcOpts = append(rcOpts, regclient.ImageWithExportRef(r))
+		}
+		r = rOut
+	}
+	if opts.exportRef != "" {
+		eRef, err := ref.New(opts.exportRef)
+		if err != nil {
+			return fmt.Errorf("cannot parse %s: %w", opts.exportRef, err)
 		}
 		rcOpts = append(rcOpts, regclient.ImageWithExportRef(eRef))
 	}
@@ -1513,81 +2484,478 @@ func (opts *imageOpts) runImageGetFile(cmd *cobra.Command, args []string) error
 	if opts.platform == "" {
 		opts.platform = "local"
 	}
-	p, err := platform.Parse(opts.platform)
+	ifs, err := rc.ImageFS(ctx, r, regclient.ImageWithPlatform(opts.platform))
 	if err != nil {
-		opts.rootOpts.log.Warn("Could not parse platform",
-			slog.String("platform", opts.platform),
-			slog.String("err", err.Error()))
+		return err
 	}
-	m, err := rc.ManifestGet(ctx, r, regclient.WithManifestPlatform(p))
+	f, err := ifs.OpenContext(ctx, filename)
 	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && !errors.Is(err, errs.ErrFileDeleted) {
+			return fmt.Errorf("%s not found in any layer%.0w", filename, errs.ErrNotFound)
+		}
 		return err
 	}
-	// go through layers in reverse
-	mi, ok := m.(manifest.Imager)
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	th, ok := fi.Sys().(*tar.Header)
 	if !ok {
-		return fmt.Errorf("reference is not a known image media type")
+		return fmt.Errorf("unexpected file info for %s", filename)
+	}
+	if target := strings.TrimPrefix(path.Clean("/"+th.Name), "/"); target != filename {
+		opts.rootOpts.log.Debug("Followed link",
+			slog.String("filename", filename),
+			slog.String("target", target))
+	}
+	// file found, output
+	if opts.format != "" {
+		data := struct {
+			Header *tar.Header
+			Reader io.Reader
+		}{
+			Header: th,
+			Reader: f,
+		}
+		return template.Writer(cmd.OutOrStdout(), opts.format, data)
 	}
-	layers, err := mi.GetLayers()
+	w := cmd.OutOrStdout()
+	if len(args) >= 3 {
+		fh, err := os.Create(args[2])
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+		w = fh
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+const (
+	// annotationInTotoPredicateType is set by buildkit and other tools on in-toto attestation layers.
+	annotationInTotoPredicateType = "in-toto.io/predicate-type"
+	// dockerReferenceType and dockerReferenceDigest are set by buildkit on attestation manifests in an index.
+	dockerReferenceType   = "vnd.docker.reference.type"
+	dockerReferenceDigest = "vnd.docker.reference.digest"
+	// dockerReferenceTypeAttestation is the value of dockerReferenceType for attestations.
+	dockerReferenceTypeAttestation = "attestation-manifest"
+)
+
+// imageHistoryReport is the output of "regctl image history".
+type imageHistoryReport struct {
+	Ref        string                   `json:"ref"`
+	Digest     digest.Digest            `json:"digest"`
+	Platform   string                   `json:"platform"`
+	History    []imageHistoryEntry      `json:"history"`
+	Provenance []imageHistoryProvenance `json:"provenance"`
+}
+
+// imageHistoryEntry is a history entry from the config with the layer it created.
+type imageHistoryEntry struct {
+	v1.History
+	Layer *descriptor.Descriptor `json:"layer,omitempty"` // Layer is nil for entries without a filesystem change.
+}
+
+// imageHistoryProvenance is a provenance attestation of the image.
+type imageHistoryProvenance struct {
+	Digest        digest.Digest `json:"digest"`        // Digest of the attestation manifest.
+	Source        string        `json:"source"`        // Source is "index" for Docker attestation manifests, or "referrer".
+	PredicateType string        `json:"predicateType"` // PredicateType of the in-toto statement.
+}
+
+func (opts *imageOpts) runImageHistory(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	r, err := ref.New(args[0])
+	if err != nil {
+		return err
+	}
+	pStr := opts.platform
+	if pStr == "" {
+		pStr = "local"
+	}
+	p, err := platform.Parse(pStr)
+	if err != nil {
+		return fmt.Errorf("failed to parse platform %s: %w", pStr, err)
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, r)
+
+	opts.rootOpts.log.Debug("Image history",
+		slog.String("host", r.Registry),
+		slog.String("repo", r.Repository),
+		slog.String("tag", r.Tag),
+		slog.String("platform", pStr))
+	m, err := rc.ManifestGet(ctx, r)
 	if err != nil {
 		return err
 	}
-	for i := len(layers) - 1; i >= 0; i-- {
-		blob, err := rc.BlobGet(ctx, r, layers[i])
+	mIndex := m
+	if m.IsList() {
+		d, err := manifest.GetPlatformDesc(m, &p)
 		if err != nil {
-			return fmt.Errorf("failed pulling layer %d: %w", i, err)
+			return err
 		}
-		btr, err := blob.ToTarReader()
+		m, err = rc.ManifestGet(ctx, r, regclient.WithManifestDesc(*d))
 		if err != nil {
-			return fmt.Errorf("could not convert layer %d to tar reader: %w", i, err)
+			return err
+		}
+	} else {
+		mIndex = nil
+	}
+	mi, ok := m.(manifest.Imager)
+	if !ok {
+		return fmt.Errorf("unsupported manifest type: %s%.0w", m.GetDescriptor().MediaType, errs.ErrUnsupportedMediaType)
+	}
+	cd, err := mi.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get image config: %w", err)
+	}
+	if cd.MediaType != mediatype.OCI1ImageConfig && cd.MediaType != mediatype.Docker2ImageConfig {
+		return fmt.Errorf("unsupported config media type %s, artifacts are not supported%.0w", cd.MediaType, errs.ErrUnsupportedMediaType)
+	}
+	oc, err := rc.BlobGetOCIConfig(ctx, r, cd)
+	if err != nil {
+		return err
+	}
+	layers, err := mi.GetLayers()
+	if err != nil {
+		return fmt.Errorf("failed to get layers: %w", err)
+	}
+	conf := oc.GetConfig()
+	report := imageHistoryReport{
+		Ref:        r.CommonName(),
+		Digest:     m.GetDescriptor().Digest,
+		Platform:   conf.Platform.String(),
+		History:    []imageHistoryEntry{},
+		Provenance: []imageHistoryProvenance{},
+	}
+	// align history with the layers, each entry that is not an empty layer created the next layer
+	li := 0
+	for _, h := range conf.History {
+		entry := imageHistoryEntry{History: h}
+		if !h.EmptyLayer && li < len(layers) {
+			entry.Layer = &layers[li]
+			li++
 		}
-		th, rdr, err := btr.ReadFile(filename)
+		report.History = append(report.History, entry)
+	}
+	if li < len(layers) {
+		opts.rootOpts.log.Warn("Image history does not include every layer",
+			slog.Int("history", li),
+			slog.Int("layers", len(layers)))
+		for ; li < len(layers); li++ {
+			report.History = append(report.History, imageHistoryEntry{Layer: &layers[li]})
+		}
+	}
+	// find provenance attestations added by buildkit to the index
+	if mIndex != nil {
+		dl, err := mIndex.(manifest.Indexer).GetManifestList()
 		if err != nil {
-			if errors.Is(err, errs.ErrFileNotFound) {
-				if err := btr.Close(); err != nil {
-					return err
-				}
-				if err := blob.Close(); err != nil {
-					return err
-				}
-				continue
-			}
-			return fmt.Errorf("failed pulling from layer %d: %w", i, err)
+			return err
 		}
-		// file found, output
-		if opts.format != "" {
-			data := struct {
-				Header *tar.Header
-				Reader io.Reader
-			}{
-				Header: th,
-				Reader: rdr,
+		for _, d := range dl {
+			if d.Annotations[dockerReferenceType] != dockerReferenceTypeAttestation || d.Annotations[dockerReferenceDigest] != report.Digest.String() {
+				continue
 			}
-			return template.Writer(cmd.OutOrStdout(), opts.format, data)
-		}
-		var w io.Writer
-		if len(args) < 3 {
-			w = cmd.OutOrStdout()
-		} else {
-			w, err = os.Create(args[2])
+			predicate, err := imageHistoryPredicate(ctx, rc, r, d)
 			if err != nil {
 				return err
 			}
+			if predicate != "" {
+				report.Provenance = append(report.Provenance, imageHistoryProvenance{Digest: d.Digest, Source: "index", PredicateType: predicate})
+			}
 		}
-		_, err = io.Copy(w, rdr)
+	}
+	// find provenance attestations in the referrers
+	rl, err := rc.ReferrerList(ctx, r.SetDigest(report.Digest.String()))
+	if err != nil {
+		opts.rootOpts.log.Warn("Failed to list referrers",
+			slog.String("ref", r.CommonName()),
+			slog.String("err", err.Error()))
+	}
+	for _, d := range rl.Descriptors {
+		predicate, err := imageHistoryPredicate(ctx, rc, r, d)
 		if err != nil {
 			return err
 		}
-		if err := btr.Close(); err != nil {
-			return err
+		if predicate != "" {
+			report.Provenance = append(report.Provenance, imageHistoryProvenance{Digest: d.Digest, Source: "referrer", PredicateType: predicate})
+		}
+	}
+	if opts.format == "table" {
+		return imageHistoryTable(cmd.OutOrStdout(), report, opts.noTrunc)
+	}
+	return template.Writer(cmd.OutOrStdout(), opts.format, report)
+}
+
+// imageHistoryPredicate returns the in-toto predicate type when an attestation contains provenance.
+// The descriptor annotations are checked before pulling the attestation manifest.
+func imageHistoryPredicate(ctx context.Context, rc *regclient.RegClient, r ref.Ref, d descriptor.Descriptor) (string, error) {
+	isProvenance := func(annot map[string]string) bool {
+		return strings.Contains(strings.ToLower(annot[annotationInTotoPredicateType]), "provenance")
+	}
+	if isProvenance(d.Annotations) {
+		return d.Annotations[annotationInTotoPredicateType], nil
+	}
+	m, err := rc.ManifestGet(ctx, r, regclient.WithManifestDesc(d))
+	if err != nil {
+		return "", err
+	}
+	mi, ok := m.(manifest.Imager)
+	if !ok {
+		return "", nil
+	}
+	layers, err := mi.GetLayers()
+	if err != nil {
+		return "", nil
+	}
+	for _, l := range layers {
+		if isProvenance(l.Annotations) {
+			return l.Annotations[annotationInTotoPredicateType], nil
+		}
+	}
+	return "", nil
+}
+
+// imageHistoryTable outputs the history report as a table.
+func imageHistoryTable(out io.Writer, report imageHistoryReport, noTrunc bool) error {
+	trunc := func(s string, l int) string {
+		if noTrunc || len(s) <= l {
+			return s
+		}
+		return s[:l-3] + "..."
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Created\tCreated By\tSize\tLayer\n")
+	for _, h := range report.History {
+		created, size, layer := "", "0B", ""
+		if h.Created != nil {
+			created = h.Created.UTC().Format(time.RFC3339)
+		}
+		if h.Layer != nil {
+			size = units.HumanSize(float64(h.Layer.Size))
+			layer = h.Layer.Digest.String()
+			if enc := h.Layer.Digest.Encoded(); !noTrunc && len(enc) > 12 {
+				layer = enc[:12]
+			}
+		}
+		createdBy := strings.Join(strings.Fields(h.CreatedBy), " ")
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", created, trunc(createdBy, 60), size, layer)
+	}
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+	for _, p := range report.Provenance {
+		fmt.Fprintf(out, "Provenance: %s (%s, %s)\n", p.Digest.String(), p.PredicateType, p.Source)
+	}
+	return nil
+}
+
+// imageDiffConfigReport is the output of "regctl image diff-config".
+type imageDiffConfigReport struct {
+	Ref1    string                  `json:"ref1"`
+	Ref2    string                  `json:"ref2"`
+	Changes []imageDiffConfigChange `json:"changes"`
+	History imageDiffConfigHistory  `json:"history"`
+}
+
+// imageDiffConfigChange is a changed setting in the image config.
+type imageDiffConfigChange struct {
+	Field  string `json:"field"`         // Field is the name of the setting, e.g. Env or Entrypoint.
+	Key    string `json:"key,omitempty"` // Key is the name of the variable, label, port, or volume.
+	Change string `json:"change"`        // Change is one of added, removed, or changed.
+	Old    string `json:"old,omitempty"` // Old is the value in the first image, lists are formatted as JSON.
+	New    string `json:"new,omitempty"` // New is the value in the second image, lists are formatted as JSON.
+}
+
+// imageDiffConfigHistory compares the history of two images.
+type imageDiffConfigHistory struct {
+	Common  int          `json:"common"`  // Common is the number of matching entries before the histories diverge.
+	Removed []v1.History `json:"removed"` // Removed entries are only in the first image after the divergence point.
+	Added   []v1.History `json:"added"`   // Added entries are only in the second image after the divergence point.
+}
+
+func (opts *imageOpts) runImageDiffConfig(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	r1, err := ref.New(args[0])
+	if err != nil {
+		return err
+	}
+	r2, err := ref.New(args[1])
+	if err != nil {
+		return err
+	}
+	pStr := opts.platform
+	if pStr == "" {
+		pStr = "local"
+	}
+	p, err := platform.Parse(pStr)
+	if err != nil {
+		return fmt.Errorf("failed to parse platform %s: %w", pStr, err)
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, r1)
+	defer rc.Close(ctx, r2)
+
+	opts.rootOpts.log.Debug("Image diff config",
+		slog.String("ref1", r1.CommonName()),
+		slog.String("ref2", r2.CommonName()),
+		slog.String("platform", pStr))
+	c1, err := imageConfigGet(ctx, rc, r1, p)
+	if err != nil {
+		return err
+	}
+	c2, err := imageConfigGet(ctx, rc, r2, p)
+	if err != nil {
+		return err
+	}
+	report := imageDiffConfig(c1, c2)
+	report.Ref1 = r1.CommonName()
+	report.Ref2 = r2.CommonName()
+	if opts.format == "table" {
+		return imageDiffConfigTable(cmd.OutOrStdout(), report)
+	}
+	return template.Writer(cmd.OutOrStdout(), opts.format, report)
+}
+
+// imageConfigGet returns the config of an image, selecting the platform from an index.
+func imageConfigGet(ctx context.Context, rc *regclient.RegClient, r ref.Ref, p platform.Platform) (v1.Image, error) {
+	m, err := rc.ManifestGet(ctx, r, regclient.WithManifestPlatform(p))
+	if err != nil {
+		return v1.Image{}, fmt.Errorf("failed to get manifest %s: %w", r.CommonName(), err)
+	}
+	mi, ok := m.(manifest.Imager)
+	if !ok || m.IsList() {
+		return v1.Image{}, fmt.Errorf("unsupported manifest type: %s%.0w", m.GetDescriptor().MediaType, errs.ErrUnsupportedMediaType)
+	}
+	cd, err := mi.GetConfig()
+	if err != nil {
+		return v1.Image{}, fmt.Errorf("failed to get image config: %w", err)
+	}
+	if cd.MediaType != mediatype.OCI1ImageConfig && cd.MediaType != mediatype.Docker2ImageConfig {
+		return v1.Image{}, fmt.Errorf("unsupported config media type %s, artifacts are not supported%.0w", cd.MediaType, errs.ErrUnsupportedMediaType)
+	}
+	oc, err := rc.BlobGetOCIConfig(ctx, r, cd)
+	if err != nil {
+		return v1.Image{}, err
+	}
+	return oc.GetConfig(), nil
+}
+
+// imageDiffConfig compares two image configs.
+func imageDiffConfig(c1, c2 v1.Image) imageDiffConfigReport {
+	report := imageDiffConfigReport{
+		Changes: []imageDiffConfigChange{},
+		History: imageDiffConfigHistory{
+			Removed: []v1.History{},
+			Added:   []v1.History{},
+		},
+	}
+	value := func(field, old, cur string) {
+		switch {
+		case old == cur:
+		case old == "":
+			report.Changes = append(report.Changes, imageDiffConfigChange{Field: field, Change: "added", New: cur})
+		case cur == "":
+			report.Changes = append(report.Changes, imageDiffConfigChange{Field: field, Change: "removed", Old: old})
+		default:
+			report.Changes = append(report.Changes, imageDiffConfigChange{Field: field, Change: "changed", Old: old, New: cur})
+		}
+	}
+	list := func(field string, l1, l2 []string) {
+		enc := func(l []string) string {
+			if l == nil {
+				return ""
+			}
+			b, _ := json.Marshal(l)
+			return string(b)
+		}
+		value(field, enc(l1), enc(l2))
+	}
+	keys := func(field string, m1, m2 map[string]string) {
+		for _, k := range slices.Sorted(maps.Keys(m1)) {
+			if v2, ok := m2[k]; !ok {
+				report.Changes = append(report.Changes, imageDiffConfigChange{Field: field, Key: k, Change: "removed", Old: m1[k]})
+			} else if v2 != m1[k] {
+				report.Changes = append(report.Changes, imageDiffConfigChange{Field: field, Key: k, Change: "changed", Old: m1[k], New: v2})
+			}
+		}
+		for _, k := range slices.Sorted(maps.Keys(m2)) {
+			if _, ok := m1[k]; !ok {
+				report.Changes = append(report.Changes, imageDiffConfigChange{Field: field, Key: k, Change: "added", New: m2[k]})
+			}
+		}
+	}
+	envMap := func(env []string) map[string]string {
+		m := map[string]string{}
+		for _, e := range env {
+			k, v, _ := strings.Cut(e, "=")
+			m[k] = v
+		}
+		return m
+	}
+	setMap := func(set map[string]struct{}) map[string]string {
+		m := map[string]string{}
+		for k := range set {
+			m[k] = ""
 		}
-		if err := blob.Close(); err != nil {
+		return m
+	}
+	value("Platform", c1.Platform.String(), c2.Platform.String())
+	value("User", c1.Config.User, c2.Config.User)
+	keys("Env", envMap(c1.Config.Env), envMap(c2.Config.Env))
+	list("Entrypoint", c1.Config.Entrypoint, c2.Config.Entrypoint)
+	list("Cmd", c1.Config.Cmd, c2.Config.Cmd)
+	value("WorkingDir", c1.Config.WorkingDir, c2.Config.WorkingDir)
+	keys("Labels", c1.Config.Labels, c2.Config.Labels)
+	keys("ExposedPorts", setMap(c1.Config.ExposedPorts), setMap(c2.Config.ExposedPorts))
+	keys("Volumes", setMap(c1.Config.Volumes), setMap(c2.Config.Volumes))
+	value("StopSignal", c1.Config.StopSignal, c2.Config.StopSignal)
+	list("Shell", c1.Config.Shell, c2.Config.Shell)
+	list("OnBuild", c1.Config.OnBuild, c2.Config.OnBuild)
+	// histories match until the command or layer change differs, the created time is ignored for rebuilt images
+	for report.History.Common < len(c1.History) && report.History.Common < len(c2.History) {
+		h1, h2 := c1.History[report.History.Common], c2.History[report.History.Common]
+		if h1.CreatedBy != h2.CreatedBy || h1.EmptyLayer != h2.EmptyLayer {
+			break
+		}
+		report.History.Common++
+	}
+	report.History.Removed = append(report.History.Removed, c1.History[report.History.Common:]...)
+	report.History.Added = append(report.History.Added, c2.History[report.History.Common:]...)
+	return report
+}
+
+func imageDiffConfigTable(out io.Writer, report imageDiffConfigReport) error {
+	trunc := func(s string) string {
+		s = strings.Join(strings.Fields(s), " ")
+		if len(s) <= 60 {
+			return s
+		}
+		return s[:57] + "..."
+	}
+	if len(report.Changes) > 0 {
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "Field\tKey\tChange\tOld\tNew\n")
+		for _, c := range report.Changes {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Field, c.Key, c.Change, trunc(c.Old), trunc(c.New))
+		}
+		err := tw.Flush()
+		if err != nil {
 			return err
 		}
-		return nil
 	}
-	// all layers exhausted, not found or deleted
-	return errs.ErrNotFound
+	fmt.Fprintf(out, "History: %d common, %d removed, %d added\n", report.History.Common, len(report.History.Removed), len(report.History.Added))
+	for _, h := range report.History.Removed {
+		fmt.Fprintf(out, "- %s\n", trunc(h.CreatedBy))
+	}
+	for _, h := range report.History.Added {
+		fmt.Fprintf(out, "+ %s\n", trunc(h.CreatedBy))
+	}
+	return nil
 }
 
 func (opts *imageOpts) runImageImport(cmd *cobra.Command, args []string) error {
@@ -1629,23 +2997,69 @@ func (opts *imageOpts) runImageInspect(cmd *cobra.Command, args []string) error
 		slog.String("tag", r.Tag),
 		slog.String("platform", opts.platform))
 
-	rcOpts := []regclient.ImageOpts{}
-	if opts.platform != "" {
-		rcOpts = append(rcOpts, regclient.ImageWithPlatform(opts.platform))
+	pStr := opts.platform
+	if pStr == "" {
+		pStr = "local"
 	}
-	blobConfig, err := rc.ImageConfig(ctx, r, rcOpts...)
+	p, err := platform.Parse(pStr)
+	if err != nil {
+		return fmt.Errorf("failed to parse platform %s: %w", pStr, err)
+	}
+	m, err := rc.ManifestGet(ctx, r, regclient.WithManifestPlatform(p))
+	if err != nil {
+		return fmt.Errorf("failed to get manifest: %w", err)
+	}
+	mi, ok := m.(manifest.Imager)
+	if !ok || m.IsList() {
+		return fmt.Errorf("unsupported manifest type: %s%.0w", m.GetDescriptor().MediaType, errs.ErrUnsupportedMediaType)
+	}
+	dConfig, err := mi.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get image config: %w", err)
+	}
+	if dConfig.MediaType != mediatype.OCI1ImageConfig && dConfig.MediaType != mediatype.Docker2ImageConfig {
+		return fmt.Errorf("artifacts are not supported with \"regctl image inspect\", use \"regctl artifact get --config\" instead: unsupported config media type %s%.0w", dConfig.MediaType, errs.ErrUnsupportedMediaType)
+	}
+	blobConfig, err := rc.BlobGetOCIConfig(ctx, r, dConfig)
 	if err != nil {
-		if errors.Is(err, errs.ErrUnsupportedMediaType) {
-			err = fmt.Errorf("artifacts are not supported with \"regctl image inspect\", use \"regctl artifact get --config\" instead: %w", err)
-		}
 		return err
 	}
+	// external layers are not pulled or copied by default, include them in the output for Windows images
+	layers, err := mi.GetLayers()
+	if err != nil {
+		return fmt.Errorf("failed to get image layers: %w", err)
+	}
+	extLayers := []descriptor.Descriptor{}
+	for _, l := range layers {
+		if len(l.URLs) > 0 {
+			extLayers = append(extLayers, l)
+		}
+	}
+	if len(extLayers) > 0 {
+		urls := []string{}
+		for _, l := range extLayers {
+			urls = append(urls, l.URLs...)
+		}
+		opts.rootOpts.log.Warn("Image includes external layers",
+			slog.String("ref", r.CommonName()),
+			slog.Int("count", len(extLayers)),
+			slog.Any("urls", urls))
+	}
+	if opts.query != "" {
+		raw, err := blobConfig.RawBody()
+		if err != nil {
+			return err
+		}
+		return queryWriter(cmd.OutOrStdout(), opts.query, raw)
+	}
 	result := struct {
 		*blob.BOCIConfig
 		v1.Image
+		ExternalLayers []descriptor.Descriptor `json:"-"`
 	}{
-		BOCIConfig: blobConfig,
-		Image:      blobConfig.GetConfig(),
+		BOCIConfig:     blobConfig,
+		Image:          blobConfig.GetConfig(),
+		ExternalLayers: extLayers,
 	}
 	switch opts.format {
 	case "raw":
@@ -1658,46 +3072,529 @@ func (opts *imageOpts) runImageInspect(cmd *cobra.Command, args []string) error
 	return template.Writer(cmd.OutOrStdout(), opts.format, result)
 }
 
-func (opts *imageOpts) runImageMod(cmd *cobra.Command, args []string) error {
-	ctx := cmd.Context()
-	rSrc, err := ref.New(args[0])
-	if err != nil {
-		return err
-	}
-	var rTgt ref.Ref
-	if opts.create != "" {
-		if strings.ContainsAny(opts.create, "/:") {
-			rTgt, err = ref.New((opts.create))
-			if err != nil {
-				return fmt.Errorf("failed to parse new image name %s: %w", opts.create, err)
-			}
-		} else {
-			rTgt = rSrc.SetTag(opts.create)
+// imageLintChecks are the checks run by "regctl image lint".
+var imageLintChecks = []string{"user", "labels", "latest", "layer-size", "layer-count", "secrets", "sbom", "signature"}
+
+// imageLintSecretPatterns are file names that commonly contain secrets.
+// Patterns with a "/" match the end of the path, and other patterns match the file name.
+var imageLintSecretPatterns = []string{
+	".aws/credentials",
+	".docker/config.json",
+	".env",
+	".git-credentials",
+	".netrc",
+	".npmrc",
+	".pypirc",
+	"*.p12",
+	"*.pfx",
+	"id_dsa",
+	"id_ecdsa",
+	"id_ed25519",
+	"id_rsa",
+}
+
+// imageLintLayerTypes are the tar layer media types searched by the secrets check.
+var imageLintLayerTypes = []string{
+	mediatype.Docker2Layer,
+	mediatype.Docker2LayerGzip,
+	mediatype.Docker2LayerZstd,
+	mediatype.OCI1Layer,
+	mediatype.OCI1LayerGzip,
+	mediatype.OCI1LayerZstd,
+}
+
+const (
+	imageLintError   = "error"
+	imageLintWarning = "warning"
+)
+
+// imageLintReport is the output of "regctl image lint".
+type imageLintReport struct {
+	Ref      string             `json:"ref"`
+	Digest   digest.Digest      `json:"digest"`
+	Findings []imageLintFinding `json:"findings"`
+}
+
+// imageLintFinding is a single policy violation.
+type imageLintFinding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`           // Severity is "error" or "warning".
+	Platform string `json:"platform,omitempty"` // Platform is empty for findings on the entire image.
+	Message  string `json:"message"`
+}
+
+// Errors returns the number of findings with an error severity.
+func (report imageLintReport) Errors() int {
+	count := 0
+	for _, f := range report.Findings {
+		if f.Severity == imageLintError {
+			count++
 		}
-	} else if opts.replace {
-		rTgt = rSrc
-	} else {
-		rTgt = rSrc.SetTag("")
 	}
-	opts.modOpts = append(opts.modOpts, mod.WithRefTgt(rTgt))
-	rc := opts.rootOpts.newRegClient()
+	return count
+}
 
-	opts.rootOpts.log.Debug("Modifying image",
-		slog.String("ref", rSrc.CommonName()))
+// Warnings returns the number of findings with a warning severity.
+func (report imageLintReport) Warnings() int {
+	return len(report.Findings) - report.Errors()
+}
 
-	defer rc.Close(ctx, rSrc)
-	rOut, err := mod.Apply(ctx, rc, rSrc, opts.modOpts...)
+func (opts *imageOpts) runImageLint(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	r, err := ref.New(args[0])
 	if err != nil {
 		return err
 	}
-	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", rOut.CommonName())
-	err = rc.Close(ctx, rOut)
-	if err != nil {
-		return fmt.Errorf("failed to close ref: %w", err)
+	switch opts.lintFailOn {
+	case imageLintError, imageLintWarning, "none":
+	default:
+		return fmt.Errorf("unknown fail-on severity %q, expected error, warning, or none%.0w", opts.lintFailOn, ErrInvalidInput)
+	}
+	for _, s := range opts.lintSkip {
+		if !slices.Contains(imageLintChecks, s) {
+			return fmt.Errorf("unknown check %q, expected one of %s%.0w", s, strings.Join(imageLintChecks, ", "), ErrInvalidInput)
+		}
+	}
+	enabled := func(check string) bool {
+		return !slices.Contains(opts.lintSkip, check)
+	}
+	maxLayerSize, err := units.ParseSize(opts.lintMaxLayerSize)
+	if err != nil {
+		return fmt.Errorf("failed to parse max-layer-size %s: %w", opts.lintMaxLayerSize, err)
+	}
+	secretPatterns := slices.Concat(imageLintSecretPatterns, opts.lintSecretPatterns)
+	for _, pattern := range secretPatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid secret pattern %s: %w", pattern, err)
+		}
+	}
+	platList := []platform.Platform{}
+	for _, pStr := range opts.platforms {
+		p, err := platform.Parse(pStr)
+		if err != nil {
+			return fmt.Errorf("failed to parse platform %s: %w", pStr, err)
+		}
+		platList = append(platList, p)
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, r)
+
+	opts.rootOpts.log.Debug("Image lint",
+		slog.String("host", r.Registry),
+		slog.String("repo", r.Repository),
+		slog.String("tag", r.Tag),
+		slog.Any("skip", opts.lintSkip))
+	m, err := rc.ManifestGet(ctx, r)
+	if err != nil {
+		return exitCodeError{code: 2, err: err}
+	}
+	report := imageLintReport{
+		Ref:      r.CommonName(),
+		Digest:   m.GetDescriptor().Digest,
+		Findings: []imageLintFinding{},
+	}
+	add := func(check, severity, p, msg string) {
+		report.Findings = append(report.Findings, imageLintFinding{Check: check, Severity: severity, Platform: p, Message: msg})
+	}
+	if enabled("latest") {
+		if r.Tag == "latest" {
+			add("latest", imageLintWarning, "", "image uses the latest tag")
+		}
+		// the base image of each platform is checked with the image
+		if rBase, _, err := manifest.GetBaseImage(m); err == nil && rBase.Tag == "latest" && m.IsList() {
+			add("latest", imageLintWarning, "", fmt.Sprintf("base image %s uses the latest tag", rBase.CommonName()))
+		}
+	}
+	// secrets found in each layer, cached for layers shared between platforms
+	layerSecrets := map[digest.Digest][]string{}
+	lintImage := func(m manifest.Manifest, p string) error {
+		mi, ok := m.(manifest.Imager)
+		if !ok {
+			return fmt.Errorf("unsupported manifest type: %s%.0w", m.GetDescriptor().MediaType, errs.ErrUnsupportedMediaType)
+		}
+		cd, err := mi.GetConfig()
+		if err != nil {
+			return fmt.Errorf("failed to get config: %w", err)
+		}
+		oc, err := rc.BlobGetOCIConfig(ctx, r, cd)
+		if err != nil {
+			return err
+		}
+		conf := oc.GetConfig()
+		if p == "" {
+			p = conf.Platform.String()
+		}
+		if enabled("user") && imageLintRoot(conf.Config.User) {
+			user := conf.Config.User
+			if user == "" {
+				user = "the default user"
+			}
+			add("user", imageLintWarning, p, fmt.Sprintf("image runs as root with %s", user))
+		}
+		if enabled("labels") {
+			for _, label := range opts.lintRequireLabels {
+				if conf.Config.Labels[label] == "" {
+					add("labels", imageLintError, p, fmt.Sprintf("required label %s is missing", label))
+				}
+			}
+		}
+		if enabled("latest") {
+			if rBase, _, err := manifest.GetBaseImage(m); err == nil && rBase.Tag == "latest" {
+				add("latest", imageLintWarning, p, fmt.Sprintf("base image %s uses the latest tag", rBase.CommonName()))
+			}
+		}
+		layers, err := mi.GetLayers()
+		if err != nil {
+			return fmt.Errorf("failed to get layers: %w", err)
+		}
+		if enabled("layer-count") && opts.lintMaxLayers > 0 && len(layers) > opts.lintMaxLayers {
+			add("layer-count", imageLintWarning, p, fmt.Sprintf("image has %d layers, more than %d", len(layers), opts.lintMaxLayers))
+		}
+		for _, l := range layers {
+			if enabled("layer-size") && maxLayerSize > 0 && l.Size > maxLayerSize {
+				add("layer-size", imageLintWarning, p, fmt.Sprintf("layer %s is %s, larger than %s", l.Digest.String(), units.HumanSize(float64(l.Size)), units.HumanSize(float64(maxLayerSize))))
+			}
+			if !enabled("secrets") || len(l.URLs) > 0 || !slices.Contains(imageLintLayerTypes, l.MediaType) {
+				continue
+			}
+			if _, ok := layerSecrets[l.Digest]; !ok {
+				layerSecrets[l.Digest], err = imageLintSecrets(ctx, rc, r, l, secretPatterns)
+				if err != nil {
+					return err
+				}
+			}
+			for _, name := range layerSecrets[l.Digest] {
+				add("secrets", imageLintError, p, fmt.Sprintf("layer %s includes %s", l.Digest.String(), name))
+			}
+		}
+		return nil
+	}
+	if mi, ok := m.(manifest.Indexer); ok {
+		dl, err := mi.GetManifestList()
+		if err != nil {
+			return exitCodeError{code: 2, err: err}
+		}
+		for _, d := range dl {
+			if d.Platform == nil || d.Platform.OS == "unknown" || !imageSizePlatformMatch(*d.Platform, platList) {
+				continue
+			}
+			mChild, err := rc.ManifestGet(ctx, r, regclient.WithManifestDesc(d))
+			if err != nil {
+				return exitCodeError{code: 2, err: err}
+			}
+			miChild, ok := mChild.(manifest.Imager)
+			if !ok {
+				continue
+			}
+			if cd, err := miChild.GetConfig(); err != nil || (cd.MediaType != mediatype.OCI1ImageConfig && cd.MediaType != mediatype.Docker2ImageConfig) {
+				// skip attestations and other artifacts
+				continue
+			}
+			err = lintImage(mChild, d.Platform.String())
+			if err != nil {
+				return exitCodeError{code: 2, err: err}
+			}
+		}
+	} else if mi, ok := m.(manifest.Imager); ok {
+		cd, err := mi.GetConfig()
+		if err != nil {
+			return exitCodeError{code: 2, err: fmt.Errorf("failed to get config: %w", err)}
+		}
+		if cd.MediaType != mediatype.OCI1ImageConfig && cd.MediaType != mediatype.Docker2ImageConfig {
+			return exitCodeError{code: 2, err: fmt.Errorf("unsupported config media type %s, artifacts are not supported%.0w", cd.MediaType, errs.ErrUnsupportedMediaType)}
+		}
+		err = lintImage(m, "")
+		if err != nil {
+			return exitCodeError{code: 2, err: err}
+		}
+	} else {
+		return exitCodeError{code: 2, err: fmt.Errorf("unsupported manifest type: %s%.0w", m.GetDescriptor().MediaType, errs.ErrUnsupportedMediaType)}
+	}
+	if enabled("sbom") || enabled("signature") {
+		hasSBOM, hasSig, err := imageLintReferrers(ctx, rc, r.SetDigest(report.Digest.String()), m)
+		if err != nil {
+			return exitCodeError{code: 2, err: err}
+		}
+		if enabled("sbom") && !hasSBOM {
+			add("sbom", imageLintWarning, "", "image does not have an SBOM")
+		}
+		if enabled("signature") && !hasSig {
+			add("signature", imageLintWarning, "", "image is not signed")
+		}
+	}
+
+	if opts.format == "table" {
+		err = imageLintTable(cmd.OutOrStdout(), report)
+	} else {
+		err = template.Writer(cmd.OutOrStdout(), opts.format, report)
+	}
+	if err != nil {
+		return exitCodeError{code: 2, err: err}
+	}
+	failed := 0
+	switch opts.lintFailOn {
+	case imageLintError:
+		failed = report.Errors()
+	case imageLintWarning:
+		failed = len(report.Findings)
+	}
+	if failed > 0 {
+		return exitCodeError{code: 1, err: fmt.Errorf("image %s has %d findings at or above %s%.0w", r.CommonName(), failed, opts.lintFailOn, errs.ErrMismatch)}
 	}
 	return nil
 }
 
+// imageLintRoot returns true when the config user is root, including the default empty user.
+func imageLintRoot(user string) bool {
+	user, _, _ = strings.Cut(user, ":")
+	return user == "" || user == "root" || user == "0"
+}
+
+// imageLintSecrets returns the files in a layer that match a secret pattern.
+// Files deleted by a later layer are included since they can still be extracted from the layer.
+func imageLintSecrets(ctx context.Context, rc *regclient.RegClient, r ref.Ref, d descriptor.Descriptor, patterns []string) ([]string, error) {
+	br, err := rc.BlobGet(ctx, r, d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull layer %s: %w", d.Digest.String(), err)
+	}
+	defer br.Close()
+	dr, err := archive.Decompress(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress layer %s: %w", d.Digest.String(), err)
+	}
+	found := []string{}
+	tr := tar.NewReader(dr)
+	for {
+		th, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read layer %s: %w", d.Digest.String(), err)
+		}
+		if th.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := strings.Trim(strings.TrimPrefix(th.Name, "./"), "/")
+		parts := strings.Split(name, "/")
+		for _, pattern := range patterns {
+			count := strings.Count(pattern, "/") + 1
+			if count > len(parts) {
+				continue
+			}
+			if match, _ := path.Match(pattern, strings.Join(parts[len(parts)-count:], "/")); match {
+				found = append(found, name)
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+// imageLintReferrers returns true when the image has an SBOM or signature.
+// SBOMs are SPDX, CycloneDX, or Syft artifacts, or in-toto attestations with an SPDX or CycloneDX predicate.
+// Signatures are cosign, notation, or sigstore bundle referrers, or a cosign "sha256-<hex>.sig" tag.
+func imageLintReferrers(ctx context.Context, rc *regclient.RegClient, r ref.Ref, m manifest.Manifest) (bool, bool, error) {
+	hasSBOM, hasSig := false, false
+	rl, err := rc.ReferrerList(ctx, r)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to list referrers: %w", err)
+	}
+	for _, d := range rl.Descriptors {
+		predicate := d.Annotations["in-toto.io/predicate-type"]
+		switch {
+		case d.ArtifactType == "application/spdx+json", d.ArtifactType == "application/vnd.cyclonedx+json", d.ArtifactType == "application/vnd.syft+json",
+			strings.Contains(predicate, "spdx"), strings.Contains(predicate, "cyclonedx"):
+			hasSBOM = true
+		case d.ArtifactType == "application/vnd.dev.cosign.artifact.sig.v1+json", d.ArtifactType == "application/vnd.cncf.notary.signature",
+			strings.HasPrefix(d.ArtifactType, "application/vnd.dev.sigstore.bundle") && (d.Annotations["dev.sigstore.bundle.predicateType"] == "" || d.Annotations["dev.sigstore.bundle.predicateType"] == "https://sigstore.dev/cosign/sign/v1"):
+			hasSig = true
+		}
+	}
+	if !hasSig {
+		_, err = rc.ManifestHead(ctx, r.SetTag(referrer.DigestTag(m.GetDescriptor().Digest, ".sig")))
+		if err == nil {
+			hasSig = true
+		} else if !errors.Is(err, errs.ErrNotFound) {
+			return false, false, fmt.Errorf("failed to check signature tag: %w", err)
+		}
+	}
+	return hasSBOM, hasSig, nil
+}
+
+// imageLintTable outputs the lint report as a table.
+func imageLintTable(out io.Writer, report imageLintReport) error {
+	if len(report.Findings) == 0 {
+		fmt.Fprintf(out, "no findings for %s\n", report.Ref)
+		return nil
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Severity\tCheck\tPlatform\tMessage\n")
+	for _, f := range report.Findings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Severity, f.Check, f.Platform, f.Message)
+	}
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Total: %d errors, %d warnings\n", report.Errors(), report.Warnings())
+	return nil
+}
+
+func (opts *imageOpts) runImageMod(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	rSrc, err := ref.New(args[0])
+	if err != nil {
+		return err
+	}
+	var rTgt ref.Ref
+	if opts.create != "" {
+		if strings.ContainsAny(opts.create, "/:") {
+			rTgt, err = ref.New((opts.create))
+			if err != nil {
+				return fmt.Errorf("failed to parse new image name %s: %w", opts.create, err)
+			}
+		} else {
+			rTgt = rSrc.SetTag(opts.create)
+		}
+	} else if opts.replace {
+		rTgt = rSrc
+	} else {
+		rTgt = rSrc.SetTag("")
+	}
+	opts.modOpts = append(opts.modOpts, mod.WithRefTgt(rTgt))
+	compressOpt, err := opts.layerCompressOpt()
+	if err != nil {
+		return err
+	}
+	if compressOpt != nil {
+		opts.modOpts = append(opts.modOpts, compressOpt)
+	}
+	rc := opts.rootOpts.newRegClient()
+
+	opts.rootOpts.log.Debug("Modifying image",
+		slog.String("ref", rSrc.CommonName()))
+
+	defer rc.Close(ctx, rSrc)
+	rOut, err := mod.Apply(ctx, rc, rSrc, opts.modOpts...)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", rOut.CommonName())
+	err = rc.Close(ctx, rOut)
+	if err != nil {
+		return fmt.Errorf("failed to close ref: %w", err)
+	}
+	return nil
+}
+
+// layerCompressOpt returns the mod option for the layer compression flags, or nil when compression is not changed.
+func (opts *imageOpts) layerCompressOpt() (mod.Opts, error) {
+	if opts.layerCompress == "" {
+		if opts.layerCompressLevel != 0 || opts.forceRecompress {
+			return nil, fmt.Errorf("layer-compress is required to set the compression level or recompress layers%.0w", errs.ErrUnsupported)
+		}
+		return nil, nil
+	}
+	var algo archive.CompressType
+	err := algo.UnmarshalText([]byte(opts.layerCompress))
+	if err != nil {
+		return nil, fmt.Errorf("unknown layer compression %s", opts.layerCompress)
+	}
+	err = archive.CompressLevelValidate(algo, opts.layerCompressLevel)
+	if err != nil {
+		return nil, err
+	}
+	if opts.forceRecompress {
+		return mod.WithLayerRecompress(algo, opts.layerCompressLevel), nil
+	}
+	return mod.WithLayerCompressionLevel(algo, opts.layerCompressLevel), nil
+}
+
+// imageLayerFilterFlags adds the flags to remove layers when copying or exporting an image.
+func imageLayerFilterFlags(cmd *cobra.Command, opts *imageOpts) {
+	cmd.Flags().StringArrayVar(&opts.layerKeepTypes, "layer-keep-media-type", []string{}, "Only include layers with this media type, this changes the image digest, may be repeated")
+	_ = cmd.RegisterFlagCompletionFunc("layer-keep-media-type", completeArgNone)
+	cmd.Flags().StringArrayVar(&opts.layerRmDigests, "layer-rm-digest", []string{}, "Remove the layer with this digest, this changes the image digest, may be repeated")
+	_ = cmd.RegisterFlagCompletionFunc("layer-rm-digest", completeArgNone)
+	cmd.Flags().StringArrayVar(&opts.layerRmTypes, "layer-rm-media-type", []string{}, "Remove layers with this media type, this changes the image digest, may be repeated")
+	_ = cmd.RegisterFlagCompletionFunc("layer-rm-media-type", completeArgNone)
+}
+
+// layerFilterOpt returns the mod option to remove layers, or nil when every layer is included.
+func (opts *imageOpts) layerFilterOpt() (mod.Opts, error) {
+	if len(opts.layerKeepTypes) == 0 && len(opts.layerRmDigests) == 0 && len(opts.layerRmTypes) == 0 {
+		return nil, nil
+	}
+	digests := make([]digest.Digest, 0, len(opts.layerRmDigests))
+	for _, ds := range opts.layerRmDigests {
+		d, err := digest.Parse(ds)
+		if err != nil {
+			return nil, fmt.Errorf("invalid layer digest %s: %w%.0w", ds, err, ErrInvalidInput)
+		}
+		digests = append(digests, d)
+	}
+	return mod.WithLayerRm(func(d descriptor.Descriptor) bool {
+		return slices.Contains(digests, d.Digest) ||
+			slices.Contains(opts.layerRmTypes, d.MediaType) ||
+			(len(opts.layerKeepTypes) > 0 && !slices.Contains(opts.layerKeepTypes, d.MediaType))
+	}), nil
+}
+
+func (opts *imageOpts) runImagePromote(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	rSrc, err := ref.New(args[0])
+	if err != nil {
+		return err
+	}
+	rTgt, err := ref.New(args[1])
+	if err != nil {
+		return err
+	}
+	if len(opts.annotations) > 0 && !opts.promoteRecord {
+		return fmt.Errorf("annotations require a promotion record%.0w", errs.ErrUnsupported)
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, rSrc)
+	defer rc.Close(ctx, rTgt)
+	rcOpts := []regclient.ImageOpts{}
+	if opts.promoteDigest != "" {
+		rcOpts = append(rcOpts, regclient.ImageWithPromoteDigest(opts.promoteDigest))
+	}
+	if opts.promoteForce {
+		rcOpts = append(rcOpts, regclient.ImageWithPromoteForce())
+	}
+	if opts.promoteRecord {
+		annotations := map[string]string{}
+		for _, a := range opts.annotations {
+			aSplit := strings.SplitN(a, "=", 2)
+			if len(aSplit) == 1 {
+				annotations[aSplit[0]] = ""
+			} else {
+				annotations[aSplit[0]] = aSplit[1]
+			}
+		}
+		rcOpts = append(rcOpts, regclient.ImageWithPromoteRecord(annotations))
+	}
+	if opts.digestTags {
+		rcOpts = append(rcOpts, regclient.ImageWithDigestTags())
+	}
+	if opts.referrers {
+		rcOpts = append(rcOpts, regclient.ImageWithReferrers())
+	}
+	opts.rootOpts.log.Debug("Image promote",
+		slog.String("source", rSrc.CommonName()),
+		slog.String("target", rTgt.CommonName()),
+		slog.Bool("force", opts.promoteForce))
+	err = rc.ImagePromote(ctx, rSrc, rTgt, rcOpts...)
+	if err != nil {
+		return err
+	}
+	if !flagChanged(cmd, "format") {
+		opts.format = "{{ .CommonName }}\n"
+	}
+	return template.Writer(cmd.OutOrStdout(), opts.format, rTgt)
+}
+
 func (opts *imageOpts) runImageRateLimit(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	r, err := ref.New(args[0])
@@ -1720,6 +3617,384 @@ func (opts *imageOpts) runImageRateLimit(cmd *cobra.Command, args []string) erro
 	return template.Writer(cmd.OutOrStdout(), opts.format, manifest.GetRateLimit(m))
 }
 
+func (opts *imageOpts) runImageSetBaseAnnotations(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	rSrc, err := ref.New(args[0])
+	if err != nil {
+		return err
+	}
+	var rTgt ref.Ref
+	if opts.create != "" {
+		if strings.ContainsAny(opts.create, "/:") {
+			rTgt, err = ref.New((opts.create))
+			if err != nil {
+				return fmt.Errorf("failed to parse new image name %s: %w", opts.create, err)
+			}
+		} else {
+			rTgt = rSrc.SetTag(opts.create)
+		}
+	} else if opts.replace {
+		rTgt = rSrc
+	} else {
+		rTgt = rSrc.SetTag("")
+	}
+	if flagChanged(cmd, "digest") && opts.checkBaseRef == "" {
+		return fmt.Errorf("digest requires a base image%.0w", errs.ErrMissingName)
+	}
+	if !flagChanged(cmd, "base") && !flagChanged(cmd, "created") && !flagChanged(cmd, "source") && !flagChanged(cmd, "revision") {
+		return fmt.Errorf("at least one annotation must be set%.0w", errs.ErrMissingAnnotation)
+	}
+	var created time.Time
+	if opts.created == "now" {
+		created = time.Now()
+	} else if opts.created != "" {
+		created, err = time.Parse(time.RFC3339, opts.created)
+		if err != nil {
+			return fmt.Errorf("failed to parse created time %s: %w", opts.created, err)
+		}
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, rSrc)
+
+	m, err := rc.ManifestGet(ctx, rSrc)
+	if err != nil {
+		return err
+	}
+	dSrc := m.GetDescriptor().Digest
+	if flagChanged(cmd, "base") {
+		var rBase ref.Ref
+		var dBase digest.Digest
+		if opts.checkBaseRef != "" {
+			rBase, err = ref.New(opts.checkBaseRef)
+			if err != nil {
+				return fmt.Errorf("failed to parse base image %s: %w", opts.checkBaseRef, err)
+			}
+			if opts.checkBaseDigest != "" {
+				dBase, err = digest.Parse(opts.checkBaseDigest)
+				if err != nil {
+					return fmt.Errorf("failed to parse base digest %s: %w", opts.checkBaseDigest, err)
+				}
+			} else if rBase.Digest == "" {
+				mBase, err := rc.ManifestHead(ctx, rBase, regclient.WithManifestRequireDigest())
+				if err != nil {
+					return fmt.Errorf("failed to get the digest of base image %s: %w", rBase.CommonName(), err)
+				}
+				_ = rc.Close(ctx, rBase)
+				dBase = mBase.GetDescriptor().Digest
+			}
+		}
+		err = manifest.SetBaseImage(m, rBase, dBase)
+		if err != nil {
+			return err
+		}
+	}
+	if flagChanged(cmd, "created") {
+		err = manifest.SetCreated(m, created)
+		if err != nil {
+			return err
+		}
+	}
+	if flagChanged(cmd, "source") {
+		err = manifest.SetSource(m, opts.source)
+		if err != nil {
+			return err
+		}
+	}
+	if flagChanged(cmd, "revision") {
+		err = manifest.SetRevision(m, opts.revision)
+		if err != nil {
+			return err
+		}
+	}
+
+	// copy the unmodified image when the target is in another repository to include the child manifests and blobs
+	if !ref.EqualRepository(rSrc, rTgt) {
+		defer rc.Close(ctx, rTgt)
+		err = rc.ImageCopy(ctx, rSrc, rTgt.SetDigest(dSrc.String()))
+		if err != nil {
+			return err
+		}
+	}
+	rOut := rTgt
+	if rOut.Tag == "" {
+		rOut = rOut.SetDigest(m.GetDescriptor().Digest.String())
+	}
+	opts.rootOpts.log.Debug("Setting base annotations",
+		slog.String("ref", rSrc.CommonName()),
+		slog.String("target", rOut.CommonName()))
+	err = rc.ManifestPut(ctx, rOut, m)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", rOut.CommonName())
+	return nil
+}
+
+// imageSizeReport is the output of "regctl image size".
+type imageSizeReport struct {
+	Ref              string              `json:"ref"`
+	Digest           digest.Digest       `json:"digest"`
+	Platforms        []imageSizePlatform `json:"platforms"`
+	Size             int64               `json:"size"`                       // Size of the unique configs and layers.
+	UncompressedSize int64               `json:"uncompressedSize,omitempty"` // UncompressedSize of the unique configs and layers.
+}
+
+// imageSizePlatform is the size of a single platform.
+type imageSizePlatform struct {
+	Platform         string        `json:"platform"`
+	Digest           digest.Digest `json:"digest"`
+	Layers           int           `json:"layers"`
+	Size             int64         `json:"size"`
+	UncompressedSize int64         `json:"uncompressedSize,omitempty"`
+}
+
+func (opts *imageOpts) runImageSize(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	r, err := ref.New(args[0])
+	if err != nil {
+		return err
+	}
+	platList := []platform.Platform{}
+	for _, pStr := range opts.platforms {
+		p, err := platform.Parse(pStr)
+		if err != nil {
+			return fmt.Errorf("failed to parse platform %s: %w", pStr, err)
+		}
+		platList = append(platList, p)
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, r)
+
+	opts.rootOpts.log.Debug("Image size",
+		slog.String("host", r.Registry),
+		slog.String("repo", r.Repository),
+		slog.String("tag", r.Tag),
+		slog.Any("platforms", opts.platforms))
+	m, err := rc.ManifestGet(ctx, r)
+	if err != nil {
+		return err
+	}
+	report := imageSizeReport{
+		Ref:       r.CommonName(),
+		Digest:    m.GetDescriptor().Digest,
+		Platforms: []imageSizePlatform{},
+	}
+	// blobs are counted once in the totals, and uncompressed sizes are cached for layers shared between platforms
+	blobs := map[digest.Digest]bool{}
+	ucSizes := map[digest.Digest]int64{}
+	addImage := func(m manifest.Manifest, p platform.Platform) error {
+		mi, ok := m.(manifest.Imager)
+		if !ok {
+			return fmt.Errorf("unsupported manifest type: %s%.0w", m.GetDescriptor().MediaType, errs.ErrUnsupportedMediaType)
+		}
+		cd, err := mi.GetConfig()
+		if err != nil {
+			return fmt.Errorf("failed to get config: %w", err)
+		}
+		layers, err := mi.GetLayers()
+		if err != nil {
+			return fmt.Errorf("failed to get layers: %w", err)
+		}
+		ps := imageSizePlatform{
+			Platform: p.String(),
+			Digest:   m.GetDescriptor().Digest,
+			Layers:   len(layers),
+			Size:     cd.Size,
+		}
+		if opts.uncompressed {
+			ps.UncompressedSize = cd.Size
+		}
+		if !blobs[cd.Digest] {
+			blobs[cd.Digest] = true
+			report.Size += cd.Size
+			report.UncompressedSize += ps.UncompressedSize
+		}
+		for _, l := range layers {
+			ps.Size += l.Size
+			ucSize := int64(0)
+			if opts.uncompressed {
+				if _, ok := ucSizes[l.Digest]; !ok {
+					ucSizes[l.Digest], err = imageSizeUncompressed(ctx, rc, r, l)
+					if err != nil {
+						return err
+					}
+				}
+				ucSize = ucSizes[l.Digest]
+				ps.UncompressedSize += ucSize
+			}
+			if !blobs[l.Digest] {
+				blobs[l.Digest] = true
+				report.Size += l.Size
+				report.UncompressedSize += ucSize
+			}
+		}
+		report.Platforms = append(report.Platforms, ps)
+		return nil
+	}
+	if mi, ok := m.(manifest.Indexer); ok {
+		dl, err := mi.GetManifestList()
+		if err != nil {
+			return err
+		}
+		for _, d := range dl {
+			if d.Platform == nil || !imageSizePlatformMatch(*d.Platform, platList) {
+				continue
+			}
+			mChild, err := rc.ManifestGet(ctx, r, regclient.WithManifestDesc(d))
+			if err != nil {
+				return err
+			}
+			miChild, ok := mChild.(manifest.Imager)
+			if !ok {
+				opts.rootOpts.log.Debug("Skipping nested index",
+					slog.String("digest", d.Digest.String()))
+				continue
+			}
+			if cd, err := miChild.GetConfig(); err != nil || (cd.MediaType != mediatype.OCI1ImageConfig && cd.MediaType != mediatype.Docker2ImageConfig) {
+				// skip attestations and other artifacts
+				continue
+			}
+			err = addImage(mChild, *d.Platform)
+			if err != nil {
+				return err
+			}
+		}
+	} else if mi, ok := m.(manifest.Imager); ok {
+		// the platform of a single image is only available in the config
+		cd, err := mi.GetConfig()
+		if err != nil {
+			return fmt.Errorf("failed to get config: %w", err)
+		}
+		if cd.MediaType != mediatype.OCI1ImageConfig && cd.MediaType != mediatype.Docker2ImageConfig {
+			return fmt.Errorf("unsupported config media type %s, artifacts are not supported%.0w", cd.MediaType, errs.ErrUnsupportedMediaType)
+		}
+		oc, err := rc.BlobGetOCIConfig(ctx, r, cd)
+		if err != nil {
+			return err
+		}
+		p := oc.GetConfig().Platform
+		if imageSizePlatformMatch(p, platList) {
+			err = addImage(m, p)
+			if err != nil {
+				return err
+			}
+		}
+	} else {
+		return fmt.Errorf("unsupported manifest type: %s%.0w", m.GetDescriptor().MediaType, errs.ErrUnsupportedMediaType)
+	}
+	if opts.format == "table" {
+		return imageSizeTable(cmd.OutOrStdout(), report, opts.uncompressed)
+	}
+	return template.Writer(cmd.OutOrStdout(), opts.format, report)
+}
+
+// imageSizePlatformMatch returns true when the list is empty or the platform matches an entry.
+func imageSizePlatformMatch(p platform.Platform, list []platform.Platform) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, entry := range list {
+		if platform.Match(p, entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// imageSizeUncompressed returns the uncompressed size of a layer.
+// Uncompressed layers and layers with an eStargz size annotation are not pulled.
+func imageSizeUncompressed(ctx context.Context, rc *regclient.RegClient, r ref.Ref, d descriptor.Descriptor) (int64, error) {
+	switch d.MediaType {
+	case mediatype.OCI1Layer, mediatype.Docker2Layer, mediatype.OCI1ForeignLayer:
+		return d.Size, nil
+	}
+	if sizeStr, ok := d.Annotations[archive.AnnotationEStargzUncompressedSize]; ok {
+		if size, err := strconv.ParseInt(sizeStr, 10, 64); err == nil {
+			return size, nil
+		}
+	}
+	br, err := rc.BlobGet(ctx, r, d)
+	if err != nil {
+		return 0, fmt.Errorf("failed to pull layer %s: %w", d.Digest.String(), err)
+	}
+	defer br.Close()
+	dr, err := archive.Decompress(br)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decompress layer %s: %w", d.Digest.String(), err)
+	}
+	size, err := io.Copy(io.Discard, dr)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read layer %s: %w", d.Digest.String(), err)
+	}
+	return size, nil
+}
+
+// imageSizeTable outputs the size report as a table.
+func imageSizeTable(out io.Writer, report imageSizeReport, uncompressed bool) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if uncompressed {
+		fmt.Fprintf(tw, "Platform\tDigest\tLayers\tSize\tUncompressed\n")
+		for _, ps := range report.Platforms {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", ps.Platform, ps.Digest.String(), ps.Layers, units.HumanSize(float64(ps.Size)), units.HumanSize(float64(ps.UncompressedSize)))
+		}
+		fmt.Fprintf(tw, "Total\t%s\t\t%s\t%s\n", report.Digest.String(), units.HumanSize(float64(report.Size)), units.HumanSize(float64(report.UncompressedSize)))
+	} else {
+		fmt.Fprintf(tw, "Platform\tDigest\tLayers\tSize\n")
+		for _, ps := range report.Platforms {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", ps.Platform, ps.Digest.String(), ps.Layers, units.HumanSize(float64(ps.Size)))
+		}
+		fmt.Fprintf(tw, "Total\t%s\t\t%s\n", report.Digest.String(), units.HumanSize(float64(report.Size)))
+	}
+	return tw.Flush()
+}
+
+func (opts *imageOpts) runImageVerifyDigests(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	r, err := ref.New(args[0])
+	if err != nil {
+		return err
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, r)
+
+	opts.rootOpts.log.Debug("Image verify digests",
+		slog.String("host", r.Registry),
+		slog.String("repo", r.Repository),
+		slog.String("tag", r.Tag),
+		slog.Any("platforms", opts.platforms))
+	report := regclient.ImageVerifyReport{}
+	rcOpts := []regclient.ImageOpts{
+		regclient.ImageWithVerify(-1, &report),
+		regclient.ImageWithVerifyParallel(opts.parallel),
+	}
+	if len(opts.platforms) > 0 {
+		rcOpts = append(rcOpts, regclient.ImageWithPlatforms(opts.platforms))
+	}
+	if opts.includeExternal {
+		rcOpts = append(rcOpts, regclient.ImageWithIncludeExternal())
+	}
+	errVerify := rc.ImageVerify(ctx, r, rcOpts...)
+	if len(report.Entries) == 0 {
+		return errVerify
+	}
+	if opts.format == "" {
+		opts.format = `{{ range .Entries }}{{ printf "%-8s %s " .Method .Descriptor.Digest }}{{ if .Err }}error: {{ .Err }}{{ else }}ok{{ end }}{{ println }}{{ end }}`
+	}
+	if err := template.Writer(cmd.OutOrStdout(), opts.format, report); err != nil {
+		return err
+	}
+	if errVerify != nil {
+		failed := 0
+		for _, e := range report.Entries {
+			if e.Err != nil {
+				failed++
+			}
+		}
+		return fmt.Errorf("failed to verify %d of %d digests in %s%.0w", failed, len(report.Entries), r.CommonName(), errVerify)
+	}
+	return nil
+}
+
 type modFlagFunc struct {
 	f func(string) error
 	t string
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"archive/tar"
 	"context"
 	"errors"
 	"fmt"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -15,7 +17,12 @@ import (
 
 	"github.com/regclient/regclient"
 	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/pkg/archive"
+	"github.com/regclient/regclient/types"
 	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/manifest"
+	v1 "github.com/regclient/regclient/types/oci/v1"
+	"github.com/regclient/regclient/types/platform"
 	"github.com/regclient/regclient/types/ref"
 )
 
@@ -61,10 +68,11 @@ func TestImageCheckBase(t *testing.T) {
 	dig := mb.GetDescriptor().Digest
 
 	tt := []struct {
-		name      string
-		args      []string
-		expectErr error
-		expectOut string
+		name       string
+		args       []string
+		expectErr  error
+		expectCode int
+		expectOut  string
 	}{
 		{
 			name:      "missing annotation",
@@ -110,6 +118,24 @@ func TestImageCheckBase(t *testing.T) {
 			args:      []string{"image", "check-base", tsHost + "/testrepo:v3", "--base", tsHost + "/testrepo:b3", "--digest", dig.String()},
 			expectOut: "base image matches",
 		},
+		{
+			name:      "report v2 b1",
+			args:      []string{"image", "check-base", tsHost + "/testrepo:v2", "--base", tsHost + "/testrepo:b1", "--format", "{{.Changed}} {{len .Platforms}}"},
+			expectOut: "false 3",
+		},
+		{
+			name:       "report v3 b3",
+			args:       []string{"image", "check-base", tsHost + "/testrepo:v3", "--base", tsHost + "/testrepo:b3", "--format", "{{.Changed}} {{range .Platforms}}{{range .Layers}}{{.Index}}{{end}}{{end}}"},
+			expectErr:  errs.ErrMismatch,
+			expectCode: 1,
+			expectOut:  "true 0000",
+		},
+		{
+			name:       "report missing annotation",
+			args:       []string{"image", "check-base", tsHost + "/testrepo:v1", "--format", "{{json .}}"},
+			expectErr:  errs.ErrMissingAnnotation,
+			expectCode: 2,
+		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
@@ -120,6 +146,13 @@ func TestImageCheckBase(t *testing.T) {
 				} else if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
 					t.Errorf("unexpected error, received %v, expected %v", err, tc.expectErr)
 				}
+				var ec exitCodeError
+				if tc.expectCode != 0 && (!errors.As(err, &ec) || ec.code != tc.expectCode) {
+					t.Errorf("unexpected exit code, expected %d, received %v", tc.expectCode, err)
+				}
+				if tc.expectCode != 0 && tc.expectOut != "" && out != tc.expectOut {
+					t.Errorf("unexpected output, expected %s, received %s", tc.expectOut, out)
+				}
 				return
 			}
 			if err != nil {
@@ -169,6 +202,167 @@ func TestImageCopy(t *testing.T) {
 			args:      []string{"image", "copy", srcRef, "ocidir://" + tempDir + "testrepo:v2"},
 			expectOut: "ocidir://" + tempDir + "testrepo:v2",
 		},
+		{
+			name:      "ocidir-verify",
+			args:      []string{"image", "copy", "--verify", "--verify-sample", "-1", srcRef, "ocidir://" + tempDir + "testrepo:verify"},
+			expectOut: "ocidir://" + tempDir + "testrepo:verify",
+		},
+		{
+			name:      "ocidir-digest-tag-pattern",
+			args:      []string{"image", "copy", "--digest-tag-pattern", `^\.(sig|att|sbom)$`, srcRef, "ocidir://" + tempDir + "testrepo:digest-tags"},
+			expectOut: "ocidir://" + tempDir + "testrepo:digest-tags",
+		},
+		{
+			name:      "ocidir-digest-tag-pattern-invalid",
+			args:      []string{"image", "copy", "--digest-tag-pattern", "(", srcRef, "ocidir://" + tempDir + "testrepo:digest-tags"},
+			expectErr: errs.ErrParsingFailed,
+		},
+		{
+			name:      "ocidir-external-policy",
+			args:      []string{"image", "copy", "--external-policy", "copy", "--external-host", "mcr.microsoft.com", srcRef, "ocidir://" + tempDir + "testrepo:external"},
+			expectOut: "ocidir://" + tempDir + "testrepo:external",
+		},
+		{
+			name:      "ocidir-external-policy-embed",
+			args:      []string{"image", "copy", "--external-policy", "embed", srcRef, "ocidir://" + tempDir + "testrepo:external"},
+			expectErr: errs.ErrUnsupported,
+		},
+		{
+			name:      "ocidir-external-policy-invalid",
+			args:      []string{"image", "copy", "--external-policy", "download", srcRef, "ocidir://" + tempDir + "testrepo:external"},
+			expectErr: errs.ErrParsingFailed,
+		},
+		{
+			name:        "ocidir-layer-compress",
+			args:        []string{"image", "copy", "--layer-compress", "zstd", "--layer-compress-level", "3", srcRef, "ocidir://" + tempDir + "testrepo:zstd"},
+			expectOut:   "ocidir://" + tempDir + "testrepo:zstd",
+			outContains: true,
+		},
+		{
+			name:        "ocidir-layer-recompress",
+			args:        []string{"image", "copy", "--layer-compress", "gzip", "--layer-compress-level", "1", "--force-recompress", srcRef, "ocidir://" + tempDir + "testrepo:gzip1"},
+			expectOut:   "ocidir://" + tempDir + "testrepo:gzip1",
+			outContains: true,
+		},
+		{
+			name:      "ocidir-layer-compress-referrers",
+			args:      []string{"image", "copy", "--layer-compress", "zstd", "--referrers", srcRef, "ocidir://" + tempDir + "testrepo:zstd"},
+			expectErr: errs.ErrUnsupported,
+		},
+		{
+			name:      "ocidir-layer-compress-level-only",
+			args:      []string{"image", "copy", "--layer-compress-level", "3", srcRef, "ocidir://" + tempDir + "testrepo:zstd"},
+			expectErr: errs.ErrUnsupported,
+		},
+		{
+			name:      "ocidir-layer-compress-level-invalid",
+			args:      []string{"image", "copy", "--layer-compress", "gzip", "--layer-compress-level", "10", srcRef, "ocidir://" + tempDir + "testrepo:zstd"},
+			expectErr: archive.ErrUnsupportedLevel,
+		},
+		{
+			name:        "ocidir-layer-rm-media-type",
+			args:        []string{"image", "copy", "--layer-rm-media-type", "application/vnd.oci.image.layer.v1.tar+gzip", srcRef, "ocidir://" + tempDir + "testrepo:rm"},
+			expectOut:   "ocidir://" + tempDir + "testrepo:rm",
+			outContains: true,
+		},
+		{
+			name:        "ocidir-layer-keep-media-type",
+			args:        []string{"image", "copy", "--layer-keep-media-type", "application/vnd.oci.image.layer.v1.tar+gzip", srcRef, "ocidir://" + tempDir + "testrepo:keep"},
+			expectOut:   "ocidir://" + tempDir + "testrepo:keep",
+			outContains: true,
+		},
+		{
+			name:      "ocidir-layer-rm-digest-invalid",
+			args:      []string{"image", "copy", "--layer-rm-digest", "sha256:invalid", srcRef, "ocidir://" + tempDir + "testrepo:rm"},
+			expectErr: ErrInvalidInput,
+		},
+		{
+			name:      "ocidir-layer-rm-referrers",
+			args:      []string{"image", "copy", "--layer-rm-digest", "sha256:50d858e0985ecc7f60418aaf0cc5ab587f42c2570a884095a9e8ccacd0f6545c", "--referrers", srcRef, "ocidir://" + tempDir + "testrepo:rm"},
+			expectErr: errs.ErrUnsupported,
+		},
+		{
+			name:        "ocidir-dry-run",
+			args:        []string{"image", "copy", "--dry-run", srcRef, "ocidir://" + tempDir + "testrepo:dry-run"},
+			expectOut:   "push ocidir://" + tempDir + "testrepo:dry-run",
+			outContains: true,
+		},
+		{
+			name:      "ocidir-dry-run-format",
+			args:      []string{"image", "copy", "--dry-run", "--format", "{{ .DryRun }} {{ .ManifestsPushed }} {{ .BlobsSkipped }}", srcRef, "ocidir://" + tempDir + "testrepo:v2"},
+			expectOut: "true 0 0",
+		},
+		{
+			name:      "ocidir-dry-run-layer-compress",
+			args:      []string{"image", "copy", "--dry-run", "--layer-compress", "zstd", srcRef, "ocidir://" + tempDir + "testrepo:dry-run"},
+			expectErr: errs.ErrUnsupported,
+		},
+		{
+			name:      "ocidir-quiet",
+			args:      []string{"image", "copy", "--quiet", srcRef, "ocidir://" + tempDir + "testrepo:quiet"},
+			expectOut: "sha256:dfae8f425735a5e3a72e40d6609e03079995511d48157c74d54801ff4430491e",
+		},
+		{
+			name:        "ocidir-quiet-layer-compress",
+			args:        []string{"image", "copy", "-q", "--layer-compress", "zstd", srcRef, "ocidir://" + tempDir + "testrepo:quiet-zstd"},
+			expectOut:   "sha256:",
+			outContains: true,
+		},
+		{
+			name:      "ocidir-quiet-format",
+			args:      []string{"image", "copy", "--quiet", "--format", "{{ .Tag }}", srcRef, "ocidir://" + tempDir + "testrepo:quiet"},
+			expectErr: fmt.Errorf("if any flags in the group [format quiet] are set none of the others can be; [format quiet] were all set"),
+		},
+		{
+			name:      "ocidir-copy-annotations",
+			args:      []string{"image", "copy", "--copy-annotation", "org.example.mirror=primary", srcRef, "ocidir://" + tempDir + "testrepo:annotated"},
+			expectOut: "ocidir://" + tempDir + "testrepo:annotated",
+		},
+		{
+			name:      "ocidir-copy-annotations-layer-compress",
+			args:      []string{"image", "copy", "--copy-annotations", "--layer-compress", "zstd", srcRef, "ocidir://" + tempDir + "testrepo:annotated"},
+			expectErr: errs.ErrUnsupported,
+		},
+		{
+			name:      "ocidir-provenance",
+			args:      []string{"image", "copy", "--provenance", srcRef, "ocidir://" + tempDir + "testrepo:provenance"},
+			expectOut: "ocidir://" + tempDir + "testrepo:provenance",
+		},
+		{
+			name:      "ocidir-tag-glob",
+			args:      []string{"image", "copy", "ocidir://../../testdata/testrepo:v*", "ocidir://" + tempDir + "glob:"},
+			expectOut: "ocidir://" + tempDir + "glob:v1\nocidir://" + tempDir + "glob:v2\nocidir://" + tempDir + "glob:v3",
+		},
+		{
+			name:      "ocidir-tag-glob-class",
+			args:      []string{"image", "copy", "--quiet", "ocidir://../../testdata/testrepo:v[2]", "ocidir://" + tempDir + "glob"},
+			expectOut: "sha256:dfae8f425735a5e3a72e40d6609e03079995511d48157c74d54801ff4430491e",
+		},
+		{
+			name:      "ocidir-tag-regex",
+			args:      []string{"image", "copy", "--tag-regex", "--dry-run", "ocidir://../../testdata/testrepo:(a|b)[0-9]+", "ocidir://" + tempDir + "regex"},
+			expectOut: "copy ocidir://../../testdata/testrepo:a1 ocidir://" + tempDir + "regex:a1\ncopy ocidir://../../testdata/testrepo:a2 ocidir://" + tempDir + "regex:a2\ncopy ocidir://../../testdata/testrepo:a3 ocidir://" + tempDir + "regex:a3\ncopy ocidir://../../testdata/testrepo:b1 ocidir://" + tempDir + "regex:b1\ncopy ocidir://../../testdata/testrepo:b2 ocidir://" + tempDir + "regex:b2\ncopy ocidir://../../testdata/testrepo:b3 ocidir://" + tempDir + "regex:b3",
+		},
+		{
+			name:      "ocidir-tag-glob-no-match",
+			args:      []string{"image", "copy", "ocidir://../../testdata/testrepo:x*", "ocidir://" + tempDir + "glob"},
+			expectErr: errs.ErrNotFound,
+		},
+		{
+			name:      "ocidir-tag-glob-target-tag",
+			args:      []string{"image", "copy", "ocidir://../../testdata/testrepo:v*", "ocidir://" + tempDir + "glob:v1"},
+			expectErr: ErrInvalidInput,
+		},
+		{
+			name:      "ocidir-tag-glob-layer-compress",
+			args:      []string{"image", "copy", "--layer-compress", "zstd", "ocidir://../../testdata/testrepo:v*", "ocidir://" + tempDir + "glob"},
+			expectErr: errs.ErrUnsupported,
+		},
+		{
+			name:      "ocidir-tag-regex-invalid",
+			args:      []string{"image", "copy", "--tag-regex", "ocidir://../../testdata/testrepo:(", "ocidir://" + tempDir + "glob"},
+			expectErr: fmt.Errorf("failed to parse tag pattern \"(\": error parsing regexp: missing closing ): `^($`"),
+		},
 		{
 			name:      "ocidir-to-reg",
 			args:      []string{"image", "copy", srcRef, tsHost + "/newrepo:v2"},
@@ -249,10 +443,299 @@ func TestImageExportImport(t *testing.T) {
 	if out != "" {
 		t.Errorf("unexpected output: %v", out)
 	}
+
+	out, err = cobraTest(t, nil, "image", "export", "--platform", "linux/amd64", "--layer-rm-media-type", "application/vnd.oci.image.layer.v1.tar+gzip", srcRef, exportFile)
+	if err != nil {
+		t.Fatalf("failed to run image export: %v", err)
+	}
+	if out != "" {
+		t.Errorf("unexpected output: %v", out)
+	}
+}
+
+func TestImageGetFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcRef := "ocidir://../../testdata/testrepo:v1"
+	modRef := fmt.Sprintf("ocidir://%s/repo:get-file", tmpDir)
+	// create a layer with links and a whiteout
+	layerFile := filepath.Join(tmpDir, "layer.tar")
+	fh, err := os.Create(layerFile)
+	if err != nil {
+		t.Fatalf("failed to create layer: %v", err)
+	}
+	tw := tar.NewWriter(fh)
+	for _, th := range []*tar.Header{
+		{Name: "usr/lib/os-release", Typeflag: tar.TypeReg, Mode: 0o644, Size: 7},
+		{Name: "usr/lib/hard", Typeflag: tar.TypeLink, Linkname: "usr/lib/os-release"},
+		{Name: "etc/os-release", Typeflag: tar.TypeSymlink, Linkname: "../usr/lib/os-release"},
+		{Name: "lib", Typeflag: tar.TypeSymlink, Linkname: "usr/lib"},
+		{Name: "loop", Typeflag: tar.TypeSymlink, Linkname: "/loop"},
+		{Name: ".wh.layer1", Typeflag: tar.TypeReg, Mode: 0o644},
+	} {
+		if err := tw.WriteHeader(th); err != nil {
+			t.Fatalf("failed to write header: %v", err)
+		}
+		if th.Size > 0 {
+			if _, err := tw.Write([]byte("ID=test")); err != nil {
+				t.Fatalf("failed to write content: %v", err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar: %v", err)
+	}
+	if err := fh.Close(); err != nil {
+		t.Fatalf("failed to close layer: %v", err)
+	}
+	_, err = cobraTest(t, nil, "image", "mod", srcRef, "--create", modRef, "--layer-add", "tar="+layerFile+",platform=linux/amd64")
+	if err != nil {
+		t.Fatalf("failed to create image: %v", err)
+	}
+	tt := []struct {
+		name      string
+		file      string
+		expectOut string
+		expectErr error
+	}{
+		{
+			name:      "base layer",
+			file:      "/base.txt",
+			expectOut: "A",
+		},
+		{
+			name:      "regular file",
+			file:      "/usr/lib/os-release",
+			expectOut: "ID=test",
+		},
+		{
+			name:      "symlink",
+			file:      "/etc/os-release",
+			expectOut: "ID=test",
+		},
+		{
+			name:      "symlink parent",
+			file:      "/lib/os-release",
+			expectOut: "ID=test",
+		},
+		{
+			name:      "hard link",
+			file:      "/usr/lib/hard",
+			expectOut: "ID=test",
+		},
+		{
+			name:      "deleted",
+			file:      "/layer1",
+			expectErr: errs.ErrFileDeleted,
+		},
+		{
+			name:      "missing",
+			file:      "/missing",
+			expectErr: errs.ErrNotFound,
+		},
+		{
+			name:      "symlink loop",
+			file:      "/loop",
+			expectErr: fmt.Errorf("open loop: too many links"),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := cobraTest(t, nil, "image", "get-file", "--platform", "linux/amd64", modRef, tc.file)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Fatalf("command did not fail with expected error: %v", tc.expectErr)
+				}
+				if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
+					t.Fatalf("command failed with unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("command failed with error: %v", err)
+			}
+			if out != tc.expectOut {
+				t.Errorf("unexpected output, expected %s, received %s", tc.expectOut, out)
+			}
+		})
+	}
+}
+
+func TestImageHistory(t *testing.T) {
+	tempDir := t.TempDir()
+	srcRef := "ocidir://" + tempDir + "/repo:v3"
+	_, err := cobraTest(t, nil, "image", "copy", "ocidir://../../testdata/testrepo:v3", srcRef)
+	if err != nil {
+		t.Fatalf("failed to copy image: %v", err)
+	}
+	// add a provenance attestation to the linux/amd64 image
+	platDig, err := cobraTest(t, nil, "image", "digest", "--platform", "linux/amd64", srcRef)
+	if err != nil {
+		t.Fatalf("failed to get digest: %v", err)
+	}
+	_, err = cobraTest(t, &cobraTestOpts{stdin: strings.NewReader(`{"_type":"https://in-toto.io/Statement/v0.1"}`)},
+		"artifact", "put", "--subject", "ocidir://"+tempDir+"/repo@"+platDig,
+		"--artifact-type", "application/vnd.in-toto+json",
+		"--annotation", "in-toto.io/predicate-type=https://slsa.dev/provenance/v1")
+	if err != nil {
+		t.Fatalf("failed to put attestation: %v", err)
+	}
+	tt := []struct {
+		name        string
+		cmd         []string
+		expectOut   string
+		expectErr   error
+		outContains bool
+	}{
+		{
+			name:        "table",
+			cmd:         []string{"image", "history", srcRef, "--platform", "linux/amd64"},
+			expectOut:   "COPY layer1.txt /layer1 # buildkit",
+			outContains: true,
+		},
+		{
+			name:        "table provenance",
+			cmd:         []string{"image", "history", srcRef, "--platform", "linux/amd64"},
+			expectOut:   "Provenance: ",
+			outContains: true,
+		},
+		{
+			name:      "aligned layers",
+			cmd:       []string{"image", "history", srcRef, "--platform", "linux/arm64", "--format", `{{ range .History }}{{ if .Layer }}{{ println .Layer.Size }}{{ else }}{{ println "empty" }}{{ end }}{{ end }}`},
+			expectOut: "106\nempty\n103\nempty\n103\n103\n227\nempty\nempty\nempty\nempty",
+		},
+		{
+			name:      "provenance",
+			cmd:       []string{"image", "history", srcRef, "--platform", "linux/amd64", "--format", `{{ range .Provenance }}{{ println .Source .PredicateType }}{{ end }}`},
+			expectOut: "referrer https://slsa.dev/provenance/v1",
+		},
+		{
+			name:      "no provenance",
+			cmd:       []string{"image", "history", srcRef, "--platform", "linux/arm64", "--format", `{{ len .Provenance }}`},
+			expectOut: "0",
+		},
+		{
+			name:      "artifact",
+			cmd:       []string{"image", "history", "ocidir://../../testdata/testrepo:a1"},
+			expectErr: errs.ErrUnsupportedMediaType,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := cobraTest(t, nil, tc.cmd...)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Errorf("command did not fail")
+				} else if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if (!tc.outContains && out != tc.expectOut) || (tc.outContains && !strings.Contains(out, tc.expectOut)) {
+				t.Errorf("unexpected output, expected %s, received %s", tc.expectOut, out)
+			}
+		})
+	}
+}
+
+func TestImageDiffConfig(t *testing.T) {
+	repo := "ocidir://../../testdata/testrepo"
+	tt := []struct {
+		name        string
+		cmd         []string
+		expectOut   string
+		expectErr   error
+		outContains bool
+	}{
+		{
+			name:      "same image",
+			cmd:       []string{"image", "diff-config", repo + ":v3", repo + ":v3", "--platform", "linux/amd64"},
+			expectOut: "History: 11 common, 0 removed, 0 added",
+		},
+		{
+			name:        "table",
+			cmd:         []string{"image", "diff-config", repo + ":v1", repo + ":v3", "--platform", "linux/amd64"},
+			expectOut:   "Labels  version  changed  1    3",
+			outContains: true,
+		},
+		{
+			name:      "changes",
+			cmd:       []string{"image", "diff-config", repo + ":b1", repo + ":v3", "--platform", "linux/amd64", "--format", `{{ range .Changes }}{{ println .Field .Key .Change }}{{ end }}`},
+			expectOut: "Cmd  added\nWorkingDir  removed\nLabels base removed\nLabels arg_label added\nLabels version added\nVolumes /volume added",
+		},
+		{
+			name:      "history",
+			cmd:       []string{"image", "diff-config", repo + ":v1", repo + ":v3", "--platform", "linux/amd64", "--format", `{{ .History.Common }} {{ len .History.Removed }} {{ len .History.Added }}`},
+			expectOut: "4 4 7",
+		},
+		{
+			name:      "artifact",
+			cmd:       []string{"image", "diff-config", repo + ":a1", repo + ":v3"},
+			expectErr: errs.ErrUnsupportedMediaType,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := cobraTest(t, nil, tc.cmd...)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Errorf("command did not fail")
+				} else if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if (!tc.outContains && out != tc.expectOut) || (tc.outContains && !strings.Contains(out, tc.expectOut)) {
+				t.Errorf("unexpected output, expected %s, received %s", tc.expectOut, out)
+			}
+		})
+	}
 }
 
 func TestImageInspect(t *testing.T) {
+	ctx := context.Background()
 	srcRef := "ocidir://../../testdata/testrepo:v3"
+	// create an image with an external layer
+	extRef := "ocidir://" + t.TempDir() + "/windows:v1"
+	rSrc, err := ref.New(srcRef)
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rExt, err := ref.New(extRef)
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rc := regclient.New()
+	m, err := rc.ManifestGet(ctx, rSrc, regclient.WithManifestPlatform(platform.Platform{OS: "linux", Architecture: "amd64"}))
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	err = rc.ImageCopy(ctx, rSrc.SetDigest(m.GetDescriptor().Digest.String()), rExt)
+	if err != nil {
+		t.Fatalf("failed to copy image: %v", err)
+	}
+	om, ok := m.GetOrig().(v1.Manifest)
+	if !ok || len(om.Layers) == 0 {
+		t.Fatalf("unexpected manifest: %v", m.GetOrig())
+	}
+	om.Layers[0].URLs = []string{"https://example.com/layer.tar.gz"}
+	mExt, err := manifest.New(manifest.WithOrig(om))
+	if err != nil {
+		t.Fatalf("failed to create manifest: %v", err)
+	}
+	err = rc.ManifestPut(ctx, rExt, mExt)
+	if err != nil {
+		t.Fatalf("failed to put manifest: %v", err)
+	}
+	err = rc.Close(ctx, rExt)
+	if err != nil {
+		t.Fatalf("failed to close ref: %v", err)
+	}
 	tt := []struct {
 		name        string
 		cmd         []string
@@ -296,6 +779,26 @@ func TestImageInspect(t *testing.T) {
 			expectOut:   "linux",
 			outContains: false,
 		},
+		{
+			name:      "external layers",
+			cmd:       []string{"image", "inspect", extRef, "--format", `{{ range .ExternalLayers }}{{ range .URLs }}{{ println . }}{{ end }}{{ end }}`},
+			expectOut: "https://example.com/layer.tar.gz",
+		},
+		{
+			name:      "query",
+			cmd:       []string{"image", "inspect", srcRef, "--platform", "linux/amd64", "--query", ".config.Labels.version"},
+			expectOut: "3",
+		},
+		{
+			name:      "query array",
+			cmd:       []string{"image", "inspect", srcRef, "--platform", "linux/amd64", "--query", ".history[?empty_layer==true && created_by==\"LABEL version=3\"].created"},
+			expectOut: "2021-01-01T00:00:00Z",
+		},
+		{
+			name:      "no external layers",
+			cmd:       []string{"image", "inspect", srcRef, "--platform", "linux/amd64", "--format", `{{ len .ExternalLayers }}`},
+			expectOut: "0",
+		},
 		{
 			name:      "invalid ref",
 			cmd:       []string{"image", "inspect", "invalid://ref*format"},
@@ -328,6 +831,152 @@ func TestImageInspect(t *testing.T) {
 	}
 }
 
+func TestImageLint(t *testing.T) {
+	tempDir := t.TempDir()
+	srcRef := "ocidir://../../testdata/testrepo:v3"
+	// copy the image to a latest tag, and add a cosign signature tag to a second copy
+	latestRef := "ocidir://" + tempDir + "/repo:latest"
+	signedRef := "ocidir://" + tempDir + "/signed:v3"
+	for _, args := range [][]string{
+		{"image", "copy", srcRef, latestRef},
+		{"image", "copy", srcRef, signedRef},
+	} {
+		_, err := cobraTest(t, nil, args...)
+		if err != nil {
+			t.Fatalf("failed to copy image: %v", err)
+		}
+	}
+	dig, err := cobraTest(t, nil, "image", "digest", srcRef)
+	if err != nil {
+		t.Fatalf("failed to get digest: %v", err)
+	}
+	sigTag := strings.Replace(dig, ":", "-", 1) + ".sig"
+	_, err = cobraTest(t, nil, "image", "copy", "ocidir://../../testdata/testrepo:a1", "ocidir://"+tempDir+"/signed:"+sigTag)
+	if err != nil {
+		t.Fatalf("failed to copy signature: %v", err)
+	}
+	checksFmt := "{{ range .Findings }}{{ println .Severity .Check .Platform }}{{ end }}"
+	tt := []struct {
+		name        string
+		cmd         []string
+		expectOut   string
+		expectErr   error
+		expectCode  int
+		outContains bool
+	}{
+		{
+			name:        "default",
+			cmd:         []string{"image", "lint", srcRef},
+			expectOut:   "Total: 0 errors, 6 warnings",
+			outContains: true,
+		},
+		{
+			name:      "platform",
+			cmd:       []string{"image", "lint", srcRef, "--platform", "linux/amd64", "--skip", "sbom", "--format", checksFmt},
+			expectOut: "warning user linux/amd64\nwarning signature",
+		},
+		{
+			name:       "fail on warning",
+			cmd:        []string{"image", "lint", srcRef, "--platform", "linux/amd64", "--fail-on", "warning", "--skip", "sbom", "--skip", "signature", "--format", checksFmt},
+			expectErr:  errs.ErrMismatch,
+			expectCode: 1,
+			expectOut:  "warning user linux/amd64",
+		},
+		{
+			name:       "required label",
+			cmd:        []string{"image", "lint", srcRef, "--platform", "linux/arm64", "--require-label", "org.example.team", "--skip", "user", "--skip", "sbom", "--skip", "signature", "--format", checksFmt},
+			expectErr:  errs.ErrMismatch,
+			expectCode: 1,
+			expectOut:  "error labels linux/arm64",
+		},
+		{
+			name:      "layers",
+			cmd:       []string{"image", "lint", srcRef, "--platform", "linux/arm64", "--max-layers", "2", "--max-layer-size", "200B", "--skip", "user", "--skip", "sbom", "--skip", "signature", "--format", "{{ range .Findings }}{{ println .Check }}{{ end }}"},
+			expectOut: "layer-count\nlayer-size",
+		},
+		{
+			name:       "secret pattern",
+			cmd:        []string{"image", "lint", srcRef, "--platform", "linux/amd64", "--secret-pattern", "base.txt", "--skip", "user", "--skip", "sbom", "--skip", "signature", "--format", "{{ range .Findings }}{{ .Check }} {{ .Message }}{{ end }}"},
+			expectErr:  errs.ErrMismatch,
+			expectCode: 1,
+			expectOut:  "base.txt",
+		},
+		{
+			name:      "latest",
+			cmd:       []string{"image", "lint", latestRef, "--platform", "linux/amd64", "--skip", "user", "--skip", "sbom", "--skip", "signature", "--format", checksFmt},
+			expectOut: "warning latest",
+		},
+		{
+			name:      "signature tag",
+			cmd:       []string{"image", "lint", signedRef, "--skip", "user", "--format", checksFmt},
+			expectOut: "warning sbom",
+		},
+		{
+			name:      "unknown check",
+			cmd:       []string{"image", "lint", srcRef, "--skip", "size"},
+			expectErr: ErrInvalidInput,
+		},
+		{
+			name:       "artifact",
+			cmd:        []string{"image", "lint", "ocidir://../../testdata/testrepo:a1"},
+			expectErr:  errs.ErrUnsupportedMediaType,
+			expectCode: 2,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := cobraTest(t, nil, tc.cmd...)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Errorf("command did not fail")
+				} else if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				var ec exitCodeError
+				if tc.expectCode != 0 && (!errors.As(err, &ec) || ec.code != tc.expectCode) {
+					t.Errorf("unexpected exit code, expected %d, received %v", tc.expectCode, err)
+				}
+				if tc.expectOut != "" && !strings.Contains(out, tc.expectOut) {
+					t.Errorf("unexpected output, expected %s, received %s", tc.expectOut, out)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if (!tc.outContains && out != tc.expectOut) || (tc.outContains && !strings.Contains(out, tc.expectOut)) {
+				t.Errorf("unexpected output, expected %s, received %s", tc.expectOut, out)
+			}
+		})
+	}
+}
+
+func TestImagePromote(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcRef := "ocidir://../../testdata/testrepo"
+	tgtRef := fmt.Sprintf("ocidir://%s/repo:prod", tmpDir)
+
+	out, err := cobraTest(t, nil, "image", "promote", "--record", "--annotation", "org.example.approver=test", srcRef+":v1", tgtRef)
+	if err != nil {
+		t.Fatalf("failed to run image promote: %v", err)
+	}
+	if out != tgtRef {
+		t.Errorf("unexpected output: %v", out)
+	}
+	_, err = cobraTest(t, nil, "image", "promote", srcRef+":v2", tgtRef)
+	if !errors.Is(err, errs.ErrTagExists) {
+		t.Errorf("promote without force did not fail: %v", err)
+	}
+	_, err = cobraTest(t, nil, "image", "promote", "--annotation", "org.example.approver=test", srcRef+":v2", tgtRef)
+	if !errors.Is(err, errs.ErrUnsupported) {
+		t.Errorf("annotation without a record did not fail: %v", err)
+	}
+	_, err = cobraTest(t, nil, "image", "promote", "--force", srcRef+":v2", tgtRef)
+	if err != nil {
+		t.Fatalf("failed to run image promote with force: %v", err)
+	}
+}
+
 func TestImageMod(t *testing.T) {
 	tmpDir := t.TempDir()
 	srcRef := "ocidir://../../testdata/testrepo:v3"
@@ -355,11 +1004,86 @@ func TestImageMod(t *testing.T) {
 			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--layer-add", "tar=../../testdata/layer.tar,dir=../../cmd,platform=linux/amd64"},
 			expectErr: fmt.Errorf(`invalid argument "tar=../../testdata/layer.tar,dir=../../cmd,platform=linux/amd64" for "--layer-add" flag: cannot use dir and tar options together in layer-add`),
 		},
+		{
+			name:      "layer-estargz",
+			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--layer-estargz"},
+			expectOut: modRef,
+		},
 		{
 			name:      "timestamps",
 			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--time", "set=2000-01-01T00:00:00Z,base-ref=" + baseRef},
 			expectOut: modRef,
 		},
+		{
+			name:      "external-layers",
+			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--external-layers", "embed,host=mcr.microsoft.com"},
+			expectOut: modRef,
+		},
+		{
+			name:      "external-layers-invalid",
+			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--external-layers", "embed,mcr.microsoft.com"},
+			expectErr: fmt.Errorf(`invalid argument "embed,mcr.microsoft.com" for "--external-layers" flag: invalid external layer option "mcr.microsoft.com", expected host=<name>`),
+		},
+		{
+			name:      "attestations-rm",
+			cmd:       []string{"image", "mod", "ocidir://../../testdata/testrepo:v1", "--create", modRef, "--attestations-rm"},
+			expectOut: modRef,
+		},
+		{
+			name:      "config-user-workdir-expose",
+			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--config-user", "65534:65534", "--config-workdir", "/app", "--config-expose", "8080/tcp,53/udp"},
+			expectOut: modRef,
+		},
+		{
+			name:      "config-workdir-relative",
+			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--config-workdir", "app"},
+			expectErr: errs.ErrParsingFailed,
+		},
+		{
+			name:      "file-scrub",
+			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--file-scrub", "file=etc/*,regex=root,replace=admin"},
+			expectOut: modRef,
+		},
+		{
+			name:      "file-scrub-missing-regex",
+			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--file-scrub", "file=etc/*"},
+			expectErr: fmt.Errorf(`invalid argument "file=etc/*" for "--file-scrub" flag: file and regex must be included in file-scrub`),
+		},
+		{
+			name:      "layer-reslice",
+			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--layer-reslice", "dir"},
+			expectOut: modRef,
+		},
+		{
+			name:      "layer-reslice-mapping",
+			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--layer-reslice", "usr/lib=lib,etc=config"},
+			expectOut: modRef,
+		},
+		{
+			name:      "label-env-templates",
+			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--label-add", "org.example.arch={{ .Platform.Architecture }}", "--label-rm", "version", "--env-add", "PATH={{ .Value }}:/opt/bin", "--env-rm", "[linux/amd64]HOME"},
+			expectOut: modRef,
+		},
+		{
+			name:      "label-add-invalid",
+			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--label-add", "org.example.arch"},
+			expectErr: fmt.Errorf(`invalid argument "org.example.arch" for "--label-add" flag: label-add must be name=template`),
+		},
+		{
+			name:      "env-add-template-error",
+			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--env-add", "PATH={{ .Missing }}"},
+			expectErr: fmt.Errorf(`failed to expand env template for PATH: template: out:1:3: executing "out" at <.Missing>: can't evaluate field Missing in type mod.ConfigTemplateData`),
+		},
+		{
+			name:      "referrers",
+			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--annotation", "org.example.referrers=true", "--referrers", "attestations"},
+			expectOut: modRef,
+		},
+		{
+			name:      "referrers-invalid",
+			cmd:       []string{"image", "mod", srcRef, "--create", modRef, "--referrers", "signed"},
+			expectErr: errs.ErrParsingFailed,
+		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
@@ -382,3 +1106,268 @@ func TestImageMod(t *testing.T) {
 		})
 	}
 }
+
+func TestImageModReferrers(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcRef := "ocidir://../../testdata/testrepo:v2"
+	attRef := "ocidir://../../testdata/testrepo:a1"
+	amd64Dig := "sha256:ee378b79279b57eb5ac1f3b892c9ad2a9be9d9ccabe1a29a9cbaed8cad182358"
+	tt := []struct {
+		name      string
+		policy    string
+		expectNew bool
+	}{
+		{
+			name:      "attestations",
+			policy:    "attestations",
+			expectNew: true,
+		},
+		{
+			name:   "unsigned",
+			policy: "unsigned",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			idxRef := fmt.Sprintf("ocidir://%s/%s:v2", tmpDir, tc.name)
+			// create an index with a buildkit style attestation for the amd64 image
+			_, err := cobraTest(t, nil, "index", "create", "--ref", srcRef, "--platform", "linux/amd64", idxRef)
+			if err != nil {
+				t.Fatalf("failed to run index create: %v", err)
+			}
+			_, err = cobraTest(t, nil, "index", "add", "--ref", attRef, "--desc-platform", "unknown/unknown",
+				"--desc-annotation", "vnd.docker.reference.type=attestation-manifest",
+				"--desc-annotation", "vnd.docker.reference.digest="+amd64Dig, idxRef)
+			if err != nil {
+				t.Fatalf("failed to run index add: %v", err)
+			}
+			_, err = cobraTest(t, nil, "image", "mod", idxRef, "--replace", "--annotation", "[linux/amd64]org.example.mod=true", "--referrers", tc.policy)
+			if err != nil {
+				t.Fatalf("failed to run image mod: %v", err)
+			}
+			out, err := cobraTest(t, nil, "manifest", "get", idxRef, "--format", `{{range .Manifests}}{{.Digest}}={{index .Annotations "vnd.docker.reference.digest"}} {{end}}`)
+			if err != nil {
+				t.Fatalf("failed to get manifest: %v", err)
+			}
+			entries := strings.Fields(out)
+			if len(entries) != 2 {
+				t.Fatalf("unexpected entries: %s", out)
+			}
+			newDig, _, _ := strings.Cut(entries[0], "=")
+			if newDig == amd64Dig {
+				t.Fatalf("image digest was not changed")
+			}
+			_, attDig, _ := strings.Cut(entries[1], "=")
+			if tc.expectNew && attDig != newDig {
+				t.Errorf("attestation was not updated, expected %s, received %s", newDig, attDig)
+			} else if !tc.expectNew && attDig != amd64Dig {
+				t.Errorf("attestation was updated, expected %s, received %s", amd64Dig, attDig)
+			}
+		})
+	}
+}
+
+func TestImageSetBaseAnnotations(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcRef := "ocidir://../../testdata/testrepo:v3"
+	baseRef := "ocidir://../../testdata/testrepo:b1"
+	tgtRef := fmt.Sprintf("ocidir://%s/repo:annotated", tmpDir)
+	baseDig, err := cobraTest(t, nil, "image", "digest", baseRef)
+	if err != nil {
+		t.Fatalf("failed to get base digest: %v", err)
+	}
+	annotFmt := `{{ index .Annotations "%s" }}`
+
+	out, err := cobraTest(t, nil, "image", "set-base-annotations", srcRef, "--create", tgtRef,
+		"--base", baseRef, "--created", "2020-01-02T03:04:05Z", "--source", "https://github.com/regclient/regclient", "--revision", "abc123")
+	if err != nil {
+		t.Fatalf("failed to set annotations: %v", err)
+	}
+	if out != tgtRef {
+		t.Errorf("unexpected output: %s", out)
+	}
+	expect := map[string]string{
+		types.AnnotationBaseImageName:   baseRef,
+		types.AnnotationBaseImageDigest: baseDig,
+		types.AnnotationCreated:         "2020-01-02T03:04:05Z",
+		types.AnnotationSource:          "https://github.com/regclient/regclient",
+		types.AnnotationRevision:        "abc123",
+	}
+	for k, v := range expect {
+		out, err := cobraTest(t, nil, "manifest", "get", tgtRef, "--format", fmt.Sprintf(annotFmt, k))
+		if err != nil {
+			t.Errorf("failed to get annotation %s: %v", k, err)
+		} else if out != v {
+			t.Errorf("unexpected annotation %s, expected %s, received %s", k, v, out)
+		}
+	}
+	out, err = cobraTest(t, nil, "image", "check-base", tgtRef)
+	if err != nil {
+		t.Errorf("check-base failed on annotated image: %v, %s", err, out)
+	}
+
+	// remove an annotation and replace the tag
+	out, err = cobraTest(t, nil, "image", "set-base-annotations", tgtRef, "--replace", "--revision", "")
+	if err != nil {
+		t.Fatalf("failed to remove annotation: %v", err)
+	}
+	if out != tgtRef {
+		t.Errorf("unexpected output: %s", out)
+	}
+	out, err = cobraTest(t, nil, "manifest", "get", tgtRef, "--format", fmt.Sprintf(annotFmt, types.AnnotationRevision))
+	if err != nil || out != "" {
+		t.Errorf("revision was not removed: %s, %v", out, err)
+	}
+
+	// push by digest
+	out, err = cobraTest(t, nil, "image", "set-base-annotations", tgtRef, "--revision", "def456")
+	if err != nil {
+		t.Fatalf("failed to push by digest: %v", err)
+	}
+	if !strings.HasPrefix(out, fmt.Sprintf("ocidir://%s/repo@sha256:", tmpDir)) {
+		t.Errorf("unexpected output: %s", out)
+	}
+
+	tt := []struct {
+		name      string
+		args      []string
+		expectErr error
+	}{
+		{
+			name:      "no annotations",
+			args:      []string{tgtRef},
+			expectErr: errs.ErrMissingAnnotation,
+		},
+		{
+			name:      "digest without base",
+			args:      []string{tgtRef, "--digest", baseDig},
+			expectErr: errs.ErrMissingName,
+		},
+		{
+			name:      "relative source",
+			args:      []string{tgtRef, "--source", "github.com/regclient/regclient"},
+			expectErr: errs.ErrParsingFailed,
+		},
+		{
+			name:      "base without tag",
+			args:      []string{tgtRef, "--base", fmt.Sprintf("ocidir://%s/repo", tmpDir), "--digest", baseDig},
+			expectErr: errs.ErrMissingTagOrDigest,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := cobraTest(t, nil, append([]string{"image", "set-base-annotations"}, tc.args...)...)
+			if !errors.Is(err, tc.expectErr) {
+				t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestImageSize(t *testing.T) {
+	srcRef := "ocidir://../../testdata/testrepo:v3"
+	tt := []struct {
+		name        string
+		cmd         []string
+		expectOut   string
+		expectErr   error
+		outContains bool
+	}{
+		{
+			name:        "default",
+			cmd:         []string{"image", "size", srcRef},
+			expectOut:   "linux/arm/v6",
+			outContains: true,
+		},
+		{
+			name:      "total",
+			cmd:       []string{"image", "size", srcRef, "--format", "{{ .Size }}"},
+			expectOut: "8716",
+		},
+		{
+			name:      "platform",
+			cmd:       []string{"image", "size", srcRef, "--platform", "linux/arm64", "--format", "{{ range .Platforms }}{{ println .Platform .Layers .Size }}{{ end }}"},
+			expectOut: "linux/arm64 5 2654",
+		},
+		{
+			name:      "shared layers",
+			cmd:       []string{"image", "size", srcRef, "--platform", "linux/amd64", "--platform", "linux/arm64", "--format", "{{ .Size }}"},
+			expectOut: "4666",
+		},
+		{
+			name:      "uncompressed",
+			cmd:       []string{"image", "size", srcRef, "--platform", "linux/amd64", "--uncompressed", "--format", "{{ range .Platforms }}{{ .UncompressedSize }}{{ end }}"},
+			expectOut: "22492",
+		},
+		{
+			name:        "uncompressed table",
+			cmd:         []string{"image", "size", srcRef, "--platform", "linux/amd64", "--uncompressed"},
+			expectOut:   "Uncompressed",
+			outContains: true,
+		},
+		{
+			name:      "artifact",
+			cmd:       []string{"image", "size", "ocidir://../../testdata/testrepo:a1"},
+			expectErr: errs.ErrUnsupportedMediaType,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := cobraTest(t, nil, tc.cmd...)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Errorf("command did not fail")
+				} else if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error: %v", err)
+			}
+			if (!tc.outContains && out != tc.expectOut) || (tc.outContains && !strings.Contains(out, tc.expectOut)) {
+				t.Errorf("unexpected output, expected %s, received %s", tc.expectOut, out)
+			}
+		})
+	}
+}
+
+func TestImageVerifyDigests(t *testing.T) {
+	tempDir := t.TempDir()
+	srcRef := "ocidir://../../testdata/testrepo:v1"
+	tgtRef := "ocidir://" + tempDir + "/repo:v1"
+	_, err := cobraTest(t, nil, "image", "copy", srcRef, tgtRef)
+	if err != nil {
+		t.Fatalf("failed to copy image: %v", err)
+	}
+	out, err := cobraTest(t, nil, "image", "verify-digests", tgtRef, "--platform", "linux/amd64", "--format", "{{ range .Entries }}{{ println .Method .Descriptor.MediaType }}{{ end }}")
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	lines := strings.Split(out, "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[0], "manifest ") || !strings.HasPrefix(lines[len(lines)-1], "digest ") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+	// corrupt the last blob in the output
+	out, err = cobraTest(t, nil, "image", "verify-digests", tgtRef, "--platform", "linux/amd64", "--format", "{{ range .Entries }}{{ println .Descriptor.Digest.Encoded }}{{ end }}")
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	lines = strings.Split(out, "\n")
+	blobFile := filepath.Join(tempDir, "repo", "blobs", "sha256", lines[len(lines)-1])
+	fi, err := os.Stat(blobFile)
+	if err != nil {
+		t.Fatalf("failed to stat blob: %v", err)
+	}
+	err = os.WriteFile(blobFile, make([]byte, fi.Size()), 0o600)
+	if err != nil {
+		t.Fatalf("failed to corrupt blob: %v", err)
+	}
+	out, err = cobraTest(t, nil, "image", "verify-digests", tgtRef, "--platform", "linux/amd64")
+	if !errors.Is(err, errs.ErrDigestMismatch) {
+		t.Errorf("verify did not fail with a digest mismatch: %v", err)
+	}
+	if !strings.Contains(out, lines[len(lines)-1]+" error: digest mismatch") {
+		t.Errorf("output does not contain the failed blob: %s", out)
+	}
+}
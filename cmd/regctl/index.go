@@ -3,9 +3,11 @@ package main
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"maps"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/opencontainers/go-digest"
 	"github.com/spf13/cobra"
@@ -33,15 +35,20 @@ type indexOpts struct {
 	annotations     []string
 	artifactType    string
 	byDigest        bool
+	conflict        string
 	descAnnotations []string
 	descPlatform    string
 	digests         []string
+	dropUnknown     bool
+	dryRun          bool
 	format          string
 	incDigestTags   bool
 	incReferrers    bool
 	mediaType       string
 	platforms       []string
 	refs            []string
+	sort            bool
+	sortAnnotation  string
 	subject         string
 }
 
@@ -53,6 +60,8 @@ func NewIndexCmd(rOpts *rootOpts) *cobra.Command {
 	indexCmd.AddCommand(newIndexAddCmd(rOpts))
 	indexCmd.AddCommand(newIndexCreateCmd(rOpts))
 	indexCmd.AddCommand(newIndexDeleteCmd(rOpts))
+	indexCmd.AddCommand(newIndexMergeCmd(rOpts))
+	indexCmd.AddCommand(newIndexNormalizeCmd(rOpts))
 	return indexCmd
 }
 
@@ -64,10 +73,17 @@ func newIndexAddCmd(rOpts *rootOpts) *cobra.Command {
 		Use:     "add <image_ref>",
 		Aliases: []string{"append", "insert"},
 		Short:   "add an index entry",
-		Long:    `Add an entry to a manifest list or OCI Index.`,
+		Long: `Add an entry to a manifest list or OCI Index.
+Annotations from --desc-annotation are set on the descriptors of the added entries, not the index.
+When an added entry already exists in the index with the same platform,
+the annotations are merged into the existing descriptor.`,
 		Example: `
 # add arm64 to the v1 image
-regctl index add registry.example.org/repo:v1 --ref registry.example.org/repo:arm64`,
+regctl index add registry.example.org/repo:v1 --ref registry.example.org/repo:arm64
+
+# annotate the arm64 descriptor in the v1 image
+regctl index add registry.example.org/repo:v1 --ref registry.example.org/repo:v1 \
+  --platform linux/arm64 --desc-annotation org.example.variant=graviton`,
 		Args:      cobra.ExactArgs(1),
 		ValidArgs: []string{}, // do not auto complete digests
 		RunE:      opts.runIndexAdd,
@@ -80,6 +96,8 @@ regctl index add registry.example.org/repo:v1 --ref registry.example.org/repo:ar
 	cmd.Flags().StringArrayVar(&opts.refs, "ref", []string{}, "References to add")
 	cmd.Flags().StringArrayVar(&opts.platforms, "platform", []string{}, "Platforms to include from ref")
 	_ = cmd.RegisterFlagCompletionFunc("platform", completeArgPlatform)
+	cmd.Flags().BoolVar(&opts.sort, "sort", false, "Sort descriptors by platform and digest for a reproducible index")
+	cmd.Flags().StringVar(&opts.sortAnnotation, "sort-annotation", "", "Sort descriptors by an annotation before the platform and digest")
 	return cmd
 }
 
@@ -109,7 +127,11 @@ regctl index create registry.example.org/busybox:1.34 \
 regctl index create registry.example.org/library/golang:windows \
   --ref golang:latest \
 	--platform windows/amd64,osver=10.0.20348.2322 \
-	--platform windows/amd64,osver=10.0.17763.5458`,
+	--platform windows/amd64,osver=10.0.17763.5458
+
+# create a reproducible index with the descriptors sorted by platform
+regctl index create registry.example.org/alpine:latest \
+  --ref alpine:latest --platform linux/arm64 --platform linux/amd64 --sort`,
 		Args:      cobra.ExactArgs(1),
 		ValidArgs: []string{}, // do not auto complete digests
 		RunE:      opts.runIndexCreate,
@@ -131,6 +153,8 @@ regctl index create registry.example.org/library/golang:windows \
 	_ = cmd.RegisterFlagCompletionFunc("platform", completeArgPlatform)
 	cmd.Flags().StringArrayVar(&opts.refs, "ref", []string{}, "References to include in new index")
 	cmd.Flags().BoolVar(&opts.incReferrers, "referrers", false, "Include referrers")
+	cmd.Flags().BoolVar(&opts.sort, "sort", false, "Sort descriptors by platform and digest for a reproducible index")
+	cmd.Flags().StringVar(&opts.sortAnnotation, "sort-annotation", "", "Sort descriptors by an annotation before the platform and digest")
 	cmd.Flags().StringVar(&opts.subject, "subject", "", "Specify a subject tag or digest (this manifest must already exist in the repo)")
 	return cmd
 }
@@ -159,6 +183,82 @@ regctl index delete registry.example.org/repo:v1 \
 	return cmd
 }
 
+func newIndexMergeCmd(rOpts *rootOpts) *cobra.Command {
+	opts := indexOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "merge <image_ref> <src_ref> [src_ref...]",
+		Short: "merge images into an index",
+		Long: `Merge the entries of multiple manifest lists, OCI Indexes, or images into a new index.
+Each image is copied into the target repository if it is missing.
+When multiple sources include different images for the same platform, the --conflict setting
+either fails the merge ("fail"), or keeps the image with the newest created time ("newest").
+Attestations for images that are not included are also dropped.`,
+		Example: `
+# merge the per-platform builds into an index
+regctl index merge registry.example.org/repo:v1 \
+  registry.example.org/repo:v1-amd64 registry.example.org/repo:v1-arm64
+
+# merge two indexes, keeping the newest image for each platform
+regctl index merge registry.example.org/repo:v1 \
+  registry.example.org/repo:v1-build1 registry.example.org/repo:v1-build2 --conflict newest`,
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: rOpts.completeArgTag,
+		RunE:              opts.runIndexMerge,
+	}
+	cmd.Flags().StringArrayVar(&opts.annotations, "annotation", []string{}, "Annotation to set on manifest")
+	cmd.Flags().BoolVar(&opts.byDigest, "by-digest", false, "Push manifest by digest instead of tag")
+	cmd.Flags().StringVar(&opts.conflict, "conflict", "fail", "Resolution for different images with the same platform (fail, newest)")
+	_ = cmd.RegisterFlagCompletionFunc("conflict", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"fail", "newest"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.Flags().BoolVar(&opts.incDigestTags, "digest-tags", false, "Include digest tags")
+	cmd.Flags().StringVar(&opts.format, "format", "", "Format output with go template syntax")
+	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	cmd.Flags().StringVarP(&opts.mediaType, "media-type", "m", mediatype.OCI1ManifestList, "Media-type for manifest list or OCI Index")
+	_ = cmd.RegisterFlagCompletionFunc("media-type", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return indexKnownTypes, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.Flags().BoolVar(&opts.incReferrers, "referrers", false, "Include referrers")
+	cmd.Flags().BoolVar(&opts.sort, "sort", false, "Sort descriptors by platform and digest for a reproducible index")
+	cmd.Flags().StringVar(&opts.sortAnnotation, "sort-annotation", "", "Sort descriptors by an annotation before the platform and digest")
+	return cmd
+}
+
+func newIndexNormalizeCmd(rOpts *rootOpts) *cobra.Command {
+	opts := indexOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "normalize <image_ref>",
+		Short: "remove duplicate entries and sort an index",
+		Long: `Remove duplicate entries from a manifest list or OCI Index and sort the remaining entries by platform and digest.
+Entries with the same digest keep the first occurrence.
+Entries with the same platform keep the last occurrence, which is the most recently added entry.
+Attestations for removed entries are also removed.
+The removed entries are output, and the index is only pushed when it changes.`,
+		Example: `
+# normalize an index
+regctl index normalize registry.example.org/repo:v1
+
+# show the changes without pushing the index
+regctl index normalize registry.example.org/repo:v1 --dry-run
+
+# remove attestations and unknown platforms
+regctl index normalize registry.example.org/repo:v1 --drop-unknown`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{}, // do not auto complete digests
+		RunE:      opts.runIndexNormalize,
+	}
+	cmd.Flags().BoolVar(&opts.dropUnknown, "drop-unknown", false, "Remove attestations and entries with an unknown platform")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Output the changes without pushing the index")
+	cmd.Flags().StringVar(&opts.format, "format", "", "Format output with go template syntax")
+	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	cmd.Flags().StringVar(&opts.sortAnnotation, "sort-annotation", "", "Sort descriptors by an annotation before the platform and digest")
+	return cmd
+}
+
 func (opts *indexOpts) runIndexAdd(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	// dedup warnings
@@ -196,9 +296,10 @@ func (opts *indexOpts) runIndexAdd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// append list
-	curDesc = append(curDesc, descList...)
+	// append list, updating the annotations on existing entries
+	curDesc = indexDescListMerge(curDesc, descList)
 	curDesc = indexDescListRmDup(curDesc)
+	curDesc = opts.indexDescListSort(curDesc)
 	err = mi.SetManifestList(curDesc)
 	if err != nil {
 		return err
@@ -264,6 +365,7 @@ func (opts *indexOpts) runIndexCreate(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	descList = indexDescListRmDup(descList)
+	descList = opts.indexDescListSort(descList)
 
 	var subj *descriptor.Descriptor
 	if opts.subject != "" && opts.mediaType == mediatype.OCI1ManifestList {
@@ -284,31 +386,7 @@ func (opts *indexOpts) runIndexCreate(cmd *cobra.Command, args []string) error {
 	}
 
 	// build the index
-	mOpts := []manifest.Opts{}
-	switch opts.mediaType {
-	case mediatype.OCI1ManifestList:
-		m := v1.Index{
-			Versioned:    v1.IndexSchemaVersion,
-			MediaType:    mediatype.OCI1ManifestList,
-			ArtifactType: opts.artifactType,
-			Manifests:    descList,
-			Subject:      subj,
-		}
-		if len(annotations) > 0 {
-			m.Annotations = annotations
-		}
-		mOpts = append(mOpts, manifest.WithOrig(m))
-	case mediatype.Docker2ManifestList:
-		m := schema2.ManifestList{
-			Versioned: schema2.ManifestListSchemaVersion,
-			Manifests: descList,
-		}
-		if len(annotations) > 0 {
-			m.Annotations = annotations
-		}
-		mOpts = append(mOpts, manifest.WithOrig(m))
-	}
-	mm, err := manifest.New(mOpts...)
+	mm, err := opts.indexNew(descList, annotations, subj)
 	if err != nil {
 		return err
 	}
@@ -416,6 +494,242 @@ func (opts *indexOpts) runIndexDelete(cmd *cobra.Command, args []string) error {
 	return template.Writer(cmd.OutOrStdout(), opts.format, result)
 }
 
+// indexMergeEntry is a descriptor from one of the merged sources.
+type indexMergeEntry struct {
+	src     ref.Ref // source reference with the digest of the descriptor
+	desc    descriptor.Descriptor
+	created *time.Time
+	drop    bool
+}
+
+func (opts *indexOpts) runIndexMerge(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	// dedup warnings
+	if w := warning.FromContext(ctx); w == nil {
+		ctx = warning.NewContext(ctx, &warning.Warning{Hook: warning.DefaultHook()})
+	}
+	if opts.mediaType != mediatype.OCI1ManifestList && opts.mediaType != mediatype.Docker2ManifestList {
+		return fmt.Errorf("unsupported manifest media type: %s%.0w", opts.mediaType, errs.ErrUnsupportedMediaType)
+	}
+	if opts.conflict != "fail" && opts.conflict != "newest" {
+		return fmt.Errorf("unsupported conflict resolution %q, must be \"fail\" or \"newest\"", opts.conflict)
+	}
+	r, err := ref.New(args[0])
+	if err != nil {
+		return err
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, r)
+
+	// collect the entries from each source
+	entries := []*indexMergeEntry{}
+	for _, arg := range args[1:] {
+		rSrc, err := ref.New(arg)
+		if err != nil {
+			return err
+		}
+		m, err := rc.ManifestGet(ctx, rSrc)
+		if err != nil {
+			return fmt.Errorf("failed to get %s: %w", rSrc.CommonName(), err)
+		}
+		if mi, ok := m.(manifest.Indexer); ok && m.IsList() {
+			dl, err := mi.GetManifestList()
+			if err != nil {
+				return err
+			}
+			for _, d := range dl {
+				entries = append(entries, &indexMergeEntry{src: rSrc.SetDigest(d.Digest.String()), desc: d})
+			}
+			continue
+		}
+		rSrc = rSrc.SetDigest(m.GetDescriptor().Digest.String())
+		desc := m.GetDescriptor()
+		desc.Annotations = nil
+		desc.Platform, err = indexGetPlatform(ctx, rc, rSrc, m)
+		if err != nil {
+			return fmt.Errorf("failed to get platform of %s: %w", rSrc.CommonName(), err)
+		}
+		entries = append(entries, &indexMergeEntry{src: rSrc, desc: desc})
+	}
+
+	// resolve platform conflicts and duplicate digests
+	for i, e := range entries {
+		if e.drop {
+			continue
+		}
+		for _, later := range entries[i+1:] {
+			if later.drop {
+				continue
+			}
+			if later.desc.Digest == e.desc.Digest {
+				later.drop = true
+				continue
+			}
+			if !indexMergeConflict(e.desc, later.desc) {
+				continue
+			}
+			if opts.conflict == "fail" {
+				return fmt.Errorf("platform %s found in %s and %s, use --conflict newest to keep the newest image", e.desc.Platform.String(), e.src.CommonName(), later.src.CommonName())
+			}
+			newer, err := indexMergeNewer(ctx, rc, e, later)
+			if err != nil {
+				return err
+			}
+			if newer == e {
+				later.drop = true
+			} else {
+				e.drop = true
+				break
+			}
+		}
+	}
+	// drop attestations for images that were dropped
+	for _, e := range entries {
+		if e.drop || e.desc.Annotations[dockerReferenceType] != dockerReferenceTypeAttestation {
+			continue
+		}
+		subject := e.desc.Annotations[dockerReferenceDigest]
+		e.drop = !slices.ContainsFunc(entries, func(other *indexMergeEntry) bool {
+			return !other.drop && other.desc.Digest.String() == subject
+		})
+	}
+
+	// copy each image to the target repository
+	imgCopyOpts := []regclient.ImageOpts{
+		regclient.ImageWithChild(),
+	}
+	if opts.incDigestTags {
+		imgCopyOpts = append(imgCopyOpts, regclient.ImageWithDigestTags())
+	}
+	if opts.incReferrers {
+		imgCopyOpts = append(imgCopyOpts, regclient.ImageWithReferrers())
+	}
+	descList := []descriptor.Descriptor{}
+	for _, e := range entries {
+		if e.drop {
+			opts.rootOpts.log.Info("Skipping image",
+				slog.String("ref", e.src.CommonName()))
+			continue
+		}
+		err = rc.ImageCopy(ctx, e.src, r.SetDigest(e.desc.Digest.String()), imgCopyOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to copy %s: %w", e.src.CommonName(), err)
+		}
+		descList = append(descList, e.desc)
+	}
+	descList = opts.indexDescListSort(descList)
+
+	// parse annotations
+	annotations := map[string]string{}
+	for _, a := range opts.annotations {
+		aSplit := strings.SplitN(a, "=", 2)
+		if len(aSplit) == 1 {
+			annotations[aSplit[0]] = ""
+		} else {
+			annotations[aSplit[0]] = aSplit[1]
+		}
+	}
+
+	// build and push the index
+	mm, err := opts.indexNew(descList, annotations, nil)
+	if err != nil {
+		return err
+	}
+	if opts.byDigest {
+		r = r.SetDigest(mm.GetDescriptor().Digest.String())
+	}
+	err = rc.ManifestPut(ctx, r, mm)
+	if err != nil {
+		return err
+	}
+
+	// format output
+	result := struct {
+		Manifest manifest.Manifest
+	}{
+		Manifest: mm,
+	}
+	if opts.byDigest && opts.format == "" {
+		opts.format = "{{ printf \"%s\\n\" .Manifest.GetDescriptor.Digest }}"
+	}
+	return template.Writer(cmd.OutOrStdout(), opts.format, result)
+}
+
+// indexMergeConflict returns true when two different images are for the same platform.
+// Attestations and entries without a known platform never conflict.
+func indexMergeConflict(a, b descriptor.Descriptor) bool {
+	if a.Platform == nil || b.Platform == nil || a.Platform.OS == "unknown" || b.Platform.OS == "unknown" ||
+		a.Annotations[dockerReferenceType] == dockerReferenceTypeAttestation || b.Annotations[dockerReferenceType] == dockerReferenceTypeAttestation {
+		return false
+	}
+	return a.ArtifactType == b.ArtifactType && platform.Match(*a.Platform, *b.Platform)
+}
+
+// indexMergeNewer returns the entry with the newest created time in the image config.
+// When the created time is missing or equal, the later entry is returned.
+func indexMergeNewer(ctx context.Context, rc *regclient.RegClient, a, b *indexMergeEntry) (*indexMergeEntry, error) {
+	for _, e := range []*indexMergeEntry{a, b} {
+		if e.created != nil {
+			continue
+		}
+		e.created = &time.Time{}
+		m, err := rc.ManifestGet(ctx, e.src)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", e.src.CommonName(), err)
+		}
+		mi, ok := m.(manifest.Imager)
+		if !ok {
+			continue
+		}
+		cd, err := mi.GetConfig()
+		if err != nil {
+			return nil, err
+		}
+		conf, err := rc.BlobGetOCIConfig(ctx, e.src, cd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config of %s: %w", e.src.CommonName(), err)
+		}
+		if created := conf.GetConfig().Created; created != nil {
+			e.created = created
+		}
+	}
+	if a.created.After(*b.created) {
+		return a, nil
+	}
+	return b, nil
+}
+
+func (opts *indexOpts) runIndexNormalize(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	r, err := ref.New(args[0])
+	if err != nil {
+		return err
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, r)
+
+	normOpts := []regclient.IndexNormalizeOpts{}
+	if opts.dropUnknown {
+		normOpts = append(normOpts, regclient.IndexNormalizeWithDropUnknown())
+	}
+	if opts.dryRun {
+		normOpts = append(normOpts, regclient.IndexNormalizeWithDryRun())
+	}
+	if opts.sortAnnotation != "" {
+		normOpts = append(normOpts, regclient.IndexNormalizeWithSortAnnotation(opts.sortAnnotation))
+	}
+	result, err := rc.ManifestIndexNormalize(ctx, r, normOpts...)
+	if err != nil {
+		return err
+	}
+	if opts.format == "" {
+		opts.format = `{{ range .Removed }}removed {{ .Descriptor.Digest }} {{ with .Descriptor.Platform }}{{ . }}{{ else }}-{{ end }}: {{ .Reason }}
+{{ end }}{{ if .Sorted }}sorted entries
+{{ end }}`
+	}
+	return template.Writer(cmd.OutOrStdout(), opts.format, result)
+}
+
 func (opts *indexOpts) indexBuildDescList(ctx context.Context, rc *regclient.RegClient, r ref.Ref) ([]descriptor.Descriptor, error) {
 	imgCopyOpts := []regclient.ImageOpts{
 		regclient.ImageWithChild(),
@@ -430,6 +744,9 @@ func (opts *indexOpts) indexBuildDescList(ctx context.Context, rc *regclient.Reg
 	descAnnotations := map[string]string{}
 	for _, a := range opts.descAnnotations {
 		aSplit := strings.SplitN(a, "=", 2)
+		if aSplit[0] == "" {
+			return nil, fmt.Errorf("annotation key is missing: %s", a)
+		}
 		if len(aSplit) == 1 {
 			descAnnotations[aSplit[0]] = ""
 		} else {
@@ -524,6 +841,35 @@ func (opts *indexOpts) indexBuildDescList(ctx context.Context, rc *regclient.Reg
 	return descList, nil
 }
 
+// indexNew builds a manifest list or OCI Index with the media type and artifact type from the options.
+func (opts *indexOpts) indexNew(descList []descriptor.Descriptor, annotations map[string]string, subj *descriptor.Descriptor) (manifest.Manifest, error) {
+	mOpts := []manifest.Opts{}
+	switch opts.mediaType {
+	case mediatype.OCI1ManifestList:
+		m := v1.Index{
+			Versioned:    v1.IndexSchemaVersion,
+			MediaType:    mediatype.OCI1ManifestList,
+			ArtifactType: opts.artifactType,
+			Manifests:    descList,
+			Subject:      subj,
+		}
+		if len(annotations) > 0 {
+			m.Annotations = annotations
+		}
+		mOpts = append(mOpts, manifest.WithOrig(m))
+	case mediatype.Docker2ManifestList:
+		m := schema2.ManifestList{
+			Versioned: schema2.ManifestListSchemaVersion,
+			Manifests: descList,
+		}
+		if len(annotations) > 0 {
+			m.Annotations = annotations
+		}
+		mOpts = append(mOpts, manifest.WithOrig(m))
+	}
+	return manifest.New(mOpts...)
+}
+
 func indexGetPlatform(ctx context.Context, rc *regclient.RegClient, r ref.Ref, m manifest.Manifest) (*platform.Platform, error) {
 	if mi, ok := m.(manifest.Imager); ok {
 		if !m.IsSet() {
@@ -553,6 +899,34 @@ func indexGetPlatform(ctx context.Context, rc *regclient.RegClient, r ref.Ref, m
 	return nil, nil
 }
 
+// indexDescListMerge appends new descriptors to a list.
+// Descriptors already in the list with the same digest, artifact type, and platform have the new annotations merged instead.
+func indexDescListMerge(dl, add []descriptor.Descriptor) []descriptor.Descriptor {
+	for _, d := range add {
+		i := slices.IndexFunc(dl, func(cur descriptor.Descriptor) bool {
+			if !cur.Same(d) || cur.ArtifactType != d.ArtifactType {
+				return false
+			}
+			if cur.Platform == nil || d.Platform == nil {
+				return cur.Platform == nil && d.Platform == nil
+			}
+			return platform.Match(*cur.Platform, *d.Platform)
+		})
+		if i < 0 {
+			dl = append(dl, d)
+			continue
+		}
+		if len(d.Annotations) > 0 {
+			dl[i].Annotations = maps.Clone(dl[i].Annotations)
+			if dl[i].Annotations == nil {
+				dl[i].Annotations = map[string]string{}
+			}
+			maps.Copy(dl[i].Annotations, d.Annotations)
+		}
+	}
+	return dl
+}
+
 func indexDescListRmDup(dl []descriptor.Descriptor) []descriptor.Descriptor {
 	i := 0
 	for i < len(dl)-1 {
@@ -568,6 +942,14 @@ func indexDescListRmDup(dl []descriptor.Descriptor) []descriptor.Descriptor {
 	return dl
 }
 
+// indexDescListSort sorts the descriptors when requested.
+func (opts *indexOpts) indexDescListSort(dl []descriptor.Descriptor) []descriptor.Descriptor {
+	if !opts.sort && opts.sortAnnotation == "" {
+		return dl
+	}
+	return descriptor.DescriptorListSort(dl, descriptor.MatchOpt{SortAnnotation: opts.sortAnnotation})
+}
+
 func indexPlatformInList(p platform.Platform, pl []platform.Platform) bool {
 	for _, cur := range pl {
 		if platform.Match(p, cur) {
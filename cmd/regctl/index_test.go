@@ -75,3 +75,190 @@ func TestIndex(t *testing.T) {
 		t.Errorf("manifest artifact type, expected %s, received %s", testArtifactType, out)
 	}
 }
+
+func TestIndexSort(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcRef := "ocidir://../../testdata/testrepo:v2"
+	refA := fmt.Sprintf("ocidir://%s/repo:a", tmpDir)
+	refB := fmt.Sprintf("ocidir://%s/repo:b", tmpDir)
+
+	// the same platforms in a different order result in the same digest
+	_, err := cobraTest(t, nil, "index", "create", "--sort", "--ref", srcRef, "--platform", "linux/arm64", "--platform", "linux/amd64", refA)
+	if err != nil {
+		t.Fatalf("failed to run index create: %v", err)
+	}
+	_, err = cobraTest(t, nil, "index", "create", "--sort", "--ref", srcRef, "--platform", "linux/amd64", "--platform", "linux/arm64", refB)
+	if err != nil {
+		t.Fatalf("failed to run index create: %v", err)
+	}
+	digA, err := cobraTest(t, nil, "manifest", "head", refA)
+	if err != nil {
+		t.Fatalf("failed to head manifest: %v", err)
+	}
+	digB, err := cobraTest(t, nil, "manifest", "head", refB)
+	if err != nil {
+		t.Fatalf("failed to head manifest: %v", err)
+	}
+	if digA != digB {
+		t.Errorf("sorted indexes differ, %s and %s", digA, digB)
+	}
+	out, err := cobraTest(t, nil, "manifest", "get", refA, "--format", "{{range .Manifests}}{{.Platform}} {{end}}")
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	if out != "linux/amd64 linux/arm64" {
+		t.Errorf("unexpected platform order: %s", out)
+	}
+
+	// adding an entry keeps the list sorted
+	_, err = cobraTest(t, nil, "index", "add", "--sort", "--ref", srcRef, "--platform", "linux/arm/v7", refA)
+	if err != nil {
+		t.Fatalf("failed to run index add: %v", err)
+	}
+	out, err = cobraTest(t, nil, "manifest", "get", refA, "--format", "{{range .Manifests}}{{.Platform}} {{end}}")
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	if out != "linux/amd64 linux/arm/v7 linux/arm64" {
+		t.Errorf("unexpected platform order: %s", out)
+	}
+}
+
+func TestIndexNormalize(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcRef := "ocidir://../../testdata/testrepo:v2"
+	tgtRef := fmt.Sprintf("ocidir://%s/repo:v2", tmpDir)
+
+	_, err := cobraTest(t, nil, "index", "create", "--ref", srcRef, "--platform", "linux/arm64", "--platform", "linux/amd64", tgtRef)
+	if err != nil {
+		t.Fatalf("failed to run index create: %v", err)
+	}
+	// submit arm64 a second time with a different digest
+	_, err = cobraTest(t, nil, "index", "add", "--ref", srcRef, "--platform", "linux/arm/v7", "--desc-platform", "linux/arm64", tgtRef)
+	if err != nil {
+		t.Fatalf("failed to run index add: %v", err)
+	}
+	out, err := cobraTest(t, nil, "index", "normalize", "--dry-run", "--format", "{{len .Removed}} {{.Sorted}} {{.Pushed}}", tgtRef)
+	if err != nil {
+		t.Fatalf("failed to run index normalize: %v", err)
+	}
+	if out != "1 false false" {
+		t.Errorf("unexpected dry run output: %s", out)
+	}
+	out, err = cobraTest(t, nil, "index", "normalize", tgtRef)
+	if err != nil {
+		t.Fatalf("failed to run index normalize: %v", err)
+	}
+	if out != "removed sha256:6bed79d0800a0d3a1d0e0e8105a6a5f7f7758ce09e160a8f142574c418302467 linux/arm64: duplicate platform" {
+		t.Errorf("unexpected output: %s", out)
+	}
+	out, err = cobraTest(t, nil, "manifest", "get", tgtRef, "--format", "{{range .Manifests}}{{.Platform}} {{end}}")
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	if out != "linux/amd64 linux/arm64" {
+		t.Errorf("unexpected platforms: %s", out)
+	}
+	out, err = cobraTest(t, nil, "index", "normalize", tgtRef)
+	if err != nil {
+		t.Fatalf("failed to run index normalize: %v", err)
+	}
+	if out != "" {
+		t.Errorf("unexpected output from a normalized index: %s", out)
+	}
+}
+
+func TestIndexMerge(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcRef := "ocidir://../../testdata/testrepo:v2"
+	armRef := "ocidir://../../testdata/testrepo@sha256:36ed7f4ec4545a40ca043f60d76653ef3d2a76f58a051c0f3a256aaab26fb847"
+	aRef := fmt.Sprintf("ocidir://%s/src:a", tmpDir)
+	bRef := fmt.Sprintf("ocidir://%s/src:b", tmpDir)
+	tgtRef := fmt.Sprintf("ocidir://%s/repo:merged", tmpDir)
+
+	_, err := cobraTest(t, nil, "index", "create", "--ref", srcRef, "--platform", "linux/amd64", "--platform", "linux/arm64", aRef)
+	if err != nil {
+		t.Fatalf("failed to run index create: %v", err)
+	}
+	// create a conflicting arm64 entry with a different digest
+	_, err = cobraTest(t, nil, "index", "create", "--ref", srcRef, "--platform", "linux/arm/v7", "--desc-platform", "linux/arm64", bRef)
+	if err != nil {
+		t.Fatalf("failed to run index create: %v", err)
+	}
+
+	// merge an index with an image
+	out, err := cobraTest(t, nil, "index", "merge", tgtRef, aRef, armRef)
+	if err != nil {
+		t.Fatalf("failed to run index merge: %v", err)
+	}
+	if out != "" {
+		t.Errorf("unexpected output: %s", out)
+	}
+	out, err = cobraTest(t, nil, "manifest", "get", tgtRef, "--format", "{{range .Manifests}}{{.Platform}} {{end}}")
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	if out != "linux/amd64 linux/arm64 linux/arm/v7" {
+		t.Errorf("unexpected platforms: %s", out)
+	}
+	_, err = cobraTest(t, nil, "manifest", "get", "--platform", "linux/arm/v7", tgtRef)
+	if err != nil {
+		t.Errorf("failed to get copied linux/arm/v7 entry: %v", err)
+	}
+
+	// conflicting platforms fail by default
+	_, err = cobraTest(t, nil, "index", "merge", tgtRef, aRef, bRef)
+	if err == nil {
+		t.Errorf("merge with a conflict did not fail")
+	}
+	_, err = cobraTest(t, nil, "index", "merge", "--conflict", "invalid", tgtRef, aRef, bRef)
+	if err == nil {
+		t.Errorf("merge with an invalid conflict setting did not fail")
+	}
+
+	// newest wins, with the later source used when the created times match
+	out, err = cobraTest(t, nil, "index", "merge", "--conflict", "newest", "--sort", "--format", "{{range .Manifest.GetManifestList}}{{.Platform}}={{.Digest}} {{end}}", tgtRef, aRef, bRef)
+	if err != nil {
+		t.Fatalf("failed to run index merge: %v", err)
+	}
+	if out != "linux/amd64=sha256:ee378b79279b57eb5ac1f3b892c9ad2a9be9d9ccabe1a29a9cbaed8cad182358 linux/arm64=sha256:36ed7f4ec4545a40ca043f60d76653ef3d2a76f58a051c0f3a256aaab26fb847" {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestIndexDescAnnotation(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcRef := "ocidir://../../testdata/testrepo:v2"
+	tgtRef := fmt.Sprintf("ocidir://%s/repo:v2", tmpDir)
+
+	_, err := cobraTest(t, nil, "index", "create", "--ref", srcRef, "--platform", "linux/amd64", "--platform", "linux/arm64", tgtRef)
+	if err != nil {
+		t.Fatalf("failed to run index create: %v", err)
+	}
+	_, err = cobraTest(t, nil, "index", "add", "--ref", srcRef, "--platform", "linux/arm64", "--desc-annotation", "org.example.a=1", tgtRef)
+	if err != nil {
+		t.Fatalf("failed to run index add: %v", err)
+	}
+	_, err = cobraTest(t, nil, "index", "add", "--ref", srcRef, "--platform", "linux/arm64", "--desc-annotation", "org.example.b=2", tgtRef)
+	if err != nil {
+		t.Fatalf("failed to run index add: %v", err)
+	}
+	_, err = cobraTest(t, nil, "index", "add", "--ref", srcRef, "--platform", "linux/arm64", "--desc-annotation", "=3", tgtRef)
+	if err == nil {
+		t.Errorf("index add with an empty annotation key did not fail")
+	}
+	out, err := cobraTest(t, nil, "manifest", "get", tgtRef, "--format", "{{range .Manifests}}{{.Platform}}:{{range $k, $v := .Annotations}}{{$k}}={{$v}},{{end}} {{end}}")
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	if out != "linux/amd64: linux/arm64:org.example.a=1,org.example.b=2," {
+		t.Errorf("unexpected descriptors: %s", out)
+	}
+	out, err = cobraTest(t, nil, "manifest", "get", tgtRef, "--format", "{{range $k, $v := .Annotations}}{{$k}}={{$v}},{{end}}")
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	if out != "" {
+		t.Errorf("unexpected index annotations: %s", out)
+	}
+}
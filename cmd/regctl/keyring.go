@@ -0,0 +1,148 @@
+package main
+
+import (
+	"log/slog"
+	"os/exec"
+	"runtime"
+	"slices"
+
+	"github.com/regclient/regclient/config"
+)
+
+// keyringHelpers are the credential helpers that store logins in the OS keyring, in order of preference.
+var keyringHelpers = keyringHelpersOS()
+
+func keyringHelpersOS() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"docker-credential-osxkeychain"}
+	case "windows":
+		return []string{"docker-credential-wincred"}
+	default:
+		return []string{"docker-credential-secretservice"}
+	}
+}
+
+// keyringHelper returns the first installed keyring credential helper, or an empty string if none are found.
+func keyringHelper() string {
+	for _, helper := range keyringHelpers {
+		if _, err := exec.LookPath(helper); err == nil {
+			return helper
+		}
+	}
+	return ""
+}
+
+// credSave moves the credentials and any device login refresh token for a host into the OS keyring when enabled and available.
+// The credentials remain in the config file when the keyring cannot be used.
+func (c *Config) credSave(h *config.Host, log *slog.Logger) {
+	cred := config.Cred{User: h.User, Password: h.Pass, Token: h.Token}
+	dl := c.DeviceLogins[h.Name]
+	helper := ""
+	if c.Keyring == nil || *c.Keyring {
+		helper = keyringHelper()
+	}
+	if h.CredHelper != "" && h.CredHelper != helper {
+		if !slices.Contains(keyringHelpers, h.CredHelper) {
+			// leave credential helpers configured by the user
+			return
+		}
+		// the keyring was disabled or the helper was removed since the previous login
+		if dl != nil && dl.RefreshToken == "" {
+			dl.RefreshToken = deviceTokenGet(h)
+		}
+		c.credErase(h, log)
+		h.User = cred.User
+		h.Pass = cred.Password
+		h.Token = cred.Token
+	}
+	if helper == "" || cred == (config.Cred{}) {
+		return
+	}
+	h.CredHelper = helper
+	err := h.StoreCred(cred)
+	if err != nil {
+		log.Warn("Failed to store credentials in the keyring, saving to the config file",
+			slog.String("registry", h.Name),
+			slog.String("helper", helper),
+			slog.String("err", err.Error()))
+		h.CredHelper = ""
+		return
+	}
+	h.User = ""
+	h.Pass = ""
+	h.Token = ""
+	log.Debug("Credentials stored in the keyring",
+		slog.String("registry", h.Name),
+		slog.String("helper", helper))
+	if dl == nil || dl.RefreshToken == "" {
+		return
+	}
+	err = deviceCredHost(h).StoreCred(config.Cred{Token: dl.RefreshToken})
+	if err != nil {
+		log.Warn("Failed to store refresh token in the keyring, saving to the config file",
+			slog.String("registry", h.Name),
+			slog.String("helper", helper),
+			slog.String("err", err.Error()))
+		return
+	}
+	dl.RefreshToken = ""
+}
+
+// credErase removes the credentials for a host from the OS keyring.
+func (c *Config) credErase(h *config.Host, log *slog.Logger) {
+	if h.CredHelper == "" || !slices.Contains(keyringHelpers, h.CredHelper) {
+		return
+	}
+	if _, ok := c.DeviceLogins[h.Name]; ok {
+		deviceTokenErase(h, log)
+	}
+	err := h.EraseCred()
+	if err != nil {
+		log.Warn("Failed to remove credentials from the keyring",
+			slog.String("registry", h.Name),
+			slog.String("helper", h.CredHelper),
+			slog.String("err", err.Error()))
+	}
+	h.CredHelper = ""
+}
+
+// deviceCredHost returns the entry used to store the refresh token of a device login in the keyring of a host.
+func deviceCredHost(h *config.Host) *config.Host {
+	return &config.Host{
+		Name:       h.Name,
+		CredHost:   "https://" + h.Name + "/regctl/device-login",
+		CredHelper: h.CredHelper,
+	}
+}
+
+// deviceTokenGet returns the refresh token of a device login from the keyring.
+// An empty string is returned when the host does not use the keyring or the token is not found.
+func deviceTokenGet(h *config.Host) string {
+	if h.CredHelper == "" || !slices.Contains(keyringHelpers, h.CredHelper) {
+		return ""
+	}
+	return deviceCredHost(h).GetCred().Token
+}
+
+// deviceTokenErase removes the refresh token of a device login from the keyring.
+func deviceTokenErase(h *config.Host, log *slog.Logger) {
+	if h.CredHelper == "" || !slices.Contains(keyringHelpers, h.CredHelper) {
+		return
+	}
+	err := deviceCredHost(h).EraseCred()
+	if err != nil {
+		log.Debug("Failed to remove refresh token from the keyring",
+			slog.String("registry", h.Name),
+			slog.String("helper", h.CredHelper),
+			slog.String("err", err.Error()))
+	}
+}
+
+// deviceErase removes the device login for a host, including any refresh token in the keyring.
+func (c *Config) deviceErase(h *config.Host, log *slog.Logger) {
+	if _, ok := c.DeviceLogins[h.Name]; ok {
+		deviceTokenErase(h, log)
+	}
+	delete(c.DeviceLogins, h.Name)
+}
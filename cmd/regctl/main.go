@@ -26,7 +26,7 @@ func main() {
 	}()
 	godbg.SignalTrace()
 
-	if err := cmd.ExecuteContext(ctx); err != nil {
+	if err := opts.outputDone(cmd.ExecuteContext(ctx)); err != nil {
 		if err.Error() != "" {
 			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
 		}
@@ -35,7 +35,21 @@ func main() {
 		case strings.Contains(err.Error(), "http: server gave HTTP response to HTTPS client"):
 			fmt.Fprintf(os.Stderr, "Try updating your registry with \"regctl registry set --tls disabled <registry>\"\n")
 		}
-		os.Exit(1)
+		os.Exit(errExitCode(err))
 	}
 	os.Exit(0)
 }
+
+// exitCodeError is returned by commands that exit with a specific status.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e exitCodeError) Unwrap() error {
+	return e.err
+}
@@ -30,6 +30,6 @@ func cobraTest(t *testing.T, opts *cobraTestOpts, args ...string) (string, error
 	rootTopCmd.SetErr(bufErr)
 	rootTopCmd.SetArgs(args)
 
-	err := rootTopCmd.Execute()
+	err := rootOpts.outputDone(rootTopCmd.Execute())
 	return strings.TrimSpace(buf.String()), err
 }
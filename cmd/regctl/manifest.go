@@ -1,13 +1,18 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
 	"strings"
 
+	"github.com/opencontainers/go-digest"
 	"github.com/spf13/cobra"
 
 	"github.com/regclient/regclient"
@@ -27,14 +32,19 @@ type manifestOpts struct {
 	contentType   string
 	diffCtx       int
 	diffFullCtx   bool
+	force         bool
 	forceTagDeref bool
 	format        string
 	ignoreMissing bool
 	list          bool
+	parallel      int
 	platform      string
+	query         string
 	referrers     bool
 	requireDigest bool
 	requireList   bool
+	refFile       string
+	watch         watchOpts
 }
 
 func NewManifestCmd(rOpts *rootOpts) *cobra.Command {
@@ -48,6 +58,7 @@ func NewManifestCmd(rOpts *rootOpts) *cobra.Command {
 	cmd.AddCommand(newManifestHeadCmd(rOpts))
 	cmd.AddCommand(newManifestGetCmd(rOpts))
 	cmd.AddCommand(newManifestPutCmd(rOpts))
+	cmd.AddCommand(newManifestWatchCmd(rOpts))
 	return cmd
 }
 
@@ -127,6 +138,9 @@ regctl manifest get alpine
 # show the original manifest body for the local platform
 regctl manifest get alpine --format raw-body --platform local
 
+# show the digest of each linux platform in a manifest list
+regctl manifest get alpine --query '.manifests[?platform.os=="linux"].digest'
+
 # retrieve the manifest for a specific windows version
 regctl manifest get golang --platform windows/amd64,osver=10.0.17763.4974`,
 		Args:              cobra.ExactArgs(1),
@@ -139,7 +153,10 @@ regctl manifest get golang --platform windows/amd64,osver=10.0.17763.4974`,
 	_ = cmd.Flags().MarkHidden("list")
 	cmd.Flags().StringVarP(&opts.platform, "platform", "p", "", "Specify platform (e.g. linux/amd64 or local)")
 	_ = cmd.RegisterFlagCompletionFunc("platform", completeArgPlatform)
+	cmd.Flags().StringVar(&opts.query, "query", "", "Output fields selected with a jq or JSONPath style query (e.g. .config.digest)")
+	_ = cmd.RegisterFlagCompletionFunc("query", completeArgNone)
 	cmd.Flags().BoolVar(&opts.requireList, "require-list", false, "Deprecated: Fail if manifest list is not received")
+	cmd.MarkFlagsMutuallyExclusive("format", "query")
 	return cmd
 }
 
@@ -151,7 +168,13 @@ func newManifestHeadCmd(rOpts *rootOpts) *cobra.Command {
 		Use:     "head <image_ref>",
 		Aliases: []string{"digest"},
 		Short:   "http head request for manifest",
-		Long:    `Shows the digest or headers from an http manifest head request.`,
+		Long: `Shows the digest or headers from an http manifest head request.
+With "--ref-file", a list of references is read from a file (or stdin with "-"),
+one per line, and the requests are run concurrently.
+Blank lines and lines beginning with "#" are ignored.
+Batch output has one line per reference with the digest and media type, or the error.
+The batch "--format" is applied to each result with the fields
+Ref, Digest, MediaType, Error, and Manifest.`,
 		Example: `
 # show the digest for an image
 regctl manifest head alpine
@@ -163,8 +186,19 @@ regctl image digest alpine
 regctl manifest head alpine --platform linux/arm64
 
 # show all headers for the request
-regctl manifest head alpine --format raw-headers`,
-		Args:              cobra.ExactArgs(1),
+regctl manifest head alpine --format raw-headers
+
+# show the digest of every image listed in a file
+regctl manifest head --ref-file refs.txt
+
+# output only the references that failed
+regctl manifest head --ref-file refs.txt --format '{{ if .Error }}{{ .Ref }}{{ end }}'`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if flagChanged(cmd, "ref-file") {
+				return nil
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
 		ValidArgsFunction: rOpts.completeArgTag,
 		RunE:              opts.runManifestHead,
 	}
@@ -172,8 +206,11 @@ regctl manifest head alpine --format raw-headers`,
 	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
 	cmd.Flags().BoolVar(&opts.list, "list", true, "Do not resolve platform from manifest list (enabled by default)")
 	_ = cmd.Flags().MarkHidden("list")
+	cmd.Flags().IntVar(&opts.parallel, "parallel", 4, "Number of concurrent requests with --ref-file")
+	_ = cmd.RegisterFlagCompletionFunc("parallel", completeArgNone)
 	cmd.Flags().StringVarP(&opts.platform, "platform", "p", "", "Specify platform (e.g. linux/amd64 or local, requires a get request)")
 	_ = cmd.RegisterFlagCompletionFunc("platform", completeArgPlatform)
+	cmd.Flags().StringVar(&opts.refFile, "ref-file", "", "File with a list of image references, one per line (use \"-\" for stdin)")
 	cmd.Flags().BoolVar(&opts.requireDigest, "require-digest", false, "Fallback to a GET request if digest is not received")
 	cmd.Flags().BoolVar(&opts.requireList, "require-list", false, "Fail if manifest list is not received")
 	return cmd
@@ -187,7 +224,8 @@ func newManifestPutCmd(rOpts *rootOpts) *cobra.Command {
 		Use:     "put <image_ref>",
 		Aliases: []string{"push"},
 		Short:   "push manifest or manifest list",
-		Long:    `Pushes a manifest or manifest list to a repository.`,
+		Long: `Pushes a manifest or manifest list to a repository.
+Tags matching the immutable tag patterns of the registry config are not replaced unless "--force" is set.`,
 		Example: `
 # push an image manifest
 regctl manifest put \
@@ -200,11 +238,44 @@ regctl manifest put \
 	cmd.Flags().BoolVar(&opts.byDigest, "by-digest", false, "Push manifest by digest instead of tag")
 	cmd.Flags().StringVarP(&opts.contentType, "content-type", "t", "", "Specify content-type (e.g. application/vnd.docker.distribution.manifest.v2+json)")
 	_ = cmd.RegisterFlagCompletionFunc("content-type", completeArgMediaTypeManifest)
+	cmd.Flags().BoolVar(&opts.force, "force", false, "Replace a tag matching the immutable tag patterns of the registry")
 	cmd.Flags().StringVar(&opts.format, "format", "", "Format output with go template syntax")
 	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
 	return cmd
 }
 
+func newManifestWatchCmd(rOpts *rootOpts) *cobra.Command {
+	opts := manifestOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "watch <image_ref>",
+		Short: "watch for digest changes of a manifest",
+		Long: `Poll a tag and report each change to the digest of the manifest.
+The registry is queried with a HEAD request, which does not count against the pull rate limit on Docker Hub.
+The digest when the watch starts is not reported, and an empty digest is reported when the tag is deleted.
+Each change is output with "--format", or passed to the "--exec" command in the environment variables
+REGCTL_WATCH_REF, REGCTL_WATCH_DIGEST, and REGCTL_WATCH_PREVIOUS.`,
+		Example: `
+# report each update to the alpine image
+regctl manifest watch alpine --interval 1h
+
+# wait for a new image to be pushed
+regctl manifest watch registry.example.org/repo:latest --count 1
+
+# redeploy when the image changes
+regctl manifest watch registry.example.org/app:prod \
+  --exec 'kubectl set image deploy/app app=$REGCTL_WATCH_REF@$REGCTL_WATCH_DIGEST'`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: rOpts.completeArgTag,
+		RunE:              opts.runManifestWatch,
+	}
+	cmd.Flags().StringVarP(&opts.platform, "platform", "p", "", "Specify platform (e.g. linux/amd64 or local)")
+	_ = cmd.RegisterFlagCompletionFunc("platform", completeArgPlatform)
+	opts.watch.watchFlags(cmd, "{{ .Ref }} {{ if .Digest }}{{ .Digest }}{{ else }}deleted{{ end }}\n")
+	return cmd
+}
+
 func (opts *manifestOpts) runManifestDelete(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	// dedup warnings
@@ -320,18 +391,6 @@ func (opts *manifestOpts) runManifestHead(cmd *cobra.Command, args []string) err
 		return fmt.Errorf("cannot request a platform and require-list simultaneously")
 	}
 
-	r, err := ref.New(args[0])
-	if err != nil {
-		return err
-	}
-	rc := opts.rootOpts.newRegClient()
-	defer rc.Close(ctx, r)
-
-	opts.rootOpts.log.Debug("Manifest head",
-		slog.String("host", r.Registry),
-		slog.String("repo", r.Repository),
-		slog.String("tag", r.Tag))
-
 	mOpts := []regclient.ManifestOpts{}
 	if opts.requireDigest || (!flagChanged(cmd, "require-digest") && !flagChanged(cmd, "format")) {
 		mOpts = append(mOpts, regclient.WithManifestRequireDigest())
@@ -343,6 +402,21 @@ func (opts *manifestOpts) runManifestHead(cmd *cobra.Command, args []string) err
 		}
 		mOpts = append(mOpts, regclient.WithManifestPlatform(p))
 	}
+	if opts.refFile != "" {
+		return opts.runManifestHeadBatch(cmd, args, mOpts)
+	}
+
+	r, err := ref.New(args[0])
+	if err != nil {
+		return err
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, r)
+
+	opts.rootOpts.log.Debug("Manifest head",
+		slog.String("host", r.Registry),
+		slog.String("repo", r.Repository),
+		slog.String("tag", r.Tag))
 
 	m, err := rc.ManifestHead(ctx, r, mOpts...)
 	if err != nil {
@@ -358,6 +432,105 @@ func (opts *manifestOpts) runManifestHead(cmd *cobra.Command, args []string) err
 	return template.Writer(cmd.OutOrStdout(), opts.format, m)
 }
 
+// manifestHeadResult is the output of each reference in a batch manifest head.
+type manifestHeadResult struct {
+	Ref       string
+	Digest    digest.Digest
+	MediaType string
+	Error     string
+	Manifest  manifest.Manifest `json:"-"`
+	ref       ref.Ref
+	err       error
+}
+
+func (opts *manifestOpts) runManifestHeadBatch(cmd *cobra.Command, args []string, mOpts []regclient.ManifestOpts) error {
+	ctx := cmd.Context()
+	var in io.Reader
+	if opts.refFile == "-" {
+		in = cmd.InOrStdin()
+	} else {
+		//#nosec G304 command is run by a user accessing their own files
+		fh, err := os.Open(opts.refFile)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", opts.refFile, err)
+		}
+		defer fh.Close()
+		in = fh
+	}
+	results := []*manifestHeadResult{}
+	for _, arg := range args {
+		results = append(results, &manifestHeadResult{Ref: arg})
+	}
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		results = append(results, &manifestHeadResult{Ref: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", opts.refFile, err)
+	}
+	for _, res := range results {
+		res.ref, res.err = ref.New(res.Ref)
+	}
+
+	ops := []regclient.BatchOp{}
+	for _, res := range results {
+		if res.err == nil {
+			ops = append(ops, regclient.BatchOpHead(res.ref, mOpts...))
+		}
+	}
+	opts.rootOpts.log.Debug("Manifest head batch",
+		slog.Int("refs", len(ops)),
+		slog.Int("parallel", opts.parallel))
+	rc := opts.rootOpts.newRegClient()
+	report, _ := rc.Batch(ctx, ops, regclient.BatchWithWorkers(opts.parallel))
+	i := 0
+	for _, res := range results {
+		if res.err != nil {
+			continue
+		}
+		batchRes := report.Results[i]
+		i++
+		if batchRes.Err != nil {
+			res.err = batchRes.Err
+			continue
+		}
+		res.Manifest = batchRes.Manifest
+		res.Digest = batchRes.Manifest.GetDescriptor().Digest
+		res.MediaType = batchRes.Manifest.GetDescriptor().MediaType
+	}
+
+	format := opts.format
+	if format == "" || format == "digest" {
+		format = `{{ .Ref }} {{ if .Error }}error: {{ .Error }}{{ else }}{{ .Digest }} {{ .MediaType }}{{ end }}`
+	}
+	failed := 0
+	buf := &bytes.Buffer{}
+	for _, res := range results {
+		if res.err != nil {
+			failed++
+			res.Error = res.err.Error()
+		}
+		buf.Reset()
+		if err := template.Writer(buf, format, res); err != nil {
+			return err
+		}
+		// each result is output on a single line, skipping empty results
+		line := strings.TrimSuffix(buf.String(), "\n")
+		if line == "" {
+			continue
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), line)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to head %d of %d references", failed, len(results))
+	}
+	return nil
+}
+
 func (opts *manifestOpts) runManifestGet(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	if flagChanged(cmd, "list") {
@@ -393,6 +566,13 @@ func (opts *manifestOpts) runManifestGet(cmd *cobra.Command, args []string) erro
 		return err
 	}
 
+	if opts.query != "" {
+		raw, err := m.RawBody()
+		if err != nil {
+			return err
+		}
+		return queryWriter(cmd.OutOrStdout(), opts.query, raw)
+	}
 	switch opts.format {
 	case "raw":
 		opts.format = "{{ range $key,$vals := .RawHeaders}}{{range $val := $vals}}{{printf \"%s: %s\\n\" $key $val }}{{end}}{{end}}{{printf \"\\n%s\" .RawBody}}"
@@ -434,7 +614,11 @@ func (opts *manifestOpts) runManifestPut(cmd *cobra.Command, args []string) erro
 		r = r.SetDigest(rcM.GetDescriptor().Digest.String())
 	}
 
-	err = rc.ManifestPut(ctx, r, rcM)
+	putOpts := []regclient.ManifestOpts{}
+	if opts.force {
+		putOpts = append(putOpts, regclient.WithManifestForce())
+	}
+	err = rc.ManifestPut(ctx, r, rcM, putOpts...)
 	if err != nil {
 		return err
 	}
@@ -449,3 +633,47 @@ func (opts *manifestOpts) runManifestPut(cmd *cobra.Command, args []string) erro
 	}
 	return template.Writer(cmd.OutOrStdout(), opts.format, result)
 }
+
+func (opts *manifestOpts) runManifestWatch(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	r, err := ref.New(args[0])
+	if err != nil {
+		return err
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, r)
+
+	mOpts := []regclient.ManifestOpts{regclient.WithManifestRequireDigest()}
+	if opts.platform != "" {
+		p, err := platform.Parse(opts.platform)
+		if err != nil {
+			return fmt.Errorf("failed to parse platform %s: %w", opts.platform, err)
+		}
+		mOpts = append(mOpts, regclient.WithManifestPlatform(p))
+	}
+
+	opts.rootOpts.log.Debug("Watching manifest",
+		slog.String("host", r.Registry),
+		slog.String("repo", r.Repository),
+		slog.String("tag", r.Tag),
+		slog.Duration("interval", opts.watch.interval))
+	first := true
+	var prev string
+	return opts.watch.watch(cmd, opts.rootOpts, func(ctx context.Context) (*watchEvent, error) {
+		cur := ""
+		m, err := rc.ManifestHead(ctx, r, mOpts...)
+		if err == nil {
+			cur = m.GetDescriptor().Digest.String()
+		} else if !errors.Is(err, errs.ErrNotFound) {
+			return nil, err
+		}
+		if first || cur == prev {
+			first = false
+			prev = cur
+			return nil, nil
+		}
+		ev := watchEvent{Ref: r.CommonName(), Digest: cur, Previous: prev}
+		prev = cur
+		return &ev, nil
+	})
+}
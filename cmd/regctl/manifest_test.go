@@ -7,6 +7,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -23,6 +26,17 @@ import (
 )
 
 func TestManifestHead(t *testing.T) {
+	tempDir := t.TempDir()
+	refFile := filepath.Join(tempDir, "refs.txt")
+	err := os.WriteFile(refFile, []byte("# test refs\nocidir://../../testdata/testrepo:v1\n\nocidir://../../testdata/testrepo:v2\n"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write ref file: %v", err)
+	}
+	refFileMissing := filepath.Join(tempDir, "refs-missing.txt")
+	err = os.WriteFile(refFileMissing, []byte("ocidir://../../testdata/testrepo:v1\nocidir://../../testdata/testrepo:missing\ninvalid*ref\n"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write ref file: %v", err)
+	}
 	tt := []struct {
 		name        string
 		args        []string
@@ -62,6 +76,22 @@ func TestManifestHead(t *testing.T) {
 			args:      []string{"manifest", "head", "ocidir://../../testdata/testrepo:v1", "--platform", "linux/unknown"},
 			expectErr: errs.ErrNotFound,
 		},
+		{
+			name:        "Batch",
+			args:        []string{"manifest", "head", "--ref-file", refFile},
+			expectOut:   "ocidir://../../testdata/testrepo:v1 sha256:",
+			outContains: true,
+		},
+		{
+			name:      "Batch format",
+			args:      []string{"manifest", "head", "--ref-file", refFile, "--format", "{{ .Ref }}"},
+			expectOut: "ocidir://../../testdata/testrepo:v1\nocidir://../../testdata/testrepo:v2",
+		},
+		{
+			name:      "Batch missing",
+			args:      []string{"manifest", "head", "--ref-file", refFileMissing},
+			expectErr: fmt.Errorf("failed to head 2 of 3 references"),
+		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
@@ -84,6 +114,176 @@ func TestManifestHead(t *testing.T) {
 	}
 }
 
+func TestManifestGet(t *testing.T) {
+	srcRef := "ocidir://../../testdata/testrepo:v3"
+	tt := []struct {
+		name        string
+		args        []string
+		expectErr   error
+		expectOut   string
+		outContains bool
+	}{
+		{
+			name:        "Default",
+			args:        []string{"manifest", "get", srcRef},
+			expectOut:   "application/vnd.oci.image.index.v1+json",
+			outContains: true,
+		},
+		{
+			name:      "Missing manifest",
+			args:      []string{"manifest", "get", "ocidir://../../testdata/testrepo:missing"},
+			expectErr: errs.ErrNotFound,
+		},
+		{
+			name:      "Query field",
+			args:      []string{"manifest", "get", srcRef, "--query", `.annotations["org.example.version"]`},
+			expectOut: "v3",
+		},
+		{
+			name:      "Query filter",
+			args:      []string{"manifest", "get", srcRef, "--query", `.manifests[?platform.architecture=="arm"].platform.variant`},
+			expectOut: "v7\nv6",
+		},
+		{
+			name:      "Query platform",
+			args:      []string{"manifest", "get", srcRef, "--platform", "linux/amd64", "--query", ".config.mediaType"},
+			expectOut: "application/vnd.oci.image.config.v1+json",
+		},
+		{
+			name:      "Query object",
+			args:      []string{"manifest", "get", srcRef, "--query", ".manifests[0].platform"},
+			expectOut: "{\n  \"architecture\": \"amd64\",\n  \"os\": \"linux\"\n}",
+		},
+		{
+			name:      "Query invalid",
+			args:      []string{"manifest", "get", srcRef, "--query", ".manifests[0"},
+			expectErr: errs.ErrParsingFailed,
+		},
+		{
+			name:      "Query and format",
+			args:      []string{"manifest", "get", srcRef, "--query", ".config", "--format", "{{ . }}"},
+			expectErr: fmt.Errorf("if any flags in the group [format query] are set none of the others can be; [format query] were all set"),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := cobraTest(t, nil, tc.args...)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Errorf("did not receive expected error: %v", tc.expectErr)
+				} else if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
+					t.Errorf("unexpected error, received %v, expected %v", err, tc.expectErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("returned unexpected error: %v", err)
+			}
+			if (!tc.outContains && out != tc.expectOut) || (tc.outContains && !strings.Contains(out, tc.expectOut)) {
+				t.Errorf("unexpected output, expected %s, received %s", tc.expectOut, out)
+			}
+		})
+	}
+}
+
+func TestManifestWatch(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	repo := "ocidir://" + t.TempDir() + "/repo"
+	rc := regclient.New()
+	rTgt, err := ref.New(repo + ":latest")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	for _, tag := range []string{"v1", "v2"} {
+		rSrc, err := ref.New("ocidir://../../testdata/testrepo:" + tag)
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		err = rc.ImageCopy(ctx, rSrc, rTgt.SetTag(tag))
+		if err != nil {
+			t.Fatalf("failed to copy image: %v", err)
+		}
+	}
+	_ = rc.Close(ctx, rTgt)
+	tt := []struct {
+		name        string
+		args        []string
+		expectErr   error
+		expectOut   string
+		outContains bool
+	}{
+		{
+			name:      "Invalid ref",
+			args:      []string{"manifest", "watch", "invalid*ref"},
+			expectErr: errs.ErrInvalidReference,
+		},
+		{
+			name:      "Invalid interval",
+			args:      []string{"manifest", "watch", rTgt.CommonName(), "--interval", "-1s"},
+			expectErr: ErrInvalidInput,
+		},
+		{
+			name:        "Change",
+			args:        []string{"manifest", "watch", rTgt.CommonName(), "--interval", "10ms", "--count", "2"},
+			expectOut:   rTgt.CommonName() + " sha256:",
+			outContains: true,
+		},
+		{
+			name:      "Format",
+			args:      []string{"manifest", "watch", rTgt.CommonName(), "--interval", "10ms", "--count", "1", "--format", "{{ .Ref }}"},
+			expectOut: rTgt.CommonName(),
+		},
+	}
+	if runtime.GOOS != "windows" {
+		tt = append(tt, struct {
+			name        string
+			args        []string
+			expectErr   error
+			expectOut   string
+			outContains bool
+		}{
+			name:        "Exec",
+			args:        []string{"manifest", "watch", rTgt.CommonName(), "--interval", "10ms", "--count", "1", "--exec", "echo digest $REGCTL_WATCH_DIGEST"},
+			expectOut:   "digest sha256:",
+			outContains: true,
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// alternate the tag between the two images until the watch returns
+			done := make(chan struct{})
+			go func() {
+				for i := 0; ; i++ {
+					select {
+					case <-done:
+						return
+					case <-time.After(time.Millisecond * 20):
+					}
+					_ = rc.ImageCopy(ctx, rTgt.SetTag(fmt.Sprintf("v%d", i%2+1)), rTgt)
+					_ = rc.Close(ctx, rTgt)
+				}
+			}()
+			out, err := cobraTest(t, nil, tc.args...)
+			close(done)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Errorf("did not receive expected error: %v", tc.expectErr)
+				} else if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
+					t.Errorf("unexpected error, received %v, expected %v", err, tc.expectErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("returned unexpected error: %v", err)
+			}
+			if (!tc.outContains && out != tc.expectOut) || (tc.outContains && !strings.Contains(out, tc.expectOut)) {
+				t.Errorf("unexpected output, expected %s, received %s", tc.expectOut, out)
+			}
+		})
+	}
+}
+
 func TestManifestRm(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
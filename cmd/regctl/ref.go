@@ -16,6 +16,13 @@ import (
 type refOpts struct {
 	rootOpts *rootOpts
 	format   string
+	strict   bool
+}
+
+// refOutput includes the defaults applied when parsing the reference.
+type refOutput struct {
+	ref.Ref
+	Normalized ref.Normalized
 }
 
 func NewRefCmd(rOpts *rootOpts) *cobra.Command {
@@ -28,25 +35,39 @@ func NewRefCmd(rOpts *rootOpts) *cobra.Command {
 		Use:    "ref",
 		Short:  "parse an image ref",
 		Long: `Parse an image reference so that it may be output with formatting.
+Docker style shorthand, like "nginx" for "docker.io/library/nginx:latest", is expanded.
+The defaults applied are available with the ".Normalized" template field,
+and the "--strict" flag rejects any reference that depends on these defaults.
 This command is EXPERIMENTAL and could be removed in the future.`,
 		Example: `
 # extract the registry (docker.io)
 regctl ref nginx --format '{{ .Registry }}'
+
+# list the defaults applied to a shorthand reference
+regctl ref nginx --format '{{ range .Normalized.List }}{{ println . }}{{ end }}'
+
+# verify a reference is fully qualified
+regctl ref --strict docker.io/library/nginx:latest
 `,
 		Args: cobra.ExactArgs(1),
 		RunE: opts.runRef,
 	}
 	cmd.Flags().StringVar(&opts.format, "format", "{{.CommonName}}", "Format the output using a Go template")
 	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	cmd.Flags().BoolVar(&opts.strict, "strict", false, "Reject references without a registry, repository namespace, and tag or digest")
 
 	return cmd
 }
 
 func (opts *refOpts) runRef(cmd *cobra.Command, args []string) error {
-	r, err := ref.New(args[0])
+	nOpts := []ref.NormalizeOpts{}
+	if opts.strict {
+		nOpts = append(nOpts, ref.WithNormalizeStrict())
+	}
+	r, n, err := ref.NewNormalized(args[0], nOpts...)
 	if err != nil {
 		return fmt.Errorf("failed to parse %s: %w", args[0], err)
 	}
 
-	return template.Writer(cmd.OutOrStdout(), opts.format, r)
+	return template.Writer(cmd.OutOrStdout(), opts.format, refOutput{Ref: r, Normalized: n})
 }
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"errors"
 	"strings"
 	"testing"
+
+	"github.com/regclient/regclient/types/errs"
 )
 
 func TestRef(t *testing.T) {
 	tt := []struct {
 		name        string
 		cmd         []string
+		expectErr   error
 		expectOut   string
 		outContains bool
 	}{
@@ -42,10 +46,36 @@ func TestRef(t *testing.T) {
 			cmd:       []string{"ref", "ocidir://regclient/regctl:v0.3", "--format", `{{.Path}}`},
 			expectOut: "regclient/regctl",
 		},
+		{
+			name:      "normalized list",
+			cmd:       []string{"ref", "nginx", "--format", `{{ join .Normalized.List "," }}`},
+			expectOut: "added registry docker.io,added repository prefix library/,added tag latest",
+		},
+		{
+			name:      "normalized alias",
+			cmd:       []string{"ref", "index.docker.io/library/nginx:1", "--format", `{{ .Normalized.RegistryAlias }}`},
+			expectOut: "index.docker.io",
+		},
+		{
+			name:      "strict",
+			cmd:       []string{"ref", "--strict", "docker.io/library/nginx:1"},
+			expectOut: "docker.io/library/nginx:1",
+		},
+		{
+			name:      "strict shorthand",
+			cmd:       []string{"ref", "--strict", "nginx:1"},
+			expectErr: errs.ErrInvalidReference,
+		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
 			out, err := cobraTest(t, nil, tc.cmd...)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
 			if err != nil {
 				t.Fatalf("error: %v", err)
 			}
@@ -2,22 +2,31 @@ package main
 
 import (
 	"bufio"
+	"cmp"
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"math"
 	"os"
 	"os/signal"
+	"regexp"
+	"slices"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 
 	"github.com/regclient/regclient"
 	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/internal/units"
 	"github.com/regclient/regclient/pkg/template"
+	"github.com/regclient/regclient/scheme"
 	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ping"
 	"github.com/regclient/regclient/types/ref"
 )
 
@@ -28,17 +37,35 @@ type registryOpts struct {
 	passStdin            bool
 	credHelper           string
 	hostname, pathPrefix string
+	basePath, url        string
 	cacert, tls          string // set opts
+	tlsMinVersion        string
+	tlsServerName        string
 	clientCert           string
 	clientKey            string
 	mirrors              []string
+	immutableTags        []string
 	priority             uint
+	proxy                string
+	noProxy              []string
 	repoAuth             bool
 	blobChunk, blobMax   int64
 	reqPerSec            float64
 	reqConcurrent        int64
 	skipCheck            bool
+	deviceCode           bool // device login opts
+	clientID, scope      string
+	issuer               string
+	deviceURL, tokenURL  string
 	apiOpts              []string
+	importDocker         string // import opts
+	importDaemon         string
+	importContainerd     string
+	dryRun               bool
+	dedupInclude         []string // dedup-report opts
+	dedupExclude         []string
+	dedupBlobs           int
+	dedupConcurrent      int
 	scheme               string   // TODO: remove
 	dns                  []string // TODO: remove
 }
@@ -53,6 +80,8 @@ This location can be overridden with the %s environment variable.
 Note that these commands do not include logins imported from Docker or values injected with --host.`, ConfigHomeDir, ConfigFilename, ConfigEnv),
 	}
 	cmd.AddCommand(newRegistryConfigCmd(rOpts))
+	cmd.AddCommand(newRegistryDedupReportCmd(rOpts))
+	cmd.AddCommand(newRegistryImportCmd(rOpts))
 	cmd.AddCommand(newRegistryLoginCmd(rOpts))
 	cmd.AddCommand(newRegistryLogoutCmd(rOpts))
 	cmd.AddCommand(newRegistrySetCmd(rOpts))
@@ -90,6 +119,81 @@ regctl registry config docker.io --format '{{.User}}'`,
 	return cmd
 }
 
+func newRegistryDedupReportCmd(rOpts *rootOpts) *cobra.Command {
+	opts := registryOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "dedup-report <registry>[/namespace]",
+		Short: "report layers shared between repositories",
+		Long: `Report the layers shared between repositories in a registry.
+Repositories are listed with the _catalog API, limited to an optional namespace,
+and filtered with include/exclude regular expressions matching the repository path.
+Every tag in each repository is walked, and manifests found in multiple repositories are only pulled once.
+The output shows the size of each repository, the size shared with other repositories,
+and the layers found in the most repositories, which are candidates for a common base image.
+Note: Docker Hub and many cloud registries do not support the _catalog API.`,
+		Example: `
+# report the shared layers in a registry
+regctl registry dedup-report registry.example.org
+
+# report the shared layers in the team namespace, excluding the test repositories
+regctl registry dedup-report --exclude 'team/test/.*' registry.example.org/team
+
+# output the total and deduplicated size in bytes
+regctl registry dedup-report --format '{{ .Size }} {{ .Dedup }}' registry.example.org`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: registryArgListReg,
+		RunE:              opts.runRegistryDedupReport,
+	}
+	cmd.Flags().IntVar(&opts.dedupBlobs, "blobs", 20, "Number of shared layers to show, -1 for all layers")
+	_ = cmd.RegisterFlagCompletionFunc("blobs", completeArgNone)
+	cmd.Flags().IntVar(&opts.dedupConcurrent, "concurrent", 4, "Number of concurrent repositories to walk")
+	cmd.Flags().StringArrayVar(&opts.dedupExclude, "exclude", []string{}, "Exclude repositories by regexp")
+	_ = cmd.RegisterFlagCompletionFunc("exclude", completeArgNone)
+	cmd.Flags().StringVar(&opts.format, "format", "", "Format output with go template syntax")
+	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	cmd.Flags().StringArrayVar(&opts.dedupInclude, "include", []string{}, "Include repositories by regexp")
+	_ = cmd.RegisterFlagCompletionFunc("include", completeArgNone)
+	return cmd
+}
+
+func newRegistryImportCmd(rOpts *rootOpts) *cobra.Command {
+	opts := registryOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "import registry settings from Docker and containerd",
+		Long: `Import logins, mirrors, and TLS settings from Docker and containerd configs into the regctl config.
+Logins are imported from the Docker client config, including credential helpers.
+Registry mirrors and insecure registries are imported from the Docker daemon config,
+with insecure registries configured as "--tls disabled".
+Registries in the containerd certs.d directory are imported from each hosts.toml,
+including the upstream server, mirrors with the pull capability, CA and client certificates, and skip_verify.
+Mirrors are added as separate registries, and the "_default" directory is not supported.
+Settings are merged with any existing configuration of each registry.`,
+		Example: `
+# import logins from docker
+regctl registry import --docker ~/.docker/config.json
+
+# import mirrors and TLS settings from containerd
+regctl registry import --containerd /etc/containerd/certs.d
+
+# show the registry settings without changing the config
+regctl registry import --docker-daemon /etc/docker/daemon.json --dry-run`,
+		Args: cobra.ExactArgs(0),
+		RunE: opts.runRegistryImport,
+	}
+	cmd.Flags().StringVar(&opts.importContainerd, "containerd", "", "Containerd registry config directory, e.g. /etc/containerd/certs.d")
+	cmd.Flags().StringVar(&opts.importDocker, "docker", "", "Docker client config, e.g. ~/.docker/config.json")
+	cmd.Flags().StringVar(&opts.importDaemon, "docker-daemon", "", "Docker daemon config, e.g. /etc/docker/daemon.json")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Output the imported registries without changing the config")
+	cmd.Flags().StringVar(&opts.format, "format", "{{jsonPretty .}}", "Format output of a dry run with go template syntax")
+	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	return cmd
+}
+
 func newRegistryLoginCmd(rOpts *rootOpts) *cobra.Command {
 	opts := registryOpts{
 		rootOpts: rOpts,
@@ -98,7 +202,12 @@ func newRegistryLoginCmd(rOpts *rootOpts) *cobra.Command {
 		Use:   "login <registry>",
 		Short: "login to a registry",
 		Long: `Provide login credentials for a registry. This may not be necessary if you
-have already logged in with docker.`,
+have already logged in with docker.
+With --device-code, the login is approved in a browser using the OAuth device authorization flow.
+The access token is used as the registry password, and is refreshed when it expires using a refresh token stored with the credentials.
+Credentials and refresh tokens are stored in the OS keyring when the docker credential helper for it is installed
+(osxkeychain, wincred, or secretservice), otherwise they are saved in the regctl config.
+Use "regctl config set --keyring=false" to always save credentials in the regctl config.`,
 		Example: `
 # login to Docker Hub
 regctl registry login
@@ -107,17 +216,37 @@ regctl registry login
 regctl registry login registry.example.org
 
 # login to GHCR with a provided password
-echo "${token}" | regctl registry login ghcr.io -u "${username}" --pass-stdin`,
+echo "${token}" | regctl registry login ghcr.io -u "${username}" --pass-stdin
+
+# login to GHCR with a browser using the client ID of a GitHub OAuth app
+regctl registry login ghcr.io --device-code --client-id "${client_id}"
+
+# login with a browser using an OIDC provider that supports the device authorization flow
+regctl registry login registry.example.org --device-code \
+  --client-id regctl --issuer https://idp.example.org/realms/example`,
 		Args:              cobra.RangeArgs(0, 1),
 		ValidArgsFunction: registryArgListReg,
 		RunE:              opts.runRegistryLogin,
 	}
+	cmd.Flags().StringVar(&opts.clientID, "client-id", "", "OAuth client ID for a device code login")
+	_ = cmd.RegisterFlagCompletionFunc("client-id", completeArgNone)
+	cmd.Flags().BoolVar(&opts.deviceCode, "device-code", false, "Login with a browser using the OAuth device authorization flow")
+	cmd.Flags().StringVar(&opts.deviceURL, "device-url", "", "OAuth device authorization endpoint, discovered from the issuer when not set")
+	_ = cmd.RegisterFlagCompletionFunc("device-url", completeArgNone)
+	cmd.Flags().StringVar(&opts.issuer, "issuer", "", "OIDC issuer used to discover the device authorization and token endpoints")
+	_ = cmd.RegisterFlagCompletionFunc("issuer", completeArgNone)
 	cmd.Flags().StringVarP(&opts.pass, "pass", "p", "", "Password")
 	_ = cmd.RegisterFlagCompletionFunc("pass", completeArgNone)
 	cmd.Flags().BoolVar(&opts.passStdin, "pass-stdin", false, "Read password from stdin")
+	cmd.Flags().StringVar(&opts.scope, "scope", "", "OAuth scopes requested for a device code login")
+	_ = cmd.RegisterFlagCompletionFunc("scope", completeArgNone)
 	cmd.Flags().BoolVar(&opts.skipCheck, "skip-check", false, "Skip checking connectivity to the registry")
+	cmd.Flags().StringVar(&opts.tokenURL, "token-url", "", "OAuth token endpoint, discovered from the issuer when not set")
+	_ = cmd.RegisterFlagCompletionFunc("token-url", completeArgNone)
 	cmd.Flags().StringVarP(&opts.user, "user", "u", "", "Username")
 	_ = cmd.RegisterFlagCompletionFunc("user", completeArgNone)
+	cmd.MarkFlagsMutuallyExclusive("device-code", "pass")
+	cmd.MarkFlagsMutuallyExclusive("device-code", "pass-stdin")
 	return cmd
 }
 
@@ -155,11 +284,33 @@ func newRegistrySetCmd(rOpts *rootOpts) *cobra.Command {
 regctl registry set localhost:5000 --tls disabled
 
 # configure a self signed certificate
-regctl registry set registry.example.org --cacert "$(cat reg-ca.crt)"
+regctl registry set registry.example.org --cacert reg-ca.crt
+
+# require TLS 1.3 and verify the certificate with a different server name
+regctl registry set registry.example.org --tls-min-version 1.3 --tls-server-name registry.internal
 
 # specify a local mirror for Docker Hub
 regctl registry set docker.io --mirror hub-mirror.example.org
 
+# try a list of mirrors in order before falling back to Docker Hub
+regctl registry set docker.io --mirror hub-mirror-1.example.org --mirror hub-mirror-2.example.org
+
+# send requests through a socks proxy, except for an internal CDN
+regctl registry set registry.example.org --proxy socks5://proxy.example.org:1080 --no-proxy cdn.example.org
+
+# protect release tags from being replaced or deleted without "--force"
+regctl registry set registry.example.org --immutable-tag '^v\d+\.\d+\.\d+$'
+
+# protect the latest tag in a single repository
+regctl registry set registry.example.org --immutable-tag '^prod/app$=^latest$'
+
+# alias a registry served under a path, with repositories in a namespace
+regctl registry set artifactory.alias --hostname artifactory.example.org \
+  --base-path artifactory/api/docker/repo --path-prefix team
+
+# alias a registry with the base url
+regctl registry set nexus.alias --url https://nexus.example.org/repository/docker-hosted
+
 # specify the requests per sec throttle
 regctl registry set quay.io --req-per-sec 10`,
 		Args:              cobra.RangeArgs(0, 1),
@@ -167,28 +318,41 @@ regctl registry set quay.io --req-per-sec 10`,
 		RunE:              opts.runRegistrySet,
 	}
 	cmd.Flags().StringArrayVar(&opts.apiOpts, "api-opts", nil, "List of options (key=value))")
+	cmd.Flags().StringVar(&opts.basePath, "base-path", "", "Path before the /v2 API for registries served under a path")
+	_ = cmd.RegisterFlagCompletionFunc("base-path", completeArgNone)
 	cmd.Flags().Int64Var(&opts.blobChunk, "blob-chunk", 0, "Blob chunk size")
 	_ = cmd.RegisterFlagCompletionFunc("blob-chunk", completeArgNone)
 	cmd.Flags().Int64Var(&opts.blobMax, "blob-max", 0, "Blob size before switching to chunked push, -1 to disable")
 	_ = cmd.RegisterFlagCompletionFunc("blob-max", completeArgNone)
-	cmd.Flags().StringVar(&opts.cacert, "cacert", "", "CA Certificate (not a filename, use \"$(cat ca.pem)\" to use a file)")
-	_ = cmd.RegisterFlagCompletionFunc("cacert", completeArgNone)
+	cmd.Flags().StringVar(&opts.cacert, "cacert", "", "CA Certificate, either a filename or the PEM contents")
 	cmd.Flags().StringVar(&opts.clientCert, "client-cert", "", "Client certificate for mTLS (not a filename, use \"$(cat client.pem)\" to use a file)")
 	cmd.Flags().StringVar(&opts.clientKey, "client-key", "", "Client key for mTLS (not a filename, use \"$(cat client.key)\" to use a file)")
 	cmd.Flags().StringVar(&opts.credHelper, "cred-helper", "", "Credential helper (full binary name, including docker-credential- prefix)")
 	cmd.Flags().StringVar(&opts.hostname, "hostname", "", "Hostname or ip with port")
 	_ = cmd.RegisterFlagCompletionFunc("hostname", completeArgNone)
-	cmd.Flags().StringArrayVar(&opts.mirrors, "mirror", nil, "List of mirrors (registry names)")
+	cmd.Flags().StringArrayVar(&opts.immutableTags, "immutable-tag", nil, "List of tag regexps that cannot be replaced or deleted without force, optionally prefixed with a repository regexp (repo=tag)")
+	_ = cmd.RegisterFlagCompletionFunc("immutable-tag", completeArgNone)
+	cmd.Flags().StringArrayVar(&opts.mirrors, "mirror", nil, "List of mirrors (registry names), attempted in order before the registry")
 	_ = cmd.RegisterFlagCompletionFunc("mirror", completeArgNone)
+	cmd.Flags().StringArrayVar(&opts.noProxy, "no-proxy", nil, "List of hosts, domains, or CIDRs that bypass the proxy")
+	_ = cmd.RegisterFlagCompletionFunc("no-proxy", completeArgNone)
 	cmd.Flags().StringVar(&opts.pathPrefix, "path-prefix", "", "Prefix to all repositories")
 	_ = cmd.RegisterFlagCompletionFunc("path-prefix", completeArgNone)
 	cmd.Flags().UintVar(&opts.priority, "priority", 0, "Priority (for sorting mirrors)")
 	_ = cmd.RegisterFlagCompletionFunc("priority", completeArgNone)
+	cmd.Flags().StringVar(&opts.proxy, "proxy", "", "Proxy URL (http, https, socks5), or \"direct\" to ignore proxy environment variables")
+	_ = cmd.RegisterFlagCompletionFunc("proxy", completeArgNone)
 	cmd.Flags().BoolVar(&opts.repoAuth, "repo-auth", false, "Separate auth requests per repository instead of per registry")
 	cmd.Flags().Int64Var(&opts.reqConcurrent, "req-concurrent", 0, "Concurrent requests")
 	cmd.Flags().Float64Var(&opts.reqPerSec, "req-per-sec", 0, "Requests per second")
 	cmd.Flags().BoolVar(&opts.skipCheck, "skip-check", false, "Skip checking connectivity to the registry")
 	cmd.Flags().StringVar(&opts.tls, "tls", "", "TLS (enabled, insecure, disabled)")
+	cmd.Flags().StringVar(&opts.tlsMinVersion, "tls-min-version", "", "Minimum TLS version (1.0, 1.1, 1.2, 1.3)")
+	_ = cmd.RegisterFlagCompletionFunc("tls-min-version", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"1.0", "1.1", "1.2", "1.3"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.Flags().StringVar(&opts.tlsServerName, "tls-server-name", "", "Server name (SNI) used to verify the registry certificate")
+	_ = cmd.RegisterFlagCompletionFunc("tls-server-name", completeArgNone)
 	_ = cmd.RegisterFlagCompletionFunc("tls", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return []string{
 			"enabled",
@@ -196,6 +360,8 @@ regctl registry set quay.io --req-per-sec 10`,
 			"disabled",
 		}, cobra.ShellCompDirectiveNoFileComp
 	})
+	cmd.Flags().StringVar(&opts.url, "url", "", "Base URL of the registry, setting the TLS, hostname, and base path")
+	_ = cmd.RegisterFlagCompletionFunc("url", completeArgNone)
 
 	// TODO: eventually remove
 	cmd.Flags().StringArrayVar(&opts.dns, "dns", nil, "[Deprecated] DNS hostname or ip with port")
@@ -210,19 +376,31 @@ func newRegistryWhoamiCmd(rOpts *rootOpts) *cobra.Command {
 		rootOpts: rOpts,
 	}
 	cmd := &cobra.Command{
-		Use:   "whoami [registry]",
+		Use:   "whoami [registry|ref]",
 		Short: "show current login for a registry",
-		Long:  `Displays the username for a given registry.`,
+		Long: `Displays the username for a given registry.
+The login is verified with the registry, and the user and access reported by the registry token are included when available.
+When a repository is provided, the pull and push access granted on that repository is displayed.
+Access is "unknown" when the registry does not return a token that can be inspected.`,
 		Example: `
 # show the login on Docker Hub
 regctl registry whoami
 
 # show the login on another registry
-regctl registry whoami registry.example.org`,
+regctl registry whoami registry.example.org
+
+# show the access to a repository
+regctl registry whoami registry.example.org/repo
+
+# show the scopes in the token
+regctl registry whoami ghcr.io/regclient/regctl --format '{{ jsonPretty .Auth }}'`,
 		Args:              cobra.RangeArgs(0, 1),
 		ValidArgsFunction: registryArgListReg,
 		RunE:              opts.runRegistryWhoami,
 	}
+	cmd.Flags().StringVar(&opts.format, "format", whoamiFormat, "Format output with go template syntax")
+	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	cmd.Flags().BoolVar(&opts.skipCheck, "skip-check", false, "Only show the configured login without checking the registry")
 	return cmd
 }
 
@@ -270,6 +448,186 @@ func (opts *registryOpts) runRegistryConfig(cmd *cobra.Command, args []string) e
 	}
 }
 
+func (opts *registryOpts) runRegistryDedupReport(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	host, namespace, _ := strings.Cut(strings.TrimSuffix(args[0], "/"), "/")
+	if namespace != "" {
+		namespace = namespace + "/"
+	}
+	include, err := registryCompileFilters(opts.dedupInclude)
+	if err != nil {
+		return err
+	}
+	exclude, err := registryCompileFilters(opts.dedupExclude)
+	if err != nil {
+		return err
+	}
+	rc := opts.rootOpts.newRegClient()
+	repos := []ref.Ref{}
+	last := ""
+	// loop through pages of the _catalog response
+	for {
+		sOpts := []scheme.RepoOpts{}
+		if last != "" {
+			sOpts = append(sOpts, scheme.WithRepoLast(last))
+		}
+		rl, err := rc.RepoList(ctx, host, sOpts...)
+		if err != nil {
+			return err
+		}
+		repoList, err := rl.GetRepos()
+		if err != nil {
+			return err
+		}
+		if len(repoList) == 0 || last == repoList[len(repoList)-1] {
+			break
+		}
+		last = repoList[len(repoList)-1]
+		for _, repo := range repoList {
+			if !strings.HasPrefix(repo, namespace) ||
+				(len(include) > 0 && !slices.ContainsFunc(include, func(re *regexp.Regexp) bool { return re.MatchString(repo) })) ||
+				slices.ContainsFunc(exclude, func(re *regexp.Regexp) bool { return re.MatchString(repo) }) {
+				continue
+			}
+			r, err := ref.New(host + "/" + repo)
+			if err != nil {
+				return err
+			}
+			repos = append(repos, r)
+		}
+	}
+	if len(repos) == 0 {
+		return fmt.Errorf("no repositories found in %s%.0w", args[0], errs.ErrNotFound)
+	}
+	opts.rootOpts.log.Debug("Registry dedup report",
+		slog.String("host", host),
+		slog.String("namespace", namespace),
+		slog.Int("repos", len(repos)))
+	report, err := rc.RepoDedup(ctx, repos,
+		regclient.RepoDedupWithBlobLimit(opts.dedupBlobs),
+		regclient.RepoDedupWithParallel(opts.dedupConcurrent))
+	if opts.format != "" {
+		if tErr := template.Writer(cmd.OutOrStdout(), opts.format, report); tErr != nil {
+			return tErr
+		}
+		return err
+	}
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Repositories: %d\n", len(report.Repos))
+	fmt.Fprintf(out, "Size:         %s\n", units.HumanSize(float64(report.Size)))
+	fmt.Fprintf(out, "Deduplicated: %s\n", units.HumanSize(float64(report.Dedup)))
+	fmt.Fprintf(out, "\n")
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "REPOSITORY\tSIZE\tSHARED\n")
+	for _, repo := range report.Repos {
+		if repo.Err != nil {
+			fmt.Fprintf(tw, "%s\terror: %s\t\n", repo.Repo, repo.Err.Error())
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", repo.Repo, units.HumanSize(float64(repo.Size)), units.HumanSize(float64(repo.Shared)))
+	}
+	if tErr := tw.Flush(); tErr != nil {
+		return tErr
+	}
+	if len(report.Blobs) > 0 {
+		fmt.Fprintf(out, "\n")
+		tw = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "LAYER\tSIZE\tSAVINGS\tREPOSITORIES\n")
+		for _, b := range report.Blobs {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", b.Digest.String(), units.HumanSize(float64(b.Size)), units.HumanSize(float64(b.Savings())), strings.Join(b.Repos, ", "))
+		}
+		if tErr := tw.Flush(); tErr != nil {
+			return tErr
+		}
+	}
+	return err
+}
+
+// registryCompileFilters compiles a list of anchored regular expressions.
+func registryCompileFilters(exps []string) ([]*regexp.Regexp, error) {
+	ret := make([]*regexp.Regexp, 0, len(exps))
+	for _, exp := range exps {
+		re, err := regexp.Compile("^" + exp + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid repository filter %q: %w%.0w", exp, err, ErrInvalidInput)
+		}
+		ret = append(ret, re)
+	}
+	return ret, nil
+}
+
+func (opts *registryOpts) runRegistryImport(cmd *cobra.Command, args []string) error {
+	if opts.importDocker == "" && opts.importDaemon == "" && opts.importContainerd == "" {
+		return fmt.Errorf("one of --docker, --docker-daemon, or --containerd is required%.0w", ErrMissingInput)
+	}
+	c, err := ConfigLoadDefault()
+	if err != nil {
+		return err
+	}
+	hosts := []config.Host{}
+	if opts.importDocker != "" {
+		dockerHosts, err := config.DockerLoadFile(opts.importDocker)
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", opts.importDocker, err)
+		}
+		hosts = append(hosts, dockerHosts...)
+	}
+	if opts.importDaemon != "" {
+		daemonHosts, err := config.DockerDaemonLoadFile(opts.importDaemon)
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", opts.importDaemon, err)
+		}
+		hosts = append(hosts, daemonHosts...)
+	}
+	if opts.importContainerd != "" {
+		ctrHosts, err := config.ContainerdLoadDir(opts.importContainerd)
+		if err != nil && len(ctrHosts) == 0 {
+			return fmt.Errorf("failed to import %s: %w", opts.importContainerd, err)
+		} else if err != nil {
+			opts.rootOpts.log.Warn("Skipped some containerd registries",
+				slog.String("dir", opts.importContainerd),
+				slog.String("err", err.Error()))
+		}
+		hosts = append(hosts, ctrHosts...)
+	}
+	imported := map[string]*config.Host{}
+	for _, h := range hosts {
+		if h.IsZero() {
+			continue
+		}
+		cur, ok := c.Hosts[h.Name]
+		if !ok {
+			cur = config.HostNewName(h.Name)
+			c.Hosts[h.Name] = cur
+		}
+		err = cur.Merge(h, opts.rootOpts.log)
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", h.Name, err)
+		}
+		imported[h.Name] = cur
+	}
+	if opts.dryRun {
+		// do not output secrets
+		for name, h := range imported {
+			hCopy := *h
+			hCopy.Pass = ""
+			hCopy.Token = ""
+			hCopy.ClientKey = ""
+			imported[name] = &hCopy
+		}
+		return template.Writer(cmd.OutOrStdout(), opts.format, imported)
+	}
+	err = c.ConfigSave()
+	if err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(imported)) {
+		opts.rootOpts.log.Info("Registry configuration imported",
+			slog.String("name", name))
+	}
+	return nil
+}
+
 func (opts *registryOpts) runRegistryLogin(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	// disable signal handler to allow ctrl-c to be used on prompts (context cancel on a blocking reader is difficult)
@@ -290,75 +648,85 @@ func (opts *registryOpts) runRegistryLogin(cmd *cobra.Command, args []string) er
 	} else {
 		c.Hosts[h.Name] = h
 	}
-	if flagChanged(cmd, "user") {
-		h.User = opts.user
-	} else if opts.passStdin {
-		return fmt.Errorf("user must be provided to read password from stdin")
-	} else {
-		// prompt for username
-		reader := bufio.NewReader(cmd.InOrStdin())
-		defUser := ""
-		if h.User != "" {
-			defUser = " [" + h.User + "]"
-		}
-		fmt.Fprintf(cmd.OutOrStdout(), "Enter Username%s: ", defUser)
-		user, _ := reader.ReadString('\n')
-		user = strings.TrimSpace(user)
-		if user != "" {
-			h.User = user
-		} else if h.User == "" {
-			opts.rootOpts.log.Error("Username is required")
-
-			return ErrMissingInput
-		}
-	}
-	if flagChanged(cmd, "pass") {
-		h.Pass = opts.pass
-	} else if opts.passStdin {
-		pass, err := io.ReadAll(cmd.InOrStdin())
+	if opts.deviceCode {
+		err = opts.registryLoginDevice(ctx, cmd, c, h)
 		if err != nil {
-			return fmt.Errorf("failed to read password from stdin: %w", err)
+			return err
 		}
-		passwd := strings.TrimRight(string(pass), "\n")
-		if passwd != "" {
-			h.Pass = passwd
+	} else {
+		if flagChanged(cmd, "user") {
+			h.User = opts.user
+		} else if opts.passStdin {
+			return fmt.Errorf("user must be provided to read password from stdin")
 		} else {
-			opts.rootOpts.log.Error("Password is required")
+			// prompt for username
+			reader := bufio.NewReader(cmd.InOrStdin())
+			defUser := ""
+			if h.User != "" {
+				defUser = " [" + h.User + "]"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Enter Username%s: ", defUser)
+			user, _ := reader.ReadString('\n')
+			user = strings.TrimSpace(user)
+			if user != "" {
+				h.User = user
+			} else if h.User == "" {
+				opts.rootOpts.log.Error("Username is required")
 
-			return ErrMissingInput
+				return ErrMissingInput
+			}
 		}
-	} else {
-		// prompt for a password
-		var fd int
-		if ifd, ok := cmd.InOrStdin().(interface{ Fd() uintptr }); ok && ifd.Fd() <= math.MaxInt {
-			//#nosec G115 false positive
-			fd = int(ifd.Fd())
+		if flagChanged(cmd, "pass") {
+			h.Pass = opts.pass
+		} else if opts.passStdin {
+			pass, err := io.ReadAll(cmd.InOrStdin())
+			if err != nil {
+				return fmt.Errorf("failed to read password from stdin: %w", err)
+			}
+			passwd := strings.TrimRight(string(pass), "\n")
+			if passwd != "" {
+				h.Pass = passwd
+			} else {
+				opts.rootOpts.log.Error("Password is required")
+
+				return ErrMissingInput
+			}
 		} else {
-			return fmt.Errorf("file descriptor needed to prompt for password (resolve by using \"-p\" flag)")
-		}
-		fmt.Fprint(cmd.OutOrStdout(), "Enter Password: ")
-		pass, err := term.ReadPassword(fd)
-		if err != nil {
-			return fmt.Errorf("unable to read from tty (resolve by using \"-p\" flag, or winpty on Windows): %w", err)
+			// prompt for a password
+			var fd int
+			if ifd, ok := cmd.InOrStdin().(interface{ Fd() uintptr }); ok && ifd.Fd() <= math.MaxInt {
+				//#nosec G115 false positive
+				fd = int(ifd.Fd())
+			} else {
+				return fmt.Errorf("file descriptor needed to prompt for password (resolve by using \"-p\" flag)")
+			}
+			fmt.Fprint(cmd.OutOrStdout(), "Enter Password: ")
+			pass, err := term.ReadPassword(fd)
+			if err != nil {
+				return fmt.Errorf("unable to read from tty (resolve by using \"-p\" flag, or winpty on Windows): %w", err)
+			}
+			passwd := strings.TrimRight(string(pass), "\n")
+			fmt.Fprint(cmd.OutOrStdout(), "\n")
+			if passwd != "" {
+				h.Pass = passwd
+			} else {
+				opts.rootOpts.log.Error("Password is required")
+
+				return ErrMissingInput
+			}
 		}
-		passwd := strings.TrimRight(string(pass), "\n")
-		fmt.Fprint(cmd.OutOrStdout(), "\n")
-		if passwd != "" {
-			h.Pass = passwd
+		// if username is <token> then process password as an identity token
+		if h.User == "<token>" {
+			h.Token = h.Pass
+			h.User = ""
+			h.Pass = ""
 		} else {
-			opts.rootOpts.log.Error("Password is required")
-
-			return ErrMissingInput
+			h.Token = ""
 		}
+		// credentials from a device login are replaced
+		c.deviceErase(h, opts.rootOpts.log)
 	}
-	// if username is <token> then process password as an identity token
-	if h.User == "<token>" {
-		h.Token = h.Pass
-		h.User = ""
-		h.Pass = ""
-	} else {
-		h.Token = ""
-	}
+	c.credSave(h, opts.rootOpts.log)
 	err = c.ConfigSave()
 	if err != nil {
 		return err
@@ -381,6 +749,77 @@ func (opts *registryOpts) runRegistryLogin(cmd *cobra.Command, args []string) er
 	return nil
 }
 
+// registryLoginDevice sets the credentials for a host using an OAuth device authorization flow.
+func (opts *registryOpts) registryLoginDevice(ctx context.Context, cmd *cobra.Command, c *Config, h *config.Host) error {
+	preset := devicePresets[h.Name]
+	if opts.clientID == "" {
+		opts.rootOpts.log.Error("Client ID is required for a device code login")
+		return ErrMissingInput
+	}
+	deviceURL, tokenURL := opts.deviceURL, opts.tokenURL
+	if deviceURL == "" || tokenURL == "" {
+		issuer := opts.issuer
+		if issuer == "" {
+			issuer = preset.issuer
+		}
+		switch {
+		case issuer != "":
+			dURL, tURL, err := deviceDiscover(ctx, issuer)
+			if err != nil {
+				return err
+			}
+			deviceURL = cmp.Or(deviceURL, dURL)
+			tokenURL = cmp.Or(tokenURL, tURL)
+		case preset.deviceURL != "":
+			deviceURL = cmp.Or(deviceURL, preset.deviceURL)
+			tokenURL = cmp.Or(tokenURL, preset.tokenURL)
+		default:
+			opts.rootOpts.log.Error("An issuer or device and token URLs are required for a device code login",
+				slog.String("registry", h.Name))
+			return ErrMissingInput
+		}
+	}
+	scope := preset.scope
+	if flagChanged(cmd, "scope") {
+		scope = opts.scope
+	}
+	user := cmp.Or(preset.user, "oauth2")
+	if flagChanged(cmd, "user") {
+		user = opts.user
+	}
+	da, err := deviceAuthorize(ctx, deviceURL, opts.clientID, scope)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "To login, open %s and enter the code: %s\n", da.VerificationURI, da.UserCode)
+	if da.VerificationURIComplete != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Or open: %s\n", da.VerificationURIComplete)
+	}
+	fmt.Fprint(cmd.OutOrStdout(), "Waiting for the login to be approved...\n")
+	tok, err := devicePoll(ctx, tokenURL, opts.clientID, da)
+	if err != nil {
+		return err
+	}
+	h.User = user
+	h.Pass = tok.AccessToken
+	h.Token = ""
+	if tok.RefreshToken == "" {
+		c.deviceErase(h, opts.rootOpts.log)
+		return nil
+	}
+	if c.DeviceLogins == nil {
+		c.DeviceLogins = map[string]*DeviceLogin{}
+	}
+	dl := &DeviceLogin{
+		ClientID: opts.clientID,
+		TokenURL: tokenURL,
+		Scope:    scope,
+	}
+	dl.update(tok)
+	c.DeviceLogins[h.Name] = dl
+	return nil
+}
+
 func (opts *registryOpts) runRegistryLogout(cmd *cobra.Command, args []string) error {
 	c, err := ConfigLoadDefault()
 	if err != nil {
@@ -398,13 +837,14 @@ func (opts *registryOpts) runRegistryLogout(cmd *cobra.Command, args []string) e
 			slog.String("registry", h.Name))
 		return nil
 	}
+	c.credErase(h, opts.rootOpts.log)
 	h.User = ""
 	h.Pass = ""
 	h.Token = ""
+	delete(c.DeviceLogins, h.Name)
 	if h.IsZero() {
 		delete(c.Hosts, h.Name)
 	}
-	// TODO: add credHelper calls to erase a password
 	err = c.ConfigSave()
 	if err != nil {
 		return err
@@ -451,8 +891,26 @@ func (opts *registryOpts) runRegistrySet(cmd *cobra.Command, args []string) erro
 			return err
 		}
 	}
+	if flagChanged(cmd, "tls-min-version") {
+		if err := h.TLSMinVersion.UnmarshalText([]byte(opts.tlsMinVersion)); err != nil {
+			return err
+		}
+	}
+	if flagChanged(cmd, "tls-server-name") {
+		h.TLSServerName = opts.tlsServerName
+	}
 	if flagChanged(cmd, "cacert") {
-		h.RegCert = opts.cacert
+		cacert := opts.cacert
+		if cacert != "" && !strings.Contains(cacert, "-----BEGIN") {
+			// value is not a pem, load it from a file
+			//#nosec G304 command is run by a user accessing their own files
+			b, err := os.ReadFile(cacert)
+			if err != nil {
+				return fmt.Errorf("failed to read cacert file %s: %w", cacert, err)
+			}
+			cacert = string(b)
+		}
+		h.RegCert = cacert
 	}
 	if flagChanged(cmd, "client-cert") {
 		h.ClientCert = opts.clientCert
@@ -463,6 +921,12 @@ func (opts *registryOpts) runRegistrySet(cmd *cobra.Command, args []string) erro
 	if flagChanged(cmd, "hostname") {
 		h.Hostname = opts.hostname
 	}
+	if flagChanged(cmd, "url") {
+		h.URL = opts.url
+	}
+	if flagChanged(cmd, "base-path") {
+		h.BasePath = opts.basePath
+	}
 	if flagChanged(cmd, "path-prefix") {
 		h.PathPrefix = opts.pathPrefix
 	}
@@ -475,6 +939,28 @@ func (opts *registryOpts) runRegistrySet(cmd *cobra.Command, args []string) erro
 	if flagChanged(cmd, "repo-auth") {
 		h.RepoAuth = opts.repoAuth
 	}
+	if flagChanged(cmd, "proxy") {
+		if _, err := config.ProxyParse(opts.proxy); err != nil {
+			return err
+		}
+		h.Proxy = opts.proxy
+	}
+	if flagChanged(cmd, "no-proxy") {
+		h.NoProxy = opts.noProxy
+	}
+	if flagChanged(cmd, "immutable-tag") {
+		h.ImmutableTags = nil
+		for _, s := range opts.immutableTags {
+			if s == "" {
+				continue
+			}
+			it, err := config.ImmutableTagParse(s)
+			if err != nil {
+				return err
+			}
+			h.ImmutableTags = append(h.ImmutableTags, it)
+		}
+	}
 	if flagChanged(cmd, "blob-chunk") {
 		h.BlobChunk = opts.blobChunk
 	}
@@ -530,7 +1016,26 @@ func (opts *registryOpts) runRegistrySet(cmd *cobra.Command, args []string) erro
 	return nil
 }
 
+// whoamiFormat is the default output of the whoami command.
+const whoamiFormat = `{{ .User }}
+{{- with .Auth.Subject }}
+Subject: {{ . }}{{ end }}
+{{- range .Auth.Access }}
+Access: {{ .Type }}:{{ .Name }}:{{ join .Actions "," }}{{ end }}
+{{- if .Repository }}
+Pull: {{ .Pull }}
+Push: {{ .Push }}{{ end }}`
+
+type whoamiResult struct {
+	Registry   string
+	User       string
+	Auth       ping.Auth
+	Repository string
+	Pull, Push string // granted, denied, or unknown
+}
+
 func (opts *registryOpts) runRegistryWhoami(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	c, err := ConfigLoadDefault()
 	if err != nil {
 		return err
@@ -538,18 +1043,58 @@ func (opts *registryOpts) runRegistryWhoami(cmd *cobra.Command, args []string) e
 	if len(args) == 0 {
 		args = []string{regclient.DockerRegistry}
 	}
-	h, ok := c.Hosts[args[0]]
+	r, err := ref.NewHost(args[0])
+	if !config.HostValidate(args[0]) {
+		r, err = ref.New(args[0])
+	}
+	if err != nil {
+		return err
+	}
+	h, ok := c.Hosts[r.Registry]
 	if !ok {
-		return fmt.Errorf("no login found for %s%.0w", args[0], errs.ErrNoLogin)
+		return fmt.Errorf("no login found for %s%.0w", r.Registry, errs.ErrNoLogin)
 	}
 	cred := h.GetCred()
 	if cred.User == "" && cred.Token != "" {
 		cred.User = "<token>"
 	}
 	if cred.User == "" {
-		return fmt.Errorf("no login found for %s%.0w", args[0], errs.ErrNoLogin)
+		return fmt.Errorf("no login found for %s%.0w", r.Registry, errs.ErrNoLogin)
+	}
+	result := whoamiResult{
+		Registry:   r.Registry,
+		User:       cred.User,
+		Repository: r.Repository,
+	}
+	if !opts.skipCheck {
+		rc := opts.rootOpts.newRegClient()
+		actions := []string{}
+		if r.Repository != "" {
+			actions = []string{"pull", "push"}
+		}
+		result.Auth, err = rc.PingAuth(ctx, r, actions...)
+		if err != nil {
+			return err
+		}
+		if r.Repository != "" {
+			result.Pull = whoamiAccess(result.Auth, r.Repository, "pull")
+			result.Push = whoamiAccess(result.Auth, r.Repository, "push")
+		}
+	} else if r.Repository != "" {
+		result.Pull = "unknown"
+		result.Push = "unknown"
+	}
+	return template.Writer(cmd.OutOrStdout(), opts.format, result)
+}
+
+// whoamiAccess reports if an action is granted on a repository by the token.
+func whoamiAccess(a ping.Auth, repo, action string) string {
+	switch {
+	case a.Type != "bearer" || a.Access == nil:
+		return "unknown"
+	case a.Allowed("repository", repo, action):
+		return "granted"
+	default:
+		return "denied"
 	}
-	// output the user
-	_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s\n", cred.User)
-	return err
 }
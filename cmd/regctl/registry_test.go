@@ -1,18 +1,31 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/olareg/olareg"
 	oConfig "github.com/olareg/olareg/config"
 
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/scheme/reg"
 	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ref"
 )
 
 func TestRegistry(t *testing.T) {
@@ -46,6 +59,10 @@ func TestRegistry(t *testing.T) {
 	tsExampleHost := "registry.example.org"
 	tempDir := t.TempDir()
 	t.Setenv(ConfigEnv, filepath.Join(tempDir, "config.json"))
+	// save logins to the config file
+	keyringHelpersOrig := keyringHelpers
+	keyringHelpers = nil
+	t.Cleanup(func() { keyringHelpers = keyringHelpersOrig })
 	tt := []struct {
 		name        string
 		args        []string
@@ -76,6 +93,27 @@ func TestRegistry(t *testing.T) {
 			expectOut:   "",
 			outContains: false,
 		},
+		{
+			name:        "set tls min version",
+			args:        []string{"registry", "set", tsBadHost, "--tls-min-version", "1.2", "--tls-server-name", "registry.example.com", "--proxy", "direct", "--no-proxy", "cdn.example.com", "--skip-check"},
+			expectOut:   "",
+			outContains: false,
+		},
+		{
+			name:      "set invalid tls min version",
+			args:      []string{"registry", "set", tsBadHost, "--tls-min-version", "0.9", "--skip-check"},
+			expectErr: fmt.Errorf(`unknown TLS version "0.9"`),
+		},
+		{
+			name:      "set invalid proxy",
+			args:      []string{"registry", "set", tsBadHost, "--proxy", "ftp://proxy.example.org", "--skip-check"},
+			expectErr: fmt.Errorf("unsupported proxy scheme ftp://proxy.example.org"),
+		},
+		{
+			name:      "set missing cacert file",
+			args:      []string{"registry", "set", tsBadHost, "--cacert", filepath.Join(tempDir, "missing.pem"), "--skip-check"},
+			expectErr: fs.ErrNotExist,
+		},
 		// set and unset config on example
 		{
 			name:        "set example",
@@ -113,6 +151,18 @@ func TestRegistry(t *testing.T) {
 			expectOut:   `"tls": "disabled",`,
 			outContains: true,
 		},
+		{
+			name:        "query bad host tls min version",
+			args:        []string{"registry", "config", tsBadHost},
+			expectOut:   `"tlsMinVersion": "1.2",`,
+			outContains: true,
+		},
+		{
+			name:        "query bad host proxy",
+			args:        []string{"registry", "config", tsBadHost},
+			expectOut:   `"proxy": "direct",`,
+			outContains: true,
+		},
 		{
 			name:        "query example",
 			args:        []string{"registry", "config", tsExampleHost},
@@ -237,3 +287,493 @@ func TestRegistry(t *testing.T) {
 		})
 	}
 }
+
+func TestRegistryLoginDevice(t *testing.T) {
+	// t.Parallel() // this is not parallel due to environment variable settings
+	var tsURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_, _ = fmt.Fprintf(w, `{"device_authorization_endpoint":"%[1]s/device","token_endpoint":"%[1]s/token"}`, tsURL)
+		case "/device":
+			if r.FormValue("client_id") != "regctl" || r.FormValue("scope") != "registry" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"invalid_request"}`))
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"device_code":"device1","user_code":"ABCD-1234","verification_uri":"%s/activate","expires_in":60,"interval":1}`, tsURL)
+		case "/token":
+			switch {
+			case r.FormValue("grant_type") == deviceGrantType && r.FormValue("device_code") == "device1":
+				_, _ = w.Write([]byte(`{"access_token":"access1","refresh_token":"refresh1","expires_in":10}`))
+			case r.FormValue("grant_type") == "refresh_token" && r.FormValue("refresh_token") == "refresh1":
+				_, _ = w.Write([]byte(`{"access_token":"access2","refresh_token":"refresh2","expires_in":3600}`))
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	tsURL = ts.URL
+	t.Cleanup(ts.Close)
+	tempDir := t.TempDir()
+	t.Setenv(ConfigEnv, filepath.Join(tempDir, "config.json"))
+	keyringHelpersOrig := keyringHelpers
+	keyringHelpers = nil
+	t.Cleanup(func() { keyringHelpers = keyringHelpersOrig })
+	host := "registry.example.org"
+
+	out, err := cobraTest(t, nil, "registry", "login", host, "--device-code", "--client-id", "regctl", "--scope", "registry", "--issuer", tsURL, "--skip-check")
+	if err != nil {
+		t.Fatalf("failed to login: %v", err)
+	}
+	if !strings.Contains(out, "ABCD-1234") || !strings.Contains(out, tsURL+"/activate") {
+		t.Errorf("login instructions missing from output: %s", out)
+	}
+	c, err := ConfigLoadDefault()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if c.Hosts[host] == nil || c.Hosts[host].User != "oauth2" || c.Hosts[host].Pass != "access1" {
+		t.Fatalf("unexpected host config: %v", c.Hosts[host])
+	}
+	if c.DeviceLogins[host] == nil || c.DeviceLogins[host].RefreshToken != "refresh1" || c.DeviceLogins[host].TokenURL != tsURL+"/token" {
+		t.Fatalf("unexpected device login: %v", c.DeviceLogins[host])
+	}
+
+	out, err = cobraTest(t, nil, "config", "get", "--format", "{{ jsonPretty .DeviceLogins }}")
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+	if strings.Contains(out, "refresh1") {
+		t.Errorf("refresh token included in config output: %s", out)
+	}
+
+	// the access token expires within the margin and is refreshed
+	c.deviceRefresh(t.Context(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	c, err = ConfigLoadDefault()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if c.Hosts[host].Pass != "access2" || c.DeviceLogins[host].RefreshToken != "refresh2" {
+		t.Errorf("login was not refreshed, pass %s, refresh token %s", c.Hosts[host].Pass, c.DeviceLogins[host].RefreshToken)
+	}
+
+	_, err = cobraTest(t, nil, "registry", "logout", host)
+	if err != nil {
+		t.Fatalf("failed to logout: %v", err)
+	}
+	c, err = ConfigLoadDefault()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if _, ok := c.DeviceLogins[host]; ok {
+		t.Errorf("device login remains after logout")
+	}
+
+	_, err = cobraTest(t, nil, "registry", "login", host, "--device-code", "--skip-check")
+	if !errors.Is(err, ErrMissingInput) {
+		t.Errorf("login without a client ID did not fail, err: %v", err)
+	}
+
+	// the refresh token is stored in the keyring with the credentials
+	t.Setenv("REGCLIENT_TEST_KEYRING", t.TempDir())
+	helperDir, err := filepath.Abs("../../config/testdata")
+	if err != nil {
+		t.Fatalf("failed to find helper: %v", err)
+	}
+	t.Setenv("PATH", helperDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	keyringHelpers = []string{"docker-credential-testkeyring"}
+	_, err = cobraTest(t, nil, "registry", "login", host, "--device-code", "--client-id", "regctl", "--scope", "registry", "--issuer", tsURL, "--skip-check")
+	if err != nil {
+		t.Fatalf("failed to login: %v", err)
+	}
+	c, err = ConfigLoadDefault()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if c.Hosts[host] == nil || c.Hosts[host].Pass != "" || c.DeviceLogins[host] == nil || c.DeviceLogins[host].RefreshToken != "" {
+		t.Fatalf("credentials saved to the config: %v, %v", c.Hosts[host], c.DeviceLogins[host])
+	}
+	if tok := deviceTokenGet(c.Hosts[host]); tok != "refresh1" {
+		t.Errorf("unexpected refresh token in the keyring: %s", tok)
+	}
+	c.deviceRefresh(t.Context(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	c, err = ConfigLoadDefault()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cred := c.Hosts[host].GetCred(); cred.Password != "access2" || c.DeviceLogins[host].RefreshToken != "" {
+		t.Errorf("login was not refreshed in the keyring, pass %s, config refresh token %s", cred.Password, c.DeviceLogins[host].RefreshToken)
+	}
+	h := c.Hosts[host]
+	if tok := deviceTokenGet(h); tok != "refresh2" {
+		t.Errorf("unexpected refresh token in the keyring: %s", tok)
+	}
+	_, err = cobraTest(t, nil, "registry", "logout", host)
+	if err != nil {
+		t.Fatalf("failed to logout: %v", err)
+	}
+	if err := deviceCredHost(h).EraseCred(); err == nil {
+		t.Errorf("refresh token remains in the keyring after logout")
+	}
+}
+
+func TestRegistryKeyring(t *testing.T) {
+	// t.Parallel() // this is not parallel due to environment variable settings
+	tempDir := t.TempDir()
+	t.Setenv(ConfigEnv, filepath.Join(tempDir, "config.json"))
+	t.Setenv("REGCLIENT_TEST_KEYRING", t.TempDir())
+	helperDir, err := filepath.Abs("../../config/testdata")
+	if err != nil {
+		t.Fatalf("failed to find helper: %v", err)
+	}
+	t.Setenv("PATH", helperDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	keyringHelpersOrig := keyringHelpers
+	keyringHelpers = []string{"docker-credential-testkeyring"}
+	t.Cleanup(func() { keyringHelpers = keyringHelpersOrig })
+	host := "registry.example.org"
+
+	_, err = cobraTest(t, nil, "registry", "login", host, "-u", "keyuser", "-p", "keypass", "--skip-check")
+	if err != nil {
+		t.Fatalf("failed to login: %v", err)
+	}
+	c, err := ConfigLoadDefault()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	h := c.Hosts[host]
+	if h == nil || h.CredHelper != "docker-credential-testkeyring" || h.User != "" || h.Pass != "" {
+		t.Fatalf("credentials not moved to the keyring: %v", h)
+	}
+	if cred := h.GetCred(); cred.User != "keyuser" || cred.Password != "keypass" {
+		t.Errorf("unexpected credential from the keyring: %v", cred)
+	}
+
+	// disable the keyring and login again
+	_, err = cobraTest(t, nil, "config", "set", "--keyring=false")
+	if err != nil {
+		t.Fatalf("failed to disable keyring: %v", err)
+	}
+	_, err = cobraTest(t, nil, "registry", "login", host, "-u", "fileuser", "-p", "filepass", "--skip-check")
+	if err != nil {
+		t.Fatalf("failed to login: %v", err)
+	}
+	c, err = ConfigLoadDefault()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	h = c.Hosts[host]
+	if h == nil || h.CredHelper != "" || h.User != "fileuser" || h.Pass != "filepass" {
+		t.Fatalf("credentials not saved to the config: %v", h)
+	}
+	hKeyring := config.HostNewName(host)
+	hKeyring.CredHelper = "docker-credential-testkeyring"
+	if err := hKeyring.EraseCred(); err == nil {
+		t.Errorf("credentials remain in the keyring")
+	}
+
+	// enable the keyring and logout
+	_, err = cobraTest(t, nil, "config", "set", "--keyring")
+	if err != nil {
+		t.Fatalf("failed to enable keyring: %v", err)
+	}
+	_, err = cobraTest(t, nil, "registry", "login", host, "-u", "keyuser", "-p", "keypass", "--skip-check")
+	if err != nil {
+		t.Fatalf("failed to login: %v", err)
+	}
+	_, err = cobraTest(t, nil, "registry", "logout", host)
+	if err != nil {
+		t.Fatalf("failed to logout: %v", err)
+	}
+	if err := hKeyring.EraseCred(); err == nil {
+		t.Errorf("credentials remain in the keyring after logout")
+	}
+	c, err = ConfigLoadDefault()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if h, ok := c.Hosts[host]; ok {
+		t.Errorf("host remains after logout: %v", h)
+	}
+}
+
+func TestRegistryImport(t *testing.T) {
+	// t.Parallel() // this is not parallel due to environment variable settings
+	tempDir := t.TempDir()
+	t.Setenv(ConfigEnv, filepath.Join(tempDir, "config.json"))
+	dockerFile := filepath.Join(tempDir, "docker.json")
+	daemonFile := filepath.Join(tempDir, "daemon.json")
+	ctrDir := filepath.Join(tempDir, "certs.d")
+	files := map[string]string{
+		dockerFile: `{"auths": {"registry.example.org": {"auth": "` + base64.StdEncoding.EncodeToString([]byte("importuser:importpass")) + `"}}}`,
+		daemonFile: `{"insecure-registries": ["localhost:5000"]}`,
+		filepath.Join(ctrDir, "docker.io", "hosts.toml"): `
+server = "https://registry-1.docker.io"
+[host."https://hub-mirror.example.org/v2/hub"]
+  capabilities = ["pull", "resolve"]
+`,
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := cobraTest(t, nil, "registry", "import")
+	if !errors.Is(err, ErrMissingInput) {
+		t.Errorf("import without a source did not fail: %v", err)
+	}
+	out, err := cobraTest(t, nil, "registry", "import", "--docker", dockerFile, "--dry-run", "--format", "{{ range $k, $v := . }}{{ $k }}={{ $v.User }}:{{ $v.Pass }}{{ end }}")
+	if err != nil {
+		t.Fatalf("failed to run dry run: %v", err)
+	}
+	if out != "registry.example.org=importuser:" {
+		t.Errorf("unexpected dry run output: %s", out)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "config.json")); err == nil {
+		t.Errorf("config saved on a dry run")
+	}
+
+	_, err = cobraTest(t, nil, "registry", "import", "--docker", dockerFile, "--docker-daemon", daemonFile, "--containerd", ctrDir)
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	c, err := ConfigLoadDefault()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if h := c.Hosts["registry.example.org"]; h == nil || h.User != "importuser" || h.Pass != "importpass" {
+		t.Errorf("login not imported: %v", h)
+	}
+	if h := c.Hosts["localhost:5000"]; h == nil || h.TLS != config.TLSDisabled {
+		t.Errorf("insecure registry not imported: %v", h)
+	}
+	if h := c.Hosts["docker.io"]; h == nil || len(h.Mirrors) != 1 || h.Mirrors[0] != "hub-mirror.example.org" {
+		t.Errorf("mirror not imported: %v", h)
+	}
+	if h := c.Hosts["hub-mirror.example.org"]; h == nil || h.BasePath != "v2/hub" {
+		t.Errorf("mirror settings not imported: %v", h)
+	}
+}
+
+func TestRegistryWhoami(t *testing.T) {
+	// t.Parallel() // this is not parallel due to environment variable settings
+	var tsURL string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			user, pass, ok := r.BasicAuth()
+			if !ok || user != "testuser" || pass != "testpass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			access := []map[string]any{}
+			for _, scope := range r.URL.Query()["scope"] {
+				parts := strings.Split(scope, ":")
+				if len(parts) != 3 {
+					continue
+				}
+				// only the rw repository allows pushes
+				actions := []string{"pull"}
+				if parts[1] == "rw" {
+					actions = append(actions, "push")
+				}
+				access = append(access, map[string]any{"type": parts[0], "name": parts[1], "actions": actions})
+			}
+			claims, _ := json.Marshal(map[string]any{"sub": user, "access": access})
+			token := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+				base64.RawURLEncoding.EncodeToString(claims) + ".sig"
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{"token": token, "expires_in": 300})
+		case "/v2/":
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+tsURL+`/token",service="test"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte("{}"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	tsURL = ts.URL
+	t.Cleanup(ts.Close)
+	tsParsed, _ := url.Parse(ts.URL)
+	tsHost := tsParsed.Host
+	tempDir := t.TempDir()
+	t.Setenv(ConfigEnv, filepath.Join(tempDir, "config.json"))
+	keyringHelpersOrig := keyringHelpers
+	keyringHelpers = nil
+	t.Cleanup(func() { keyringHelpers = keyringHelpersOrig })
+	_, err := cobraTest(t, nil, "registry", "set", tsHost, "--tls", "disabled", "--skip-check")
+	if err != nil {
+		t.Fatalf("failed to configure registry: %v", err)
+	}
+	_, err = cobraTest(t, nil, "registry", "login", tsHost, "-u", "testuser", "-p", "testpass", "--skip-check")
+	if err != nil {
+		t.Fatalf("failed to login: %v", err)
+	}
+
+	tt := []struct {
+		name      string
+		args      []string
+		expectOut string
+		expectErr error
+	}{
+		{
+			name:      "registry",
+			args:      []string{"registry", "whoami", tsHost},
+			expectOut: "testuser\nSubject: testuser",
+		},
+		{
+			name:      "read only repo",
+			args:      []string{"registry", "whoami", tsHost + "/ro"},
+			expectOut: "testuser\nSubject: testuser\nAccess: repository:ro:pull\nPull: granted\nPush: denied",
+		},
+		{
+			name:      "read write repo",
+			args:      []string{"registry", "whoami", tsHost + "/rw", "--format", "{{ .Pull }} {{ .Push }}"},
+			expectOut: "granted granted",
+		},
+		{
+			name:      "skip check",
+			args:      []string{"registry", "whoami", tsHost + "/rw", "--skip-check"},
+			expectOut: "testuser\nPull: unknown\nPush: unknown",
+		},
+		{
+			name:      "no login",
+			args:      []string{"registry", "whoami", "registry.example.org/repo"},
+			expectErr: errs.ErrNoLogin,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := cobraTest(t, nil, tc.args...)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("returned unexpected error: %v", err)
+			}
+			if out != tc.expectOut {
+				t.Errorf("unexpected output, expected:\n%s\nreceived:\n%s", tc.expectOut, out)
+			}
+		})
+	}
+}
+
+func TestRegistryDedupReport(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "../../testdata",
+		},
+	})
+	// olareg does not implement the _catalog API
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/_catalog" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"repositories":["team/app-a","team/app-b","testrepo"]}`))
+			return
+		}
+		regHandler.ServeHTTP(w, r)
+	}))
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+	rcOpts := []regclient.Opt{
+		regclient.WithConfigHost(config.Host{
+			Name: tsHost,
+			TLS:  config.TLSDisabled,
+		}),
+		regclient.WithRegOpts(reg.WithDelay(time.Millisecond*10, time.Millisecond*100)),
+	}
+	rc := regclient.New(rcOpts...)
+	rSrc, err := ref.New(tsHost + "/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	for _, repo := range []string{"team/app-a", "team/app-b"} {
+		rTgt, err := ref.New(tsHost + "/" + repo + ":v1")
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		err = rc.ImageCopy(ctx, rSrc, rTgt)
+		if err != nil {
+			t.Fatalf("failed to copy image: %v", err)
+		}
+	}
+	tt := []struct {
+		name        string
+		args        []string
+		expectErr   error
+		expectOut   string
+		outContains bool
+	}{
+		{
+			name:        "namespace",
+			args:        []string{"registry", "dedup-report", tsHost + "/team"},
+			expectOut:   tsHost + "/team/app-a, " + tsHost + "/team/app-b",
+			outContains: true,
+		},
+		{
+			name:      "format",
+			args:      []string{"registry", "dedup-report", "--format", "{{ len .Repos }} {{ gt .Size .Dedup }}", tsHost + "/team"},
+			expectOut: "2 true",
+		},
+		{
+			name:      "exclude",
+			args:      []string{"registry", "dedup-report", "--exclude", "team/app-b", "--format", "{{ len .Repos }} {{ len .Blobs }}", tsHost + "/team"},
+			expectOut: "1 0",
+		},
+		{
+			name:      "include",
+			args:      []string{"registry", "dedup-report", "--include", "team/.*", "--format", "{{ len .Repos }}", tsHost},
+			expectOut: "2",
+		},
+		{
+			name:      "invalid filter",
+			args:      []string{"registry", "dedup-report", "--include", "[", tsHost},
+			expectErr: ErrInvalidInput,
+		},
+		{
+			name:      "no repositories",
+			args:      []string{"registry", "dedup-report", tsHost + "/missing"},
+			expectErr: errs.ErrNotFound,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := cobraTest(t, &cobraTestOpts{rcOpts: rcOpts}, tc.args...)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Errorf("did not receive expected error: %v", tc.expectErr)
+				} else if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
+					t.Errorf("unexpected error, received %v, expected %v", err, tc.expectErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("returned unexpected error: %v", err)
+			}
+			if (!tc.outContains && out != tc.expectOut) || (tc.outContains && !strings.Contains(out, tc.expectOut)) {
+				t.Errorf("unexpected output, expected %s, received %s", tc.expectOut, out)
+			}
+		})
+	}
+}
@@ -1,31 +1,29 @@
 package main
 
 import (
-	"context"
-	"errors"
 	"fmt"
 	"log/slog"
-	"regexp"
-	"slices"
 	"strings"
-	"sync"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
 	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/internal/units"
 	"github.com/regclient/regclient/pkg/template"
 	"github.com/regclient/regclient/scheme"
-	"github.com/regclient/regclient/types/errs"
 	"github.com/regclient/regclient/types/ref"
 )
 
 type repoOpts struct {
 	rootOpts   *rootOpts
 	concurrent int
+	digestTags bool
 	exclude    []string
 	format     string
 	include    []string
 	last       string
+	layers     int
 	limit      int
 	newTags    bool
 	referrers  bool
@@ -38,6 +36,7 @@ func NewRepoCmd(rOpts *rootOpts) *cobra.Command {
 	}
 	cmd.AddCommand(newRepoCopyCmd(rOpts))
 	cmd.AddCommand(newRepoLsCmd(rOpts))
+	cmd.AddCommand(newRepoUsageCmd(rOpts))
 	return cmd
 }
 
@@ -59,12 +58,17 @@ regctl repo copy registry-a.example.org/repo registry-b.example.org/repo
 
 # copy all tags beginning with v1.2
 regctl repo copy --include 'v1\\.2.*' registry-a.example.org/repo registry-b.example.org/repo
+
+# copy release tags with their referrers, skipping tags already in the destination
+regctl repo copy --include 'v[0-9].*' --referrers --new-tags \
+  registry-a.example.org/repo registry-b.example.org/repo
 		`,
 		Args:              cobra.ExactArgs(2),
 		ValidArgsFunction: completeArgNone,
 		RunE:              opts.runRepoCopy,
 	}
 	cmd.Flags().IntVar(&opts.concurrent, "concurrent", 2, "Number of concurrent images to copy")
+	cmd.Flags().BoolVar(&opts.digestTags, "digest-tags", false, "Include digest tags (\"sha256-<digest>.*\") with each image")
 	cmd.Flags().StringArrayVar(&opts.exclude, "exclude", []string{}, "Exclude tags by regexp")
 	_ = cmd.RegisterFlagCompletionFunc("exclude", completeArgNone)
 	cmd.Flags().StringArrayVar(&opts.include, "include", []string{}, "Include tags by regexp")
@@ -103,26 +107,42 @@ regctl repo ls --last repo1 --limit 5 registry.example.org`,
 	return cmd
 }
 
-func (opts *repoOpts) runRepoCopy(cmd *cobra.Command, args []string) error {
-	var err error
-	ctx, cancel := context.WithCancel(cmd.Context())
-	defer cancel()
-	// compile regexp include/exclude list
-	incRe := make([]*regexp.Regexp, len(opts.include))
-	for i, str := range opts.include {
-		incRe[i], err = regexp.Compile("^" + str + "$")
-		if err != nil {
-			return fmt.Errorf("failed to parse include regexp: %q, %w", str, err)
-		}
+func newRepoUsageCmd(rOpts *rootOpts) *cobra.Command {
+	opts := repoOpts{
+		rootOpts: rOpts,
 	}
-	excRe := make([]*regexp.Regexp, len(opts.exclude))
-	for i, str := range opts.exclude {
-		excRe[i], err = regexp.Compile("^" + str + "$")
-		if err != nil {
-			return fmt.Errorf("failed to parse exclude regexp: %q, %w", str, err)
-		}
+	cmd := &cobra.Command{
+		Use:   "usage <repository>",
+		Short: "show storage used by a repository",
+		Long: `Show the storage used by a repository.
+Every tag is walked to find the referenced manifests, configs, and layers.
+The total size counts content shared between tags once.
+The unique size of a tag is the content not shared with any other tag, which is
+the storage freed by deleting that tag after garbage collection.
+Untagged manifests and external layers are not included.`,
+		Example: `
+# show the storage used by a repository
+regctl repo usage registry.example.org/repo
+
+# show the 20 largest layers
+regctl repo usage --layers 20 registry.example.org/repo
+
+# output the total size in bytes
+regctl repo usage --format '{{ .Size }}' registry.example.org/repo`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeArgNone,
+		RunE:              opts.runRepoUsage,
 	}
-	// list all tags in source repo
+	cmd.Flags().IntVar(&opts.concurrent, "concurrent", 4, "Number of concurrent tags to walk")
+	cmd.Flags().StringVarP(&opts.format, "format", "", "", "Format output with go template syntax")
+	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	cmd.Flags().IntVar(&opts.layers, "layers", 10, "Number of the largest layers to show, -1 for all layers")
+	_ = cmd.RegisterFlagCompletionFunc("layers", completeArgNone)
+	return cmd
+}
+
+func (opts *repoOpts) runRepoCopy(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	srcRef, err := ref.New(args[0])
 	if err != nil {
 		return err
@@ -132,98 +152,23 @@ func (opts *repoOpts) runRepoCopy(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	rc := opts.rootOpts.newRegClient()
-	tagList, err := rc.TagList(ctx, srcRef)
-	if err != nil {
-		return fmt.Errorf("failed to list tags in %s: %w", args[0], err)
+	rcOpts := []regclient.RepoCopyOpts{
+		regclient.RepoCopyWithParallel(opts.concurrent),
+		regclient.RepoCopyWithStopOnError(),
+		regclient.RepoCopyWithTagAllow(opts.include...),
+		regclient.RepoCopyWithTagDeny(opts.exclude...),
 	}
-	// filter include/exclude regexp
-	tags := []string{}
-	for _, tag := range tagList.Tags {
-		if len(incRe) > 0 {
-			included := false
-			for _, re := range incRe {
-				if re.MatchString(tag) {
-					included = true
-					break
-				}
-			}
-			if !included {
-				continue
-			}
-		}
-		excluded := false
-		for _, re := range excRe {
-			if re.MatchString(tag) {
-				excluded = true
-				break
-			}
-		}
-		if excluded {
-			continue
-		}
-		tags = append(tags, tag)
-	}
-	// if "new-tags", list all tags in target repo and filter out all existing tags
 	if opts.newTags {
-		tgtTags, err := rc.TagList(ctx, tgtRef)
-		if err != nil && !errors.Is(err, errs.ErrNotFound) {
-			return fmt.Errorf("failed to list tags in %s: %w", args[1], err)
-		}
-		if tgtTags != nil && len(tgtTags.Tags) > 0 {
-			newTags := []string{}
-			for _, tag := range tags {
-				if !slices.Contains(tgtTags.Tags, tag) {
-					newTags = append(newTags, tag)
-				}
-			}
-			tags = newTags
-		}
-	}
-	// use a channel to throttle requests
-	if opts.concurrent <= 0 {
-		// no throttle
-		opts.concurrent = len(tags)
+		rcOpts = append(rcOpts, regclient.RepoCopyWithSkipExisting())
 	}
-	throttle := make(chan struct{}, opts.concurrent)
-	errList := []error{}
-	mu := sync.Mutex{}
-	// iterate over each tag, running image copy in goroutine
-	rcOpts := []regclient.ImageOpts{}
 	if opts.referrers {
-		rcOpts = append(rcOpts, regclient.ImageWithReferrers())
-	}
-	for _, tag := range tags {
-		mu.Lock()
-		foundErr := len(errList) > 0
-		mu.Unlock()
-		if foundErr {
-			break
-		}
-		srcTag := srcRef.SetTag(tag)
-		tgtTag := tgtRef.SetTag(tag)
-		throttle <- struct{}{}
-		go func() {
-			err := rc.ImageCopy(ctx, srcTag, tgtTag, rcOpts...)
-			if err != nil {
-				mu.Lock()
-				if !errors.Is(err, context.Canceled) || len(errList) == 0 {
-					errList = append(errList, err)
-					// cancel other copies on any errors
-					cancel()
-				}
-				mu.Unlock()
-			}
-			<-throttle
-		}()
-	}
-	// wait for all copies to finish
-	for range opts.concurrent {
-		throttle <- struct{}{}
+		rcOpts = append(rcOpts, regclient.RepoCopyWithReferrers())
 	}
-	if len(errList) == 1 {
-		return errList[0]
+	if opts.digestTags {
+		rcOpts = append(rcOpts, regclient.RepoCopyWithImageOpts(regclient.ImageWithDigestTags()))
 	}
-	return errors.Join(errList...)
+	_, err = rc.RepoCopy(ctx, srcRef, tgtRef, rcOpts...)
+	return err
 	// TODO: include tty progress
 }
 
@@ -263,3 +208,56 @@ func (opts *repoOpts) runRepoLs(cmd *cobra.Command, args []string) error {
 	}
 	return template.Writer(cmd.OutOrStdout(), opts.format, rl)
 }
+
+func (opts *repoOpts) runRepoUsage(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	r, err := ref.New(args[0])
+	if err != nil {
+		return err
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, r)
+	opts.rootOpts.log.Debug("Repository usage",
+		slog.String("repo", r.CommonName()),
+		slog.Int("concurrent", opts.concurrent))
+	report, err := rc.RepoUsage(ctx, r,
+		regclient.RepoUsageWithLayerLimit(opts.layers),
+		regclient.RepoUsageWithParallel(opts.concurrent))
+	if err != nil && len(report.Tags) == 0 {
+		return err
+	}
+	if opts.format != "" {
+		if tErr := template.Writer(cmd.OutOrStdout(), opts.format, report); tErr != nil {
+			return tErr
+		}
+		return err
+	}
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Repository: %s\n", report.Repo)
+	fmt.Fprintf(out, "Size:       %s (%d manifests, %d blobs)\n", units.HumanSize(float64(report.Size)), report.Manifests, report.Blobs)
+	fmt.Fprintf(out, "\n")
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "TAG\tDIGEST\tSIZE\tUNIQUE\n")
+	for _, tag := range report.Tags {
+		if tag.Err != nil {
+			fmt.Fprintf(tw, "%s\terror: %s\t\t\n", tag.Tag, tag.Err.Error())
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", tag.Tag, tag.Digest.String(), units.HumanSize(float64(tag.Size)), units.HumanSize(float64(tag.Unique)))
+	}
+	if tErr := tw.Flush(); tErr != nil {
+		return tErr
+	}
+	if len(report.Layers) > 0 {
+		fmt.Fprintf(out, "\n")
+		tw = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "LAYER\tSIZE\tTAGS\n")
+		for _, layer := range report.Layers {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", layer.Digest.String(), units.HumanSize(float64(layer.Size)), strings.Join(layer.Tags, ", "))
+		}
+		if tErr := tw.Flush(); tErr != nil {
+			return tErr
+		}
+	}
+	return err
+}
@@ -23,6 +23,7 @@ import (
 func TestRepoCopy(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
+	tempDir := t.TempDir()
 	regHandler := olareg.New(oConfig.Config{
 		Storage: oConfig.ConfigStorage{
 			StoreType: oConfig.StoreMem,
@@ -108,6 +109,24 @@ func TestRepoCopy(t *testing.T) {
 			expectNoTag:     []string{"a1", "a2", "a3", "ai", "child", "loop", "mirror"},
 			expectReferrers: []string{"v2"},
 		},
+		{
+			name:        "Copy testrepo to digest-tags with digest tags",
+			args:        []string{"repo", "copy", "--include", "v2", "--digest-tags", "ocidir://../../testdata/testrepo", "ocidir://" + tempDir + "/digest-tags"},
+			expectRepo:  "ocidir://" + tempDir + "/digest-tags",
+			expectTags:  []string{"v2", "sha256-dfae8f425735a5e3a72e40d6609e03079995511d48157c74d54801ff4430491e"},
+			expectNoTag: []string{"v1", "v3"},
+		},
+		{
+			name:       "Copy testrepo to vx with only new tags",
+			args:       []string{"repo", "copy", "--new-tags", tsHost + "/testrepo", tsHost + "/vx"},
+			expectRepo: tsHost + "/vx",
+			expectTags: []string{"a1", "child", "v1", "v2", "v3"},
+		},
+		{
+			name:      "Invalid include",
+			args:      []string{"repo", "copy", "--include", "v[", tsHost + "/testrepo", tsHost + "/invalid"},
+			expectErr: fmt.Errorf(`invalid tag filter "v[": error parsing regexp: missing closing ]: ` + "`[$`"),
+		},
 		{
 			name:       "Copy testrepo to concurrent without throttle",
 			args:       []string{"repo", "copy", "--concurrent", "-1", tsHost + "/testrepo", tsHost + "/concurrent"},
@@ -176,3 +195,23 @@ func TestRepoCopy(t *testing.T) {
 		})
 	}
 }
+
+func TestRepoUsage(t *testing.T) {
+	repo := "ocidir://../../testdata/testrepo"
+	out, err := cobraTest(t, nil, "repo", "usage", "--layers", "2", repo)
+	if err != nil {
+		t.Fatalf("failed to run repo usage: %v", err)
+	}
+	for _, expect := range []string{"Repository: " + repo, "TAG", "v3", "LAYER"} {
+		if !strings.Contains(out, expect) {
+			t.Errorf("output missing %s: %s", expect, out)
+		}
+	}
+	out, err = cobraTest(t, nil, "repo", "usage", "--format", "{{ len .Layers }} {{ gt .Size 0 }}", "--layers", "1", repo)
+	if err != nil {
+		t.Fatalf("failed to run repo usage: %v", err)
+	}
+	if out != "1 true" {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
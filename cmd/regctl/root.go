@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -11,7 +17,10 @@ import (
 	"github.com/regclient/regclient"
 	"github.com/regclient/regclient/config"
 	"github.com/regclient/regclient/internal/cobradoc"
+	"github.com/regclient/regclient/internal/httptrace"
+	"github.com/regclient/regclient/internal/jsonquery"
 	"github.com/regclient/regclient/internal/strparse"
+	"github.com/regclient/regclient/internal/units"
 	"github.com/regclient/regclient/internal/version"
 	"github.com/regclient/regclient/pkg/template"
 	"github.com/regclient/regclient/scheme/reg"
@@ -25,13 +34,18 @@ const (
 )
 
 type rootOpts struct {
-	hosts     []string
-	name      string
-	logopts   []string
-	log       *slog.Logger
-	rcOpts    []regclient.Opt
-	userAgent string
-	verbosity string
+	hosts      []string
+	name       string
+	logopts    []string
+	log        *slog.Logger
+	logHTTP    string // file to write http request logs
+	logHTTPMax string // largest http body to include in the http request logs
+	outputFile string
+	outputTmp  *os.File
+	pinFile    string
+	rcOpts     []regclient.Opt
+	userAgent  string
+	verbosity  string
 }
 
 type versionOpts struct {
@@ -45,7 +59,17 @@ func NewRootCmd() (*cobra.Command, *rootOpts) {
 		Use:   "regctl <cmd>",
 		Short: "Utility for accessing docker registries",
 		Long: `Utility for accessing docker registries
-More details at <https://regclient.org>`,
+More details at <https://regclient.org>
+
+Failures return an exit code based on the cause:
+  1: generic failure
+  3: not found
+  4: repository not found (NAME_UNKNOWN)
+  5: manifest not found (MANIFEST_UNKNOWN)
+  6: blob not found (BLOB_UNKNOWN)
+  7: authentication or authorization failed (UNAUTHORIZED, DENIED)
+  8: rate limit exceeded (TOOMANYREQUESTS)
+Some commands define additional exit codes.`,
 		Example: `
 # login to ghcr.io
 regctl registry login ghcr.io
@@ -62,8 +86,18 @@ regctl tag ls ghcr.io/regclient/regctl -v debug
 # format log output in json
 regctl image ratelimit --logopt json alpine
 
+# log http requests and small response bodies to a file
+regctl manifest head --log-http http.log --log-http-body 16KiB alpine
+
 # override registry config for a single command
-regctl image digest --host reg=localhost:5000,tls=disabled localhost:5000/repo:v1`,
+regctl image digest --host reg=localhost:5000,tls=disabled localhost:5000/repo:v1
+
+# fail when a tag does not match the digest in a pin file
+# each line of pins.txt has a reference and digest, e.g. "alpine:3 sha256:..."
+regctl image copy --pin-file pins.txt alpine:3 registry.example.org/library/alpine:3
+
+# save a manifest, leaving any existing file unchanged if the command fails
+regctl manifest get --output-file manifest.json --format raw-body ghcr.io/regclient/regctl:latest`,
 		SilenceUsage:  true,
 		SilenceErrors: true,
 	}
@@ -76,8 +110,13 @@ regctl image digest --host reg=localhost:5000,tls=disabled localhost:5000/repo:v
 	})
 	cmd.PersistentFlags().StringArrayVar(&rOpts.logopts, "logopt", []string{}, "Log options")
 	_ = cmd.RegisterFlagCompletionFunc("logopt", completeArgNone)
+	cmd.PersistentFlags().StringVar(&rOpts.logHTTP, "log-http", "", "Append a log of every http request to a file, with credentials redacted")
+	cmd.PersistentFlags().StringVar(&rOpts.logHTTPMax, "log-http-body", "", "Include http bodies up to this size in the http request log (e.g. 4KiB)")
+	_ = cmd.RegisterFlagCompletionFunc("log-http-body", completeArgNone)
 	cmd.PersistentFlags().StringArrayVar(&rOpts.hosts, "host", []string{}, "Registry hosts to add (reg=registry,user=username,pass=password,tls=enabled)")
 	_ = cmd.RegisterFlagCompletionFunc("host", completeArgNone)
+	cmd.PersistentFlags().StringVarP(&rOpts.outputFile, "output-file", "", "", "Write output to a file, replaced only after the command succeeds")
+	cmd.PersistentFlags().StringVar(&rOpts.pinFile, "pin-file", "", "File of tags pinned to a digest, requests resolving a pinned tag to another digest fail")
 	cmd.PersistentFlags().StringVarP(&rOpts.userAgent, "user-agent", "", "", "Override user agent")
 	_ = cmd.RegisterFlagCompletionFunc("user-agent", completeArgNone)
 
@@ -86,6 +125,7 @@ regctl image digest --host reg=localhost:5000,tls=disabled localhost:5000/repo:v
 	cmd.AddCommand(
 		NewArtifactCmd(rOpts),
 		NewBlobCmd(rOpts),
+		NewBrowseCmd(rOpts),
 		NewConfigCmd(rOpts),
 		NewDigestCmd(rOpts),
 		NewImageCmd(rOpts),
@@ -144,9 +184,79 @@ func (opts *rootOpts) rootPreRun(cmd *cobra.Command, args []string) error {
 	} else {
 		opts.log = slog.New(slog.NewTextHandler(cmd.ErrOrStderr(), &slog.HandlerOptions{Level: lvl}))
 	}
+	if opts.logHTTP != "" {
+		mw, err := httpTrace(opts.logHTTP, opts.logHTTPMax)
+		if err != nil {
+			return err
+		}
+		opts.rcOpts = append(opts.rcOpts, regclient.WithHTTPMiddleware(mw))
+	} else if opts.logHTTPMax != "" {
+		return fmt.Errorf("--log-http-body requires --log-http%.0w", ErrInvalidInput)
+	}
+	if opts.pinFile != "" {
+		pins, err := config.PinsLoadFile(opts.pinFile)
+		if err != nil {
+			return err
+		}
+		opts.rcOpts = append(opts.rcOpts, regclient.WithPins(pins))
+	}
+	if opts.outputFile != "" && opts.outputTmp == nil {
+		// output is written to a temp file in the same directory, and renamed by outputDone
+		tmp, err := os.CreateTemp(filepath.Dir(opts.outputFile), "."+filepath.Base(opts.outputFile)+".*")
+		if err != nil {
+			return fmt.Errorf("failed to create output file %s: %w", opts.outputFile, err)
+		}
+		opts.outputTmp = tmp
+		cmd.SetOut(tmp)
+	}
 	return nil
 }
 
+// outputDone finishes writing the output file after the command completes with err.
+// On success, the temp file replaces the output file. On failure, the temp file is removed and the output file is unchanged.
+func (opts *rootOpts) outputDone(err error) error {
+	if opts.outputTmp == nil {
+		return err
+	}
+	tmp := opts.outputTmp
+	opts.outputTmp = nil
+	tmpName := tmp.Name()
+	errC := tmp.Close()
+	if err == nil && errC != nil {
+		err = fmt.Errorf("failed to close output file %s: %w", opts.outputFile, errC)
+	}
+	if err == nil {
+		// preserve the mode of an existing file
+		mode := os.FileMode(0o644)
+		if stat, errS := os.Stat(opts.outputFile); errS == nil && stat.Mode().IsRegular() {
+			mode = stat.Mode()
+		}
+		//#nosec G703 output location is user controlled
+		if errM := os.Chmod(tmpName, mode); errM != nil {
+			err = fmt.Errorf("failed to set mode on output file %s: %w", opts.outputFile, errM)
+		} else if errR := os.Rename(tmpName, opts.outputFile); errR != nil {
+			err = fmt.Errorf("failed to write output file %s: %w", opts.outputFile, errR)
+		}
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+	}
+	return err
+}
+
+// httpTrace returns middleware that logs http requests to a file.
+func httpTrace(filename, bodyMax string) (func(http.RoundTripper) http.RoundTripper, error) {
+	traceOpts := []httptrace.Opt{}
+	if bodyMax != "" {
+		limit, err := units.ParseSize(bodyMax)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse http body size: %w%.0w", err, ErrInvalidInput)
+		}
+		traceOpts = append(traceOpts, httptrace.WithBodyLimit(limit))
+	}
+	return httptrace.NewFile(filename, traceOpts...)
+}
+
 func (opts *rootOpts) newRegClient() *regclient.RegClient {
 	conf, err := ConfigLoadDefault()
 	if err != nil {
@@ -156,6 +266,9 @@ func (opts *rootOpts) newRegClient() *regclient.RegClient {
 			conf = ConfigNew()
 		}
 	}
+	if len(conf.DeviceLogins) > 0 {
+		conf.deviceRefresh(context.Background(), opts.log)
+	}
 
 	rcOpts := []regclient.Opt{
 		regclient.WithSlog(opts.log),
@@ -237,3 +350,31 @@ func flagChanged(cmd *cobra.Command, name string) bool {
 	}
 	return flag.Changed
 }
+
+// queryWriter outputs the values selected from the raw json with a jq or JSONPath style query.
+// Strings are output without quotes, and other values are output as indented json, one per line.
+func queryWriter(w io.Writer, query string, raw []byte) error {
+	q, err := jsonquery.Parse(query)
+	if err != nil {
+		return err
+	}
+	results, err := q.EvalJSON(raw)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if s, ok := result.(string); ok {
+			_, err = fmt.Fprintln(w, s)
+		} else {
+			var b []byte
+			b, err = json.MarshalIndent(result, "", "  ")
+			if err == nil {
+				_, err = fmt.Fprintf(w, "%s\n", b)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
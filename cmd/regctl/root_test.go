@@ -1,7 +1,17 @@
 package main
 
 import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/regclient/regclient/types/errs"
 )
 
 func TestRootConfigDir(t *testing.T) {
@@ -18,3 +28,171 @@ func TestRootConfigDir(t *testing.T) {
 		t.Errorf("missing output")
 	}
 }
+
+func TestRootOutputFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	outFile := filepath.Join(tmpDir, "out.txt")
+
+	t.Run("success", func(t *testing.T) {
+		out, err := cobraTest(t, nil, "tag", "ls", "--output-file", outFile, "ocidir://../../testdata/testrepo")
+		if err != nil {
+			t.Fatalf("failed to list tags: %v", err)
+		}
+		if out != "" {
+			t.Errorf("unexpected output to stdout: %s", out)
+		}
+		b, err := os.ReadFile(outFile)
+		if err != nil {
+			t.Fatalf("failed to read output file: %v", err)
+		}
+		if !strings.Contains(string(b), "v1") {
+			t.Errorf("output file missing tags: %s", string(b))
+		}
+	})
+	t.Run("failure", func(t *testing.T) {
+		_, err := cobraTest(t, nil, "manifest", "get", "--output-file", outFile, "ocidir://../../testdata/testrepo:missing")
+		if err == nil {
+			t.Fatalf("manifest get did not fail")
+		}
+		b, err := os.ReadFile(outFile)
+		if err != nil {
+			t.Fatalf("failed to read output file: %v", err)
+		}
+		if !strings.Contains(string(b), "v1") {
+			t.Errorf("output file was modified: %s", string(b))
+		}
+		entries, err := os.ReadDir(tmpDir)
+		if err != nil {
+			t.Fatalf("failed to read dir: %v", err)
+		}
+		if len(entries) != 1 {
+			t.Errorf("temp file was not removed, found %d entries", len(entries))
+		}
+	})
+	t.Run("missing dir", func(t *testing.T) {
+		_, err := cobraTest(t, nil, "tag", "ls", "--output-file", filepath.Join(tmpDir, "missing", "out.txt"), "ocidir://../../testdata/testrepo")
+		if err == nil {
+			t.Fatalf("output to a missing directory did not fail")
+		}
+	})
+}
+
+func TestRootLogHTTP(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Request-Id", "req-123")
+		switch r.URL.Path {
+		case "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case "/v2/repo/tags/list":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name":"repo","tags":["v1","v2"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	hostArg := "reg=" + tsHost + ",tls=disabled,user=testuser,pass=testpass"
+	logFile := filepath.Join(t.TempDir(), "http.log")
+
+	out, err := cobraTest(t, nil, "tag", "ls", "--host", hostArg, "--log-http", logFile, "--log-http-body", "1KiB", tsHost+"/repo")
+	if err != nil {
+		t.Fatalf("failed to list tags: %v", err)
+	}
+	if out != "v1\nv2" {
+		t.Errorf("unexpected output: %s", out)
+	}
+	b, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("failed to read http log: %v", err)
+	}
+	for _, expect := range []string{`"url":"http://` + tsHost + `/v2/repo/tags/list"`, `"status":200`, `"requestID":"req-123"`, `"respBody":"{\"name\":\"repo\",\"tags\":[\"v1\",\"v2\"]}"`} {
+		if !strings.Contains(string(b), expect) {
+			t.Errorf("http log missing %s: %s", expect, string(b))
+		}
+	}
+	if strings.Contains(string(b), "testpass") {
+		t.Errorf("http log contains a password: %s", string(b))
+	}
+
+	_, err = cobraTest(t, nil, "tag", "ls", "--log-http-body", "1KiB", "ocidir://../../testdata/testrepo")
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("body size without a log file did not fail: %v", err)
+	}
+}
+
+func TestRootPinFile(t *testing.T) {
+	t.Parallel()
+	tmpDir := t.TempDir()
+	d1, err := cobraTest(t, nil, "image", "digest", "ocidir://../../testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to get digest: %v", err)
+	}
+	pinFile := filepath.Join(tmpDir, "pins.txt")
+	pins := "# pinned tags\n" +
+		"ocidir://../../testdata/testrepo:v1 " + d1 + "\n" +
+		"ocidir://../../testdata/testrepo:v2@" + d1 + "\n"
+	if err := os.WriteFile(pinFile, []byte(pins), 0o600); err != nil {
+		t.Fatalf("failed to write pin file: %v", err)
+	}
+	invalidFile := filepath.Join(tmpDir, "invalid.txt")
+	if err := os.WriteFile(invalidFile, []byte("ocidir://../../testdata/testrepo:v1\n"), 0o600); err != nil {
+		t.Fatalf("failed to write pin file: %v", err)
+	}
+	tt := []struct {
+		name      string
+		args      []string
+		expectErr error
+		expectOut string
+	}{
+		{
+			name:      "match",
+			args:      []string{"image", "digest", "--pin-file", pinFile, "ocidir://../../testdata/testrepo:v1"},
+			expectOut: d1,
+		},
+		{
+			name:      "unpinned",
+			args:      []string{"manifest", "head", "--pin-file", pinFile, "ocidir://../../testdata/testrepo:v3"},
+			expectOut: "sha256:",
+		},
+		{
+			name:      "mismatch",
+			args:      []string{"image", "digest", "--pin-file", pinFile, "ocidir://../../testdata/testrepo:v2"},
+			expectErr: errs.ErrPinMismatch,
+		},
+		{
+			name:      "copy mismatch",
+			args:      []string{"image", "copy", "--pin-file", pinFile, "ocidir://../../testdata/testrepo:v2", "ocidir://" + tmpDir + "/repo:v2"},
+			expectErr: errs.ErrPinMismatch,
+		},
+		{
+			name:      "invalid file",
+			args:      []string{"image", "digest", "--pin-file", invalidFile, "ocidir://../../testdata/testrepo:v1"},
+			expectErr: errs.ErrMissingDigest,
+		},
+		{
+			name:      "missing file",
+			args:      []string{"image", "digest", "--pin-file", filepath.Join(tmpDir, "missing.txt"), "ocidir://../../testdata/testrepo:v1"},
+			expectErr: fs.ErrNotExist,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := cobraTest(t, nil, tc.args...)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("expected error %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("returned unexpected error: %v", err)
+			}
+			if !strings.HasPrefix(out, tc.expectOut) {
+				t.Errorf("unexpected output, expected prefix %s, received %s", tc.expectOut, out)
+			}
+		})
+	}
+}
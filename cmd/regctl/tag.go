@@ -1,13 +1,21 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"regexp"
+	"slices"
+	"strings"
 
+	"github.com/opencontainers/go-digest"
 	"github.com/spf13/cobra"
 
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/internal/ascii"
 	"github.com/regclient/regclient/pkg/template"
 	"github.com/regclient/regclient/scheme"
 	"github.com/regclient/regclient/types/errs"
@@ -20,8 +28,19 @@ type tagOpts struct {
 	last          string
 	include       []string
 	exclude       []string
+	filter        []string
+	force         bool
 	format        string
+	dryRun        bool
+	from          string
+	ifUnchanged   string
 	ignoreMissing bool
+	noFallback    bool
+	parallel      int
+	tombAnnot     []string
+	tombMT        string
+	watch         watchOpts
+	yes           bool
 }
 
 func NewTagCmd(rOpts *rootOpts) *cobra.Command {
@@ -31,6 +50,8 @@ func NewTagCmd(rOpts *rootOpts) *cobra.Command {
 	}
 	cmd.AddCommand(newTagDeleteCmd(rOpts))
 	cmd.AddCommand(newTagLsCmd(rOpts))
+	cmd.AddCommand(newTagSetCmd(rOpts))
+	cmd.AddCommand(newTagWatchCmd(rOpts))
 	return cmd
 }
 
@@ -39,22 +60,44 @@ func newTagDeleteCmd(rOpts *rootOpts) *cobra.Command {
 		rootOpts: rOpts,
 	}
 	cmd := &cobra.Command{
-		Use:     "delete <image_ref>",
+		Use:     "delete <image_ref> [tag...]",
 		Aliases: []string{"del", "rm", "remove"},
 		Short:   "delete a tag in a repo",
 		Long: `Delete a tag in a repository.
 This avoids deleting the manifest when multiple tags reference the same image.
 For registries that do not support the OCI tag delete API, this is implemented
 by pushing a unique dummy manifest and deleting that by digest.
-If the registry does not support the delete API, the dummy manifest will remain.`,
+If the registry does not support the delete API, the dummy manifest will remain.
+Additional tags in the same repository may be listed after the image reference,
+or selected with a "--filter" on the tag listing.
+When deleting multiple tags from a terminal, a confirmation is requested unless "--yes" is set.
+Tags matching the immutable tag patterns of the registry config are not deleted unless "--force" is set.`,
 		Example: `
 # delete a tag
-regctl tag delete registry.example.org/repo:v42`,
-		Args:              cobra.ExactArgs(1),
+regctl tag delete registry.example.org/repo:v42
+
+# delete multiple tags
+regctl tag delete registry.example.org/repo v40 v41 v42
+
+# show the tags that would be deleted by a filter
+regctl tag delete registry.example.org/repo --filter 'pr-[0-9]+' --dry-run`,
+		Args:              cobra.MinimumNArgs(1),
 		ValidArgsFunction: rOpts.completeArgTag,
 		RunE:              opts.runTagDelete,
 	}
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Output the tags that would be deleted")
+	cmd.Flags().StringArrayVar(&opts.filter, "filter", []string{}, "Regexp of tags to delete (expression is bound to beginning and ending of tag)")
+	_ = cmd.RegisterFlagCompletionFunc("filter", completeArgNone)
+	cmd.Flags().BoolVar(&opts.force, "force", false, "Delete tags matching the immutable tag patterns of the registry")
 	cmd.Flags().BoolVar(&opts.ignoreMissing, "ignore-missing", false, "Ignore errors if tag is missing")
+	cmd.Flags().BoolVar(&opts.noFallback, "no-fallback", false, "Do not push a tombstone manifest when the registry does not support the tag delete API")
+	cmd.Flags().IntVar(&opts.parallel, "parallel", 4, "Number of concurrent deletes")
+	_ = cmd.RegisterFlagCompletionFunc("parallel", completeArgNone)
+	cmd.Flags().StringArrayVar(&opts.tombAnnot, "tombstone-annotation", []string{}, "Annotation to set on the tombstone manifest (name=value)")
+	_ = cmd.RegisterFlagCompletionFunc("tombstone-annotation", completeArgNone)
+	cmd.Flags().StringVar(&opts.tombMT, "tombstone-media-type", "", "Media type of the tombstone manifest")
+	_ = cmd.RegisterFlagCompletionFunc("tombstone-media-type", completeArgMediaTypeManifest)
+	cmd.Flags().BoolVarP(&opts.yes, "yes", "y", false, "Do not prompt for confirmation")
 	return cmd
 }
 
@@ -93,6 +136,78 @@ regctl tag ls registry.example.org/repo --exclude 'sha256-.*'`,
 	return cmd
 }
 
+func newTagSetCmd(rOpts *rootOpts) *cobra.Command {
+	opts := tagOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:     "set <image_ref> --from <image_ref>",
+		Aliases: []string{"retag"},
+		Short:   "point a tag to an existing manifest",
+		Long: `Point a tag to an existing manifest in the same repository.
+The source manifest is pushed with the new tag, no blobs are copied.
+The source may be a full reference, or a ":tag" or "@digest" in the target repository.
+With "--if-unchanged", the tag is only replaced when it currently points to the digest,
+returning an error when another change was pushed first.
+To copy an image between repositories, use "regctl image copy".
+Tags matching the immutable tag patterns of the registry config are not replaced unless "--force" is set.`,
+		Example: `
+# point the stable tag to the v1.2.3 image
+regctl tag set registry.example.org/repo:stable --from registry.example.org/repo:v1.2.3
+
+# point a tag to a digest
+regctl tag set registry.example.org/repo:stable \
+  --from @sha256:1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef
+
+# only replace the tag if it has not changed since it was checked
+old=$(regctl image digest registry.example.org/repo:stable)
+regctl tag set registry.example.org/repo:stable --from :v1.2.4 --if-unchanged "$old"`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: rOpts.completeArgTag,
+		RunE:              opts.runTagSet,
+	}
+	cmd.Flags().BoolVar(&opts.force, "force", false, "Replace a tag matching the immutable tag patterns of the registry")
+	cmd.Flags().StringVar(&opts.from, "from", "", "Source tag or digest of the manifest")
+	_ = cmd.RegisterFlagCompletionFunc("from", rOpts.completeArgTag)
+	_ = cmd.MarkFlagRequired("from")
+	cmd.Flags().StringVar(&opts.ifUnchanged, "if-unchanged", "", "Only replace the tag if it currently points to this digest")
+	_ = cmd.RegisterFlagCompletionFunc("if-unchanged", completeArgNone)
+	return cmd
+}
+
+func newTagWatchCmd(rOpts *rootOpts) *cobra.Command {
+	opts := tagOpts{
+		rootOpts: rOpts,
+	}
+	cmd := &cobra.Command{
+		Use:   "watch <repository>",
+		Short: "watch for tag changes in a repo",
+		Long: `Poll the tag listing of a repository and report each tag that is added or removed.
+The first listing is used as the baseline and is not reported.
+Each change is output with "--format", or passed to the "--exec" command in the environment variables
+REGCTL_WATCH_REF, REGCTL_WATCH_ADDED, and REGCTL_WATCH_REMOVED, with tags separated by spaces.
+To watch the digest of a tag, use "regctl manifest watch".`,
+		Example: `
+# report new release tags every 5 minutes
+regctl tag watch registry.example.org/repo --include 'v[0-9.]+' --interval 5m
+
+# wait for the next tag to be pushed
+regctl tag watch registry.example.org/repo --count 1
+
+# run a script for each change
+regctl tag watch registry.example.org/repo --exec './notify.sh "$REGCTL_WATCH_ADDED"'`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{},
+		RunE:      opts.runTagWatch,
+	}
+	cmd.Flags().StringArrayVar(&opts.exclude, "exclude", []string{}, "Regexp of tags to exclude (expression is bound to beginning and ending of tag)")
+	_ = cmd.RegisterFlagCompletionFunc("exclude", completeArgNone)
+	cmd.Flags().StringArrayVar(&opts.include, "include", []string{}, "Regexp of tags to include (expression is bound to beginning and ending of tag)")
+	_ = cmd.RegisterFlagCompletionFunc("include", completeArgNone)
+	opts.watch.watchFlags(cmd, "{{ range .Added }}added {{ . }}\n{{ end }}{{ range .Removed }}removed {{ . }}\n{{ end }}")
+	return cmd
+}
+
 func (opts *tagOpts) runTagDelete(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	r, err := ref.New(args[0])
@@ -101,20 +216,169 @@ func (opts *tagOpts) runTagDelete(cmd *cobra.Command, args []string) error {
 	}
 	rc := opts.rootOpts.newRegClient()
 	defer rc.Close(ctx, r)
-	opts.rootOpts.log.Debug("Delete tag",
-		slog.String("host", r.Registry),
-		slog.String("repository", r.Repository),
-		slog.String("tag", r.Tag))
-	err = rc.TagDelete(ctx, r)
-	if err != nil && opts.ignoreMissing {
+
+	// build the list of tags to delete
+	multi := len(args) > 1 || len(opts.filter) > 0
+	tags := []string{}
+	if !multi || (r.Tag != "" && strings.HasSuffix(args[0], ":"+r.Tag)) || r.Digest != "" {
+		// the tag in the image ref is only deleted when explicitly provided
+		if r.Tag == "" {
+			return errs.ErrMissingTag
+		}
+		tags = append(tags, r.Tag)
+	}
+	tags = append(tags, args[1:]...)
+	if len(opts.filter) > 0 {
+		reFilter := []*regexp.Regexp{}
+		for _, expr := range opts.filter {
+			re, err := regexp.Compile("^" + expr + "$")
+			if err != nil {
+				return fmt.Errorf("failed to parse regexp \"%s\": %w", expr, err)
+			}
+			reFilter = append(reFilter, re)
+		}
+		tl, err := rc.TagList(ctx, r)
+		if err != nil {
+			return err
+		}
+		for _, tag := range tl.Tags {
+			for _, re := range reFilter {
+				if re.MatchString(tag) {
+					tags = append(tags, tag)
+					break
+				}
+			}
+		}
+	}
+	slices.Sort(tags)
+	tags = slices.Compact(tags)
+
+	if opts.dryRun {
+		for _, tag := range tags {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s\n", r.SetTag(tag).CommonName())
+		}
+		return nil
+	}
+	if multi && !opts.yes && ascii.IsReaderTerminal(cmd.InOrStdin()) {
+		for _, tag := range tags {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", r.SetTag(tag).CommonName())
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Delete %d tags? [y/N] ", len(tags))
+		answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			return fmt.Errorf("tag delete was not confirmed%.0w", errs.ErrCanceled)
+		}
+	}
+
+	sOpts := []scheme.TagDeleteOpts{}
+	if opts.force {
+		sOpts = append(sOpts, scheme.WithTagDeleteForce())
+	}
+	if opts.noFallback {
+		sOpts = append(sOpts, scheme.WithTagDeleteNoFallback())
+	}
+	if opts.tombMT != "" || len(opts.tombAnnot) > 0 {
+		annotations := map[string]string{}
+		for _, a := range opts.tombAnnot {
+			aSplit := strings.SplitN(a, "=", 2)
+			if len(aSplit) == 1 {
+				annotations[aSplit[0]] = ""
+			} else {
+				annotations[aSplit[0]] = aSplit[1]
+			}
+		}
+		sOpts = append(sOpts, scheme.WithTagDeleteTombstone(opts.tombMT, annotations))
+	}
+
+	// delete tags concurrently with a bounded number of workers
+	ops := make([]regclient.BatchOp, len(tags))
+	methods := make([]scheme.TagDeleteMethod, len(tags))
+	for i, tag := range tags {
+		opts.rootOpts.log.Debug("Delete tag",
+			slog.String("host", r.Registry),
+			slog.String("repository", r.Repository),
+			slog.String("tag", tag))
+		ops[i] = regclient.BatchOpTagDelete(r.SetTag(tag), append(slices.Clone(sOpts), scheme.WithTagDeleteMethod(&methods[i]))...)
+	}
+	report, _ := rc.Batch(ctx, ops, regclient.BatchWithWorkers(max(opts.parallel, 1)))
+	errList := make([]error, len(tags))
+	for i, res := range report.Results {
+		errList[i] = opts.tagDeleteResult(ctx, rc, res.Op.Ref, methods[i], res.Err)
+	}
+	return errors.Join(errList...)
+}
+
+// tagDeleteResult logs a deleted tag, and ignores the error of a missing tag when requested.
+func (opts *tagOpts) tagDeleteResult(ctx context.Context, rc *regclient.RegClient, r ref.Ref, method scheme.TagDeleteMethod, err error) error {
+	if err == nil {
+		opts.rootOpts.log.Debug("Deleted tag",
+			slog.String("tag", r.Tag),
+			slog.String("method", string(method)))
+		return nil
+	}
+	if opts.ignoreMissing {
 		_, mErr := rc.ManifestHead(ctx, r)
 		if errors.Is(mErr, errs.ErrNotFound) {
 			return nil
 		}
 	}
+	return err
+}
+
+func (opts *tagOpts) runTagSet(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	rTgt, err := ref.New(args[0])
 	if err != nil {
 		return err
 	}
+	if rTgt.Digest != "" {
+		return fmt.Errorf("tag set requires a tag without a digest: %s%.0w", rTgt.CommonName(), errs.ErrInvalidReference)
+	}
+	var rSrc ref.Ref
+	switch {
+	case strings.HasPrefix(opts.from, "@"):
+		rSrc = rTgt.SetDigest(strings.TrimPrefix(opts.from, "@"))
+	case strings.HasPrefix(opts.from, ":"):
+		rSrc = rTgt.SetTag(strings.TrimPrefix(opts.from, ":"))
+	default:
+		rSrc, err = ref.New(opts.from)
+		if err != nil {
+			return err
+		}
+	}
+	if !ref.EqualRepository(rSrc, rTgt) {
+		return fmt.Errorf("source %s must be in the same repository as %s, use \"regctl image copy\" between repositories%.0w", rSrc.CommonName(), rTgt.CommonName(), ErrInvalidInput)
+	}
+	mOpts := []regclient.ManifestOpts{}
+	if opts.force {
+		mOpts = append(mOpts, regclient.WithManifestForce())
+	}
+	if opts.ifUnchanged != "" {
+		d, err := digest.Parse(opts.ifUnchanged)
+		if err != nil {
+			return fmt.Errorf("failed to parse digest %s: %w", opts.ifUnchanged, err)
+		}
+		mOpts = append(mOpts, regclient.WithManifestIfMatch(d))
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, rTgt)
+	m, err := rc.ManifestGet(ctx, rSrc)
+	if err != nil {
+		return fmt.Errorf("failed to get source manifest %s: %w", rSrc.CommonName(), err)
+	}
+	opts.rootOpts.log.Debug("Set tag",
+		slog.String("source", rSrc.CommonName()),
+		slog.String("target", rTgt.CommonName()),
+		slog.String("digest", m.GetDescriptor().Digest.String()),
+		slog.String("if-unchanged", opts.ifUnchanged))
+	err = rc.ManifestPut(ctx, rTgt, m, mOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to set tag %s: %w", rTgt.CommonName(), err)
+	}
 	return nil
 }
 
@@ -124,21 +388,9 @@ func (opts *tagOpts) runTagLs(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	reInclude := []*regexp.Regexp{}
-	reExclude := []*regexp.Regexp{}
-	for _, expr := range opts.include {
-		re, err := regexp.Compile("^" + expr + "$")
-		if err != nil {
-			return fmt.Errorf("failed to parse regexp \"%s\": %w", expr, err)
-		}
-		reInclude = append(reInclude, re)
-	}
-	for _, expr := range opts.exclude {
-		re, err := regexp.Compile("^" + expr + "$")
-		if err != nil {
-			return fmt.Errorf("failed to parse regexp \"%s\": %w", expr, err)
-		}
-		reExclude = append(reExclude, re)
+	filter, err := opts.tagFilter()
+	if err != nil {
+		return err
 	}
 	rc := opts.rootOpts.newRegClient()
 	defer rc.Close(ctx, r)
@@ -156,30 +408,8 @@ func (opts *tagOpts) runTagLs(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	if len(reInclude) > 0 || len(reExclude) > 0 {
-		filtered := []string{}
-		var included, excluded bool
-		for _, tag := range tl.Tags {
-			included = len(reInclude) == 0
-			excluded = false
-			for _, re := range reInclude {
-				if re.MatchString(tag) {
-					included = true
-					break
-				}
-			}
-			if included {
-				for _, re := range reExclude {
-					if re.MatchString(tag) {
-						excluded = true
-					}
-				}
-			}
-			if included && !excluded {
-				filtered = append(filtered, tag)
-			}
-		}
-		tl.Tags = filtered
+	if len(opts.include) > 0 || len(opts.exclude) > 0 {
+		tl.Tags = slices.DeleteFunc(tl.Tags, func(tag string) bool { return !filter(tag) })
 	}
 	switch opts.format {
 	case "raw":
@@ -191,3 +421,88 @@ func (opts *tagOpts) runTagLs(cmd *cobra.Command, args []string) error {
 	}
 	return template.Writer(cmd.OutOrStdout(), opts.format, tl)
 }
+
+func (opts *tagOpts) runTagWatch(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	r, err := ref.New(args[0])
+	if err != nil {
+		return err
+	}
+	filter, err := opts.tagFilter()
+	if err != nil {
+		return err
+	}
+	rc := opts.rootOpts.newRegClient()
+	defer rc.Close(ctx, r)
+	opts.rootOpts.log.Debug("Watching tags",
+		slog.String("host", r.Registry),
+		slog.String("repository", r.Repository),
+		slog.Duration("interval", opts.watch.interval))
+	var prev []string
+	return opts.watch.watch(cmd, opts.rootOpts, func(ctx context.Context) (*watchEvent, error) {
+		tl, err := rc.TagList(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		cur := slices.DeleteFunc(slices.Clone(tl.Tags), func(tag string) bool { return !filter(tag) })
+		slices.Sort(cur)
+		if prev == nil {
+			prev = cur
+			return nil, nil
+		}
+		ev := watchEvent{Ref: r.CommonName()}
+		for _, tag := range cur {
+			if _, found := slices.BinarySearch(prev, tag); !found {
+				ev.Added = append(ev.Added, tag)
+			}
+		}
+		for _, tag := range prev {
+			if _, found := slices.BinarySearch(cur, tag); !found {
+				ev.Removed = append(ev.Removed, tag)
+			}
+		}
+		prev = cur
+		if len(ev.Added) == 0 && len(ev.Removed) == 0 {
+			return nil, nil
+		}
+		return &ev, nil
+	})
+}
+
+// tagFilter returns a function that matches tags against the include and exclude expressions.
+func (opts *tagOpts) tagFilter() (func(string) bool, error) {
+	reInclude := []*regexp.Regexp{}
+	reExclude := []*regexp.Regexp{}
+	for _, expr := range opts.include {
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse regexp \"%s\": %w", expr, err)
+		}
+		reInclude = append(reInclude, re)
+	}
+	for _, expr := range opts.exclude {
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse regexp \"%s\": %w", expr, err)
+		}
+		reExclude = append(reExclude, re)
+	}
+	return func(tag string) bool {
+		included := len(reInclude) == 0
+		for _, re := range reInclude {
+			if re.MatchString(tag) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+		for _, re := range reExclude {
+			if re.MatchString(tag) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
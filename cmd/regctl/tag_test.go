@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -19,6 +21,7 @@ import (
 	"github.com/regclient/regclient/config"
 	"github.com/regclient/regclient/scheme/reg"
 	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ref"
 )
 
 func TestTagList(t *testing.T) {
@@ -136,7 +139,7 @@ func TestTagRm(t *testing.T) {
 		{
 			name:      "Missing arg",
 			args:      []string{"tag", "rm"},
-			expectErr: fmt.Errorf("accepts 1 arg(s), received 0"),
+			expectErr: fmt.Errorf("requires at least 1 arg(s), only received 0"),
 		},
 		{
 			name:      "Delete digest",
@@ -156,6 +159,38 @@ func TestTagRm(t *testing.T) {
 			name: "Delete missing with ignore missing",
 			args: []string{"tag", "rm", tsHost + "/testrepo:missing", "--ignore-missing"},
 		},
+		{
+			name:      "Delete filter dry run",
+			args:      []string{"tag", "rm", tsHost + "/testrepo", "--filter", "b[0-9]", "--dry-run"},
+			expectOut: tsHost + "/testrepo:b1\n" + tsHost + "/testrepo:b2\n" + tsHost + "/testrepo:b3",
+		},
+		{
+			name: "Delete multiple",
+			args: []string{"tag", "rm", tsHost + "/testrepo:v2", "v3", "a1"},
+		},
+		{
+			name:      "Delete multiple missing",
+			args:      []string{"tag", "rm", tsHost + "/testrepo", "v2", "a2"},
+			expectErr: errs.ErrNotFound,
+		},
+		{
+			name: "Delete filter",
+			args: []string{"tag", "rm", tsHost + "/testrepo", "--filter", "b[0-9]", "--parallel", "2"},
+		},
+		{
+			name:      "Delete filter verify",
+			args:      []string{"tag", "ls", tsHost + "/testrepo", "--include", "b[0-9]|v[0-9]"},
+			expectOut: "",
+		},
+		{
+			name:      "Delete invalid tombstone",
+			args:      []string{"tag", "rm", tsHost + "/testrepo:v1", "--tombstone-media-type", "application/vnd.oci.image.index.v1+json"},
+			expectErr: errs.ErrUnsupportedMediaType,
+		},
+		{
+			name: "Delete no fallback",
+			args: []string{"tag", "rm", tsHost + "/testrepo:a3", "--no-fallback"},
+		},
 		{
 			name:      "Delete tls error with ignore missing",
 			args:      []string{"tag", "rm", "invalid-tls." + tsHost + "/testrepo:missing", "--ignore-missing"},
@@ -182,3 +217,214 @@ func TestTagRm(t *testing.T) {
 		})
 	}
 }
+
+func TestTagSet(t *testing.T) {
+	t.Parallel()
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "../../testdata",
+		},
+	})
+	ts := httptest.NewServer(regHandler)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+	rcOpts := []regclient.Opt{
+		regclient.WithConfigHost(config.Host{
+			Name: tsHost,
+			TLS:  config.TLSDisabled,
+		}),
+		regclient.WithRegOpts(reg.WithDelay(time.Millisecond*10, time.Millisecond*100), reg.WithRetryLimit(2)),
+	}
+	digV1 := "sha256:7ceb9b6bcc274697d0c38be6214b50cec79d601bc61708747d3f6cb772f6c6fa"
+	digV2 := "sha256:dfae8f425735a5e3a72e40d6609e03079995511d48157c74d54801ff4430491e"
+	digV3 := "sha256:6fe828b32b9b4572f32b16c1c0a4d675660b19ec207d010724309374252c2d6d"
+
+	tt := []struct {
+		name      string
+		args      []string
+		expectErr error
+		expectOut string
+	}{
+		{
+			name:      "Missing from",
+			args:      []string{"tag", "set", tsHost + "/testrepo:stable"},
+			expectErr: fmt.Errorf(`required flag(s) "from" not set`),
+		},
+		{
+			name: "Set from tag",
+			args: []string{"tag", "set", tsHost + "/testrepo:stable", "--from", tsHost + "/testrepo:v1"},
+		},
+		{
+			name:      "Verify from tag",
+			args:      []string{"image", "digest", tsHost + "/testrepo:stable"},
+			expectOut: digV1,
+		},
+		{
+			name: "Set from short digest",
+			args: []string{"tag", "set", tsHost + "/testrepo:stable", "--from", "@" + digV2},
+		},
+		{
+			name:      "Verify from digest",
+			args:      []string{"image", "digest", tsHost + "/testrepo:stable"},
+			expectOut: digV2,
+		},
+		{
+			name:      "If unchanged conflict",
+			args:      []string{"tag", "set", tsHost + "/testrepo:stable", "--from", ":v3", "--if-unchanged", digV1},
+			expectErr: errs.ErrConflict,
+		},
+		{
+			name: "If unchanged",
+			args: []string{"tag", "set", tsHost + "/testrepo:stable", "--from", ":v3", "--if-unchanged", digV2},
+		},
+		{
+			name:      "Verify if unchanged",
+			args:      []string{"image", "digest", tsHost + "/testrepo:stable"},
+			expectOut: digV3,
+		},
+		{
+			name:      "If unchanged invalid",
+			args:      []string{"tag", "set", tsHost + "/testrepo:stable", "--from", ":v1", "--if-unchanged", "sha256:abc"},
+			expectErr: digest.ErrDigestInvalidLength,
+		},
+		{
+			name:      "Other repository",
+			args:      []string{"tag", "set", tsHost + "/testrepo:stable", "--from", tsHost + "/testcopy:v1"},
+			expectErr: ErrInvalidInput,
+		},
+		{
+			name:      "Target digest",
+			args:      []string{"tag", "set", tsHost + "/testrepo@" + digV1, "--from", ":v1"},
+			expectErr: errs.ErrInvalidReference,
+		},
+		{
+			name:      "Missing source",
+			args:      []string{"tag", "set", tsHost + "/testrepo:stable", "--from", ":missing"},
+			expectErr: errs.ErrNotFound,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := cobraTest(t, &cobraTestOpts{rcOpts: rcOpts}, tc.args...)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Errorf("did not receive expected error: %v", tc.expectErr)
+				} else if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
+					t.Errorf("unexpected error, received %v, expected %v", err, tc.expectErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("returned unexpected error: %v", err)
+			}
+			if out != tc.expectOut {
+				t.Errorf("unexpected output, expected %s, received %s", tc.expectOut, out)
+			}
+		})
+	}
+}
+
+func TestTagWatch(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	repo := "ocidir://" + t.TempDir() + "/repo"
+	rc := regclient.New()
+	rSrc, err := ref.New("ocidir://../../testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rTgt, err := ref.New(repo + ":v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	err = rc.ImageCopy(ctx, rSrc, rTgt)
+	if err != nil {
+		t.Fatalf("failed to copy image: %v", err)
+	}
+	_ = rc.Close(ctx, rTgt)
+	tt := []struct {
+		name        string
+		args        []string
+		expectErr   error
+		expectOut   string
+		outContains bool
+	}{
+		{
+			name:      "Invalid interval",
+			args:      []string{"tag", "watch", repo, "--interval", "0s"},
+			expectErr: ErrInvalidInput,
+		},
+		{
+			name:      "Invalid filter",
+			args:      []string{"tag", "watch", repo, "--include", "v[0-9"},
+			expectErr: fmt.Errorf("failed to parse regexp \"v[0-9\": error parsing regexp: missing closing ]: `[0-9$`"),
+		},
+		{
+			name:        "Added",
+			args:        []string{"tag", "watch", repo, "--interval", "10ms", "--count", "1", "--exclude", "skip.*"},
+			expectOut:   "added new-",
+			outContains: true,
+		},
+		{
+			name:        "Format",
+			args:        []string{"tag", "watch", repo, "--interval", "10ms", "--count", "1", "--include", "new-.*", "--format", "{{ .Ref }}"},
+			expectOut:   rTgt.SetTag("").CommonName(),
+			outContains: false,
+		},
+	}
+	if runtime.GOOS != "windows" {
+		tt = append(tt, struct {
+			name        string
+			args        []string
+			expectErr   error
+			expectOut   string
+			outContains bool
+		}{
+			name:        "Exec",
+			args:        []string{"tag", "watch", repo, "--interval", "10ms", "--count", "1", "--include", "new-.*", "--exec", "echo tags $REGCTL_WATCH_ADDED"},
+			expectOut:   "tags new-",
+			outContains: true,
+		})
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// push a new tag until the watch returns, the initial listing is not reported
+			done := make(chan struct{})
+			go func() {
+				for i := 0; ; i++ {
+					select {
+					case <-done:
+						return
+					case <-time.After(time.Millisecond * 20):
+					}
+					for _, prefix := range []string{"skip", "new"} {
+						rNew := rTgt.SetTag(fmt.Sprintf("%s-%d", prefix, i))
+						_ = rc.ImageCopy(ctx, rTgt, rNew)
+						_ = rc.Close(ctx, rNew)
+					}
+				}
+			}()
+			out, err := cobraTest(t, nil, tc.args...)
+			close(done)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Errorf("did not receive expected error: %v", tc.expectErr)
+				} else if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
+					t.Errorf("unexpected error, received %v, expected %v", err, tc.expectErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("returned unexpected error: %v", err)
+			}
+			if (!tc.outContains && out != tc.expectOut) || (tc.outContains && !strings.Contains(out, tc.expectOut)) {
+				t.Errorf("unexpected output, expected %s, received %s", tc.expectOut, out)
+			}
+		})
+	}
+}
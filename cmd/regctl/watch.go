@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/regclient/regclient/pkg/template"
+)
+
+// watchOpts are the flags shared by the watch commands.
+type watchOpts struct {
+	count    int
+	exec     string
+	format   string
+	interval time.Duration
+}
+
+// watchEvent describes a change found by a watch command.
+type watchEvent struct {
+	Ref      string    `json:"ref"`
+	Time     time.Time `json:"time"`
+	Digest   string    `json:"digest,omitempty"`   // current digest of a manifest, empty when deleted
+	Previous string    `json:"previous,omitempty"` // previous digest of a manifest, empty when created
+	Added    []string  `json:"added,omitempty"`    // tags added to a repository
+	Removed  []string  `json:"removed,omitempty"`  // tags removed from a repository
+}
+
+// watchFlags adds the shared watch flags to a command.
+func (opts *watchOpts) watchFlags(cmd *cobra.Command, format string) {
+	cmd.Flags().IntVar(&opts.count, "count", 0, "Exit after the number of changes, 0 to watch until interrupted")
+	_ = cmd.RegisterFlagCompletionFunc("count", completeArgNone)
+	cmd.Flags().StringVar(&opts.exec, "exec", "", "Shell command to run on each change, the format output is skipped unless the format is also set")
+	_ = cmd.RegisterFlagCompletionFunc("exec", completeArgNone)
+	cmd.Flags().StringVar(&opts.format, "format", format, "Format output of each change with go template syntax")
+	_ = cmd.RegisterFlagCompletionFunc("format", completeArgNone)
+	cmd.Flags().DurationVar(&opts.interval, "interval", time.Minute, "Time between each poll of the registry")
+	_ = cmd.RegisterFlagCompletionFunc("interval", completeArgNone)
+}
+
+// watch polls until the context is canceled or the count of changes is reached.
+// The poll function returns nil when nothing has changed, and errors are logged without stopping the watch.
+func (opts *watchOpts) watch(cmd *cobra.Command, rOpts *rootOpts, poll func(context.Context) (*watchEvent, error)) error {
+	ctx := cmd.Context()
+	if opts.interval <= 0 {
+		return fmt.Errorf("interval must be greater than zero%.0w", ErrInvalidInput)
+	}
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+	changes := 0
+	for {
+		ev, err := poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			rOpts.log.Warn("Failed to poll registry",
+				slog.String("err", err.Error()))
+		}
+		if err == nil && ev != nil {
+			ev.Time = time.Now().UTC()
+			err = opts.watchOutput(cmd, rOpts, *ev)
+			if err != nil {
+				return err
+			}
+			changes++
+			if opts.count > 0 && changes >= opts.count {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchOutput prints the change with the format and runs the exec command.
+// Failures of the exec command are logged and do not stop the watch.
+func (opts *watchOpts) watchOutput(cmd *cobra.Command, rOpts *rootOpts, ev watchEvent) error {
+	if opts.exec == "" || flagChanged(cmd, "format") {
+		err := template.Writer(cmd.OutOrStdout(), opts.format, ev)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.exec == "" {
+		return nil
+	}
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.CommandContext(cmd.Context(), "cmd", "/C", opts.exec)
+	} else {
+		c = exec.CommandContext(cmd.Context(), "sh", "-c", opts.exec)
+	}
+	c.Env = append(os.Environ(),
+		"REGCTL_WATCH_REF="+ev.Ref,
+		"REGCTL_WATCH_DIGEST="+ev.Digest,
+		"REGCTL_WATCH_PREVIOUS="+ev.Previous,
+		"REGCTL_WATCH_ADDED="+strings.Join(ev.Added, " "),
+		"REGCTL_WATCH_REMOVED="+strings.Join(ev.Removed, " "),
+	)
+	c.Stdout = cmd.OutOrStdout()
+	c.Stderr = cmd.ErrOrStderr()
+	if err := c.Run(); err != nil {
+		rOpts.log.Warn("Watch command failed",
+			slog.String("ref", ev.Ref),
+			slog.String("exec", opts.exec),
+			slog.String("err", err.Error()))
+	}
+	return nil
+}
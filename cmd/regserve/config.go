@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/internal/conffile"
+)
+
+var (
+	// ConfigFilename is the default filename of the regctl configuration
+	ConfigFilename = "config.json"
+	// ConfigHomeDir is the default directory within the user's home directory of the regctl configuration
+	ConfigHomeDir = ".regctl"
+	// ConfigAppDir is an alternate location for the regctl configuration
+	ConfigAppDir = "regctl"
+	// ConfigEnv is the environment variable to override the config filename
+	ConfigEnv = "REGCTL_CONFIG"
+)
+
+// Config contains the settings used from a regctl config file.
+// regserve shares the config with regctl so registry logins, TLS settings, and mirrors only need to be configured once.
+type Config struct {
+	Filename      string                  `json:"-"`                 // filename that was loaded
+	Version       int                     `json:"version,omitempty"` // version the file in case the config file syntax changes in the future
+	Hosts         map[string]*config.Host `json:"hosts,omitempty"`
+	HostDefault   *config.Host            `json:"hostDefault,omitempty"`
+	BlobLimit     int64                   `json:"blobLimit,omitempty"`
+	IncDockerCert *bool                   `json:"incDockerCert,omitempty"`
+	IncDockerCred *bool                   `json:"incDockerCred,omitempty"`
+}
+
+// ConfigNew creates an empty configuration
+func ConfigNew() *Config {
+	c := Config{
+		Hosts: map[string]*config.Host{},
+	}
+	return &c
+}
+
+// ConfigLoad reads the config from filename, or the default regctl config file when filename is empty.
+// A missing default config file returns an empty config.
+func ConfigLoad(filename string) (*Config, error) {
+	var cf *conffile.File
+	if filename != "" {
+		cf = conffile.New(conffile.WithFullname(filename))
+	} else {
+		cf = conffile.New(
+			conffile.WithHomeDir(ConfigHomeDir, ConfigFilename, true),
+			conffile.WithAppDir(ConfigAppDir, ConfigAppDir, ConfigFilename, false),
+			conffile.WithEnvFile(ConfigEnv),
+		)
+	}
+	if cf == nil {
+		return nil, fmt.Errorf("failed to define config file")
+	}
+	rdr, err := cf.Open()
+	if err != nil {
+		if filename == "" && errors.Is(err, fs.ErrNotExist) {
+			c := ConfigNew()
+			c.Filename = cf.Name()
+			return c, nil
+		}
+		return nil, err
+	}
+	defer rdr.Close()
+	c := ConfigNew()
+	if err := json.NewDecoder(rdr).Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	c.Filename = cf.Name()
+	if c.Version > 1 {
+		return c, ErrUnsupportedConfigVersion
+	}
+	for h := range c.Hosts {
+		if c.Hosts[h].Name == "" {
+			c.Hosts[h].Name = h
+		}
+		if h == config.DockerRegistryDNS || h == config.DockerRegistry || h == config.DockerRegistryAuth {
+			c.Hosts[h].Name = config.DockerRegistry
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,8 @@
+package main
+
+import "errors"
+
+var (
+	// ErrUnsupportedConfigVersion happens when config file version is greater than this command supports
+	ErrUnsupportedConfigVersion = errors.New("unsupported config version")
+)
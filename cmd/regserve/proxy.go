@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/ref"
+)
+
+var (
+	reContent = regexp.MustCompile(`^/v2/(.+)/(manifests|blobs)/([^/]+)$`)
+	reTags    = regexp.MustCompile(`^/v2/(.+)/tags/list$`)
+)
+
+// proxy serves the pull side of the OCI distribution API from an upstream registry.
+// Clients are not authenticated, the listener must be restricted to trusted clients.
+type proxy struct {
+	rc       *regclient.RegClient
+	upstream string
+	cache    string
+	log      *slog.Logger
+	cacheWG  sync.WaitGroup // blobs being written to the cache
+}
+
+func newProxy(rc *regclient.RegClient, upstream, cache string, log *slog.Logger) (*proxy, error) {
+	if _, err := ref.NewHost(upstream); err != nil {
+		return nil, fmt.Errorf("invalid upstream %s: %w", upstream, err)
+	}
+	return &proxy{
+		rc:       rc,
+		upstream: upstream,
+		cache:    cache,
+		log:      log,
+	}, nil
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		p.writeErr(w, http.StatusMethodNotAllowed, errs.ErrorCodeUnsupported, "only pull requests are supported")
+		return
+	}
+	if req.URL.Path == "/v2/" || req.URL.Path == "/v2" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if req.Method == http.MethodGet {
+			_, _ = w.Write([]byte("{}"))
+		}
+		return
+	}
+	if match := reTags.FindStringSubmatch(req.URL.Path); match != nil {
+		p.serveTags(w, req, match[1])
+		return
+	}
+	if match := reContent.FindStringSubmatch(req.URL.Path); match != nil {
+		if match[2] == "manifests" {
+			p.serveManifest(w, req, match[1], match[3])
+		} else {
+			p.serveBlob(w, req, match[1], match[3])
+		}
+		return
+	}
+	p.writeErr(w, http.StatusNotFound, errs.ErrorCodeNameUnknown, "unknown path")
+}
+
+// refs returns the upstream and cache references for a repository.
+// The cache reference is unset when caching is disabled.
+func (p *proxy) refs(repo string) (ref.Ref, ref.Ref, error) {
+	rUp, err := ref.New(p.upstream + "/" + repo)
+	if err != nil {
+		return rUp, ref.Ref{}, err
+	}
+	if p.cache == "" {
+		return rUp, ref.Ref{}, nil
+	}
+	rCache, err := ref.New("ocidir://" + path.Join(p.cache, repo))
+	if err != nil {
+		return rUp, rCache, err
+	}
+	return rUp, rCache, nil
+}
+
+func (p *proxy) serveManifest(w http.ResponseWriter, req *http.Request, repo, reference string) {
+	ctx := req.Context()
+	rUp, rCache, err := p.refs(repo)
+	if err != nil {
+		p.writeErr(w, http.StatusBadRequest, errs.ErrorCodeNameInvalid, err.Error())
+		return
+	}
+	byDigest := false
+	if _, err := digest.Parse(reference); err == nil {
+		byDigest = true
+		rUp = rUp.SetDigest(reference)
+		rCache = rCache.SetDigest(reference)
+	} else {
+		rUp = rUp.SetTag(reference)
+		rCache = rCache.SetTag(reference)
+	}
+	// content pulled by digest cannot change, use the cache without contacting the upstream
+	if byDigest && rCache.IsSet() {
+		if m, err := p.manifestGet(ctx, rCache, req.Method); err == nil {
+			p.writeManifest(w, req, m)
+			return
+		}
+	}
+	m, err := p.manifestGet(ctx, rUp, req.Method)
+	if err != nil {
+		// fall back to the cache when the upstream is unavailable
+		if !byDigest && rCache.IsSet() && !errors.Is(err, errs.ErrNotFound) {
+			if mCache, errCache := p.manifestGet(ctx, rCache, req.Method); errCache == nil {
+				p.log.Warn("Upstream unavailable, returning cached manifest",
+					slog.String("ref", rUp.CommonName()),
+					slog.String("err", err.Error()))
+				p.writeManifest(w, req, mCache)
+				return
+			}
+		}
+		p.writeRegErr(w, err, errs.ErrorCodeManifestUnknown)
+		return
+	}
+	if rCache.IsSet() && req.Method == http.MethodGet {
+		if err := p.rc.ManifestPut(ctx, rCache, m); err != nil {
+			p.log.Warn("Failed to cache manifest",
+				slog.String("ref", rUp.CommonName()),
+				slog.String("err", err.Error()))
+		}
+	}
+	p.writeManifest(w, req, m)
+}
+
+func (p *proxy) manifestGet(ctx context.Context, r ref.Ref, method string) (manifest.Manifest, error) {
+	if method == http.MethodHead {
+		m, err := p.rc.ManifestHead(ctx, r, regclient.WithManifestRequireDigest())
+		if err == nil {
+			return m, nil
+		}
+		// some registries do not support HEAD requests
+		if !errors.Is(err, errs.ErrUnsupportedAPI) {
+			return nil, err
+		}
+	}
+	return p.rc.ManifestGet(ctx, r)
+}
+
+func (p *proxy) writeManifest(w http.ResponseWriter, req *http.Request, m manifest.Manifest) {
+	desc := m.GetDescriptor()
+	w.Header().Set("Content-Type", desc.MediaType)
+	w.Header().Set("Docker-Content-Digest", desc.Digest.String())
+	if req.Method == http.MethodHead {
+		if desc.Size > 0 {
+			w.Header().Set("Content-Length", strconv.FormatInt(desc.Size, 10))
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	body, err := m.RawBody()
+	if err != nil {
+		p.writeErr(w, http.StatusInternalServerError, errs.ErrorCodeManifestInvalid, err.Error())
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+func (p *proxy) serveBlob(w http.ResponseWriter, req *http.Request, repo, reference string) {
+	ctx := req.Context()
+	dig, err := digest.Parse(reference)
+	if err != nil {
+		p.writeErr(w, http.StatusBadRequest, errs.ErrorCodeDigestInvalid, err.Error())
+		return
+	}
+	rUp, rCache, err := p.refs(repo)
+	if err != nil {
+		p.writeErr(w, http.StatusBadRequest, errs.ErrorCodeNameInvalid, err.Error())
+		return
+	}
+	d := descriptor.Descriptor{Digest: dig}
+	if req.Method == http.MethodHead {
+		if rCache.IsSet() {
+			if br, err := p.rc.BlobHead(ctx, rCache, d); err == nil {
+				_ = p.writeBlob(w, req, br.GetDescriptor(), nil)
+				_ = br.Close()
+				return
+			}
+		}
+		br, err := p.rc.BlobHead(ctx, rUp, d)
+		if err != nil {
+			p.writeRegErr(w, err, errs.ErrorCodeBlobUnknown)
+			return
+		}
+		_ = p.writeBlob(w, req, br.GetDescriptor(), nil)
+		_ = br.Close()
+		return
+	}
+	if rCache.IsSet() {
+		br, errCache := p.rc.BlobGet(ctx, rCache, d)
+		if errCache == nil {
+			_ = p.writeBlob(w, req, br.GetDescriptor(), br)
+			_ = br.Close()
+			return
+		}
+		if !errors.Is(errCache, errs.ErrNotFound) && !errors.Is(errCache, fs.ErrNotExist) {
+			p.log.Warn("Failed to read cached blob",
+				slog.String("repo", rCache.CommonName()),
+				slog.String("digest", dig.String()),
+				slog.String("err", errCache.Error()))
+		}
+	}
+	br, err := p.rc.BlobGet(ctx, rUp, d)
+	if err != nil {
+		p.writeRegErr(w, err, errs.ErrorCodeBlobUnknown)
+		return
+	}
+	defer br.Close()
+	if !rCache.IsSet() {
+		_ = p.writeBlob(w, req, br.GetDescriptor(), br)
+		return
+	}
+	// the blob is written to the cache while it is sent to the client
+	pr, pw := io.Pipe()
+	p.cacheWG.Add(1)
+	go func() {
+		defer p.cacheWG.Done()
+		_, err := p.rc.BlobPut(context.WithoutCancel(ctx), rCache, br.GetDescriptor(), pr)
+		// stop any remaining writes when the put fails early
+		_ = pr.CloseWithError(errProxyCacheDone)
+		if err != nil {
+			p.log.Warn("Failed to cache blob",
+				slog.String("repo", rUp.CommonName()),
+				slog.String("digest", dig.String()),
+				slog.String("err", err.Error()))
+		}
+	}()
+	err = p.writeBlob(w, req, br.GetDescriptor(), io.TeeReader(br, &proxyCacheWriter{pw: pw}))
+	if err != nil {
+		_ = pw.CloseWithError(err)
+		return
+	}
+	_ = pw.Close()
+}
+
+// wait blocks until blobs being written to the cache are complete.
+func (p *proxy) wait() {
+	p.cacheWG.Wait()
+}
+
+var errProxyCacheDone = errors.New("cache write finished")
+
+// proxyCacheWriter writes to the cache without failing the client response when the cache stops reading.
+type proxyCacheWriter struct {
+	pw  *io.PipeWriter
+	err error
+}
+
+func (cw *proxyCacheWriter) Write(b []byte) (int, error) {
+	if cw.err == nil {
+		_, cw.err = cw.pw.Write(b)
+	}
+	return len(b), nil
+}
+
+// writeBlob sends a blob to the client, returning any error reading the blob or writing the response.
+func (p *proxy) writeBlob(w http.ResponseWriter, req *http.Request, d descriptor.Descriptor, rdr io.Reader) error {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", d.Digest.String())
+	if d.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(d.Size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if req.Method == http.MethodHead || rdr == nil {
+		return nil
+	}
+	if _, err := io.Copy(w, rdr); err != nil {
+		p.log.Warn("Failed to send blob",
+			slog.String("digest", d.Digest.String()),
+			slog.String("err", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (p *proxy) serveTags(w http.ResponseWriter, req *http.Request, repo string) {
+	ctx := req.Context()
+	rUp, rCache, err := p.refs(repo)
+	if err != nil {
+		p.writeErr(w, http.StatusBadRequest, errs.ErrorCodeNameInvalid, err.Error())
+		return
+	}
+	tl, err := p.rc.TagList(ctx, rUp)
+	if err != nil && rCache.IsSet() && !errors.Is(err, errs.ErrNotFound) {
+		if tlCache, errCache := p.rc.TagList(ctx, rCache); errCache == nil {
+			p.log.Warn("Upstream unavailable, returning cached tags",
+				slog.String("repo", rUp.CommonName()),
+				slog.String("err", err.Error()))
+			tl, err = tlCache, nil
+		}
+	}
+	if err != nil {
+		p.writeRegErr(w, err, errs.ErrorCodeNameUnknown)
+		return
+	}
+	tags, err := tl.GetTags()
+	if err != nil {
+		p.writeErr(w, http.StatusBadGateway, "UNKNOWN", err.Error())
+		return
+	}
+	body, err := json.Marshal(struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}{
+		Name: repo,
+		Tags: tags,
+	})
+	if err != nil {
+		p.writeErr(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if req.Method == http.MethodGet {
+		_, _ = w.Write(body)
+	}
+}
+
+// writeRegErr converts an error from regclient to a registry error response.
+// The status and error codes from the upstream registry are returned when available.
+func (p *proxy) writeRegErr(w http.ResponseWriter, err error, notFound errs.ErrorCode) {
+	var regErr *errs.RegistryError
+	switch {
+	case errors.As(err, &regErr) && regErr.StatusCode >= 400 && len(regErr.Errors) > 0:
+		p.writeErrDetails(w, regErr.StatusCode, regErr.Errors)
+	case errors.Is(err, errs.ErrNotFound):
+		p.writeErr(w, http.StatusNotFound, notFound, err.Error())
+	case errors.Is(err, errs.ErrHTTPUnauthorized):
+		p.writeErr(w, http.StatusForbidden, errs.ErrorCodeDenied, err.Error())
+	case errors.Is(err, errs.ErrHTTPRateLimit):
+		p.writeErr(w, http.StatusTooManyRequests, errs.ErrorCodeTooManyRequests, err.Error())
+	default:
+		p.log.Warn("Upstream request failed",
+			slog.String("err", err.Error()))
+		p.writeErr(w, http.StatusBadGateway, "UNKNOWN", err.Error())
+	}
+}
+
+func (p *proxy) writeErr(w http.ResponseWriter, status int, code errs.ErrorCode, msg string) {
+	p.writeErrDetails(w, status, []errs.RegistryErrorDetail{{Code: code, Message: msg}})
+}
+
+func (p *proxy) writeErrDetails(w http.ResponseWriter, status int, details []errs.RegistryErrorDetail) {
+	body, _ := json.Marshal(struct {
+		Errors []errs.RegistryErrorDetail `json:"errors"`
+	}{
+		Errors: details,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
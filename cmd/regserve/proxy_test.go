@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/olareg/olareg"
+	oConfig "github.com/olareg/olareg/config"
+
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/scheme/reg"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ref"
+)
+
+func TestProxy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "../../testdata",
+		},
+	})
+	ts := httptest.NewServer(regHandler)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	regOpts := regclient.WithRegOpts(reg.WithDelay(time.Millisecond*10, time.Millisecond*100), reg.WithRetryLimit(2))
+	rcUp := regclient.New(
+		regclient.WithConfigHost(config.Host{Name: tsHost, TLS: config.TLSDisabled}),
+		regclient.WithSlog(log),
+		regOpts,
+	)
+	cacheDir := t.TempDir()
+	p, err := newProxy(rcUp, tsHost, cacheDir, log)
+	if err != nil {
+		t.Fatalf("failed to create proxy: %v", err)
+	}
+	tsProxy := httptest.NewServer(p)
+	tsProxyURL, _ := url.Parse(tsProxy.URL)
+	proxyHost := tsProxyURL.Host
+	t.Cleanup(func() {
+		tsProxy.Close()
+	})
+	rc := regclient.New(
+		regclient.WithConfigHost(config.Host{Name: proxyHost, TLS: config.TLSDisabled}),
+		regclient.WithSlog(log),
+		regOpts,
+	)
+	rUp, err := ref.New(tsHost + "/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rProxy, err := ref.New(proxyHost + "/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	mUp, err := rcUp.ManifestGet(ctx, rUp)
+	if err != nil {
+		t.Fatalf("failed to get upstream manifest: %v", err)
+	}
+	dig := mUp.GetDescriptor().Digest
+
+	t.Run("manifest get", func(t *testing.T) {
+		m, err := rc.ManifestGet(ctx, rProxy)
+		if err != nil {
+			t.Fatalf("failed to get manifest: %v", err)
+		}
+		if m.GetDescriptor().Digest != dig {
+			t.Errorf("unexpected digest, expected %s, received %s", dig, m.GetDescriptor().Digest)
+		}
+	})
+	t.Run("manifest head", func(t *testing.T) {
+		m, err := rc.ManifestHead(ctx, rProxy, regclient.WithManifestRequireDigest())
+		if err != nil {
+			t.Fatalf("failed to head manifest: %v", err)
+		}
+		if m.GetDescriptor().Digest != dig {
+			t.Errorf("unexpected digest, expected %s, received %s", dig, m.GetDescriptor().Digest)
+		}
+	})
+	t.Run("image copy", func(t *testing.T) {
+		rOut, err := ref.New("ocidir://" + t.TempDir() + "/out:v1")
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		err = rc.ImageCopy(ctx, rProxy, rOut)
+		if err != nil {
+			t.Fatalf("failed to copy image: %v", err)
+		}
+	})
+	t.Run("tag list", func(t *testing.T) {
+		tl, err := rc.TagList(ctx, rProxy)
+		if err != nil {
+			t.Fatalf("failed to list tags: %v", err)
+		}
+		tags, err := tl.GetTags()
+		if err != nil {
+			t.Fatalf("failed to get tags: %v", err)
+		}
+		if !slices.Contains(tags, "v1") {
+			t.Errorf("tag v1 missing from %v", tags)
+		}
+	})
+	t.Run("missing tag", func(t *testing.T) {
+		_, err := rc.ManifestGet(ctx, rProxy.SetTag("missing"))
+		if !errors.Is(err, errs.ErrNotFound) {
+			t.Errorf("unexpected error, expected %v, received %v", errs.ErrNotFound, err)
+		}
+	})
+	t.Run("push rejected", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPut, tsProxy.URL+"/v2/testrepo/manifests/new", nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to send request: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("unexpected status, expected %d, received %d", http.StatusMethodNotAllowed, resp.StatusCode)
+		}
+	})
+	if t.Failed() {
+		return
+	}
+	// remaining tests run from the cache with the upstream stopped
+	p.wait()
+	ts.Close()
+	t.Run("cached tag", func(t *testing.T) {
+		m, err := rc.ManifestGet(ctx, rProxy)
+		if err != nil {
+			t.Fatalf("failed to get manifest: %v", err)
+		}
+		if m.GetDescriptor().Digest != dig {
+			t.Errorf("unexpected digest, expected %s, received %s", dig, m.GetDescriptor().Digest)
+		}
+	})
+	t.Run("cached digest", func(t *testing.T) {
+		rOut, err := ref.New("ocidir://" + t.TempDir() + "/out")
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		err = rc.ImageCopy(ctx, rProxy.SetDigest(dig.String()), rOut)
+		if err != nil {
+			t.Fatalf("failed to copy image: %v", err)
+		}
+	})
+}
+
+func TestServeListenDefault(t *testing.T) {
+	t.Parallel()
+	cmd, _ := NewRootCmd()
+	serveCmd, _, err := cmd.Find([]string{"serve"})
+	if err != nil {
+		t.Fatalf("failed to find serve command: %v", err)
+	}
+	// clients are not authenticated, so the default must not listen on every interface
+	if listen := serveCmd.Flags().Lookup("listen").DefValue; listen != "127.0.0.1:5000" {
+		t.Errorf("unexpected default listen address: %s", listen)
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	// crypto libraries included for go-digest
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
+	"github.com/spf13/cobra"
+
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/internal/cobradoc"
+	"github.com/regclient/regclient/internal/strparse"
+	"github.com/regclient/regclient/internal/version"
+	"github.com/regclient/regclient/pkg/template"
+	"github.com/regclient/regclient/scheme/reg"
+	"github.com/regclient/regclient/types"
+)
+
+const (
+	// UserAgent sets the header on http requests
+	UserAgent = "regclient/regserve"
+)
+
+type rootOpts struct {
+	confFile  string
+	format    string
+	hosts     []string
+	logopts   []string
+	log       *slog.Logger
+	rcOpts    []regclient.Opt
+	verbosity string
+	// serve options
+	cache    string
+	listen   string
+	tlsCert  string
+	tlsKey   string
+	upstream string
+}
+
+func NewRootCmd() (*cobra.Command, *rootOpts) {
+	opts := rootOpts{}
+	cmd := &cobra.Command{
+		Use:   "regserve <cmd>",
+		Short: "Read-through proxy for OCI registries",
+		Long: `Read-through proxy for OCI registries
+More details at <https://github.com/regclient/regclient>`,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		PersistentPreRunE: opts.rootPreRun,
+	}
+	cmd.PersistentFlags().StringVarP(&opts.verbosity, "verbosity", "v", slog.LevelInfo.String(), "Log level (trace, debug, info, warn, error)")
+	cmd.PersistentFlags().StringArrayVar(&opts.logopts, "logopt", []string{}, "Log options")
+
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "run the proxy server",
+		Long: `Serve the OCI distribution API for pulling content from an upstream registry.
+Manifests and blobs are stored in an OCI Layout cache directory when --cache is set.
+Other storage backends for the cache are not supported.
+Manifests pulled by tag are refreshed from the upstream registry, and the cached copy is returned if the upstream is unavailable.
+Content pulled by digest is returned from the cache without contacting the upstream registry.
+Registry logins, TLS settings, and mirrors are loaded from the regctl configuration and Docker credentials.
+Push and delete requests are rejected.
+
+Clients are not authenticated.
+Anyone that can connect to the listener can pull any content the upstream credentials can access.
+The default listens on the loopback address.
+Only listen on other interfaces when access is restricted by a firewall or an authenticating reverse proxy.`,
+		Example: `
+# run a pull-through cache of Docker Hub
+regserve serve --upstream docker.io --cache /var/lib/regserve
+
+# listen on every interface, only when access to the port is restricted
+regserve serve --listen :5000 --upstream docker.io --cache /var/lib/regserve
+
+# proxy a private registry over TLS without a cache
+regserve serve --upstream registry.example.org --tls-cert server.crt --tls-key server.key`,
+		Args: cobra.ExactArgs(0),
+		RunE: opts.runServe,
+	}
+	serveCmd.Flags().StringVar(&opts.cache, "cache", "", "Directory for an OCI Layout cache of pulled content")
+	_ = serveCmd.MarkFlagDirname("cache")
+	serveCmd.Flags().StringVarP(&opts.confFile, "config", "c", "", "Config file (defaults to the regctl config)")
+	_ = serveCmd.MarkFlagFilename("config")
+	serveCmd.Flags().StringArrayVar(&opts.hosts, "host", []string{}, "Registry hosts to add (reg=registry,user=username,pass=password,tls=enabled)")
+	serveCmd.Flags().StringVar(&opts.listen, "listen", "127.0.0.1:5000", "Address to listen on, clients are not authenticated")
+	serveCmd.Flags().StringVar(&opts.tlsCert, "tls-cert", "", "TLS certificate file for the listener")
+	_ = serveCmd.MarkFlagFilename("tls-cert")
+	serveCmd.Flags().StringVar(&opts.tlsKey, "tls-key", "", "TLS key file for the listener")
+	_ = serveCmd.MarkFlagFilename("tls-key")
+	serveCmd.Flags().StringVar(&opts.upstream, "upstream", config.DockerRegistry, "Upstream registry")
+	serveCmd.MarkFlagsRequiredTogether("tls-cert", "tls-key")
+
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: "Show the version",
+		Long:  fmt.Sprintf(`Show the version of %s. Note that docker image builds will always be marked "dirty".`, cmd.Name()),
+		Example: fmt.Sprintf(`
+# display full version details
+%[1]s version
+
+# retrieve the version number
+%[1]s version --format '{{.VCSTag}}'`, cmd.Name()),
+		Args: cobra.ExactArgs(0),
+		RunE: opts.runVersion,
+	}
+	versionCmd.Flags().StringVar(&opts.format, "format", "{{printPretty .}}", "Format output with go template syntax")
+	_ = versionCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	opts.log = slog.New(slog.NewTextHandler(cmd.ErrOrStderr(), &slog.HandlerOptions{Level: slog.LevelInfo}))
+	cmd.AddCommand(
+		serveCmd,
+		versionCmd,
+		cobradoc.NewCmd(cmd.Name(), "cli-doc"),
+	)
+	return cmd, &opts
+}
+
+func (opts *rootOpts) rootPreRun(cmd *cobra.Command, args []string) error {
+	var lvl slog.Level
+	err := lvl.UnmarshalText([]byte(opts.verbosity))
+	if err != nil {
+		// handle custom levels
+		if opts.verbosity == strings.ToLower("trace") {
+			lvl = types.LevelTrace
+		} else {
+			return fmt.Errorf("unable to parse verbosity %s: %v", opts.verbosity, err)
+		}
+	}
+	formatJSON := false
+	for _, opt := range opts.logopts {
+		if opt == "json" {
+			formatJSON = true
+		}
+	}
+	if formatJSON {
+		opts.log = slog.New(slog.NewJSONHandler(cmd.ErrOrStderr(), &slog.HandlerOptions{Level: lvl}))
+	} else {
+		opts.log = slog.New(slog.NewTextHandler(cmd.ErrOrStderr(), &slog.HandlerOptions{Level: lvl}))
+	}
+	return nil
+}
+
+func (opts *rootOpts) runVersion(cmd *cobra.Command, args []string) error {
+	info := version.GetInfo()
+	return template.Writer(cmd.OutOrStdout(), opts.format, info)
+}
+
+// runServe runs the proxy until the context is canceled.
+func (opts *rootOpts) runServe(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	rc, err := opts.newRegClient()
+	if err != nil {
+		return err
+	}
+	p, err := newProxy(rc, opts.upstream, opts.cache, opts.log)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:              opts.listen,
+		Handler:           p,
+		ReadHeaderTimeout: time.Second * 30,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		opts.log.Info("Starting proxy",
+			slog.String("listen", opts.listen),
+			slog.String("upstream", opts.upstream),
+			slog.String("cache", opts.cache))
+		if opts.tlsCert != "" {
+			errCh <- srv.ListenAndServeTLS(opts.tlsCert, opts.tlsKey)
+		} else {
+			errCh <- srv.ListenAndServe()
+		}
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	opts.log.Info("Stopping proxy")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	p.wait()
+	if errS := <-errCh; errS != nil && !errors.Is(errS, http.ErrServerClosed) && err == nil {
+		err = errS
+	}
+	return err
+}
+
+func (opts *rootOpts) newRegClient() (*regclient.RegClient, error) {
+	conf, err := ConfigLoad(opts.confFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	rcOpts := []regclient.Opt{
+		regclient.WithSlog(opts.log),
+		regclient.WithRegOpts(reg.WithCache(time.Minute*5, 500)),
+	}
+	info := version.GetInfo()
+	if info.VCSTag != "" {
+		rcOpts = append(rcOpts, regclient.WithUserAgent(UserAgent+" ("+info.VCSTag+")"))
+	} else {
+		rcOpts = append(rcOpts, regclient.WithUserAgent(UserAgent+" ("+info.VCSRef+")"))
+	}
+	if conf.BlobLimit != 0 {
+		rcOpts = append(rcOpts, regclient.WithRegOpts(reg.WithBlobLimit(conf.BlobLimit)))
+	}
+	if conf.IncDockerCred == nil || *conf.IncDockerCred {
+		rcOpts = append(rcOpts, regclient.WithDockerCreds())
+	}
+	if conf.IncDockerCert == nil || *conf.IncDockerCert {
+		rcOpts = append(rcOpts, regclient.WithDockerCerts())
+	}
+	if conf.HostDefault != nil {
+		rcOpts = append(rcOpts, regclient.WithConfigHostDefault(*conf.HostDefault))
+	}
+	rcHosts := []config.Host{}
+	for _, host := range conf.Hosts {
+		rcHosts = append(rcHosts, *host)
+	}
+	for _, h := range opts.hosts {
+		hKV, err := strparse.SplitCSKV(h)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse host string %s: %w", h, err)
+		}
+		host := config.Host{
+			Name: hKV["reg"],
+			User: hKV["user"],
+			Pass: hKV["pass"],
+		}
+		if hKV["tls"] != "" {
+			err := host.TLS.UnmarshalText([]byte(hKV["tls"]))
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse tls setting for host %s: %w", h, err)
+			}
+		}
+		rcHosts = append(rcHosts, host)
+	}
+	if len(rcHosts) > 0 {
+		rcOpts = append(rcOpts, regclient.WithConfigHost(rcHosts...))
+	}
+	rcOpts = append(rcOpts, opts.rcOpts...)
+	return regclient.New(rcOpts...), nil
+}
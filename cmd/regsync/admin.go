@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// adminEntry is the admin API view of a scheduled sync entry.
+type adminEntry struct {
+	ID       int         `json:"id"`
+	Source   string      `json:"source"`
+	Target   string      `json:"target"`
+	Type     string      `json:"type"`
+	Schedule string      `json:"schedule"`
+	Paused   bool        `json:"paused"`
+	Running  bool        `json:"running"`
+	Next     time.Time   `json:"next,omitzero"`
+	Last     *syncResult `json:"last,omitempty"`
+}
+
+// adminHandler returns the admin API for the server listening on addr.
+// POST requests must have a JSON content type, see [adminCheck].
+//
+//	GET  /entries             list the scheduled sync entries
+//	GET  /entries/{id}        show a single sync entry
+//	POST /entries/{id}/run    sync the entry immediately, even when paused
+//	POST /entries/{id}/pause  skip scheduled runs of the entry
+//	POST /entries/{id}/resume resume scheduled runs of the entry
+func (srv *syncServer) adminHandler(addr net.Addr) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /entries", func(w http.ResponseWriter, r *http.Request) {
+		srv.mu.Lock()
+		list := []*syncEntry{}
+		for _, entries := range srv.entries {
+			list = append(list, entries...)
+		}
+		srv.mu.Unlock()
+		slices.SortFunc(list, func(a, b *syncEntry) int { return int(a.id - b.id) })
+		out := make([]adminEntry, 0, len(list))
+		for _, e := range list {
+			out = append(out, srv.adminEntry(e))
+		}
+		adminWrite(w, http.StatusOK, out)
+	})
+	mux.HandleFunc("GET /entries/{id}", func(w http.ResponseWriter, r *http.Request) {
+		e := srv.adminLookup(w, r)
+		if e == nil {
+			return
+		}
+		adminWrite(w, http.StatusOK, srv.adminEntry(e))
+	})
+	mux.HandleFunc("POST /entries/{id}/run", func(w http.ResponseWriter, r *http.Request) {
+		e := srv.adminLookup(w, r)
+		if e == nil {
+			return
+		}
+		if err := srv.trigger(e); err != nil {
+			adminError(w, http.StatusConflict, err.Error())
+			return
+		}
+		srv.opts.Load().log.Info("Sync triggered by admin request",
+			slog.String("source", e.sync.Source),
+			slog.String("target", e.sync.Target))
+		adminWrite(w, http.StatusAccepted, srv.adminEntry(e))
+	})
+	mux.HandleFunc("POST /entries/{id}/pause", func(w http.ResponseWriter, r *http.Request) {
+		e := srv.adminLookup(w, r)
+		if e == nil {
+			return
+		}
+		e.paused.Store(true)
+		srv.opts.Load().log.Info("Sync paused by admin request",
+			slog.String("source", e.sync.Source),
+			slog.String("target", e.sync.Target))
+		adminWrite(w, http.StatusOK, srv.adminEntry(e))
+	})
+	mux.HandleFunc("POST /entries/{id}/resume", func(w http.ResponseWriter, r *http.Request) {
+		e := srv.adminLookup(w, r)
+		if e == nil {
+			return
+		}
+		e.paused.Store(false)
+		srv.opts.Load().log.Info("Sync resumed by admin request",
+			slog.String("source", e.sync.Source),
+			slog.String("target", e.sync.Target))
+		adminWrite(w, http.StatusOK, srv.adminEntry(e))
+	})
+	return adminCheck(mux, addr)
+}
+
+// adminCheck rejects requests that may be sent by a web browser, since the API does not require authentication.
+// On a TCP listener, the Host header must be a loopback address with the listener port to prevent DNS rebinding.
+// POST requests must have a JSON content type, which a cross-site request cannot send without a CORS preflight.
+func adminCheck(next http.Handler, addr net.Addr) http.Handler {
+	port := ""
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		port = strconv.Itoa(tcpAddr.Port)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if port != "" && !adminHostValid(r.Host, port) {
+			adminError(w, http.StatusForbidden, "invalid host: "+r.Host)
+			return
+		}
+		if r.Method == http.MethodPost {
+			mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mt != "application/json" {
+				adminError(w, http.StatusUnsupportedMediaType, "content type must be application/json")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminHostValid returns true when the Host header is a loopback address with the listener port.
+func adminHostValid(hostport, port string) bool {
+	host, p, err := net.SplitHostPort(hostport)
+	if err != nil || p != port {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (srv *syncServer) adminEntry(e *syncEntry) adminEntry {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ae := adminEntry{
+		ID:       int(e.id),
+		Source:   e.sync.Source,
+		Target:   e.sync.Target,
+		Type:     e.sync.Type,
+		Schedule: syncSched(e.sync),
+		Paused:   e.paused.Load(),
+		Running:  e.running,
+		Next:     srv.cron.Entry(e.id).Next,
+	}
+	if e.last != nil {
+		last := *e.last
+		ae.Last = &last
+	}
+	return ae
+}
+
+// adminLookup returns the entry from the request path, writing an error response if it is not found.
+func (srv *syncServer) adminLookup(w http.ResponseWriter, r *http.Request) *syncEntry {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		adminError(w, http.StatusBadRequest, "invalid id: "+r.PathValue("id"))
+		return nil
+	}
+	e := srv.entry(cron.EntryID(id))
+	if e == nil {
+		adminError(w, http.StatusNotFound, "sync entry not found: "+r.PathValue("id"))
+		return nil
+	}
+	return e
+}
+
+func adminWrite(w http.ResponseWriter, status int, out any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(out)
+}
+
+func adminError(w http.ResponseWriter, status int, msg string) {
+	adminWrite(w, status, struct {
+		Error string `json:"error"`
+	}{Error: msg})
+}
+
+// adminListen listens on a TCP address or a "unix://" socket path for the admin API.
+// TCP listeners are restricted to loopback addresses since the API does not require authentication.
+func adminListen(addr string) (net.Listener, error) {
+	if sock, ok := strings.CutPrefix(addr, "unix://"); ok {
+		// remove a stale socket from a previous run
+		if fi, err := os.Lstat(sock); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(sock)
+		}
+		return net.Listen("unix", sock)
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid admin address %s: %w", addr, err)
+	}
+	if host != "localhost" {
+		ip := net.ParseIP(host)
+		if ip == nil || !ip.IsLoopback() {
+			return nil, fmt.Errorf("admin address must be a loopback address or unix socket: %s%.0w", addr, ErrInvalidInput)
+		}
+	}
+	return net.Listen("tcp", addr)
+}
+
+// adminServe runs the admin API until the context is canceled.
+func (srv *syncServer) adminServe(ctx context.Context, l net.Listener) {
+	log := srv.opts.Load().log
+	hs := &http.Server{
+		Handler:           srv.adminHandler(l.Addr()),
+		ReadHeaderTimeout: time.Second * 10,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		_ = hs.Shutdown(shutdownCtx)
+	}()
+	log.Info("Starting admin API",
+		slog.String("addr", l.Addr().String()))
+	err := hs.Serve(l)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error("Admin API failed",
+			slog.String("err", err.Error()))
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/regclient/regclient/pkg/template"
+	"github.com/regclient/regclient/types/ref"
+)
+
+// backupTimes expand the backup template at two fixed times to find the parts of the backup tag that change on each run.
+// Every field of the times differ so any time format in the template changes.
+var backupTimes = []time.Time{
+	time.Date(1901, time.January, 1, 1, 1, 1, 100000000, time.UTC),
+	time.Date(2098, time.December, 28, 22, 58, 58, 900000000, time.UTC),
+}
+
+// backupTime replaces the "time" template function to expand a backup template at a fixed time.
+type backupTime struct {
+	now time.Time
+}
+
+// Now returns the fixed time.
+func (t *backupTime) Now() time.Time {
+	return t.now
+}
+
+// Parse parses the value according to layout.
+func (t *backupTime) Parse(layout string, value string) (time.Time, error) {
+	return time.Parse(layout, value)
+}
+
+// expandBackup expands the backup template for the target image.
+// A backup without a ":" or "/" is the tag of the backup in the target repository.
+func expandBackup(s ConfigSync, tgt ref.Ref, opts ...template.Opt) (ref.Ref, error) {
+	data := struct {
+		Ref  ref.Ref
+		Step ConfigSync
+		Sync ConfigSync
+	}{Ref: tgt, Step: s, Sync: s}
+	backupStr, err := template.String(s.Backup, data, opts...)
+	if err != nil {
+		return tgt, fmt.Errorf("failed to expand backup template %s: %w", s.Backup, err)
+	}
+	backupStr = strings.TrimSpace(backupStr)
+	if !strings.ContainsAny(backupStr, ":/") {
+		return tgt.SetTag(backupStr), nil
+	}
+	// if the : or / are in the string, parse it as a full reference
+	r, err := ref.New(backupStr)
+	if err != nil {
+		return tgt, fmt.Errorf("failed to parse backup reference %s: %w", backupStr, err)
+	}
+	return r, nil
+}
+
+// backupPrune deletes the oldest backups of the target image, keeping the newest s.BackupKeep backups.
+// Backups are found by expanding the template at different times, and sorted by tag, so the template should include a sortable timestamp.
+// Templates that do not change over time overwrite a single backup and are not pruned.
+func (opts *rootOpts) backupPrune(ctx context.Context, s ConfigSync, tgt, backup ref.Ref) error {
+	if s.BackupKeep <= 0 {
+		return nil
+	}
+	tags := make([]string, len(backupTimes))
+	for i, now := range backupTimes {
+		r, err := expandBackup(s, tgt, template.WithFuncs(map[string]any{
+			"time": func() *backupTime { return &backupTime{now: now} },
+		}))
+		if err != nil {
+			return err
+		}
+		if !ref.EqualRepository(r, backup) {
+			return fmt.Errorf("backup repository changes over time, cannot prune backups of %s%.0w", tgt.CommonName(), ErrInvalidInput)
+		}
+		tags[i] = r.Tag
+	}
+	if tags[0] == tags[1] {
+		opts.log.Debug("Backup is overwritten, skipping prune",
+			slog.String("original", tgt.CommonName()),
+			slog.String("backup", backup.CommonName()))
+		return nil
+	}
+	prefixLen := 0
+	for prefixLen < min(len(tags[0]), len(tags[1])) && tags[0][prefixLen] == tags[1][prefixLen] {
+		prefixLen++
+	}
+	suffixLen := 0
+	for suffixLen < min(len(tags[0]), len(tags[1]))-prefixLen && tags[0][len(tags[0])-1-suffixLen] == tags[1][len(tags[1])-1-suffixLen] {
+		suffixLen++
+	}
+	prefix, suffix := tags[0][:prefixLen], tags[0][len(tags[0])-suffixLen:]
+
+	tl, err := opts.rc.TagList(ctx, backup)
+	if err != nil {
+		return fmt.Errorf("failed to list backups in %s: %w", backup.CommonName(), err)
+	}
+	tagList, err := tl.GetTags()
+	if err != nil {
+		return fmt.Errorf("failed to list backups in %s: %w", backup.CommonName(), err)
+	}
+	backups := []string{}
+	for _, tag := range tagList {
+		if len(tag) <= prefixLen+suffixLen || !strings.HasPrefix(tag, prefix) || !strings.HasSuffix(tag, suffix) {
+			continue
+		}
+		// never prune the original image
+		if ref.EqualRepository(backup, tgt) && tag == tgt.Tag {
+			continue
+		}
+		backups = append(backups, tag)
+	}
+	if len(backups) <= s.BackupKeep {
+		return nil
+	}
+	// the newest backups have the highest tag
+	slices.Sort(backups)
+	slices.Reverse(backups)
+	errList := []error{}
+	for _, tag := range backups[s.BackupKeep:] {
+		if tag == backup.Tag {
+			continue
+		}
+		opts.log.Info("Pruning backup",
+			slog.String("original", tgt.CommonName()),
+			slog.String("backup", backup.SetTag(tag).CommonName()))
+		err := opts.rc.TagDelete(ctx, backup.SetTag(tag))
+		if err != nil {
+			errList = append(errList, fmt.Errorf("failed to delete backup %s: %w", backup.SetTag(tag).CommonName(), err))
+		}
+	}
+	return errors.Join(errList...)
+}
@@ -54,11 +54,11 @@ func (opts *rootOpts) findSyncEntriesForTarget(tgt string) []ConfigSync {
 	return entries
 }
 
-// digestTagRe matches cosign-style digest tags of the form "<alg>-<hex>.(att|sig)",
-// e.g. "sha256-abc123.sig" or "sha256-abc123.att".
-var digestTagRe = regexp.MustCompile(`^([a-z0-9]+)-([0-9a-f]+)\.(att|sig)$`)
+// digestTagRe matches cosign-style digest tags of the form "<alg>-<hex>.(att|sbom|sig)",
+// e.g. "sha256-abc123.sig", "sha256-abc123.att", or "sha256-abc123.sbom".
+var digestTagRe = regexp.MustCompile(`^([a-z0-9]+)-([0-9a-f]+)\.(att|sbom|sig)$`)
 
-// isOrphanedDigestTag returns true when tag is a cosign-style .att or .sig tag
+// isOrphanedDigestTag returns true when tag is a cosign-style .att, .sbom, or .sig tag
 // whose referenced image digest is no longer present in the repository.
 // It returns false (never orphaned) when the digest still resolves, and an
 // error only for unexpected failures.
@@ -69,7 +69,7 @@ func isOrphanedDigestTag(ctx context.Context, rc *regclient.RegClient, tgtRef re
 		return false, nil
 	}
 	// Reconstruct the digest: replace the first "-" separator with ":".
-	// m[1] = algorithm (e.g. "sha256"), m[2] = hex, m[3] = "att" or "sig".
+	// m[1] = algorithm (e.g. "sha256"), m[2] = hex, m[3] = "att", "sbom", or "sig".
 	digestStr := m[1] + ":" + m[2]
 	digestRef := tgtRef.SetTag("").SetDigest(digestStr)
 	_, err := rc.ManifestHead(ctx, digestRef)
@@ -225,6 +225,14 @@ func (opts *rootOpts) cleanupTags(ctx context.Context, s ConfigSync, tgt string)
 		tagsToDelete = append(tagsToDelete, tag)
 	}
 
+	// Only list the deletes when planning
+	if opts.plan != nil {
+		for _, tag := range tagsToDelete {
+			opts.plan.add(planEntry{Action: "delete", Target: tgtRef.SetTag(tag).CommonName()})
+		}
+		return nil
+	}
+
 	// Delete unwanted tags
 	errs := []error{}
 	for _, tag := range tagsToDelete {
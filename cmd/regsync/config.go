@@ -2,15 +2,21 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"strings"
+	gotemplate "text/template"
 	"time"
 
 	"github.com/goccy/go-yaml"
 
 	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/internal/bwlimit"
+	"github.com/regclient/regclient/internal/units"
 	"github.com/regclient/regclient/pkg/template"
 	"github.com/regclient/regclient/types/mediatype"
+	"github.com/regclient/regclient/types/ref"
 )
 
 // delay checking for at least 5 minutes when rate limit is exceeded
@@ -22,6 +28,19 @@ var (
 		mediatype.OCI1Manifest,
 		mediatype.OCI1ManifestList,
 	}
+	// queue requests receiving a 429 or 503 response for up to 10 minutes by default
+	retryAfterMaxDefault = time.Minute * 10
+)
+
+const (
+	syncPolicyAll         = ""             // copy every matching tag
+	syncPolicyNewerSemver = "newer-semver" // copy tags newer than the highest semver tag in the target
+)
+
+const (
+	onErrorContinue   = "continue"   // process the remaining images of the entry and the remaining entries
+	onErrorAbortEntry = "abortEntry" // stop processing the entry, continuing with the remaining entries
+	onErrorAbortRun   = "abortRun"   // stop processing the entry and every other entry
 )
 
 // Config is parsed configuration file for regsync
@@ -35,11 +54,15 @@ type Config struct {
 // ConfigDefaults is uses for general options and defaults for ConfigSync entries
 type ConfigDefaults struct {
 	Backup             string                 `yaml:"backup" json:"backup"`
+	BackupKeep         int                    `yaml:"backupKeep" json:"backupKeep"` // number of backups to keep for each tag, all are kept when 0
+	Staging            string                 `yaml:"staging" json:"staging"`       // template of the staging tag, the image is validated there before updating the target tag
 	Interval           time.Duration          `yaml:"interval" json:"interval"`
 	Schedule           string                 `yaml:"schedule" json:"schedule"`
 	RateLimit          ConfigRateLimit        `yaml:"ratelimit" json:"ratelimit"`
+	BandwidthLimit     string                 `yaml:"bandwidthLimit" json:"bandwidthLimit"` // combined limit for all blob transfers (e.g. "50MiB/s")
 	Parallel           int                    `yaml:"parallel" json:"parallel"`
 	DigestTags         *bool                  `yaml:"digestTags" json:"digestTags"`
+	DigestTagPatterns  []string               `yaml:"digestTagPatterns" json:"digestTagPatterns"` // regexp of digest tag suffixes to copy, all when empty
 	Referrers          *bool                  `yaml:"referrers" json:"referrers"`
 	ReferrerFilters    []ConfigReferrerFilter `yaml:"referrerFilters" json:"referrerFilters"`
 	ReferrerSrc        string                 `yaml:"referrerSource" json:"referrerSource"`
@@ -47,16 +70,31 @@ type ConfigDefaults struct {
 	FastCheck          *bool                  `yaml:"fastCheck" json:"fastCheck"`
 	ForceRecursive     *bool                  `yaml:"forceRecursive" json:"forceRecursive"`
 	IncludeExternal    *bool                  `yaml:"includeExternal" json:"includeExternal"`
+	ExternalHosts      []string               `yaml:"externalHosts" json:"externalHosts"`     // limit included external layers to URLs on these hosts
+	CopyAnnotations    *bool                  `yaml:"copyAnnotations" json:"copyAnnotations"` // annotate the target tag with the source and time of the copy
+	Annotations        map[string]string      `yaml:"annotations" json:"annotations"`         // added to the target tag, implies copyAnnotations
+	Provenance         *bool                  `yaml:"provenance" json:"provenance"`           // push a provenance record as a referrer of the copied image
 	MediaTypes         []string               `yaml:"mediaTypes" json:"mediaTypes"`
 	Hooks              ConfigHooks            `yaml:"hooks" json:"hooks"`
 	CleanupTags        *bool                  `yaml:"cleanupTags" json:"cleanupTags"`
 	CleanupTagsExclude []string               `yaml:"cleanupTagsExclude" json:"cleanupTagsExclude"`
+	SyncPolicy         string                 `yaml:"syncPolicy" json:"syncPolicy"` // "newer-semver" only copies tags newer than the highest semver tag in the target
+	OnError            string                 `yaml:"onError" json:"onError"`       // "continue", "abortEntry", or "abortRun" when a copy fails
 	// general options
-	BlobLimit      int64         `yaml:"blobLimit" json:"blobLimit"`
-	CacheCount     int           `yaml:"cacheCount" json:"cacheCount"`
-	CacheTime      time.Duration `yaml:"cacheTime" json:"cacheTime"`
-	SkipDockerConf bool          `yaml:"skipDockerConfig" json:"skipDockerConfig"`
-	UserAgent      string        `yaml:"userAgent" json:"userAgent"`
+	AuditLog        string        `yaml:"auditLog" json:"auditLog"`
+	BlobLimit       int64         `yaml:"blobLimit" json:"blobLimit"`
+	CacheCount      int           `yaml:"cacheCount" json:"cacheCount"`
+	CacheTime       time.Duration `yaml:"cacheTime" json:"cacheTime"`
+	Faults          *ConfigFaults `yaml:"faults" json:"faults"`                   // failures injected to test retry and verification settings, never use in production
+	ManifestMaxSize string        `yaml:"manifestMaxSize" json:"manifestMaxSize"` // largest manifest to pull (e.g. "4MiB")
+	Memoize         bool          `yaml:"memoize" json:"memoize"`                 // reuse manifest and blob HEAD requests within each run
+	PinFile         string        `yaml:"pinFile" json:"pinFile"`                 // file of tags pinned to a digest, a mismatched source fails the copy
+	RetryAfterMax   time.Duration `yaml:"retryAfterMax" json:"retryAfterMax"`     // how long to queue rate limited requests, negative disables queueing
+	SkipDockerConf  bool          `yaml:"skipDockerConfig" json:"skipDockerConfig"`
+	UserAgent       string        `yaml:"userAgent" json:"userAgent"`
+
+	bwLimit     *bwlimit.Limiter
+	manifestMax int64
 }
 
 // ConfigRateLimit is for rate limit settings
@@ -65,6 +103,16 @@ type ConfigRateLimit struct {
 	Retry time.Duration `yaml:"retry" json:"retry"`
 }
 
+// ConfigFaults injects failures into registry requests, each rate is a fraction from 0 to 1
+type ConfigFaults struct {
+	ErrorRate    float64       `yaml:"errorRate" json:"errorRate"`
+	TruncateRate float64       `yaml:"truncateRate" json:"truncateRate"`
+	CorruptRate  float64       `yaml:"corruptRate" json:"corruptRate"`
+	SlowRate     float64       `yaml:"slowRate" json:"slowRate"`
+	SlowDelay    time.Duration `yaml:"slowDelay" json:"slowDelay"`
+	Seed         uint64        `yaml:"seed" json:"seed"`
+}
+
 // ConfigSync defines a source/target repository to sync
 type ConfigSync struct {
 	Source             string                 `yaml:"source" json:"source"`
@@ -74,6 +122,7 @@ type ConfigSync struct {
 	TagSets            []TagAllowDeny         `yaml:"tagSets" json:"tagSets"`
 	Repos              RepoAllowDeny          `yaml:"repos" json:"repos"`
 	DigestTags         *bool                  `yaml:"digestTags" json:"digestTags"`
+	DigestTagPatterns  []string               `yaml:"digestTagPatterns" json:"digestTagPatterns"` // regexp of digest tag suffixes to copy, all when empty
 	Referrers          *bool                  `yaml:"referrers" json:"referrers"`
 	ReferrerFilters    []ConfigReferrerFilter `yaml:"referrerFilters" json:"referrerFilters"`
 	ReferrerSrc        string                 `yaml:"referrerSource" json:"referrerSource"`
@@ -83,14 +132,25 @@ type ConfigSync struct {
 	FastCheck          *bool                  `yaml:"fastCheck" json:"fastCheck"`
 	ForceRecursive     *bool                  `yaml:"forceRecursive" json:"forceRecursive"`
 	IncludeExternal    *bool                  `yaml:"includeExternal" json:"includeExternal"`
+	ExternalHosts      []string               `yaml:"externalHosts" json:"externalHosts"`     // limit included external layers to URLs on these hosts
+	CopyAnnotations    *bool                  `yaml:"copyAnnotations" json:"copyAnnotations"` // annotate the target tag with the source and time of the copy
+	Annotations        map[string]string      `yaml:"annotations" json:"annotations"`         // added to the target tag, implies copyAnnotations
+	Provenance         *bool                  `yaml:"provenance" json:"provenance"`           // push a provenance record as a referrer of the copied image
 	Backup             string                 `yaml:"backup" json:"backup"`
+	BackupKeep         int                    `yaml:"backupKeep" json:"backupKeep"` // number of backups to keep for each tag, all are kept when 0
+	Staging            string                 `yaml:"staging" json:"staging"`       // template of the staging tag, the image is validated there before updating the target tag
 	Interval           time.Duration          `yaml:"interval" json:"interval"`
 	Schedule           string                 `yaml:"schedule" json:"schedule"`
 	RateLimit          ConfigRateLimit        `yaml:"ratelimit" json:"ratelimit"`
+	BandwidthLimit     string                 `yaml:"bandwidthLimit" json:"bandwidthLimit"` // limit for blob transfers of this entry, in addition to the defaults limit
 	MediaTypes         []string               `yaml:"mediaTypes" json:"mediaTypes"`
 	Hooks              ConfigHooks            `yaml:"hooks" json:"hooks"`
 	CleanupTags        *bool                  `yaml:"cleanupTags" json:"cleanupTags"`
 	CleanupTagsExclude []string               `yaml:"cleanupTagsExclude" json:"cleanupTagsExclude"`
+	SyncPolicy         string                 `yaml:"syncPolicy" json:"syncPolicy"` // "newer-semver" only copies tags newer than the highest semver tag in the target
+	OnError            string                 `yaml:"onError" json:"onError"`       // "continue", "abortEntry", or "abortRun" when a copy fails
+
+	bwLimit *bwlimit.Limiter
 }
 
 // RepoAllowDeny is an allow and deny list of regex strings for repository names
@@ -116,6 +176,8 @@ type ConfigHooks struct {
 	Pre       *ConfigHook `yaml:"pre" json:"pre"`
 	Post      *ConfigHook `yaml:"post" json:"post"`
 	Unchanged *ConfigHook `yaml:"unchanged" json:"unchanged"`
+	PostSync  []string    `yaml:"postSync" json:"postSync"` // command and args run after each image is copied
+	Validate  []string    `yaml:"validate" json:"validate"` // command and args run on the staging tag, a failure leaves the target unchanged
 }
 
 // ConfigHook identifies the hook type and params
@@ -158,6 +220,53 @@ func ConfigLoadReader(r io.Reader) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	// each bandwidth limiter is shared by every transfer it applies to
+	c.Defaults.bwLimit, err = bwlimit.Parse(c.Defaults.BandwidthLimit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range c.Sync {
+		err = configValidateTarget(c.Sync[i])
+		if err != nil {
+			return nil, fmt.Errorf("sync entry %s: %w", c.Sync[i].Source, err)
+		}
+		c.Sync[i].bwLimit, err = bwlimit.Parse(c.Sync[i].BandwidthLimit)
+		if err != nil {
+			return nil, fmt.Errorf("sync entry %s: %w", c.Sync[i].Source, err)
+		}
+		if c.Sync[i].BackupKeep < 0 {
+			return nil, fmt.Errorf("sync entry %s: backup keep must not be negative: %d%.0w", c.Sync[i].Source, c.Sync[i].BackupKeep, ErrInvalidInput)
+		}
+		if len(c.Sync[i].Hooks.Validate) > 0 && c.Sync[i].Staging == "" {
+			return nil, fmt.Errorf("sync entry %s: validate hook requires a staging tag%.0w", c.Sync[i].Source, ErrInvalidInput)
+		}
+		switch c.Sync[i].SyncPolicy {
+		case syncPolicyAll, syncPolicyNewerSemver:
+		default:
+			return nil, fmt.Errorf("sync entry %s: unknown sync policy %q%.0w", c.Sync[i].Source, c.Sync[i].SyncPolicy, ErrInvalidInput)
+		}
+		switch c.Sync[i].OnError {
+		case "", onErrorContinue, onErrorAbortEntry, onErrorAbortRun:
+		default:
+			return nil, fmt.Errorf("sync entry %s: unknown onError value %q, must be one of %s, %s, or %s%.0w", c.Sync[i].Source, c.Sync[i].OnError, onErrorContinue, onErrorAbortEntry, onErrorAbortRun, ErrInvalidInput)
+		}
+	}
+	if f := c.Defaults.Faults; f != nil {
+		for _, rate := range []float64{f.ErrorRate, f.TruncateRate, f.CorruptRate, f.SlowRate} {
+			if rate < 0 || rate > 1 {
+				return nil, fmt.Errorf("fault rates must be between 0 and 1: %v%.0w", rate, ErrInvalidInput)
+			}
+		}
+	}
+	if c.Defaults.ManifestMaxSize != "" {
+		c.Defaults.manifestMax, err = units.ParseSize(c.Defaults.ManifestMaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest max size %q: %w", c.Defaults.ManifestMaxSize, err)
+		}
+		if c.Defaults.manifestMax <= 0 {
+			return nil, fmt.Errorf("manifest max size must be greater than zero: %q", c.Defaults.ManifestMaxSize)
+		}
+	}
 	return c, nil
 }
 
@@ -185,8 +294,45 @@ func ConfigWrite(c *Config, w io.Writer) error {
 	return yaml.NewEncoder(w).Encode(c)
 }
 
+// configRefFuncs are available in the templates of sync references.
+// The returned refs include helpers like SetRegistry and JoinPath that validate the result.
+var configRefFuncs = gotemplate.FuncMap{
+	"ref":     ref.New,
+	"refHost": ref.NewHost,
+}
+
+// configValidateTarget verifies the target of a sync step is a valid reference.
+func configValidateTarget(s ConfigSync) error {
+	var err error
+	switch s.Type {
+	case "registry", "registryFilter":
+		_, err = registryTarget(s.Target, "")
+	case "repository", "image":
+		_, err = ref.New(s.Target)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid target %s: %w", s.Target, err)
+	}
+	return nil
+}
+
+// registryTarget returns the target repository for a repository synced by a registry step.
+// The target may include a repository path prefix.
+func registryTarget(tgt, repo string) (ref.Ref, error) {
+	host, prefix := tgt, ""
+	if !strings.Contains(tgt, "://") {
+		host, prefix, _ = strings.Cut(strings.TrimSuffix(tgt, "/"), "/")
+	}
+	r, err := ref.NewHost(host)
+	if err != nil {
+		return r, err
+	}
+	return r.JoinPath(prefix, repo)
+}
+
 // expand templates in various parts of the config
 func configExpandTemplates(c *Config) error {
+	refFuncs := template.WithFuncs(configRefFuncs)
 	dataSync := struct {
 		Sync ConfigSync
 	}{}
@@ -224,30 +370,30 @@ func configExpandTemplates(c *Config) error {
 	}
 	for i := range c.Sync {
 		dataSync.Sync = c.Sync[i]
-		val, err := template.String(c.Sync[i].Source, dataSync)
+		val, err := template.String(c.Sync[i].Source, dataSync, refFuncs)
 		if err != nil {
 			return err
 		}
 		c.Sync[i].Source = val
 		dataSync.Sync.Source = val
-		val, err = template.String(c.Sync[i].ReferrerSrc, dataSync)
+		val, err = template.String(c.Sync[i].ReferrerSrc, dataSync, refFuncs)
 		if err != nil {
 			return err
 		}
 		c.Sync[i].ReferrerSrc = val
 		dataSync.Sync.ReferrerSrc = val
-		val, err = template.String(c.Sync[i].Target, dataSync)
+		val, err = template.String(c.Sync[i].Target, dataSync, refFuncs)
 		if err != nil {
 			return err
 		}
 		c.Sync[i].Target = val
-		val, err = template.String(c.Sync[i].ReferrerTgt, dataSync)
+		val, err = template.String(c.Sync[i].ReferrerTgt, dataSync, refFuncs)
 		if err != nil {
 			return err
 		}
 		c.Sync[i].ReferrerTgt = val
 		dataSync.Sync.ReferrerTgt = val
-		// templates for Backup are expanded in each sync step
+		// templates for Backup and Staging are expanded in each sync step
 	}
 	return nil
 }
@@ -257,6 +403,12 @@ func syncSetDefaults(s *ConfigSync, d ConfigDefaults) {
 	if s.Backup == "" && d.Backup != "" {
 		s.Backup = d.Backup
 	}
+	if s.BackupKeep == 0 {
+		s.BackupKeep = d.BackupKeep
+	}
+	if s.Staging == "" {
+		s.Staging = d.Staging
+	}
 	if s.Schedule == "" && s.Interval == 0 {
 		if d.Schedule != "" {
 			s.Schedule = d.Schedule
@@ -283,6 +435,9 @@ func syncSetDefaults(s *ConfigSync, d ConfigDefaults) {
 		b := (d.DigestTags != nil && *d.DigestTags)
 		s.DigestTags = &b
 	}
+	if s.DigestTagPatterns == nil {
+		s.DigestTagPatterns = d.DigestTagPatterns
+	}
 	if s.Referrers == nil {
 		b := (d.Referrers != nil && *d.Referrers)
 		s.Referrers = &b
@@ -308,6 +463,20 @@ func syncSetDefaults(s *ConfigSync, d ConfigDefaults) {
 		b := (d.IncludeExternal != nil && *d.IncludeExternal)
 		s.IncludeExternal = &b
 	}
+	if s.ExternalHosts == nil {
+		s.ExternalHosts = d.ExternalHosts
+	}
+	if s.CopyAnnotations == nil {
+		b := (d.CopyAnnotations != nil && *d.CopyAnnotations)
+		s.CopyAnnotations = &b
+	}
+	if s.Annotations == nil {
+		s.Annotations = d.Annotations
+	}
+	if s.Provenance == nil {
+		b := (d.Provenance != nil && *d.Provenance)
+		s.Provenance = &b
+	}
 	if s.Hooks.Pre == nil && d.Hooks.Pre != nil {
 		s.Hooks.Pre = d.Hooks.Pre
 	}
@@ -317,6 +486,12 @@ func syncSetDefaults(s *ConfigSync, d ConfigDefaults) {
 	if s.Hooks.Unchanged == nil && d.Hooks.Unchanged != nil {
 		s.Hooks.Unchanged = d.Hooks.Unchanged
 	}
+	if s.Hooks.PostSync == nil && d.Hooks.PostSync != nil {
+		s.Hooks.PostSync = d.Hooks.PostSync
+	}
+	if s.Hooks.Validate == nil && d.Hooks.Validate != nil {
+		s.Hooks.Validate = d.Hooks.Validate
+	}
 	// Set cleanupTags default (follows existing pattern for bool pointers)
 	if s.CleanupTags == nil {
 		b := (d.CleanupTags != nil && *d.CleanupTags)
@@ -326,4 +501,10 @@ func syncSetDefaults(s *ConfigSync, d ConfigDefaults) {
 	if s.CleanupTagsExclude == nil && d.CleanupTagsExclude != nil {
 		s.CleanupTagsExclude = d.CleanupTagsExclude
 	}
+	if s.SyncPolicy == "" {
+		s.SyncPolicy = d.SyncPolicy
+	}
+	if s.OnError == "" {
+		s.OnError = d.OnError
+	}
 }
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/scheme"
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/ref"
+)
+
+const (
+	// configOCIPrefix selects a config stored as an OCI artifact instead of a file
+	configOCIPrefix = "oci://"
+	// configOCIMediaType is the preferred media type of the layer containing the config
+	configOCIMediaType = "application/vnd.regclient.regsync.config.v1+yaml"
+	// configOCIMaxSize limits the size of the config and signature payloads
+	configOCIMaxSize = 4 * 1024 * 1024
+	// configOCITimeout limits the time spent pulling the config artifact
+	configOCITimeout = 5 * time.Minute
+
+	cosignArtifactType        = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	cosignSimpleSigning       = "application/vnd.dev.cosign.simplesigning.v1+json"
+)
+
+// cosignPayload is the simple signing payload of a cosign signature.
+type cosignPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// configOCIClient returns the client used to pull the config artifact.
+// Logins from the docker config are used since the config has not been loaded.
+func (opts *rootOpts) configOCIClient() *regclient.RegClient {
+	if opts.confRC != nil {
+		return opts.confRC
+	}
+	rcOpts := []regclient.Opt{
+		regclient.WithSlog(opts.log),
+		regclient.WithDockerCreds(),
+		regclient.WithDockerCerts(),
+		regclient.WithUserAgent(defaultUserAgent()),
+	}
+	if opts.httpTrace != nil {
+		rcOpts = append(rcOpts, regclient.WithHTTPMiddleware(opts.httpTrace))
+	}
+	return regclient.New(rcOpts...)
+}
+
+// configLoadOCI pulls the config from an OCI artifact, verifying the signature when a key is configured.
+// The digest of the artifact manifest is returned to detect changes.
+func (opts *rootOpts) configLoadOCI(refStr string) (*Config, digest.Digest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), configOCITimeout)
+	defer cancel()
+	r, err := ref.New(refStr)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse config reference %s: %w", refStr, err)
+	}
+	rc := opts.configOCIClient()
+	defer rc.Close(ctx, r)
+	m, err := rc.ManifestGet(ctx, r)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get config artifact %s: %w", r.CommonName(), err)
+	}
+	dig := m.GetDescriptor().Digest
+	mi, ok := m.(manifest.Imager)
+	if !ok || m.IsList() {
+		return nil, "", fmt.Errorf("config artifact %s is not an image manifest, %s%.0w", r.CommonName(), m.GetDescriptor().MediaType, errs.ErrUnsupportedMediaType)
+	}
+	layers, err := mi.GetLayers()
+	if err != nil {
+		return nil, "", err
+	}
+	var layer descriptor.Descriptor
+	for _, l := range layers {
+		if l.MediaType == configOCIMediaType {
+			layer = l
+			break
+		}
+	}
+	if layer.Digest == "" {
+		if len(layers) != 1 {
+			return nil, "", fmt.Errorf("config artifact %s must contain a single layer or a layer with media type %s%.0w", r.CommonName(), configOCIMediaType, ErrInvalidInput)
+		}
+		layer = layers[0]
+	}
+	if opts.confVerifyKey != "" {
+		err = opts.configVerifyOCI(ctx, rc, r, dig)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	b, err := configOCIBlob(ctx, rc, r, layer)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get config from %s: %w", r.CommonName(), err)
+	}
+	conf, err := ConfigLoadReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, "", err
+	}
+	opts.log.Debug("Loaded config artifact",
+		slog.String("ref", r.CommonName()),
+		slog.String("digest", dig.String()))
+	return conf, dig, nil
+}
+
+// configOCIChanged returns true when the digest of the config artifact differs from the loaded config.
+func (opts *rootOpts) configOCIChanged(ctx context.Context) (bool, error) {
+	refStr, ok := strings.CutPrefix(opts.confFile, configOCIPrefix)
+	if !ok {
+		return false, nil
+	}
+	r, err := ref.New(refStr)
+	if err != nil {
+		return false, err
+	}
+	rc := opts.configOCIClient()
+	defer rc.Close(ctx, r)
+	m, err := rc.ManifestHead(ctx, r, regclient.WithManifestRequireDigest())
+	if err != nil {
+		return false, err
+	}
+	return m.GetDescriptor().Digest != opts.confDigest, nil
+}
+
+// configVerifyOCI verifies a cosign signature of the config artifact with the configured public key.
+// Signatures are found with the cosign digest tag and OCI referrers.
+func (opts *rootOpts) configVerifyOCI(ctx context.Context, rc *regclient.RegClient, r ref.Ref, dig digest.Digest) error {
+	pub, err := configLoadKey(opts.confVerifyKey)
+	if err != nil {
+		return err
+	}
+	sigRefs := []ref.Ref{r.SetTag(fmt.Sprintf("%s-%s.sig", dig.Algorithm(), dig.Encoded()))}
+	rl, err := rc.ReferrerList(ctx, r.SetDigest(dig.String()), scheme.WithReferrerMatchOpt(descriptor.MatchOpt{ArtifactType: cosignArtifactType}))
+	if err == nil {
+		for _, d := range rl.Descriptors {
+			sigRefs = append(sigRefs, r.SetDigest(d.Digest.String()))
+		}
+	}
+	for _, sigRef := range sigRefs {
+		m, err := rc.ManifestGet(ctx, sigRef)
+		if err != nil {
+			continue
+		}
+		mi, ok := m.(manifest.Imager)
+		if !ok {
+			continue
+		}
+		layers, err := mi.GetLayers()
+		if err != nil {
+			continue
+		}
+		for _, l := range layers {
+			if l.MediaType != cosignSimpleSigning || l.Annotations[cosignSignatureAnnotation] == "" {
+				continue
+			}
+			sig, err := base64.StdEncoding.DecodeString(l.Annotations[cosignSignatureAnnotation])
+			if err != nil {
+				continue
+			}
+			payload, err := configOCIBlob(ctx, rc, r, l)
+			if err != nil || !configVerifySig(pub, payload, sig) {
+				continue
+			}
+			p := cosignPayload{}
+			if err := json.Unmarshal(payload, &p); err != nil || p.Critical.Image.DockerManifestDigest != dig.String() {
+				continue
+			}
+			opts.log.Debug("Verified config signature",
+				slog.String("ref", r.CommonName()),
+				slog.String("signature", sigRef.CommonName()))
+			return nil
+		}
+	}
+	return fmt.Errorf("no valid signature found for config %s@%s%.0w", r.CommonName(), dig.String(), ErrSignatureInvalid)
+}
+
+// configOCIBlob returns the content of a small blob from the config repository.
+func configOCIBlob(ctx context.Context, rc *regclient.RegClient, r ref.Ref, d descriptor.Descriptor) ([]byte, error) {
+	if d.Size > configOCIMaxSize {
+		return nil, fmt.Errorf("blob %s exceeds the size limit of %d bytes%.0w", d.Digest.String(), configOCIMaxSize, errs.ErrSizeLimitExceeded)
+	}
+	br, err := rc.BlobGet(ctx, r, d)
+	if err != nil {
+		return nil, err
+	}
+	defer br.Close()
+	return io.ReadAll(br)
+}
+
+// configLoadKey reads a PEM encoded public key.
+func configLoadKey(filename string) (crypto.PublicKey, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM public key %s%.0w", filename, ErrInvalidInput)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %w", filename, err)
+	}
+	return pub, nil
+}
+
+// configVerifySig returns true when sig is a valid signature of the payload.
+func configVerifySig(pub crypto.PublicKey, payload, sig []byte) bool {
+	h := sha256.Sum256(payload)
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, h[:], sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], sig) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, payload, sig)
+	}
+	return false
+}
@@ -13,6 +13,12 @@ var (
 	ErrNotImplemented = errors.New("not implemented")
 	// ErrNotFound when anything else isn't found
 	ErrNotFound = errors.New("not found")
+	// ErrSyncFailed is returned when sync entries fail after every entry was processed
+	ErrSyncFailed = errors.New("sync failed")
+	// ErrSignatureInvalid is returned when a signed config cannot be verified
+	ErrSignatureInvalid = errors.New("signature verification failed")
+	// ErrValidationFailed is returned when the validate hook rejects a staged image
+	ErrValidationFailed = errors.New("validation failed")
 	// ErrUnsupportedConfigVersion happens when config file version is greater than this command supports
 	ErrUnsupportedConfigVersion = errors.New("unsupported config version")
 )
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/types/ref"
+)
+
+// postSyncHook runs the postSync command of a sync step after an image is copied.
+// The command is run directly without a shell, with environment variables describing the change.
+// Failures of the command are logged and do not fail the sync.
+func (opts *rootOpts) postSyncHook(ctx context.Context, s ConfigSync, src, tgt ref.Ref, dig, previous digest.Digest) {
+	if len(s.Hooks.PostSync) == 0 {
+		return
+	}
+	//#nosec G204 command is configured by the user running regsync
+	c := exec.CommandContext(ctx, s.Hooks.PostSync[0], s.Hooks.PostSync[1:]...)
+	c.Env = append(os.Environ(),
+		"REGSYNC_SOURCE="+src.CommonName(),
+		"REGSYNC_TARGET="+tgt.CommonName(),
+		"REGSYNC_TAG="+tgt.Tag,
+		"REGSYNC_DIGEST="+dig.String(),
+		"REGSYNC_PREVIOUS="+previous.String(),
+		"REGSYNC_TYPE="+s.Type,
+	)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	opts.log.Debug("Running post sync hook",
+		slog.String("target", tgt.CommonName()),
+		slog.Any("command", s.Hooks.PostSync))
+	if err := c.Run(); err != nil {
+		opts.log.Warn("Post sync hook failed",
+			slog.String("source", src.CommonName()),
+			slog.String("target", tgt.CommonName()),
+			slog.Any("command", s.Hooks.PostSync),
+			slog.String("err", err.Error()))
+	}
+}
+
+// validateHook runs the validate command of a sync step against the staged image.
+// The staged reference includes the digest that is promoted when the command succeeds.
+// The command is run directly without a shell, and a failure returns an error wrapping [ErrValidationFailed].
+func (opts *rootOpts) validateHook(ctx context.Context, s ConfigSync, src, stage, tgt ref.Ref, dig digest.Digest) error {
+	if len(s.Hooks.Validate) == 0 {
+		return nil
+	}
+	//#nosec G204 command is configured by the user running regsync
+	c := exec.CommandContext(ctx, s.Hooks.Validate[0], s.Hooks.Validate[1:]...)
+	c.Env = append(os.Environ(),
+		"REGSYNC_SOURCE="+src.CommonName(),
+		"REGSYNC_STAGING="+stage.CommonName(),
+		"REGSYNC_TARGET="+tgt.CommonName(),
+		"REGSYNC_TAG="+tgt.Tag,
+		"REGSYNC_DIGEST="+dig.String(),
+		"REGSYNC_STAGING_DIGEST="+stage.Digest,
+		"REGSYNC_TYPE="+s.Type,
+	)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	opts.log.Debug("Running validate hook",
+		slog.String("staging", stage.CommonName()),
+		slog.Any("command", s.Hooks.Validate))
+	if err := c.Run(); err != nil {
+		opts.log.Error("Validate hook failed",
+			slog.String("source", src.CommonName()),
+			slog.String("staging", stage.CommonName()),
+			slog.String("target", tgt.CommonName()),
+			slog.Any("command", s.Hooks.Validate),
+			slog.String("err", err.Error()))
+		return fmt.Errorf("validation of %s failed: %w%.0w", stage.CommonName(), err, ErrValidationFailed)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -27,6 +28,10 @@ func main() {
 
 	if err := rootTopCmd.ExecuteContext(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
+		// a sync that processed every entry and had failures is distinguished from an aborted run
+		if errors.Is(err, ErrSyncFailed) {
+			os.Exit(2)
+		}
 		os.Exit(1)
 	}
 	os.Exit(0)
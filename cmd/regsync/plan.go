@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/spf13/cobra"
+
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/internal/units"
+	"github.com/regclient/regclient/pkg/template"
+	"github.com/regclient/regclient/types/ref"
+)
+
+const planFormatDefault = `{{ range .Entries }}{{ .Action }} {{ if .Source }}{{ .Source }} -> {{ end }}{{ .Target }}{{ if .Bytes }} ({{ .Size }}){{ end }}
+{{ end }}{{ .Copies }} copies, {{ .Deletes }} deletes, {{ .Size }} to transfer
+`
+
+// syncPlan lists the changes a sync would make, output by the plan command.
+type syncPlan struct {
+	mu      sync.Mutex
+	Entries []planEntry `json:"entries"`
+	Copies  int         `json:"copies"`  // number of images copied or refreshed
+	Deletes int         `json:"deletes"` // number of tags deleted by cleanup
+	Bytes   int64       `json:"bytes"`   // estimated size of the blobs pulled or mounted for all copies
+}
+
+// planEntry is a single copy or delete in a [syncPlan].
+type planEntry struct {
+	Action    string `json:"action"` // copy, refresh, or delete
+	Source    string `json:"source,omitempty"`
+	Target    string `json:"target"`
+	Digest    string `json:"digest,omitempty"`
+	Manifests int    `json:"manifests,omitempty"` // number of manifests pushed
+	Blobs     int    `json:"blobs,omitempty"`     // number of blobs pulled or mounted
+	Bytes     int64  `json:"bytes,omitempty"`     // estimated size of the blobs pulled or mounted
+}
+
+// Size returns the estimated bytes of the plan in a human readable format.
+func (p *syncPlan) Size() string {
+	return units.HumanSize(float64(p.Bytes))
+}
+
+// Size returns the estimated bytes of the entry in a human readable format.
+func (e planEntry) Size() string {
+	return units.HumanSize(float64(e.Bytes))
+}
+
+func (p *syncPlan) add(e planEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Entries = append(p.Entries, e)
+	if e.Action == "delete" {
+		p.Deletes++
+	} else {
+		p.Copies++
+	}
+	p.Bytes += e.Bytes
+}
+
+// runPlan processes each sync step with a dry run copy and outputs the plan
+func (opts *rootOpts) runPlan(cmd *cobra.Command, args []string) error {
+	err := opts.loadConf()
+	if err != nil {
+		return err
+	}
+	opts.plan = &syncPlan{Entries: []planEntry{}}
+	errs := []error{}
+	ctx := cmd.Context()
+	aborted := false
+	for _, s := range opts.conf.Sync {
+		err := opts.process(ctx, s, actionPlan)
+		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, ErrCanceled) {
+			errs = append(errs, err)
+			if opts.abortRun(s) {
+				aborted = true
+				break
+			}
+		}
+	}
+	if err := syncErr(errs, aborted, len(opts.conf.Sync)); err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("format") {
+		opts.format = planFormatDefault
+	}
+	return template.Writer(cmd.OutOrStdout(), opts.format, opts.plan)
+}
+
+// planImage adds the copy of an image to the plan, using a dry run to estimate the transferred blobs.
+// Images where the dry run finds nothing to copy are skipped.
+func (opts *rootOpts) planImage(ctx context.Context, s ConfigSync, src, tgt ref.Ref, dig digest.Digest, refresh bool) error {
+	report, err := opts.rc.ImageCopyWithReport(ctx, src, tgt, append(opts.imageCopyOpts(s), regclient.ImageWithDryRun())...)
+	if err != nil {
+		opts.log.Error("Failed to plan image copy",
+			slog.String("source", src.CommonName()),
+			slog.String("target", tgt.CommonName()),
+			slog.String("error", err.Error()))
+		return err
+	}
+	if len(report.Plan) == 0 {
+		return nil
+	}
+	e := planEntry{
+		Action:    "copy",
+		Source:    src.CommonName(),
+		Target:    tgt.CommonName(),
+		Digest:    dig.String(),
+		Manifests: report.ManifestsPushed,
+		Blobs:     report.BlobsPulled + report.BlobsMounted,
+		Bytes:     report.BytesPulled + report.BytesMounted,
+	}
+	if refresh {
+		e.Action = "refresh"
+	}
+	opts.plan.add(e)
+	return nil
+}
@@ -3,21 +3,37 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io/fs"
 	"log/slog"
+	"net"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/olareg/olareg"
 	oConfig "github.com/olareg/olareg/config"
 	"github.com/opencontainers/go-digest"
+	"github.com/robfig/cron/v3"
 
 	"github.com/regclient/regclient"
 	"github.com/regclient/regclient/config"
@@ -28,6 +44,8 @@ import (
 	"github.com/regclient/regclient/types/descriptor"
 	"github.com/regclient/regclient/types/errs"
 	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/mediatype"
+	v1 "github.com/regclient/regclient/types/oci/v1"
 	"github.com/regclient/regclient/types/platform"
 	"github.com/regclient/regclient/types/ref"
 )
@@ -280,6 +298,37 @@ defaults:
 			},
 			expErr: nil,
 		},
+		{
+			name: "NewerSemver Seed",
+			sync: ConfigSync{
+				Source: tsHost + "/testrepo:v2",
+				Target: tsHost + "/testnewer:v2",
+				Type:   "image",
+			},
+			action: actionCopy,
+			expect: map[string]digest.Digest{
+				tsHost + "/testnewer:v2": d2,
+			},
+		},
+		{
+			name: "NewerSemver",
+			sync: ConfigSync{
+				Source: tsHost + "/testrepo",
+				Target: tsHost + "/testnewer",
+				Type:   "repository",
+				Tags: TagAllowDeny{
+					Allow: []string{`v\d`},
+				},
+				SyncPolicy: syncPolicyNewerSemver,
+			},
+			action: actionCopy,
+			expect: map[string]digest.Digest{
+				tsHost + "/testnewer:v3": d3,
+			},
+			missing: []string{
+				tsHost + "/testnewer:v1",
+			},
+		},
 		{
 			name: "ReadOnly Error Abort",
 			sync: ConfigSync{
@@ -978,6 +1027,149 @@ func TestProcessRef(t *testing.T) {
 	}
 }
 
+func TestProcessRegistryFilter(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	catalog := []string{"org/team-x/app", "org/team-x/tool", "org/team-y/app", "testrepo"}
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "../../testdata",
+		},
+	})
+	// olareg does not implement the _catalog API
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/_catalog" {
+			regHandler.ServeHTTP(w, r)
+			return
+		}
+		last := r.URL.Query().Get("last")
+		repos := []string{}
+		for _, repo := range catalog {
+			if repo > last {
+				repos = append(repos, repo)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string][]string{"repositories": repos})
+	}))
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+	rc := regclient.New(
+		regclient.WithConfigHost(config.Host{
+			Name: tsHost,
+			TLS:  config.TLSDisabled,
+		}),
+		regclient.WithRegOpts(reg.WithDelay(time.Millisecond*10, time.Millisecond*100)),
+	)
+	rSrc, err := ref.New(tsHost + "/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	for _, repo := range catalog[:3] {
+		rTgt, err := ref.New(tsHost + "/" + repo + ":v1")
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		err = rc.ImageCopy(ctx, rSrc, rTgt)
+		if err != nil {
+			t.Fatalf("failed to copy %s: %v", repo, err)
+		}
+	}
+	tt := []struct {
+		name    string
+		sync    ConfigSync
+		exists  []string
+		missing []string
+		expErr  error
+	}{
+		{
+			name: "prefix",
+			sync: ConfigSync{
+				Source: tsHost + "/org/team-x",
+				Target: "ocidir://" + tempDir + "/prefix",
+				Type:   "registryFilter",
+			},
+			exists:  []string{"prefix/app:v1", "prefix/tool:v1"},
+			missing: []string{"prefix/org/team-y/app:v1", "prefix/testrepo:v1"},
+		},
+		{
+			name: "prefix and deny",
+			sync: ConfigSync{
+				Source: tsHost + "/org/team-x/",
+				Target: "ocidir://" + tempDir + "/deny",
+				Type:   "registryFilter",
+				Repos:  RepoAllowDeny{Deny: []string{"tool"}},
+			},
+			exists:  []string{"deny/app:v1"},
+			missing: []string{"deny/tool:v1"},
+		},
+		{
+			name: "allow",
+			sync: ConfigSync{
+				Source: tsHost,
+				Target: "ocidir://" + tempDir + "/allow",
+				Type:   "registryFilter",
+				Repos:  RepoAllowDeny{Allow: []string{"org/team-y/.*"}},
+			},
+			exists:  []string{"allow/org/team-y/app:v1"},
+			missing: []string{"allow/org/team-x/app:v1", "allow/testrepo:v1"},
+		},
+		{
+			name: "missing filter",
+			sync: ConfigSync{
+				Source: tsHost,
+				Target: "ocidir://" + tempDir + "/missing",
+				Type:   "registryFilter",
+			},
+			expErr: ErrInvalidInput,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rootOpts := rootOpts{
+				conf: &Config{},
+				rc:   rc,
+				log:  slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+			}
+			syncSetDefaults(&tc.sync, ConfigDefaults{})
+			err := rootOpts.process(ctx, tc.sync, actionCopy)
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to process: %v", err)
+			}
+			for _, exist := range tc.exists {
+				r, err := ref.New("ocidir://" + tempDir + "/" + exist)
+				if err != nil {
+					t.Fatalf("cannot parse ref %s: %v", exist, err)
+				}
+				if _, err = rc.ManifestHead(ctx, r); err != nil {
+					t.Errorf("ref does not exist: %s", exist)
+				}
+			}
+			for _, missing := range tc.missing {
+				r, err := ref.New("ocidir://" + tempDir + "/" + missing)
+				if err != nil {
+					t.Fatalf("cannot parse ref %s: %v", missing, err)
+				}
+				if _, err = rc.ManifestHead(ctx, r); err == nil {
+					t.Errorf("ref exists that should be missing: %s", missing)
+				}
+			}
+		})
+	}
+}
+
 // TestFilterListVersionScheme tests the integration of semver filtering with tag filtering.
 // This focuses on real-world scenarios including:
 // - Tag patterns with suffixes (alpine, scratch, debian, etc.)
@@ -1236,6 +1428,8 @@ func TestConfigRead(t *testing.T) {
 						FastCheck:       &bFalse,
 						ForceRecursive:  &bFalse,
 						IncludeExternal: &bFalse,
+						CopyAnnotations: &bFalse,
+						Provenance:      &bFalse,
 						CleanupTags:     &bFalse,
 					},
 					{
@@ -1257,6 +1451,8 @@ func TestConfigRead(t *testing.T) {
 						FastCheck:       &bFalse,
 						ForceRecursive:  &bFalse,
 						IncludeExternal: &bFalse,
+						CopyAnnotations: &bFalse,
+						Provenance:      &bFalse,
 						CleanupTags:     &bFalse,
 					},
 					{
@@ -1278,6 +1474,8 @@ func TestConfigRead(t *testing.T) {
 						FastCheck:       &bFalse,
 						ForceRecursive:  &bFalse,
 						IncludeExternal: &bFalse,
+						CopyAnnotations: &bFalse,
+						Provenance:      &bFalse,
 						CleanupTags:     &bFalse,
 					},
 				},
@@ -1299,6 +1497,7 @@ func TestConfigRead(t *testing.T) {
 					RateLimit: ConfigRateLimit{
 						Retry: rateLimitRetryMin,
 					},
+					AuditLog: "/var/log/regsync/audit.json",
 				},
 				Sync: []ConfigSync{
 					{
@@ -1315,6 +1514,8 @@ func TestConfigRead(t *testing.T) {
 						FastCheck:       &bFalse,
 						ForceRecursive:  &bFalse,
 						IncludeExternal: &bFalse,
+						CopyAnnotations: &bFalse,
+						Provenance:      &bFalse,
 						CleanupTags:     &bFalse,
 					},
 					{
@@ -1331,6 +1532,8 @@ func TestConfigRead(t *testing.T) {
 						FastCheck:       &bFalse,
 						ForceRecursive:  &bFalse,
 						IncludeExternal: &bFalse,
+						CopyAnnotations: &bFalse,
+						Provenance:      &bFalse,
 						CleanupTags:     &bFalse,
 					},
 				},
@@ -1356,1574 +1559,3489 @@ func TestConfigRead(t *testing.T) {
 	}
 }
 
-// TestConfigCleanupParsing tests parsing of cleanupTags and cleanupTagsExclude fields
-func TestConfigCleanupParsing(t *testing.T) {
+func TestConfigManifestMaxSize(t *testing.T) {
 	t.Parallel()
-	bTrue := true
-	bFalse := false
-
 	tt := []struct {
-		name   string
-		file   string
-		expect Config
-		expErr error
+		name      string
+		conf      string
+		expect    int64
+		expectErr bool
 	}{
 		{
-			name: "cleanup with defaults",
-			file: "config-cleanup.yml",
-			expect: Config{
-				Version: 1,
-				Creds: []config.Host{
-					{
-						Name: "registry:5000",
-						TLS:  config.TLSDisabled,
-					},
-				},
-				Defaults: ConfigDefaults{
-					CleanupTags: &bTrue,
-					CleanupTagsExclude: []string{
-						".*\\.sig$",
-						".*\\.att$",
-						"backup-.*",
-					},
-					RateLimit: ConfigRateLimit{
-						Retry: rateLimitRetryMin,
-					},
-				},
-				Sync: []ConfigSync{
-					{
-						Source:      "test/repo1",
-						Target:      "registry:5000/test/repo1",
-						Type:        "repository",
-						CleanupTags: &bTrue,
-						CleanupTagsExclude: []string{
-							".*\\.sig$",
-							".*\\.att$",
-							"backup-.*",
-						},
-						RateLimit: ConfigRateLimit{
-							Retry: rateLimitRetryMin,
-						},
-						MediaTypes:      defaultMediaTypes,
-						DigestTags:      &bFalse,
-						Referrers:       &bFalse,
-						FastCheck:       &bFalse,
-						ForceRecursive:  &bFalse,
-						IncludeExternal: &bFalse,
-					},
-					{
-						Source:      "test/repo2",
-						Target:      "registry:5000/test/repo2",
-						Type:        "repository",
-						CleanupTags: &bFalse,
-						CleanupTagsExclude: []string{
-							".*\\.sig$",
-							".*\\.att$",
-							"backup-.*",
-						},
-						RateLimit: ConfigRateLimit{
-							Retry: rateLimitRetryMin,
-						},
-						MediaTypes:      defaultMediaTypes,
-						DigestTags:      &bFalse,
-						Referrers:       &bFalse,
-						FastCheck:       &bFalse,
-						ForceRecursive:  &bFalse,
-						IncludeExternal: &bFalse,
-					},
-					{
-						Source:      "test/repo3",
-						Target:      "registry:5000/test/repo3",
-						Type:        "repository",
-						CleanupTags: &bTrue,
-						CleanupTagsExclude: []string{
-							".*\\.sig$",
-							".*\\.att$",
-							"backup-.*",
-						},
-						RateLimit: ConfigRateLimit{
-							Retry: rateLimitRetryMin,
-						},
-						MediaTypes:      defaultMediaTypes,
-						DigestTags:      &bFalse,
-						Referrers:       &bFalse,
-						FastCheck:       &bFalse,
-						ForceRecursive:  &bFalse,
-						IncludeExternal: &bFalse,
-					},
-					{
-						Source:      "test/repo4",
-						Target:      "registry:5000/test/repo4",
-						Type:        "repository",
-						CleanupTags: &bTrue,
-						CleanupTagsExclude: []string{
-							"prod-.*",
-							"stable",
-						},
-						RateLimit: ConfigRateLimit{
-							Retry: rateLimitRetryMin,
-						},
-						MediaTypes:      defaultMediaTypes,
-						DigestTags:      &bFalse,
-						Referrers:       &bFalse,
-						FastCheck:       &bFalse,
-						ForceRecursive:  &bFalse,
-						IncludeExternal: &bFalse,
-					},
-					{
-						Source:             "test/repo5",
-						Target:             "registry:5000/test/repo5",
-						Type:               "repository",
-						CleanupTags:        &bTrue,
-						CleanupTagsExclude: []string{},
-						RateLimit: ConfigRateLimit{
-							Retry: rateLimitRetryMin,
-						},
-						MediaTypes:      defaultMediaTypes,
-						DigestTags:      &bFalse,
-						Referrers:       &bFalse,
-						FastCheck:       &bFalse,
-						ForceRecursive:  &bFalse,
-						IncludeExternal: &bFalse,
-					},
-					{
-						Source:      "test/repo6",
-						Target:      "registry:5000/test/repo6",
-						Type:        "repository",
-						CleanupTags: &bTrue,
-						CleanupTagsExclude: []string{
-							".*\\.sig$",
-							".*\\.att$",
-							"backup-.*",
-						},
-						RateLimit: ConfigRateLimit{
-							Retry: rateLimitRetryMin,
-						},
-						MediaTypes:      defaultMediaTypes,
-						DigestTags:      &bFalse,
-						Referrers:       &bFalse,
-						FastCheck:       &bFalse,
-						ForceRecursive:  &bFalse,
-						IncludeExternal: &bFalse,
-					},
-				},
-			},
+			name: "unset",
+			conf: `
+defaults:
+  parallel: 1
+`,
 		},
 		{
-			name: "cleanup without defaults",
-			file: "config-cleanup-no-defaults.yml",
-			expect: Config{
-				Version: 1,
-				Creds: []config.Host{
-					{
-						Name: "registry:5000",
-						TLS:  config.TLSDisabled,
-					},
-				},
-				Defaults: ConfigDefaults{
-					RateLimit: ConfigRateLimit{
-						Retry: rateLimitRetryMin,
-					},
-				},
-				Sync: []ConfigSync{
-					{
-						Source:             "test/repo1",
-						Target:             "registry:5000/test/repo1",
-						Type:               "repository",
-						CleanupTags:        &bTrue,
-						CleanupTagsExclude: nil,
-						RateLimit: ConfigRateLimit{
-							Retry: rateLimitRetryMin,
-						},
-						MediaTypes:      defaultMediaTypes,
-						DigestTags:      &bFalse,
-						Referrers:       &bFalse,
-						FastCheck:       &bFalse,
-						ForceRecursive:  &bFalse,
-						IncludeExternal: &bFalse,
-					},
-					{
-						Source:             "test/repo2",
-						Target:             "registry:5000/test/repo2",
-						Type:               "repository",
-						CleanupTags:        &bFalse,
-						CleanupTagsExclude: nil,
-						RateLimit: ConfigRateLimit{
-							Retry: rateLimitRetryMin,
-						},
-						MediaTypes:      defaultMediaTypes,
-						DigestTags:      &bFalse,
-						Referrers:       &bFalse,
-						FastCheck:       &bFalse,
-						ForceRecursive:  &bFalse,
-						IncludeExternal: &bFalse,
-					},
-					{
-						Source:             "test/repo3",
-						Target:             "registry:5000/test/repo3",
-						Type:               "repository",
-						CleanupTags:        &bTrue,
-						CleanupTagsExclude: nil,
-						RateLimit: ConfigRateLimit{
-							Retry: rateLimitRetryMin,
-						},
-						MediaTypes:      defaultMediaTypes,
-						DigestTags:      &bFalse,
-						Referrers:       &bFalse,
-						FastCheck:       &bFalse,
-						ForceRecursive:  &bFalse,
-						IncludeExternal: &bFalse,
-					},
-				},
-			},
+			name: "binary",
+			conf: `
+defaults:
+  manifestMaxSize: 4MiB
+`,
+			expect: 4 * 1024 * 1024,
+		},
+		{
+			name: "invalid",
+			conf: `
+defaults:
+  manifestMaxSize: huge
+`,
+			expectErr: true,
+		},
+		{
+			name: "zero",
+			conf: `
+defaults:
+  manifestMaxSize: "0"
+`,
+			expectErr: true,
 		},
 	}
-
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			cRead, err := ConfigLoadFile(filepath.Join("./testdata", tc.file))
-			if tc.expErr != nil {
-				if !errors.Is(err, tc.expErr) {
-					t.Errorf("expected error %v, received %v", tc.expErr, err)
+			c, err := ConfigLoadReader(bytes.NewReader([]byte(tc.conf)))
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("config load did not fail")
 				}
 				return
 			}
 			if err != nil {
-				t.Fatalf("failed to read: %v", err)
+				t.Fatalf("failed to load config: %v", err)
 			}
-			if !reflect.DeepEqual(tc.expect, *cRead) {
-				t.Errorf("parsing mismatch, expected:\n%#v\n  received:\n%#v", tc.expect, *cRead)
+			if c.Defaults.manifestMax != tc.expect {
+				t.Errorf("unexpected manifest max size, expected %d, received %d", tc.expect, c.Defaults.manifestMax)
 			}
 		})
 	}
 }
 
-// TestConfigCleanupDefaults tests the defaults inheritance mechanism for cleanup fields
-func TestConfigCleanupDefaults(t *testing.T) {
+func TestConfigFaults(t *testing.T) {
 	t.Parallel()
-	bTrue := true
-	bFalse := false
-
 	tt := []struct {
-		name     string
-		sync     ConfigSync
-		defaults ConfigDefaults
-		expect   ConfigSync
+		name      string
+		conf      string
+		expect    *ConfigFaults
+		expectErr error
 	}{
 		{
-			name: "cleanupTags true in defaults, not in sync",
-			sync: ConfigSync{
-				Source: "test/repo",
-				Target: "registry:5000/test/repo",
-				Type:   "repository",
-			},
-			defaults: ConfigDefaults{
-				CleanupTags: &bTrue,
-			},
-			expect: ConfigSync{
-				Source:      "test/repo",
-				Target:      "registry:5000/test/repo",
-				Type:        "repository",
-				CleanupTags: &bTrue,
-			},
+			name: "unset",
+			conf: `
+defaults:
+  parallel: 1
+`,
 		},
 		{
-			name: "cleanupTags false in defaults, not in sync",
-			sync: ConfigSync{
-				Source: "test/repo",
-				Target: "registry:5000/test/repo",
-				Type:   "repository",
-			},
-			defaults: ConfigDefaults{
-				CleanupTags: &bFalse,
-			},
-			expect: ConfigSync{
-				Source:      "test/repo",
-				Target:      "registry:5000/test/repo",
-				Type:        "repository",
-				CleanupTags: &bFalse,
-			},
+			name: "rates",
+			conf: `
+defaults:
+  faults:
+    errorRate: 0.1
+    corruptRate: 0.05
+    slowRate: 1
+    slowDelay: 2s
+    seed: 42
+`,
+			expect: &ConfigFaults{ErrorRate: 0.1, CorruptRate: 0.05, SlowRate: 1, SlowDelay: time.Second * 2, Seed: 42},
 		},
 		{
-			name: "cleanupTags not in defaults, not in sync (default to false)",
-			sync: ConfigSync{
-				Source: "test/repo",
-				Target: "registry:5000/test/repo",
-				Type:   "repository",
-			},
-			defaults: ConfigDefaults{},
-			expect: ConfigSync{
-				Source:      "test/repo",
-				Target:      "registry:5000/test/repo",
-				Type:        "repository",
-				CleanupTags: &bFalse,
-			},
+			name: "invalid rate",
+			conf: `
+defaults:
+  faults:
+    truncateRate: 1.5
+`,
+			expectErr: ErrInvalidInput,
 		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := ConfigLoadReader(bytes.NewReader([]byte(tc.conf)))
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to load config: %v", err)
+			}
+			if (tc.expect == nil) != (c.Defaults.Faults == nil) || (tc.expect != nil && *tc.expect != *c.Defaults.Faults) {
+				t.Errorf("unexpected faults, expected %v, received %v", tc.expect, c.Defaults.Faults)
+			}
+		})
+	}
+}
+
+func TestConfigSyncPolicy(t *testing.T) {
+	t.Parallel()
+	tt := []struct {
+		name      string
+		conf      string
+		expect    []string
+		expectErr error
+	}{
 		{
-			name: "sync entry overrides defaults (true overrides false)",
-			sync: ConfigSync{
-				Source:      "test/repo",
-				Target:      "registry:5000/test/repo",
-				Type:        "repository",
-				CleanupTags: &bTrue,
-			},
-			defaults: ConfigDefaults{
-				CleanupTags: &bFalse,
-			},
-			expect: ConfigSync{
-				Source:      "test/repo",
-				Target:      "registry:5000/test/repo",
-				Type:        "repository",
-				CleanupTags: &bTrue,
-			},
+			name: "default",
+			conf: `
+sync:
+  - source: registry.example.org/proj
+    target: registry.example.com/proj
+    type: repository
+`,
+			expect: []string{syncPolicyAll},
 		},
 		{
-			name: "sync entry overrides defaults (false overrides true)",
-			sync: ConfigSync{
-				Source:      "test/repo",
-				Target:      "registry:5000/test/repo",
-				Type:        "repository",
-				CleanupTags: &bFalse,
-			},
-			defaults: ConfigDefaults{
-				CleanupTags: &bTrue,
-			},
-			expect: ConfigSync{
-				Source:      "test/repo",
-				Target:      "registry:5000/test/repo",
-				Type:        "repository",
-				CleanupTags: &bFalse,
-			},
+			name: "newer semver",
+			conf: `
+defaults:
+  syncPolicy: newer-semver
+sync:
+  - source: registry.example.org/proj
+    target: registry.example.com/proj
+    type: repository
+  - source: registry.example.org/app
+    target: registry.example.com/app
+    type: repository
+    syncPolicy: newer-semver
+`,
+			expect: []string{syncPolicyNewerSemver, syncPolicyNewerSemver},
 		},
 		{
-			name: "cleanupTagsExclude in defaults, not in sync",
-			sync: ConfigSync{
-				Source: "test/repo",
-				Target: "registry:5000/test/repo",
-				Type:   "repository",
-			},
-			defaults: ConfigDefaults{
-				CleanupTagsExclude: []string{".*\\.sig$", ".*\\.att$"},
-			},
-			expect: ConfigSync{
-				Source:             "test/repo",
-				Target:             "registry:5000/test/repo",
-				Type:               "repository",
-				CleanupTags:        &bFalse,
-				CleanupTagsExclude: []string{".*\\.sig$", ".*\\.att$"},
-			},
+			name: "invalid",
+			conf: `
+sync:
+  - source: registry.example.org/proj
+    target: registry.example.com/proj
+    type: repository
+    syncPolicy: newest
+`,
+			expectErr: ErrInvalidInput,
 		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := ConfigLoadReader(bytes.NewReader([]byte(tc.conf)))
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to load config: %v", err)
+			}
+			policies := []string{}
+			for _, s := range c.Sync {
+				policies = append(policies, s.SyncPolicy)
+			}
+			if !slices.Equal(policies, tc.expect) {
+				t.Errorf("unexpected sync policies, expected %v, received %v", tc.expect, policies)
+			}
+		})
+	}
+}
+
+func TestFilterNewerSemver(t *testing.T) {
+	t.Parallel()
+	tt := []struct {
+		name      string
+		src       []string
+		tgt       []string
+		expect    []string
+		expectMax string
+	}{
 		{
-			name: "cleanupTagsExclude not in defaults, not in sync (nil)",
-			sync: ConfigSync{
-				Source: "test/repo",
-				Target: "registry:5000/test/repo",
-				Type:   "repository",
-			},
-			defaults: ConfigDefaults{},
-			expect: ConfigSync{
-				Source:             "test/repo",
-				Target:             "registry:5000/test/repo",
-				Type:               "repository",
-				CleanupTags:        &bFalse,
-				CleanupTagsExclude: nil,
-			},
+			name:   "empty target",
+			src:    []string{"latest", "1.0.0", "1.1.0"},
+			tgt:    []string{},
+			expect: []string{"1.0.0", "1.1.0"},
 		},
 		{
-			name: "sync entry overrides defaults exclusion patterns",
-			sync: ConfigSync{
-				Source:             "test/repo",
-				Target:             "registry:5000/test/repo",
-				Type:               "repository",
-				CleanupTagsExclude: []string{"prod-.*"},
-			},
-			defaults: ConfigDefaults{
-				CleanupTagsExclude: []string{".*\\.sig$", ".*\\.att$"},
-			},
-			expect: ConfigSync{
-				Source:             "test/repo",
-				Target:             "registry:5000/test/repo",
-				Type:               "repository",
-				CleanupTags:        &bFalse,
-				CleanupTagsExclude: []string{"prod-.*"},
-			},
+			name:      "newer only",
+			src:       []string{"1.0.0", "1.2.0", "1.2.1", "2.0.0-rc1", "2.0.0"},
+			tgt:       []string{"latest", "1.0.0", "1.2.0", "1.1.0"},
+			expect:    []string{"1.2.1", "2.0.0-rc1", "2.0.0"},
+			expectMax: "1.2.0",
 		},
 		{
-			name: "sync entry empty array overrides defaults (disable exclusions)",
-			sync: ConfigSync{
-				Source:             "test/repo",
-				Target:             "registry:5000/test/repo",
-				Type:               "repository",
-				CleanupTagsExclude: []string{},
-			},
-			defaults: ConfigDefaults{
-				CleanupTagsExclude: []string{".*\\.sig$", ".*\\.att$"},
-			},
-			expect: ConfigSync{
-				Source:             "test/repo",
-				Target:             "registry:5000/test/repo",
-				Type:               "repository",
-				CleanupTags:        &bFalse,
-				CleanupTagsExclude: []string{},
-			},
+			name:      "prerelease in target",
+			src:       []string{"2.0.0-rc1", "2.0.0-rc2", "2.0.0"},
+			tgt:       []string{"2.0.0-rc1"},
+			expect:    []string{"2.0.0-rc2", "2.0.0"},
+			expectMax: "2.0.0-rc1",
+		},
+		{
+			name:      "nothing newer",
+			src:       []string{"v1", "v2"},
+			tgt:       []string{"v3"},
+			expect:    []string{},
+			expectMax: "v3",
 		},
 	}
-
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			syncSetDefaults(&tc.sync, tc.defaults)
-
-			// Check CleanupTags
-			if tc.sync.CleanupTags == nil && tc.expect.CleanupTags != nil {
-				t.Errorf("CleanupTags is nil, expected %v", *tc.expect.CleanupTags)
-			} else if tc.sync.CleanupTags != nil && tc.expect.CleanupTags == nil {
-				t.Errorf("CleanupTags is %v, expected nil", *tc.sync.CleanupTags)
-			} else if tc.sync.CleanupTags != nil && tc.expect.CleanupTags != nil && *tc.sync.CleanupTags != *tc.expect.CleanupTags {
-				t.Errorf("CleanupTags mismatch: got %v, expected %v", *tc.sync.CleanupTags, *tc.expect.CleanupTags)
+			result, tMax := filterNewerSemver(tc.src, tc.tgt)
+			if !slices.Equal(result, tc.expect) {
+				t.Errorf("unexpected tags, expected %v, received %v", tc.expect, result)
 			}
-
-			// Check CleanupTagsExclude
-			if !reflect.DeepEqual(tc.sync.CleanupTagsExclude, tc.expect.CleanupTagsExclude) {
-				t.Errorf("CleanupTagsExclude mismatch: got %v, expected %v", tc.sync.CleanupTagsExclude, tc.expect.CleanupTagsExclude)
+			if tMax != tc.expectMax {
+				t.Errorf("unexpected highest tag, expected %s, received %s", tc.expectMax, tMax)
 			}
 		})
 	}
 }
 
-// TestMatchesExclusionPattern tests the matchesExclusionPattern function
-func TestMatchesExclusionPattern(t *testing.T) {
+func TestConfigExternalHosts(t *testing.T) {
+	t.Parallel()
+	conf := `
+defaults:
+  includeExternal: true
+  externalHosts:
+    - mcr.microsoft.com
+sync:
+  - source: registry.example.org/windows:ltsc2022
+    target: registry.example.com/windows:ltsc2022
+    type: image
+  - source: registry.example.org/windows:ltsc2019
+    target: registry.example.com/windows:ltsc2019
+    type: image
+    externalHosts:
+      - registry.example.org
+`
+	c, err := ConfigLoadReader(bytes.NewReader([]byte(conf)))
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if len(c.Sync) != 2 {
+		t.Fatalf("unexpected sync entries: %d", len(c.Sync))
+	}
+	expect := [][]string{{"mcr.microsoft.com"}, {"registry.example.org"}}
+	for i, s := range c.Sync {
+		if s.IncludeExternal == nil || !*s.IncludeExternal {
+			t.Errorf("sync %d did not include external layers", i)
+		}
+		if !slices.Equal(s.ExternalHosts, expect[i]) {
+			t.Errorf("sync %d unexpected external hosts, expected %v, received %v", i, expect[i], s.ExternalHosts)
+		}
+	}
+}
+
+func TestConfigCopyAnnotations(t *testing.T) {
 	t.Parallel()
+	conf := `
+defaults:
+  copyAnnotations: true
+sync:
+  - source: registry.example.org/repo:v1
+    target: registry.example.com/repo:v1
+    type: image
+  - source: registry.example.org/repo:v2
+    target: registry.example.com/repo:v2
+    type: image
+    copyAnnotations: false
+    annotations:
+      org.example.mirror: primary
+`
+	c, err := ConfigLoadReader(bytes.NewReader([]byte(conf)))
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if len(c.Sync) != 2 {
+		t.Fatalf("unexpected sync entries: %d", len(c.Sync))
+	}
+	if c.Sync[0].CopyAnnotations == nil || !*c.Sync[0].CopyAnnotations || c.Sync[0].Annotations != nil {
+		t.Errorf("sync 0 unexpected copy annotations: %v, %v", c.Sync[0].CopyAnnotations, c.Sync[0].Annotations)
+	}
+	if c.Sync[1].CopyAnnotations == nil || *c.Sync[1].CopyAnnotations || c.Sync[1].Annotations["org.example.mirror"] != "primary" {
+		t.Errorf("sync 1 unexpected copy annotations: %v, %v", c.Sync[1].CopyAnnotations, c.Sync[1].Annotations)
+	}
+	// annotations enable the copy annotations
+	for i, s := range c.Sync {
+		rcOpts := (&rootOpts{}).imageCopyOpts(s)
+		if len(rcOpts) != 1 {
+			t.Errorf("sync %d unexpected number of copy options: %d", i, len(rcOpts))
+		}
+	}
+}
 
-	tests := []struct {
-		name          string
-		tag           string
-		patterns      []string
-		expectMatch   bool
-		expectPattern string
-		expectError   bool
+func TestConfigBandwidthLimit(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	tt := []struct {
+		name         string
+		conf         string
+		expectGlobal int64
+		expectSync   int64
+		expectErr    bool
 	}{
-		// Custom exclusion patterns tests (8.2)
-		{
-			name:          "custom pattern matches",
-			tag:           "backup-v1.0.0",
-			patterns:      []string{"^backup-.*"},
-			expectMatch:   true,
-			expectPattern: "^backup-.*",
-			expectError:   false,
-		},
-		{
-			name:          "multiple custom patterns first matches",
-			tag:           "prod-v1.0.0",
-			patterns:      []string{"^prod-.*", "^staging-.*", "^dev-.*"},
-			expectMatch:   true,
-			expectPattern: "^prod-.*",
-			expectError:   false,
-		},
-		{
-			name:          "multiple custom patterns second matches",
-			tag:           "staging-v1.0.0",
-			patterns:      []string{"^prod-.*", "^staging-.*", "^dev-.*"},
-			expectMatch:   true,
-			expectPattern: "^staging-.*",
-			expectError:   false,
-		},
-		{
-			name:          "multiple custom patterns none match",
-			tag:           "v1.0.0",
-			patterns:      []string{"^prod-.*", "^staging-.*", "^dev-.*"},
-			expectMatch:   false,
-			expectPattern: "",
-			expectError:   false,
-		},
 		{
-			name:          "complex regex pattern matches",
-			tag:           "v1.0.0-rc1",
-			patterns:      []string{".*-rc[0-9]+$"},
-			expectMatch:   true,
-			expectPattern: ".*-rc[0-9]+$",
-			expectError:   false,
+			name: "unset",
+			conf: `
+sync:
+  - source: ocidir://../../testdata/testrepo:v1
+    target: ocidir://` + tempDir + `/unset:v1
+    type: image
+`,
 		},
 		{
-			name:          "sig suffix pattern matches",
-			tag:           "v1.0.0.sig",
-			patterns:      []string{".*\\.sig$"},
-			expectMatch:   true,
-			expectPattern: ".*\\.sig$",
-			expectError:   false,
+			name: "global and entry",
+			conf: `
+defaults:
+  bandwidthLimit: 50MiB/s
+sync:
+  - source: ocidir://../../testdata/testrepo:v1
+    target: ocidir://` + tempDir + `/limited:v1
+    type: image
+    bandwidthLimit: 10MB/s
+`,
+			expectGlobal: 50 * 1024 * 1024,
+			expectSync:   10 * 1000 * 1000,
 		},
 		{
-			name:          "att suffix pattern matches",
-			tag:           "v1.0.0.att",
-			patterns:      []string{".*\\.att$"},
-			expectMatch:   true,
-			expectPattern: ".*\\.att$",
-			expectError:   false,
+			name: "invalid global",
+			conf: `
+defaults:
+  bandwidthLimit: fast
+`,
+			expectErr: true,
 		},
 		{
-			name:          "empty patterns list",
-			tag:           "v1.0.0",
-			patterns:      []string{},
-			expectMatch:   false,
-			expectPattern: "",
-			expectError:   false,
+			name: "invalid entry",
+			conf: `
+sync:
+  - source: ocidir://../../testdata/testrepo:v1
+    target: ocidir://` + tempDir + `/invalid:v1
+    type: image
+    bandwidthLimit: 0/s
+`,
+			expectErr: true,
 		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := ConfigLoadReader(bytes.NewReader([]byte(tc.conf)))
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("config load did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to load config: %v", err)
+			}
+			if c.Defaults.bwLimit.Rate() != tc.expectGlobal || c.Sync[0].bwLimit.Rate() != tc.expectSync {
+				t.Errorf("unexpected limits, expected %d/%d, received %d/%d", tc.expectGlobal, tc.expectSync, c.Defaults.bwLimit.Rate(), c.Sync[0].bwLimit.Rate())
+			}
+			// copy the image with the limits
+			rootOpts := rootOpts{
+				rc:   regclient.New(),
+				conf: c,
+				log:  slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+			}
+			err = rootOpts.process(ctx, c.Sync[0], actionCopy)
+			if err != nil {
+				t.Fatalf("failed to copy: %v", err)
+			}
+			src, err := ref.New(c.Sync[0].Source)
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			tgt, err := ref.New(c.Sync[0].Target)
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			mSrc, err := rootOpts.rc.ManifestHead(ctx, src)
+			if err != nil {
+				t.Fatalf("failed to head source: %v", err)
+			}
+			mTgt, err := rootOpts.rc.ManifestHead(ctx, tgt)
+			if err != nil {
+				t.Fatalf("failed to head target: %v", err)
+			}
+			if mSrc.GetDescriptor().Digest != mTgt.GetDescriptor().Digest {
+				t.Errorf("source and target mismatch")
+			}
+		})
+	}
+}
+
+// TestConfigCleanupParsing tests parsing of cleanupTags and cleanupTagsExclude fields
+func TestConfigTargets(t *testing.T) {
+	t.Parallel()
+	tt := []struct {
+		name      string
+		conf      string
+		expect    string
+		expectErr error
+	}{
 		{
-			name:          "nil patterns list",
-			tag:           "v1.0.0",
-			patterns:      nil,
-			expectMatch:   false,
-			expectPattern: "",
-			expectError:   false,
+			name: "template set registry",
+			conf: `
+sync:
+  - source: alpine:3
+    target: '{{ ((ref .Sync.Source).SetRegistry "registry.example.org").CommonName }}'
+    type: image
+`,
+			expect: "registry.example.org/library/alpine:3",
 		},
-
-		// Invalid regex patterns tests (8.3)
 		{
-			name:          "invalid regex pattern unclosed bracket",
-			tag:           "v1.0.0",
-			patterns:      []string{"[invalid"},
-			expectMatch:   false,
-			expectPattern: "",
-			expectError:   true,
+			name: "template repo prefix",
+			conf: `
+sync:
+  - source: ghcr.io/regclient/regctl
+    target: '{{ (((ref .Sync.Source).SetRegistry "registry.example.org").SetRepoPrefix "regclient" "mirror").CommonName }}'
+    type: repository
+`,
+			expect: "registry.example.org/mirror/regctl:latest",
 		},
 		{
-			name:          "invalid regex pattern unclosed paren",
-			tag:           "v1.0.0",
-			patterns:      []string{"(invalid"},
-			expectMatch:   false,
-			expectPattern: "",
-			expectError:   true,
+			name: "template invalid",
+			conf: `
+sync:
+  - source: alpine:3
+    target: '{{ ((ref .Sync.Source).AddPathPrefix "Mirror").CommonName }}'
+    type: image
+`,
+			expectErr: errs.ErrInvalidReference,
 		},
 		{
-			name:          "invalid regex pattern bad repetition",
-			tag:           "v1.0.0",
-			patterns:      []string{"*invalid"},
-			expectMatch:   false,
-			expectPattern: "",
-			expectError:   true,
+			name: "registry prefix",
+			conf: `
+sync:
+  - source: registry.example.org
+    target: mirror.example.org/team/
+    type: registry
+`,
+			expect: "mirror.example.org/team/",
 		},
 		{
-			name:          "valid pattern before invalid pattern",
-			tag:           "v1.0.0.sig",
-			patterns:      []string{".*\\.sig$", "[invalid"},
-			expectMatch:   true,
-			expectPattern: ".*\\.sig$",
-			expectError:   false,
+			name: "invalid registry",
+			conf: `
+sync:
+  - source: registry.example.org
+    target: mirror.example.org/Team
+    type: registry
+`,
+			expectErr: errs.ErrInvalidReference,
 		},
 		{
-			name:          "invalid pattern before valid pattern",
-			tag:           "v1.0.0.sig",
-			patterns:      []string{"[invalid", ".*\\.sig$"},
-			expectMatch:   false,
-			expectPattern: "",
-			expectError:   true,
+			name: "invalid image",
+			conf: `
+sync:
+  - source: alpine:3
+    target: registry.example.org/Alpine:3
+    type: image
+`,
+			expectErr: errs.ErrInvalidReference,
 		},
 	}
-
-	for _, tc := range tests {
+	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			matched, pattern, err := matchesExclusionPattern(tc.tag, tc.patterns)
-
-			// Check error expectation
-			if tc.expectError {
-				if err == nil {
-					t.Errorf("expected error but got none")
+			c, err := ConfigLoadReader(bytes.NewReader([]byte(tc.conf)))
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
 				}
 				return
 			}
 			if err != nil {
-				t.Errorf("unexpected error: %v", err)
-				return
-			}
-
-			// Check match expectation
-			if matched != tc.expectMatch {
-				t.Errorf("expected match=%v, got match=%v", tc.expectMatch, matched)
+				t.Fatalf("failed to load config: %v", err)
 			}
-
-			// Check pattern expectation
-			if pattern != tc.expectPattern {
-				t.Errorf("expected pattern=%q, got pattern=%q", tc.expectPattern, pattern)
+			if len(c.Sync) != 1 || c.Sync[0].Target != tc.expect {
+				t.Errorf("unexpected target, expected %s, received %v", tc.expect, c.Sync)
 			}
 		})
 	}
+	t.Run("registryTarget", func(t *testing.T) {
+		for _, tgt := range []struct{ tgt, repo, expect string }{
+			{"registry.example.org", "team/app", "registry.example.org/team/app"},
+			{"registry.example.org/mirror/", "app", "registry.example.org/mirror/app"},
+			{"docker.io", "app", "docker.io/library/app"},
+			{"ocidir://mirror", "app", "ocidir://mirror/app"},
+		} {
+			r, err := registryTarget(tgt.tgt, tgt.repo)
+			if err != nil {
+				t.Errorf("failed to parse %s: %v", tgt.tgt, err)
+			} else if r.CommonName() != tgt.expect {
+				t.Errorf("unexpected target, expected %s, received %s", tgt.expect, r.CommonName())
+			}
+		}
+	})
 }
 
-// TestCleanupTagIdentification tests the tag identification logic for cleanup (9.1)
-// This test requires a registry that supports tag deletion and is skipped if not available
-func TestCleanupTagIdentification(t *testing.T) {
-	t.Skip("Skipping cleanup test: requires registry with tag deletion support (not available in olareg)")
+func TestConfigCleanupParsing(t *testing.T) {
 	t.Parallel()
-	ctx := context.Background()
-	boolT := true
-
-	// Setup test registry
-	tempDir := t.TempDir()
-	err := copyfs.Copy(tempDir+"/testrepo", "../../testdata/testrepo")
-	if err != nil {
-		t.Fatalf("failed to copy testrepo to tempdir: %v", err)
-	}
-
-	regHandler := olareg.New(oConfig.Config{
-		Storage: oConfig.ConfigStorage{
-			StoreType: oConfig.StoreMem,
-			RootDir:   "../../testdata",
-		},
-	})
-	ts := httptest.NewServer(regHandler)
-	tsURL, _ := url.Parse(ts.URL)
-	tsHost := tsURL.Host
-	t.Cleanup(func() {
-		ts.Close()
-		_ = regHandler.Close()
-	})
+	bTrue := true
+	bFalse := false
 
-	rcHosts := []config.Host{
+	tt := []struct {
+		name   string
+		file   string
+		expect Config
+		expErr error
+	}{
 		{
-			Name:     tsHost,
-			Hostname: tsHost,
-			TLS:      config.TLSDisabled,
-		},
-	}
-	delayInit, _ := time.ParseDuration("0.05s")
-	delayMax, _ := time.ParseDuration("0.10s")
-	rc := regclient.New(
-		regclient.WithConfigHost(rcHosts...),
-		regclient.WithRegOpts(reg.WithDelay(delayInit, delayMax)),
-	)
+			name: "cleanup with defaults",
+			file: "config-cleanup.yml",
+			expect: Config{
+				Version: 1,
+				Creds: []config.Host{
+					{
+						Name: "registry:5000",
+						TLS:  config.TLSDisabled,
+					},
+				},
+				Defaults: ConfigDefaults{
+					CleanupTags: &bTrue,
+					CleanupTagsExclude: []string{
+						".*\\.sig$",
+						".*\\.att$",
+						"backup-.*",
+					},
+					RateLimit: ConfigRateLimit{
+						Retry: rateLimitRetryMin,
+					},
+				},
+				Sync: []ConfigSync{
+					{
+						Source:      "test/repo1",
+						Target:      "registry:5000/test/repo1",
+						Type:        "repository",
+						CleanupTags: &bTrue,
+						CleanupTagsExclude: []string{
+							".*\\.sig$",
+							".*\\.att$",
+							"backup-.*",
+						},
+						RateLimit: ConfigRateLimit{
+							Retry: rateLimitRetryMin,
+						},
+						MediaTypes:      defaultMediaTypes,
+						DigestTags:      &bFalse,
+						Referrers:       &bFalse,
+						FastCheck:       &bFalse,
+						ForceRecursive:  &bFalse,
+						IncludeExternal: &bFalse,
+						CopyAnnotations: &bFalse,
+						Provenance:      &bFalse,
+					},
+					{
+						Source:      "test/repo2",
+						Target:      "registry:5000/test/repo2",
+						Type:        "repository",
+						CleanupTags: &bFalse,
+						CleanupTagsExclude: []string{
+							".*\\.sig$",
+							".*\\.att$",
+							"backup-.*",
+						},
+						RateLimit: ConfigRateLimit{
+							Retry: rateLimitRetryMin,
+						},
+						MediaTypes:      defaultMediaTypes,
+						DigestTags:      &bFalse,
+						Referrers:       &bFalse,
+						FastCheck:       &bFalse,
+						ForceRecursive:  &bFalse,
+						IncludeExternal: &bFalse,
+						CopyAnnotations: &bFalse,
+						Provenance:      &bFalse,
+					},
+					{
+						Source:      "test/repo3",
+						Target:      "registry:5000/test/repo3",
+						Type:        "repository",
+						CleanupTags: &bTrue,
+						CleanupTagsExclude: []string{
+							".*\\.sig$",
+							".*\\.att$",
+							"backup-.*",
+						},
+						RateLimit: ConfigRateLimit{
+							Retry: rateLimitRetryMin,
+						},
+						MediaTypes:      defaultMediaTypes,
+						DigestTags:      &bFalse,
+						Referrers:       &bFalse,
+						FastCheck:       &bFalse,
+						ForceRecursive:  &bFalse,
+						IncludeExternal: &bFalse,
+						CopyAnnotations: &bFalse,
+						Provenance:      &bFalse,
+					},
+					{
+						Source:      "test/repo4",
+						Target:      "registry:5000/test/repo4",
+						Type:        "repository",
+						CleanupTags: &bTrue,
+						CleanupTagsExclude: []string{
+							"prod-.*",
+							"stable",
+						},
+						RateLimit: ConfigRateLimit{
+							Retry: rateLimitRetryMin,
+						},
+						MediaTypes:      defaultMediaTypes,
+						DigestTags:      &bFalse,
+						Referrers:       &bFalse,
+						FastCheck:       &bFalse,
+						ForceRecursive:  &bFalse,
+						IncludeExternal: &bFalse,
+						CopyAnnotations: &bFalse,
+						Provenance:      &bFalse,
+					},
+					{
+						Source:             "test/repo5",
+						Target:             "registry:5000/test/repo5",
+						Type:               "repository",
+						CleanupTags:        &bTrue,
+						CleanupTagsExclude: []string{},
+						RateLimit: ConfigRateLimit{
+							Retry: rateLimitRetryMin,
+						},
+						MediaTypes:      defaultMediaTypes,
+						DigestTags:      &bFalse,
+						Referrers:       &bFalse,
+						FastCheck:       &bFalse,
+						ForceRecursive:  &bFalse,
+						IncludeExternal: &bFalse,
+						CopyAnnotations: &bFalse,
+						Provenance:      &bFalse,
+					},
+					{
+						Source:      "test/repo6",
+						Target:      "registry:5000/test/repo6",
+						Type:        "repository",
+						CleanupTags: &bTrue,
+						CleanupTagsExclude: []string{
+							".*\\.sig$",
+							".*\\.att$",
+							"backup-.*",
+						},
+						RateLimit: ConfigRateLimit{
+							Retry: rateLimitRetryMin,
+						},
+						MediaTypes:      defaultMediaTypes,
+						DigestTags:      &bFalse,
+						Referrers:       &bFalse,
+						FastCheck:       &bFalse,
+						ForceRecursive:  &bFalse,
+						IncludeExternal: &bFalse,
+						CopyAnnotations: &bFalse,
+						Provenance:      &bFalse,
+					},
+				},
+			},
+		},
+		{
+			name: "cleanup without defaults",
+			file: "config-cleanup-no-defaults.yml",
+			expect: Config{
+				Version: 1,
+				Creds: []config.Host{
+					{
+						Name: "registry:5000",
+						TLS:  config.TLSDisabled,
+					},
+				},
+				Defaults: ConfigDefaults{
+					RateLimit: ConfigRateLimit{
+						Retry: rateLimitRetryMin,
+					},
+				},
+				Sync: []ConfigSync{
+					{
+						Source:             "test/repo1",
+						Target:             "registry:5000/test/repo1",
+						Type:               "repository",
+						CleanupTags:        &bTrue,
+						CleanupTagsExclude: nil,
+						RateLimit: ConfigRateLimit{
+							Retry: rateLimitRetryMin,
+						},
+						MediaTypes:      defaultMediaTypes,
+						DigestTags:      &bFalse,
+						Referrers:       &bFalse,
+						FastCheck:       &bFalse,
+						ForceRecursive:  &bFalse,
+						IncludeExternal: &bFalse,
+						CopyAnnotations: &bFalse,
+						Provenance:      &bFalse,
+					},
+					{
+						Source:             "test/repo2",
+						Target:             "registry:5000/test/repo2",
+						Type:               "repository",
+						CleanupTags:        &bFalse,
+						CleanupTagsExclude: nil,
+						RateLimit: ConfigRateLimit{
+							Retry: rateLimitRetryMin,
+						},
+						MediaTypes:      defaultMediaTypes,
+						DigestTags:      &bFalse,
+						Referrers:       &bFalse,
+						FastCheck:       &bFalse,
+						ForceRecursive:  &bFalse,
+						IncludeExternal: &bFalse,
+						CopyAnnotations: &bFalse,
+						Provenance:      &bFalse,
+					},
+					{
+						Source:             "test/repo3",
+						Target:             "registry:5000/test/repo3",
+						Type:               "repository",
+						CleanupTags:        &bTrue,
+						CleanupTagsExclude: nil,
+						RateLimit: ConfigRateLimit{
+							Retry: rateLimitRetryMin,
+						},
+						MediaTypes:      defaultMediaTypes,
+						DigestTags:      &bFalse,
+						Referrers:       &bFalse,
+						FastCheck:       &bFalse,
+						ForceRecursive:  &bFalse,
+						IncludeExternal: &bFalse,
+						CopyAnnotations: &bFalse,
+						Provenance:      &bFalse,
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cRead, err := ConfigLoadFile(filepath.Join("./testdata", tc.file))
+			if tc.expErr != nil {
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("expected error %v, received %v", tc.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to read: %v", err)
+			}
+			if !reflect.DeepEqual(tc.expect, *cRead) {
+				t.Errorf("parsing mismatch, expected:\n%#v\n  received:\n%#v", tc.expect, *cRead)
+			}
+		})
+	}
+}
+
+// TestConfigCleanupDefaults tests the defaults inheritance mechanism for cleanup fields
+func TestConfigCleanupDefaults(t *testing.T) {
+	t.Parallel()
+	bTrue := true
+	bFalse := false
+
+	tt := []struct {
+		name     string
+		sync     ConfigSync
+		defaults ConfigDefaults
+		expect   ConfigSync
+	}{
+		{
+			name: "cleanupTags true in defaults, not in sync",
+			sync: ConfigSync{
+				Source: "test/repo",
+				Target: "registry:5000/test/repo",
+				Type:   "repository",
+			},
+			defaults: ConfigDefaults{
+				CleanupTags: &bTrue,
+			},
+			expect: ConfigSync{
+				Source:      "test/repo",
+				Target:      "registry:5000/test/repo",
+				Type:        "repository",
+				CleanupTags: &bTrue,
+			},
+		},
+		{
+			name: "cleanupTags false in defaults, not in sync",
+			sync: ConfigSync{
+				Source: "test/repo",
+				Target: "registry:5000/test/repo",
+				Type:   "repository",
+			},
+			defaults: ConfigDefaults{
+				CleanupTags: &bFalse,
+			},
+			expect: ConfigSync{
+				Source:      "test/repo",
+				Target:      "registry:5000/test/repo",
+				Type:        "repository",
+				CleanupTags: &bFalse,
+			},
+		},
+		{
+			name: "cleanupTags not in defaults, not in sync (default to false)",
+			sync: ConfigSync{
+				Source: "test/repo",
+				Target: "registry:5000/test/repo",
+				Type:   "repository",
+			},
+			defaults: ConfigDefaults{},
+			expect: ConfigSync{
+				Source:      "test/repo",
+				Target:      "registry:5000/test/repo",
+				Type:        "repository",
+				CleanupTags: &bFalse,
+			},
+		},
+		{
+			name: "sync entry overrides defaults (true overrides false)",
+			sync: ConfigSync{
+				Source:      "test/repo",
+				Target:      "registry:5000/test/repo",
+				Type:        "repository",
+				CleanupTags: &bTrue,
+			},
+			defaults: ConfigDefaults{
+				CleanupTags: &bFalse,
+			},
+			expect: ConfigSync{
+				Source:      "test/repo",
+				Target:      "registry:5000/test/repo",
+				Type:        "repository",
+				CleanupTags: &bTrue,
+			},
+		},
+		{
+			name: "sync entry overrides defaults (false overrides true)",
+			sync: ConfigSync{
+				Source:      "test/repo",
+				Target:      "registry:5000/test/repo",
+				Type:        "repository",
+				CleanupTags: &bFalse,
+			},
+			defaults: ConfigDefaults{
+				CleanupTags: &bTrue,
+			},
+			expect: ConfigSync{
+				Source:      "test/repo",
+				Target:      "registry:5000/test/repo",
+				Type:        "repository",
+				CleanupTags: &bFalse,
+			},
+		},
+		{
+			name: "cleanupTagsExclude in defaults, not in sync",
+			sync: ConfigSync{
+				Source: "test/repo",
+				Target: "registry:5000/test/repo",
+				Type:   "repository",
+			},
+			defaults: ConfigDefaults{
+				CleanupTagsExclude: []string{".*\\.sig$", ".*\\.att$"},
+			},
+			expect: ConfigSync{
+				Source:             "test/repo",
+				Target:             "registry:5000/test/repo",
+				Type:               "repository",
+				CleanupTags:        &bFalse,
+				CleanupTagsExclude: []string{".*\\.sig$", ".*\\.att$"},
+			},
+		},
+		{
+			name: "cleanupTagsExclude not in defaults, not in sync (nil)",
+			sync: ConfigSync{
+				Source: "test/repo",
+				Target: "registry:5000/test/repo",
+				Type:   "repository",
+			},
+			defaults: ConfigDefaults{},
+			expect: ConfigSync{
+				Source:             "test/repo",
+				Target:             "registry:5000/test/repo",
+				Type:               "repository",
+				CleanupTags:        &bFalse,
+				CleanupTagsExclude: nil,
+			},
+		},
+		{
+			name: "sync entry overrides defaults exclusion patterns",
+			sync: ConfigSync{
+				Source:             "test/repo",
+				Target:             "registry:5000/test/repo",
+				Type:               "repository",
+				CleanupTagsExclude: []string{"prod-.*"},
+			},
+			defaults: ConfigDefaults{
+				CleanupTagsExclude: []string{".*\\.sig$", ".*\\.att$"},
+			},
+			expect: ConfigSync{
+				Source:             "test/repo",
+				Target:             "registry:5000/test/repo",
+				Type:               "repository",
+				CleanupTags:        &bFalse,
+				CleanupTagsExclude: []string{"prod-.*"},
+			},
+		},
+		{
+			name: "sync entry empty array overrides defaults (disable exclusions)",
+			sync: ConfigSync{
+				Source:             "test/repo",
+				Target:             "registry:5000/test/repo",
+				Type:               "repository",
+				CleanupTagsExclude: []string{},
+			},
+			defaults: ConfigDefaults{
+				CleanupTagsExclude: []string{".*\\.sig$", ".*\\.att$"},
+			},
+			expect: ConfigSync{
+				Source:             "test/repo",
+				Target:             "registry:5000/test/repo",
+				Type:               "repository",
+				CleanupTags:        &bFalse,
+				CleanupTagsExclude: []string{},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			syncSetDefaults(&tc.sync, tc.defaults)
+
+			// Check CleanupTags
+			if tc.sync.CleanupTags == nil && tc.expect.CleanupTags != nil {
+				t.Errorf("CleanupTags is nil, expected %v", *tc.expect.CleanupTags)
+			} else if tc.sync.CleanupTags != nil && tc.expect.CleanupTags == nil {
+				t.Errorf("CleanupTags is %v, expected nil", *tc.sync.CleanupTags)
+			} else if tc.sync.CleanupTags != nil && tc.expect.CleanupTags != nil && *tc.sync.CleanupTags != *tc.expect.CleanupTags {
+				t.Errorf("CleanupTags mismatch: got %v, expected %v", *tc.sync.CleanupTags, *tc.expect.CleanupTags)
+			}
+
+			// Check CleanupTagsExclude
+			if !reflect.DeepEqual(tc.sync.CleanupTagsExclude, tc.expect.CleanupTagsExclude) {
+				t.Errorf("CleanupTagsExclude mismatch: got %v, expected %v", tc.sync.CleanupTagsExclude, tc.expect.CleanupTagsExclude)
+			}
+		})
+	}
+}
+
+// TestMatchesExclusionPattern tests the matchesExclusionPattern function
+func TestMatchesExclusionPattern(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		tag           string
+		patterns      []string
+		expectMatch   bool
+		expectPattern string
+		expectError   bool
+	}{
+		// Custom exclusion patterns tests (8.2)
+		{
+			name:          "custom pattern matches",
+			tag:           "backup-v1.0.0",
+			patterns:      []string{"^backup-.*"},
+			expectMatch:   true,
+			expectPattern: "^backup-.*",
+			expectError:   false,
+		},
+		{
+			name:          "multiple custom patterns first matches",
+			tag:           "prod-v1.0.0",
+			patterns:      []string{"^prod-.*", "^staging-.*", "^dev-.*"},
+			expectMatch:   true,
+			expectPattern: "^prod-.*",
+			expectError:   false,
+		},
+		{
+			name:          "multiple custom patterns second matches",
+			tag:           "staging-v1.0.0",
+			patterns:      []string{"^prod-.*", "^staging-.*", "^dev-.*"},
+			expectMatch:   true,
+			expectPattern: "^staging-.*",
+			expectError:   false,
+		},
+		{
+			name:          "multiple custom patterns none match",
+			tag:           "v1.0.0",
+			patterns:      []string{"^prod-.*", "^staging-.*", "^dev-.*"},
+			expectMatch:   false,
+			expectPattern: "",
+			expectError:   false,
+		},
+		{
+			name:          "complex regex pattern matches",
+			tag:           "v1.0.0-rc1",
+			patterns:      []string{".*-rc[0-9]+$"},
+			expectMatch:   true,
+			expectPattern: ".*-rc[0-9]+$",
+			expectError:   false,
+		},
+		{
+			name:          "sig suffix pattern matches",
+			tag:           "v1.0.0.sig",
+			patterns:      []string{".*\\.sig$"},
+			expectMatch:   true,
+			expectPattern: ".*\\.sig$",
+			expectError:   false,
+		},
+		{
+			name:          "att suffix pattern matches",
+			tag:           "v1.0.0.att",
+			patterns:      []string{".*\\.att$"},
+			expectMatch:   true,
+			expectPattern: ".*\\.att$",
+			expectError:   false,
+		},
+		{
+			name:          "empty patterns list",
+			tag:           "v1.0.0",
+			patterns:      []string{},
+			expectMatch:   false,
+			expectPattern: "",
+			expectError:   false,
+		},
+		{
+			name:          "nil patterns list",
+			tag:           "v1.0.0",
+			patterns:      nil,
+			expectMatch:   false,
+			expectPattern: "",
+			expectError:   false,
+		},
+
+		// Invalid regex patterns tests (8.3)
+		{
+			name:          "invalid regex pattern unclosed bracket",
+			tag:           "v1.0.0",
+			patterns:      []string{"[invalid"},
+			expectMatch:   false,
+			expectPattern: "",
+			expectError:   true,
+		},
+		{
+			name:          "invalid regex pattern unclosed paren",
+			tag:           "v1.0.0",
+			patterns:      []string{"(invalid"},
+			expectMatch:   false,
+			expectPattern: "",
+			expectError:   true,
+		},
+		{
+			name:          "invalid regex pattern bad repetition",
+			tag:           "v1.0.0",
+			patterns:      []string{"*invalid"},
+			expectMatch:   false,
+			expectPattern: "",
+			expectError:   true,
+		},
+		{
+			name:          "valid pattern before invalid pattern",
+			tag:           "v1.0.0.sig",
+			patterns:      []string{".*\\.sig$", "[invalid"},
+			expectMatch:   true,
+			expectPattern: ".*\\.sig$",
+			expectError:   false,
+		},
+		{
+			name:          "invalid pattern before valid pattern",
+			tag:           "v1.0.0.sig",
+			patterns:      []string{"[invalid", ".*\\.sig$"},
+			expectMatch:   false,
+			expectPattern: "",
+			expectError:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			matched, pattern, err := matchesExclusionPattern(tc.tag, tc.patterns)
+
+			// Check error expectation
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			// Check match expectation
+			if matched != tc.expectMatch {
+				t.Errorf("expected match=%v, got match=%v", tc.expectMatch, matched)
+			}
+
+			// Check pattern expectation
+			if pattern != tc.expectPattern {
+				t.Errorf("expected pattern=%q, got pattern=%q", tc.expectPattern, pattern)
+			}
+		})
+	}
+}
+
+// TestCleanupTagIdentification tests the tag identification logic for cleanup (9.1)
+// This test requires a registry that supports tag deletion and is skipped if not available
+func TestCleanupTagIdentification(t *testing.T) {
+	t.Skip("Skipping cleanup test: requires registry with tag deletion support (not available in olareg)")
+	t.Parallel()
+	ctx := context.Background()
+	boolT := true
+
+	// Setup test registry
+	tempDir := t.TempDir()
+	err := copyfs.Copy(tempDir+"/testrepo", "../../testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to copy testrepo to tempdir: %v", err)
+	}
+
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "../../testdata",
+		},
+	})
+	ts := httptest.NewServer(regHandler)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+
+	rcHosts := []config.Host{
+		{
+			Name:     tsHost,
+			Hostname: tsHost,
+			TLS:      config.TLSDisabled,
+		},
+	}
+	delayInit, _ := time.ParseDuration("0.05s")
+	delayMax, _ := time.ParseDuration("0.10s")
+	rc := regclient.New(
+		regclient.WithConfigHost(rcHosts...),
+		regclient.WithRegOpts(reg.WithDelay(delayInit, delayMax)),
+	)
+	pq := pqueue.New(pqueue.Opts[throttle]{
+		Max:  1,
+		Next: throttleNext,
+	})
+
+	confBytes := `
+version: 1
+defaults:
+  parallel: 1
+`
+	confRdr := bytes.NewReader([]byte(confBytes))
+	conf, err := ConfigLoadReader(confRdr)
+	if err != nil {
+		t.Fatalf("failed parsing config: %v", err)
+	}
+
+	// Get reference digests
+	r1, _ := ref.New(tsHost + "/testrepo:v1")
+	r2, _ := ref.New(tsHost + "/testrepo:v2")
+	r3, _ := ref.New(tsHost + "/testrepo:v3")
+	m1, _ := rc.ManifestGet(ctx, r1)
+	d1 := m1.GetDescriptor().Digest
+	m2, _ := rc.ManifestGet(ctx, r2)
+	d2 := m2.GetDescriptor().Digest
+	m3, _ := rc.ManifestGet(ctx, r3)
+	d3 := m3.GetDescriptor().Digest
+
+	tests := []struct {
+		name          string
+		setupSync     ConfigSync
+		cleanupSync   ConfigSync
+		expectTags    map[string]digest.Digest
+		expectMissing []string
+	}{
+		{
+			name: "tags matching filters are preserved",
+			setupSync: ConfigSync{
+				Source: tsHost + "/testrepo",
+				Target: tsHost + "/test-cleanup1",
+				Type:   "repository",
+			},
+			cleanupSync: ConfigSync{
+				Source:      tsHost + "/testrepo",
+				Target:      tsHost + "/test-cleanup1",
+				Type:        "repository",
+				CleanupTags: &boolT,
+				Tags: TagAllowDeny{
+					Allow: []string{"v1", "v2"},
+				},
+			},
+			expectTags: map[string]digest.Digest{
+				tsHost + "/test-cleanup1:v1": d1,
+				tsHost + "/test-cleanup1:v2": d2,
+			},
+			expectMissing: []string{
+				tsHost + "/test-cleanup1:v3",
+			},
+		},
+		{
+			name: "tags not matching filters are deleted",
+			setupSync: ConfigSync{
+				Source: tsHost + "/testrepo",
+				Target: tsHost + "/test-cleanup2",
+				Type:   "repository",
+			},
+			cleanupSync: ConfigSync{
+				Source:      tsHost + "/testrepo",
+				Target:      tsHost + "/test-cleanup2",
+				Type:        "repository",
+				CleanupTags: &boolT,
+				Tags: TagAllowDeny{
+					Allow: []string{"v3"},
+				},
+			},
+			expectTags: map[string]digest.Digest{
+				tsHost + "/test-cleanup2:v3": d3,
+			},
+			expectMissing: []string{
+				tsHost + "/test-cleanup2:v1",
+				tsHost + "/test-cleanup2:v2",
+			},
+		},
+		{
+			name: "excluded tags are preserved regardless of filters",
+			setupSync: ConfigSync{
+				Source: tsHost + "/testrepo",
+				Target: tsHost + "/test-cleanup3",
+				Type:   "repository",
+			},
+			cleanupSync: ConfigSync{
+				Source:             tsHost + "/testrepo",
+				Target:             tsHost + "/test-cleanup3",
+				Type:               "repository",
+				CleanupTags:        &boolT,
+				CleanupTagsExclude: []string{"v2"},
+				Tags: TagAllowDeny{
+					Allow: []string{"v1"},
+				},
+			},
+			expectTags: map[string]digest.Digest{
+				tsHost + "/test-cleanup3:v1": d1,
+				tsHost + "/test-cleanup3:v2": d2,
+			},
+			expectMissing: []string{
+				tsHost + "/test-cleanup3:v3",
+			},
+		},
+		{
+			name: "multiple tagSets preserve matching tags",
+			setupSync: ConfigSync{
+				Source: tsHost + "/testrepo",
+				Target: tsHost + "/test-cleanup4",
+				Type:   "repository",
+			},
+			cleanupSync: ConfigSync{
+				Source:      tsHost + "/testrepo",
+				Target:      tsHost + "/test-cleanup4",
+				Type:        "repository",
+				CleanupTags: &boolT,
+				TagSets: []TagAllowDeny{
+					{Allow: []string{"v1"}},
+					{Allow: []string{"v3"}},
+				},
+			},
+			expectTags: map[string]digest.Digest{
+				tsHost + "/test-cleanup4:v1": d1,
+				tsHost + "/test-cleanup4:v3": d3,
+			},
+			expectMissing: []string{
+				tsHost + "/test-cleanup4:v2",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rootOpts := rootOpts{
+				conf:     conf,
+				rc:       rc,
+				throttle: pq,
+				log:      slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+			}
+
+			// Setup: sync all tags first
+			syncSetDefaults(&tc.setupSync, conf.Defaults)
+			err = rootOpts.process(ctx, tc.setupSync, actionCopy)
+			if err != nil {
+				t.Fatalf("setup sync failed: %v", err)
+			}
+
+			// Run cleanup with filters
+			syncSetDefaults(&tc.cleanupSync, conf.Defaults)
+			err = rootOpts.process(ctx, tc.cleanupSync, actionCopy)
+			if err != nil {
+				t.Fatalf("cleanup sync failed: %v", err)
+			}
+
+			// Verify expected tags exist
+			for tag, expectedDigest := range tc.expectTags {
+				r, err := ref.New(tag)
+				if err != nil {
+					t.Fatalf("cannot parse ref %s: %v", tag, err)
+				}
+				m, err := rc.ManifestHead(ctx, r)
+				if err != nil {
+					t.Errorf("expected tag does not exist: %s", tag)
+				} else if m.GetDescriptor().Digest != expectedDigest {
+					t.Errorf("digest mismatch for %s, expected %s, received %s",
+						tag, expectedDigest.String(), m.GetDescriptor().Digest.String())
+				}
+			}
+
+			// Verify missing tags don't exist
+			for _, missing := range tc.expectMissing {
+				r, err := ref.New(missing)
+				if err != nil {
+					t.Fatalf("cannot parse ref %s: %v", missing, err)
+				}
+				_, err = rc.ManifestHead(ctx, r)
+				if err == nil {
+					t.Errorf("tag exists that should be deleted: %s", missing)
+				}
+			}
+		})
+	}
+}
+
+// TestCleanupSyncTypes tests cleanup with different sync types (9.2)
+// This test requires a registry that supports tag deletion and is skipped if not available
+func TestCleanupSyncTypes(t *testing.T) {
+	t.Skip("Skipping cleanup test: requires registry with tag deletion support (not available in olareg)")
+	t.Parallel()
+	ctx := context.Background()
+	boolT := true
+
+	// Setup test registry
+	tempDir := t.TempDir()
+	err := copyfs.Copy(tempDir+"/testrepo", "../../testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to copy testrepo to tempdir: %v", err)
+	}
+
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "../../testdata",
+		},
+	})
+	ts := httptest.NewServer(regHandler)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+
+	rcHosts := []config.Host{
+		{
+			Name:     tsHost,
+			Hostname: tsHost,
+			TLS:      config.TLSDisabled,
+		},
+	}
+	delayInit, _ := time.ParseDuration("0.05s")
+	delayMax, _ := time.ParseDuration("0.10s")
+	rc := regclient.New(
+		regclient.WithConfigHost(rcHosts...),
+		regclient.WithRegOpts(reg.WithDelay(delayInit, delayMax)),
+	)
+	pq := pqueue.New(pqueue.Opts[throttle]{
+		Max:  1,
+		Next: throttleNext,
+	})
+
+	confBytes := `
+version: 1
+defaults:
+  parallel: 1
+`
+	confRdr := bytes.NewReader([]byte(confBytes))
+	conf, err := ConfigLoadReader(confRdr)
+	if err != nil {
+		t.Fatalf("failed parsing config: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		setupSync     ConfigSync
+		cleanupSync   ConfigSync
+		expectCleanup bool
+	}{
+		{
+			name: "cleanup runs for repository type",
+			setupSync: ConfigSync{
+				Source: tsHost + "/testrepo",
+				Target: tsHost + "/test-type-repo",
+				Type:   "repository",
+			},
+			cleanupSync: ConfigSync{
+				Source:      tsHost + "/testrepo",
+				Target:      tsHost + "/test-type-repo",
+				Type:        "repository",
+				CleanupTags: &boolT,
+				Tags: TagAllowDeny{
+					Allow: []string{"v1"},
+				},
+			},
+			expectCleanup: true,
+		},
+		{
+			name: "cleanup does NOT run for image type",
+			setupSync: ConfigSync{
+				Source: tsHost + "/testrepo:v1",
+				Target: tsHost + "/test-type-image:v1",
+				Type:   "image",
+			},
+			cleanupSync: ConfigSync{
+				Source:      tsHost + "/testrepo:v2",
+				Target:      tsHost + "/test-type-image:v2",
+				Type:        "image",
+				CleanupTags: &boolT,
+			},
+			expectCleanup: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rootOpts := rootOpts{
+				conf:     conf,
+				rc:       rc,
+				throttle: pq,
+				log:      slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+			}
+
+			// Setup: sync initial tags
+			syncSetDefaults(&tc.setupSync, conf.Defaults)
+			err = rootOpts.process(ctx, tc.setupSync, actionCopy)
+			if err != nil {
+				t.Fatalf("setup sync failed: %v", err)
+			}
+
+			// For repository type, add an extra tag that should be cleaned up
+			if tc.cleanupSync.Type == "repository" {
+				// Copy v2 to target
+				extraSync := ConfigSync{
+					Source: tsHost + "/testrepo:v2",
+					Target: tc.cleanupSync.Target + ":v2",
+					Type:   "image",
+				}
+				syncSetDefaults(&extraSync, conf.Defaults)
+				err = rootOpts.process(ctx, extraSync, actionCopy)
+				if err != nil {
+					t.Fatalf("extra tag sync failed: %v", err)
+				}
+			}
+
+			// Run cleanup
+			syncSetDefaults(&tc.cleanupSync, conf.Defaults)
+			err = rootOpts.process(ctx, tc.cleanupSync, actionCopy)
+			if err != nil {
+				t.Fatalf("cleanup sync failed: %v", err)
+			}
+
+			// Verify cleanup behavior
+			if tc.expectCleanup {
+				// For repository type, v2 should be deleted
+				r, _ := ref.New(tc.cleanupSync.Target + ":v2")
+				_, err = rc.ManifestHead(ctx, r)
+				if err == nil {
+					t.Errorf("cleanup did not run: v2 tag still exists")
+				}
+			} else {
+				// For image type, both tags should exist
+				r1, _ := ref.New(tc.cleanupSync.Target)
+				r1 = r1.SetTag("v1")
+				_, err1 := rc.ManifestHead(ctx, r1)
+				r2, _ := ref.New(tc.cleanupSync.Target)
+				r2 = r2.SetTag("v2")
+				_, err2 := rc.ManifestHead(ctx, r2)
+				if err1 != nil || err2 != nil {
+					t.Errorf("cleanup ran when it should not have: v1 err=%v, v2 err=%v", err1, err2)
+				}
+			}
+		})
+	}
+}
+
+// TestCleanupErrorHandling tests error handling during cleanup (9.3)
+// This test requires a registry that supports tag deletion and is skipped if not available
+func TestCleanupErrorHandling(t *testing.T) {
+	t.Skip("Skipping cleanup test: requires registry with tag deletion support (not available in olareg)")
+	t.Parallel()
+	ctx := context.Background()
+	boolT := true
+
+	// Setup test registry
+	tempDir := t.TempDir()
+	err := copyfs.Copy(tempDir+"/testrepo", "../../testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to copy testrepo to tempdir: %v", err)
+	}
+
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "../../testdata",
+		},
+	})
+	ts := httptest.NewServer(regHandler)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+
+	// Setup read-only registry
+	regROHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "../../testdata",
+			ReadOnly:  &boolT,
+		},
+	})
+	tsRO := httptest.NewServer(regROHandler)
+	tsROURL, _ := url.Parse(tsRO.URL)
+	tsROHost := tsROURL.Host
+	t.Cleanup(func() {
+		tsRO.Close()
+		_ = regROHandler.Close()
+	})
+
+	rcHosts := []config.Host{
+		{
+			Name:     tsHost,
+			Hostname: tsHost,
+			TLS:      config.TLSDisabled,
+		},
+		{
+			Name:     tsROHost,
+			Hostname: tsROHost,
+			TLS:      config.TLSDisabled,
+		},
+	}
+	delayInit, _ := time.ParseDuration("0.05s")
+	delayMax, _ := time.ParseDuration("0.10s")
+	rc := regclient.New(
+		regclient.WithConfigHost(rcHosts...),
+		regclient.WithRegOpts(reg.WithDelay(delayInit, delayMax)),
+	)
 	pq := pqueue.New(pqueue.Opts[throttle]{
 		Max:  1,
 		Next: throttleNext,
 	})
 
-	confBytes := `
-version: 1
-defaults:
-  parallel: 1
-`
-	confRdr := bytes.NewReader([]byte(confBytes))
-	conf, err := ConfigLoadReader(confRdr)
-	if err != nil {
-		t.Fatalf("failed parsing config: %v", err)
-	}
+	confBytes := `
+version: 1
+defaults:
+  parallel: 1
+`
+	confRdr := bytes.NewReader([]byte(confBytes))
+	conf, err := ConfigLoadReader(confRdr)
+	if err != nil {
+		t.Fatalf("failed parsing config: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		setupSync   ConfigSync
+		cleanupSync ConfigSync
+		abortOnErr  bool
+		expectError bool
+	}{
+		{
+			name: "cleanup continues after tag deletion failure without abortOnErr",
+			setupSync: ConfigSync{
+				Source: tsHost + "/testrepo",
+				Target: tsROHost + "/test-error1",
+				Type:   "repository",
+			},
+			cleanupSync: ConfigSync{
+				Source:      tsHost + "/testrepo",
+				Target:      tsROHost + "/test-error1",
+				Type:        "repository",
+				CleanupTags: &boolT,
+				Tags: TagAllowDeny{
+					Allow: []string{"v1"},
+				},
+			},
+			abortOnErr:  false,
+			expectError: true,
+		},
+		{
+			name: "cleanup aborts with abortOnErr",
+			setupSync: ConfigSync{
+				Source: tsHost + "/testrepo",
+				Target: tsROHost + "/test-error2",
+				Type:   "repository",
+			},
+			cleanupSync: ConfigSync{
+				Source:      tsHost + "/testrepo",
+				Target:      tsROHost + "/test-error2",
+				Type:        "repository",
+				CleanupTags: &boolT,
+				Tags: TagAllowDeny{
+					Allow: []string{"v1"},
+				},
+			},
+			abortOnErr:  true,
+			expectError: true,
+		},
+		{
+			name: "cleanup handles context cancellation",
+			setupSync: ConfigSync{
+				Source: tsHost + "/testrepo",
+				Target: tsHost + "/test-cancel",
+				Type:   "repository",
+			},
+			cleanupSync: ConfigSync{
+				Source:      tsHost + "/testrepo",
+				Target:      tsHost + "/test-cancel",
+				Type:        "repository",
+				CleanupTags: &boolT,
+				Tags: TagAllowDeny{
+					Allow: []string{"v1"},
+				},
+			},
+			abortOnErr:  false,
+			expectError: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rootOpts := rootOpts{
+				conf:       conf,
+				rc:         rc,
+				throttle:   pq,
+				log:        slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+				abortOnErr: tc.abortOnErr,
+			}
+
+			// Setup: sync all tags first
+			syncSetDefaults(&tc.setupSync, conf.Defaults)
+			err = rootOpts.process(ctx, tc.setupSync, actionCopy)
+			if err != nil {
+				t.Fatalf("setup sync failed: %v", err)
+			}
+
+			// Run cleanup
+			syncSetDefaults(&tc.cleanupSync, conf.Defaults)
+
+			// For context cancellation test, use a cancelled context
+			testCtx := ctx
+			if tc.name == "cleanup handles context cancellation" {
+				cancelCtx, cancel := context.WithCancel(ctx)
+				cancel()
+				testCtx = cancelCtx
+			}
+
+			err = rootOpts.process(testCtx, tc.cleanupSync, actionCopy)
+
+			// Verify error expectation
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+			} else {
+				if err != nil && !errors.Is(err, ErrCanceled) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestThrottlePriority tests that the throttle priority mechanism works correctly
+// ensuring that actionMissing tasks are prioritized over actionCopy tasks
+func TestThrottlePriority(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	// Create a priority queue with the throttleNext function
+	q := pqueue.New(pqueue.Opts[throttle]{
+		Max:  1, // Only allow 1 at a time for clearer testing
+		Next: throttleNext,
+	})
+
+	// Track the order in which tasks are processed
+	processed := make(chan int, 10)
+
+	// Acquire one slot to fill the queue
+	done0, err := q.Acquire(ctx, throttle{priority: 0})
+	if err != nil {
+		t.Fatalf("failed to acquire slot 0: %v", err)
+	}
+
+	// Queue up tasks with different priorities
+	// Task 2: normal priority (0)
+	go func() {
+		done, err := q.Acquire(ctx, throttle{priority: 0})
+		if err != nil {
+			t.Errorf("task 2 acquire failed: %v", err)
+			return
+		}
+		processed <- 2
+		time.Sleep(5 * time.Millisecond) // small delay to prevent immediate completion
+		done()
+	}()
+
+	// Task 3: high priority (1) - should jump ahead
+	time.Sleep(10 * time.Millisecond) // ensure task 2 queues first
+	go func() {
+		done, err := q.Acquire(ctx, throttle{priority: 1})
+		if err != nil {
+			t.Errorf("task 3 acquire failed: %v", err)
+			return
+		}
+		processed <- 3
+		time.Sleep(5 * time.Millisecond)
+		done()
+	}()
+
+	// Task 4: normal priority (0)
+	time.Sleep(10 * time.Millisecond)
+	go func() {
+		done, err := q.Acquire(ctx, throttle{priority: 0})
+		if err != nil {
+			t.Errorf("task 4 acquire failed: %v", err)
+			return
+		}
+		processed <- 4
+		time.Sleep(5 * time.Millisecond)
+		done()
+	}()
+
+	// Task 5: high priority (1) - should also jump ahead
+	time.Sleep(10 * time.Millisecond)
+	go func() {
+		done, err := q.Acquire(ctx, throttle{priority: 1})
+		if err != nil {
+			t.Errorf("task 5 acquire failed: %v", err)
+			return
+		}
+		processed <- 5
+		time.Sleep(5 * time.Millisecond)
+		done()
+	}()
+
+	// Wait for tasks to queue up
+	time.Sleep(50 * time.Millisecond)
+
+	// Release the first slot and observe processing order
+	done0()
+
+	// Collect the processing order
+	var order []int
+	timeout := time.After(2 * time.Second)
+	for i := 0; i < 4; i++ {
+		select {
+		case id := <-processed:
+			order = append(order, id)
+		case <-timeout:
+			t.Fatalf("timeout waiting for tasks to complete, got %v", order)
+		}
+	}
+
+	// Verify that high priority tasks (3, 5) were processed before normal tasks (2, 4)
+	// Expected order: either [3, 5, 2, 4] or [5, 3, 2, 4] depending on timing
+	// But all high priority (3, 5) should come before all normal (2, 4)
+	firstNormalIdx := -1
+	lastHighIdx := -1
+	for i, id := range order {
+		if id == 2 || id == 4 {
+			if firstNormalIdx == -1 {
+				firstNormalIdx = i
+			}
+		}
+		if id == 3 || id == 5 {
+			lastHighIdx = i
+		}
+	}
+
+	if firstNormalIdx != -1 && lastHighIdx > firstNormalIdx {
+		t.Errorf("priority queue failed: high priority task at index %d came after normal priority task at index %d, order: %v",
+			lastHighIdx, firstNormalIdx, order)
+	}
+
+	t.Logf("Tasks processed in order: %v (high priority tasks correctly prioritized)", order)
+}
+
+// TestThrottleNextFunction tests the throttleNext function directly
+func TestThrottleNextFunction(t *testing.T) {
+	tests := []struct {
+		name     string
+		queued   []*throttle
+		expected int
+	}{
+		{
+			name:     "empty queue",
+			queued:   []*throttle{},
+			expected: 0,
+		},
+		{
+			name:     "single item",
+			queued:   []*throttle{{priority: 0}},
+			expected: 0,
+		},
+		{
+			name:     "all same priority",
+			queued:   []*throttle{{priority: 0}, {priority: 0}, {priority: 0}},
+			expected: 0, // FIFO - returns first item
+		},
+		{
+			name:     "high priority at end",
+			queued:   []*throttle{{priority: 0}, {priority: 0}, {priority: 1}},
+			expected: 2,
+		},
+		{
+			name:     "high priority in middle",
+			queued:   []*throttle{{priority: 0}, {priority: 1}, {priority: 0}},
+			expected: 1,
+		},
+		{
+			name:     "high priority at start",
+			queued:   []*throttle{{priority: 1}, {priority: 0}, {priority: 0}},
+			expected: 0,
+		},
+		{
+			name:     "multiple high priority",
+			queued:   []*throttle{{priority: 0}, {priority: 1}, {priority: 1}},
+			expected: 1, // returns first high priority (FIFO within same priority)
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := throttleNext(tc.queued, nil)
+			if result != tc.expected {
+				t.Errorf("expected index %d, got %d", tc.expected, result)
+			}
+		})
+	}
+}
+
+// TestDigestTagRe tests the digestTagRe regular expression against various tag strings.
+func TestDigestTagRe(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		tag     string
+		matches bool
+	}{
+		// valid cosign-style digest tags
+		{"sha256-abc123def456.sig", true},
+		{"sha256-abc123def456.att", true},
+		{"sha512-deadbeef.sig", true},
+		{"sha512-deadbeef.att", true},
+		{"sha256-abc123def456.sbom", true},
+		// must have at least one hex char
+		{"sha256-0.sig", true},
+		// normal image tags must not match
+		{"v1", false},
+		{"latest", false},
+		{"v1.0.0", false},
+		// looks similar but wrong suffix
+		{"sha256-abc123.tar", false},
+		{"sha256-abc123", false},
+		// uppercase algorithm must not match (pattern requires lowercase)
+		{"SHA256-abc123.sig", false},
+		// no hex part
+		{"sha256-.sig", false},
+		// no algorithm part
+		{"-abc123.sig", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.tag, func(t *testing.T) {
+			got := digestTagRe.MatchString(tc.tag)
+			if got != tc.matches {
+				t.Errorf("digestTagRe.MatchString(%q) = %v, want %v", tc.tag, got, tc.matches)
+			}
+		})
+	}
+}
+
+// TestIsOrphanedDigestTag tests the isOrphanedDigestTag helper using a live
+// olareg registry.  It covers:
+//   - a plain tag (never treated as a digest tag)
+//   - a .sig/.att tag whose referenced digest still exists (not orphaned)
+//   - a .sig/.att tag whose referenced digest has been deleted (orphaned)
+//
+// It also verifies end-to-end that cleanupTags removes orphaned digest tags
+// even when they match a CleanupTagsExclude pattern.
+func TestIsOrphanedDigestTag(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "../../testdata",
+		},
+	})
+	ts := httptest.NewServer(regHandler)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+
+	rcHosts := []config.Host{{Name: tsHost, Hostname: tsHost, TLS: config.TLSDisabled}}
+	rc := regclient.New(regclient.WithConfigHost(rcHosts...))
+
+	// ── helpers ──────────────────────────────────────────────────────────────
+
+	// newRef parses a reference and fatals on error.
+	newRef := func(s string) ref.Ref {
+		r, err := ref.New(s)
+		if err != nil {
+			t.Fatalf("ref.New(%q): %v", s, err)
+		}
+		return r
+	}
+
+	// digestTag builds the cosign-style tag string from a digest and extension.
+	digestTag := func(d digest.Digest, ext string) string {
+		return string(d.Algorithm()) + "-" + d.Hex() + "." + ext
+	}
+
+	// ── sub-tests ─────────────────────────────────────────────────────────────
+
+	t.Run("non-digest tag is never orphaned", func(t *testing.T) {
+		tgtRef := newRef(tsHost + "/testrepo")
+		orphaned, err := isOrphanedDigestTag(ctx, rc, tgtRef, "latest")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if orphaned {
+			t.Errorf("expected non-digest tag to not be orphaned")
+		}
+	})
+
+	t.Run("sig tag whose referenced digest exists is not orphaned", func(t *testing.T) {
+		// Resolve the actual digest of testrepo:v1.
+		srcRef := newRef(tsHost + "/testrepo:v1")
+		m, err := rc.ManifestHead(ctx, srcRef, regclient.WithManifestRequireDigest())
+		if err != nil {
+			t.Fatalf("ManifestHead testrepo:v1: %v", err)
+		}
+		baseDigest := m.GetDescriptor().Digest
+
+		// isOrphanedDigestTag resolves the digest against tgtRef's repository.
+		// testrepo has the manifest, so use testrepo as the target repo.
+		tgtRef := newRef(tsHost + "/testrepo")
+		tag := digestTag(baseDigest, "sig")
+
+		orphaned, err := isOrphanedDigestTag(ctx, rc, tgtRef, tag)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if orphaned {
+			t.Errorf("sig tag %s should not be orphaned: digest %s still exists", tag, baseDigest)
+		}
+	})
+
+	t.Run("att tag whose referenced digest exists is not orphaned", func(t *testing.T) {
+		srcRef := newRef(tsHost + "/testrepo:v2")
+		m, err := rc.ManifestHead(ctx, srcRef, regclient.WithManifestRequireDigest())
+		if err != nil {
+			t.Fatalf("ManifestHead testrepo:v2: %v", err)
+		}
+		baseDigest := m.GetDescriptor().Digest
+		tgtRef := newRef(tsHost + "/testrepo")
+		tag := digestTag(baseDigest, "att")
+
+		orphaned, err := isOrphanedDigestTag(ctx, rc, tgtRef, tag)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if orphaned {
+			t.Errorf("att tag %s should not be orphaned: digest %s still exists", tag, baseDigest)
+		}
+	})
+
+	t.Run("sig tag with non-existent digest is orphaned", func(t *testing.T) {
+		fakeDigest := digest.NewDigestFromEncoded(digest.SHA256,
+			"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+		tgtRef := newRef(tsHost + "/testrepo")
+		tag := digestTag(fakeDigest, "sig")
+
+		orphaned, err := isOrphanedDigestTag(ctx, rc, tgtRef, tag)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !orphaned {
+			t.Errorf("sig tag %s should be orphaned: digest does not exist", tag)
+		}
+	})
+
+	t.Run("att tag with non-existent digest is orphaned", func(t *testing.T) {
+		fakeDigest := digest.NewDigestFromEncoded(digest.SHA256,
+			"bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
+		tgtRef := newRef(tsHost + "/testrepo")
+		tag := digestTag(fakeDigest, "att")
 
-	// Get reference digests
-	r1, _ := ref.New(tsHost + "/testrepo:v1")
-	r2, _ := ref.New(tsHost + "/testrepo:v2")
-	r3, _ := ref.New(tsHost + "/testrepo:v3")
-	m1, _ := rc.ManifestGet(ctx, r1)
-	d1 := m1.GetDescriptor().Digest
-	m2, _ := rc.ManifestGet(ctx, r2)
-	d2 := m2.GetDescriptor().Digest
-	m3, _ := rc.ManifestGet(ctx, r3)
-	d3 := m3.GetDescriptor().Digest
+		orphaned, err := isOrphanedDigestTag(ctx, rc, tgtRef, tag)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !orphaned {
+			t.Errorf("att tag %s should be orphaned: digest does not exist", tag)
+		}
+	})
 
-	tests := []struct {
-		name          string
-		setupSync     ConfigSync
-		cleanupSync   ConfigSync
-		expectTags    map[string]digest.Digest
-		expectMissing []string
-	}{
-		{
-			name: "tags matching filters are preserved",
-			setupSync: ConfigSync{
-				Source: tsHost + "/testrepo",
-				Target: tsHost + "/test-cleanup1",
-				Type:   "repository",
-			},
-			cleanupSync: ConfigSync{
-				Source:      tsHost + "/testrepo",
-				Target:      tsHost + "/test-cleanup1",
-				Type:        "repository",
-				CleanupTags: &boolT,
-				Tags: TagAllowDeny{
-					Allow: []string{"v1", "v2"},
-				},
-			},
-			expectTags: map[string]digest.Digest{
-				tsHost + "/test-cleanup1:v1": d1,
-				tsHost + "/test-cleanup1:v2": d2,
-			},
-			expectMissing: []string{
-				tsHost + "/test-cleanup1:v3",
-			},
-		},
-		{
-			name: "tags not matching filters are deleted",
-			setupSync: ConfigSync{
-				Source: tsHost + "/testrepo",
-				Target: tsHost + "/test-cleanup2",
-				Type:   "repository",
-			},
-			cleanupSync: ConfigSync{
-				Source:      tsHost + "/testrepo",
-				Target:      tsHost + "/test-cleanup2",
-				Type:        "repository",
-				CleanupTags: &boolT,
-				Tags: TagAllowDeny{
-					Allow: []string{"v3"},
-				},
-			},
-			expectTags: map[string]digest.Digest{
-				tsHost + "/test-cleanup2:v3": d3,
-			},
-			expectMissing: []string{
-				tsHost + "/test-cleanup2:v1",
-				tsHost + "/test-cleanup2:v2",
-			},
-		},
-		{
-			name: "excluded tags are preserved regardless of filters",
-			setupSync: ConfigSync{
-				Source: tsHost + "/testrepo",
-				Target: tsHost + "/test-cleanup3",
-				Type:   "repository",
-			},
-			cleanupSync: ConfigSync{
-				Source:             tsHost + "/testrepo",
-				Target:             tsHost + "/test-cleanup3",
-				Type:               "repository",
-				CleanupTags:        &boolT,
-				CleanupTagsExclude: []string{"v2"},
-				Tags: TagAllowDeny{
-					Allow: []string{"v1"},
-				},
-			},
-			expectTags: map[string]digest.Digest{
-				tsHost + "/test-cleanup3:v1": d1,
-				tsHost + "/test-cleanup3:v2": d2,
-			},
-			expectMissing: []string{
-				tsHost + "/test-cleanup3:v3",
-			},
-		},
-		{
-			name: "multiple tagSets preserve matching tags",
-			setupSync: ConfigSync{
-				Source: tsHost + "/testrepo",
-				Target: tsHost + "/test-cleanup4",
-				Type:   "repository",
-			},
-			cleanupSync: ConfigSync{
-				Source:      tsHost + "/testrepo",
-				Target:      tsHost + "/test-cleanup4",
-				Type:        "repository",
-				CleanupTags: &boolT,
-				TagSets: []TagAllowDeny{
-					{Allow: []string{"v1"}},
-					{Allow: []string{"v3"}},
-				},
-			},
-			expectTags: map[string]digest.Digest{
-				tsHost + "/test-cleanup4:v1": d1,
-				tsHost + "/test-cleanup4:v3": d3,
-			},
-			expectMissing: []string{
-				tsHost + "/test-cleanup4:v2",
-			},
-		},
+	t.Run("cleanupTags removes orphaned digest tag that matches exclusion pattern", func(t *testing.T) {
+		t.Skip("Skipping: requires registry with tag deletion support (not available in olareg)")
+
+		boolT := true
+		repo := "test-orphan-excl"
+
+		// Copy testrepo:v1 to get a real manifest and its digest.
+		srcRef := newRef(tsHost + "/testrepo:v1")
+		dstRef := newRef(tsHost + "/" + repo + ":v1")
+		if err := rc.ImageCopy(ctx, srcRef, dstRef); err != nil {
+			t.Fatalf("ImageCopy: %v", err)
+		}
+		m, err := rc.ManifestHead(ctx, dstRef, regclient.WithManifestRequireDigest())
+		if err != nil {
+			t.Fatalf("ManifestHead: %v", err)
+		}
+		baseDigest := m.GetDescriptor().Digest
+
+		// Build the sig tag name for the deleted digest.
+		sigTag := digestTag(baseDigest, "sig")
+
+		// Now delete v1 so the digest tag becomes orphaned.
+		if err := rc.TagDelete(ctx, dstRef); err != nil {
+			t.Fatalf("TagDelete v1: %v", err)
+		}
+
+		confBytes := `version: 1`
+		conf, err := ConfigLoadReader(bytes.NewReader([]byte(confBytes)))
+		if err != nil {
+			t.Fatalf("ConfigLoadReader: %v", err)
+		}
+		pq := pqueue.New(pqueue.Opts[throttle]{Max: 1, Next: throttleNext})
+		rOpts := rootOpts{
+			conf:     conf,
+			rc:       rc,
+			throttle: pq,
+			log:      slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+		}
+
+		// Run cleanup with an exclusion pattern that would normally protect .sig tags.
+		// The orphan check should override it and delete the tag anyway.
+		s := ConfigSync{
+			Source:             tsHost + "/" + repo,
+			Target:             tsHost + "/" + repo,
+			Type:               "repository",
+			CleanupTagsExclude: []string{`.*\.sig$`},
+		}
+		bTrue := boolT
+		s.CleanupTags = &bTrue
+		syncSetDefaults(&s, conf.Defaults)
+
+		if err := rOpts.cleanupTags(ctx, s, tsHost+"/"+repo); err != nil {
+			t.Fatalf("cleanupTags: %v", err)
+		}
+
+		// The orphaned .sig tag must be gone.
+		sigRef := newRef(tsHost + "/" + repo + ":" + sigTag)
+		_, err = rc.ManifestHead(ctx, sigRef)
+		if err == nil {
+			t.Errorf("orphaned sig tag %s still exists after cleanup", sigTag)
+		}
+	})
+}
+
+func TestServerReload(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	tempDir := t.TempDir()
+	err := copyfs.Copy(tempDir+"/testrepo", "../../testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to copy testrepo to tempdir: %v", err)
+	}
+	confFile := filepath.Join(tempDir, "regsync.yml")
+	writeConf := func(targets ...string) {
+		t.Helper()
+		conf := "version: 1\nsync:\n"
+		for _, tgt := range targets {
+			conf += fmt.Sprintf("- source: ocidir://%s/testrepo:v1\n  target: ocidir://%s/%s:v1\n  type: image\n  interval: 1h\n", tempDir, tempDir, tgt)
+		}
+		err := os.WriteFile(confFile, []byte(conf), 0o600)
+		if err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+	}
+	checkTarget := func(opts *rootOpts, tgt string) {
+		t.Helper()
+		r, err := ref.New(fmt.Sprintf("ocidir://%s/%s:v1", tempDir, tgt))
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		_, err = opts.rc.ManifestHead(ctx, r)
+		if err != nil {
+			t.Errorf("target %s missing: %v", tgt, err)
+		}
+	}
+	writeConf("out1", "out2")
+	opts := &rootOpts{
+		confFile: confFile,
+		log:      slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+	}
+	err = opts.loadConf()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	srv := newSyncServer(ctx, cancel, opts)
+	srv.add(opts, opts.conf.Sync)
+	srv.wg.Wait()
+	if err := srv.err(); err != nil {
+		t.Fatalf("initial sync failed: %v", err)
+	}
+	checkTarget(opts, "out1")
+	checkTarget(opts, "out2")
+	if len(srv.cron.Entries()) != 2 {
+		t.Fatalf("unexpected number of entries, expected 2, received %d", len(srv.cron.Entries()))
 	}
 
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			rootOpts := rootOpts{
-				conf:     conf,
-				rc:       rc,
-				throttle: pq,
-				log:      slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+	t.Run("invalid config", func(t *testing.T) {
+		err := os.WriteFile(confFile, []byte("version: 1\nsync:\n- source: ocidir://"+tempDir+"/testrepo:v1\n  target: ocidir://"+tempDir+"/out9:v1\n  type: image\n  schedule: not-a-schedule\n"), 0o600)
+		if err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+		err = srv.reload()
+		if err == nil {
+			t.Errorf("reload of an invalid config did not fail")
+		}
+		err = os.WriteFile(confFile, []byte("version: 1\nsync: [\n"), 0o600)
+		if err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+		err = srv.reload()
+		if err == nil {
+			t.Errorf("reload of an unparsable config did not fail")
+		}
+		if srv.opts.Load() != opts {
+			t.Errorf("settings replaced after a failed reload")
+		}
+		if len(srv.cron.Entries()) != 2 {
+			t.Errorf("unexpected number of entries, expected 2, received %d", len(srv.cron.Entries()))
+		}
+	})
+	t.Run("add and remove", func(t *testing.T) {
+		entries := srv.cron.Entries()
+		slices.SortFunc(entries, func(a, b cron.Entry) int { return int(a.ID - b.ID) })
+		writeConf("out1", "out3")
+		err := srv.reload()
+		if err != nil {
+			t.Fatalf("failed to reload: %v", err)
+		}
+		srv.wg.Wait()
+		if err := srv.err(); err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+		cur := srv.opts.Load()
+		if cur == opts || cur.rc == opts.rc {
+			t.Errorf("settings not replaced after reload")
+		}
+		checkTarget(cur, "out3")
+		newEntries := srv.cron.Entries()
+		if len(newEntries) != 2 {
+			t.Fatalf("unexpected number of entries, expected 2, received %d", len(newEntries))
+		}
+		// the unchanged entry keeps its schedule
+		if !slices.ContainsFunc(newEntries, func(e cron.Entry) bool { return e.ID == entries[0].ID }) {
+			t.Errorf("unchanged entry was rescheduled")
+		}
+		if slices.ContainsFunc(newEntries, func(e cron.Entry) bool { return e.ID == entries[1].ID }) {
+			t.Errorf("removed entry is still scheduled")
+		}
+	})
+	t.Run("audit log", func(t *testing.T) {
+		writeAudit := func(name string) {
+			t.Helper()
+			conf := fmt.Sprintf("version: 1\ndefaults:\n  auditLog: %s\nsync:\n- source: ocidir://%s/testrepo:v1\n  target: ocidir://%s/out1:v1\n  type: image\n  interval: 1h\n",
+				filepath.Join(tempDir, name), tempDir, tempDir)
+			err := os.WriteFile(confFile, []byte(conf), 0o600)
+			if err != nil {
+				t.Fatalf("failed to write config: %v", err)
 			}
-
-			// Setup: sync all tags first
-			syncSetDefaults(&tc.setupSync, conf.Defaults)
-			err = rootOpts.process(ctx, tc.setupSync, actionCopy)
+			err = srv.reload()
 			if err != nil {
-				t.Fatalf("setup sync failed: %v", err)
+				t.Fatalf("failed to reload: %v", err)
 			}
+			srv.wg.Wait()
+		}
+		writeAudit("audit1.json")
+		aw := srv.opts.Load().audit
+		file := aw.file
+		if file == nil {
+			t.Fatalf("audit log was not opened")
+		}
+		// an unchanged path reuses the open file
+		writeAudit("audit1.json")
+		if srv.opts.Load().audit != aw || aw.file != file {
+			t.Errorf("audit log was reopened on reload")
+		}
+		// a new path closes the previous file
+		writeAudit("audit2.json")
+		if aw.file == nil || aw.file == file || aw.name != filepath.Join(tempDir, "audit2.json") {
+			t.Errorf("audit log was not switched, name %s", aw.name)
+		}
+		if _, err := file.Write([]byte("test")); err == nil {
+			t.Errorf("previous audit log was not closed")
+		}
+		writeConf("out1", "out3")
+		err := srv.reload()
+		if err != nil {
+			t.Fatalf("failed to reload: %v", err)
+		}
+		srv.wg.Wait()
+		if aw.file != nil {
+			t.Errorf("audit log was not closed after removing it from the config")
+		}
+	})
+	t.Run("stdin", func(t *testing.T) {
+		srvStdin := newSyncServer(ctx, cancel, &rootOpts{confFile: "-", log: opts.log})
+		err := srvStdin.reload()
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("unexpected error, expected %v, received %v", ErrInvalidInput, err)
+		}
+	})
+}
 
-			// Run cleanup with filters
-			syncSetDefaults(&tc.cleanupSync, conf.Defaults)
-			err = rootOpts.process(ctx, tc.cleanupSync, actionCopy)
+func TestServerAdmin(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	tempDir := t.TempDir()
+	err := copyfs.Copy(tempDir+"/testrepo", "../../testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to copy testrepo to tempdir: %v", err)
+	}
+	conf := fmt.Sprintf(`
+version: 1
+sync:
+- source: ocidir://%[1]s/testrepo:v1
+  target: ocidir://%[1]s/out1:v1
+  type: image
+  interval: 1h
+- source: ocidir://%[1]s/testrepo:v2
+  target: ocidir://%[1]s/out2:v2
+  type: image
+  interval: 1h
+`, tempDir)
+	confFile := filepath.Join(tempDir, "regsync.yml")
+	err = os.WriteFile(confFile, []byte(conf), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	opts := &rootOpts{
+		confFile: confFile,
+		log:      slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+	}
+	err = opts.loadConf()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	srv := newSyncServer(ctx, cancel, opts)
+	srv.add(opts, opts.conf.Sync)
+	srv.wg.Wait()
+	ts := httptest.NewUnstartedServer(nil)
+	ts.Config.Handler = srv.adminHandler(ts.Listener.Addr())
+	ts.Start()
+	t.Cleanup(ts.Close)
+	send := func(t *testing.T, method, path string, expectStatus int, out any) {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		if method == http.MethodPost {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != expectStatus {
+			t.Fatalf("unexpected status, expected %d, received %d", expectStatus, resp.StatusCode)
+		}
+		if out != nil {
+			err = json.NewDecoder(resp.Body).Decode(out)
 			if err != nil {
-				t.Fatalf("cleanup sync failed: %v", err)
+				t.Fatalf("failed to parse response: %v", err)
 			}
+		}
+	}
+	entries := []adminEntry{}
+	send(t, http.MethodGet, "/entries", http.StatusOK, &entries)
+	if len(entries) != 2 {
+		t.Fatalf("unexpected number of entries, expected 2, received %d", len(entries))
+	}
+	if entries[0].Target != "ocidir://"+tempDir+"/out1:v1" || entries[0].Schedule != "@every 1h0m0s" {
+		t.Errorf("unexpected entry: %v", entries[0])
+	}
+	if entries[0].Last == nil || entries[0].Last.Action != "missing" || entries[0].Last.Error != "" || entries[0].Last.End.IsZero() {
+		t.Errorf("unexpected last result: %v", entries[0].Last)
+	}
+	id := strconv.Itoa(entries[0].ID)
 
-			// Verify expected tags exist
-			for tag, expectedDigest := range tc.expectTags {
-				r, err := ref.New(tag)
+	t.Run("pause", func(t *testing.T) {
+		e := adminEntry{}
+		send(t, http.MethodPost, "/entries/"+id+"/pause", http.StatusOK, &e)
+		if !e.Paused {
+			t.Errorf("entry not paused")
+		}
+		send(t, http.MethodGet, "/entries/"+id, http.StatusOK, &e)
+		if !e.Paused {
+			t.Errorf("entry not paused")
+		}
+		send(t, http.MethodPost, "/entries/"+id+"/resume", http.StatusOK, &e)
+		if e.Paused {
+			t.Errorf("entry not resumed")
+		}
+	})
+	t.Run("run", func(t *testing.T) {
+		err := os.RemoveAll(filepath.Join(tempDir, "out1"))
+		if err != nil {
+			t.Fatalf("failed to remove target: %v", err)
+		}
+		send(t, http.MethodPost, "/entries/"+id+"/run", http.StatusAccepted, nil)
+		srv.wg.Wait()
+		e := adminEntry{}
+		send(t, http.MethodGet, "/entries/"+id, http.StatusOK, &e)
+		if e.Running || e.Last == nil || e.Last.Action != "copy" || e.Last.Error != "" {
+			t.Errorf("unexpected entry after run: %v", e)
+		}
+		r, err := ref.New("ocidir://" + tempDir + "/out1:v1")
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		_, err = opts.rc.ManifestHead(ctx, r)
+		if err != nil {
+			t.Errorf("target was not synced: %v", err)
+		}
+	})
+	t.Run("errors", func(t *testing.T) {
+		send(t, http.MethodGet, "/entries/999", http.StatusNotFound, nil)
+		send(t, http.MethodPost, "/entries/abc/run", http.StatusBadRequest, nil)
+		send(t, http.MethodGet, "/entries/"+id+"/run", http.StatusMethodNotAllowed, nil)
+	})
+	t.Run("browser requests", func(t *testing.T) {
+		_, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+		if err != nil {
+			t.Fatalf("failed to parse listener address: %v", err)
+		}
+		tt := []struct {
+			name         string
+			host         string
+			contentType  string
+			expectStatus int
+		}{
+			{
+				name:         "form post",
+				contentType:  "application/x-www-form-urlencoded",
+				expectStatus: http.StatusUnsupportedMediaType,
+			},
+			{
+				name:         "missing content type",
+				expectStatus: http.StatusUnsupportedMediaType,
+			},
+			{
+				name:         "dns rebinding",
+				host:         "attacker.example.com:" + port,
+				contentType:  "application/json",
+				expectStatus: http.StatusForbidden,
+			},
+			{
+				name:         "other port",
+				host:         "localhost:1",
+				contentType:  "application/json",
+				expectStatus: http.StatusForbidden,
+			},
+			{
+				name:         "localhost",
+				host:         "localhost:" + port,
+				contentType:  "application/json; charset=utf-8",
+				expectStatus: http.StatusOK,
+			},
+		}
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				req, err := http.NewRequest(http.MethodPost, ts.URL+"/entries/"+id+"/resume", nil)
 				if err != nil {
-					t.Fatalf("cannot parse ref %s: %v", tag, err)
+					t.Fatalf("failed to create request: %v", err)
 				}
-				m, err := rc.ManifestHead(ctx, r)
-				if err != nil {
-					t.Errorf("expected tag does not exist: %s", tag)
-				} else if m.GetDescriptor().Digest != expectedDigest {
-					t.Errorf("digest mismatch for %s, expected %s, received %s",
-						tag, expectedDigest.String(), m.GetDescriptor().Digest.String())
+				if tc.host != "" {
+					req.Host = tc.host
 				}
-			}
-
-			// Verify missing tags don't exist
-			for _, missing := range tc.expectMissing {
-				r, err := ref.New(missing)
+				if tc.contentType != "" {
+					req.Header.Set("Content-Type", tc.contentType)
+				}
+				resp, err := http.DefaultClient.Do(req)
 				if err != nil {
-					t.Fatalf("cannot parse ref %s: %v", missing, err)
+					t.Fatalf("failed to send request: %v", err)
 				}
-				_, err = rc.ManifestHead(ctx, r)
-				if err == nil {
-					t.Errorf("tag exists that should be deleted: %s", missing)
+				_ = resp.Body.Close()
+				if resp.StatusCode != tc.expectStatus {
+					t.Errorf("unexpected status, expected %d, received %d", tc.expectStatus, resp.StatusCode)
 				}
-			}
-		})
-	}
+			})
+		}
+	})
+	t.Run("listen", func(t *testing.T) {
+		_, err := adminListen("0.0.0.0:0")
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("non-loopback address did not fail: %v", err)
+		}
+		l, err := adminListen("127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		_ = l.Close()
+	})
 }
 
-// TestCleanupSyncTypes tests cleanup with different sync types (9.2)
-// This test requires a registry that supports tag deletion and is skipped if not available
-func TestCleanupSyncTypes(t *testing.T) {
-	t.Skip("Skipping cleanup test: requires registry with tag deletion support (not available in olareg)")
+func TestPlan(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
-	boolT := true
-
-	// Setup test registry
 	tempDir := t.TempDir()
 	err := copyfs.Copy(tempDir+"/testrepo", "../../testdata/testrepo")
 	if err != nil {
 		t.Fatalf("failed to copy testrepo to tempdir: %v", err)
 	}
-
-	regHandler := olareg.New(oConfig.Config{
-		Storage: oConfig.ConfigStorage{
-			StoreType: oConfig.StoreMem,
-			RootDir:   "../../testdata",
-		},
-	})
-	ts := httptest.NewServer(regHandler)
-	tsURL, _ := url.Parse(ts.URL)
-	tsHost := tsURL.Host
-	t.Cleanup(func() {
-		ts.Close()
-		_ = regHandler.Close()
-	})
-
-	rcHosts := []config.Host{
-		{
-			Name:     tsHost,
-			Hostname: tsHost,
-			TLS:      config.TLSDisabled,
-		},
+	rc := regclient.New()
+	// populate the targets with a current image and a tag that cleanup removes
+	for _, cp := range [][2]string{{"testrepo:v2", "out:v2"}, {"testrepo:v1", "clean:v1"}, {"testrepo:v3", "clean:v3"}} {
+		rSrc, err := ref.New("ocidir://" + tempDir + "/" + cp[0])
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		rTgt, err := ref.New("ocidir://" + tempDir + "/" + cp[1])
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		err = rc.ImageCopy(ctx, rSrc, rTgt)
+		if err != nil {
+			t.Fatalf("failed to copy %s: %v", cp[0], err)
+		}
 	}
-	delayInit, _ := time.ParseDuration("0.05s")
-	delayMax, _ := time.ParseDuration("0.10s")
-	rc := regclient.New(
-		regclient.WithConfigHost(rcHosts...),
-		regclient.WithRegOpts(reg.WithDelay(delayInit, delayMax)),
-	)
-	pq := pqueue.New(pqueue.Opts[throttle]{
-		Max:  1,
-		Next: throttleNext,
-	})
-
-	confBytes := `
-version: 1
+	confFile := filepath.Join(tempDir, "regsync.yml")
+	conf := fmt.Sprintf(`version: 1
 defaults:
-  parallel: 1
-`
-	confRdr := bytes.NewReader([]byte(confBytes))
-	conf, err := ConfigLoadReader(confRdr)
+  skipDockerConfig: true
+sync:
+- source: ocidir://%[1]s/testrepo:v1
+  target: ocidir://%[1]s/out:v1
+  type: image
+- source: ocidir://%[1]s/testrepo:v2
+  target: ocidir://%[1]s/out:v2
+  type: image
+- source: ocidir://%[1]s/testrepo
+  target: ocidir://%[1]s/clean
+  type: repository
+  tags:
+    allow:
+    - v1
+  cleanupTags: true
+`, tempDir)
+	err = os.WriteFile(confFile, []byte(conf), 0o600)
 	if err != nil {
-		t.Fatalf("failed parsing config: %v", err)
+		t.Fatalf("failed to write config: %v", err)
 	}
-
-	tests := []struct {
-		name          string
-		setupSync     ConfigSync
-		cleanupSync   ConfigSync
-		expectCleanup bool
-	}{
-		{
-			name: "cleanup runs for repository type",
-			setupSync: ConfigSync{
-				Source: tsHost + "/testrepo",
-				Target: tsHost + "/test-type-repo",
-				Type:   "repository",
-			},
-			cleanupSync: ConfigSync{
-				Source:      tsHost + "/testrepo",
-				Target:      tsHost + "/test-type-repo",
-				Type:        "repository",
-				CleanupTags: &boolT,
-				Tags: TagAllowDeny{
-					Allow: []string{"v1"},
-				},
-			},
-			expectCleanup: true,
-		},
-		{
-			name: "cleanup does NOT run for image type",
-			setupSync: ConfigSync{
-				Source: tsHost + "/testrepo:v1",
-				Target: tsHost + "/test-type-image:v1",
-				Type:   "image",
-			},
-			cleanupSync: ConfigSync{
-				Source:      tsHost + "/testrepo:v2",
-				Target:      tsHost + "/test-type-image:v2",
-				Type:        "image",
-				CleanupTags: &boolT,
-			},
-			expectCleanup: false,
-		},
+	cmd, _ := NewRootCmd()
+	out := &bytes.Buffer{}
+	cmd.SetOut(out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"plan", "-c", confFile, "--format", "{{ json . }}"})
+	err = cmd.ExecuteContext(ctx)
+	if err != nil {
+		t.Fatalf("failed to run plan: %v", err)
 	}
-
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			rootOpts := rootOpts{
-				conf:     conf,
-				rc:       rc,
-				throttle: pq,
-				log:      slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
-			}
-
-			// Setup: sync initial tags
-			syncSetDefaults(&tc.setupSync, conf.Defaults)
-			err = rootOpts.process(ctx, tc.setupSync, actionCopy)
-			if err != nil {
-				t.Fatalf("setup sync failed: %v", err)
-			}
-
-			// For repository type, add an extra tag that should be cleaned up
-			if tc.cleanupSync.Type == "repository" {
-				// Copy v2 to target
-				extraSync := ConfigSync{
-					Source: tsHost + "/testrepo:v2",
-					Target: tc.cleanupSync.Target + ":v2",
-					Type:   "image",
-				}
-				syncSetDefaults(&extraSync, conf.Defaults)
-				err = rootOpts.process(ctx, extraSync, actionCopy)
-				if err != nil {
-					t.Fatalf("extra tag sync failed: %v", err)
-				}
-			}
-
-			// Run cleanup
-			syncSetDefaults(&tc.cleanupSync, conf.Defaults)
-			err = rootOpts.process(ctx, tc.cleanupSync, actionCopy)
-			if err != nil {
-				t.Fatalf("cleanup sync failed: %v", err)
-			}
-
-			// Verify cleanup behavior
-			if tc.expectCleanup {
-				// For repository type, v2 should be deleted
-				r, _ := ref.New(tc.cleanupSync.Target + ":v2")
-				_, err = rc.ManifestHead(ctx, r)
-				if err == nil {
-					t.Errorf("cleanup did not run: v2 tag still exists")
-				}
-			} else {
-				// For image type, both tags should exist
-				r1, _ := ref.New(tc.cleanupSync.Target)
-				r1 = r1.SetTag("v1")
-				_, err1 := rc.ManifestHead(ctx, r1)
-				r2, _ := ref.New(tc.cleanupSync.Target)
-				r2 = r2.SetTag("v2")
-				_, err2 := rc.ManifestHead(ctx, r2)
-				if err1 != nil || err2 != nil {
-					t.Errorf("cleanup ran when it should not have: v1 err=%v, v2 err=%v", err1, err2)
-				}
-			}
-		})
+	plan := syncPlan{}
+	err = json.Unmarshal(out.Bytes(), &plan)
+	if err != nil {
+		t.Fatalf("failed to parse plan output %s: %v", out.String(), err)
+	}
+	if plan.Copies != 1 || plan.Deletes == 0 || len(plan.Entries) != plan.Copies+plan.Deletes || plan.Bytes <= 0 {
+		t.Fatalf("unexpected plan: %s", out.String())
+	}
+	e := plan.Entries[0]
+	if e.Action != "copy" || e.Target != "ocidir://"+tempDir+"/out:v1" || e.Digest == "" || e.Manifests == 0 || e.Blobs == 0 || e.Bytes != plan.Bytes {
+		t.Errorf("unexpected copy entry: %+v", e)
+	}
+	if !slices.ContainsFunc(plan.Entries, func(e planEntry) bool {
+		return e.Action == "delete" && e.Target == "ocidir://"+tempDir+"/clean:v3"
+	}) {
+		t.Errorf("delete of clean:v3 missing from plan: %s", out.String())
+	}
+	if slices.ContainsFunc(plan.Entries, func(e planEntry) bool { return e.Target == "ocidir://"+tempDir+"/clean:v1" }) {
+		t.Errorf("plan includes the current clean:v1: %s", out.String())
+	}
+	// nothing is changed by the plan
+	for _, missing := range []string{"out:v1"} {
+		r, err := ref.New("ocidir://" + tempDir + "/" + missing)
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		if _, err := rc.ManifestHead(ctx, r); err == nil {
+			t.Errorf("plan copied %s", missing)
+		}
+	}
+	r, err := ref.New("ocidir://" + tempDir + "/clean:v3")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	if _, err := rc.ManifestHead(ctx, r); err != nil {
+		t.Errorf("plan deleted clean:v3: %v", err)
 	}
 }
 
-// TestCleanupErrorHandling tests error handling during cleanup (9.3)
-// This test requires a registry that supports tag deletion and is skipped if not available
-func TestCleanupErrorHandling(t *testing.T) {
-	t.Skip("Skipping cleanup test: requires registry with tag deletion support (not available in olareg)")
+func TestConfigOCI(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
-	boolT := true
-
-	// Setup test registry
 	tempDir := t.TempDir()
-	err := copyfs.Copy(tempDir+"/testrepo", "../../testdata/testrepo")
+	rc := regclient.New()
+	confRef, err := ref.New("ocidir://" + tempDir + "/configs:prod")
 	if err != nil {
-		t.Fatalf("failed to copy testrepo to tempdir: %v", err)
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	blobPut := func(t *testing.T, mt string, b []byte, annotations map[string]string) descriptor.Descriptor {
+		t.Helper()
+		d := descriptor.Descriptor{MediaType: mt, Digest: digest.FromBytes(b), Size: int64(len(b)), Annotations: annotations}
+		_, err := rc.BlobPut(ctx, confRef, d, bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("failed to push blob: %v", err)
+		}
+		return d
+	}
+	manifestPut := func(t *testing.T, r ref.Ref, layer descriptor.Descriptor) digest.Digest {
+		t.Helper()
+		m, err := manifest.New(manifest.WithOrig(v1.Manifest{
+			Versioned:    v1.ManifestSchemaVersion,
+			MediaType:    mediatype.OCI1Manifest,
+			ArtifactType: "application/vnd.regclient.regsync.config",
+			Config:       blobPut(t, mediatype.OCI1Empty, descriptor.EmptyData, nil),
+			Layers:       []descriptor.Descriptor{layer},
+		}))
+		if err != nil {
+			t.Fatalf("failed to create manifest: %v", err)
+		}
+		err = rc.ManifestPut(ctx, r, m)
+		if err != nil {
+			t.Fatalf("failed to push manifest: %v", err)
+		}
+		return m.GetDescriptor().Digest
+	}
+	confPut := func(t *testing.T, tgt string) digest.Digest {
+		t.Helper()
+		conf := fmt.Sprintf("version: 1\nsync:\n- source: ocidir://%s/testrepo:v1\n  target: ocidir://%s/%s:v1\n  type: image\n", tempDir, tempDir, tgt)
+		return manifestPut(t, confRef, blobPut(t, configOCIMediaType, []byte(conf), nil))
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyWrite := func(t *testing.T, filename string, pub crypto.PublicKey) {
+		t.Helper()
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			t.Fatalf("failed to marshal key: %v", err)
+		}
+		err = os.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600)
+		if err != nil {
+			t.Fatalf("failed to write key: %v", err)
+		}
+	}
+	keyFile := filepath.Join(tempDir, "cosign.pub")
+	keyWrite(t, keyFile, key.Public())
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	otherKeyFile := filepath.Join(tempDir, "other.pub")
+	keyWrite(t, otherKeyFile, otherKey.Public())
+	newOpts := func(verifyKey string) *rootOpts {
+		return &rootOpts{
+			confFile:      configOCIPrefix + confRef.CommonName(),
+			confVerifyKey: verifyKey,
+			confRC:        rc,
+			log:           slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+		}
 	}
 
-	regHandler := olareg.New(oConfig.Config{
-		Storage: oConfig.ConfigStorage{
-			StoreType: oConfig.StoreMem,
-			RootDir:   "../../testdata",
-		},
+	dig := confPut(t, "out1")
+	t.Run("load", func(t *testing.T) {
+		opts := newOpts("")
+		err := opts.loadConf()
+		if err != nil {
+			t.Fatalf("failed to load config: %v", err)
+		}
+		if len(opts.conf.Sync) != 1 || opts.conf.Sync[0].Target != "ocidir://"+tempDir+"/out1:v1" || opts.confDigest != dig {
+			t.Errorf("unexpected config: %v, digest %s", opts.conf.Sync, opts.confDigest)
+		}
+		changed, err := opts.configOCIChanged(ctx)
+		if err != nil || changed {
+			t.Errorf("unexpected change: %t, %v", changed, err)
+		}
 	})
-	ts := httptest.NewServer(regHandler)
-	tsURL, _ := url.Parse(ts.URL)
-	tsHost := tsURL.Host
-	t.Cleanup(func() {
-		ts.Close()
-		_ = regHandler.Close()
+	t.Run("unsigned", func(t *testing.T) {
+		err := newOpts(keyFile).loadConf()
+		if !errors.Is(err, ErrSignatureInvalid) {
+			t.Errorf("unexpected error, expected %v, received %v", ErrSignatureInvalid, err)
+		}
 	})
-
-	// Setup read-only registry
-	regROHandler := olareg.New(oConfig.Config{
-		Storage: oConfig.ConfigStorage{
-			StoreType: oConfig.StoreMem,
-			RootDir:   "../../testdata",
-			ReadOnly:  &boolT,
-		},
+	// sign the config with a cosign simple signing payload
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"%s"},"image":{"docker-manifest-digest":"%s"},"type":"cosign container image signature"},"optional":null}`, confRef.CommonName(), dig.String()))
+	h := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, h[:])
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	sigLayer := blobPut(t, cosignSimpleSigning, payload, map[string]string{cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(sig)})
+	manifestPut(t, confRef.SetTag(fmt.Sprintf("%s-%s.sig", dig.Algorithm(), dig.Encoded())), sigLayer)
+	t.Run("signed", func(t *testing.T) {
+		opts := newOpts(keyFile)
+		err := opts.loadConf()
+		if err != nil {
+			t.Fatalf("failed to load signed config: %v", err)
+		}
+		err = newOpts(otherKeyFile).loadConf()
+		if !errors.Is(err, ErrSignatureInvalid) {
+			t.Errorf("unexpected error with another key, expected %v, received %v", ErrSignatureInvalid, err)
+		}
 	})
-	tsRO := httptest.NewServer(regROHandler)
-	tsROURL, _ := url.Parse(tsRO.URL)
-	tsROHost := tsROURL.Host
-	t.Cleanup(func() {
-		tsRO.Close()
-		_ = regROHandler.Close()
+	t.Run("changed", func(t *testing.T) {
+		opts := newOpts("")
+		err := opts.loadConf()
+		if err != nil {
+			t.Fatalf("failed to load config: %v", err)
+		}
+		confPut(t, "out2")
+		changed, err := opts.configOCIChanged(ctx)
+		if err != nil || !changed {
+			t.Errorf("change not detected: %t, %v", changed, err)
+		}
+		// the signature of the previous digest does not apply to the new config
+		err = newOpts(keyFile).loadConf()
+		if !errors.Is(err, ErrSignatureInvalid) {
+			t.Errorf("unexpected error, expected %v, received %v", ErrSignatureInvalid, err)
+		}
 	})
-
-	rcHosts := []config.Host{
-		{
-			Name:     tsHost,
-			Hostname: tsHost,
-			TLS:      config.TLSDisabled,
-		},
-		{
-			Name:     tsROHost,
-			Hostname: tsROHost,
-			TLS:      config.TLSDisabled,
-		},
-	}
-	delayInit, _ := time.ParseDuration("0.05s")
-	delayMax, _ := time.ParseDuration("0.10s")
-	rc := regclient.New(
-		regclient.WithConfigHost(rcHosts...),
-		regclient.WithRegOpts(reg.WithDelay(delayInit, delayMax)),
-	)
-	pq := pqueue.New(pqueue.Opts[throttle]{
-		Max:  1,
-		Next: throttleNext,
+	t.Run("file with key", func(t *testing.T) {
+		opts := newOpts(keyFile)
+		opts.confFile = filepath.Join(tempDir, "regsync.yml")
+		err := opts.loadConf()
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("unexpected error, expected %v, received %v", ErrInvalidInput, err)
+		}
 	})
+}
 
-	confBytes := `
-version: 1
-defaults:
-  parallel: 1
-`
-	confRdr := bytes.NewReader([]byte(confBytes))
-	conf, err := ConfigLoadReader(confRdr)
-	if err != nil {
-		t.Fatalf("failed parsing config: %v", err)
-	}
-
-	tests := []struct {
-		name        string
-		setupSync   ConfigSync
-		cleanupSync ConfigSync
-		abortOnErr  bool
-		expectError bool
-	}{
-		{
-			name: "cleanup continues after tag deletion failure without abortOnErr",
-			setupSync: ConfigSync{
-				Source: tsHost + "/testrepo",
-				Target: tsROHost + "/test-error1",
-				Type:   "repository",
-			},
-			cleanupSync: ConfigSync{
-				Source:      tsHost + "/testrepo",
-				Target:      tsROHost + "/test-error1",
-				Type:        "repository",
-				CleanupTags: &boolT,
-				Tags: TagAllowDeny{
-					Allow: []string{"v1"},
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+	t.Run("parse", func(t *testing.T) {
+		tt := []struct {
+			name      string
+			body      string
+			expect    []webhookEvent
+			expectErr error
+		}{
+			{
+				name: "distribution",
+				body: `{"events":[
+					{"action":"push","target":{"mediaType":"application/octet-stream","repository":"app"},"request":{"host":"registry.example.org"}},
+					{"action":"push","target":{"mediaType":"application/vnd.oci.image.manifest.v1+json","repository":"app","tag":"v1"},"request":{"host":"registry.example.org"}},
+					{"action":"push","target":{"mediaType":"application/vnd.oci.image.index.v1+json","repository":"app"},"request":{"host":"registry.example.org"}},
+					{"action":"pull","target":{"mediaType":"application/vnd.oci.image.manifest.v1+json","repository":"app","tag":"v1"},"request":{"host":"registry.example.org"}}
+				]}`,
+				expect: []webhookEvent{
+					{Host: "registry.example.org", Repository: "app", Tag: "v1"},
+					{Host: "registry.example.org", Repository: "app"},
 				},
 			},
-			abortOnErr:  false,
-			expectError: true,
-		},
-		{
-			name: "cleanup aborts with abortOnErr",
-			setupSync: ConfigSync{
-				Source: tsHost + "/testrepo",
-				Target: tsROHost + "/test-error2",
-				Type:   "repository",
+			{
+				name: "harbor",
+				body: `{"type":"PUSH_ARTIFACT","event_data":{
+					"resources":[{"digest":"sha256:1234","tag":"v2","resource_url":"harbor.example.org/library/app:v2"}],
+					"repository":{"name":"app","namespace":"library","repo_full_name":"library/app"}}}`,
+				expect: []webhookEvent{
+					{Host: "harbor.example.org", Repository: "library/app", Tag: "v2"},
+				},
 			},
-			cleanupSync: ConfigSync{
-				Source:      tsHost + "/testrepo",
-				Target:      tsROHost + "/test-error2",
-				Type:        "repository",
-				CleanupTags: &boolT,
-				Tags: TagAllowDeny{
-					Allow: []string{"v1"},
+			{
+				name:   "harbor delete",
+				body:   `{"type":"DELETE_ARTIFACT","event_data":{"resources":[{"tag":"v2","resource_url":"harbor.example.org/library/app:v2"}]}}`,
+				expect: []webhookEvent{},
+			},
+			{
+				name: "quay",
+				body: `{"repository":"org/app","namespace":"org","name":"app","docker_url":"quay.io/org/app","updated_tags":["latest","v3"]}`,
+				expect: []webhookEvent{
+					{Host: "quay.io", Repository: "org/app", Tag: "latest"},
+					{Host: "quay.io", Repository: "org/app", Tag: "v3"},
 				},
 			},
-			abortOnErr:  true,
-			expectError: true,
-		},
-		{
-			name: "cleanup handles context cancellation",
-			setupSync: ConfigSync{
-				Source: tsHost + "/testrepo",
-				Target: tsHost + "/test-cancel",
-				Type:   "repository",
+			{
+				name:      "unknown",
+				body:      `{"hello":"world"}`,
+				expectErr: ErrInvalidInput,
 			},
-			cleanupSync: ConfigSync{
-				Source:      tsHost + "/testrepo",
-				Target:      tsHost + "/test-cancel",
-				Type:        "repository",
-				CleanupTags: &boolT,
-				Tags: TagAllowDeny{
-					Allow: []string{"v1"},
-				},
+			{
+				name:      "invalid json",
+				body:      `{"events":`,
+				expectErr: fmt.Errorf("failed to parse webhook: unexpected end of JSON input"),
 			},
-			abortOnErr:  false,
-			expectError: false,
-		},
-	}
-
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			rootOpts := rootOpts{
-				conf:       conf,
-				rc:         rc,
-				throttle:   pq,
-				log:        slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
-				abortOnErr: tc.abortOnErr,
-			}
-
-			// Setup: sync all tags first
-			syncSetDefaults(&tc.setupSync, conf.Defaults)
-			err = rootOpts.process(ctx, tc.setupSync, actionCopy)
-			if err != nil {
-				t.Fatalf("setup sync failed: %v", err)
-			}
-
-			// Run cleanup
-			syncSetDefaults(&tc.cleanupSync, conf.Defaults)
-
-			// For context cancellation test, use a cancelled context
-			testCtx := ctx
-			if tc.name == "cleanup handles context cancellation" {
-				cancelCtx, cancel := context.WithCancel(ctx)
-				cancel()
-				testCtx = cancelCtx
-			}
-
-			err = rootOpts.process(testCtx, tc.cleanupSync, actionCopy)
-
-			// Verify error expectation
-			if tc.expectError {
-				if err == nil {
-					t.Errorf("expected error but got none")
-				}
-			} else {
-				if err != nil && !errors.Is(err, ErrCanceled) {
-					t.Errorf("unexpected error: %v", err)
-				}
-			}
-		})
-	}
-}
-
-// TestThrottlePriority tests that the throttle priority mechanism works correctly
-// ensuring that actionMissing tasks are prioritized over actionCopy tasks
-func TestThrottlePriority(t *testing.T) {
-	t.Parallel()
-	ctx := context.Background()
-
-	// Create a priority queue with the throttleNext function
-	q := pqueue.New(pqueue.Opts[throttle]{
-		Max:  1, // Only allow 1 at a time for clearer testing
-		Next: throttleNext,
-	})
-
-	// Track the order in which tasks are processed
-	processed := make(chan int, 10)
-
-	// Acquire one slot to fill the queue
-	done0, err := q.Acquire(ctx, throttle{priority: 0})
-	if err != nil {
-		t.Fatalf("failed to acquire slot 0: %v", err)
-	}
-
-	// Queue up tasks with different priorities
-	// Task 2: normal priority (0)
-	go func() {
-		done, err := q.Acquire(ctx, throttle{priority: 0})
-		if err != nil {
-			t.Errorf("task 2 acquire failed: %v", err)
-			return
 		}
-		processed <- 2
-		time.Sleep(5 * time.Millisecond) // small delay to prevent immediate completion
-		done()
-	}()
-
-	// Task 3: high priority (1) - should jump ahead
-	time.Sleep(10 * time.Millisecond) // ensure task 2 queues first
-	go func() {
-		done, err := q.Acquire(ctx, throttle{priority: 1})
-		if err != nil {
-			t.Errorf("task 3 acquire failed: %v", err)
-			return
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				events, err := webhookParse([]byte(tc.body))
+				if tc.expectErr != nil {
+					if err == nil {
+						t.Errorf("did not fail")
+					} else if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
+						t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(events, tc.expect) {
+					t.Errorf("unexpected events, expected %v, received %v", tc.expect, events)
+				}
+			})
 		}
-		processed <- 3
-		time.Sleep(5 * time.Millisecond)
-		done()
-	}()
-
-	// Task 4: normal priority (0)
-	time.Sleep(10 * time.Millisecond)
-	go func() {
-		done, err := q.Acquire(ctx, throttle{priority: 0})
+	})
+	t.Run("match", func(t *testing.T) {
+		tt := []struct {
+			name   string
+			sync   ConfigSync
+			event  webhookEvent
+			expect bool
+		}{
+			{
+				name:   "image",
+				sync:   ConfigSync{Type: "image", Source: "registry.example.org/app:v1"},
+				event:  webhookEvent{Host: "registry.example.org", Repository: "app", Tag: "v1"},
+				expect: true,
+			},
+			{
+				name:   "image other tag",
+				sync:   ConfigSync{Type: "image", Source: "registry.example.org/app:v1"},
+				event:  webhookEvent{Host: "registry.example.org", Repository: "app", Tag: "v2"},
+				expect: false,
+			},
+			{
+				name:   "image without host",
+				sync:   ConfigSync{Type: "image", Source: "registry.example.org/app:v1"},
+				event:  webhookEvent{Repository: "app", Tag: "v1"},
+				expect: true,
+			},
+			{
+				name:   "image other host",
+				sync:   ConfigSync{Type: "image", Source: "registry.example.org/app:v1"},
+				event:  webhookEvent{Host: "registry.example.com", Repository: "app", Tag: "v1"},
+				expect: false,
+			},
+			{
+				name:   "docker hub",
+				sync:   ConfigSync{Type: "image", Source: "alpine:latest"},
+				event:  webhookEvent{Host: "docker.io", Repository: "library/alpine", Tag: "latest"},
+				expect: true,
+			},
+			{
+				name:   "repository",
+				sync:   ConfigSync{Type: "repository", Source: "registry.example.org/app"},
+				event:  webhookEvent{Host: "registry.example.org", Repository: "app", Tag: "v2"},
+				expect: true,
+			},
+			{
+				name:   "repository other repo",
+				sync:   ConfigSync{Type: "repository", Source: "registry.example.org/app"},
+				event:  webhookEvent{Host: "registry.example.org", Repository: "other", Tag: "v2"},
+				expect: false,
+			},
+			{
+				name:   "repository tag denied",
+				sync:   ConfigSync{Type: "repository", Source: "registry.example.org/app", Tags: TagAllowDeny{Allow: []string{"v.*"}, Deny: []string{"v2"}}},
+				event:  webhookEvent{Host: "registry.example.org", Repository: "app", Tag: "v2"},
+				expect: false,
+			},
+			{
+				name:   "repository digest",
+				sync:   ConfigSync{Type: "repository", Source: "registry.example.org/app", Tags: TagAllowDeny{Allow: []string{"v.*"}}},
+				event:  webhookEvent{Host: "registry.example.org", Repository: "app"},
+				expect: true,
+			},
+			{
+				name:   "registry",
+				sync:   ConfigSync{Type: "registry", Source: "registry.example.org"},
+				event:  webhookEvent{Host: "registry.example.org", Repository: "team/app", Tag: "v1"},
+				expect: true,
+			},
+			{
+				name:   "registry filter prefix",
+				sync:   ConfigSync{Type: "registryFilter", Source: "registry.example.org/team", Repos: RepoAllowDeny{Allow: []string{"app"}}},
+				event:  webhookEvent{Host: "registry.example.org", Repository: "team/app", Tag: "v1"},
+				expect: true,
+			},
+			{
+				name:   "registry filter other prefix",
+				sync:   ConfigSync{Type: "registryFilter", Source: "registry.example.org/team", Repos: RepoAllowDeny{Allow: []string{"app"}}},
+				event:  webhookEvent{Host: "registry.example.org", Repository: "other/app", Tag: "v1"},
+				expect: false,
+			},
+			{
+				name:   "registry filter denied",
+				sync:   ConfigSync{Type: "registryFilter", Source: "registry.example.org/team", Repos: RepoAllowDeny{Deny: []string{"app"}}},
+				event:  webhookEvent{Host: "registry.example.org", Repository: "team/app", Tag: "v1"},
+				expect: false,
+			},
+		}
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				if result := webhookMatch(tc.sync, tc.event); result != tc.expect {
+					t.Errorf("unexpected match, expected %t, received %t", tc.expect, result)
+				}
+			})
+		}
+	})
+	t.Run("server", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		tempDir := t.TempDir()
+		regHandler := olareg.New(oConfig.Config{
+			Storage: oConfig.ConfigStorage{
+				StoreType: oConfig.StoreMem,
+				RootDir:   "../../testdata",
+			},
+		})
+		ts := httptest.NewServer(regHandler)
+		tsURL, _ := url.Parse(ts.URL)
+		tsHost := tsURL.Host
+		t.Cleanup(func() {
+			ts.Close()
+			_ = regHandler.Close()
+		})
+		conf := fmt.Sprintf(`
+version: 1
+creds:
+- registry: %[2]s
+  tls: disabled
+sync:
+- source: %[2]s/testrepo:v1
+  target: ocidir://%[1]s/out1:v1
+  type: image
+  interval: 1h
+- source: %[2]s/testrepo:v2
+  target: ocidir://%[1]s/out2:v2
+  type: image
+  interval: 1h
+`, tempDir, tsHost)
+		confFile := filepath.Join(tempDir, "regsync.yml")
+		err := os.WriteFile(confFile, []byte(conf), 0o600)
 		if err != nil {
-			t.Errorf("task 4 acquire failed: %v", err)
-			return
+			t.Fatalf("failed to write config: %v", err)
 		}
-		processed <- 4
-		time.Sleep(5 * time.Millisecond)
-		done()
-	}()
-
-	// Task 5: high priority (1) - should also jump ahead
-	time.Sleep(10 * time.Millisecond)
-	go func() {
-		done, err := q.Acquire(ctx, throttle{priority: 1})
+		opts := &rootOpts{
+			confFile: confFile,
+			log:      slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+		}
+		err = opts.loadConf()
 		if err != nil {
-			t.Errorf("task 5 acquire failed: %v", err)
-			return
+			t.Fatalf("failed to load config: %v", err)
 		}
-		processed <- 5
-		time.Sleep(5 * time.Millisecond)
-		done()
-	}()
-
-	// Wait for tasks to queue up
-	time.Sleep(50 * time.Millisecond)
-
-	// Release the first slot and observe processing order
-	done0()
-
-	// Collect the processing order
-	var order []int
-	timeout := time.After(2 * time.Second)
-	for i := 0; i < 4; i++ {
-		select {
-		case id := <-processed:
-			order = append(order, id)
-		case <-timeout:
-			t.Fatalf("timeout waiting for tasks to complete, got %v", order)
+		srv := newSyncServer(ctx, cancel, opts)
+		srv.add(opts, opts.conf.Sync)
+		srv.wg.Wait()
+		hook := httptest.NewServer(srv.webhookHandler("secret"))
+		t.Cleanup(hook.Close)
+		body := fmt.Sprintf(`{"events":[{"action":"push","target":{"mediaType":"application/vnd.oci.image.index.v1+json","repository":"testrepo","tag":"v2"},"request":{"host":%q}}]}`, tsHost)
+		send := func(t *testing.T, path, auth string, expectStatus int) {
+			t.Helper()
+			req, err := http.NewRequest(http.MethodPost, hook.URL+path, bytes.NewReader([]byte(body)))
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if auth != "" {
+				req.Header.Set("Authorization", auth)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("failed to send request: %v", err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != expectStatus {
+				t.Fatalf("unexpected status, expected %d, received %d", expectStatus, resp.StatusCode)
+			}
 		}
-	}
-
-	// Verify that high priority tasks (3, 5) were processed before normal tasks (2, 4)
-	// Expected order: either [3, 5, 2, 4] or [5, 3, 2, 4] depending on timing
-	// But all high priority (3, 5) should come before all normal (2, 4)
-	firstNormalIdx := -1
-	lastHighIdx := -1
-	for i, id := range order {
-		if id == 2 || id == 4 {
-			if firstNormalIdx == -1 {
-				firstNormalIdx = i
+		send(t, "/webhook", "", http.StatusUnauthorized)
+		send(t, "/webhook", "Bearer wrong", http.StatusUnauthorized)
+		for _, dir := range []string{"out1", "out2"} {
+			err = os.RemoveAll(filepath.Join(tempDir, dir))
+			if err != nil {
+				t.Fatalf("failed to remove target: %v", err)
 			}
 		}
-		if id == 3 || id == 5 {
-			lastHighIdx = i
+		send(t, "/webhook", "Bearer secret", http.StatusAccepted)
+		send(t, "/webhook?token=secret", "", http.StatusAccepted)
+		srv.wg.Wait()
+		// only the matching entry is synced
+		if _, err := os.Stat(filepath.Join(tempDir, "out2", "index.json")); err != nil {
+			t.Errorf("matching entry was not synced: %v", err)
 		}
-	}
-
-	if firstNormalIdx != -1 && lastHighIdx > firstNormalIdx {
-		t.Errorf("priority queue failed: high priority task at index %d came after normal priority task at index %d, order: %v",
-			lastHighIdx, firstNormalIdx, order)
-	}
-
-	t.Logf("Tasks processed in order: %v (high priority tasks correctly prioritized)", order)
+		if _, err := os.Stat(filepath.Join(tempDir, "out1")); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("other entry was synced: %v", err)
+		}
+		srv.mu.Lock()
+		for _, list := range srv.entries {
+			for _, e := range list {
+				if e.sync.Source == tsHost+"/testrepo:v2" && (e.last == nil || e.last.Action != "copy" || e.last.Error != "") {
+					t.Errorf("unexpected last result: %v", e.last)
+				}
+			}
+		}
+		srv.mu.Unlock()
+	})
 }
 
-// TestThrottleNextFunction tests the throttleNext function directly
-func TestThrottleNextFunction(t *testing.T) {
-	tests := []struct {
-		name     string
-		queued   []*throttle
-		expected int
+func TestBackupPrune(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	err := copyfs.Copy(tempDir+"/testrepo", "../../testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to copy testrepo to tempdir: %v", err)
+	}
+	rc := regclient.New()
+	conf, err := ConfigLoadReader(bytes.NewReader([]byte("version: 1\n")))
+	if err != nil {
+		t.Fatalf("failed parsing config: %v", err)
+	}
+	pq := pqueue.New(pqueue.Opts[throttle]{
+		Max:  1,
+		Next: throttleNext,
+	})
+	r2, err := ref.New("ocidir://" + tempDir + "/testrepo:v2")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	m2, err := rc.ManifestHead(ctx, r2, regclient.WithManifestRequireDigest())
+	if err != nil {
+		t.Fatalf("failed to head v2: %v", err)
+	}
+	d2 := m2.GetDescriptor().Digest
+	tt := []struct {
+		name          string
+		keep          int
+		backup        string
+		exists        []string
+		missing       []string
+		expectBackups int
 	}{
 		{
-			name:     "empty queue",
-			queued:   []*throttle{},
-			expected: 0,
-		},
-		{
-			name:     "single item",
-			queued:   []*throttle{{priority: 0}},
-			expected: 0,
-		},
-		{
-			name:     "all same priority",
-			queued:   []*throttle{{priority: 0}, {priority: 0}, {priority: 0}},
-			expected: 0, // FIFO - returns first item
-		},
-		{
-			name:     "high priority at end",
-			queued:   []*throttle{{priority: 0}, {priority: 0}, {priority: 1}},
-			expected: 2,
-		},
-		{
-			name:     "high priority in middle",
-			queued:   []*throttle{{priority: 0}, {priority: 1}, {priority: 0}},
-			expected: 1,
+			name:          "keep all",
+			backup:        `{{ .Ref.Tag }}-{{ (time.Now).Format "20060102150405" }}`,
+			exists:        []string{"latest-20200101000000", "latest-20210101000000", "other-20190101000000"},
+			expectBackups: 3,
 		},
 		{
-			name:     "high priority at start",
-			queued:   []*throttle{{priority: 1}, {priority: 0}, {priority: 0}},
-			expected: 0,
+			name:          "keep two",
+			keep:          2,
+			backup:        `{{ .Ref.Tag }}-{{ (time.Now).Format "20060102150405" }}`,
+			exists:        []string{"latest-20210101000000", "other-20190101000000"},
+			missing:       []string{"latest-20200101000000"},
+			expectBackups: 2,
 		},
 		{
-			name:     "multiple high priority",
-			queued:   []*throttle{{priority: 0}, {priority: 1}, {priority: 1}},
-			expected: 1, // returns first high priority (FIFO within same priority)
+			name:          "static backup",
+			keep:          1,
+			backup:        `{{ .Ref.Tag }}-20200101000000`,
+			exists:        []string{"latest-20200101000000", "latest-20210101000000", "other-20190101000000"},
+			expectBackups: 2,
 		},
 	}
-
-	for _, tc := range tests {
+	for i, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			result := throttleNext(tc.queued, nil)
-			if result != tc.expected {
-				t.Errorf("expected index %d, got %d", tc.expected, result)
+			tgt, err := ref.New(fmt.Sprintf("ocidir://%s/backup%d:latest", tempDir, i))
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			// seed the target with the existing image and older backups
+			for _, tag := range []string{"latest", "latest-20200101000000", "latest-20210101000000", "other-20190101000000"} {
+				err = rc.ImageCopy(ctx, r2.SetTag("v1"), tgt.SetTag(tag))
+				if err != nil {
+					t.Fatalf("failed to seed %s: %v", tag, err)
+				}
+			}
+			s := ConfigSync{
+				Source:     r2.CommonName(),
+				Target:     tgt.CommonName(),
+				Type:       "image",
+				Backup:     tc.backup,
+				BackupKeep: tc.keep,
+			}
+			syncSetDefaults(&s, conf.Defaults)
+			opts := rootOpts{
+				conf:     conf,
+				rc:       rc,
+				throttle: pq,
+				log:      slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+			}
+			err = opts.process(ctx, s, actionCopy)
+			if err != nil {
+				t.Fatalf("failed to process: %v", err)
+			}
+			m, err := rc.ManifestHead(ctx, tgt, regclient.WithManifestRequireDigest())
+			if err != nil || m.GetDescriptor().Digest != d2 {
+				t.Errorf("target was not updated: %v", err)
+			}
+			tl, err := rc.TagList(ctx, tgt)
+			if err != nil {
+				t.Fatalf("failed to list tags: %v", err)
+			}
+			tags, _ := tl.GetTags()
+			for _, tag := range tc.exists {
+				if !slices.Contains(tags, tag) {
+					t.Errorf("missing tag %s: %v", tag, tags)
+				}
+			}
+			for _, tag := range tc.missing {
+				if slices.Contains(tags, tag) {
+					t.Errorf("tag %s was not pruned: %v", tag, tags)
+				}
+			}
+			backups := slices.DeleteFunc(tags, func(tag string) bool { return !strings.HasPrefix(tag, "latest-") })
+			if len(backups) != tc.expectBackups {
+				t.Errorf("unexpected backups, expected %d, received %v", tc.expectBackups, backups)
 			}
 		})
 	}
 }
 
-// TestDigestTagRe tests the digestTagRe regular expression against various tag strings.
-func TestDigestTagRe(t *testing.T) {
+func TestConfigBackupKeep(t *testing.T) {
 	t.Parallel()
-	tests := []struct {
-		tag     string
-		matches bool
-	}{
-		// valid cosign-style digest tags
-		{"sha256-abc123def456.sig", true},
-		{"sha256-abc123def456.att", true},
-		{"sha512-deadbeef.sig", true},
-		{"sha512-deadbeef.att", true},
-		// must have at least one hex char
-		{"sha256-0.sig", true},
-		// normal image tags must not match
-		{"v1", false},
-		{"latest", false},
-		{"v1.0.0", false},
-		// looks similar but wrong suffix
-		{"sha256-abc123.tar", false},
-		{"sha256-abc123", false},
-		// uppercase algorithm must not match (pattern requires lowercase)
-		{"SHA256-abc123.sig", false},
-		// no hex part
-		{"sha256-.sig", false},
-		// no algorithm part
-		{"-abc123.sig", false},
+	conf := `
+defaults:
+  backupKeep: 3
+sync:
+  - source: registry.example.org/proj
+    target: registry.example.com/proj
+    type: repository
+    backup: "{{ .Ref.Tag }}-{{ (time.Now).Format \"20060102\" }}"
+  - source: registry.example.org/app
+    target: registry.example.com/app
+    type: repository
+    backupKeep: 1
+`
+	c, err := ConfigLoadReader(bytes.NewReader([]byte(conf)))
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
 	}
-	for _, tc := range tests {
-		t.Run(tc.tag, func(t *testing.T) {
-			got := digestTagRe.MatchString(tc.tag)
-			if got != tc.matches {
-				t.Errorf("digestTagRe.MatchString(%q) = %v, want %v", tc.tag, got, tc.matches)
-			}
-		})
+	if c.Sync[0].BackupKeep != 3 || c.Sync[1].BackupKeep != 1 {
+		t.Errorf("unexpected backup keep, expected [3 1], received [%d %d]", c.Sync[0].BackupKeep, c.Sync[1].BackupKeep)
+	}
+	_, err = ConfigLoadReader(bytes.NewReader([]byte(`
+sync:
+  - source: registry.example.org/proj
+    target: registry.example.com/proj
+    type: repository
+    backupKeep: -1
+`)))
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("unexpected error, expected %v, received %v", ErrInvalidInput, err)
 	}
 }
 
-// TestIsOrphanedDigestTag tests the isOrphanedDigestTag helper using a live
-// olareg registry.  It covers:
-//   - a plain tag (never treated as a digest tag)
-//   - a .sig/.att tag whose referenced digest still exists (not orphaned)
-//   - a .sig/.att tag whose referenced digest has been deleted (orphaned)
-//
-// It also verifies end-to-end that cleanupTags removes orphaned digest tags
-// even when they match a CleanupTagsExclude pattern.
-func TestIsOrphanedDigestTag(t *testing.T) {
+func TestOnError(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
-
-	regHandler := olareg.New(oConfig.Config{
-		Storage: oConfig.ConfigStorage{
-			StoreType: oConfig.StoreMem,
-			RootDir:   "../../testdata",
+	tempDir := t.TempDir()
+	err := copyfs.Copy(tempDir+"/testrepo", "../../testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to copy testrepo to tempdir: %v", err)
+	}
+	rc := regclient.New()
+	tt := []struct {
+		name         string
+		onError      string
+		args         []string
+		expectCopy   bool
+		expectFailed bool
+	}{
+		{
+			name:         "default",
+			expectCopy:   true,
+			expectFailed: true,
+		},
+		{
+			name:         "continue",
+			onError:      onErrorContinue,
+			expectCopy:   true,
+			expectFailed: true,
+		},
+		{
+			name:         "abort entry",
+			onError:      onErrorAbortEntry,
+			expectCopy:   true,
+			expectFailed: true,
+		},
+		{
+			name:    "abort run",
+			onError: onErrorAbortRun,
+		},
+		{
+			name:    "abort on error flag",
+			onError: onErrorContinue,
+			args:    []string{"--abort-on-error"},
 		},
-	})
-	ts := httptest.NewServer(regHandler)
-	tsURL, _ := url.Parse(ts.URL)
-	tsHost := tsURL.Host
-	t.Cleanup(func() {
-		ts.Close()
-		_ = regHandler.Close()
-	})
-
-	rcHosts := []config.Host{{Name: tsHost, Hostname: tsHost, TLS: config.TLSDisabled}}
-	rc := regclient.New(regclient.WithConfigHost(rcHosts...))
-
-	// ── helpers ──────────────────────────────────────────────────────────────
-
-	// newRef parses a reference and fatals on error.
-	newRef := func(s string) ref.Ref {
-		r, err := ref.New(s)
-		if err != nil {
-			t.Fatalf("ref.New(%q): %v", s, err)
-		}
-		return r
 	}
-
-	// digestTag builds the cosign-style tag string from a digest and extension.
-	digestTag := func(d digest.Digest, ext string) string {
-		return string(d.Algorithm()) + "-" + d.Hex() + "." + ext
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			confFile := filepath.Join(tempDir, fmt.Sprintf("regsync%d.yml", i))
+			conf := fmt.Sprintf(`version: 1
+defaults:
+  skipDockerConfig: true
+  onError: %[3]s
+sync:
+- source: ocidir://%[1]s/testrepo:missing
+  target: ocidir://%[1]s/out%[2]d:missing
+  type: image
+- source: ocidir://%[1]s/testrepo:v1
+  target: ocidir://%[1]s/out%[2]d:v1
+  type: image
+`, tempDir, i, tc.onError)
+			err := os.WriteFile(confFile, []byte(conf), 0o600)
+			if err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+			cmd, _ := NewRootCmd()
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetArgs(append([]string{"once", "-c", confFile, "-v", "error"}, tc.args...))
+			err = cmd.ExecuteContext(ctx)
+			if err == nil {
+				t.Fatalf("once did not fail")
+			}
+			if errors.Is(err, ErrSyncFailed) != tc.expectFailed {
+				t.Errorf("unexpected error, expected sync failed %t, received %v", tc.expectFailed, err)
+			}
+			r, err := ref.New(fmt.Sprintf("ocidir://%s/out%d:v1", tempDir, i))
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			_, err = rc.ManifestHead(ctx, r)
+			if (err == nil) != tc.expectCopy {
+				t.Errorf("unexpected copy of the second entry, expected %t, received %v", tc.expectCopy, err)
+			}
+		})
 	}
-
-	// ── sub-tests ─────────────────────────────────────────────────────────────
-
-	t.Run("non-digest tag is never orphaned", func(t *testing.T) {
-		tgtRef := newRef(tsHost + "/testrepo")
-		orphaned, err := isOrphanedDigestTag(ctx, rc, tgtRef, "latest")
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
-		}
-		if orphaned {
-			t.Errorf("expected non-digest tag to not be orphaned")
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ConfigLoadReader(bytes.NewReader([]byte(`
+defaults:
+  onError: abort
+sync:
+- source: registry.example.org/proj
+  target: registry.example.com/proj
+  type: repository
+`)))
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("unexpected error, expected %v, received %v", ErrInvalidInput, err)
 		}
 	})
+}
 
-	t.Run("sig tag whose referenced digest exists is not orphaned", func(t *testing.T) {
-		// Resolve the actual digest of testrepo:v1.
-		srcRef := newRef(tsHost + "/testrepo:v1")
-		m, err := rc.ManifestHead(ctx, srcRef, regclient.WithManifestRequireDigest())
-		if err != nil {
-			t.Fatalf("ManifestHead testrepo:v1: %v", err)
-		}
-		baseDigest := m.GetDescriptor().Digest
-
-		// isOrphanedDigestTag resolves the digest against tgtRef's repository.
-		// testrepo has the manifest, so use testrepo as the target repo.
-		tgtRef := newRef(tsHost + "/testrepo")
-		tag := digestTag(baseDigest, "sig")
-
-		orphaned, err := isOrphanedDigestTag(ctx, rc, tgtRef, tag)
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
-		}
-		if orphaned {
-			t.Errorf("sig tag %s should not be orphaned: digest %s still exists", tag, baseDigest)
-		}
+func TestPostSyncHook(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook test requires sh")
+	}
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	err := copyfs.Copy(tempDir+"/testrepo", "../../testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to copy testrepo to tempdir: %v", err)
+	}
+	rc := regclient.New()
+	conf, err := ConfigLoadReader(bytes.NewReader([]byte(`
+version: 1
+defaults:
+  hooks:
+    postSync: ["sh", "-c", "env | grep ^REGSYNC_ | sort > \"$0\""]
+`)))
+	if err != nil {
+		t.Fatalf("failed parsing config: %v", err)
+	}
+	pq := pqueue.New(pqueue.Opts[throttle]{
+		Max:  1,
+		Next: throttleNext,
 	})
-
-	t.Run("att tag whose referenced digest exists is not orphaned", func(t *testing.T) {
-		srcRef := newRef(tsHost + "/testrepo:v2")
-		m, err := rc.ManifestHead(ctx, srcRef, regclient.WithManifestRequireDigest())
-		if err != nil {
-			t.Fatalf("ManifestHead testrepo:v2: %v", err)
-		}
-		baseDigest := m.GetDescriptor().Digest
-		tgtRef := newRef(tsHost + "/testrepo")
-		tag := digestTag(baseDigest, "att")
-
-		orphaned, err := isOrphanedDigestTag(ctx, rc, tgtRef, tag)
+	opts := rootOpts{
+		conf:     conf,
+		rc:       rc,
+		throttle: pq,
+		log:      slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+	}
+	digests := map[string]digest.Digest{}
+	for _, tag := range []string{"v1", "v2"} {
+		r, err := ref.New("ocidir://" + tempDir + "/testrepo:" + tag)
 		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
-		}
-		if orphaned {
-			t.Errorf("att tag %s should not be orphaned: digest %s still exists", tag, baseDigest)
+			t.Fatalf("failed to parse ref: %v", err)
 		}
-	})
-
-	t.Run("sig tag with non-existent digest is orphaned", func(t *testing.T) {
-		fakeDigest := digest.NewDigestFromEncoded(digest.SHA256,
-			"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
-		tgtRef := newRef(tsHost + "/testrepo")
-		tag := digestTag(fakeDigest, "sig")
-
-		orphaned, err := isOrphanedDigestTag(ctx, rc, tgtRef, tag)
+		m, err := rc.ManifestHead(ctx, r, regclient.WithManifestRequireDigest())
 		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
+			t.Fatalf("failed to head %s: %v", tag, err)
 		}
-		if !orphaned {
-			t.Errorf("sig tag %s should be orphaned: digest does not exist", tag)
-		}
-	})
-
-	t.Run("att tag with non-existent digest is orphaned", func(t *testing.T) {
-		fakeDigest := digest.NewDigestFromEncoded(digest.SHA256,
-			"bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb")
-		tgtRef := newRef(tsHost + "/testrepo")
-		tag := digestTag(fakeDigest, "att")
+		digests[tag] = m.GetDescriptor().Digest
+	}
+	tgt := "ocidir://" + tempDir + "/hook:latest"
+	tt := []struct {
+		name   string
+		src    string
+		expect []string
+	}{
+		{
+			name: "new image",
+			src:  "v1",
+			expect: []string{
+				"REGSYNC_DIGEST=" + digests["v1"].String(),
+				"REGSYNC_PREVIOUS=",
+				"REGSYNC_SOURCE=ocidir://" + tempDir + "/testrepo:v1",
+				"REGSYNC_TAG=latest",
+				"REGSYNC_TARGET=" + tgt,
+				"REGSYNC_TYPE=image",
+			},
+		},
+		{
+			name: "updated image",
+			src:  "v2",
+			expect: []string{
+				"REGSYNC_DIGEST=" + digests["v2"].String(),
+				"REGSYNC_PREVIOUS=" + digests["v1"].String(),
+				"REGSYNC_SOURCE=ocidir://" + tempDir + "/testrepo:v2",
+				"REGSYNC_TAG=latest",
+				"REGSYNC_TARGET=" + tgt,
+				"REGSYNC_TYPE=image",
+			},
+		},
+		{
+			name: "unchanged image",
+			src:  "v2",
+		},
+	}
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			outFile := filepath.Join(tempDir, fmt.Sprintf("hook%d.env", i))
+			s := ConfigSync{
+				Source: "ocidir://" + tempDir + "/testrepo:" + tc.src,
+				Target: tgt,
+				Type:   "image",
+			}
+			syncSetDefaults(&s, conf.Defaults)
+			s.Hooks.PostSync = append(slices.Clone(s.Hooks.PostSync), outFile)
+			err := opts.process(ctx, s, actionCopy)
+			if err != nil {
+				t.Fatalf("failed to process: %v", err)
+			}
+			out, err := os.ReadFile(outFile)
+			if tc.expect == nil {
+				if err == nil {
+					t.Errorf("hook ran for an unchanged image: %s", out)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("hook did not run: %v", err)
+			}
+			lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+			if !slices.Equal(lines, tc.expect) {
+				t.Errorf("unexpected hook env, expected %v, received %v", tc.expect, lines)
+			}
+		})
+	}
+}
 
-		orphaned, err := isOrphanedDigestTag(ctx, rc, tgtRef, tag)
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
-		}
-		if !orphaned {
-			t.Errorf("att tag %s should be orphaned: digest does not exist", tag)
-		}
+func TestStaging(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("hook test requires sh")
+	}
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	err := copyfs.Copy(tempDir+"/testrepo", "../../testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to copy testrepo to tempdir: %v", err)
+	}
+	rc := regclient.New()
+	conf, err := ConfigLoadReader(bytes.NewReader([]byte(`
+version: 1
+defaults:
+  staging: "{{ .Ref.Tag }}-staging"
+  hooks:
+    validate: ["sh", "-c", "env | grep ^REGSYNC_ | sort > \"$0\"; exit $1"]
+`)))
+	if err != nil {
+		t.Fatalf("failed parsing config: %v", err)
+	}
+	pq := pqueue.New(pqueue.Opts[throttle]{
+		Max:  1,
+		Next: throttleNext,
 	})
-
-	t.Run("cleanupTags removes orphaned digest tag that matches exclusion pattern", func(t *testing.T) {
-		t.Skip("Skipping: requires registry with tag deletion support (not available in olareg)")
-
-		boolT := true
-		repo := "test-orphan-excl"
-
-		// Copy testrepo:v1 to get a real manifest and its digest.
-		srcRef := newRef(tsHost + "/testrepo:v1")
-		dstRef := newRef(tsHost + "/" + repo + ":v1")
-		if err := rc.ImageCopy(ctx, srcRef, dstRef); err != nil {
-			t.Fatalf("ImageCopy: %v", err)
-		}
-		m, err := rc.ManifestHead(ctx, dstRef, regclient.WithManifestRequireDigest())
+	opts := rootOpts{
+		conf:     conf,
+		rc:       rc,
+		throttle: pq,
+		log:      slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
+	}
+	digests := map[string]digest.Digest{}
+	for _, tag := range []string{"v1", "v2"} {
+		r, err := ref.New("ocidir://" + tempDir + "/testrepo:" + tag)
 		if err != nil {
-			t.Fatalf("ManifestHead: %v", err)
-		}
-		baseDigest := m.GetDescriptor().Digest
-
-		// Build the sig tag name for the deleted digest.
-		sigTag := digestTag(baseDigest, "sig")
-
-		// Now delete v1 so the digest tag becomes orphaned.
-		if err := rc.TagDelete(ctx, dstRef); err != nil {
-			t.Fatalf("TagDelete v1: %v", err)
+			t.Fatalf("failed to parse ref: %v", err)
 		}
-
-		confBytes := `version: 1`
-		conf, err := ConfigLoadReader(bytes.NewReader([]byte(confBytes)))
+		m, err := rc.ManifestHead(ctx, r, regclient.WithManifestRequireDigest())
 		if err != nil {
-			t.Fatalf("ConfigLoadReader: %v", err)
-		}
-		pq := pqueue.New(pqueue.Opts[throttle]{Max: 1, Next: throttleNext})
-		rOpts := rootOpts{
-			conf:     conf,
-			rc:       rc,
-			throttle: pq,
-			log:      slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})),
-		}
-
-		// Run cleanup with an exclusion pattern that would normally protect .sig tags.
-		// The orphan check should override it and delete the tag anyway.
-		s := ConfigSync{
-			Source:             tsHost + "/" + repo,
-			Target:             tsHost + "/" + repo,
-			Type:               "repository",
-			CleanupTagsExclude: []string{`.*\.sig$`},
+			t.Fatalf("failed to head %s: %v", tag, err)
 		}
-		bTrue := boolT
-		s.CleanupTags = &bTrue
-		syncSetDefaults(&s, conf.Defaults)
+		digests[tag] = m.GetDescriptor().Digest
+	}
+	tgt, err := ref.New("ocidir://" + tempDir + "/staging:latest")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	stage := tgt.SetTag("latest-staging")
+	tt := []struct {
+		name         string
+		src          string
+		exit         string
+		expectErr    error
+		expectTgt    digest.Digest
+		expectStage  digest.Digest
+		expectNoHook bool
+	}{
+		{
+			name:        "rejected",
+			src:         "v1",
+			exit:        "1",
+			expectErr:   ErrValidationFailed,
+			expectStage: digests["v1"],
+		},
+		{
+			name:      "validated",
+			src:       "v1",
+			exit:      "0",
+			expectTgt: digests["v1"],
+		},
+		{
+			name:         "unchanged",
+			src:          "v1",
+			exit:         "1",
+			expectTgt:    digests["v1"],
+			expectNoHook: true,
+		},
+		{
+			name:        "rejected update",
+			src:         "v2",
+			exit:        "1",
+			expectErr:   ErrValidationFailed,
+			expectTgt:   digests["v1"],
+			expectStage: digests["v2"],
+		},
+		{
+			name:      "validated update",
+			src:       "v2",
+			exit:      "0",
+			expectTgt: digests["v2"],
+		},
+	}
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			outFile := filepath.Join(tempDir, fmt.Sprintf("validate%d.env", i))
+			s := ConfigSync{
+				Source: "ocidir://" + tempDir + "/testrepo:" + tc.src,
+				Target: tgt.CommonName(),
+				Type:   "image",
+			}
+			syncSetDefaults(&s, conf.Defaults)
+			s.Hooks.Validate = append(slices.Clone(s.Hooks.Validate), outFile, tc.exit)
+			err := opts.process(ctx, s, actionCopy)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected error %v, received %v", tc.expectErr, err)
+			}
+			for _, check := range []struct {
+				r   ref.Ref
+				dig digest.Digest
+			}{{r: tgt, dig: tc.expectTgt}, {r: stage, dig: tc.expectStage}} {
+				m, err := rc.ManifestHead(ctx, check.r, regclient.WithManifestRequireDigest())
+				if check.dig == "" {
+					if err == nil {
+						t.Errorf("%s exists with digest %s", check.r.CommonName(), m.GetDescriptor().Digest)
+					}
+				} else if err != nil {
+					t.Errorf("failed to head %s: %v", check.r.CommonName(), err)
+				} else if m.GetDescriptor().Digest != check.dig {
+					t.Errorf("unexpected digest for %s, expected %s, received %s", check.r.CommonName(), check.dig, m.GetDescriptor().Digest)
+				}
+			}
+			out, err := os.ReadFile(outFile)
+			if tc.expectNoHook {
+				if err == nil {
+					t.Errorf("hook ran for an unchanged image: %s", out)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("hook did not run: %v", err)
+			}
+			lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+			expect := []string{
+				"REGSYNC_DIGEST=" + digests[tc.src].String(),
+				"REGSYNC_SOURCE=ocidir://" + tempDir + "/testrepo:" + tc.src,
+				"REGSYNC_STAGING=" + stage.AddDigest(digests[tc.src].String()).CommonName(),
+				"REGSYNC_STAGING_DIGEST=" + digests[tc.src].String(),
+				"REGSYNC_TAG=latest",
+				"REGSYNC_TARGET=" + tgt.CommonName(),
+				"REGSYNC_TYPE=image",
+			}
+			if !slices.Equal(lines, expect) {
+				t.Errorf("unexpected hook env, expected %v, received %v", expect, lines)
+			}
+		})
+	}
 
-		if err := rOpts.cleanupTags(ctx, s, tsHost+"/"+repo); err != nil {
-			t.Fatalf("cleanupTags: %v", err)
+	t.Run("config", func(t *testing.T) {
+		_, err := ConfigLoadReader(bytes.NewReader([]byte(`
+version: 1
+sync:
+  - source: registry.example.org/repo:v1
+    target: registry.example.org/mirror:v1
+    type: image
+    hooks:
+      validate: ["true"]
+`)))
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("validate without staging, expected %v, received %v", ErrInvalidInput, err)
 		}
-
-		// The orphaned .sig tag must be gone.
-		sigRef := newRef(tsHost + "/" + repo + ":" + sigTag)
-		_, err = rc.ManifestHead(ctx, sigRef)
-		if err == nil {
-			t.Errorf("orphaned sig tag %s still exists after cleanup", sigTag)
+		for _, staging := range []string{"{{ .Ref.Tag }}", "staging/{{ .Ref.Tag }}", " ", "{{ .Missing }}"} {
+			_, err := expandStaging(ConfigSync{Staging: staging}, tgt)
+			if err == nil {
+				t.Errorf("staging template %q did not fail", staging)
+			}
 		}
 	})
 }
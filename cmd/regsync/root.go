@@ -4,12 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"regexp"
 	"slices"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	// crypto libraries included for go-digest
@@ -17,19 +22,22 @@ import (
 	_ "crypto/sha512"
 
 	"github.com/opencontainers/go-digest"
-	"github.com/robfig/cron/v3"
 	"github.com/spf13/cobra"
 
 	"github.com/regclient/regclient"
 	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/internal/bwlimit"
 	"github.com/regclient/regclient/internal/cobradoc"
+	"github.com/regclient/regclient/internal/httptrace"
 	"github.com/regclient/regclient/internal/pqueue"
 	"github.com/regclient/regclient/internal/semver"
+	"github.com/regclient/regclient/internal/units"
 	"github.com/regclient/regclient/internal/version"
 	"github.com/regclient/regclient/pkg/template"
 	"github.com/regclient/regclient/scheme"
 	"github.com/regclient/regclient/scheme/reg"
 	"github.com/regclient/regclient/types"
+	"github.com/regclient/regclient/types/blob"
 	"github.com/regclient/regclient/types/descriptor"
 	"github.com/regclient/regclient/types/errs"
 	"github.com/regclient/regclient/types/manifest"
@@ -48,6 +56,7 @@ const (
 	actionCheck actionType = iota
 	actionCopy
 	actionMissing
+	actionPlan
 )
 
 // throttle is used for limiting concurrent sync steps from running.
@@ -72,16 +81,28 @@ func throttleNext(queued, active []*throttle) int {
 }
 
 type rootOpts struct {
-	confFile   string
-	verbosity  string
-	logopts    []string
-	log        *slog.Logger
-	format     string // for Go template formatting of various commands
-	abortOnErr bool
-	missing    bool
-	conf       *Config
-	rc         *regclient.RegClient
-	throttle   *pqueue.Queue[throttle]
+	confFile      string
+	confVerifyKey string               // public key file used to verify the signature of an oci:// config
+	confRefresh   time.Duration        // interval to check for changes to an oci:// config in server mode
+	confDigest    digest.Digest        // digest of the loaded oci:// config
+	confRC        *regclient.RegClient // client for pulling an oci:// config, defaults to using the docker config
+	verbosity     string
+	logopts       []string
+	log           *slog.Logger
+	format        string // for Go template formatting of various commands
+	abortOnErr    bool
+	admin         string
+	webhook       string // listen address for registry webhooks in server mode
+	webhookToken  string // file containing the token required by the webhook listener
+	missing       bool
+	plan          *syncPlan // changes collected by the plan command
+	conf          *Config
+	rc            *regclient.RegClient
+	throttle      *pqueue.Queue[throttle]
+	logHTTP       string                                         // file to write http request logs
+	logHTTPMax    string                                         // largest http body to include in the http request logs
+	httpTrace     func(next http.RoundTripper) http.RoundTripper // middleware writing the http request logs
+	audit         *auditWriter                                   // audit log shared by each reload of the config
 }
 
 func NewRootCmd() (*cobra.Command, *rootOpts) {
@@ -97,14 +118,59 @@ More details at <https://github.com/regclient/regclient>`,
 	}
 	cmd.PersistentFlags().StringVarP(&opts.verbosity, "verbosity", "v", slog.LevelInfo.String(), "Log level (trace, debug, info, warn, error)")
 	cmd.PersistentFlags().StringArrayVar(&opts.logopts, "logopt", []string{}, "Log options")
+	cmd.PersistentFlags().StringVar(&opts.logHTTP, "log-http", "", "Append a log of every http request to a file, with credentials redacted")
+	cmd.PersistentFlags().StringVar(&opts.logHTTPMax, "log-http-body", "", "Include http bodies up to this size in the http request log (e.g. 4KiB)")
 
 	serverCmd := &cobra.Command{
 		Use:   "server",
 		Short: "run the regsync server",
-		Long:  `Sync registries according to the configuration.`,
-		Args:  cobra.RangeArgs(0, 0),
-		RunE:  opts.runServer,
+		Long: `Sync registries according to the configuration.
+Sending a SIGHUP reloads the configuration file, including the registry logins.
+The configuration may be pulled from an OCI artifact with "oci://<image_ref>", using the logins from the docker config.
+The artifact contains the configuration as a single layer, or a layer with the media type
+"application/vnd.regclient.regsync.config.v1+yaml". The --config-verify-key flag requires a
+cosign signature of the artifact, and --config-refresh reloads the configuration when the artifact digest changes.
+Removed sync entries are unscheduled, and added sync entries are scheduled and immediately copy any missing images.
+Running tasks finish with the previous configuration.
+If the new configuration is invalid, an error is logged and the previous configuration remains in use.
+The --admin flag enables an HTTP API on a loopback address or unix socket for operators:
+  GET  /entries             list the scheduled sync entries with their last result
+  GET  /entries/{id}        show a single sync entry
+  POST /entries/{id}/run    sync the entry immediately
+  POST /entries/{id}/pause  skip scheduled runs of the entry
+  POST /entries/{id}/resume resume scheduled runs of the entry
+POST requests must include the header "Content-Type: application/json", and requests to a TCP address
+must use a loopback address or localhost in the Host header.
+The --webhook flag listens for registry push notifications on POST /webhook, immediately syncing each entry
+with a matching source. Docker distribution notifications, Harbor webhooks, and Quay repository push
+notifications are supported. With --webhook-token-file, requests must include the token in the Authorization
+header or the "token" query parameter.`,
+		Example: `
+# run the server
+regsync server -c regsync.yml
+
+# run the server with a signed config from a registry, checking for updates every 5 minutes
+regsync server -c oci://registry.example.org/configs/regsync:prod \
+  --config-verify-key cosign.pub --config-refresh 5m
+
+# run the server with the admin API on a unix socket
+regsync server -c regsync.yml --admin unix:///run/regsync.sock
+
+# list the sync entries with the admin API
+curl --unix-socket /run/regsync.sock http://localhost/entries
+
+# trigger an immediate sync of entry 2
+curl --unix-socket /run/regsync.sock -X POST -H "Content-Type: application/json" http://localhost/entries/2/run
+
+# sync when the source registry sends a push notification to http://regsync.example.org:8080/webhook
+regsync server -c regsync.yml --webhook :8080 --webhook-token-file /run/secrets/webhook-token`,
+		Args: cobra.RangeArgs(0, 0),
+		RunE: opts.runServer,
 	}
+	serverCmd.Flags().StringVar(&opts.admin, "admin", "", "Listen address for the admin API (localhost:port or unix:///path)")
+	serverCmd.Flags().DurationVar(&opts.confRefresh, "config-refresh", 0, "Interval to check an oci:// config for changes, reloading when the digest changes")
+	serverCmd.Flags().StringVar(&opts.webhook, "webhook", "", "Listen address for registry webhooks that trigger an immediate sync (host:port)")
+	serverCmd.Flags().StringVar(&opts.webhookToken, "webhook-token-file", "", "File containing the token required by the webhook listener")
 	checkCmd := &cobra.Command{
 		Use:   "check",
 		Short: "processes each sync command once but skip actual copy",
@@ -115,12 +181,33 @@ sync step is finished.`,
 		Args: cobra.RangeArgs(0, 0),
 		RunE: opts.runCheck,
 	}
+	planCmd := &cobra.Command{
+		Use:   "plan",
+		Short: "output the copies and deletes each sync command would run",
+		Long: `Processes each sync command in the configuration file in order without changing the targets.
+Images needing a copy are checked with a dry run, and the size of the blobs
+that would be pulled or mounted is estimated from head requests on the target.
+Tags removed by cleanupTags are listed as deletes.
+No jobs are run in parallel, and the plan is output after the last sync step.`,
+		Example: `
+# review the changes before running regsync once
+regsync plan -c regsync.yml
+
+# output the plan as json
+regsync plan -c regsync.yml --format '{{ jsonPretty . }}'`,
+		Args: cobra.RangeArgs(0, 0),
+		RunE: opts.runPlan,
+	}
+	planCmd.Flags().StringVar(&opts.format, "format", "", "Format output with go template syntax")
 	onceCmd := &cobra.Command{
 		Use:   "once",
 		Short: "processes each sync command once, ignoring cron schedule",
 		Long: `Processes each sync command in the configuration file in order.
-No jobs are run in parallel, and the command returns after any error or last
-sync step is finished.`,
+No jobs are run in parallel, and the command returns after the last sync step
+is finished, or after an error in a sync step with "onError: abortRun".
+The exit code is 0 when every sync step succeeds, 2 when any sync step failed
+after every step was processed, and 1 when the run was aborted or could not
+start.`,
 		Args: cobra.RangeArgs(0, 0),
 		RunE: opts.runOnce,
 	}
@@ -132,13 +219,15 @@ sync step is finished.`,
 		Args:  cobra.RangeArgs(0, 0),
 		RunE:  opts.runConfig,
 	}
-	for _, curCmd := range []*cobra.Command{serverCmd, checkCmd, onceCmd, configCmd} {
-		curCmd.Flags().StringVarP(&opts.confFile, "config", "c", "", "Config file")
+	for _, curCmd := range []*cobra.Command{serverCmd, checkCmd, planCmd, onceCmd, configCmd} {
+		curCmd.Flags().StringVarP(&opts.confFile, "config", "c", "", "Config file, \"-\" for stdin, or \"oci://<image_ref>\" for an artifact")
 		_ = curCmd.MarkFlagFilename("config")
 		_ = curCmd.MarkFlagRequired("config")
+		curCmd.Flags().StringVar(&opts.confVerifyKey, "config-verify-key", "", "Public key file to verify the cosign signature of an oci:// config")
+		_ = curCmd.MarkFlagFilename("config-verify-key")
 	}
-	for _, curCmd := range []*cobra.Command{serverCmd, checkCmd, onceCmd} {
-		curCmd.Flags().BoolVar(&opts.abortOnErr, "abort-on-error", false, "Immediately abort on any errors")
+	for _, curCmd := range []*cobra.Command{serverCmd, checkCmd, planCmd, onceCmd} {
+		curCmd.Flags().BoolVar(&opts.abortOnErr, "abort-on-error", false, "Immediately abort on any errors, overriding the onError setting")
 	}
 
 	versionCmd := &cobra.Command{
@@ -163,6 +252,7 @@ sync step is finished.`,
 	cmd.AddCommand(
 		serverCmd,
 		checkCmd,
+		planCmd,
 		onceCmd,
 		configCmd,
 		versionCmd,
@@ -193,7 +283,23 @@ func (opts *rootOpts) rootPreRun(cmd *cobra.Command, args []string) error {
 	} else {
 		opts.log = slog.New(slog.NewTextHandler(cmd.ErrOrStderr(), &slog.HandlerOptions{Level: lvl}))
 	}
-	return nil
+	if opts.logHTTP == "" {
+		if opts.logHTTPMax != "" {
+			return fmt.Errorf("--log-http-body requires --log-http%.0w", ErrInvalidInput)
+		}
+		return nil
+	}
+	traceOpts := []httptrace.Opt{}
+	if opts.logHTTPMax != "" {
+		limit, err := units.ParseSize(opts.logHTTPMax)
+		if err != nil {
+			return fmt.Errorf("failed to parse http body size: %w%.0w", err, ErrInvalidInput)
+		}
+		traceOpts = append(traceOpts, httptrace.WithBodyLimit(limit))
+	}
+	// the same middleware is reused when the config is reloaded
+	opts.httpTrace, err = httptrace.NewFile(opts.logHTTP, traceOpts...)
+	return err
 }
 
 func (opts *rootOpts) runVersion(cmd *cobra.Command, args []string) error {
@@ -226,16 +332,18 @@ func (opts *rootOpts) runOnce(cmd *cobra.Command, args []string) error {
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 	errs := []error{}
+	aborted := false
 	for _, s := range opts.conf.Sync {
 		if opts.conf.Defaults.Parallel > 0 {
 			wg.Go(func() {
 				err := opts.process(ctx, s, action)
 				if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, ErrCanceled) {
-					if opts.abortOnErr {
-						cancel()
-					}
 					mu.Lock()
 					errs = append(errs, err)
+					if opts.abortRun(s) {
+						aborted = true
+						cancel()
+					}
 					mu.Unlock()
 				}
 			})
@@ -243,127 +351,171 @@ func (opts *rootOpts) runOnce(cmd *cobra.Command, args []string) error {
 			err := opts.process(ctx, s, action)
 			if err != nil {
 				errs = append(errs, err)
-				if opts.abortOnErr {
+				if opts.abortRun(s) {
+					aborted = true
 					break
 				}
 			}
 		}
 	}
 	wg.Wait()
-	return errors.Join(errs...)
+	return syncErr(errs, aborted, len(opts.conf.Sync))
+}
+
+// onError returns the error policy of a sync entry.
+// The --abort-on-error flag aborts the run on any error.
+func (opts *rootOpts) onError(s ConfigSync) string {
+	if opts.abortOnErr {
+		return onErrorAbortRun
+	}
+	if s.OnError == "" {
+		return onErrorContinue
+	}
+	return s.OnError
+}
+
+// abortEntry returns true when an error stops processing the remaining images of the sync entry.
+func (opts *rootOpts) abortEntry(s ConfigSync) bool {
+	onError := opts.onError(s)
+	return onError == onErrorAbortEntry || onError == onErrorAbortRun
+}
+
+// abortRun returns true when an error in the sync entry stops processing every other entry.
+func (opts *rootOpts) abortRun(s ConfigSync) bool {
+	return opts.onError(s) == onErrorAbortRun
+}
+
+// syncErr combines the errors from processing each sync entry.
+// Errors from a run that processed every entry wrap ErrSyncFailed, resulting in a distinct exit code.
+func syncErr(errs []error, aborted bool, total int) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	err := errors.Join(errs...)
+	if aborted {
+		return err
+	}
+	return fmt.Errorf("%d of %d sync entries failed: %w%.0w", len(errs), total, err, ErrSyncFailed)
 }
 
 // runServer stays running with cron scheduled tasks
 func (opts *rootOpts) runServer(cmd *cobra.Command, args []string) error {
-	err := opts.loadConf()
+	if opts.confRefresh > 0 && !strings.HasPrefix(opts.confFile, configOCIPrefix) {
+		return fmt.Errorf("config refresh requires an %s config%.0w", configOCIPrefix, ErrInvalidInput)
+	}
+	if opts.webhookToken != "" && opts.webhook == "" {
+		return fmt.Errorf("webhook token requires a webhook listener%.0w", ErrInvalidInput)
+	}
+	token, err := webhookToken(opts.webhookToken)
+	if err != nil {
+		return err
+	}
+	err = opts.loadConf()
 	if err != nil {
 		return err
 	}
 	ctx, cancel := context.WithCancel(cmd.Context())
 	defer cancel()
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-	errs := []error{}
-	c := cron.New(cron.WithChain(
-		cron.SkipIfStillRunning(cron.DefaultLogger),
-	))
-	for _, s := range opts.conf.Sync {
-		sched := s.Schedule
-		if sched == "" && s.Interval != 0 {
-			sched = "@every " + s.Interval.String()
-		}
-		if sched != "" {
-			opts.log.Debug("Scheduled task",
-				slog.String("source", s.Source),
-				slog.String("target", s.Target),
-				slog.String("type", s.Type),
-				slog.String("sched", sched))
-			_, err := c.AddFunc(sched, func() {
-				opts.log.Debug("Running task",
-					slog.String("source", s.Source),
-					slog.String("target", s.Target),
-					slog.String("type", s.Type))
-				wg.Add(1)
-				defer wg.Done()
-				err := opts.process(ctx, s, actionCopy)
-				if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, ErrCanceled) {
-					if opts.abortOnErr {
-						cancel()
-					}
-					mu.Lock()
-					errs = append(errs, err)
-					mu.Unlock()
-				}
-			})
-			if err != nil {
-				opts.log.Error("Failed to schedule cron",
-					slog.String("source", s.Source),
-					slog.String("target", s.Target),
-					slog.String("sched", sched),
-					slog.String("err", err.Error()))
-				errs = append(errs, err)
-				if opts.abortOnErr {
-					break
-				}
-			}
-			// immediately copy any images that are missing from target
-			if opts.conf.Defaults.Parallel > 0 {
-				wg.Go(func() {
-					err := opts.process(ctx, s, actionMissing)
-					if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, ErrCanceled) {
-						if opts.abortOnErr {
-							cancel()
-						}
-						mu.Lock()
-						errs = append(errs, err)
-						mu.Unlock()
-					}
-				})
-			} else {
-				err := opts.process(ctx, s, actionMissing)
-				if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, ErrCanceled) {
-					mu.Lock()
-					errs = append(errs, err)
-					mu.Unlock()
-					if opts.abortOnErr {
-						break
-					}
-				}
-			}
-		} else {
-			opts.log.Error("No schedule or interval found, ignoring",
-				slog.String("source", s.Source),
-				slog.String("target", s.Target),
-				slog.String("type", s.Type))
+	srv := newSyncServer(ctx, cancel, opts)
+	if opts.admin != "" {
+		l, err := adminListen(opts.admin)
+		if err != nil {
+			return err
 		}
-	}
+		adminDone := make(chan struct{})
+		go func() {
+			srv.adminServe(ctx, l)
+			close(adminDone)
+		}()
+		defer func() {
+			cancel()
+			<-adminDone
+		}()
+	}
+	if opts.webhook != "" {
+		l, err := net.Listen("tcp", opts.webhook)
+		if err != nil {
+			return fmt.Errorf("failed to listen for webhooks on %s: %w", opts.webhook, err)
+		}
+		if token == "" {
+			opts.log.Warn("Webhook listener does not require a token",
+				slog.String("addr", opts.webhook))
+		}
+		webhookDone := make(chan struct{})
+		go func() {
+			srv.webhookServe(ctx, l, token)
+			close(webhookDone)
+		}()
+		defer func() {
+			cancel()
+			<-webhookDone
+		}()
+	}
+	srv.add(opts, opts.conf.Sync)
 	// wait for any initial copies to finish
-	wg.Wait()
+	srv.wg.Wait()
 	if ctx.Err() != nil {
-		return errors.Join(errs...)
+		return srv.err()
 	}
 	// run cleanup on startup to ensure defined state
 	cleanupErr := opts.runCleanupForAllTargets(ctx)
 	if cleanupErr != nil {
 		opts.log.Error("Startup cleanup encountered errors",
 			slog.String("error", cleanupErr.Error()))
-		errs = append(errs, cleanupErr)
+		srv.appendErr(cleanupErr)
 		if opts.abortOnErr {
-			return errors.Join(errs...)
+			return srv.err()
 		}
 	}
-	// start the server and wait until interrupted
-	c.Start()
-	done := ctx.Done()
-	if done != nil {
-		<-done
+	// start the server and wait until interrupted, reloading the config on a SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	// poll an oci:// config for changes
+	var refresh <-chan time.Time
+	if opts.confRefresh > 0 {
+		ticker := time.NewTicker(opts.confRefresh)
+		defer ticker.Stop()
+		refresh = ticker.C
+	}
+	srv.cron.Start()
+	done := false
+	for !done {
+		select {
+		case <-ctx.Done():
+			done = true
+		case <-hup:
+			opts.log.Info("Reloading config",
+				slog.String("file", opts.confFile))
+			if err := srv.reload(); err != nil {
+				opts.log.Error("Failed to reload config, continuing with the previous config",
+					slog.String("err", err.Error()))
+			}
+		case <-refresh:
+			changed, err := srv.opts.Load().configOCIChanged(ctx)
+			if err != nil {
+				opts.log.Warn("Failed to check config for changes",
+					slog.String("file", opts.confFile),
+					slog.String("err", err.Error()))
+				continue
+			}
+			if !changed {
+				continue
+			}
+			opts.log.Info("Reloading changed config",
+				slog.String("file", opts.confFile))
+			if err := srv.reload(); err != nil {
+				opts.log.Error("Failed to reload config, continuing with the previous config",
+					slog.String("err", err.Error()))
+			}
+		}
 	}
 	// perform a clean shutdown
 	opts.log.Info("Stopping server")
-	c.Stop()
+	srv.cron.Stop()
 	opts.log.Debug("Waiting on running tasks")
-	wg.Wait()
-	return errors.Join(errs...)
+	srv.wg.Wait()
+	return srv.err()
 }
 
 // run check is used for a dry-run
@@ -374,25 +526,35 @@ func (opts *rootOpts) runCheck(cmd *cobra.Command, args []string) error {
 	}
 	errs := []error{}
 	ctx := cmd.Context()
+	aborted := false
 	for _, s := range opts.conf.Sync {
 		err := opts.process(ctx, s, actionCheck)
 		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, ErrCanceled) {
 			errs = append(errs, err)
-			if opts.abortOnErr {
+			if opts.abortRun(s) {
+				aborted = true
 				break
 			}
 		}
 	}
-	return errors.Join(errs...)
+	return syncErr(errs, aborted, len(opts.conf.Sync))
 }
 
 func (opts *rootOpts) loadConf() error {
 	var err error
+	if opts.confVerifyKey != "" && !strings.HasPrefix(opts.confFile, configOCIPrefix) {
+		return fmt.Errorf("a config verify key requires an %s config%.0w", configOCIPrefix, ErrInvalidInput)
+	}
 	if opts.confFile == "-" {
 		opts.conf, err = ConfigLoadReader(os.Stdin)
 		if err != nil {
 			return err
 		}
+	} else if confRef, ok := strings.CutPrefix(opts.confFile, configOCIPrefix); ok {
+		opts.conf, opts.confDigest, err = opts.configLoadOCI(confRef)
+		if err != nil {
+			return err
+		}
 	} else if opts.confFile != "" {
 		r, err := os.Open(opts.confFile)
 		if err != nil {
@@ -421,24 +583,58 @@ func (opts *rootOpts) loadConf() error {
 	rcOpts := []regclient.Opt{
 		regclient.WithSlog(opts.log),
 	}
+	if opts.audit == nil {
+		opts.audit = &auditWriter{}
+	}
+	if opts.conf.Defaults.AuditLog != "" {
+		rcOpts = append(rcOpts, regclient.WithAuditLogger(slog.New(slog.NewJSONHandler(opts.audit, nil))))
+	}
+	if opts.conf.Defaults.PinFile != "" {
+		pins, err := config.PinsLoadFile(opts.conf.Defaults.PinFile)
+		if err != nil {
+			return err
+		}
+		rcOpts = append(rcOpts, regclient.WithPins(pins))
+	}
+	if opts.httpTrace != nil {
+		rcOpts = append(rcOpts, regclient.WithHTTPMiddleware(opts.httpTrace))
+	}
+	if f := opts.conf.Defaults.Faults; f != nil {
+		rcOpts = append(rcOpts, regclient.WithRegOpts(reg.WithFaults(reg.Faults{
+			ErrorRate:    f.ErrorRate,
+			TruncateRate: f.TruncateRate,
+			CorruptRate:  f.CorruptRate,
+			SlowRate:     f.SlowRate,
+			SlowDelay:    f.SlowDelay,
+			Seed:         f.Seed,
+		})))
+	}
 	if opts.conf.Defaults.BlobLimit != 0 {
 		rcOpts = append(rcOpts, regclient.WithRegOpts(reg.WithBlobLimit(opts.conf.Defaults.BlobLimit)))
 	}
+	if opts.conf.Defaults.manifestMax > 0 {
+		rcOpts = append(rcOpts, regclient.WithManifestMaxSize(opts.conf.Defaults.manifestMax))
+	}
+	retryAfterMax := opts.conf.Defaults.RetryAfterMax
+	if retryAfterMax == 0 {
+		retryAfterMax = retryAfterMaxDefault
+	}
+	if retryAfterMax > 0 {
+		rcOpts = append(rcOpts, regclient.WithRegOpts(reg.WithRetryAfterMax(retryAfterMax)))
+	}
 	if opts.conf.Defaults.CacheCount > 0 && opts.conf.Defaults.CacheTime > 0 {
 		rcOpts = append(rcOpts, regclient.WithRegOpts(reg.WithCache(opts.conf.Defaults.CacheTime, opts.conf.Defaults.CacheCount)))
 	}
+	if opts.conf.Defaults.Memoize {
+		rcOpts = append(rcOpts, regclient.WithMemoize())
+	}
 	if !opts.conf.Defaults.SkipDockerConf {
 		rcOpts = append(rcOpts, regclient.WithDockerCreds(), regclient.WithDockerCerts())
 	}
 	if opts.conf.Defaults.UserAgent != "" {
 		rcOpts = append(rcOpts, regclient.WithUserAgent(opts.conf.Defaults.UserAgent))
 	} else {
-		info := version.GetInfo()
-		if info.VCSTag != "" {
-			rcOpts = append(rcOpts, regclient.WithUserAgent(UserAgent+" ("+info.VCSTag+")"))
-		} else {
-			rcOpts = append(rcOpts, regclient.WithUserAgent(UserAgent+" ("+info.VCSRef+")"))
-		}
+		rcOpts = append(rcOpts, regclient.WithUserAgent(defaultUserAgent()))
 	}
 	rcHosts := []config.Host{}
 	for _, host := range opts.conf.Creds {
@@ -451,17 +647,103 @@ func (opts *rootOpts) loadConf() error {
 	if len(rcHosts) > 0 {
 		rcOpts = append(rcOpts, regclient.WithConfigHost(rcHosts...))
 	}
+	// the audit log is only changed after the rest of the config is loaded
+	if opts.conf.Defaults.AuditLog != "" {
+		err = opts.audit.open(opts.conf.Defaults.AuditLog)
+		if err != nil {
+			return err
+		}
+	} else if err := opts.audit.Close(); err != nil {
+		opts.log.Warn("Failed to close audit log",
+			slog.String("err", err.Error()))
+	}
 	opts.rc = regclient.New(rcOpts...)
 	return nil
 }
 
+// auditWriter writes the audit log to a file that is kept open across config reloads.
+// Clients from the previous config write to the new file when the path changes.
+type auditWriter struct {
+	mu   sync.Mutex
+	name string
+	file *os.File
+}
+
+// open switches to the named file, reusing the current file when the name is unchanged.
+func (aw *auditWriter) open(name string) error {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	if aw.file != nil && aw.name == name {
+		return nil
+	}
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log %s: %w", name, err)
+	}
+	if aw.file != nil {
+		_ = aw.file.Close()
+	}
+	aw.name = name
+	aw.file = file
+	return nil
+}
+
+func (aw *auditWriter) Write(p []byte) (int, error) {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	if aw.file == nil {
+		// the audit log was removed from the config
+		return len(p), nil
+	}
+	return aw.file.Write(p)
+}
+
+// Close closes the current file.
+func (aw *auditWriter) Close() error {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	if aw.file == nil {
+		return nil
+	}
+	err := aw.file.Close()
+	aw.name = ""
+	aw.file = nil
+	return err
+}
+
+// defaultUserAgent returns the user agent with the version of regsync.
+func defaultUserAgent() string {
+	info := version.GetInfo()
+	if info.VCSTag != "" {
+		return UserAgent + " (" + info.VCSTag + ")"
+	}
+	return UserAgent + " (" + info.VCSRef + ")"
+}
+
+// bwReadCloser limits the bandwidth of a blob while closing the original reader.
+type bwReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
 // process a sync step
 func (opts *rootOpts) process(ctx context.Context, s ConfigSync, action actionType) error {
+	defer opts.logHostStats(s)
 	switch s.Type {
 	case "registry":
 		if err := opts.processRegistry(ctx, s, s.Source, s.Target, action); err != nil {
 			return err
 		}
+	case "registryFilter":
+		// the source may include a repository path prefix, and a filter is required to avoid mirroring an entire registry by mistake
+		if !strings.Contains(s.Source, "/") && len(s.Repos.Allow) == 0 {
+			opts.log.Error("Registry filter requires a repository path prefix in the source or a repos allow list",
+				slog.String("source", s.Source))
+			return ErrInvalidInput
+		}
+		if err := opts.processRegistry(ctx, s, s.Source, s.Target, action); err != nil {
+			return err
+		}
 	case "repository":
 		if err := opts.processRepo(ctx, s, s.Source, s.Target, action); err != nil {
 			return err
@@ -471,7 +753,7 @@ func (opts *rootOpts) process(ctx context.Context, s ConfigSync, action actionTy
 			return err
 		}
 	default:
-		opts.log.Error("Type not recognized, must be one of: registry, repository, or image",
+		opts.log.Error("Type not recognized, must be one of: registry, registryFilter, repository, or image",
 			slog.Any("step", s),
 			slog.String("type", s.Type))
 		return ErrInvalidInput
@@ -479,7 +761,40 @@ func (opts *rootOpts) process(ctx context.Context, s ConfigSync, action actionTy
 	return nil
 }
 
+// logHostStats reports requests to the source and target registries that were delayed by rate limits or backoffs.
+// The counts include all requests since regsync started.
+func (opts *rootOpts) logHostStats(s ConfigSync) {
+	for _, name := range []string{s.Source, s.Target} {
+		host, _, _ := strings.Cut(name, "/")
+		if s.Type == "repository" || s.Type == "image" {
+			r, err := ref.New(name)
+			if err != nil || r.Scheme != "reg" {
+				continue
+			}
+			host = r.Registry
+		}
+		stats := opts.rc.HostStats(host)
+		if stats.RateLimited == 0 && stats.QueuedTotal == 0 {
+			continue
+		}
+		opts.log.Info("Registry requests delayed",
+			slog.String("host", host),
+			slog.Int64("rate-limited", stats.RateLimited),
+			slog.Int64("queued-total", stats.QueuedTotal),
+			slog.Int("queued", stats.Queued),
+			slog.Duration("queue-wait", stats.QueueWait),
+			slog.Int64("queue-expired", stats.QueueExpired))
+	}
+}
+
+// processRegistry syncs each repository returned by the registry _catalog API.
+// The src may include a repository path prefix, limiting the sync to repositories under that path.
+// Repository filters are applied to the path relative to the prefix, and the relative path is appended to tgt.
 func (opts *rootOpts) processRegistry(ctx context.Context, s ConfigSync, src, tgt string, action actionType) error {
+	host, prefix, _ := strings.Cut(strings.TrimSuffix(src, "/"), "/")
+	if prefix != "" {
+		prefix = prefix + "/"
+	}
 	last := ""
 	errs := []error{}
 	// loop through pages of the _catalog response
@@ -488,7 +803,7 @@ func (opts *rootOpts) processRegistry(ctx context.Context, s ConfigSync, src, tg
 		if last != "" {
 			repoOpts = append(repoOpts, scheme.WithRepoLast(last))
 		}
-		sRepos, err := opts.rc.RepoList(ctx, src, repoOpts...)
+		sRepos, err := opts.rc.RepoList(ctx, host, repoOpts...)
 		if err != nil {
 			opts.log.Error("Failed to list source repositories",
 				slog.String("source", src),
@@ -506,6 +821,16 @@ func (opts *rootOpts) processRegistry(ctx context.Context, s ConfigSync, src, tg
 			break
 		}
 		last = sRepoList[len(sRepoList)-1]
+		// limit to repos under the prefix
+		if prefix != "" {
+			relList := []string{}
+			for _, repo := range sRepoList {
+				if rel, ok := strings.CutPrefix(repo, prefix); ok {
+					relList = append(relList, rel)
+				}
+			}
+			sRepoList = relList
+		}
 		// filter repos according to allow/deny rules
 		sRepoList, err = filterRepoList(s.Repos, sRepoList)
 		if err != nil {
@@ -517,14 +842,26 @@ func (opts *rootOpts) processRegistry(ctx context.Context, s ConfigSync, src, tg
 			return err
 		}
 		for _, repo := range sRepoList {
-			if err := opts.processRepo(ctx, s, fmt.Sprintf("%s/%s", src, repo), fmt.Sprintf("%s/%s", tgt, repo), action); err != nil {
+			tRepoRef, err := registryTarget(tgt, repo)
+			if err != nil {
+				opts.log.Error("Failed parsing target",
+					slog.String("target", tgt),
+					slog.String("repo", repo),
+					slog.String("error", err.Error()))
+				errs = append(errs, err)
+				if opts.abortEntry(s) {
+					break
+				}
+				continue
+			}
+			if err := opts.processRepo(ctx, s, fmt.Sprintf("%s/%s%s", host, prefix, repo), tRepoRef.CommonName(), action); err != nil {
 				errs = append(errs, err)
-				if opts.abortOnErr {
+				if opts.abortEntry(s) {
 					break
 				}
 			}
 		}
-		if opts.abortOnErr && len(errs) > 0 {
+		if opts.abortEntry(s) && len(errs) > 0 {
 			break
 		}
 	}
@@ -592,29 +929,31 @@ func (opts *rootOpts) processRepo(ctx context.Context, s ConfigSync, src, tgt st
 			slog.Any("available", sTagsList))
 		return nil
 	}
-	// if only copying missing entries, delete tags that already exist on target
-	if action == actionMissing {
-		tRepoRef, err := ref.New(tgt)
+	// skip tags that are not newer than the highest semver tag in the target
+	if s.SyncPolicy == syncPolicyNewerSemver {
+		tTagList, err := opts.targetTags(ctx, tgt)
 		if err != nil {
-			opts.log.Error("Failed parsing target",
-				slog.String("target", tgt),
-				slog.String("error", err.Error()))
 			return err
 		}
-		tTags, err := opts.rc.TagList(ctx, tRepoRef)
-		if err != nil {
-			opts.log.Debug("Failed getting target tags",
-				slog.String("target", tRepoRef.CommonName()),
-				slog.String("error", err.Error()))
+		var tMax string
+		sTagsFiltered, tMax = filterNewerSemver(sTagsFiltered, tTagList)
+		opts.log.Debug("Filtered tags newer than the target",
+			slog.String("source", sRepoRef.CommonName()),
+			slog.String("target", tgt),
+			slog.String("highest", tMax),
+			slog.Any("tags", sTagsFiltered))
+		if len(sTagsFiltered) == 0 {
+			opts.log.Info("No tags newer than the target",
+				slog.String("source", sRepoRef.CommonName()),
+				slog.String("target", tgt),
+				slog.String("highest", tMax))
 		}
-		tTagList := []string{}
-		if err == nil {
-			tTagList, err = tTags.GetTags()
-			if err != nil {
-				opts.log.Debug("Failed getting target tags",
-					slog.String("target", tRepoRef.CommonName()),
-					slog.String("error", err.Error()))
-			}
+	}
+	// if only copying missing entries, delete tags that already exist on target
+	if action == actionMissing {
+		tTagList, err := opts.targetTags(ctx, tgt)
+		if err != nil {
+			return err
 		}
 		slices.Sort(sTagsFiltered)
 		slices.Sort(tTagList)
@@ -644,14 +983,14 @@ func (opts *rootOpts) processRepo(ctx context.Context, s ConfigSync, src, tgt st
 	for _, tag := range sTagsFiltered {
 		if err := opts.processImage(ctx, s, fmt.Sprintf("%s:%s", src, tag), fmt.Sprintf("%s:%s", tgt, tag), action); err != nil {
 			errs = append(errs, err)
-			if opts.abortOnErr {
+			if opts.abortEntry(s) {
 				break
 			}
 		}
 	}
 
-	// Run cleanup if enabled (only for actionCopy, not for image sync type)
-	if action == actionCopy && s.CleanupTags != nil && *s.CleanupTags {
+	// Run cleanup if enabled (only for actionCopy and actionPlan, not for image sync type)
+	if (action == actionCopy || action == actionPlan) && s.CleanupTags != nil && *s.CleanupTags {
 		opts.log.Debug("Cleanup enabled for target",
 			slog.String("target", tgt))
 		cleanupErr := opts.cleanupTags(ctx, s, tgt)
@@ -660,7 +999,7 @@ func (opts *rootOpts) processRepo(ctx context.Context, s ConfigSync, src, tgt st
 				slog.String("target", tgt),
 				slog.String("error", cleanupErr.Error()))
 			errs = append(errs, cleanupErr)
-			if opts.abortOnErr {
+			if opts.abortEntry(s) {
 				return errors.Join(errs...)
 			}
 		}
@@ -721,6 +1060,14 @@ func (opts *rootOpts) processRef(ctx context.Context, s ConfigSync, src, tgt ref
 	if err == nil && manifest.GetDigest(mSrc).String() == manifest.GetDigest(mTgt).String() {
 		tgtMatches = true
 	}
+	// an annotated target is a modified copy of the source, compare the annotated source digest
+	var mTgtAnnot manifest.Manifest
+	if tgtExists && ((s.CopyAnnotations != nil && *s.CopyAnnotations) || len(s.Annotations) > 0) {
+		if m, err := opts.rc.ManifestGet(ctx, tgt); err == nil {
+			mTgtAnnot = m
+			tgtMatches = tgtMatches || regclient.CopyAnnotationsMatch(m, manifest.GetDigest(mSrc))
+		}
+	}
 	if tgtMatches && (fastCheck || (!forceRecursive && !referrers && !digestTags)) {
 		opts.log.Debug("Image matches",
 			slog.String("source", src.CommonName()),
@@ -754,6 +1101,9 @@ func (opts *rootOpts) processRef(ctx context.Context, s ConfigSync, src, tgt ref
 		if tgtExists && platDigest.String() == manifest.GetDigest(mTgt).String() {
 			tgtMatches = true
 		}
+		if mTgtAnnot != nil && regclient.CopyAnnotationsMatch(mTgtAnnot, platDigest) {
+			tgtMatches = true
+		}
 		if tgtMatches && (s.ForceRecursive == nil || !*s.ForceRecursive) {
 			opts.log.Debug("Image matches for platform",
 				slog.String("source", src.CommonName()),
@@ -777,6 +1127,13 @@ func (opts *rootOpts) processRef(ctx context.Context, s ConfigSync, src, tgt ref
 	if action == actionCheck {
 		return nil
 	}
+	if action == actionPlan {
+		dig := manifest.GetDigest(mSrc)
+		if src.Digest != "" {
+			dig = digest.Digest(src.Digest)
+		}
+		return opts.planImage(ctx, s, src, tgt, dig, tgtMatches)
+	}
 
 	// wait for parallel tasks
 	priority := 0
@@ -843,37 +1200,14 @@ func (opts *rootOpts) processRef(ctx context.Context, s ConfigSync, src, tgt ref
 
 	// run backup
 	if tgtExists && !tgtMatches && s.Backup != "" {
-		// expand template
-		data := struct {
-			Ref  ref.Ref
-			Step ConfigSync
-			Sync ConfigSync
-		}{Ref: tgt, Step: s, Sync: s}
-		backupStr, err := template.String(s.Backup, data)
+		backupRef, err := expandBackup(s, tgt)
 		if err != nil {
-			opts.log.Error("Failed to expand backup template",
+			opts.log.Error("Failed to expand backup",
 				slog.String("original", tgt.CommonName()),
-				slog.String("backup-template", s.Backup),
+				slog.String("template", s.Backup),
 				slog.String("error", err.Error()))
 			return err
 		}
-		backupStr = strings.TrimSpace(backupStr)
-		backupRef := tgt
-		if strings.ContainsAny(backupStr, ":/") {
-			// if the : or / are in the string, parse it as a full reference
-			backupRef, err = ref.New(backupStr)
-			if err != nil {
-				opts.log.Error("Failed to parse backup reference",
-					slog.String("original", tgt.CommonName()),
-					slog.String("template", s.Backup),
-					slog.String("backup", backupStr),
-					slog.String("error", err.Error()))
-				return err
-			}
-		} else {
-			// else parse backup string as just a tag
-			backupRef = backupRef.SetTag(backupStr)
-		}
 		defer opts.rc.Close(ctx, backupRef)
 		// run copy from tgt ref to backup ref
 		opts.log.Info("Saving backup",
@@ -887,12 +1221,54 @@ func (opts *rootOpts) processRef(ctx context.Context, s ConfigSync, src, tgt ref
 				slog.String("template", s.Backup),
 				slog.String("backup", backupRef.CommonName()),
 				slog.String("error", err.Error()))
+		} else if err = opts.backupPrune(ctx, s, tgt, backupRef); err != nil {
+			opts.log.Warn("Failed to prune backups",
+				slog.String("original", tgt.CommonName()),
+				slog.String("template", s.Backup),
+				slog.Int("keep", s.BackupKeep),
+				slog.String("error", err.Error()))
 		}
 	}
 
+	rcOpts := opts.imageCopyOpts(s)
+
+	// Copy the image
+	opts.log.Debug("Image sync running",
+		slog.String("source", src.CommonName()),
+		slog.String("target", tgt.CommonName()))
+	dig := manifest.GetDigest(mSrc)
+	if src.Digest != "" {
+		dig = digest.Digest(src.Digest)
+	}
+	// an image already in the target is refreshed without staging
+	if s.Staging != "" && !tgtMatches {
+		err = opts.copyStaged(ctx, s, src, tgt, dig, rcOpts)
+	} else {
+		err = opts.rc.ImageCopy(ctx, src, tgt, rcOpts...)
+	}
+	if err != nil {
+		opts.log.Error("Failed to copy image",
+			slog.String("source", src.CommonName()),
+			slog.String("target", tgt.CommonName()),
+			slog.String("error", err.Error()))
+		return err
+	}
+	previous := digest.Digest("")
+	if tgtExists {
+		previous = manifest.GetDigest(mTgt)
+	}
+	opts.postSyncHook(ctx, s, src, tgt, dig, previous)
+	return nil
+}
+
+// imageCopyOpts returns the options used to copy an image for a sync step.
+func (opts *rootOpts) imageCopyOpts(s ConfigSync) []regclient.ImageOpts {
 	rcOpts := []regclient.ImageOpts{}
 	if s.DigestTags != nil && *s.DigestTags {
 		rcOpts = append(rcOpts, regclient.ImageWithDigestTags())
+		if len(s.DigestTagPatterns) > 0 {
+			rcOpts = append(rcOpts, regclient.ImageWithDigestTagPatterns(s.DigestTagPatterns...))
+		}
 	}
 	if s.Referrers != nil && *s.Referrers {
 		if len(s.ReferrerFilters) == 0 {
@@ -935,25 +1311,28 @@ func (opts *rootOpts) processRef(ctx context.Context, s ConfigSync, src, tgt ref
 		rcOpts = append(rcOpts, regclient.ImageWithForceRecursive())
 	}
 	if s.IncludeExternal != nil && *s.IncludeExternal {
-		rcOpts = append(rcOpts, regclient.ImageWithIncludeExternal())
+		rcOpts = append(rcOpts, regclient.ImageWithExternalPolicy(regclient.ExternalCopy, s.ExternalHosts...))
 	}
 	if len(s.Platforms) > 0 {
 		rcOpts = append(rcOpts, regclient.ImageWithPlatforms(s.Platforms))
 	}
-
-	// Copy the image
-	opts.log.Debug("Image sync running",
-		slog.String("source", src.CommonName()),
-		slog.String("target", tgt.CommonName()))
-	err = opts.rc.ImageCopy(ctx, src, tgt, rcOpts...)
-	if err != nil {
-		opts.log.Error("Failed to copy image",
-			slog.String("source", src.CommonName()),
-			slog.String("target", tgt.CommonName()),
-			slog.String("error", err.Error()))
-		return err
+	if (s.CopyAnnotations != nil && *s.CopyAnnotations) || len(s.Annotations) > 0 {
+		rcOpts = append(rcOpts, regclient.ImageWithCopyAnnotations(s.Annotations))
 	}
-	return nil
+	if s.Provenance != nil && *s.Provenance {
+		rcOpts = append(rcOpts, regclient.ImageWithProvenance())
+	}
+	limiters := []*bwlimit.Limiter{s.bwLimit}
+	if opts.conf != nil {
+		limiters = append(limiters, opts.conf.Defaults.bwLimit)
+	}
+	if slices.ContainsFunc(limiters, func(l *bwlimit.Limiter) bool { return l != nil }) {
+		rcOpts = append(rcOpts, regclient.ImageWithBlobReaderHook(func(br *blob.BReader) (*blob.BReader, error) {
+			rdr := bwlimit.Reader(br, limiters...)
+			return blob.NewReader(blob.WithDesc(br.GetDescriptor()), blob.WithReader(bwReadCloser{Reader: rdr, Closer: br})), nil
+		}))
+	}
+	return rcOpts
 }
 
 // filterByRegex applies allow/deny regex patterns to a list of strings.
@@ -1031,6 +1410,57 @@ func filterRepoList(ad RepoAllowDeny, in []string) ([]string, error) {
 	return filterRegexDeny(ad.Deny, result)
 }
 
+// targetTags lists the tags in the target repository, returning an empty list when the repository does not exist.
+func (opts *rootOpts) targetTags(ctx context.Context, tgt string) ([]string, error) {
+	tRepoRef, err := ref.New(tgt)
+	if err != nil {
+		opts.log.Error("Failed parsing target",
+			slog.String("target", tgt),
+			slog.String("error", err.Error()))
+		return nil, err
+	}
+	tTags, err := opts.rc.TagList(ctx, tRepoRef)
+	if err == nil {
+		var tTagList []string
+		tTagList, err = tTags.GetTags()
+		if err == nil {
+			return tTagList, nil
+		}
+	}
+	opts.log.Debug("Failed getting target tags",
+		slog.String("target", tRepoRef.CommonName()),
+		slog.String("error", err.Error()))
+	return []string{}, nil
+}
+
+// filterNewerSemver returns the source tags with a semver newer than every semver tag in the target, and the highest target tag.
+// Tags that are not a semver are excluded from the source and ignored in the target.
+func filterNewerSemver(src, tgt []string) ([]string, string) {
+	var tMax semver.Version
+	tMaxTag := ""
+	for _, tag := range tgt {
+		v, err := semver.NewVersion(tag)
+		if err != nil {
+			continue
+		}
+		if tMaxTag == "" || v.Compare(tMax) > 0 {
+			tMax = v
+			tMaxTag = tag
+		}
+	}
+	result := make([]string, 0, len(src))
+	for _, tag := range src {
+		v, err := semver.NewVersion(tag)
+		if err != nil {
+			continue
+		}
+		if tMaxTag == "" || v.Compare(tMax) > 0 {
+			result = append(result, tag)
+		}
+	}
+	return result, tMaxTag
+}
+
 func filterTagList(ad TagAllowDeny, in []string) ([]string, error) {
 	result := in
 
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// errEntryRunning is returned when a sync entry is triggered while a previous run is still active.
+var errEntryRunning = errors.New("sync entry is already running")
+
+// syncServer tracks the scheduled sync entries in server mode, allowing the config to be reloaded without a restart.
+type syncServer struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	cron    *cron.Cron
+	opts    atomic.Pointer[rootOpts] // current settings, replaced on each reload
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	entries map[string][]*syncEntry // scheduled entries, indexed by syncKey
+	errs    []error
+}
+
+// syncEntry is a scheduled sync entry.
+type syncEntry struct {
+	id      cron.EntryID
+	sync    ConfigSync
+	paused  atomic.Bool
+	mu      sync.Mutex
+	running bool
+	rerun   bool // run again when the current run finishes
+	last    *syncResult
+}
+
+// syncResult is the outcome of the last run of a sync entry.
+type syncResult struct {
+	Action string    `json:"action"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end,omitzero"`
+	Error  string    `json:"error,omitempty"`
+}
+
+func newSyncServer(ctx context.Context, cancel context.CancelFunc, opts *rootOpts) *syncServer {
+	srv := &syncServer{
+		ctx:    ctx,
+		cancel: cancel,
+		cron: cron.New(cron.WithChain(
+			cron.SkipIfStillRunning(cron.DefaultLogger),
+		)),
+		entries: map[string][]*syncEntry{},
+	}
+	srv.opts.Store(opts)
+	return srv
+}
+
+// syncKey identifies a sync entry for comparing configs.
+func syncKey(s ConfigSync) string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Sprintf("%v", s)
+	}
+	return string(b)
+}
+
+// syncSched returns the cron schedule for a sync entry, or an empty string if it is not scheduled.
+func syncSched(s ConfigSync) string {
+	sched := s.Schedule
+	if sched == "" && s.Interval != 0 {
+		sched = "@every " + s.Interval.String()
+	}
+	return sched
+}
+
+func (a actionType) String() string {
+	switch a {
+	case actionCheck:
+		return "check"
+	case actionCopy:
+		return "copy"
+	case actionMissing:
+		return "missing"
+	}
+	return "unknown"
+}
+
+// add schedules each sync entry and copies any images that are missing from the target.
+func (srv *syncServer) add(opts *rootOpts, syncs []ConfigSync) {
+	for _, s := range syncs {
+		e, err := srv.schedule(s)
+		if err != nil {
+			srv.appendErr(err)
+			if opts.abortRun(s) {
+				break
+			}
+		}
+		if e != nil && !srv.copyMissing(opts, e) {
+			break
+		}
+	}
+}
+
+// copyMissing immediately copies any images that are missing from the target.
+// The copy runs in the background when parallel syncs are enabled.
+// False is returned when processing of additional entries should stop.
+func (srv *syncServer) copyMissing(opts *rootOpts, e *syncEntry) bool {
+	if opts.conf.Defaults.Parallel > 0 {
+		srv.wg.Go(func() {
+			err := srv.run(e, actionMissing)
+			if err != nil && opts.abortRun(e.sync) {
+				srv.cancel()
+			}
+		})
+		return true
+	}
+	err := srv.run(e, actionMissing)
+	return err == nil || !opts.abortRun(e.sync)
+}
+
+// schedule adds a cron entry for the sync entry.
+// Entries without a schedule or interval are ignored, returning nil.
+func (srv *syncServer) schedule(s ConfigSync) (*syncEntry, error) {
+	log := srv.opts.Load().log
+	sched := syncSched(s)
+	if sched == "" {
+		log.Error("No schedule or interval found, ignoring",
+			slog.String("source", s.Source),
+			slog.String("target", s.Target),
+			slog.String("type", s.Type))
+		return nil, nil
+	}
+	log.Debug("Scheduled task",
+		slog.String("source", s.Source),
+		slog.String("target", s.Target),
+		slog.String("type", s.Type),
+		slog.String("sched", sched))
+	e := &syncEntry{sync: s}
+	id, err := srv.cron.AddFunc(sched, func() {
+		opts := srv.opts.Load()
+		if e.paused.Load() {
+			opts.log.Debug("Skipping paused task",
+				slog.String("source", s.Source),
+				slog.String("target", s.Target),
+				slog.String("type", s.Type))
+			return
+		}
+		opts.log.Debug("Running task",
+			slog.String("source", s.Source),
+			slog.String("target", s.Target),
+			slog.String("type", s.Type))
+		srv.wg.Add(1)
+		defer srv.wg.Done()
+		err := srv.run(e, actionCopy)
+		if err != nil && opts.abortRun(s) {
+			srv.cancel()
+		}
+	})
+	if err != nil {
+		log.Error("Failed to schedule cron",
+			slog.String("source", s.Source),
+			slog.String("target", s.Target),
+			slog.String("sched", sched),
+			slog.String("err", err.Error()))
+		return nil, err
+	}
+	e.id = id
+	key := syncKey(s)
+	srv.mu.Lock()
+	srv.entries[key] = append(srv.entries[key], e)
+	srv.mu.Unlock()
+	return e, nil
+}
+
+// run processes a sync entry with the current settings and records the result.
+// Errors other than a cancel are saved and returned.
+func (srv *syncServer) run(e *syncEntry, action actionType) error {
+	e.mu.Lock()
+	if e.running {
+		e.mu.Unlock()
+		srv.opts.Load().log.Debug("Skipping task that is already running",
+			slog.String("source", e.sync.Source),
+			slog.String("target", e.sync.Target),
+			slog.String("type", e.sync.Type))
+		return nil
+	}
+	e.running = true
+	result := &syncResult{Action: action.String(), Start: time.Now().UTC()}
+	e.last = result
+	e.mu.Unlock()
+	opts := srv.opts.Load()
+	// tags may change between scheduled runs
+	opts.rc.MemoClear()
+	err := opts.process(srv.ctx, e.sync, action)
+	e.mu.Lock()
+	e.running = false
+	e.last = &syncResult{Action: result.Action, Start: result.Start, End: time.Now().UTC()}
+	if err != nil {
+		e.last.Error = err.Error()
+	}
+	rerun := e.rerun
+	e.rerun = false
+	e.mu.Unlock()
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, ErrCanceled) {
+		srv.appendErr(err)
+		return err
+	}
+	if rerun && srv.ctx.Err() == nil {
+		return srv.run(e, actionCopy)
+	}
+	return nil
+}
+
+// trigger runs a sync entry in the background, returning errEntryRunning if it is already running.
+func (srv *syncServer) trigger(e *syncEntry) error {
+	e.mu.Lock()
+	running := e.running
+	e.mu.Unlock()
+	if running {
+		return errEntryRunning
+	}
+	srv.wg.Go(func() {
+		err := srv.run(e, actionCopy)
+		if err != nil && srv.opts.Load().abortRun(e.sync) {
+			srv.cancel()
+		}
+	})
+	return nil
+}
+
+// queue runs a sync entry in the background.
+// When the entry is already running, it runs again after the current run finishes to include any newly pushed images.
+func (srv *syncServer) queue(e *syncEntry) {
+	e.mu.Lock()
+	if e.running {
+		e.rerun = true
+		e.mu.Unlock()
+		return
+	}
+	e.mu.Unlock()
+	srv.wg.Go(func() {
+		err := srv.run(e, actionCopy)
+		if err != nil && srv.opts.Load().abortRun(e.sync) {
+			srv.cancel()
+		}
+	})
+}
+
+// entry returns the scheduled entry with the given id.
+func (srv *syncServer) entry(id cron.EntryID) *syncEntry {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for _, list := range srv.entries {
+		for _, e := range list {
+			if e.id == id {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+func (srv *syncServer) appendErr(err error) {
+	srv.mu.Lock()
+	srv.errs = append(srv.errs, err)
+	srv.mu.Unlock()
+}
+
+func (srv *syncServer) err() error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return errors.Join(srv.errs...)
+}
+
+// reload reads the config file and updates the scheduled tasks.
+// New logins are used by the next run of every task, removed entries are unscheduled, and added entries are scheduled and copy any missing images.
+// Running tasks finish with the previous config.
+// The current config is kept if the new config cannot be loaded.
+func (srv *syncServer) reload() error {
+	cur := srv.opts.Load()
+	if cur.confFile == "-" {
+		return fmt.Errorf("config cannot be reloaded from stdin%.0w", ErrInvalidInput)
+	}
+	next := *cur
+	err := next.loadConf()
+	if err != nil {
+		return err
+	}
+	for _, s := range next.conf.Sync {
+		if sched := syncSched(s); sched != "" {
+			if _, err := cron.ParseStandard(sched); err != nil {
+				return fmt.Errorf("invalid schedule %q for %s: %w", sched, s.Source, err)
+			}
+		}
+	}
+	// keep the throttle so running tasks count against the parallel limit
+	if next.conf.Defaults.Parallel == cur.conf.Defaults.Parallel {
+		next.throttle = cur.throttle
+	}
+	srv.opts.Store(&next)
+	// find entries that are unchanged, the remaining entries are added
+	srv.mu.Lock()
+	keep := map[string]int{}
+	added := []ConfigSync{}
+	for _, s := range next.conf.Sync {
+		key := syncKey(s)
+		if keep[key] < len(srv.entries[key]) {
+			keep[key]++
+			continue
+		}
+		added = append(added, s)
+	}
+	removed := 0
+	for key, list := range srv.entries {
+		for _, e := range list[keep[key]:] {
+			srv.cron.Remove(e.id)
+			removed++
+		}
+		if keep[key] == 0 {
+			delete(srv.entries, key)
+		} else {
+			srv.entries[key] = list[:keep[key]]
+		}
+	}
+	srv.mu.Unlock()
+	next.log.Info("Reloaded config",
+		slog.Int("added", len(added)),
+		slog.Int("removed", removed))
+	missing := []*syncEntry{}
+	for _, s := range added {
+		e, err := srv.schedule(s)
+		if err != nil {
+			srv.appendErr(err)
+		} else if e != nil {
+			missing = append(missing, e)
+		}
+	}
+	if len(missing) > 0 {
+		srv.wg.Go(func() {
+			for _, e := range missing {
+				if !srv.copyMissing(&next, e) {
+					break
+				}
+			}
+		})
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/pkg/template"
+	"github.com/regclient/regclient/types/ref"
+)
+
+// expandStaging expands the staging template to a tag in the target repository.
+func expandStaging(s ConfigSync, tgt ref.Ref) (ref.Ref, error) {
+	data := struct {
+		Ref  ref.Ref
+		Step ConfigSync
+		Sync ConfigSync
+	}{Ref: tgt, Step: s, Sync: s}
+	tag, err := template.String(s.Staging, data)
+	if err != nil {
+		return tgt, fmt.Errorf("failed to expand staging template %s: %w", s.Staging, err)
+	}
+	tag = strings.TrimSpace(tag)
+	if tag == "" || strings.ContainsAny(tag, ":/@") {
+		return tgt, fmt.Errorf("staging must be a tag in the target repository, received %q%.0w", tag, ErrInvalidInput)
+	}
+	if tag == tgt.Tag {
+		return tgt, fmt.Errorf("staging tag must differ from the target tag %s%.0w", tgt.CommonName(), ErrInvalidInput)
+	}
+	return tgt.SetTag(tag), nil
+}
+
+// copyStaged copies the image to the staging tag, runs the validate hook, and then points the target tag to the validated manifest.
+// The target is unchanged when the copy or validation fails, and the staging tag is left for inspection.
+// After the target is updated, the staging tag is deleted.
+func (opts *rootOpts) copyStaged(ctx context.Context, s ConfigSync, src, tgt ref.Ref, dig digest.Digest, rcOpts []regclient.ImageOpts) error {
+	stage, err := expandStaging(s, tgt)
+	if err != nil {
+		return err
+	}
+	defer opts.rc.Close(ctx, stage)
+	opts.log.Info("Copying image to staging",
+		slog.String("source", src.CommonName()),
+		slog.String("staging", stage.CommonName()))
+	err = opts.rc.ImageCopy(ctx, src, stage, rcOpts...)
+	if err != nil {
+		return err
+	}
+	// the validated manifest is pinned before running the hook
+	m, err := opts.rc.ManifestGet(ctx, stage)
+	if err != nil {
+		return fmt.Errorf("failed to get staged image %s: %w", stage.CommonName(), err)
+	}
+	err = opts.validateHook(ctx, s, src, stage.AddDigest(m.GetDescriptor().Digest.String()), tgt, dig)
+	if err != nil {
+		return err
+	}
+	opts.log.Info("Promoting staged image",
+		slog.String("staging", stage.CommonName()),
+		slog.String("target", tgt.CommonName()),
+		slog.String("digest", m.GetDescriptor().Digest.String()))
+	err = opts.rc.ManifestPut(ctx, tgt, m)
+	if err != nil {
+		return fmt.Errorf("failed to promote %s to %s: %w", stage.CommonName(), tgt.CommonName(), err)
+	}
+	err = opts.rc.TagDelete(ctx, stage)
+	if err != nil {
+		opts.log.Warn("Failed to delete staging tag",
+			slog.String("staging", stage.CommonName()),
+			slog.String("err", err.Error()))
+	}
+	return nil
+}
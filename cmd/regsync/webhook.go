@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/regclient/regclient/types/mediatype"
+	"github.com/regclient/regclient/types/ref"
+)
+
+// webhookMaxBody limits the size of a webhook request body
+const webhookMaxBody = 1024 * 1024
+
+// webhookEvent is a push to a registry reported by a webhook.
+type webhookEvent struct {
+	Host       string // registry host, empty when the notification does not include it
+	Repository string
+	Tag        string // empty when only the digest was pushed
+}
+
+// webhookPayload contains the fields used from each supported notification format.
+type webhookPayload struct {
+	// Docker distribution notifications
+	Events []struct {
+		Action string `json:"action"`
+		Target struct {
+			MediaType  string `json:"mediaType"`
+			Repository string `json:"repository"`
+			Tag        string `json:"tag"`
+		} `json:"target"`
+		Request struct {
+			Host string `json:"host"`
+		} `json:"request"`
+	} `json:"events"`
+	// Harbor webhooks
+	Type      string `json:"type"`
+	EventData struct {
+		Resources []struct {
+			Tag         string `json:"tag"`
+			ResourceURL string `json:"resource_url"`
+		} `json:"resources"`
+		Repository struct {
+			RepoFullName string `json:"repo_full_name"`
+		} `json:"repository"`
+	} `json:"event_data"`
+	// Quay repository push notifications
+	Repository  string   `json:"repository"`
+	DockerURL   string   `json:"docker_url"`
+	UpdatedTags []string `json:"updated_tags"`
+}
+
+// webhookParse returns the push events in a Docker distribution, Harbor, or Quay notification.
+// Other events, like pulls and deletes, are ignored.
+func webhookParse(body []byte) ([]webhookEvent, error) {
+	p := webhookPayload{}
+	err := json.Unmarshal(body, &p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse webhook: %w", err)
+	}
+	events := []webhookEvent{}
+	switch {
+	case len(p.Events) > 0:
+		for _, e := range p.Events {
+			// blob pushes are followed by a manifest push
+			if e.Action != "push" || (e.Target.Tag == "" && !webhookManifest(e.Target.MediaType)) {
+				continue
+			}
+			events = append(events, webhookEvent{
+				Host:       e.Request.Host,
+				Repository: e.Target.Repository,
+				Tag:        e.Target.Tag,
+			})
+		}
+	case p.Type != "":
+		if p.Type != "PUSH_ARTIFACT" {
+			break
+		}
+		for _, res := range p.EventData.Resources {
+			ev := webhookEvent{Repository: p.EventData.Repository.RepoFullName, Tag: res.Tag}
+			if r, err := ref.New(res.ResourceURL); err == nil {
+				ev.Host = r.Registry
+				ev.Repository = r.Repository
+			}
+			events = append(events, ev)
+		}
+	case p.DockerURL != "" || p.Repository != "":
+		ev := webhookEvent{Repository: p.Repository}
+		if host, repo, ok := strings.Cut(p.DockerURL, "/"); ok {
+			ev.Host = host
+			ev.Repository = repo
+		}
+		if len(p.UpdatedTags) == 0 {
+			events = append(events, ev)
+		}
+		for _, tag := range p.UpdatedTags {
+			ev.Tag = tag
+			events = append(events, ev)
+		}
+	default:
+		return nil, fmt.Errorf("unknown webhook format%.0w", ErrInvalidInput)
+	}
+	return events, nil
+}
+
+func webhookManifest(mt string) bool {
+	switch mt {
+	case mediatype.OCI1Manifest, mediatype.OCI1ManifestList, mediatype.Docker2Manifest, mediatype.Docker2ManifestList,
+		mediatype.Docker1Manifest, mediatype.Docker1ManifestSigned:
+		return true
+	}
+	return false
+}
+
+// webhookMatch returns true when the event changes the source of the sync entry.
+// The host is only compared when the notification includes it.
+func webhookMatch(s ConfigSync, ev webhookEvent) bool {
+	switch s.Type {
+	case "registry", "registryFilter":
+		host, prefix, _ := strings.Cut(strings.TrimSuffix(s.Source, "/"), "/")
+		if ev.Host != "" && ev.Host != host {
+			return false
+		}
+		if prefix != "" {
+			var ok bool
+			if ev.Repository, ok = strings.CutPrefix(ev.Repository, prefix+"/"); !ok {
+				return false
+			}
+		}
+		repos, err := filterRepoList(s.Repos, []string{ev.Repository})
+		return err == nil && len(repos) > 0
+	case "repository", "image":
+		r, err := ref.New(s.Source)
+		if err != nil || r.Repository != ev.Repository || (ev.Host != "" && ev.Host != r.Registry) {
+			return false
+		}
+		if s.Type == "image" {
+			return ev.Tag == "" || r.Tag == ev.Tag
+		}
+		if ev.Tag == "" || len(s.TagSets) > 0 {
+			return true
+		}
+		tags, err := filterTagList(s.Tags, []string{ev.Tag})
+		return err == nil && len(tags) > 0
+	}
+	return false
+}
+
+// webhookHandler returns the handler for registry webhooks.
+// When a token is set, it must be included in the Authorization header, with an optional Bearer prefix, or the token query parameter.
+func (srv *syncServer) webhookHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhook", func(w http.ResponseWriter, r *http.Request) {
+		log := srv.opts.Load().log
+		if token != "" {
+			auth := r.URL.Query().Get("token")
+			if auth == "" {
+				auth = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			}
+			if subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+				adminError(w, http.StatusUnauthorized, "invalid token")
+				return
+			}
+		}
+		b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, webhookMaxBody))
+		if err != nil {
+			adminError(w, http.StatusRequestEntityTooLarge, "failed to read request: "+err.Error())
+			return
+		}
+		events, err := webhookParse(b)
+		if err != nil {
+			log.Warn("Failed to parse webhook",
+				slog.String("remote", r.RemoteAddr),
+				slog.String("err", err.Error()))
+			adminError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		srv.mu.Lock()
+		triggered := []*syncEntry{}
+		for _, list := range srv.entries {
+			for _, e := range list {
+				for _, ev := range events {
+					if webhookMatch(e.sync, ev) {
+						triggered = append(triggered, e)
+						break
+					}
+				}
+			}
+		}
+		srv.mu.Unlock()
+		for _, e := range triggered {
+			if e.paused.Load() {
+				continue
+			}
+			log.Info("Sync triggered by webhook",
+				slog.String("source", e.sync.Source),
+				slog.String("target", e.sync.Target))
+			srv.queue(e)
+		}
+		adminWrite(w, http.StatusAccepted, struct {
+			Events    int `json:"events"`
+			Triggered int `json:"triggered"`
+		}{Events: len(events), Triggered: len(triggered)})
+	})
+	return mux
+}
+
+// webhookToken reads the webhook token from a file, trimming any trailing newline.
+func webhookToken(filename string) (string, error) {
+	if filename == "" {
+		return "", nil
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read webhook token: %w", err)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("webhook token file %s is empty%.0w", filename, ErrInvalidInput)
+	}
+	return token, nil
+}
+
+// webhookServe runs the webhook listener until the context is canceled.
+func (srv *syncServer) webhookServe(ctx context.Context, l net.Listener, token string) {
+	log := srv.opts.Load().log
+	hs := &http.Server{
+		Handler:           srv.webhookHandler(token),
+		ReadHeaderTimeout: time.Second * 10,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		_ = hs.Shutdown(shutdownCtx)
+	}()
+	log.Info("Starting webhook listener",
+		slog.String("addr", l.Addr().String()))
+	err := hs.Serve(l)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error("Webhook listener failed",
+			slog.String("err", err.Error()))
+	}
+}
@@ -0,0 +1,446 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/regclient/regclient/types/errs"
+)
+
+const (
+	// containerdHostsFile is the name of the registry config in each directory of containerd's certs.d.
+	containerdHostsFile = "hosts.toml"
+	// containerdDefault is the directory with settings for every registry, which is not supported.
+	containerdDefault = "_default"
+)
+
+// ContainerdLoadDir returns a slice of hosts from a containerd registry config directory, e.g. /etc/containerd/certs.d.
+// Each subdirectory is named for a registry and contains a hosts.toml with the upstream server and mirrors.
+// Mirrors are returned as separate hosts, listed in the order they are attempted.
+// Without a hosts.toml, CA (*.crt) and client certificates (*.cert, *.key) in the subdirectory are used.
+// If the directory is missing, an empty list is returned.
+func ContainerdLoadDir(dir string) ([]Host, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && errors.Is(err, fs.ErrNotExist) {
+		return []Host{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	hosts := []Host{}
+	errList := []error{}
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == containerdDefault {
+			continue
+		}
+		hostList, err := containerdLoadHost(filepath.Join(dir, e.Name()), e.Name())
+		if err != nil {
+			errList = append(errList, fmt.Errorf("failed to load containerd config for %s: %w", e.Name(), err))
+			continue
+		}
+		hosts = append(hosts, hostList...)
+	}
+	return hosts, errors.Join(errList...)
+}
+
+// containerdLoadHost returns the registry and each mirror configured in a certs.d subdirectory.
+func containerdLoadHost(dir, name string) ([]Host, error) {
+	if !HostValidate(name) {
+		return nil, fmt.Errorf("invalid registry name %s%.0w", name, errs.ErrParsingFailed)
+	}
+	h := HostNewName(name)
+	//#nosec G304 command is run by a user accessing their own files
+	f, err := os.Open(filepath.Join(dir, containerdHostsFile))
+	if err != nil && errors.Is(err, fs.ErrNotExist) {
+		err = containerdCertFiles(dir, h)
+		if err != nil {
+			return nil, err
+		}
+		return []Host{*h}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tables, err := tomlParse(f)
+	if err != nil {
+		return nil, err
+	}
+	hosts := []Host{}
+	for _, table := range tables {
+		switch {
+		case len(table.name) == 0:
+			// upstream server is configured at the top level
+			if server, ok := table.values["server"].(string); ok && server != "" && !containerdDefaultServer(h.Name, server) {
+				err = containerdURL(h, server, table.values)
+				if err != nil {
+					return nil, err
+				}
+			}
+			err = containerdTLS(dir, h, table.values)
+			if err != nil {
+				return nil, err
+			}
+		case len(table.name) == 2 && table.name[0] == "host":
+			capabilities, ok := table.values["capabilities"].([]any)
+			if ok && !slices.Contains(capabilities, any("pull")) {
+				continue
+			}
+			u, err := url.Parse(table.name[1])
+			if err != nil || u.Host == "" {
+				return nil, fmt.Errorf("invalid mirror url %s%.0w", table.name[1], errs.ErrParsingFailed)
+			}
+			m := HostNewName(u.Host)
+			if m.Name == h.Name {
+				// the upstream registry may be listed with the mirrors
+				continue
+			}
+			err = containerdURL(m, table.name[1], table.values)
+			if err != nil {
+				return nil, err
+			}
+			err = containerdTLS(dir, m, table.values)
+			if err != nil {
+				return nil, err
+			}
+			if i := slices.IndexFunc(hosts, func(cur Host) bool { return cur.Name == m.Name }); i >= 0 {
+				if hosts[i].BasePath != m.BasePath || hosts[i].TLS != m.TLS {
+					return nil, fmt.Errorf("conflicting settings for mirror %s%.0w", m.Name, errs.ErrParsingFailed)
+				}
+				continue
+			}
+			h.Mirrors = append(h.Mirrors, m.Name)
+			hosts = append(hosts, *m)
+		}
+		// other tables, like headers, are not supported
+	}
+	return append([]Host{*h}, hosts...), nil
+}
+
+// containerdDefaultServer returns true when the server is the default for the registry name.
+func containerdDefaultServer(name, server string) bool {
+	server = strings.TrimSuffix(server, "/")
+	if name == DockerRegistry {
+		return server == "https://"+DockerRegistryDNS || server == "https://"+DockerRegistry
+	}
+	return server == "https://"+name
+}
+
+// containerdURL sets the TLS, hostname, and base path from the url of a server or mirror.
+func containerdURL(h *Host, server string, values map[string]any) error {
+	u, err := url.Parse(server)
+	if err != nil {
+		return fmt.Errorf("invalid server url %s: %w", server, err)
+	}
+	basePath := strings.Trim(u.Path, "/")
+	if override, _ := values["override_path"].(bool); override {
+		// the path replaces the "/v2" of the API
+		var ok bool
+		if basePath, ok = strings.CutSuffix(basePath, "v2"); !ok {
+			return fmt.Errorf("override path must end with /v2: %s%.0w", server, errs.ErrUnsupported)
+		}
+	}
+	u.Path = "/" + strings.Trim(basePath, "/")
+	tlsConf, hostname, basePath, err := parseURL(u.String())
+	if err != nil {
+		return err
+	}
+	h.TLS = tlsConf
+	h.Hostname = hostname
+	h.BasePath = basePath
+	return nil
+}
+
+// containerdTLS sets the CA and client certificates, and skip_verify, from a hosts.toml table.
+// Relative filenames are resolved from the directory of the hosts.toml.
+func containerdTLS(dir string, h *Host, values map[string]any) error {
+	if skip, _ := values["skip_verify"].(bool); skip && h.TLS == TLSEnabled {
+		h.TLS = TLSInsecure
+	}
+	caList := []string{}
+	switch ca := values["ca"].(type) {
+	case string:
+		caList = append(caList, ca)
+	case []any:
+		for _, v := range ca {
+			if s, ok := v.(string); ok {
+				caList = append(caList, s)
+			}
+		}
+	}
+	for _, ca := range caList {
+		b, err := containerdReadFile(dir, ca)
+		if err != nil {
+			return err
+		}
+		h.RegCert += b
+	}
+	var pair []any
+	switch client := values["client"].(type) {
+	case string:
+		pair = []any{client}
+	case []any:
+		pair = client
+		if len(client) > 0 {
+			if first, ok := client[0].([]any); ok {
+				pair = first
+			}
+		}
+	}
+	if len(pair) > 0 {
+		cert, _ := pair[0].(string)
+		key := cert
+		if len(pair) > 1 {
+			key, _ = pair[1].(string)
+		}
+		var err error
+		h.ClientCert, err = containerdReadFile(dir, cert)
+		if err != nil {
+			return err
+		}
+		h.ClientKey, err = containerdReadFile(dir, key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// containerdCertFiles loads the CA and client certificates from files in the directory.
+func containerdCertFiles(dir string, h *Host) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		switch filepath.Ext(e.Name()) {
+		case ".crt":
+			b, err := containerdReadFile(dir, e.Name())
+			if err != nil {
+				return err
+			}
+			h.RegCert += b
+		case ".cert":
+			keyName := strings.TrimSuffix(e.Name(), ".cert") + ".key"
+			cert, err := containerdReadFile(dir, e.Name())
+			if err != nil {
+				return err
+			}
+			key, err := containerdReadFile(dir, keyName)
+			if err != nil {
+				return err
+			}
+			h.ClientCert = cert
+			h.ClientKey = key
+		}
+	}
+	return nil
+}
+
+func containerdReadFile(dir, filename string) (string, error) {
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(dir, filename)
+	}
+	//#nosec G304 command is run by a user accessing their own files
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// tomlTable is a table from a TOML file, the root table has an empty name.
+type tomlTable struct {
+	name   []string
+	values map[string]any
+}
+
+// tomlParse parses the subset of TOML used by containerd's hosts.toml.
+// Tables are returned in the order of the file, and values are strings, booleans, or arrays of values.
+// Other values, including numbers, inline tables, and multi-line strings, return an error.
+func tomlParse(rdr io.Reader) ([]tomlTable, error) {
+	tables := []tomlTable{{values: map[string]any{}}}
+	scanner := bufio.NewScanner(rdr)
+	lineNum := 0
+	line := ""
+	for scanner.Scan() {
+		lineNum++
+		line += tomlStripComment(scanner.Text())
+		// continue multi-line arrays
+		if tomlDepth(line) > 0 {
+			line += " "
+			continue
+		}
+		cur := strings.TrimSpace(line)
+		line = ""
+		switch {
+		case cur == "":
+		case strings.HasPrefix(cur, "[["):
+			return nil, fmt.Errorf("line %d: arrays of tables are not supported%.0w", lineNum, errs.ErrParsingFailed)
+		case strings.HasPrefix(cur, "[") && strings.HasSuffix(cur, "]"):
+			name, err := tomlKey(cur[1 : len(cur)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			tables = append(tables, tomlTable{name: name, values: map[string]any{}})
+		default:
+			k, v, ok := tomlCut(cur, '=')
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected a key and value%.0w", lineNum, errs.ErrParsingFailed)
+			}
+			key, err := tomlKey(k)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			val, err := tomlValue(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			tables[len(tables)-1].values[strings.Join(key, ".")] = val
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(line) != "" {
+		return nil, fmt.Errorf("unterminated array%.0w", errs.ErrParsingFailed)
+	}
+	return tables, nil
+}
+
+// tomlStripComment removes a trailing comment that is not inside a string.
+func tomlStripComment(s string) string {
+	quote := byte(0)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// tomlDepth returns the number of open brackets outside of strings.
+func tomlDepth(s string) int {
+	depth := 0
+	quote := byte(0)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && (c == '[' || c == '{'):
+			depth++
+		case quote == 0 && (c == ']' || c == '}'):
+			depth--
+		}
+	}
+	return depth
+}
+
+// tomlCut splits s at the first sep that is not inside a string or brackets.
+func tomlCut(s string, sep byte) (string, string, bool) {
+	depth := 0
+	quote := byte(0)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && (c == '[' || c == '{'):
+			depth++
+		case quote == 0 && (c == ']' || c == '}'):
+			depth--
+		case quote == 0 && depth == 0 && c == sep:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// tomlKey splits a dotted key, removing quotes from each part.
+func tomlKey(s string) ([]string, error) {
+	key := []string{}
+	for s = strings.TrimSpace(s); s != ""; {
+		part, rest, _ := tomlCut(s, '.')
+		part = strings.TrimSpace(part)
+		if strings.HasPrefix(part, "\"") || strings.HasPrefix(part, "'") {
+			v, err := tomlString(part)
+			if err != nil {
+				return nil, err
+			}
+			part = v
+		} else if part == "" {
+			return nil, fmt.Errorf("empty key%.0w", errs.ErrParsingFailed)
+		}
+		key = append(key, part)
+		s = strings.TrimSpace(rest)
+	}
+	return key, nil
+}
+
+// tomlValue parses a string, boolean, or array.
+func tomlValue(s string) (any, error) {
+	switch {
+	case strings.HasPrefix(s, `"""`) || strings.HasPrefix(s, "'''"):
+		return nil, fmt.Errorf("multi-line strings are not supported%.0w", errs.ErrParsingFailed)
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	case strings.HasPrefix(s, "\"") || strings.HasPrefix(s, "'"):
+		return tomlString(s)
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("invalid array %s%.0w", s, errs.ErrParsingFailed)
+		}
+		list := []any{}
+		for rest := strings.TrimSpace(s[1 : len(s)-1]); rest != ""; {
+			entry, next, _ := tomlCut(rest, ',')
+			v, err := tomlValue(strings.TrimSpace(entry))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			rest = strings.TrimSpace(next)
+		}
+		return list, nil
+	case s == "":
+		return nil, fmt.Errorf("missing value%.0w", errs.ErrParsingFailed)
+	}
+	return nil, fmt.Errorf("unsupported value %s%.0w", s, errs.ErrParsingFailed)
+}
+
+// tomlString parses a basic (double quoted) or literal (single quoted) string.
+func tomlString(s string) (string, error) {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' && !strings.Contains(s[1:len(s)-1], "'") {
+		return s[1 : len(s)-1], nil
+	}
+	v, err := strconv.Unquote(s)
+	if err != nil || !strings.HasPrefix(s, "\"") {
+		return "", fmt.Errorf("invalid string %s%.0w", s, errs.ErrParsingFailed)
+	}
+	return v, nil
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestContainerd(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	files := map[string]string{
+		"docker.io/hosts.toml": `
+server = "https://registry-1.docker.io"
+
+# mirrors are attempted in order
+[host."https://mirror-a.example.org"]
+  capabilities = ["pull", "resolve"]
+  ca = "mirror-a.crt"
+
+[host."http://mirror-b.example.org:5000/artifactory/api/docker/hub/v2"]
+  capabilities = [
+    "pull",
+    "resolve", # comment in an array
+  ]
+  override_path = true
+
+[host."https://push.example.org"]
+  capabilities = ["push"]
+
+[host."https://registry-1.docker.io"]
+  capabilities = ["pull", "resolve"]
+`,
+		"docker.io/mirror-a.crt": "mirror-a-ca",
+		"registry.example.org:5000/hosts.toml": `
+server = 'https://internal.example.org/registry'
+skip_verify = true
+client = [["/not/found.cert", "/not/found.key"]]
+`,
+		"certs.example.org/ca.crt":      "certs-ca",
+		"certs.example.org/client.cert": "certs-cert",
+		"certs.example.org/client.key":  "certs-key",
+		"_default/hosts.toml":           `server = "https://default.example.org"`,
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hosts, err := ContainerdLoadDir(dir)
+	// client cert files are missing from the registry.example.org config
+	if err == nil || !strings.Contains(err.Error(), "registry.example.org:5000") {
+		t.Errorf("missing client cert did not fail: %v", err)
+	}
+	hostMap := map[string]Host{}
+	for _, h := range hosts {
+		hostMap[h.Name] = h
+	}
+	if len(hostMap) != 4 {
+		t.Errorf("unexpected hosts: %v", hostMap)
+	}
+	hub := hostMap[DockerRegistry]
+	if !slices.Equal(hub.Mirrors, []string{"mirror-a.example.org", "mirror-b.example.org:5000"}) || hub.Hostname != DockerRegistryDNS {
+		t.Errorf("unexpected docker hub config: %v", hub)
+	}
+	if m := hostMap["mirror-a.example.org"]; m.TLS != TLSEnabled || m.RegCert != "mirror-a-ca" || m.BasePath != "" {
+		t.Errorf("unexpected mirror-a config: %v", m)
+	}
+	if m := hostMap["mirror-b.example.org:5000"]; m.TLS != TLSDisabled || m.BasePath != "artifactory/api/docker/hub" || m.Hostname != "mirror-b.example.org:5000" {
+		t.Errorf("unexpected mirror-b config: %v", m)
+	}
+	if h := hostMap["certs.example.org"]; h.RegCert != "certs-ca" || h.ClientCert != "certs-cert" || h.ClientKey != "certs-key" {
+		t.Errorf("unexpected certs config: %v", h)
+	}
+
+	t.Run("server", func(t *testing.T) {
+		_ = os.WriteFile(filepath.Join(dir, "registry.example.org:5000/hosts.toml"), []byte(`
+server = 'https://internal.example.org/registry'
+skip_verify = true
+`), 0o600)
+		hosts, err := containerdLoadHost(filepath.Join(dir, "registry.example.org:5000"), "registry.example.org:5000")
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if len(hosts) != 1 || hosts[0].Hostname != "internal.example.org" || hosts[0].BasePath != "registry" || hosts[0].TLS != TLSInsecure {
+			t.Errorf("unexpected host: %v", hosts)
+		}
+	})
+	t.Run("missing", func(t *testing.T) {
+		hosts, err := ContainerdLoadDir(filepath.Join(dir, "missing"))
+		if err != nil || len(hosts) != 0 {
+			t.Errorf("unexpected result for a missing directory: %v, %v", hosts, err)
+		}
+	})
+}
+
+func TestTOMLParse(t *testing.T) {
+	t.Parallel()
+	tt := []struct {
+		name      string
+		in        string
+		expect    []tomlTable
+		expectErr bool
+	}{
+		{
+			name: "tables",
+			in: `
+key = "value # not a comment" # comment
+"quoted.key" = 'literal\n'
+list = [["a", "b"], "c"]
+[host."https://example.org".header]
+  x-custom = ["1"]
+  enabled = false
+`,
+			expect: []tomlTable{
+				{values: map[string]any{"key": "value # not a comment", "quoted.key": `literal\n`, "list": []any{[]any{"a", "b"}, "c"}}},
+				{name: []string{"host", "https://example.org", "header"}, values: map[string]any{"x-custom": []any{"1"}, "enabled": false}},
+			},
+		},
+		{
+			name:      "array of tables",
+			in:        "[[host]]\n",
+			expectErr: true,
+		},
+		{
+			name:      "missing value",
+			in:        "key =\n",
+			expectErr: true,
+		},
+		{
+			name:      "inline table",
+			in:        "header = { x-custom = \"1\" }\n",
+			expectErr: true,
+		},
+		{
+			name:      "number",
+			in:        "dial_timeout = 30\n",
+			expectErr: true,
+		},
+		{
+			name:      "multi-line string",
+			in:        "ca = \"\"\"\n/etc/ca.pem\n\"\"\"\n",
+			expectErr: true,
+		},
+		{
+			name:      "multi-line literal string",
+			in:        "ca = '''/etc/ca.pem'''\n",
+			expectErr: true,
+		},
+		{
+			name:      "bare string",
+			in:        "server = https://example.org\n",
+			expectErr: true,
+		},
+		{
+			name:      "unterminated array",
+			in:        "key = [\"a\",\n",
+			expectErr: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tables, err := tomlParse(strings.NewReader(tc.in))
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("parse did not fail: %v", tables)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if len(tables) != len(tc.expect) {
+				t.Fatalf("unexpected tables, expected %v, received %v", tc.expect, tables)
+			}
+			for i := range tables {
+				if !slices.Equal(tables[i].name, tc.expect[i].name) || !reflect.DeepEqual(tables[i].values, tc.expect[i].values) {
+					t.Errorf("unexpected table %d, expected %v, received %v", i, tc.expect[i], tables[i])
+				}
+			}
+		})
+	}
+}
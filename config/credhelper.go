@@ -42,11 +42,7 @@ type credStore struct {
 
 // get requests a credential from the helper for a given host.
 func (ch *credHelper) get(host *Host) error {
-	hostname := host.Hostname
-	if host.CredHost != "" {
-		hostname = host.CredHost
-	}
-	hostIn := strings.NewReader(hostname)
+	hostIn := strings.NewReader(host.credHostname())
 	credOut := credStore{
 		Username: host.User,
 		Secret:   host.Pass,
@@ -97,4 +93,35 @@ func (ch *credHelper) list() ([]Host, error) {
 	return hostList, nil
 }
 
-// TODO: store method not implemented
+// store saves a credential with the helper for a given host.
+func (ch *credHelper) store(host *Host, cred Cred) error {
+	credIn := credStore{
+		ServerURL: host.credHostname(),
+		Username:  cred.User,
+		Secret:    cred.Password,
+	}
+	if cred.Token != "" {
+		credIn.Username = tokenUser
+		credIn.Secret = cred.Token
+	}
+	inB, err := json.Marshal(credIn)
+	if err != nil {
+		return err
+	}
+	outB, err := ch.run("store", bytes.NewReader(inB))
+	if err != nil {
+		outS := strings.TrimSpace(string(outB))
+		return fmt.Errorf("error storing credentials, output: %s, error: %w", outS, err)
+	}
+	return nil
+}
+
+// erase removes a credential from the helper for a given host.
+func (ch *credHelper) erase(host *Host) error {
+	outB, err := ch.run("erase", strings.NewReader(host.credHostname()))
+	if err != nil {
+		outS := strings.TrimSpace(string(outB))
+		return fmt.Errorf("error erasing credentials, output: %s, error: %w", outS, err)
+	}
+	return nil
+}
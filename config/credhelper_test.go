@@ -88,3 +88,65 @@ func TestCredHelper(t *testing.T) {
 		})
 	}
 }
+
+func TestCredHelperStore(t *testing.T) {
+	// cannot run cred helper in parallel because of OS working directory race conditions
+	tests := []struct {
+		name string
+		host *Host
+		cred Cred
+	}{
+		{
+			name: "user/pass",
+			host: HostNewName("keyring.example.com"),
+			cred: Cred{User: "hello", Password: "world"},
+		},
+		{
+			name: "token",
+			host: HostNewName("keyringtoken.example.com"),
+			cred: Cred{Token: "deadbeefcafe"},
+		},
+		{
+			name: "config entry",
+			host: &Host{Name: DockerRegistry},
+			cred: Cred{User: "hubuser", Password: "password123"},
+		},
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed checking current directory: %v", err)
+	}
+	curPath := os.Getenv("PATH")
+	t.Setenv("PATH", filepath.Join(cwd, "testdata")+string(os.PathListSeparator)+curPath)
+	t.Setenv("REGCLIENT_TEST_KEYRING", t.TempDir())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.host.CredHelper = "docker-credential-testkeyring"
+			err := tt.host.StoreCred(tt.cred)
+			if err != nil {
+				t.Fatalf("failed to store: %v", err)
+			}
+			h := HostNewName(tt.host.Name)
+			h.CredHelper = tt.host.CredHelper
+			cred := h.GetCred()
+			if cred != tt.cred {
+				t.Errorf("cred mismatch: expected %v, received %v", tt.cred, cred)
+			}
+			err = tt.host.EraseCred()
+			if err != nil {
+				t.Fatalf("failed to erase: %v", err)
+			}
+			err = newCredHelper(h.CredHelper, map[string]string{}).get(h)
+			if err == nil {
+				t.Errorf("credential found after erase")
+			}
+			err = tt.host.EraseCred()
+			if err == nil {
+				t.Errorf("erase of missing credential did not fail")
+			}
+		})
+	}
+	if err := (&Host{Name: "nohelper.example.com"}).StoreCred(Cred{User: "hello"}); err == nil {
+		t.Errorf("store without a helper did not fail")
+	}
+}
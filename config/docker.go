@@ -8,6 +8,7 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/regclient/regclient/internal/conffile"
@@ -37,6 +38,12 @@ type dockerConfig struct {
 	Proxies           map[string]dockerProxyConfig `json:"proxies,omitempty"`
 }
 
+// dockerDaemonConfig is used to parse the registry settings from the docker daemon.json
+type dockerDaemonConfig struct {
+	InsecureRegistries []string `json:"insecure-registries,omitempty"`
+	RegistryMirrors    []string `json:"registry-mirrors,omitempty"`
+}
+
 // dockerProxyConfig contains proxy configuration settings
 type dockerProxyConfig struct {
 	HTTPProxy  string `json:"httpProxy,omitempty"`
@@ -114,6 +121,52 @@ func DockerLoadFile(fname string) ([]Host, error) {
 	return dockerParse(rdr)
 }
 
+// DockerDaemonLoadFile returns a slice of hosts from the registry settings of a docker daemon.json, e.g. /etc/docker/daemon.json.
+// Registry mirrors are added to Docker Hub, and insecure registries are configured with TLS disabled.
+// Insecure registries specified with a CIDR are skipped.
+func DockerDaemonLoadFile(fname string) ([]Host, error) {
+	//#nosec G304 scoping file operations to a directory is not yet a feature of regclient.
+	rdr, err := os.Open(fname)
+	if err != nil && errors.Is(err, fs.ErrNotExist) {
+		return []Host{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer rdr.Close()
+	dc := dockerDaemonConfig{}
+	if err := json.NewDecoder(rdr).Decode(&dc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	hosts := []Host{}
+	if len(dc.RegistryMirrors) > 0 {
+		hub := HostNewName(DockerRegistry)
+		for _, mirror := range dc.RegistryMirrors {
+			m := HostNewName(mirror)
+			m.CredHost = ""
+			_, _, path := parseName(mirror)
+			if path = strings.Trim(path, "/"); path != "" {
+				m.BasePath = path
+			}
+			hub.Mirrors = append(hub.Mirrors, m.Name)
+			hosts = append(hosts, *m)
+		}
+		hosts = append([]Host{*hub}, hosts...)
+	}
+	for _, name := range dc.InsecureRegistries {
+		if strings.Contains(name, "/") {
+			continue
+		}
+		h := HostNewName(name)
+		h.TLS = TLSDisabled
+		if i := slices.IndexFunc(hosts, func(cur Host) bool { return cur.Name == h.Name }); i >= 0 {
+			hosts[i].TLS = TLSDisabled
+			continue
+		}
+		hosts = append(hosts, *h)
+	}
+	return hosts, nil
+}
+
 // DockerLoadEnv returns a slice of hosts extracted from the config injected in an environment variable.
 func DockerLoadEnv(envName string) ([]Host, error) {
 	envVal := os.Getenv(envName)
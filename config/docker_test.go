@@ -176,3 +176,39 @@ func TestLoadMissing(t *testing.T) {
 		t.Errorf("hosts returned from missing file")
 	}
 }
+
+func TestDockerDaemon(t *testing.T) {
+	t.Parallel()
+	fname := filepath.Join(t.TempDir(), "daemon.json")
+	err := os.WriteFile(fname, []byte(`{
+  "registry-mirrors": ["https://mirror.example.org", "http://mirror2.example.org:5000/hub/"],
+  "insecure-registries": ["localhost:5000", "mirror2.example.org:5000", "10.0.0.0/8"],
+  "log-driver": "json-file"
+}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts, err := DockerDaemonLoadFile(fname)
+	if err != nil {
+		t.Fatalf("failed to load daemon config: %v", err)
+	}
+	if len(hosts) != 4 {
+		t.Fatalf("unexpected hosts: %v", hosts)
+	}
+	if hosts[0].Name != DockerRegistry || len(hosts[0].Mirrors) != 2 || hosts[0].Mirrors[0] != "mirror.example.org" || hosts[0].Mirrors[1] != "mirror2.example.org:5000" {
+		t.Errorf("unexpected docker hub config: %v", hosts[0])
+	}
+	if hosts[1].Name != "mirror.example.org" || hosts[1].TLS != TLSEnabled || hosts[1].CredHost != "" {
+		t.Errorf("unexpected mirror config: %v", hosts[1])
+	}
+	if hosts[2].Name != "mirror2.example.org:5000" || hosts[2].TLS != TLSDisabled || hosts[2].BasePath != "hub" {
+		t.Errorf("unexpected mirror config: %v", hosts[2])
+	}
+	if hosts[3].Name != "localhost:5000" || hosts[3].TLS != TLSDisabled {
+		t.Errorf("unexpected insecure registry config: %v", hosts[3])
+	}
+	hosts, err = DockerDaemonLoadFile(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || len(hosts) != 0 {
+		t.Errorf("unexpected result for a missing file: %v, %v", hosts, err)
+	}
+}
@@ -2,11 +2,14 @@
 package config
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"maps"
+	"net/url"
+	"regexp"
 	"slices"
 	"strings"
 	"time"
@@ -98,14 +101,78 @@ func (t *TLSConf) UnmarshalText(b []byte) error {
 	return nil
 }
 
+// TLSVersion specifies a minimum TLS version for a host.
+type TLSVersion uint16
+
+// MarshalJSON converts TLSVersion to a json string using MarshalText.
+func (v TLSVersion) MarshalJSON() ([]byte, error) {
+	s, err := v.MarshalText()
+	if err != nil {
+		return []byte(""), err
+	}
+	return json.Marshal(string(s))
+}
+
+// MarshalText converts TLSVersion to a string.
+func (v TLSVersion) MarshalText() ([]byte, error) {
+	var s string
+	switch uint16(v) {
+	case 0:
+		s = ""
+	case tls.VersionTLS10:
+		s = "1.0"
+	case tls.VersionTLS11:
+		s = "1.1"
+	case tls.VersionTLS12:
+		s = "1.2"
+	case tls.VersionTLS13:
+		s = "1.3"
+	default:
+		return []byte(""), fmt.Errorf("unknown TLS version %d", v)
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalJSON converts TLSVersion from a json string.
+func (v *TLSVersion) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return v.UnmarshalText([]byte(s))
+}
+
+// UnmarshalText converts TLSVersion from a string.
+func (v *TLSVersion) UnmarshalText(b []byte) error {
+	switch strings.TrimPrefix(strings.ToLower(string(b)), "tls") {
+	default:
+		return fmt.Errorf("unknown TLS version \"%s\"", b)
+	case "":
+		*v = 0
+	case "1.0":
+		*v = TLSVersion(tls.VersionTLS10)
+	case "1.1":
+		*v = TLSVersion(tls.VersionTLS11)
+	case "1.2":
+		*v = TLSVersion(tls.VersionTLS12)
+	case "1.3":
+		*v = TLSVersion(tls.VersionTLS13)
+	}
+	return nil
+}
+
 // Host defines settings for connecting to a registry.
 type Host struct {
 	Name          string            `json:"-" yaml:"registry,omitempty"`                  // Name of the registry (required) (yaml configs pass this as a field, json provides this from the object key)
 	TLS           TLSConf           `json:"tls,omitempty" yaml:"tls"`                     // TLS setting: enabled (default), disabled, insecure
+	TLSMinVersion TLSVersion        `json:"tlsMinVersion,omitempty" yaml:"tlsMinVersion"` // minimum TLS version: 1.0, 1.1, 1.2 (default), 1.3
+	TLSServerName string            `json:"tlsServerName,omitempty" yaml:"tlsServerName"` // server name (SNI) used for the TLS handshake and certificate verification
 	RegCert       string            `json:"regcert,omitempty" yaml:"regcert"`             // public pem cert of registry
 	ClientCert    string            `json:"clientCert,omitempty" yaml:"clientCert"`       // public pem cert for client (mTLS)
 	ClientKey     string            `json:"clientKey,omitempty" yaml:"clientKey"`         //#nosec G117 private pem cert for client (mTLS)
 	Hostname      string            `json:"hostname,omitempty" yaml:"hostname"`           // hostname of registry, default is the registry name
+	BasePath      string            `json:"basePath,omitempty" yaml:"basePath"`           // path before the /v2 API for registries served under a path, e.g. artifactory/api/docker/repo
+	URL           string            `json:"url,omitempty" yaml:"url"`                     // base url of the registry, sets the TLS, hostname, and base path when they are not configured
 	User          string            `json:"user,omitempty" yaml:"user"`                   // username, not used with credHelper
 	Pass          string            `json:"pass,omitempty" yaml:"pass"`                   //#nosec G117 password, not used with credHelper
 	Token         string            `json:"token,omitempty" yaml:"token"`                 // token, experimental for specific APIs
@@ -113,19 +180,100 @@ type Host struct {
 	CredExpire    timejson.Duration `json:"credExpire,omitempty" yaml:"credExpire"`       // time until credential expires
 	CredHost      string            `json:"credHost,omitempty" yaml:"credHost"`           // used when a helper hostname doesn't match Hostname
 	PathPrefix    string            `json:"pathPrefix,omitempty" yaml:"pathPrefix"`       // used for mirrors defined within a repository namespace
-	Mirrors       []string          `json:"mirrors,omitempty" yaml:"mirrors"`             // list of other Host Names to use as mirrors
+	Mirrors       []string          `json:"mirrors,omitempty" yaml:"mirrors"`             // list of other Host Names to use as mirrors, attempted in order before this host
 	Priority      uint              `json:"priority,omitempty" yaml:"priority"`           // priority when sorting mirrors, higher priority attempted first
 	RepoAuth      bool              `json:"repoAuth,omitempty" yaml:"repoAuth"`           // tracks a separate auth per repo
+	Proxy         string            `json:"proxy,omitempty" yaml:"proxy"`                 // proxy url (http, https, socks5), "direct" to ignore proxy environment variables
+	NoProxy       []string          `json:"noProxy,omitempty" yaml:"noProxy"`             // list of hosts, domains, or CIDRs to bypass the proxy, similar to NO_PROXY
 	API           string            `json:"api,omitempty" yaml:"api"`                     // Deprecated: registry API to use
 	APIOpts       map[string]string `json:"apiOpts,omitempty" yaml:"apiOpts"`             // options for APIs
 	BlobChunk     int64             `json:"blobChunk,omitempty" yaml:"blobChunk"`         // size of each blob chunk
 	BlobMax       int64             `json:"blobMax,omitempty" yaml:"blobMax"`             // threshold to switch to chunked upload, -1 to disable, 0 for regclient.blobMaxPut
 	ReqPerSec     float64           `json:"reqPerSec,omitempty" yaml:"reqPerSec"`         // requests per second
 	ReqConcurrent int64             `json:"reqConcurrent,omitempty" yaml:"reqConcurrent"` // concurrent requests, default is defaultConcurrent(3)
+	ImmutableTags []ImmutableTag    `json:"immutableTags,omitempty" yaml:"immutableTags"` // tags that cannot be replaced or deleted without forcing the request
 	Scheme        string            `json:"scheme,omitempty" yaml:"scheme"`               // Deprecated: use TLS instead
 	credRefresh   time.Time         `json:"-" yaml:"-"`                                   // internal use, when to refresh credentials
 }
 
+// ImmutableTag defines a pattern of tags that are protected from being replaced or deleted.
+// Both fields are regular expressions, and an empty Repo matches every repository on the host.
+type ImmutableTag struct {
+	Repo string `json:"repo,omitempty" yaml:"repo"` // regexp of repositories, e.g. ^library/
+	Tag  string `json:"tag" yaml:"tag"`             // regexp of tags, e.g. ^v\d+\.\d+\.\d+$
+}
+
+// ImmutableTagParse converts a string in the format "[repo=]tag" to an [ImmutableTag].
+func ImmutableTagParse(s string) (ImmutableTag, error) {
+	it := ImmutableTag{Tag: s}
+	if repo, tag, ok := strings.Cut(s, "="); ok {
+		it.Repo = repo
+		it.Tag = tag
+	}
+	if err := it.validate(); err != nil {
+		return ImmutableTag{}, err
+	}
+	return it, nil
+}
+
+// String returns the [ImmutableTag] in the format parsed by [ImmutableTagParse].
+func (it ImmutableTag) String() string {
+	if it.Repo == "" {
+		return it.Tag
+	}
+	return it.Repo + "=" + it.Tag
+}
+
+// Match returns true if the repository and tag match the patterns.
+func (it ImmutableTag) Match(repo, tag string) (bool, error) {
+	if it.Tag == "" {
+		return false, fmt.Errorf("immutable tag pattern is empty")
+	}
+	if it.Repo != "" {
+		re, err := regexp.Compile(it.Repo)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse immutable tag repo pattern %q: %w", it.Repo, err)
+		}
+		if !re.MatchString(repo) {
+			return false, nil
+		}
+	}
+	re, err := regexp.Compile(it.Tag)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse immutable tag pattern %q: %w", it.Tag, err)
+	}
+	return re.MatchString(tag), nil
+}
+
+func (it ImmutableTag) validate() error {
+	_, err := it.Match("", "")
+	return err
+}
+
+// ProxyDirect is the [Host] Proxy value to ignore any proxy from the environment.
+const ProxyDirect = "direct"
+
+// ProxyParse parses the [Host] Proxy value.
+// A nil URL is returned for an empty value or [ProxyDirect].
+func ProxyParse(proxy string) (*url.URL, error) {
+	if proxy == "" || proxy == ProxyDirect {
+		return nil, nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proxy %s: %w", proxy, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %s", proxy)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy host is missing %s", proxy)
+	}
+	return u, nil
+}
+
 // Cred defines a user credential for accessing a registry.
 type Cred struct {
 	User, Password, Token string //#nosec G117 exported struct intentionally holds secrets
@@ -178,6 +326,12 @@ func HostNewDefName(def *Host, name string) *Host {
 			h.Mirrors = make([]string, len(orig))
 			copy(h.Mirrors, orig)
 		}
+		if h.NoProxy != nil {
+			h.NoProxy = slices.Clone(h.NoProxy)
+		}
+		if h.ImmutableTags != nil {
+			h.ImmutableTags = slices.Clone(h.ImmutableTags)
+		}
 	}
 	// configure host
 	scheme, registry, _ := parseName(name)
@@ -205,6 +359,18 @@ func HostValidate(name string) bool {
 	return path == "" && (scheme == "https" || scheme == "http")
 }
 
+// IsImmutableTag returns true if the tag in the repository matches one of the ImmutableTags patterns.
+// An invalid pattern returns an error so that the tag is not accidentally modified.
+func (host Host) IsImmutableTag(repo, tag string) (bool, error) {
+	for _, it := range host.ImmutableTags {
+		match, err := it.Match(repo, tag)
+		if err != nil || match {
+			return true, err
+		}
+	}
+	return false, nil
+}
+
 // GetCred returns the credential, fetching from a credential helper if needed.
 func (host *Host) GetCred() Cred {
 	// refresh from credHelper if needed
@@ -214,6 +380,53 @@ func (host *Host) GetCred() Cred {
 	return Cred{User: host.User, Password: host.Pass, Token: host.Token}
 }
 
+// StoreCred saves the credential with the credential helper.
+func (host *Host) StoreCred(cred Cred) error {
+	if host.CredHelper == "" {
+		return fmt.Errorf("credential helper not configured for %s", host.Name)
+	}
+	ch := newCredHelper(host.CredHelper, map[string]string{})
+	err := ch.store(host, cred)
+	if err != nil {
+		return err
+	}
+	host.User = cred.User
+	host.Pass = cred.Password
+	host.Token = cred.Token
+	return nil
+}
+
+// EraseCred removes the credential from the credential helper.
+func (host *Host) EraseCred() error {
+	if host.CredHelper == "" {
+		return fmt.Errorf("credential helper not configured for %s", host.Name)
+	}
+	ch := newCredHelper(host.CredHelper, map[string]string{})
+	err := ch.erase(host)
+	if err != nil {
+		return err
+	}
+	host.User = ""
+	host.Pass = ""
+	host.Token = ""
+	host.credRefresh = time.Time{}
+	return nil
+}
+
+// credHostname returns the name of the host used with a credential helper.
+func (host *Host) credHostname() string {
+	switch {
+	case host.CredHost != "":
+		return host.CredHost
+	case host.Hostname != "":
+		return host.Hostname
+	case host.Name == DockerRegistry:
+		return DockerRegistryAuth
+	default:
+		return host.Name
+	}
+}
+
 func (host *Host) refreshHelper() {
 	if host.CredHelper == "" {
 		return
@@ -234,10 +447,14 @@ func (host *Host) refreshHelper() {
 // IsZero returns true if the struct is set to the zero value or the result of [HostNew].
 func (host Host) IsZero() bool {
 	if (host.TLS != TLSUndefined && host.TLS != TLSEnabled) ||
+		host.TLSMinVersion != 0 ||
+		host.TLSServerName != "" ||
 		host.RegCert != "" ||
 		host.ClientCert != "" ||
 		host.ClientKey != "" ||
 		(host.Hostname != "" && host.Hostname != host.Name) ||
+		host.BasePath != "" ||
+		host.URL != "" ||
 		host.User != "" ||
 		host.Pass != "" ||
 		host.Token != "" ||
@@ -248,6 +465,9 @@ func (host Host) IsZero() bool {
 		len(host.Mirrors) != 0 ||
 		host.Priority != 0 ||
 		host.RepoAuth ||
+		host.Proxy != "" ||
+		len(host.NoProxy) != 0 ||
+		len(host.ImmutableTags) != 0 ||
 		len(host.APIOpts) != 0 ||
 		host.BlobChunk != 0 ||
 		host.BlobMax != 0 ||
@@ -275,6 +495,30 @@ func (host *Host) Merge(newHost Host, log *slog.Logger) error {
 		host.Name = newHost.Name
 	}
 
+	if newHost.URL != "" {
+		// expand the url into the other settings, values set directly take precedence
+		tlsConf, hostname, basePath, err := parseURL(newHost.URL)
+		if err != nil {
+			return err
+		}
+		if newHost.TLS == TLSUndefined {
+			newHost.TLS = tlsConf
+		}
+		if newHost.Hostname == "" {
+			newHost.Hostname = hostname
+		}
+		if newHost.BasePath == "" {
+			newHost.BasePath = basePath
+		}
+		if host.URL != "" && host.URL != newHost.URL {
+			log.Warn("Changing url settings for registry",
+				slog.String("orig", host.URL),
+				slog.String("new", newHost.URL),
+				slog.String("host", name))
+		}
+		host.URL = newHost.URL
+	}
+
 	if newHost.CredHelper == "" && (newHost.Pass != "" || host.Token != "") {
 		// unset existing cred helper for user/pass or token
 		host.CredHelper = ""
@@ -355,6 +599,28 @@ func (host *Host) Merge(newHost Host, log *slog.Logger) error {
 		host.TLS = newHost.TLS
 	}
 
+	if newHost.TLSMinVersion != 0 {
+		if host.TLSMinVersion != 0 && host.TLSMinVersion != newHost.TLSMinVersion {
+			verOrig, _ := host.TLSMinVersion.MarshalText()
+			verNew, _ := newHost.TLSMinVersion.MarshalText()
+			log.Warn("Changing TLS minimum version for registry",
+				slog.String("orig", string(verOrig)),
+				slog.String("new", string(verNew)),
+				slog.String("host", name))
+		}
+		host.TLSMinVersion = newHost.TLSMinVersion
+	}
+
+	if newHost.TLSServerName != "" {
+		if host.TLSServerName != "" && host.TLSServerName != newHost.TLSServerName {
+			log.Warn("Changing TLS server name for registry",
+				slog.String("orig", host.TLSServerName),
+				slog.String("new", newHost.TLSServerName),
+				slog.String("host", name))
+		}
+		host.TLSServerName = newHost.TLSServerName
+	}
+
 	if newHost.RegCert != "" {
 		if host.RegCert != "" && host.RegCert != newHost.RegCert {
 			log.Warn("Changing certificate settings for registry",
@@ -393,6 +659,17 @@ func (host *Host) Merge(newHost Host, log *slog.Logger) error {
 		host.Hostname = newHost.Hostname
 	}
 
+	if newHost.BasePath != "" {
+		newHost.BasePath = strings.Trim(newHost.BasePath, "/") // leading and trailing / are not needed
+		if host.BasePath != "" && host.BasePath != newHost.BasePath {
+			log.Warn("Changing base path settings for registry",
+				slog.String("orig", host.BasePath),
+				slog.String("new", newHost.BasePath),
+				slog.String("host", name))
+		}
+		host.BasePath = newHost.BasePath
+	}
+
 	if newHost.PathPrefix != "" {
 		newHost.PathPrefix = strings.Trim(newHost.PathPrefix, "/") // leading and trailing / are not needed
 		if host.PathPrefix != "" && host.PathPrefix != newHost.PathPrefix {
@@ -428,6 +705,41 @@ func (host *Host) Merge(newHost Host, log *slog.Logger) error {
 		host.RepoAuth = newHost.RepoAuth
 	}
 
+	if newHost.Proxy != "" {
+		if host.Proxy != "" && host.Proxy != newHost.Proxy {
+			log.Warn("Changing proxy settings for registry",
+				slog.String("orig", host.Proxy),
+				slog.String("new", newHost.Proxy),
+				slog.String("host", name))
+		}
+		host.Proxy = newHost.Proxy
+	}
+
+	if len(newHost.NoProxy) > 0 {
+		if len(host.NoProxy) > 0 && !slices.Equal(host.NoProxy, newHost.NoProxy) {
+			log.Warn("Changing no proxy settings for registry",
+				slog.Any("orig", host.NoProxy),
+				slog.Any("new", newHost.NoProxy),
+				slog.String("host", name))
+		}
+		host.NoProxy = newHost.NoProxy
+	}
+
+	if len(newHost.ImmutableTags) > 0 {
+		for _, it := range newHost.ImmutableTags {
+			if err := it.validate(); err != nil {
+				return err
+			}
+		}
+		if len(host.ImmutableTags) > 0 && !slices.Equal(host.ImmutableTags, newHost.ImmutableTags) {
+			log.Warn("Changing immutable tag settings for registry",
+				slog.Any("orig", host.ImmutableTags),
+				slog.Any("new", newHost.ImmutableTags),
+				slog.String("host", name))
+		}
+		host.ImmutableTags = newHost.ImmutableTags
+	}
+
 	// TODO: eventually delete
 	if newHost.API != "" {
 		log.Warn("API field has been deprecated",
@@ -497,6 +809,28 @@ func (host *Host) Merge(newHost Host, log *slog.Logger) error {
 	return nil
 }
 
+// parseURL splits the base url of a registry into the TLS setting, hostname, and base path.
+func parseURL(baseURL string) (TLSConf, string, string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return TLSUndefined, "", "", fmt.Errorf("invalid registry url \"%s\": %w", baseURL, err)
+	}
+	tlsConf := TLSEnabled
+	switch u.Scheme {
+	case "https":
+	case "http":
+		tlsConf = TLSDisabled
+	default:
+		return TLSUndefined, "", "", fmt.Errorf("invalid registry url \"%s\", scheme must be http or https", baseURL)
+	}
+	if u.Host == "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return TLSUndefined, "", "", fmt.Errorf("invalid registry url \"%s\", only the scheme, host, and path may be set", baseURL)
+	}
+	basePath := strings.Trim(u.Path, "/")
+	basePath = strings.TrimSuffix(strings.TrimSuffix(basePath, "v2"), "/")
+	return tlsConf, u.Host, basePath, nil
+}
+
 // parseName splits a registry into the scheme, hostname, and repository/path.
 func parseName(name string) (string, string, string) {
 	scheme := "https"
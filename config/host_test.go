@@ -1,6 +1,7 @@
 package config
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -125,12 +126,15 @@ func TestConfig(t *testing.T) {
 	exJSON2 := `
 	{
 	  "tls": "disabled",
+		"tlsMinVersion": "1.3",
+		"tlsServerName": "registry.example.com",
 		"hostname": "host2.example.com",
 		"user": "user-ex3",
 		"pass": "secret3",
 		"regcert": "` + strings.ReplaceAll(caCert, "\n", "\\n") + `",
 		"clientCert": "` + strings.ReplaceAll(clientCert, "\n", "\\n") + `",
 		"clientKey": "` + strings.ReplaceAll(clientKey, "\n", "\\n") + `",
+		"basePath": "/api/docker/hub3/",
 		"pathPrefix": "hub3",
 		"mirrors": ["testhost.example.com"],
 		"priority": 42,
@@ -286,19 +290,22 @@ func TestConfig(t *testing.T) {
 			name: "exHost2",
 			host: exHost2,
 			hostExpect: Host{
-				TLS:        TLSDisabled,
-				Hostname:   "host2.example.com",
-				User:       "user-ex3",
-				Pass:       "secret3",
-				RegCert:    caCert,
-				ClientCert: clientCert,
-				ClientKey:  clientKey,
-				PathPrefix: "hub3",
-				Mirrors:    []string{"testhost.example.com"},
-				Priority:   42,
-				APIOpts:    map[string]string{"disableHead": "false", "unknownOpt": "3"},
-				BlobChunk:  333333,
-				BlobMax:    333333,
+				TLS:           TLSDisabled,
+				TLSMinVersion: TLSVersion(tls.VersionTLS13),
+				TLSServerName: "registry.example.com",
+				Hostname:      "host2.example.com",
+				User:          "user-ex3",
+				Pass:          "secret3",
+				RegCert:       caCert,
+				ClientCert:    clientCert,
+				ClientKey:     clientKey,
+				BasePath:      "/api/docker/hub3/",
+				PathPrefix:    "hub3",
+				Mirrors:       []string{"testhost.example.com"},
+				Priority:      42,
+				APIOpts:       map[string]string{"disableHead": "false", "unknownOpt": "3"},
+				BlobChunk:     333333,
+				BlobMax:       333333,
 			},
 			credExpect: Cred{
 				User:     "user-ex3",
@@ -344,19 +351,22 @@ func TestConfig(t *testing.T) {
 			name: "mergeHost2",
 			host: exMergeHost2,
 			hostExpect: Host{
-				TLS:        TLSDisabled,
-				Hostname:   "host2.example.com",
-				User:       "user-ex3",
-				Pass:       "secret3",
-				RegCert:    caCert,
-				ClientCert: clientCert,
-				ClientKey:  clientKey,
-				PathPrefix: "hub3",
-				Mirrors:    []string{"testhost.example.com"},
-				Priority:   42,
-				APIOpts:    map[string]string{"disableHead": "false", "unknownOpt": "3"},
-				BlobChunk:  333333,
-				BlobMax:    333333,
+				TLS:           TLSDisabled,
+				TLSMinVersion: TLSVersion(tls.VersionTLS13),
+				TLSServerName: "registry.example.com",
+				Hostname:      "host2.example.com",
+				User:          "user-ex3",
+				Pass:          "secret3",
+				RegCert:       caCert,
+				ClientCert:    clientCert,
+				ClientKey:     clientKey,
+				BasePath:      "api/docker/hub3",
+				PathPrefix:    "hub3",
+				Mirrors:       []string{"testhost.example.com"},
+				Priority:      42,
+				APIOpts:       map[string]string{"disableHead": "false", "unknownOpt": "3"},
+				BlobChunk:     333333,
+				BlobMax:       333333,
 			},
 			credExpect: Cred{
 				User:     "user-ex3",
@@ -431,6 +441,12 @@ func TestConfig(t *testing.T) {
 				found, _ := tc.host.TLS.MarshalText()
 				t.Errorf("tls field mismatch, expected %s, found %s", expect, found)
 			}
+			if tc.host.TLSMinVersion != tc.hostExpect.TLSMinVersion {
+				t.Errorf("tlsMinVersion field mismatch, expected %d, found %d", tc.hostExpect.TLSMinVersion, tc.host.TLSMinVersion)
+			}
+			if tc.host.TLSServerName != tc.hostExpect.TLSServerName {
+				t.Errorf("tlsServerName field mismatch, expected %s, found %s", tc.hostExpect.TLSServerName, tc.host.TLSServerName)
+			}
 			if tc.host.RegCert != tc.hostExpect.RegCert {
 				t.Errorf("regCert field mismatch, expected %s, found %s", tc.hostExpect.RegCert, tc.host.RegCert)
 			}
@@ -458,6 +474,9 @@ func TestConfig(t *testing.T) {
 			if tc.host.CredExpire != tc.hostExpect.CredExpire {
 				t.Errorf("credExCredExpire field mismatch, expected %s, found %s", time.Duration(tc.hostExpect.CredExpire).String(), time.Duration(tc.host.CredExpire).String())
 			}
+			if tc.host.BasePath != tc.hostExpect.BasePath {
+				t.Errorf("basePath field mismatch, expected %s, found %s", tc.hostExpect.BasePath, tc.host.BasePath)
+			}
 			if tc.host.PathPrefix != tc.hostExpect.PathPrefix {
 				t.Errorf("pathPrefix field mismatch, expected %s, found %s", tc.hostExpect.PathPrefix, tc.host.PathPrefix)
 			}
@@ -501,3 +520,201 @@ func TestConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestImmutableTags(t *testing.T) {
+	t.Parallel()
+	h := Host{
+		Name: "registry.example.org",
+		ImmutableTags: []ImmutableTag{
+			{Tag: `^v\d+\.\d+\.\d+$`},
+			{Repo: `^prod/`, Tag: `^latest$`},
+		},
+	}
+	tt := []struct {
+		name   string
+		repo   string
+		tag    string
+		expect bool
+	}{
+		{name: "semver", repo: "app", tag: "v1.2.3", expect: true},
+		{name: "semver prefix", repo: "app", tag: "v1.2", expect: false},
+		{name: "semver suffix", repo: "app", tag: "v1.2.3-rc1", expect: false},
+		{name: "repo match", repo: "prod/app", tag: "latest", expect: true},
+		{name: "repo mismatch", repo: "dev/app", tag: "latest", expect: false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := h.IsImmutableTag(tc.repo, tc.tag)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tc.expect {
+				t.Errorf("unexpected result for %s:%s, expected %t, received %t", tc.repo, tc.tag, tc.expect, result)
+			}
+		})
+	}
+	t.Run("invalid", func(t *testing.T) {
+		hBad := Host{ImmutableTags: []ImmutableTag{{Tag: "[invalid"}}}
+		result, err := hBad.IsImmutableTag("app", "latest")
+		if err == nil || !result {
+			t.Errorf("invalid pattern did not fail, result %t, err %v", result, err)
+		}
+		err = h.Merge(hBad, nil)
+		if err == nil {
+			t.Errorf("merge did not fail with an invalid pattern")
+		}
+	})
+	t.Run("parse", func(t *testing.T) {
+		for _, s := range []string{`^v\d+$`, `^prod/=^latest$`} {
+			it, err := ImmutableTagParse(s)
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", s, err)
+			}
+			if it.String() != s {
+				t.Errorf("unexpected string, expected %s, received %s", s, it.String())
+			}
+		}
+		it, err := ImmutableTagParse(`^prod/=^latest$`)
+		if err != nil {
+			t.Fatalf("failed to parse: %v", err)
+		}
+		if it.Repo != `^prod/` || it.Tag != `^latest$` {
+			t.Errorf("unexpected parse result: %v", it)
+		}
+		_, err = ImmutableTagParse(`app=`)
+		if err == nil {
+			t.Errorf("empty tag pattern did not fail")
+		}
+	})
+	t.Run("merge", func(t *testing.T) {
+		hNew := HostNewName("registry.example.org")
+		err := hNew.Merge(h, nil)
+		if err != nil {
+			t.Fatalf("failed to merge: %v", err)
+		}
+		if len(hNew.ImmutableTags) != 2 || hNew.IsZero() {
+			t.Errorf("immutable tags not merged: %v", hNew.ImmutableTags)
+		}
+	})
+}
+
+func TestHostURL(t *testing.T) {
+	t.Parallel()
+	tt := []struct {
+		name           string
+		host           Host
+		expectTLS      TLSConf
+		expectHostname string
+		expectBasePath string
+		expectErr      bool
+	}{
+		{
+			name:           "https with path",
+			host:           Host{Name: "nexus.alias", URL: "https://nexus.example.org/repository/docker-hosted/"},
+			expectTLS:      TLSEnabled,
+			expectHostname: "nexus.example.org",
+			expectBasePath: "repository/docker-hosted",
+		},
+		{
+			name:           "http with port and v2",
+			host:           Host{Name: "local.alias", URL: "http://[::1]:5000/registry/v2/"},
+			expectTLS:      TLSDisabled,
+			expectHostname: "[::1]:5000",
+			expectBasePath: "registry",
+		},
+		{
+			name:           "explicit values take precedence",
+			host:           Host{Name: "art.alias", URL: "http://art.example.org/artifactory/api/docker/repo", TLS: TLSInsecure, Hostname: "art-internal.example.org"},
+			expectTLS:      TLSInsecure,
+			expectHostname: "art-internal.example.org",
+			expectBasePath: "artifactory/api/docker/repo",
+		},
+		{
+			name:      "missing scheme",
+			host:      Host{Name: "bad.alias", URL: "registry.example.org/path"},
+			expectErr: true,
+		},
+		{
+			name:      "query",
+			host:      Host{Name: "bad.alias", URL: "https://registry.example.org/path?x=y"},
+			expectErr: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := HostNewName(tc.host.Name)
+			err := h.Merge(tc.host, nil)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("merge did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to merge: %v", err)
+			}
+			if h.TLS != tc.expectTLS || h.Hostname != tc.expectHostname || h.BasePath != tc.expectBasePath {
+				t.Errorf("unexpected host, expected tls %d, hostname %s, base path %s, received tls %d, hostname %s, base path %s",
+					tc.expectTLS, tc.expectHostname, tc.expectBasePath, h.TLS, h.Hostname, h.BasePath)
+			}
+			if h.URL != tc.host.URL || h.IsZero() {
+				t.Errorf("url not saved: %s", h.URL)
+			}
+		})
+	}
+}
+
+func TestProxyParse(t *testing.T) {
+	t.Parallel()
+	tt := []struct {
+		name      string
+		proxy     string
+		expect    string
+		expectErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "direct",
+			proxy: ProxyDirect,
+		},
+		{
+			name:   "socks5",
+			proxy:  "socks5://proxy.example.org:1080",
+			expect: "socks5://proxy.example.org:1080",
+		},
+		{
+			name:      "missing scheme",
+			proxy:     "proxy.example.org:3128",
+			expectErr: true,
+		},
+		{
+			name:      "unsupported scheme",
+			proxy:     "ftp://proxy.example.org",
+			expectErr: true,
+		},
+		{
+			name:      "missing host",
+			proxy:     "http:///path",
+			expectErr: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := ProxyParse(tc.proxy)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("parse did not fail: %v", u)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if (u == nil && tc.expect != "") || (u != nil && u.String() != tc.expect) {
+				t.Errorf("unexpected url, expected %q, received %v", tc.expect, u)
+			}
+		})
+	}
+}
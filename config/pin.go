@@ -0,0 +1,112 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ref"
+)
+
+// Pins map image references with a tag to the expected digest.
+// Requests that resolve a pinned tag to a different digest fail with an error wrapping [errs.ErrPinMismatch].
+type Pins map[string]digest.Digest
+
+// PinsLoad parses a pin file.
+// Each line contains a reference with a tag and the expected digest separated by whitespace,
+// or a single reference with both a tag and digest (e.g. "alpine:3@sha256:...").
+// Blank lines and lines beginning with "#" are ignored.
+func PinsLoad(rdr io.Reader) (Pins, error) {
+	pins := Pins{}
+	scanner := bufio.NewScanner(rdr)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("pin on line %d has too many fields%.0w", line, errs.ErrParsingFailed)
+		}
+		r, err := ref.New(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pin on line %d: %w", line, err)
+		}
+		dStr := r.Digest
+		if len(fields) == 2 {
+			if r.Digest != "" {
+				return nil, fmt.Errorf("pin on line %d has a digest in the reference and a separate digest%.0w", line, errs.ErrParsingFailed)
+			}
+			dStr = fields[1]
+		}
+		if dStr == "" {
+			return nil, fmt.Errorf("pin on line %d is missing the digest%.0w", line, errs.ErrMissingDigest)
+		}
+		d, err := digest.Parse(dStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse digest of pin on line %d: %w", line, err)
+		}
+		key, ok := pinKey(r)
+		if !ok {
+			return nil, fmt.Errorf("pin on line %d is missing the tag%.0w", line, errs.ErrMissingTag)
+		}
+		if prev, ok := pins[key]; ok && prev != d {
+			return nil, fmt.Errorf("pin on line %d for %s conflicts with digest %s%.0w", line, key, prev.String(), errs.ErrParsingFailed)
+		}
+		pins[key] = d
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
+
+// PinsLoadFile parses a pin file, see [PinsLoad] for the format.
+// Unlike other config files, a missing pin file returns an error.
+func PinsLoadFile(fname string) (Pins, error) {
+	//#nosec G304 scoping file operations to a directory is not yet a feature of regclient.
+	rdr, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Close()
+	pins, err := PinsLoad(rdr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pin file %s: %w", fname, err)
+	}
+	return pins, nil
+}
+
+// Get returns the pinned digest of the reference.
+// References without a tag are never pinned.
+func (pins Pins) Get(r ref.Ref) (digest.Digest, bool) {
+	key, ok := pinKey(r)
+	if !ok {
+		return "", false
+	}
+	d, ok := pins[key]
+	return d, ok
+}
+
+// Check returns an error wrapping [errs.ErrPinMismatch] when the reference is pinned to a different digest.
+func (pins Pins) Check(r ref.Ref, d digest.Digest) error {
+	pin, ok := pins.Get(r)
+	if !ok || pin == d {
+		return nil
+	}
+	return fmt.Errorf("%s resolved to %s, pinned to %s%.0w", r.SetTag(r.Tag).CommonName(), d.String(), pin.String(), errs.ErrPinMismatch)
+}
+
+// pinKey returns the reference without the digest, and false if the reference does not have a tag.
+func pinKey(r ref.Ref) (string, bool) {
+	if r.Tag == "" {
+		return "", false
+	}
+	return r.SetTag(r.Tag).CommonName(), true
+}
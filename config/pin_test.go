@@ -0,0 +1,170 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ref"
+)
+
+func TestPins(t *testing.T) {
+	t.Parallel()
+	d1 := digest.FromString("image 1")
+	d2 := digest.FromString("image 2")
+	tt := []struct {
+		name      string
+		file      string
+		expectErr error
+		expect    Pins
+	}{
+		{
+			name:   "empty",
+			file:   "",
+			expect: Pins{},
+		},
+		{
+			name: "pins",
+			file: "# comment\n\n" +
+				"alpine:3 " + d1.String() + "\n" +
+				"  registry.example.org/repo:v1\t" + d2.String() + "  \n" +
+				"ocidir://testrepo:v1@" + d1.String() + "\n" +
+				"busybox " + d2.String() + "\n",
+			expect: Pins{
+				"docker.io/library/alpine:3":       d1,
+				"registry.example.org/repo:v1":     d2,
+				"ocidir://testrepo:v1":             d1,
+				"docker.io/library/busybox:latest": d2,
+			},
+		},
+		{
+			name: "duplicate",
+			file: "alpine:3 " + d1.String() + "\n" +
+				"docker.io/library/alpine:3@" + d1.String() + "\n",
+			expect: Pins{
+				"docker.io/library/alpine:3": d1,
+			},
+		},
+		{
+			name: "conflict",
+			file: "alpine:3 " + d1.String() + "\n" +
+				"docker.io/library/alpine:3 " + d2.String() + "\n",
+			expectErr: errs.ErrParsingFailed,
+		},
+		{
+			name:      "missing digest",
+			file:      "alpine:3\n",
+			expectErr: errs.ErrMissingDigest,
+		},
+		{
+			name:      "missing tag",
+			file:      "alpine@" + d1.String() + "\n",
+			expectErr: errs.ErrMissingTag,
+		},
+		{
+			name:      "two digests",
+			file:      "alpine:3@" + d1.String() + " " + d2.String() + "\n",
+			expectErr: errs.ErrParsingFailed,
+		},
+		{
+			name:      "extra fields",
+			file:      "alpine:3 " + d1.String() + " extra\n",
+			expectErr: errs.ErrParsingFailed,
+		},
+		{
+			name:      "invalid digest",
+			file:      "alpine:3 sha256:abc\n",
+			expectErr: digest.ErrDigestInvalidLength,
+		},
+		{
+			name:      "invalid ref",
+			file:      "Alpine:3 " + d1.String() + "\n",
+			expectErr: errs.ErrInvalidReference,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			pins, err := PinsLoad(strings.NewReader(tc.file))
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("expected error %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to load pins: %v", err)
+			}
+			if len(pins) != len(tc.expect) {
+				t.Errorf("unexpected pins, expected %v, received %v", tc.expect, pins)
+			}
+			for k, v := range tc.expect {
+				if pins[k] != v {
+					t.Errorf("unexpected pin for %s, expected %s, received %s", k, v, pins[k])
+				}
+			}
+		})
+	}
+
+	t.Run("check", func(t *testing.T) {
+		pins := Pins{"docker.io/library/alpine:3": d1}
+		tt := []struct {
+			name      string
+			ref       string
+			dig       digest.Digest
+			expectErr error
+		}{
+			{
+				name: "match",
+				ref:  "alpine:3",
+				dig:  d1,
+			},
+			{
+				name: "match with digest",
+				ref:  "alpine:3@" + d2.String(),
+				dig:  d1,
+			},
+			{
+				name:      "mismatch",
+				ref:       "alpine:3",
+				dig:       d2,
+				expectErr: errs.ErrPinMismatch,
+			},
+			{
+				name: "other tag",
+				ref:  "alpine:edge",
+				dig:  d2,
+			},
+			{
+				name: "digest only",
+				ref:  "alpine@" + d1.String(),
+				dig:  d2,
+			},
+		}
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				r, err := ref.New(tc.ref)
+				if err != nil {
+					t.Fatalf("failed to parse ref: %v", err)
+				}
+				err = pins.Check(r, tc.dig)
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("expected error %v, received %v", tc.expectErr, err)
+				}
+				if tc.expectErr != nil && !errors.Is(err, errs.ErrDigestMismatch) {
+					t.Errorf("pin mismatch does not wrap the digest mismatch: %v", err)
+				}
+			})
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := PinsLoadFile("testdata/missing.pins")
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected not exist error, received %v", err)
+		}
+	})
+}
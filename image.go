@@ -10,8 +10,11 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"math/rand/v2"
 	"net/url"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
 	"sync"
@@ -23,6 +26,7 @@ import (
 
 	digest "github.com/opencontainers/go-digest"
 
+	"github.com/regclient/regclient/internal/urlhost"
 	"github.com/regclient/regclient/pkg/archive"
 	"github.com/regclient/regclient/scheme"
 	"github.com/regclient/regclient/types"
@@ -35,6 +39,8 @@ import (
 	v1 "github.com/regclient/regclient/types/oci/v1"
 	"github.com/regclient/regclient/types/platform"
 	"github.com/regclient/regclient/types/ref"
+	"github.com/regclient/regclient/types/referrer"
+	"github.com/regclient/regclient/types/trace"
 	"github.com/regclient/regclient/types/warning"
 )
 
@@ -86,28 +92,79 @@ type tarWriteData struct {
 }
 
 type imageOpt struct {
-	callback        func(kind types.CallbackKind, instance string, state types.CallbackState, cur, total int64)
-	checkBaseDigest string
-	checkBaseRef    string
-	checkSkipConfig bool
-	child           bool
-	exportCompress  bool
-	exportRef       ref.Ref
-	fastCheck       bool
-	forceRecursive  bool
-	importName      string
-	includeExternal bool
-	digestTags      bool
-	platform        string
-	platforms       []string
-	referrerConfs   []scheme.ReferrerConfig
-	referrerSrc     ref.Ref
-	referrerTgt     ref.Ref
-	tagList         []string
-	mu              sync.Mutex
-	seen            map[string]*imageSeen
-	finalFn         []func(context.Context) error
-	blobReaderHook  func(*blob.BReader) (*blob.BReader, error)
+	authCheck        bool
+	callback         func(kind types.CallbackKind, instance string, state types.CallbackState, cur, total int64)
+	checkBaseDigest  string
+	checkBaseRef     string
+	checkSkipConfig  bool
+	child            bool
+	copyAnnot        map[string]string
+	copyReport       *ImageCopyReport
+	dryRun           bool
+	exportCompress   bool
+	exportRef        ref.Ref
+	fastCheck        bool
+	force            bool
+	forceRecursive   bool
+	importName       string
+	externalPolicy   ExternalPolicy
+	externalHosts    []string
+	digestTags       bool
+	digestTagPattern []string
+	digestTagRe      []*regexp.Regexp
+	platform         string
+	platforms        []string
+	promoteAnnot     map[string]string
+	promoteDigest    string
+	promoteForce     bool
+	promoteRecord    bool
+	provenance       bool
+	referrerConfs    []scheme.ReferrerConfig
+	referrerParallel int
+	referrerSem      chan struct{}
+	referrerSrc      ref.Ref
+	referrerTgt      ref.Ref
+	seekableVerify   bool
+	tagList          []string
+	verify           bool
+	verifySample     int
+	verifyParallel   int
+	verifyReport     *ImageVerifyReport
+	mu               sync.Mutex
+	seen             *imageSeenList
+	finalFn          []func(context.Context) error
+	blobReaderHook   func(*blob.BReader) (*blob.BReader, error)
+}
+
+// ExternalPolicy defines how external layers are handled.
+// External layers are foreign or non-distributable layers with URLs, commonly found in Windows images.
+type ExternalPolicy string
+
+const (
+	// ExternalSkip does not copy external layers, the manifest continues to reference the external URLs.
+	ExternalSkip ExternalPolicy = "skip"
+	// ExternalCopy copies external layers while leaving the URLs in the manifest.
+	ExternalCopy ExternalPolicy = "copy"
+	// ExternalEmbed copies external layers, removing the URLs and converting the media type to a distributable layer.
+	// This changes the digest of the manifest.
+	ExternalEmbed ExternalPolicy = "embed"
+)
+
+// ExternalPolicyParse converts a string to an [ExternalPolicy].
+func ExternalPolicyParse(s string) (ExternalPolicy, error) {
+	switch p := ExternalPolicy(strings.ToLower(s)); p {
+	case ExternalSkip, ExternalCopy, ExternalEmbed:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown external layer policy %q, expected skip, copy, or embed%.0w", s, errs.ErrParsingFailed)
+}
+
+// externalInclude returns true when an external layer should be copied.
+func (opt *imageOpt) externalInclude(d descriptor.Descriptor) bool {
+	if opt.externalPolicy != ExternalCopy {
+		return false
+	}
+	return len(opt.externalHosts) == 0 || urlhost.Match(d.URLs, opt.externalHosts)
 }
 
 type imageSeen struct {
@@ -115,9 +172,28 @@ type imageSeen struct {
 	err  error
 }
 
+// imageSeenList tracks manifests and blobs being copied, it may be shared by multiple copies to the same target.
+type imageSeenList struct {
+	mu      sync.Mutex
+	entries map[string]*imageSeen
+}
+
+func newImageSeenList() *imageSeenList {
+	return &imageSeenList{entries: map[string]*imageSeen{}}
+}
+
 // ImageOpts define options for the Image* commands.
 type ImageOpts func(*imageOpt)
 
+// ImageWithAuthCheck verifies pull access to the source and push access to the target before [RegClient.ImageCopy] starts.
+// This also requests the combined scopes in a single token for each registry, avoiding additional auth requests during the copy.
+// See [RegClient.AuthCheck].
+func ImageWithAuthCheck() ImageOpts {
+	return func(opts *imageOpt) {
+		opts.authCheck = true
+	}
+}
+
 // ImageWithBlobReaderHook calls the given function on every blob copy in [RegClient.ImageCopy].
 // The hook receives a [blob.BReader] from getting the blob from the source.
 // The returned [blob.BReader] will be used for pushing the blob to the target.
@@ -163,6 +239,40 @@ func ImageWithChild() ImageOpts {
 	}
 }
 
+const (
+	// CopyAnnotationSource is the annotation key for the source reference of a copy with [ImageWithCopyAnnotations].
+	CopyAnnotationSource = "org.regclient.copy.source"
+	// CopyAnnotationDigest is the annotation key for the digest of the source manifest of a copy.
+	CopyAnnotationDigest = "org.regclient.copy.digest"
+	// CopyAnnotationUpdated is the annotation key for the time of a copy (date-time string as defined by RFC 3339).
+	CopyAnnotationUpdated = "org.regclient.copy.updated"
+)
+
+// ImageWithCopyAnnotations annotates the target tag of [RegClient.ImageCopy] with when and from where the image was copied.
+// The source is copied by digest, and the tag is pushed with a copy of the top level manifest that includes the annotations, changing the digest of the tag.
+// [CopyAnnotationSource], [CopyAnnotationDigest], and [CopyAnnotationUpdated] are always set,
+// and "org.opencontainers.image.created" is set to the time of the copy when the source does not include it.
+// The provided annotations are added last, an empty value removes the annotation.
+// The copy is skipped when the target was annotated from the same source digest, unless a recursive copy is needed.
+// Referrers and digest tags remain associated with the source digest.
+func ImageWithCopyAnnotations(annotations map[string]string) ImageOpts {
+	return func(opts *imageOpt) {
+		opts.copyAnnot = map[string]string{}
+		maps.Copy(opts.copyAnnot, annotations)
+	}
+}
+
+// ImageWithDryRun reports the manifests and blobs [RegClient.ImageCopy] would copy without pushing to the target.
+// The target is only checked with head requests, and the plan is returned with [ImageWithCopyReport].
+// Blobs missing from the target are planned as a mount when the source is on the same registry,
+// the copy falls back to pulling the blob when the mount fails.
+// [ImageWithVerify] is ignored.
+func ImageWithDryRun() ImageOpts {
+	return func(opts *imageOpt) {
+		opts.dryRun = true
+	}
+}
+
 // ImageWithExportCompress adds gzip compression to tar export output in ImageExport.
 func ImageWithExportCompress() ImageOpts {
 	return func(opts *imageOpt) {
@@ -184,6 +294,13 @@ func ImageWithFastCheck() ImageOpts {
 	}
 }
 
+// ImageWithForce allows ImageCopy to replace a target tag matching the immutable tag patterns in the host config.
+func ImageWithForce() ImageOpts {
+	return func(opts *imageOpt) {
+		opts.force = true
+	}
+}
+
 // ImageWithForceRecursive attempts to copy every manifest and blob even if parent manifests already exist in ImageCopy.
 func ImageWithForceRecursive() ImageOpts {
 	return func(opts *imageOpt) {
@@ -198,10 +315,21 @@ func ImageWithImportName(name string) ImageOpts {
 	}
 }
 
-// ImageWithIncludeExternal attempts to copy every manifest and blob even if parent manifests already exist in ImageCopy.
+// ImageWithIncludeExternal includes external layers in ImageCopy and ImageVerify.
+// External layers are foreign or non-distributable layers with URLs, commonly found in Windows images.
+// By default these layers are skipped, and the copied manifest continues to reference the external URLs.
+// This is the same as [ImageWithExternalPolicy] with [ExternalCopy] and no hosts.
 func ImageWithIncludeExternal() ImageOpts {
+	return ImageWithExternalPolicy(ExternalCopy)
+}
+
+// ImageWithExternalPolicy sets the handling of external layers in ImageCopy and ImageVerify.
+// When hosts are provided, only external layers with a URL on one of the hosts are copied, other external layers are skipped.
+// The [ExternalEmbed] policy modifies the image and is not supported by ImageCopy, see [github.com/regclient/regclient/mod.WithExternalLayers].
+func ImageWithExternalPolicy(policy ExternalPolicy, hosts ...string) ImageOpts {
 	return func(opts *imageOpt) {
-		opts.includeExternal = true
+		opts.externalPolicy = policy
+		opts.externalHosts = hosts
 	}
 }
 
@@ -213,6 +341,30 @@ func ImageWithDigestTags() ImageOpts {
 	}
 }
 
+// ImageWithDigestTagPatterns limits the digest tags copied by [ImageWithDigestTags] to the suffixes matching a regular expression.
+// The suffix is the part of the tag after the "<algorithm>-<hex>" prefix, e.g. `^\.(sig|att|sbom)$`.
+// Every digest tag is copied when no patterns are set.
+// This option also enables [ImageWithDigestTags].
+func ImageWithDigestTagPatterns(patterns ...string) ImageOpts {
+	return func(opts *imageOpt) {
+		opts.digestTags = true
+		opts.digestTagPattern = append(opts.digestTagPattern, patterns...)
+	}
+}
+
+// digestTagInclude returns true when a digest tag suffix matches the patterns.
+func (opt *imageOpt) digestTagInclude(suffix string) bool {
+	if len(opt.digestTagRe) == 0 {
+		return true
+	}
+	for _, re := range opt.digestTagRe {
+		if re.MatchString(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
 // ImageWithPlatform requests specific platforms from a manifest list in ImageCheckBase.
 func ImageWithPlatform(p string) ImageOpts {
 	return func(opts *imageOpt) {
@@ -220,7 +372,7 @@ func ImageWithPlatform(p string) ImageOpts {
 	}
 }
 
-// ImageWithPlatforms only copies specific platforms from a manifest list in ImageCopy.
+// ImageWithPlatforms only copies specific platforms from a manifest list in ImageCopy and ImageVerify.
 // This will result in a failure on many registries that validate manifests.
 // Use the empty string to indicate images without a platform definition should be copied.
 func ImageWithPlatforms(p []string) ImageOpts {
@@ -243,6 +395,14 @@ func ImageWithReferrers(rOpts ...scheme.ReferrerOpts) ImageOpts {
 	}
 }
 
+// ImageWithReferrerParallel sets the number of referrers copied concurrently by [ImageWithReferrers], defaults to 4.
+// Nested referrers are copied in the calling goroutine when every worker is busy.
+func ImageWithReferrerParallel(n int) ImageOpts {
+	return func(opts *imageOpt) {
+		opts.referrerParallel = n
+	}
+}
+
 // ImageWithReferrerSrc specifies an alternate repository to pull referrers from.
 func ImageWithReferrerSrc(src ref.Ref) ImageOpts {
 	return func(opts *imageOpt) {
@@ -257,15 +417,166 @@ func ImageWithReferrerTgt(tgt ref.Ref) ImageOpts {
 	}
 }
 
+// ImageWithSeekableVerify verifies the eStargz TOC and zstd:chunked checksums of layers in [RegClient.ImageCopy].
+// Only layers pulled from the source are verified, layers that already exist on the target or are mounted are skipped.
+func ImageWithSeekableVerify() ImageOpts {
+	return func(opts *imageOpt) {
+		opts.seekableVerify = true
+	}
+}
+
+// ImageWithCopyReport populates the report with a summary of the work performed by [RegClient.ImageCopy].
+// Copies within the same registry mount blobs from the source repository rather than pulling them.
+func ImageWithCopyReport(report *ImageCopyReport) ImageOpts {
+	return func(opts *imageOpt) {
+		opts.copyReport = report
+	}
+}
+
+// ImageCopyReport summarizes the manifests and blobs transferred by [RegClient.ImageCopy].
+type ImageCopyReport struct {
+	SameRegistry     bool            `json:"sameRegistry"`     // SameRegistry is true when blobs can be mounted from the source repository.
+	SameRepository   bool            `json:"sameRepository"`   // SameRepository is true when the source and target share blobs, only manifests are pushed.
+	ManifestsPushed  int             `json:"manifestsPushed"`  // ManifestsPushed is the number of manifests pushed to the target.
+	ManifestsSkipped int             `json:"manifestsSkipped"` // ManifestsSkipped is the number of manifests that already existed in the target.
+	ReferrersCopied  int             `json:"referrersCopied"`  // ReferrersCopied is the number of referrers copied, including referrers that already existed in the target.
+	BlobsMounted     int             `json:"blobsMounted"`     // BlobsMounted is the number of blobs mounted from the source repository.
+	BlobsPulled      int             `json:"blobsPulled"`      // BlobsPulled is the number of blobs pulled from the source and pushed to the target.
+	BlobsSkipped     int             `json:"blobsSkipped"`     // BlobsSkipped is the number of blobs that already existed in the target.
+	BytesPulled      int64           `json:"bytesPulled"`      // BytesPulled is the size of the blobs pulled from the source and pushed to the target.
+	BytesMounted     int64           `json:"bytesMounted"`     // BytesMounted is the size of the blobs mounted from the source repository.
+	Duration         time.Duration   `json:"duration"`         // Duration is the time spent running the copy.
+	DryRun           bool            `json:"dryRun"`           // DryRun is true when nothing was pushed, see [ImageWithDryRun].
+	Plan             []ImageCopyPlan `json:"plan,omitempty"`   // Plan lists the content that would be copied in a dry run.
+}
+
+// ImageCopyPlan is a manifest or blob that would be copied by [RegClient.ImageCopy] with [ImageWithDryRun].
+type ImageCopyPlan struct {
+	Action     string                `json:"action"`     // Action is one of "push" for a manifest, "mount" or "pull" for a blob.
+	Target     string                `json:"target"`     // Target is the reference that would be pushed.
+	Descriptor descriptor.Descriptor `json:"descriptor"` // Descriptor of the manifest or blob.
+}
+
+// reportAdd updates the copy report when one was requested.
+func (opt *imageOpt) reportAdd(fn func(r *ImageCopyReport)) {
+	if opt.copyReport == nil {
+		return
+	}
+	opt.mu.Lock()
+	defer opt.mu.Unlock()
+	fn(opt.copyReport)
+}
+
+// ManifestOnly returns true when the copy did not pull any blobs from the source.
+func (r ImageCopyReport) ManifestOnly() bool {
+	return r.BlobsPulled == 0
+}
+
+// ImageWithVerify checks the target after [RegClient.ImageCopy] completes.
+// Each manifest is pulled from the target and its digest is verified.
+// The content of sample blobs, selected at random, is pulled to verify the digest,
+// and all other blobs are checked with a head request.
+// A negative sample pulls every blob.
+// Referrers and digest tags are not verified.
+// The report is populated with the result of each check when it is not nil.
+func ImageWithVerify(sample int, report *ImageVerifyReport) ImageOpts {
+	return func(opts *imageOpt) {
+		opts.verify = true
+		opts.verifySample = sample
+		opts.verifyReport = report
+	}
+}
+
+// ImageWithVerifyParallel sets the number of blobs checked concurrently by [ImageWithVerify] and [RegClient.ImageVerify], defaults to 4.
+func ImageWithVerifyParallel(n int) ImageOpts {
+	return func(opts *imageOpt) {
+		opts.verifyParallel = n
+	}
+}
+
+// ImageVerifyReport contains the results of verifying the target of [RegClient.ImageCopy] or [RegClient.ImageVerify].
+type ImageVerifyReport struct {
+	Entries []ImageVerifyEntry
+}
+
+// ImageVerifyEntry is the result of verifying a single manifest or blob.
+type ImageVerifyEntry struct {
+	Ref        ref.Ref               // Ref is the target manifest, or the repository for a blob.
+	Descriptor descriptor.Descriptor // Descriptor is the expected content.
+	Method     ImageVerifyMethod     // Method is the check that was run.
+	Err        error                 // Err is set when the check failed.
+}
+
+// ImageVerifyMethod describes how an entry was verified.
+type ImageVerifyMethod string
+
+const (
+	// ImageVerifyManifest pulls a manifest and verifies the digest.
+	ImageVerifyManifest ImageVerifyMethod = "manifest"
+	// ImageVerifyHead checks a blob exists with the expected size.
+	ImageVerifyHead ImageVerifyMethod = "head"
+	// ImageVerifyDigest pulls a blob and verifies the digest.
+	ImageVerifyDigest ImageVerifyMethod = "digest"
+)
+
+// Err returns the joined errors of all failed checks, or nil if every check succeeded.
+func (r ImageVerifyReport) Err() error {
+	errList := []error{}
+	for _, e := range r.Entries {
+		if e.Err != nil {
+			errList = append(errList, fmt.Errorf("%s %s: %w", e.Method, e.Descriptor.Digest.String(), e.Err))
+		}
+	}
+	return errors.Join(errList...)
+}
+
+// ImageBaseReport contains the result of comparing an image to its base image.
+type ImageBaseReport struct {
+	Ref               string             `json:"ref"`                         // image being checked
+	Platform          string             `json:"platform,omitempty"`          // platform of the image, when selected from an index
+	BaseRef           string             `json:"baseRef"`                     // base image reference
+	BaseDigest        string             `json:"baseDigest,omitempty"`        // expected digest of the base image
+	BaseDigestCurrent string             `json:"baseDigestCurrent,omitempty"` // current digest of the base image
+	Changed           bool               `json:"changed"`                     // true when the base image has changed
+	Reason            string             `json:"reason,omitempty"`            // description of the first change found
+	Layers            []ImageBaseLayer   `json:"layers,omitempty"`            // layers that diverge from the base image
+	Platforms         []*ImageBaseReport `json:"platforms,omitempty"`         // reports for each platform of an index
+}
+
+// ImageBaseLayer describes a layer that diverges between an image and its base image.
+type ImageBaseLayer struct {
+	Index    int    `json:"index"`
+	Expected string `json:"expected"` // digest of the layer in the image, empty when the image has fewer layers
+	Current  string `json:"current"`  // digest of the layer in the base image
+}
+
 // ImageCheckBase returns nil if the base image is unchanged.
 // A base image mismatch returns an error that wraps errs.ErrMismatch.
 func (rc *RegClient) ImageCheckBase(ctx context.Context, r ref.Ref, opts ...ImageOpts) error {
+	report, err := rc.ImageCheckBaseReport(ctx, r, opts...)
+	if err != nil {
+		return err
+	}
+	if report.Changed {
+		return fmt.Errorf("%s%.0w", report.Reason, errs.ErrMismatch)
+	}
+	return nil
+}
+
+// ImageCheckBaseReport compares an image to its base image and returns a report of any changes.
+// The base image is found from annotations unless [ImageWithCheckBaseRef] is provided.
+// A changed base image is indicated in the report and is not returned as an error.
+func (rc *RegClient) ImageCheckBaseReport(ctx context.Context, r ref.Ref, opts ...ImageOpts) (*ImageBaseReport, error) {
 	var opt imageOpt
 	for _, optFn := range opts {
 		optFn(&opt)
 	}
 	var m manifest.Manifest
 	var err error
+	report := &ImageBaseReport{
+		Ref:      r.CommonName(),
+		Platform: opt.platform,
+	}
 
 	// dedup warnings
 	if w := warning.FromContext(ctx); w == nil {
@@ -275,20 +586,20 @@ func (rc *RegClient) ImageCheckBase(ctx context.Context, r ref.Ref, opts ...Imag
 	if opt.checkBaseRef == "" {
 		m, err = rc.ManifestGet(ctx, r)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		ma, ok := m.(manifest.Annotator)
 		if !ok {
-			return fmt.Errorf("image does not support annotations, base image must be provided%.0w", errs.ErrMissingAnnotation)
+			return nil, fmt.Errorf("image does not support annotations, base image must be provided%.0w", errs.ErrMissingAnnotation)
 		}
 		annot, err := ma.GetAnnotations()
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if baseName, ok := annot[types.AnnotationBaseImageName]; ok {
 			opt.checkBaseRef = baseName
 		} else {
-			return fmt.Errorf("image does not have a base annotation, base image must be provided%.0w", errs.ErrMissingAnnotation)
+			return nil, fmt.Errorf("image does not have a base annotation, base image must be provided%.0w", errs.ErrMissingAnnotation)
 		}
 		if baseDig, ok := annot[types.AnnotationBaseImageDigest]; ok {
 			opt.checkBaseDigest = baseDig
@@ -296,32 +607,37 @@ func (rc *RegClient) ImageCheckBase(ctx context.Context, r ref.Ref, opts ...Imag
 	}
 	baseR, err := ref.New(opt.checkBaseRef)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer rc.Close(ctx, baseR)
+	report.BaseRef = baseR.CommonName()
+	report.BaseDigest = opt.checkBaseDigest
 
 	// if the digest is available, check if that matches the base name
 	if opt.checkBaseDigest != "" {
 		baseMH, err := rc.ManifestHead(ctx, baseR, WithManifestRequireDigest())
 		if err != nil {
-			return err
+			return nil, err
 		}
 		expectDig, err := digest.Parse(opt.checkBaseDigest)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		report.BaseDigestCurrent = baseMH.GetDescriptor().Digest.String()
 		if baseMH.GetDescriptor().Digest == expectDig {
 			rc.slog.Debug("base image digest matches",
 				slog.String("name", baseR.CommonName()),
 				slog.String("digest", baseMH.GetDescriptor().Digest.String()))
-			return nil
+			return report, nil
 		} else {
 			rc.slog.Debug("base image digest changed",
 				slog.String("name", baseR.CommonName()),
 				slog.String("digest", baseMH.GetDescriptor().Digest.String()),
 				slog.String("expected", expectDig.String()))
-			return fmt.Errorf("base digest changed, %s, expected %s, received %s%.0w",
-				baseR.CommonName(), expectDig.String(), baseMH.GetDescriptor().Digest.String(), errs.ErrMismatch)
+			report.Changed = true
+			report.Reason = fmt.Sprintf("base digest changed, %s, expected %s, received %s",
+				baseR.CommonName(), expectDig.String(), baseMH.GetDescriptor().Digest.String())
+			return report, nil
 		}
 	}
 
@@ -329,121 +645,148 @@ func (rc *RegClient) ImageCheckBase(ctx context.Context, r ref.Ref, opts ...Imag
 	if m == nil {
 		m, err = rc.ManifestGet(ctx, r)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 	if m.IsList() && opt.platform != "" {
 		p, err := platform.Parse(opt.platform)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		d, err := manifest.GetPlatformDesc(m, &p)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		rp := r.AddDigest(d.Digest.String())
 		m, err = rc.ManifestGet(ctx, rp)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 	if m.IsList() {
 		// loop through each platform
 		ml, ok := m.(manifest.Indexer)
 		if !ok {
-			return fmt.Errorf("manifest list is not an Indexer")
+			return nil, fmt.Errorf("manifest list is not an Indexer")
 		}
 		dl, err := ml.GetManifestList()
 		if err != nil {
-			return err
+			return nil, err
 		}
 		for _, d := range dl {
 			rp := r.AddDigest(d.Digest.String())
 			optP := append(opts, ImageWithPlatform(d.Platform.String()))
-			err = rc.ImageCheckBase(ctx, rp, optP...)
+			pReport, err := rc.ImageCheckBaseReport(ctx, rp, optP...)
 			if err != nil {
-				return fmt.Errorf("platform %s mismatch: %w", d.Platform.String(), err)
+				return nil, fmt.Errorf("failed to check platform %s: %w", d.Platform.String(), err)
+			}
+			report.Platforms = append(report.Platforms, pReport)
+			if pReport.Changed && !report.Changed {
+				report.Changed = true
+				report.Reason = fmt.Sprintf("platform %s mismatch: %s", d.Platform.String(), pReport.Reason)
 			}
 		}
-		return nil
+		return report, nil
 	}
 	img, ok := m.(manifest.Imager)
 	if !ok {
-		return fmt.Errorf("manifest must be an image")
+		return nil, fmt.Errorf("manifest must be an image")
 	}
 	layers, err := img.GetLayers()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	baseM, err := rc.ManifestGet(ctx, baseR)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if baseM.IsList() && opt.platform != "" {
 		p, err := platform.Parse(opt.platform)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		d, err := manifest.GetPlatformDesc(baseM, &p)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		baseM, err = rc.ManifestGet(ctx, baseR, WithManifestDesc(*d))
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 	baseImg, ok := baseM.(manifest.Imager)
 	if !ok {
-		return fmt.Errorf("base image manifest must be an image")
+		return nil, fmt.Errorf("base image manifest must be an image")
 	}
 	baseLayers, err := baseImg.GetLayers()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if len(baseLayers) <= 0 {
-		return fmt.Errorf("base image has no layers")
+		return nil, fmt.Errorf("base image has no layers")
 	}
+	// report every layer that diverges, including base layers missing from the image
 	for i := range baseLayers {
 		if i >= len(layers) {
-			return fmt.Errorf("image has fewer layers than base image")
+			rc.slog.Debug("image layer missing",
+				slog.Int("layer", i),
+				slog.String("digest", baseLayers[i].Digest.String()))
+			report.Layers = append(report.Layers, ImageBaseLayer{
+				Index:   i,
+				Current: baseLayers[i].Digest.String(),
+			})
+			continue
 		}
 		if !layers[i].Same(baseLayers[i]) {
 			rc.slog.Debug("image layer changed",
 				slog.Int("layer", i),
 				slog.String("expected", layers[i].Digest.String()),
 				slog.String("digest", baseLayers[i].Digest.String()))
-			return fmt.Errorf("base layer changed, %s[%d], expected %s, received %s%.0w",
-				baseR.CommonName(), i, layers[i].Digest.String(), baseLayers[i].Digest.String(), errs.ErrMismatch)
+			report.Layers = append(report.Layers, ImageBaseLayer{
+				Index:    i,
+				Expected: layers[i].Digest.String(),
+				Current:  baseLayers[i].Digest.String(),
+			})
+		}
+	}
+	if len(report.Layers) > 0 {
+		report.Changed = true
+		if report.Layers[0].Expected == "" {
+			report.Reason = fmt.Sprintf("base layer added, %s[%d], image has %d layers, received %s",
+				baseR.CommonName(), report.Layers[0].Index, len(layers), report.Layers[0].Current)
+		} else {
+			report.Reason = fmt.Sprintf("base layer changed, %s[%d], expected %s, received %s",
+				baseR.CommonName(), report.Layers[0].Index, report.Layers[0].Expected, report.Layers[0].Current)
 		}
+		return report, nil
 	}
 
 	if opt.checkSkipConfig {
-		return nil
+		return report, nil
 	}
 
 	// if the layers match, compare the config history
 	confDesc, err := img.GetConfig()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	conf, err := rc.BlobGetOCIConfig(ctx, r, confDesc)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	confOCI := conf.GetConfig()
 	baseConfDesc, err := baseImg.GetConfig()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	baseConf, err := rc.BlobGetOCIConfig(ctx, baseR, baseConfDesc)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	baseConfOCI := baseConf.GetConfig()
 	for i := range baseConfOCI.History {
 		if i >= len(confOCI.History) {
-			return fmt.Errorf("image has fewer history entries than base image")
+			return nil, fmt.Errorf("image has fewer history entries than base image")
 		}
 		if baseConfOCI.History[i].Author != confOCI.History[i].Author ||
 			baseConfOCI.History[i].Comment != confOCI.History[i].Comment ||
@@ -454,14 +797,16 @@ func (rc *RegClient) ImageCheckBase(ctx context.Context, r ref.Ref, opts ...Imag
 				slog.Int("index", i),
 				slog.Any("expected", confOCI.History[i]),
 				slog.Any("history", baseConfOCI.History[i]))
-			return fmt.Errorf("base history changed, %s[%d], expected %v, received %v%.0w",
-				baseR.CommonName(), i, confOCI.History[i], baseConfOCI.History[i], errs.ErrMismatch)
+			report.Changed = true
+			report.Reason = fmt.Sprintf("base history changed, %s[%d], expected %v, received %v",
+				baseR.CommonName(), i, confOCI.History[i], baseConfOCI.History[i])
+			return report, nil
 		}
 	}
 
 	rc.slog.Debug("base image layers and history matches",
 		slog.String("base", baseR.CommonName()))
-	return nil
+	return report, nil
 }
 
 // ImageConfig returns the OCI config of a given image.
@@ -477,9 +822,25 @@ func (rc *RegClient) ImageConfig(ctx context.Context, r ref.Ref, opts ...ImageOp
 	if w := warning.FromContext(ctx); w == nil {
 		ctx = warning.NewContext(ctx, &warning.Warning{Hook: warning.DefaultHook()})
 	}
-	p, err := platform.Parse(opt.platform)
+	mi, err := rc.imageManifestPlatform(ctx, r, opt.platform)
+	if err != nil {
+		return nil, err
+	}
+	d, err := mi.GetConfig()
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse platform %s: %w", opt.platform, err)
+		return nil, fmt.Errorf("failed to get image config: %w", err)
+	}
+	if d.MediaType != mediatype.OCI1ImageConfig && d.MediaType != mediatype.Docker2ImageConfig {
+		return nil, fmt.Errorf("unsupported config media type %s: %w", d.MediaType, errs.ErrUnsupportedMediaType)
+	}
+	return rc.BlobGetOCIConfig(ctx, r, d)
+}
+
+// imageManifestPlatform returns the image manifest for a platform, resolving any Index or Manifest List.
+func (rc *RegClient) imageManifestPlatform(ctx context.Context, r ref.Ref, pStr string) (manifest.Imager, error) {
+	p, err := platform.Parse(pStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse platform %s: %w", pStr, err)
 	}
 	m, err := rc.ManifestGet(ctx, r, WithManifestPlatform(p))
 	if err != nil {
@@ -507,14 +868,7 @@ func (rc *RegClient) ImageConfig(ctx context.Context, r ref.Ref, opts ...ImageOp
 	if !ok {
 		return nil, fmt.Errorf("unsupported manifest type: %s", m.GetDescriptor().MediaType)
 	}
-	d, err := mi.GetConfig()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get image config: %w", err)
-	}
-	if d.MediaType != mediatype.OCI1ImageConfig && d.MediaType != mediatype.Docker2ImageConfig {
-		return nil, fmt.Errorf("unsupported config media type %s: %w", d.MediaType, errs.ErrUnsupportedMediaType)
-	}
-	return rc.BlobGetOCIConfig(ctx, r, d)
+	return mi, nil
 }
 
 // ImageCopy copies an image.
@@ -522,14 +876,47 @@ func (rc *RegClient) ImageConfig(ctx context.Context, r ref.Ref, opts ...ImageOp
 // On the same registry, it will attempt to use cross-repository blob mounts to avoid pulling blobs.
 // Blobs are only pulled when they don't exist on the target and a blob mount fails.
 // Referrers are optionally copied recursively.
-func (rc *RegClient) ImageCopy(ctx context.Context, refSrc ref.Ref, refTgt ref.Ref, opts ...ImageOpts) error {
+func (rc *RegClient) ImageCopy(ctx context.Context, refSrc ref.Ref, refTgt ref.Ref, opts ...ImageOpts) (err error) {
+	ctx, span := rc.traceStart(ctx, "ImageCopy",
+		slog.String("source", refSrc.CommonName()),
+		slog.String("target", refTgt.CommonName()))
+	defer func() { trace.End(span, err) }()
 	opt := imageOpt{
-		seen:    map[string]*imageSeen{},
+		seen:    newImageSeenList(),
 		finalFn: []func(context.Context) error{},
 	}
 	for _, optFn := range opts {
 		optFn(&opt)
 	}
+	if opt.authCheck {
+		if err := rc.AuthCheck(ctx, refSrc, "pull"); err != nil {
+			return fmt.Errorf("failed to verify access to the source: %w", err)
+		}
+		if err := rc.AuthCheck(ctx, refTgt, "pull", "push"); err != nil {
+			return fmt.Errorf("failed to verify access to the target: %w", err)
+		}
+	}
+	return rc.imageCopy(ctx, refSrc, refTgt, &opt)
+}
+
+// ImageCopyWithReport copies an image and returns a report of the manifests, blobs, and referrers transferred.
+// This is the same as [RegClient.ImageCopy] with [ImageWithCopyReport].
+// The report is returned with the progress made before an error.
+func (rc *RegClient) ImageCopyWithReport(ctx context.Context, refSrc ref.Ref, refTgt ref.Ref, opts ...ImageOpts) (*ImageCopyReport, error) {
+	report := &ImageCopyReport{}
+	err := rc.ImageCopy(ctx, refSrc, refTgt, append(slices.Clone(opts), ImageWithCopyReport(report))...)
+	return report, err
+}
+
+// imageCopy runs a copy of an image with the options already applied.
+func (rc *RegClient) imageCopy(ctx context.Context, refSrc ref.Ref, refTgt ref.Ref, opt *imageOpt) error {
+	switch opt.externalPolicy {
+	case "", ExternalSkip, ExternalCopy:
+	case ExternalEmbed:
+		return fmt.Errorf("external layer policy %s modifies the image and is not supported by image copy%.0w", opt.externalPolicy, errs.ErrUnsupported)
+	default:
+		return fmt.Errorf("unknown external layer policy %q%.0w", opt.externalPolicy, errs.ErrUnsupported)
+	}
 	// dedup warnings
 	if w := warning.FromContext(ctx); w == nil {
 		ctx = warning.NewContext(ctx, &warning.Warning{Hook: warning.DefaultHook()})
@@ -539,25 +926,166 @@ func (rc *RegClient) ImageCopy(ctx context.Context, refSrc ref.Ref, refTgt ref.R
 	if err != nil {
 		return err
 	}
-	if tgtGCLocker, isGCLocker := schemeTgtAPI.(scheme.GCLocker); isGCLocker {
+	if tgtGCLocker, isGCLocker := schemeAs[scheme.GCLocker](schemeTgtAPI); isGCLocker {
 		tgtGCLocker.GCLock(refTgt)
 		defer tgtGCLocker.GCUnlock(refTgt)
 	}
+	if opt.copyReport != nil {
+		start := time.Now()
+		*opt.copyReport = ImageCopyReport{
+			SameRegistry:   ref.EqualRegistry(refSrc, refTgt),
+			SameRepository: ref.EqualRepository(refSrc, refTgt),
+			DryRun:         opt.dryRun,
+		}
+		defer opt.reportAdd(func(r *ImageCopyReport) { r.Duration = time.Since(start) })
+	}
+	if len(opt.digestTagPattern) > 0 && opt.digestTagRe == nil {
+		opt.digestTagRe = make([]*regexp.Regexp, 0, len(opt.digestTagPattern))
+		for _, pattern := range opt.digestTagPattern {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("failed to parse digest tag pattern %q: %w%.0w", pattern, err, errs.ErrParsingFailed)
+			}
+			opt.digestTagRe = append(opt.digestTagRe, re)
+		}
+	}
+	if opt.referrerSem == nil {
+		parallel := opt.referrerParallel
+		if parallel <= 0 {
+			parallel = 4
+		}
+		opt.referrerSem = make(chan struct{}, parallel)
+	}
 	// run the copy of manifests and blobs recursively
-	err = rc.imageCopyOpt(ctx, refSrc, refTgt, descriptor.Descriptor{}, opt.child, []digest.Digest{}, &opt)
+	if opt.copyAnnot != nil {
+		// the original manifest is verified by digest
+		refTgt, err = rc.imageCopyAnnotated(ctx, refSrc, refTgt, opt)
+	} else {
+		err = rc.imageCopyOpt(ctx, refSrc, refTgt, descriptor.Descriptor{}, opt.child, []digest.Digest{}, opt)
+	}
 	if err != nil {
 		return err
 	}
 	// run any final functions, digest-tags and referrers that detected loops are retried here
-	for _, fn := range opt.finalFn {
+	// a final function may add more entries to the list
+	for i := 0; ; i++ {
+		opt.mu.Lock()
+		if i >= len(opt.finalFn) {
+			opt.mu.Unlock()
+			break
+		}
+		fn := opt.finalFn[i]
+		opt.mu.Unlock()
 		err := fn(ctx)
 		if err != nil {
 			return err
 		}
 	}
+	if opt.provenance && !opt.dryRun {
+		err = rc.imageCopyProvenance(ctx, refSrc, refTgt, opt)
+		if err != nil {
+			return err
+		}
+	}
+	if opt.verify && !opt.dryRun {
+		return rc.imageVerify(ctx, refSrc, refTgt, opt)
+	}
 	return nil
 }
 
+// imageCopyAnnotated copies the source by digest and pushes the target tag with the copy annotations added to the top level manifest.
+// The returned ref is the target of the unmodified source manifest.
+func (rc *RegClient) imageCopyAnnotated(ctx context.Context, refSrc ref.Ref, refTgt ref.Ref, opt *imageOpt) (ref.Ref, error) {
+	if refTgt.Tag == "" || refTgt.Digest != "" {
+		return refTgt, fmt.Errorf("copy annotations require a target tag without a digest: %s%.0w", refTgt.CommonName(), errs.ErrInvalidReference)
+	}
+	mSrc, err := rc.ManifestGet(ctx, refSrc)
+	if err != nil {
+		return refTgt, fmt.Errorf("copy failed, error getting source: %w", err)
+	}
+	sDig := mSrc.GetDescriptor().Digest
+	refTgtDig := refTgt.SetDigest(sDig.String())
+	// skip when the target tag was annotated from the same source
+	mTgt, err := rc.ManifestGet(ctx, refTgt)
+	matched := err == nil && CopyAnnotationsMatch(mTgt, sDig)
+	if matched && (opt.fastCheck || (!opt.forceRecursive && opt.referrerConfs == nil && !opt.digestTags)) {
+		if opt.callback != nil {
+			opt.callback(types.CallbackManifest, sDig.String(), types.CallbackSkipped, mTgt.GetDescriptor().Size, mTgt.GetDescriptor().Size)
+		}
+		opt.reportAdd(func(r *ImageCopyReport) { r.ManifestsSkipped++ })
+		return refTgtDig, nil
+	}
+	err = rc.imageCopyOpt(ctx, refSrc.SetDigest(sDig.String()), refTgtDig, descriptor.Descriptor{}, opt.child, []digest.Digest{}, opt)
+	if err != nil {
+		return refTgtDig, err
+	}
+	// referrers and digest tags were refreshed, the annotated tag is unchanged
+	if matched {
+		opt.reportAdd(func(r *ImageCopyReport) { r.ManifestsSkipped++ })
+		return refTgtDig, nil
+	}
+	// push the tag with a copy of the manifest
+	body, err := mSrc.RawBody()
+	if err != nil {
+		return refTgtDig, err
+	}
+	mTgt, err = manifest.New(manifest.WithDesc(mSrc.GetDescriptor()), manifest.WithRaw(body))
+	if err != nil {
+		return refTgtDig, err
+	}
+	ma, ok := mTgt.(manifest.Annotator)
+	if !ok {
+		return refTgtDig, fmt.Errorf("copy annotations not supported for media type %s%.0w", mTgt.GetDescriptor().MediaType, errs.ErrUnsupportedMediaType)
+	}
+	annot, err := ma.GetAnnotations()
+	if err != nil {
+		return refTgtDig, err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	setAnnot := map[string]string{
+		CopyAnnotationSource:  refSrc.CommonName(),
+		CopyAnnotationDigest:  sDig.String(),
+		CopyAnnotationUpdated: now,
+	}
+	if annot[types.AnnotationCreated] == "" {
+		setAnnot[types.AnnotationCreated] = now
+	}
+	maps.Copy(setAnnot, opt.copyAnnot)
+	for _, key := range slices.Sorted(maps.Keys(setAnnot)) {
+		err = ma.SetAnnotation(key, setAnnot[key])
+		if err != nil {
+			return refTgtDig, err
+		}
+	}
+	if opt.dryRun {
+		opt.reportAdd(func(r *ImageCopyReport) {
+			r.ManifestsPushed++
+			r.Plan = append(r.Plan, ImageCopyPlan{Action: "push", Target: refTgt.CommonName(), Descriptor: mTgt.GetDescriptor()})
+		})
+		return refTgtDig, nil
+	}
+	mOpts := []ManifestOpts{}
+	if opt.force {
+		mOpts = append(mOpts, WithManifestForce())
+	}
+	err = rc.ManifestPut(ctx, refTgt, mTgt, mOpts...)
+	if err != nil {
+		return refTgtDig, err
+	}
+	opt.reportAdd(func(r *ImageCopyReport) { r.ManifestsPushed++ })
+	return refTgtDig, nil
+}
+
+// CopyAnnotationsMatch returns true when the manifest was pushed with [ImageWithCopyAnnotations] from the source digest.
+func CopyAnnotationsMatch(m manifest.Manifest, d digest.Digest) bool {
+	ma, ok := m.(manifest.Annotator)
+	if !ok {
+		return false
+	}
+	annot, err := ma.GetAnnotations()
+	return err == nil && annot[CopyAnnotationDigest] == d.String()
+}
+
 // imageCopyOpt is a thread safe copy of a manifest and nested content.
 func (rc *RegClient) imageCopyOpt(ctx context.Context, refSrc ref.Ref, refTgt ref.Ref, d descriptor.Descriptor, child bool, parents []digest.Digest, opt *imageOpt) (err error) {
 	var mSrc, mTgt manifest.Manifest
@@ -634,12 +1162,19 @@ func (rc *RegClient) imageCopyOpt(ctx context.Context, refSrc ref.Ref, refTgt re
 	if child {
 		mOpts = append(mOpts, WithManifestChild())
 	}
+	if opt.force || opt.promoteForce {
+		mOpts = append(mOpts, WithManifestForce())
+	}
 	bOpt := []BlobOpts{}
 	if opt.callback != nil {
 		bOpt = append(bOpt, BlobWithCallback(opt.callback))
 	}
-	if opt.blobReaderHook != nil {
-		bOpt = append(bOpt, BlobWithReaderHook(opt.blobReaderHook))
+	hook := opt.blobReaderHook
+	if opt.seekableVerify {
+		hook = imageSeekableVerifyHook(hook)
+	}
+	if hook != nil {
+		bOpt = append(bOpt, BlobWithReaderHook(hook))
 	}
 	waitCh := make(chan error)
 	waitCount := 0
@@ -706,6 +1241,10 @@ func (rc *RegClient) imageCopyOpt(ctx context.Context, refSrc ref.Ref, refTgt re
 
 	// If source is image, copy blobs
 	if mSrcImg, ok := mSrc.(manifest.Imager); ok && mSrc.IsSet() && !ref.EqualRepository(refSrc, refTgt) {
+		copyLvl := slog.LevelInfo
+		if opt.dryRun {
+			copyLvl = slog.LevelDebug
+		}
 		// copy the config
 		cd, err := mSrcImg.GetConfig()
 		if err != nil {
@@ -719,7 +1258,7 @@ func (rc *RegClient) imageCopyOpt(ctx context.Context, refSrc ref.Ref, refTgt re
 		} else {
 			waitCount++
 			go func() {
-				rc.slog.Info("Copy config",
+				rc.slog.Log(ctx, copyLvl, "Copy config",
 					slog.String("source", refSrc.Reference),
 					slog.String("target", refTgt.Reference),
 					slog.String("digest", cd.Digest.String()))
@@ -741,7 +1280,7 @@ func (rc *RegClient) imageCopyOpt(ctx context.Context, refSrc ref.Ref, refTgt re
 			return err
 		}
 		for _, layerSrc := range l {
-			if len(layerSrc.URLs) > 0 && !opt.includeExternal {
+			if len(layerSrc.URLs) > 0 && !opt.externalInclude(layerSrc) {
 				// skip blobs where the URLs are defined, these aren't hosted and won't be pulled from the source
 				rc.slog.Debug("Skipping external layer",
 					slog.String("source", refSrc.Reference),
@@ -752,7 +1291,7 @@ func (rc *RegClient) imageCopyOpt(ctx context.Context, refSrc ref.Ref, refTgt re
 			}
 			waitCount++
 			go func() {
-				rc.slog.Info("Copy layer",
+				rc.slog.Log(ctx, copyLvl, "Copy layer",
 					slog.String("source", refSrc.Reference),
 					slog.String("target", refTgt.Reference),
 					slog.String("layer", layerSrc.Digest.String()))
@@ -834,36 +1373,65 @@ func (rc *RegClient) imageCopyOpt(ctx context.Context, refSrc ref.Ref, refTgt re
 				descList = append(descList, rlFilter.Descriptors...)
 			}
 		}
+		var errReferrer error
 		for _, rDesc := range descList {
-			opt.mu.Lock()
-			seen := opt.seen[":"+rDesc.Digest.String()]
-			opt.mu.Unlock()
-			if seen != nil {
-				continue // skip referrers that have been seen
+			if slices.Contains(parentsNew, rDesc.Digest) {
+				// a referrer that is also a parent, A refers to B refers to A, is already being copied
+				rc.slog.Debug("Referrer loop detected",
+					slog.String("digest", rDesc.Digest.String()),
+					slog.String("src", referrerSrc.CommonName()))
+				continue
 			}
 			referrerSrc := referrerSrc.SetDigest(rDesc.Digest.String())
 			referrerTgt := referrerTgt.SetDigest(rDesc.Digest.String())
-			waitCount++
-			go func() {
+			copyReferrer := func() error {
 				err := rc.imageCopyOpt(ctx, referrerSrc, referrerTgt, rDesc, true, parentsNew, opt)
 				if errors.Is(err, errs.ErrLoopDetected) {
 					// if a loop is detected, push the referrers copy to the end
 					opt.mu.Lock()
 					opt.finalFn = append(opt.finalFn, func(ctx context.Context) error {
-						return rc.imageCopyOpt(ctx, referrerSrc, referrerTgt, rDesc, true, []digest.Digest{}, opt)
+						err := rc.imageCopyOpt(ctx, referrerSrc, referrerTgt, rDesc, true, []digest.Digest{}, opt)
+						if err == nil {
+							opt.reportAdd(func(r *ImageCopyReport) { r.ReferrersCopied++ })
+						}
+						return err
 					})
 					opt.mu.Unlock()
-					waitCh <- nil
-				} else {
-					if err != nil && !errors.Is(err, context.Canceled) {
-						rc.slog.Warn("Failed to copy referrer",
-							slog.String("digest", rDesc.Digest.String()),
-							slog.String("src", referrerSrc.CommonName()),
-							slog.String("tgt", referrerTgt.CommonName()))
-					}
-					waitCh <- err
+					return nil
 				}
-			}()
+				if err == nil {
+					opt.reportAdd(func(r *ImageCopyReport) { r.ReferrersCopied++ })
+				}
+				if err != nil && !errors.Is(err, context.Canceled) {
+					rc.slog.Warn("Failed to copy referrer",
+						slog.String("digest", rDesc.Digest.String()),
+						slog.String("src", referrerSrc.CommonName()),
+						slog.String("tgt", referrerTgt.CommonName()))
+				}
+				return err
+			}
+			// bound the number of concurrent referrer copies, a nested referrer is copied inline when workers are busy to avoid a deadlock
+			select {
+			case opt.referrerSem <- struct{}{}:
+				waitCount++
+				go func() {
+					err := copyReferrer()
+					<-opt.referrerSem
+					waitCh <- err
+				}()
+			default:
+				errReferrer = copyReferrer()
+			}
+			if errReferrer != nil {
+				cancel()
+				break
+			}
+		}
+		if errReferrer != nil {
+			for ; waitCount > 0; waitCount-- {
+				<-waitCh
+			}
+			return errReferrer
 		}
 	}
 
@@ -894,9 +1462,8 @@ func (rc *RegClient) imageCopyOpt(ctx context.Context, refSrc ref.Ref, refTgt re
 			opt.tagList = tags
 		}
 		opt.mu.Unlock()
-		prefix := fmt.Sprintf("%s-%s", sDig.Algorithm(), sDig.Encoded())
 		for _, tag := range opt.tagList {
-			if strings.HasPrefix(tag, prefix) {
+			if suffix, ok := referrer.DigestTagMatch(sDig, tag); ok && opt.digestTagInclude(suffix) {
 				// skip referrers that were copied above
 				if slices.Contains(referrerTags, tag) {
 					continue
@@ -951,7 +1518,15 @@ func (rc *RegClient) imageCopyOpt(ctx context.Context, refSrc ref.Ref, refTgt re
 	}
 
 	// push manifest
-	if mTgt == nil || sDig != mTgt.GetDescriptor().Digest || opt.forceRecursive {
+	if (mTgt == nil || sDig != mTgt.GetDescriptor().Digest || opt.forceRecursive) && opt.dryRun {
+		if opt.callback != nil {
+			opt.callback(types.CallbackManifest, d.Digest.String(), types.CallbackSkipped, d.Size, d.Size)
+		}
+		opt.reportAdd(func(r *ImageCopyReport) {
+			r.ManifestsPushed++
+			r.Plan = append(r.Plan, ImageCopyPlan{Action: "push", Target: refTgt.CommonName(), Descriptor: mSrc.GetDescriptor()})
+		})
+	} else if mTgt == nil || sDig != mTgt.GetDescriptor().Digest || opt.forceRecursive {
 		err = rc.ManifestPut(ctx, refTgt, mSrc, mOpts...)
 		if err != nil {
 			if !errors.Is(err, context.Canceled) {
@@ -964,10 +1539,12 @@ func (rc *RegClient) imageCopyOpt(ctx context.Context, refSrc ref.Ref, refTgt re
 		if opt.callback != nil {
 			opt.callback(types.CallbackManifest, d.Digest.String(), types.CallbackFinished, d.Size, d.Size)
 		}
+		opt.reportAdd(func(r *ImageCopyReport) { r.ManifestsPushed++ })
 	} else {
 		if opt.callback != nil {
 			opt.callback(types.CallbackManifest, d.Digest.String(), types.CallbackSkipped, d.Size, d.Size)
 		}
+		opt.reportAdd(func(r *ImageCopyReport) { r.ManifestsSkipped++ })
 	}
 	if seenCB != nil {
 		seenCB(nil)
@@ -982,25 +1559,280 @@ func (rc *RegClient) imageCopyBlob(ctx context.Context, refSrc ref.Ref, refTgt r
 	if seenCB == nil {
 		return err
 	}
-	err = rc.BlobCopy(ctx, refSrc, refTgt, d, bOpt...)
+	var method blobCopyMethod
+	if opt.copyReport != nil {
+		bOpt = append(slices.Clone(bOpt), blobWithCopyMethod(&method))
+	}
+	if opt.dryRun {
+		method = rc.imageCopyBlobPlan(ctx, refSrc, refTgt, d)
+	} else {
+		err = rc.BlobCopy(ctx, refSrc, refTgt, d, bOpt...)
+	}
+	if err == nil {
+		opt.reportAdd(func(r *ImageCopyReport) {
+			action := ""
+			switch method {
+			case blobCopyMounted:
+				r.BlobsMounted++
+				r.BytesMounted += d.Size
+				action = "mount"
+			case blobCopyPulled:
+				r.BlobsPulled++
+				r.BytesPulled += d.Size
+				action = "pull"
+			default:
+				r.BlobsSkipped++
+			}
+			if r.DryRun && action != "" {
+				r.Plan = append(r.Plan, ImageCopyPlan{Action: action, Target: refTgt.SetDigest(d.Digest.String()).CommonName(), Descriptor: d})
+			}
+		})
+	}
 	seenCB(err)
 	return err
 }
 
+// imageCopyBlobPlan returns the method [RegClient.BlobCopy] is expected to use without copying the blob.
+func (rc *RegClient) imageCopyBlobPlan(ctx context.Context, refSrc ref.Ref, refTgt ref.Ref, d descriptor.Descriptor) blobCopyMethod {
+	if ref.EqualRepository(refSrc, refTgt) {
+		return blobCopySkipped
+	}
+	tDesc := d
+	tDesc.URLs = []string{}
+	if _, err := rc.BlobHead(ctx, refTgt, tDesc); err == nil {
+		return blobCopySkipped
+	}
+	if ref.EqualRegistry(refSrc, refTgt) {
+		return blobCopyMounted
+	}
+	return blobCopyPulled
+}
+
+// ImageVerify pulls the manifests and blobs of an image to verify each digest.
+// Every blob is pulled unless a sample is set with [ImageWithVerify], which also returns a report of each check.
+// Platforms are limited with [ImageWithPlatforms], and external layers are only checked with [ImageWithIncludeExternal].
+// Concurrent blob checks are set with [ImageWithVerifyParallel].
+func (rc *RegClient) ImageVerify(ctx context.Context, r ref.Ref, opts ...ImageOpts) error {
+	if !r.IsSet() {
+		return fmt.Errorf("ref is not set: %s%.0w", r.CommonName(), errs.ErrInvalidReference)
+	}
+	opt := imageOpt{
+		verifySample: -1,
+	}
+	for _, optFn := range opts {
+		optFn(&opt)
+	}
+	return rc.imageVerify(ctx, r, r, &opt)
+}
+
+// imageVerify checks the manifests and blobs copied to the target.
+func (rc *RegClient) imageVerify(ctx context.Context, refSrc ref.Ref, refTgt ref.Ref, opt *imageOpt) error {
+	report := opt.verifyReport
+	if report == nil {
+		report = &ImageVerifyReport{}
+	}
+	report.Entries = []ImageVerifyEntry{}
+	// the top level target must match the source digest
+	d := descriptor.Descriptor{Digest: digest.Digest(refSrc.Digest)}
+	if d.Digest == "" {
+		mSrc, err := rc.ManifestHead(ctx, refSrc, WithManifestRequireDigest())
+		if err != nil {
+			return fmt.Errorf("verify failed, error getting source: %w", err)
+		}
+		d = mSrc.GetDescriptor()
+	}
+	blobs := []descriptor.Descriptor{}
+	rc.imageVerifyManifest(ctx, refTgt, d, opt, report, &blobs)
+	// select the blobs to pull
+	sample := len(blobs)
+	if opt.verifySample >= 0 {
+		sample = min(opt.verifySample, len(blobs))
+	}
+	pull := map[int]bool{}
+	for _, i := range rand.Perm(len(blobs))[:sample] {
+		pull[i] = true
+	}
+	parallel := opt.verifyParallel
+	if parallel <= 0 {
+		parallel = 4
+	}
+	entries := make([]ImageVerifyEntry, len(blobs))
+	ops := make([]BatchOp, len(blobs))
+	for i, bd := range blobs {
+		entries[i] = ImageVerifyEntry{
+			Ref:        refTgt.SetTag(""),
+			Descriptor: bd,
+			Method:     ImageVerifyHead,
+		}
+		if pull[i] {
+			entries[i].Method = ImageVerifyDigest
+			ops[i] = BatchOpFunc(refTgt, func(ctx context.Context) error {
+				return rc.imageVerifyBlobDigest(ctx, refTgt, bd)
+			})
+		} else {
+			ops[i] = BatchOpFunc(refTgt, func(ctx context.Context) error {
+				return rc.imageVerifyBlobHead(ctx, refTgt, bd)
+			})
+		}
+	}
+	// errors are included in the report
+	br, _ := rc.Batch(ctx, ops, BatchWithWorkers(parallel))
+	for i, res := range br.Results {
+		entries[i].Err = res.Err
+	}
+	report.Entries = append(report.Entries, entries...)
+	if err := report.Err(); err != nil {
+		return fmt.Errorf("verify failed for %s: %w", refTgt.CommonName(), err)
+	}
+	return nil
+}
+
+// imageVerifyManifest checks a manifest on the target, recursing into nested manifests and adding blobs to the list.
+func (rc *RegClient) imageVerifyManifest(ctx context.Context, refTgt ref.Ref, d descriptor.Descriptor, opt *imageOpt, report *ImageVerifyReport, blobs *[]descriptor.Descriptor) {
+	r := refTgt.SetDigest(d.Digest.String())
+	entry := ImageVerifyEntry{
+		Ref:        r,
+		Descriptor: d,
+		Method:     ImageVerifyManifest,
+	}
+	// embedded data would be returned without reading the manifest from the target
+	dGet := d
+	dGet.Data = nil
+	m, err := rc.ManifestGet(ctx, r, WithManifestDesc(dGet))
+	if err == nil {
+		var raw []byte
+		raw, err = m.RawBody()
+		if err == nil && d.Digest.Algorithm().FromBytes(raw) != d.Digest {
+			err = fmt.Errorf("manifest %s%.0w", r.CommonName(), errs.ErrDigestMismatch)
+		}
+	}
+	entry.Err = err
+	report.Entries = append(report.Entries, entry)
+	if err != nil {
+		return
+	}
+	addBlob := func(bd descriptor.Descriptor) {
+		if !slices.ContainsFunc(*blobs, func(e descriptor.Descriptor) bool { return e.Digest == bd.Digest }) {
+			*blobs = append(*blobs, bd)
+		}
+	}
+	if mIndex, ok := m.(manifest.Indexer); ok {
+		dList, err := mIndex.GetManifestList()
+		if err != nil {
+			report.Entries[len(report.Entries)-1].Err = err
+			return
+		}
+		for _, dEntry := range dList {
+			// platforms and media types are filtered the same as the copy
+			if len(opt.platforms) > 0 {
+				match, err := imagePlatformInList(dEntry.Platform, opt.platforms)
+				if err != nil || !match {
+					continue
+				}
+			}
+			switch dEntry.MediaType {
+			case mediatype.Docker2ImageConfig, mediatype.OCI1ImageConfig,
+				mediatype.Docker2Layer, mediatype.Docker2LayerGzip, mediatype.Docker2LayerZstd,
+				mediatype.OCI1Layer, mediatype.OCI1LayerGzip, mediatype.OCI1LayerZstd,
+				mediatype.BuildkitCacheConfig:
+				addBlob(dEntry)
+			default:
+				rc.imageVerifyManifest(ctx, refTgt, dEntry, opt, report, blobs)
+			}
+		}
+	}
+	if mImg, ok := m.(manifest.Imager); ok {
+		cd, err := mImg.GetConfig()
+		if err == nil {
+			addBlob(cd)
+		} else if !errors.Is(err, errs.ErrUnsupportedMediaType) {
+			report.Entries[len(report.Entries)-1].Err = err
+			return
+		}
+		layers, err := mImg.GetLayers()
+		if err != nil {
+			report.Entries[len(report.Entries)-1].Err = err
+			return
+		}
+		for _, ld := range layers {
+			if len(ld.URLs) > 0 && !opt.externalInclude(ld) {
+				continue
+			}
+			addBlob(ld)
+		}
+	}
+}
+
+// imageVerifyBlobHead checks a blob exists on the target with the expected size.
+func (rc *RegClient) imageVerifyBlobHead(ctx context.Context, refTgt ref.Ref, d descriptor.Descriptor) error {
+	br, err := rc.BlobHead(ctx, refTgt, d)
+	if err != nil {
+		return err
+	}
+	_ = br.Close()
+	if size := br.GetDescriptor().Size; size > 0 && d.Size > 0 && size != d.Size {
+		return fmt.Errorf("blob size %d, expected %d%.0w", size, d.Size, errs.ErrMismatch)
+	}
+	return nil
+}
+
+// imageVerifyBlobDigest pulls a blob from the target and verifies the digest.
+func (rc *RegClient) imageVerifyBlobDigest(ctx context.Context, refTgt ref.Ref, d descriptor.Descriptor) error {
+	br, err := rc.BlobGet(ctx, refTgt, d)
+	if err != nil {
+		return err
+	}
+	defer br.Close()
+	// the blob reader verifies the size and digest when the content is read
+	_, err = io.Copy(io.Discard, br)
+	return err
+}
+
+// imageSeekableVerifyHook returns a blob reader hook that verifies seekable layers before calling the next hook.
+func imageSeekableVerifyHook(next func(*blob.BReader) (*blob.BReader, error)) func(*blob.BReader) (*blob.BReader, error) {
+	return func(br *blob.BReader) (*blob.BReader, error) {
+		d := br.GetDescriptor()
+		if slices.ContainsFunc(archive.SeekableAnnotations, func(k string) bool {
+			_, ok := d.Annotations[k]
+			return ok
+		}) {
+			vr, err := archive.SeekableVerifyReader(br, d.Annotations)
+			if err != nil {
+				return nil, fmt.Errorf("failed to verify layer %s: %w", d.Digest.String(), err)
+			}
+			br = blob.NewReader(blob.WithDesc(d), blob.WithReader(imageVerifyReader{ReadCloser: vr, src: br}))
+		}
+		if next != nil {
+			return next(br)
+		}
+		return br, nil
+	}
+}
+
+// imageVerifyReader closes both the verifying reader and the source.
+type imageVerifyReader struct {
+	io.ReadCloser
+	src io.Closer
+}
+
+func (r imageVerifyReader) Close() error {
+	return errors.Join(r.ReadCloser.Close(), r.src.Close())
+}
+
 // imageSeenOrWait returns either a callback to report the error when the digest hasn't been seen before
 // or it will wait for the previous copy to run and return the error from that copy
 func imageSeenOrWait(ctx context.Context, opt *imageOpt, repo, tag string, dig digest.Digest, parents []digest.Digest) (func(error), error) {
 	var seenNew *imageSeen
 	key := repo + "/" + tag + ":" + dig.String()
-	opt.mu.Lock()
-	seen := opt.seen[key]
+	opt.seen.mu.Lock()
+	seen := opt.seen.entries[key]
 	if seen == nil {
 		seenNew = &imageSeen{
 			done: make(chan struct{}),
 		}
-		opt.seen[key] = seenNew
+		opt.seen.entries[key] = seenNew
 	}
-	opt.mu.Unlock()
+	opt.seen.mu.Unlock()
 	if seen != nil {
 		// quick check for the previous copy already done
 		select {
@@ -1028,9 +1860,9 @@ func imageSeenOrWait(ctx context.Context, opt *imageOpt, repo, tag string, dig d
 			close(seenNew.done)
 			// on failures, delete the history to allow a retry
 			if err != nil {
-				opt.mu.Lock()
-				delete(opt.seen, key)
-				opt.mu.Unlock()
+				opt.seen.mu.Lock()
+				delete(opt.seen.entries, key)
+				opt.seen.mu.Unlock()
 			}
 		}, nil
 	}
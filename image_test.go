@@ -7,23 +7,35 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/olareg/olareg"
 	oConfig "github.com/olareg/olareg/config"
+	"github.com/opencontainers/go-digest"
 
 	"github.com/regclient/regclient/config"
 	"github.com/regclient/regclient/internal/copyfs"
+	"github.com/regclient/regclient/pkg/archive"
+	"github.com/regclient/regclient/scheme"
 	"github.com/regclient/regclient/scheme/reg"
+	"github.com/regclient/regclient/types"
 	"github.com/regclient/regclient/types/blob"
+	"github.com/regclient/regclient/types/descriptor"
 	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/mediatype"
+	v1 "github.com/regclient/regclient/types/oci/v1"
+	"github.com/regclient/regclient/types/platform"
 	"github.com/regclient/regclient/types/ref"
+	"github.com/regclient/regclient/types/referrer"
 )
 
 func TestImageCheckBase(t *testing.T) {
@@ -135,6 +147,12 @@ func TestImageCheckBase(t *testing.T) {
 			r:    r3,
 			opts: []ImageOpts{ImageWithCheckBaseRef(rb1.CommonName())},
 		},
+		{
+			name:      "manual b1, v3 with more layers",
+			r:         rb1,
+			opts:      []ImageOpts{ImageWithCheckBaseRef(r3.CommonName())},
+			expectErr: errs.ErrMismatch,
+		},
 		{
 			name: "manual v3, b3 with digest",
 			r:    r3,
@@ -461,6 +479,824 @@ func TestCopy(t *testing.T) {
 	}
 }
 
+func TestCopyVerify(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := New()
+	tempDir := t.TempDir()
+	rSrc, err := ref.New("ocidir://./testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rTgt, err := ref.New("ocidir://" + tempDir + "/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	report := ImageVerifyReport{}
+	err = rc.ImageCopy(ctx, rSrc, rTgt, ImageWithVerify(-1, &report))
+	if err != nil {
+		t.Fatalf("failed to copy: %v", err)
+	}
+	count := map[ImageVerifyMethod]int{}
+	layers := []descriptor.Descriptor{}
+	for _, e := range report.Entries {
+		count[e.Method]++
+		if e.Err != nil {
+			t.Errorf("unexpected error verifying %s: %v", e.Descriptor.Digest, e.Err)
+		}
+		if e.Method == ImageVerifyDigest && mediatype.Base(e.Descriptor.MediaType) != mediatype.OCI1ImageConfig {
+			layers = append(layers, e.Descriptor)
+		}
+	}
+	if count[ImageVerifyManifest] < 2 || count[ImageVerifyDigest] < 2 || count[ImageVerifyHead] != 0 || len(layers) < 2 {
+		t.Fatalf("unexpected verify counts: %v", count)
+	}
+	// verify the source limited to a platform
+	reportSrc := ImageVerifyReport{}
+	err = rc.ImageVerify(ctx, rSrc, ImageWithPlatforms([]string{"linux/amd64"}), ImageWithVerify(-1, &reportSrc), ImageWithVerifyParallel(1))
+	if err != nil {
+		t.Fatalf("failed to verify source: %v", err)
+	}
+	if len(reportSrc.Entries) == 0 || len(reportSrc.Entries) >= len(report.Entries) {
+		t.Errorf("unexpected number of entries verifying a single platform: %d of %d", len(reportSrc.Entries), len(report.Entries))
+	}
+	// a manifest embedded in the index descriptor must still be read from the target
+	mEmbed, err := manifest.New(manifest.WithOrig(v1.Manifest{
+		Versioned: v1.ManifestSchemaVersion,
+		MediaType: mediatype.OCI1Manifest,
+		Config: descriptor.Descriptor{
+			MediaType: mediatype.OCI1Empty,
+			Digest:    descriptor.EmptyDigest,
+			Size:      int64(len(descriptor.EmptyData)),
+		},
+		Layers: []descriptor.Descriptor{},
+	}))
+	if err != nil {
+		t.Fatalf("failed to create manifest: %v", err)
+	}
+	dEmbed := mEmbed.GetDescriptor()
+	dEmbed.Data, err = mEmbed.RawBody()
+	if err != nil {
+		t.Fatalf("failed to get manifest body: %v", err)
+	}
+	mEmbedIndex, err := manifest.New(manifest.WithOrig(v1.Index{
+		Versioned: v1.IndexSchemaVersion,
+		MediaType: mediatype.OCI1ManifestList,
+		Manifests: []descriptor.Descriptor{dEmbed},
+	}))
+	if err != nil {
+		t.Fatalf("failed to create index: %v", err)
+	}
+	rEmbed := rTgt.SetTag("embed")
+	err = rc.ManifestPut(ctx, rEmbed, mEmbedIndex)
+	if err != nil {
+		t.Fatalf("failed to push index: %v", err)
+	}
+	err = rc.ImageVerify(ctx, rEmbed, ImageWithVerify(-1, nil))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("verify of a missing embedded manifest did not fail, received %v", err)
+	}
+	// corrupt one layer without changing the size, and delete another (ocidir returns fs.ErrNotExist for missing blobs)
+	blobFile := func(d descriptor.Descriptor) string {
+		return filepath.Join(tempDir, "testrepo", "blobs", d.Digest.Algorithm().String(), d.Digest.Encoded())
+	}
+	err = os.WriteFile(blobFile(layers[0]), bytes.Repeat([]byte{0}, int(layers[0].Size)), 0o600)
+	if err != nil {
+		t.Fatalf("failed to corrupt blob: %v", err)
+	}
+	err = os.Remove(blobFile(layers[1]))
+	if err != nil {
+		t.Fatalf("failed to delete blob: %v", err)
+	}
+	tt := []struct {
+		name        string
+		sample      int
+		expectErr   []error
+		expectNoErr []error
+	}{
+		{
+			name:        "head",
+			sample:      0,
+			expectErr:   []error{fs.ErrNotExist},
+			expectNoErr: []error{errs.ErrDigestMismatch},
+		},
+		{
+			name:      "digest",
+			sample:    -1,
+			expectErr: []error{fs.ErrNotExist, errs.ErrDigestMismatch},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			report := ImageVerifyReport{}
+			err := rc.ImageCopy(ctx, rSrc, rTgt, ImageWithVerify(tc.sample, &report))
+			if err == nil {
+				t.Fatalf("verify did not fail")
+			}
+			for _, expect := range tc.expectErr {
+				if !errors.Is(err, expect) || !errors.Is(report.Err(), expect) {
+					t.Errorf("missing expected error %v, received %v", expect, err)
+				}
+			}
+			for _, expect := range tc.expectNoErr {
+				if errors.Is(err, expect) {
+					t.Errorf("unexpected error %v, received %v", expect, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCopyReport(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	boolT := true
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "./testdata",
+		},
+		API: oConfig.ConfigAPI{
+			DeleteEnabled: &boolT,
+		},
+	})
+	ts := httptest.NewServer(regHandler)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+	rc := New(
+		WithConfigHost(config.Host{
+			Name:     tsHost,
+			Hostname: tsHost,
+			TLS:      config.TLSDisabled,
+		}),
+		WithRegOpts(reg.WithDelay(time.Millisecond*10, time.Millisecond*100)),
+	)
+	tempDir := t.TempDir()
+	rSrc, err := ref.New(tsHost + "/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	t.Run("same repository", func(t *testing.T) {
+		report := ImageCopyReport{}
+		err := rc.ImageCopy(ctx, rSrc, rSrc.SetTag("v1-copy"), ImageWithCopyReport(&report))
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		if !report.SameRegistry || !report.SameRepository || !report.ManifestOnly() || report.BlobsMounted != 0 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	})
+	t.Run("same registry", func(t *testing.T) {
+		rTgt, err := ref.New(tsHost + "/copyreport:v1")
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		report := ImageCopyReport{}
+		err = rc.ImageCopy(ctx, rSrc, rTgt, ImageWithCopyReport(&report), ImageWithReferrers())
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		if !report.SameRegistry || report.SameRepository || !report.ManifestOnly() || report.BlobsMounted == 0 || report.BytesMounted == 0 || report.ManifestsPushed == 0 || report.ReferrersCopied == 0 || report.Duration <= 0 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+		// a second copy skips the existing content
+		report = ImageCopyReport{}
+		err = rc.ImageCopy(ctx, rSrc, rTgt, ImageWithCopyReport(&report), ImageWithForceRecursive())
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		if !report.ManifestOnly() || report.BlobsMounted+report.BlobsSkipped == 0 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	})
+	t.Run("different registry", func(t *testing.T) {
+		rTgt, err := ref.New("ocidir://" + tempDir + "/testrepo:v1")
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		report, err := rc.ImageCopyWithReport(ctx, rSrc, rTgt)
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		if report.SameRegistry || report.ManifestOnly() || report.BlobsMounted != 0 || report.BlobsPulled == 0 || report.BytesPulled == 0 || report.ReferrersCopied != 0 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	})
+	t.Run("dry run", func(t *testing.T) {
+		rTgt, err := ref.New(tsHost + "/dryrun:v1")
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		report, err := rc.ImageCopyWithReport(ctx, rSrc, rTgt, ImageWithDryRun(), ImageWithVerify(-1, nil))
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		if !report.DryRun || report.ManifestsPushed == 0 || report.BlobsMounted == 0 || report.BlobsSkipped != 0 || len(report.Plan) != report.ManifestsPushed+report.BlobsMounted {
+			t.Errorf("unexpected report: %+v", report)
+		}
+		for _, p := range report.Plan {
+			if (p.Action != "push" && p.Action != "mount") || p.Descriptor.Digest == "" {
+				t.Errorf("unexpected plan entry: %+v", p)
+			}
+		}
+		_, err = rc.ManifestHead(ctx, rTgt)
+		if !errors.Is(err, errs.ErrNotFound) {
+			t.Errorf("target exists after dry run: %v", err)
+		}
+		// the plan is empty when the target is current
+		rCur, err := ref.New(tsHost + "/copyreport:v1")
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		report, err = rc.ImageCopyWithReport(ctx, rSrc, rCur, ImageWithDryRun())
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		if !report.DryRun || len(report.Plan) != 0 || report.ManifestsPushed != 0 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+		// a different registry pulls every blob
+		rTgt, err = ref.New("ocidir://" + tempDir + "/dryrun:v1")
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		report, err = rc.ImageCopyWithReport(ctx, rSrc, rTgt, ImageWithDryRun())
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		if report.BlobsPulled == 0 || report.BytesPulled == 0 || report.BlobsMounted != 0 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+		if _, err := os.Stat(tempDir + "/dryrun"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("ocidir created by dry run: %v", err)
+		}
+	})
+}
+
+func TestCopyAnnotations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := New()
+	tempDir := t.TempDir()
+	rSrc, err := ref.New("ocidir://./testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rTgt, err := ref.New("ocidir://" + tempDir + "/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	mSrc, err := rc.ManifestHead(ctx, rSrc, WithManifestRequireDigest())
+	if err != nil {
+		t.Fatalf("failed to head source: %v", err)
+	}
+	srcDig := mSrc.GetDescriptor().Digest
+	getAnnot := func(t *testing.T, r ref.Ref) (map[string]string, digest.Digest) {
+		t.Helper()
+		m, err := rc.ManifestGet(ctx, r)
+		if err != nil {
+			t.Fatalf("failed to get %s: %v", r.CommonName(), err)
+		}
+		annot, err := m.(manifest.Annotator).GetAnnotations()
+		if err != nil {
+			t.Fatalf("failed to get annotations: %v", err)
+		}
+		return annot, m.GetDescriptor().Digest
+	}
+
+	t.Run("copy", func(t *testing.T) {
+		report, err := rc.ImageCopyWithReport(ctx, rSrc, rTgt, ImageWithCopyAnnotations(map[string]string{"org.example.mirror": "primary"}), ImageWithVerify(-1, nil))
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		if report.ManifestsPushed < 2 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+		annot, tgtDig := getAnnot(t, rTgt)
+		if tgtDig == srcDig {
+			t.Errorf("target digest was not changed")
+		}
+		if annot[CopyAnnotationSource] != rSrc.CommonName() || annot[CopyAnnotationDigest] != srcDig.String() || annot["org.example.mirror"] != "primary" {
+			t.Errorf("unexpected annotations: %v", annot)
+		}
+		for _, key := range []string{CopyAnnotationUpdated, types.AnnotationCreated} {
+			if _, err := time.Parse(time.RFC3339, annot[key]); err != nil {
+				t.Errorf("failed to parse annotation %s: %v", key, err)
+			}
+		}
+		// the source manifest is copied by digest
+		if _, err := rc.ManifestHead(ctx, rTgt.SetDigest(srcDig.String())); err != nil {
+			t.Errorf("source digest missing from target: %v", err)
+		}
+		// a second copy skips the annotated tag
+		report, err = rc.ImageCopyWithReport(ctx, rSrc, rTgt, ImageWithCopyAnnotations(nil))
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		if report.ManifestsPushed != 0 || report.ManifestsSkipped != 1 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+		if _, tgtDig2 := getAnnot(t, rTgt); tgtDig2 != tgtDig {
+			t.Errorf("annotated tag was replaced, expected %s, received %s", tgtDig, tgtDig2)
+		}
+	})
+	t.Run("remove annotation", func(t *testing.T) {
+		r := rTgt.SetTag("v1-remove")
+		err := rc.ImageCopy(ctx, rSrc, r, ImageWithCopyAnnotations(map[string]string{CopyAnnotationSource: ""}))
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		annot, _ := getAnnot(t, r)
+		if _, ok := annot[CopyAnnotationSource]; ok || annot[CopyAnnotationDigest] != srcDig.String() {
+			t.Errorf("unexpected annotations: %v", annot)
+		}
+	})
+	t.Run("dry run", func(t *testing.T) {
+		r := rTgt.SetTag("v1-dry-run")
+		report, err := rc.ImageCopyWithReport(ctx, rSrc, r, ImageWithCopyAnnotations(nil), ImageWithDryRun())
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		if !slices.ContainsFunc(report.Plan, func(p ImageCopyPlan) bool { return p.Target == r.CommonName() && p.Descriptor.Digest != srcDig }) {
+			t.Errorf("annotated tag missing from plan: %+v", report.Plan)
+		}
+		if _, err := rc.ManifestHead(ctx, r); !errors.Is(err, errs.ErrNotFound) {
+			t.Errorf("target exists after dry run: %v", err)
+		}
+	})
+	t.Run("target digest", func(t *testing.T) {
+		err := rc.ImageCopy(ctx, rSrc, rTgt.SetDigest(srcDig.String()), ImageWithCopyAnnotations(nil))
+		if !errors.Is(err, errs.ErrInvalidReference) {
+			t.Errorf("expected invalid reference, received %v", err)
+		}
+	})
+}
+
+func TestCopyProvenance(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := New()
+	tempDir := t.TempDir()
+	rSrc, err := ref.New("ocidir://./testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rTgt, err := ref.New("ocidir://" + tempDir + "/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	mSrc, err := rc.ManifestHead(ctx, rSrc, WithManifestRequireDigest())
+	if err != nil {
+		t.Fatalf("failed to head source: %v", err)
+	}
+	srcDig := mSrc.GetDescriptor().Digest
+	rTgtDig := rTgt.SetDigest(srcDig.String())
+	listProvenance := func(t *testing.T, r ref.Ref) []descriptor.Descriptor {
+		t.Helper()
+		rl, err := rc.ReferrerList(ctx, r, scheme.WithReferrerMatchOpt(descriptor.MatchOpt{ArtifactType: ProvenanceArtifactType}))
+		if err != nil {
+			t.Fatalf("failed to list referrers: %v", err)
+		}
+		return rl.Descriptors
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		rDry, err := ref.New("ocidir://" + tempDir + "/dryrun:v1")
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		err = rc.ImageCopy(ctx, rSrc, rDry, ImageWithProvenance(), ImageWithDryRun())
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		if _, err := rc.ManifestHead(ctx, rDry); err == nil {
+			t.Errorf("dry run pushed the target")
+		}
+	})
+	t.Run("copy", func(t *testing.T) {
+		report, err := rc.ImageCopyWithReport(ctx, rSrc, rTgt, ImageWithProvenance())
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		descs := listProvenance(t, rTgtDig)
+		if len(descs) != 1 {
+			t.Fatalf("unexpected number of provenance records, expected 1, received %d", len(descs))
+		}
+		annot := descs[0].Annotations
+		if annot[ProvenanceAnnotationSource] != rSrc.CommonName() || annot[ProvenanceAnnotationDigest] != srcDig.String() || annot[ProvenanceAnnotationVersion] == "" {
+			t.Errorf("unexpected annotations: %v", annot)
+		}
+		if _, err := time.Parse(time.RFC3339, annot[types.AnnotationCreated]); err != nil {
+			t.Errorf("failed to parse created annotation: %v", err)
+		}
+		if report.ManifestsPushed < 2 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+		// a second copy does not push another record
+		err = rc.ImageCopy(ctx, rSrc, rTgt, ImageWithProvenance())
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		if descs := listProvenance(t, rTgtDig); len(descs) != 1 {
+			t.Errorf("unexpected number of provenance records after second copy, expected 1, received %d", len(descs))
+		}
+	})
+	t.Run("copy annotations", func(t *testing.T) {
+		rAnnot := rTgt.SetTag("annotated")
+		err := rc.ImageCopy(ctx, rSrc, rAnnot, ImageWithProvenance(), ImageWithCopyAnnotations(nil))
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		// the record refers to the unmodified source manifest, and was already pushed
+		if descs := listProvenance(t, rTgtDig); len(descs) != 1 {
+			t.Errorf("unexpected number of provenance records, expected 1, received %d", len(descs))
+		}
+	})
+}
+
+func TestCopyDigestTags(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := New()
+	tempDir := t.TempDir()
+	rSrc, err := ref.New("ocidir://./testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	metaTag := "sha256-7ceb9b6bcc274697d0c38be6214b50cec79d601bc61708747d3f6cb772f6c6fa.6fe828b32b9b4572.meta"
+	tt := []struct {
+		name       string
+		opts       []ImageOpts
+		expectErr  error
+		expectMeta bool
+	}{
+		{
+			name:       "all",
+			opts:       []ImageOpts{ImageWithDigestTags()},
+			expectMeta: true,
+		},
+		{
+			name:       "pattern match",
+			opts:       []ImageOpts{ImageWithDigestTagPatterns(`^\.sig$`, `\.meta$`)},
+			expectMeta: true,
+		},
+		{
+			name: "pattern skip",
+			opts: []ImageOpts{ImageWithDigestTagPatterns(`^\.(sig|att|sbom)$`)},
+		},
+		{
+			name:      "invalid pattern",
+			opts:      []ImageOpts{ImageWithDigestTagPatterns(`(`)},
+			expectErr: errs.ErrParsingFailed,
+		},
+	}
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rTgt, err := ref.New(fmt.Sprintf("ocidir://%s/copy%d:v1", tempDir, i))
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			err = rc.ImageCopy(ctx, rSrc, rTgt, tc.opts...)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to copy: %v", err)
+			}
+			_, err = rc.ManifestHead(ctx, rTgt.SetTag(metaTag))
+			if tc.expectMeta && err != nil {
+				t.Errorf("digest tag not copied: %v", err)
+			} else if !tc.expectMeta && err == nil {
+				t.Errorf("digest tag copied")
+			}
+		})
+	}
+}
+
+func TestCopyReferrers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	err := copyfs.Copy(tempDir+"/testrepo", "./testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to copy testrepo to tempDir: %v", err)
+	}
+	rc := New()
+	rSrc, err := ref.New("ocidir://" + tempDir + "/testrepo:v3")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	mSubject, err := rc.ManifestHead(ctx, rSrc, WithManifestRequireDigest())
+	if err != nil {
+		t.Fatalf("failed to head subject: %v", err)
+	}
+	subjectDesc := mSubject.GetDescriptor()
+	rlOrig, err := rc.ReferrerList(ctx, rSrc)
+	if err != nil {
+		t.Fatalf("failed to list referrers: %v", err)
+	}
+	_, err = rc.BlobPut(ctx, rSrc, descriptor.Descriptor{Digest: descriptor.EmptyDigest, Size: int64(len(descriptor.EmptyData))}, bytes.NewReader(descriptor.EmptyData))
+	if err != nil {
+		t.Fatalf("failed to push empty blob: %v", err)
+	}
+	artifactPut := func(subject descriptor.Descriptor, i int) descriptor.Descriptor {
+		t.Helper()
+		m, err := manifest.New(manifest.WithOrig(v1.Manifest{
+			Versioned:    v1.ManifestSchemaVersion,
+			MediaType:    mediatype.OCI1Manifest,
+			ArtifactType: "application/example.attestation",
+			Config: descriptor.Descriptor{
+				MediaType: mediatype.OCI1Empty,
+				Digest:    descriptor.EmptyDigest,
+				Size:      int64(len(descriptor.EmptyData)),
+			},
+			Layers: []descriptor.Descriptor{
+				{
+					MediaType: mediatype.OCI1Empty,
+					Digest:    descriptor.EmptyDigest,
+					Size:      int64(len(descriptor.EmptyData)),
+				},
+			},
+			Subject:     &subject,
+			Annotations: map[string]string{"org.example.index": fmt.Sprintf("%d", i)},
+		}))
+		if err != nil {
+			t.Fatalf("failed to create artifact: %v", err)
+		}
+		err = rc.ManifestPut(ctx, rSrc.SetDigest(m.GetDescriptor().Digest.String()), m)
+		if err != nil {
+			t.Fatalf("failed to push artifact: %v", err)
+		}
+		return m.GetDescriptor()
+	}
+	// attach many referrers to the image, and a nested referrer to the first
+	count := 8
+	artifacts := []descriptor.Descriptor{}
+	for i := range count {
+		artifacts = append(artifacts, artifactPut(subjectDesc, i))
+	}
+	artifactPut(artifacts[0], count)
+	// create a loop, the fallback tag of the last artifact lists the image as a referrer
+	rLoop, err := referrer.FallbackTag(rSrc.SetDigest(artifacts[count-1].Digest.String()))
+	if err != nil {
+		t.Fatalf("failed to get fallback tag: %v", err)
+	}
+	mLoop, err := manifest.New(manifest.WithOrig(v1.Index{
+		Versioned: v1.IndexSchemaVersion,
+		MediaType: mediatype.OCI1ManifestList,
+		Manifests: []descriptor.Descriptor{subjectDesc},
+	}))
+	if err != nil {
+		t.Fatalf("failed to create loop: %v", err)
+	}
+	err = rc.ManifestPut(ctx, rLoop, mLoop)
+	if err != nil {
+		t.Fatalf("failed to push loop: %v", err)
+	}
+	rl, err := rc.ReferrerList(ctx, rSrc.SetDigest(artifacts[count-1].Digest.String()))
+	if err != nil || len(rl.Descriptors) != 1 || rl.Descriptors[0].Digest != subjectDesc.Digest {
+		t.Fatalf("failed to create loop: %v, %v", rl.Descriptors, err)
+	}
+	expectCopied := len(rlOrig.Descriptors) + count + 1
+	tt := []struct {
+		name     string
+		parallel int
+	}{
+		{
+			name:     "default",
+			parallel: 0,
+		},
+		{
+			name:     "single worker",
+			parallel: 1,
+		},
+		{
+			name:     "more workers than referrers",
+			parallel: 20,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rTgt, err := ref.New(fmt.Sprintf("ocidir://%s/copy-%d:v3", tempDir, tc.parallel))
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+			defer cancel()
+			report, err := rc.ImageCopyWithReport(ctx, rSrc, rTgt, ImageWithReferrers(), ImageWithReferrerParallel(tc.parallel))
+			if err != nil {
+				t.Fatalf("failed to copy: %v", err)
+			}
+			if report.ReferrersCopied != expectCopied {
+				t.Errorf("unexpected referrers copied, expected %d, received %d", expectCopied, report.ReferrersCopied)
+			}
+			rl, err := rc.ReferrerList(ctx, rTgt)
+			if err != nil {
+				t.Fatalf("failed to list referrers: %v", err)
+			}
+			if len(rl.Descriptors) != len(rlOrig.Descriptors)+count {
+				t.Errorf("unexpected referrers on target, expected %d, received %d", len(rlOrig.Descriptors)+count, len(rl.Descriptors))
+			}
+			rl, err = rc.ReferrerList(ctx, rTgt.SetDigest(artifacts[0].Digest.String()))
+			if err != nil || len(rl.Descriptors) != 1 {
+				t.Errorf("nested referrer not copied: %v, %v", rl.Descriptors, err)
+			}
+		})
+	}
+}
+
+func TestCopySeekableVerify(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := New()
+	tempDir := t.TempDir()
+	// create an eStargz layer
+	tarBuf := &bytes.Buffer{}
+	tw := tar.NewWriter(tarBuf)
+	if err := tw.WriteHeader(&tar.Header{Name: "hello.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: 5}); err != nil {
+		t.Fatalf("failed to write header: %v", err)
+	}
+	if _, err := tw.Write([]byte("hello")); err != nil {
+		t.Fatalf("failed to write content: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar: %v", err)
+	}
+	layerBuf := &bytes.Buffer{}
+	info, err := archive.EStargz(layerBuf, tarBuf)
+	if err != nil {
+		t.Fatalf("failed to create eStargz layer: %v", err)
+	}
+	badAnnot := info.Annotations()
+	badAnnot[archive.AnnotationEStargzTOCDigest] = digest.FromString("invalid").String()
+	tt := []struct {
+		name        string
+		annotations map[string]string
+		expectErr   error
+	}{
+		{
+			name:        "valid",
+			annotations: info.Annotations(),
+		},
+		{
+			name:        "invalid",
+			annotations: badAnnot,
+			expectErr:   errs.ErrDigestMismatch,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rSrc, err := ref.New("ocidir://" + tempDir + "/src-" + tc.name + ":v1")
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			rTgt, err := ref.New("ocidir://" + tempDir + "/tgt-" + tc.name + ":v1")
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			imageTestPush(ctx, t, rc, rSrc, imageTestLayer{
+				desc: descriptor.Descriptor{
+					MediaType:   mediatype.OCI1LayerGzip,
+					Annotations: tc.annotations,
+				},
+				data:   layerBuf.Bytes(),
+				diffID: info.UncompressedDigest,
+			})
+			rUnverified, err := ref.New("ocidir://" + tempDir + "/unverified-" + tc.name + ":v1")
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			// copy without verification succeeds
+			err = rc.ImageCopy(ctx, rSrc, rUnverified)
+			if err != nil {
+				t.Fatalf("failed to copy: %v", err)
+			}
+			err = rc.ImageCopy(ctx, rSrc, rTgt, ImageWithSeekableVerify())
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to copy: %v", err)
+			}
+		})
+	}
+}
+
+func TestCopyExternal(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := New()
+	tempDir := t.TempDir()
+	rBase, err := ref.New("ocidir://./testdata/testrepo:v3")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rSrc, err := ref.New("ocidir://" + tempDir + "/src:ext")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	// create an image with a foreign layer
+	m, err := rc.ManifestGet(ctx, rBase, WithManifestPlatform(platform.Platform{OS: "linux", Architecture: "amd64"}))
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	err = rc.ImageCopy(ctx, rBase.SetDigest(m.GetDescriptor().Digest.String()), rSrc)
+	if err != nil {
+		t.Fatalf("failed to copy image: %v", err)
+	}
+	om, err := manifest.OCIManifestFromAny(m.GetOrig())
+	if err != nil || len(om.Layers) == 0 {
+		t.Fatalf("unexpected manifest: %v", err)
+	}
+	extLayer := om.Layers[0]
+	om.Layers[0].MediaType = mediatype.OCI1ForeignLayerGzip
+	om.Layers[0].URLs = []string{"https://registry.example.org/layer.tar.gz"}
+	mSrc, err := manifest.New(manifest.WithOrig(om))
+	if err != nil {
+		t.Fatalf("failed to create manifest: %v", err)
+	}
+	err = rc.ManifestPut(ctx, rSrc, mSrc)
+	if err != nil {
+		t.Fatalf("failed to put manifest: %v", err)
+	}
+	tt := []struct {
+		name       string
+		opts       []ImageOpts
+		expectErr  error
+		expectBlob bool
+	}{
+		{
+			name: "default",
+		},
+		{
+			name: "skip",
+			opts: []ImageOpts{ImageWithExternalPolicy(ExternalSkip)},
+		},
+		{
+			name:       "include",
+			opts:       []ImageOpts{ImageWithIncludeExternal()},
+			expectBlob: true,
+		},
+		{
+			name:       "copy allowed host",
+			opts:       []ImageOpts{ImageWithExternalPolicy(ExternalCopy, "registry.example.org")},
+			expectBlob: true,
+		},
+		{
+			name: "copy other host",
+			opts: []ImageOpts{ImageWithExternalPolicy(ExternalCopy, "mcr.microsoft.com")},
+		},
+		{
+			name:      "embed",
+			opts:      []ImageOpts{ImageWithExternalPolicy(ExternalEmbed)},
+			expectErr: errs.ErrUnsupported,
+		},
+	}
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rTgt, err := ref.New(fmt.Sprintf("ocidir://%s/tgt%d:ext", tempDir, i))
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			err = rc.ImageCopy(ctx, rSrc, rTgt, tc.opts...)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Errorf("copy did not fail")
+				} else if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to copy: %v", err)
+			}
+			mTgt, err := rc.ManifestHead(ctx, rTgt, WithManifestRequireDigest())
+			if err != nil {
+				t.Fatalf("failed to head manifest: %v", err)
+			}
+			if mTgt.GetDescriptor().Digest != mSrc.GetDescriptor().Digest {
+				t.Errorf("unexpected digest %s, expected %s", mTgt.GetDescriptor().Digest, mSrc.GetDescriptor().Digest)
+			}
+			_, err = rc.BlobHead(ctx, rTgt, extLayer)
+			if tc.expectBlob && err != nil {
+				t.Errorf("external layer was not copied: %v", err)
+			} else if !tc.expectBlob && err == nil {
+				t.Errorf("external layer was copied")
+			}
+		})
+	}
+}
+
 func TestExportImport(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
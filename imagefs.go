@@ -0,0 +1,529 @@
+package regclient
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ref"
+)
+
+const (
+	// imageFSMaxLinks limits the number of symlinks followed when resolving a path.
+	imageFSMaxLinks = 40
+	imageFSWhiteout = ".wh."
+	imageFSOpaque   = ".wh..wh..opq"
+)
+
+// ImageFS is a read-only [fs.FS] of the merged filesystem from the layers of an image.
+// Whiteouts are applied, and symlinks are resolved relative to the root of the image.
+// Layers are pulled on demand, from the top layer down, and only until a path is resolved.
+// The headers of each pulled layer are indexed once, and file contents are streamed from the layer up to the entry when a file is opened.
+// Range requests are not used, each opened file reads its layer from the start.
+// The [fs.FS] methods use a background context, the Context variants of each method can be used to cancel requests.
+// ImageFS also implements [fs.StatFS], [fs.ReadDirFS], and [fs.ReadLinkFS].
+type ImageFS struct {
+	rc     *RegClient
+	r      ref.Ref
+	layers []*imageFSLayer
+}
+
+// imageFSEntry is an entry in the merged filesystem.
+type imageFSEntry struct {
+	layer  int         // index of the layer containing the entry
+	data   string      // name of the tar entry with the content, differs from the path for hard links
+	header *tar.Header // header of the entry
+}
+
+// imageFSLayer is the index of the headers in a single layer.
+type imageFSLayer struct {
+	desc      descriptor.Descriptor
+	mu        sync.Mutex
+	loaded    bool
+	entries   map[string]*imageFSEntry // entries included in the layer
+	dirs      map[string]bool          // parent directories without an entry in the layer
+	children  map[string][]string      // sorted entries and parent directories within each directory
+	whiteouts map[string]bool          // paths deleted from lower layers
+	opaque    map[string]bool          // directories hiding the content of lower layers
+}
+
+// ImageFS returns a read-only filesystem for the merged layers of an image.
+// Use [ImageWithPlatform] to select a platform from an Index or Manifest List, defaulting to the local platform.
+// The context is only used to resolve the image manifest.
+func (rc *RegClient) ImageFS(ctx context.Context, r ref.Ref, opts ...ImageOpts) (*ImageFS, error) {
+	opt := imageOpt{
+		platform: "local",
+	}
+	for _, optFn := range opts {
+		optFn(&opt)
+	}
+	mi, err := rc.imageManifestPlatform(ctx, r, opt.platform)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := mi.GetLayers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get layers: %w", err)
+	}
+	ifs := &ImageFS{
+		rc:     rc,
+		r:      r,
+		layers: make([]*imageFSLayer, len(layers)),
+	}
+	for i, d := range layers {
+		ifs.layers[i] = &imageFSLayer{desc: d}
+	}
+	return ifs, nil
+}
+
+// Open opens the named file, following symlinks.
+func (ifs *ImageFS) Open(name string) (fs.File, error) {
+	return ifs.OpenContext(context.Background(), name)
+}
+
+// OpenContext opens the named file, following symlinks.
+func (ifs *ImageFS) OpenContext(ctx context.Context, name string) (fs.File, error) {
+	name, e, err := ifs.lookup(ctx, "open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if e.header.Typeflag == tar.TypeDir {
+		des, err := ifs.readDir(ctx, name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &imageFSDir{name: name, e: e, entries: des}, nil
+	}
+	if e.header.Typeflag != tar.TypeReg && e.header.Typeflag != tar.TypeLink {
+		return &imageFSFile{e: e, rdr: strings.NewReader("")}, nil
+	}
+	br, err := ifs.rc.BlobGet(ctx, ifs.r, ifs.layers[e.layer].desc)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("failed pulling layer %d: %w", e.layer, err)}
+	}
+	btr, err := br.ToTarReader()
+	if err != nil {
+		_ = br.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("could not convert layer %d to tar reader: %w", e.layer, err)}
+	}
+	_, rdr, err := btr.ReadFile(e.data)
+	if err != nil {
+		_ = btr.Close()
+		_ = br.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &imageFSFile{e: e, rdr: rdr, closeFn: func() error { return errors.Join(btr.Close(), br.Close()) }}, nil
+}
+
+// Stat returns the file info for the named file, following symlinks.
+func (ifs *ImageFS) Stat(name string) (fs.FileInfo, error) {
+	return ifs.StatContext(context.Background(), name)
+}
+
+// StatContext returns the file info for the named file, following symlinks.
+func (ifs *ImageFS) StatContext(ctx context.Context, name string) (fs.FileInfo, error) {
+	_, e, err := ifs.lookup(ctx, "stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return e.infoAs(name), nil
+}
+
+// Lstat returns the file info for the named file without following a final symlink.
+func (ifs *ImageFS) Lstat(name string) (fs.FileInfo, error) {
+	return ifs.LstatContext(context.Background(), name)
+}
+
+// LstatContext returns the file info for the named file without following a final symlink.
+func (ifs *ImageFS) LstatContext(ctx context.Context, name string) (fs.FileInfo, error) {
+	_, e, err := ifs.lookup(ctx, "lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return e.info(), nil
+}
+
+// ReadLink returns the target of the named symlink.
+func (ifs *ImageFS) ReadLink(name string) (string, error) {
+	return ifs.ReadLinkContext(context.Background(), name)
+}
+
+// ReadLinkContext returns the target of the named symlink.
+func (ifs *ImageFS) ReadLinkContext(ctx context.Context, name string) (string, error) {
+	_, e, err := ifs.lookup(ctx, "readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if e.header.Typeflag != tar.TypeSymlink {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return e.header.Linkname, nil
+}
+
+// ReadDir returns the entries of the named directory sorted by filename.
+func (ifs *ImageFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return ifs.ReadDirContext(context.Background(), name)
+}
+
+// ReadDirContext returns the entries of the named directory sorted by filename.
+func (ifs *ImageFS) ReadDirContext(ctx context.Context, name string) ([]fs.DirEntry, error) {
+	name, e, err := ifs.lookup(ctx, "readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if e.header.Typeflag != tar.TypeDir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	des, err := ifs.readDir(ctx, name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return des, nil
+}
+
+// readDir merges the children of a directory from each layer, stopping at the first layer that hides the lower content.
+func (ifs *ImageFS) readDir(ctx context.Context, name string) ([]fs.DirEntry, error) {
+	names := []string{}
+	for i := len(ifs.layers) - 1; i >= 0; i-- {
+		l, err := ifs.layer(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, l.children[name]...)
+		if hidden, _ := l.hidesChildren(name); hidden {
+			break
+		}
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+	des := make([]fs.DirEntry, 0, len(names))
+	for _, child := range names {
+		e, err := ifs.entry(ctx, child)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		des = append(des, fs.FileInfoToDirEntry(e.info()))
+	}
+	return des, nil
+}
+
+// lookup resolves a name to an entry, following symlinks in parent directories.
+func (ifs *ImageFS) lookup(ctx context.Context, op, name string, followLast bool) (string, *imageFSEntry, error) {
+	if !fs.ValidPath(name) {
+		return name, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	parts := imageFSSplit(name)
+	cur := "."
+	e, err := ifs.entry(ctx, cur)
+	if err != nil {
+		return name, nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	links := 0
+	for i := 0; i < len(parts); i++ {
+		next := path.Join(cur, parts[i])
+		e, err = ifs.entry(ctx, next)
+		if err != nil {
+			return name, nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		last := i == len(parts)-1
+		if e.header.Typeflag == tar.TypeSymlink && (!last || followLast) {
+			links++
+			if links > imageFSMaxLinks {
+				return name, nil, &fs.PathError{Op: op, Path: name, Err: errors.New("too many links")}
+			}
+			target := e.header.Linkname
+			if !path.IsAbs(target) {
+				target = path.Join(cur, target)
+			}
+			parts = append(imageFSSplit(imageFSClean(target)), parts[i+1:]...)
+			cur = "."
+			e, err = ifs.entry(ctx, cur)
+			if err != nil {
+				return name, nil, &fs.PathError{Op: op, Path: name, Err: err}
+			}
+			i = -1
+			continue
+		}
+		if !last && e.header.Typeflag != tar.TypeDir {
+			return name, nil, &fs.PathError{Op: op, Path: name, Err: errs.ErrFileNotFound}
+		}
+		cur = next
+	}
+	return cur, e, nil
+}
+
+// entry returns the merged entry for a path, searching from the top layer down.
+// The parent directories of the path must already be resolved to directories.
+func (ifs *ImageFS) entry(ctx context.Context, name string) (*imageFSEntry, error) {
+	if name == "." {
+		return &imageFSEntry{layer: -1, header: imageFSDirHeader(".")}, nil
+	}
+	// a parent directory created by an upper layer is replaced by a lower directory entry
+	var implicit *imageFSEntry
+	for i := len(ifs.layers) - 1; i >= 0; i-- {
+		l, err := ifs.layer(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		if e, ok := l.entries[name]; ok {
+			if implicit != nil && e.header.Typeflag != tar.TypeDir {
+				return implicit, nil
+			}
+			return e, nil
+		}
+		if implicit == nil && l.dirs[name] {
+			implicit = &imageFSEntry{layer: i, header: imageFSDirHeader(name)}
+		}
+		if hidden, deleted := l.hides(name); hidden {
+			if implicit != nil {
+				return implicit, nil
+			}
+			if deleted {
+				return nil, fmt.Errorf("deleted in layer %d%.0w%.0w", i, errs.ErrFileDeleted, fs.ErrNotExist)
+			}
+			return nil, errs.ErrFileNotFound
+		}
+	}
+	if implicit != nil {
+		return implicit, nil
+	}
+	return nil, errs.ErrFileNotFound
+}
+
+// layer returns the index of a layer, pulling the layer on first use.
+func (ifs *ImageFS) layer(ctx context.Context, i int) (*imageFSLayer, error) {
+	l := ifs.layers[i]
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.loaded {
+		err := l.index(ctx, ifs.rc, ifs.r, i)
+		if err != nil {
+			return nil, fmt.Errorf("failed to index layer %d: %w", i, err)
+		}
+		l.loaded = true
+	}
+	return l, nil
+}
+
+// index reads the headers from the layer, i is the index of the layer in the image.
+func (l *imageFSLayer) index(ctx context.Context, rc *RegClient, r ref.Ref, i int) error {
+	br, err := rc.BlobGet(ctx, r, l.desc)
+	if err != nil {
+		return err
+	}
+	defer br.Close()
+	btr, err := br.ToTarReader()
+	if err != nil {
+		return err
+	}
+	defer btr.Close()
+	tr, err := btr.GetTarReader()
+	if err != nil {
+		return err
+	}
+	entries := map[string]*imageFSEntry{}
+	whiteouts := map[string]bool{}
+	opaque := map[string]bool{}
+	for {
+		th, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		name := imageFSClean(th.Name)
+		if name == "." {
+			continue
+		}
+		dir, base := path.Split(name)
+		dir = imageFSClean(dir)
+		if base == imageFSOpaque {
+			opaque[dir] = true
+			continue
+		} else if strings.HasPrefix(base, imageFSWhiteout) {
+			whiteouts[path.Join(dir, strings.TrimPrefix(base, imageFSWhiteout))] = true
+			continue
+		}
+		e := &imageFSEntry{layer: i, data: name, header: th}
+		if th.Typeflag == tar.TypeLink {
+			// hard links share the content of an earlier entry in the same layer
+			e.data = imageFSClean(th.Linkname)
+			if target, ok := entries[e.data]; ok {
+				hdr := *target.header
+				hdr.Name = th.Name
+				e.header = &hdr
+				e.data = target.data
+			}
+		}
+		entries[name] = e
+	}
+	dirs := map[string]bool{}
+	children := map[string][]string{}
+	for name := range entries {
+		children[path.Dir(name)] = append(children[path.Dir(name)], name)
+		// track any missing parent directories
+		for dir := path.Dir(name); dir != "." && !dirs[dir]; dir = path.Dir(dir) {
+			if _, ok := entries[dir]; ok {
+				break
+			}
+			dirs[dir] = true
+			children[path.Dir(dir)] = append(children[path.Dir(dir)], dir)
+		}
+	}
+	for dir := range children {
+		slices.Sort(children[dir])
+	}
+	l.entries, l.dirs, l.children, l.whiteouts, l.opaque = entries, dirs, children, whiteouts, opaque
+	return nil
+}
+
+// hides reports if the layer hides a path in lower layers, and if the path was deleted with a whiteout.
+func (l *imageFSLayer) hides(name string) (bool, bool) {
+	if l.whiteouts[name] {
+		return true, true
+	}
+	return l.hidesChildren(path.Dir(name))
+}
+
+// hidesChildren reports if the layer hides the content of a directory in lower layers,
+// and if the directory or a parent was deleted with a whiteout.
+func (l *imageFSLayer) hidesChildren(dir string) (bool, bool) {
+	for {
+		if l.whiteouts[dir] {
+			return true, true
+		}
+		if l.opaque[dir] {
+			return true, false
+		}
+		if e, ok := l.entries[dir]; ok && e.header.Typeflag != tar.TypeDir {
+			return true, false
+		}
+		if dir == "." {
+			return false, false
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+func (e *imageFSEntry) info() fs.FileInfo {
+	return e.header.FileInfo()
+}
+
+// imageFSInfo reports the name used to access a file when a symlink was followed.
+type imageFSInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (fi imageFSInfo) Name() string {
+	return fi.name
+}
+
+// infoAs returns the file info using the base of the requested name.
+func (e *imageFSEntry) infoAs(name string) fs.FileInfo {
+	fi := e.info()
+	if base := path.Base(name); base != fi.Name() {
+		return imageFSInfo{FileInfo: fi, name: base}
+	}
+	return fi
+}
+
+// imageFSFile is a file opened from an [ImageFS].
+type imageFSFile struct {
+	e       *imageFSEntry
+	rdr     io.Reader
+	closeFn func() error
+}
+
+func (f *imageFSFile) Stat() (fs.FileInfo, error) {
+	return f.e.info(), nil
+}
+
+func (f *imageFSFile) Read(b []byte) (int, error) {
+	return f.rdr.Read(b)
+}
+
+func (f *imageFSFile) Close() error {
+	if f.closeFn == nil {
+		return nil
+	}
+	closeFn := f.closeFn
+	f.closeFn = nil
+	return closeFn()
+}
+
+// imageFSDir is a directory opened from an [ImageFS].
+type imageFSDir struct {
+	name    string
+	e       *imageFSEntry
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *imageFSDir) Stat() (fs.FileInfo, error) {
+	return d.e.info(), nil
+}
+
+func (d *imageFSDir) Read(b []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *imageFSDir) Close() error {
+	return nil
+}
+
+func (d *imageFSDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remain := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remain, nil
+	}
+	if len(remain) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(remain))
+	d.offset += n
+	return remain[:n], nil
+}
+
+func imageFSClean(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+func imageFSSplit(name string) []string {
+	if name == "" || name == "." {
+		return []string{}
+	}
+	return strings.Split(name, "/")
+}
+
+func imageFSDirHeader(name string) *tar.Header {
+	return &tar.Header{
+		Name:     name + "/",
+		Typeflag: tar.TypeDir,
+		Mode:     0o755,
+		ModTime:  time.Unix(0, 0),
+	}
+}
+
+var _ interface {
+	fs.ReadDirFS
+	fs.ReadLinkFS
+	fs.StatFS
+} = (*ImageFS)(nil)
@@ -0,0 +1,207 @@
+package regclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/mediatype"
+	v1 "github.com/regclient/regclient/types/oci/v1"
+	"github.com/regclient/regclient/types/platform"
+	"github.com/regclient/regclient/types/ref"
+)
+
+func TestImageFS(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := New()
+
+	t.Run("testrepo", func(t *testing.T) {
+		r, err := ref.New("ocidir://./testdata/testrepo:v3")
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		ifs, err := rc.ImageFS(ctx, r, ImageWithPlatform("linux/amd64"))
+		if err != nil {
+			t.Fatalf("failed to create image fs: %v", err)
+		}
+		err = fstest.TestFS(ifs, "base.txt", "layer1", "layer2", "layer3", "dir/layer.tar")
+		if err != nil {
+			t.Errorf("image fs failed: %v", err)
+		}
+		b, err := fs.ReadFile(ifs, "layer2")
+		if err != nil {
+			t.Fatalf("failed to read file: %v", err)
+		}
+		if string(b) != "2\n" {
+			t.Errorf("unexpected content: %q", b)
+		}
+	})
+
+	t.Run("whiteout and links", func(t *testing.T) {
+		tempDir := t.TempDir()
+		r, err := ref.New("ocidir://" + tempDir + "/testrepo:fs")
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		lower := imageFSTestLayer(t, []*tar.Header{
+			{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0o755},
+			{Name: "etc/deleted", Typeflag: tar.TypeReg, Mode: 0o644, Size: 5},
+			{Name: "etc/kept", Typeflag: tar.TypeReg, Mode: 0o644, Size: 5},
+			{Name: "opq/lower", Typeflag: tar.TypeReg, Mode: 0o644, Size: 5},
+			{Name: "usr/lib/os-release", Typeflag: tar.TypeReg, Mode: 0o644, Size: 5},
+		}, "lower")
+		upper := imageFSTestLayer(t, []*tar.Header{
+			{Name: "etc/.wh.deleted", Typeflag: tar.TypeReg, Mode: 0o644},
+			{Name: "etc/os-release", Typeflag: tar.TypeSymlink, Linkname: "../usr/lib/os-release"},
+			{Name: "lib", Typeflag: tar.TypeSymlink, Linkname: "/usr/lib/"},
+			{Name: "opq/upper", Typeflag: tar.TypeReg, Mode: 0o644, Size: 5},
+			{Name: "opq/.wh..wh..opq", Typeflag: tar.TypeReg, Mode: 0o644},
+			{Name: "opq/hard", Typeflag: tar.TypeLink, Linkname: "opq/upper"},
+		}, "upper")
+		imageTestPush(ctx, t, rc, r, imageTestLayer{data: lower}, imageTestLayer{data: upper})
+
+		ifs, err := rc.ImageFS(ctx, r)
+		if err != nil {
+			t.Fatalf("failed to create image fs: %v", err)
+		}
+		err = fstest.TestFS(ifs, "etc/kept", "etc/os-release", "opq/upper", "opq/hard", "usr/lib/os-release")
+		if err != nil {
+			t.Errorf("image fs failed: %v", err)
+		}
+		for _, name := range []string{"etc/deleted", "opq/lower"} {
+			if _, err := ifs.Stat(name); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("file was not removed %s: %v", name, err)
+			}
+		}
+		for name, expect := range map[string]string{
+			"etc/kept":       "lower",
+			"etc/os-release": "lower",
+			"lib/os-release": "lower",
+			"opq/hard":       "upper",
+		} {
+			b, err := fs.ReadFile(ifs, name)
+			if err != nil {
+				t.Errorf("failed to read %s: %v", name, err)
+			} else if string(b) != expect {
+				t.Errorf("unexpected content for %s: %q", name, b)
+			}
+		}
+		link, err := ifs.ReadLink("lib")
+		if err != nil || link != "/usr/lib/" {
+			t.Errorf("unexpected link: %s, %v", link, err)
+		}
+		fi, err := ifs.Lstat("etc/os-release")
+		if err != nil || fi.Mode()&fs.ModeSymlink == 0 {
+			t.Errorf("lstat did not return a symlink: %v", err)
+		}
+		fi, err = ifs.Stat("lib")
+		if err != nil || !fi.IsDir() || fi.Name() != "lib" {
+			t.Errorf("stat did not follow the symlink: %v", err)
+		}
+		if _, err := ifs.Stat("etc/deleted"); !errors.Is(err, errs.ErrFileDeleted) {
+			t.Errorf("deleted file did not return ErrFileDeleted: %v", err)
+		}
+
+		// layers are only pulled when needed to resolve a path
+		ifs, err = rc.ImageFS(ctx, r)
+		if err != nil {
+			t.Fatalf("failed to create image fs: %v", err)
+		}
+		if _, err := ifs.Lstat("lib"); err != nil {
+			t.Errorf("failed to lstat: %v", err)
+		}
+		if ifs.layers[0].loaded || !ifs.layers[1].loaded {
+			t.Errorf("unexpected layers loaded, lower %t, upper %t", ifs.layers[0].loaded, ifs.layers[1].loaded)
+		}
+	})
+}
+
+// imageFSTestLayer creates an uncompressed layer, using content for each regular file with a size.
+func imageFSTestLayer(t *testing.T, headers []*tar.Header, content string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, th := range headers {
+		if err := tw.WriteHeader(th); err != nil {
+			t.Fatalf("failed to write header: %v", err)
+		}
+		if th.Size > 0 {
+			if _, err := tw.Write([]byte(content)); err != nil {
+				t.Fatalf("failed to write content: %v", err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// imageTestLayer is a layer pushed by imageTestPush.
+// The descriptor digest and size are set from the data, and the media type defaults to an uncompressed OCI layer.
+type imageTestLayer struct {
+	desc   descriptor.Descriptor
+	data   []byte
+	diffID digest.Digest // defaults to the digest of data
+}
+
+// imageTestPush pushes an image with the provided layers.
+func imageTestPush(ctx context.Context, t *testing.T, rc *RegClient, r ref.Ref, layers ...imageTestLayer) {
+	t.Helper()
+	conf := v1.Image{
+		Platform: platform.Platform{OS: "linux", Architecture: "amd64"},
+		RootFS:   v1.RootFS{Type: "layers"},
+	}
+	descLayers := []descriptor.Descriptor{}
+	for _, l := range layers {
+		d := l.desc
+		if d.MediaType == "" {
+			d.MediaType = mediatype.OCI1Layer
+		}
+		d.Digest = digest.FromBytes(l.data)
+		d.Size = int64(len(l.data))
+		if _, err := rc.BlobPut(ctx, r, d, bytes.NewReader(l.data)); err != nil {
+			t.Fatalf("failed to push layer: %v", err)
+		}
+		descLayers = append(descLayers, d)
+		if l.diffID == "" {
+			l.diffID = d.Digest
+		}
+		conf.RootFS.DiffIDs = append(conf.RootFS.DiffIDs, l.diffID)
+	}
+	confBytes, err := json.Marshal(conf)
+	if err != nil {
+		t.Fatalf("failed to marshal config: %v", err)
+	}
+	descConf := descriptor.Descriptor{
+		MediaType: mediatype.OCI1ImageConfig,
+		Digest:    digest.FromBytes(confBytes),
+		Size:      int64(len(confBytes)),
+	}
+	if _, err := rc.BlobPut(ctx, r, descConf, bytes.NewReader(confBytes)); err != nil {
+		t.Fatalf("failed to push config: %v", err)
+	}
+	m, err := manifest.New(manifest.WithOrig(v1.Manifest{
+		Versioned: v1.ManifestSchemaVersion,
+		MediaType: mediatype.OCI1Manifest,
+		Config:    descConf,
+		Layers:    descLayers,
+	}))
+	if err != nil {
+		t.Fatalf("failed to create manifest: %v", err)
+	}
+	if err := rc.ManifestPut(ctx, r, m); err != nil {
+		t.Fatalf("failed to push manifest: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package regclient
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/platform"
+	"github.com/regclient/regclient/types/ref"
+)
+
+const (
+	dockerReferenceType            = "vnd.docker.reference.type"
+	dockerReferenceDigest          = "vnd.docker.reference.digest"
+	dockerReferenceTypeAttestation = "attestation-manifest"
+)
+
+type indexNormalizeOpt struct {
+	dropUnknown    bool
+	dryRun         bool
+	sortAnnotation string
+}
+
+// IndexNormalizeOpts define options for [RegClient.ManifestIndexNormalize].
+type IndexNormalizeOpts func(*indexNormalizeOpt)
+
+// IndexNormalizeWithDropUnknown removes entries without a platform, with an unknown platform, and attestations.
+func IndexNormalizeWithDropUnknown() IndexNormalizeOpts {
+	return func(opts *indexNormalizeOpt) {
+		opts.dropUnknown = true
+	}
+}
+
+// IndexNormalizeWithDryRun reports the changes without pushing the normalized index.
+func IndexNormalizeWithDryRun() IndexNormalizeOpts {
+	return func(opts *indexNormalizeOpt) {
+		opts.dryRun = true
+	}
+}
+
+// IndexNormalizeWithSortAnnotation sorts the entries by an annotation before the platform and digest.
+func IndexNormalizeWithSortAnnotation(name string) IndexNormalizeOpts {
+	return func(opts *indexNormalizeOpt) {
+		opts.sortAnnotation = name
+	}
+}
+
+// IndexRemoved is an entry removed from an index by [RegClient.ManifestIndexNormalize].
+type IndexRemoved struct {
+	Descriptor descriptor.Descriptor `json:"descriptor"`
+	Reason     string                `json:"reason"`
+}
+
+// IndexNormalizeResult reports the changes made by [RegClient.ManifestIndexNormalize].
+type IndexNormalizeResult struct {
+	Manifest manifest.Manifest `json:"manifest"` // normalized index
+	Removed  []IndexRemoved    `json:"removed"`  // entries removed from the index
+	Sorted   bool              `json:"sorted"`   // true when the order of the remaining entries changed
+	Changed  bool              `json:"changed"`  // true when the index was modified
+	Pushed   bool              `json:"pushed"`   // true when the modified index was pushed
+}
+
+// ManifestIndexNormalize removes duplicate entries from an index and sorts the remaining entries with [descriptor.DescriptorListSort].
+// Entries with the same digest keep the first occurrence.
+// Entries with the same platform and artifact type keep the last occurrence, which is the most recently added entry.
+// Attestations for a removed entry are also removed.
+// The index is pushed to r when it changes, unless [IndexNormalizeWithDryRun] is set.
+func (rc *RegClient) ManifestIndexNormalize(ctx context.Context, r ref.Ref, opts ...IndexNormalizeOpts) (IndexNormalizeResult, error) {
+	var opt indexNormalizeOpt
+	for _, optFn := range opts {
+		optFn(&opt)
+	}
+	result := IndexNormalizeResult{Removed: []IndexRemoved{}}
+	m, err := rc.ManifestGet(ctx, r)
+	if err != nil {
+		return result, err
+	}
+	mi, ok := m.(manifest.Indexer)
+	if !ok || !m.IsList() {
+		return result, fmt.Errorf("manifest is not an index, %s%.0w", m.GetDescriptor().MediaType, errs.ErrUnsupportedMediaType)
+	}
+	dl, err := mi.GetManifestList()
+	if err != nil {
+		return result, err
+	}
+	result.Manifest = m
+	keep, removed := indexNormalize(dl, opt.dropUnknown)
+	result.Removed = removed
+	sorted := descriptor.DescriptorListSort(keep, descriptor.MatchOpt{SortAnnotation: opt.sortAnnotation})
+	result.Sorted = !slices.EqualFunc(keep, sorted, func(a, b descriptor.Descriptor) bool { return a.Equal(b) })
+	result.Changed = result.Sorted || len(removed) > 0
+	if !result.Changed {
+		return result, nil
+	}
+	err = mi.SetManifestList(sorted)
+	if err != nil {
+		return result, err
+	}
+	if opt.dryRun {
+		return result, nil
+	}
+	if r.Digest != "" {
+		r = r.SetDigest(m.GetDescriptor().Digest.String())
+	}
+	err = rc.ManifestPut(ctx, r, m)
+	if err != nil {
+		return result, err
+	}
+	result.Pushed = true
+	return result, nil
+}
+
+// indexNormalize returns the entries to keep and the removed entries.
+func indexNormalize(dl []descriptor.Descriptor, dropUnknown bool) ([]descriptor.Descriptor, []IndexRemoved) {
+	removed := []IndexRemoved{}
+	drop := make([]string, len(dl))
+	for i, d := range dl {
+		switch {
+		case dropUnknown && (d.Platform == nil || d.Platform.OS == "" || d.Platform.OS == "unknown"):
+			drop[i] = "unknown platform"
+		case dropUnknown && d.Annotations[dockerReferenceType] == dockerReferenceTypeAttestation:
+			drop[i] = "attestation"
+		case slices.ContainsFunc(dl[:i], func(prev descriptor.Descriptor) bool { return prev.Digest == d.Digest }):
+			drop[i] = "duplicate digest"
+		}
+	}
+	// remove earlier entries for the same platform
+	for i, d := range dl {
+		if drop[i] != "" || d.Platform == nil || d.Platform.OS == "unknown" || d.Annotations[dockerReferenceType] == dockerReferenceTypeAttestation {
+			continue
+		}
+		for j := i + 1; j < len(dl); j++ {
+			later := dl[j]
+			if drop[j] == "" && later.Platform != nil && later.ArtifactType == d.ArtifactType && platform.Match(*d.Platform, *later.Platform) {
+				drop[i] = "duplicate platform"
+				break
+			}
+		}
+	}
+	// remove attestations for removed entries that are not also kept with another entry
+	for i, d := range dl {
+		if drop[i] != "" || d.Annotations[dockerReferenceType] != dockerReferenceTypeAttestation {
+			continue
+		}
+		subject := d.Annotations[dockerReferenceDigest]
+		kept := false
+		for j, other := range dl {
+			if drop[j] == "" && other.Digest.String() == subject {
+				kept = true
+				break
+			}
+		}
+		if !kept && slices.ContainsFunc(dl, func(other descriptor.Descriptor) bool { return other.Digest.String() == subject }) {
+			drop[i] = "attestation for a removed entry"
+		}
+	}
+	keep := []descriptor.Descriptor{}
+	for i, d := range dl {
+		if drop[i] != "" {
+			removed = append(removed, IndexRemoved{Descriptor: d, Reason: drop[i]})
+		} else {
+			keep = append(keep, d)
+		}
+	}
+	return keep, removed
+}
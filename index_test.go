@@ -0,0 +1,146 @@
+package regclient
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/mediatype"
+	"github.com/regclient/regclient/types/platform"
+	"github.com/regclient/regclient/types/ref"
+)
+
+func TestManifestIndexNormalize(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	rc := New()
+	rSrc, err := ref.New("ocidir://./testdata/testrepo:v2")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rTgt, err := ref.New("ocidir://" + tempDir + "/testrepo:v2")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	err = rc.ImageCopy(ctx, rSrc, rTgt)
+	if err != nil {
+		t.Fatalf("failed to copy image: %v", err)
+	}
+	m, err := rc.ManifestGet(ctx, rTgt)
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	mi := m.(manifest.Indexer)
+	dl, err := mi.GetManifestList()
+	if err != nil {
+		t.Fatalf("failed to get manifest list: %v", err)
+	}
+	dAMD64, err := descriptor.DescriptorListSearch(dl, descriptor.MatchOpt{Platform: &platform.Platform{OS: "linux", Architecture: "amd64"}})
+	if err != nil {
+		t.Fatalf("failed to find amd64: %v", err)
+	}
+	dARM64, err := descriptor.DescriptorListSearch(dl, descriptor.MatchOpt{Platform: &platform.Platform{OS: "linux", Architecture: "arm64"}})
+	if err != nil {
+		t.Fatalf("failed to find arm64: %v", err)
+	}
+	dARMv7, err := descriptor.DescriptorListSearch(dl, descriptor.MatchOpt{Platform: &platform.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}})
+	if err != nil {
+		t.Fatalf("failed to find arm/v7: %v", err)
+	}
+	// a second submission of arm64 with a different digest
+	dARM64New := dARM64
+	dARM64New.Digest = digest.FromString("rebuilt arm64")
+	dAttest := descriptor.Descriptor{
+		MediaType: mediatype.OCI1Manifest,
+		Digest:    digest.FromString("arm64 attestation"),
+		Size:      1234,
+		Platform:  &platform.Platform{OS: "unknown", Architecture: "unknown"},
+		Annotations: map[string]string{
+			dockerReferenceType:   dockerReferenceTypeAttestation,
+			dockerReferenceDigest: dARM64.Digest.String(),
+		},
+	}
+	dAttestNew := dAttest
+	dAttestNew.Digest = digest.FromString("rebuilt arm64 attestation")
+	dAttestNew.Annotations = map[string]string{
+		dockerReferenceType:   dockerReferenceTypeAttestation,
+		dockerReferenceDigest: dARM64New.Digest.String(),
+	}
+	err = mi.SetManifestList([]descriptor.Descriptor{dARM64, dAttest, dARMv7, dAMD64, dAMD64, dARM64New, dAttestNew})
+	if err != nil {
+		t.Fatalf("failed to set manifest list: %v", err)
+	}
+	err = rc.ManifestPut(ctx, rTgt, m)
+	if err != nil {
+		t.Fatalf("failed to put manifest: %v", err)
+	}
+
+	t.Run("not an index", func(t *testing.T) {
+		_, err := rc.ManifestIndexNormalize(ctx, rTgt.SetDigest(dAMD64.Digest.String()))
+		if !errors.Is(err, errs.ErrUnsupportedMediaType) {
+			t.Errorf("unexpected error, expected %v, received %v", errs.ErrUnsupportedMediaType, err)
+		}
+	})
+	t.Run("dry run", func(t *testing.T) {
+		result, err := rc.ManifestIndexNormalize(ctx, rTgt, IndexNormalizeWithDryRun(), IndexNormalizeWithDropUnknown())
+		if err != nil {
+			t.Fatalf("failed to normalize: %v", err)
+		}
+		if !result.Changed || result.Pushed || len(result.Removed) != 4 {
+			t.Errorf("unexpected result: %v", result)
+		}
+		mCur, err := rc.ManifestHead(ctx, rTgt, WithManifestRequireDigest())
+		if err != nil {
+			t.Fatalf("failed to head manifest: %v", err)
+		}
+		if mCur.GetDescriptor().Digest != m.GetDescriptor().Digest {
+			t.Errorf("manifest was modified on a dry run")
+		}
+	})
+	t.Run("normalize", func(t *testing.T) {
+		result, err := rc.ManifestIndexNormalize(ctx, rTgt)
+		if err != nil {
+			t.Fatalf("failed to normalize: %v", err)
+		}
+		if !result.Changed || !result.Pushed || !result.Sorted {
+			t.Errorf("unexpected result: %v", result)
+		}
+		expectRemoved := []IndexRemoved{
+			{Descriptor: dARM64, Reason: "duplicate platform"},
+			{Descriptor: dAttest, Reason: "attestation for a removed entry"},
+			{Descriptor: dAMD64, Reason: "duplicate digest"},
+		}
+		if !slices.EqualFunc(expectRemoved, result.Removed, func(a, b IndexRemoved) bool {
+			return a.Reason == b.Reason && a.Descriptor.Equal(b.Descriptor)
+		}) {
+			t.Errorf("unexpected removed entries, expected %v, received %v", expectRemoved, result.Removed)
+		}
+		mCur, err := rc.ManifestGet(ctx, rTgt)
+		if err != nil {
+			t.Fatalf("failed to get manifest: %v", err)
+		}
+		dlCur, err := mCur.(manifest.Indexer).GetManifestList()
+		if err != nil {
+			t.Fatalf("failed to get manifest list: %v", err)
+		}
+		expect := []descriptor.Descriptor{dAMD64, dARMv7, dARM64New, dAttestNew}
+		if !slices.EqualFunc(expect, dlCur, func(a, b descriptor.Descriptor) bool { return a.Equal(b) }) {
+			t.Errorf("unexpected index entries, expected %v, received %v", expect, dlCur)
+		}
+		// a normalized index is unchanged
+		result, err = rc.ManifestIndexNormalize(ctx, rTgt)
+		if err != nil {
+			t.Fatalf("failed to normalize: %v", err)
+		}
+		if result.Changed || result.Pushed || len(result.Removed) > 0 {
+			t.Errorf("normalized index was changed: %v", result)
+		}
+	})
+}
@@ -8,6 +8,16 @@ import (
 	"golang.org/x/term"
 )
 
+// IsReaderTerminal returns true if the reader is an interactive terminal.
+func IsReaderTerminal(r io.Reader) bool {
+	rFd, ok := r.(interface{ Fd() uintptr })
+	if !ok {
+		return false
+	}
+	//#nosec G115 false positive
+	return rFd.Fd() <= math.MaxInt && term.IsTerminal(int(rFd.Fd()))
+}
+
 func IsWriterTerminal(w io.Writer) bool {
 	wFd, ok := w.(interface{ Fd() uintptr })
 	if !ok {
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ping"
 )
 
 type charLU byte
@@ -283,6 +284,29 @@ func (a *Auth) UpdateRequest(req *http.Request) error {
 	return nil
 }
 
+// Info returns details of the auth for a host from previous requests.
+// False is returned when the host has not requested auth.
+func (a *Auth) Info(host string) (ping.Auth, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.hs[host] == nil {
+		return ping.Auth{}, false
+	}
+	for _, at := range a.authTypes {
+		switch h := a.hs[host][at].(type) {
+		case nil:
+			continue
+		case *basicHandler:
+			return ping.Auth{Type: at, User: h.credsFn(h.host).User}, true
+		case *bearerHandler:
+			return h.info(), true
+		default:
+			return ping.Auth{Type: at}, true
+		}
+	}
+	return ping.Auth{}, false
+}
+
 func (a *Auth) addDefaultHandlers() {
 	if _, ok := a.hbs["basic"]; !ok {
 		a.hbs["basic"] = NewBasicHandler
@@ -675,6 +699,44 @@ func (b *bearerHandler) isExpired() bool {
 	return time.Now().After(expireSec)
 }
 
+// jwtClaims are the claims used by registry token servers.
+type jwtClaims struct {
+	Subject string            `json:"sub"`
+	Expires int64             `json:"exp"`
+	Access  []ping.AuthAccess `json:"access"`
+}
+
+// info returns details of the current token.
+func (b *bearerHandler) info() ping.Auth {
+	info := ping.Auth{
+		Type:   "bearer",
+		User:   b.credsFn(b.host).User,
+		Scopes: slices.Clone(b.scopes),
+	}
+	if !b.token.IssuedAt.IsZero() && b.token.ExpiresIn > 0 {
+		info.Expires = b.token.IssuedAt.Add(time.Duration(b.token.ExpiresIn) * time.Second)
+	}
+	// tokens are not verified, the claims are only used to report the access granted by the registry
+	parts := strings.Split(b.token.Token, ".")
+	if len(parts) != 3 {
+		return info
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return info
+	}
+	claims := jwtClaims{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return info
+	}
+	info.Subject = claims.Subject
+	info.Access = claims.Access
+	if claims.Expires > 0 {
+		info.Expires = time.Unix(claims.Expires, 0)
+	}
+	return info
+}
+
 // tryGet requests a new token with a GET request
 func (b *bearerHandler) tryGet(cred Cred) error {
 	//#nosec G704 inputs follow specification
@@ -0,0 +1,119 @@
+// Package bwlimit limits the bandwidth of readers
+package bwlimit
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/regclient/regclient/internal/units"
+)
+
+// maxChunk is the largest read passed through before waiting on the limiter.
+const maxChunk = 32 * 1024
+
+// Limiter restricts the combined rate of all readers sharing it.
+// A nil Limiter does not limit reads.
+type Limiter struct {
+	rate int64 // bytes per second
+	mu   sync.Mutex
+	next time.Time
+}
+
+// New returns a Limiter for the rate in bytes per second.
+// A nil Limiter is returned when rate is not positive.
+func New(rate int64) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &Limiter{rate: rate}
+}
+
+// Parse returns a Limiter from a human-readable rate (eg. "50MiB/s" or "10MB").
+// An empty string returns a nil Limiter.
+func Parse(rate string) (*Limiter, error) {
+	rate = strings.TrimSpace(rate)
+	if rate == "" {
+		return nil, nil
+	}
+	size, err := units.ParseSize(strings.TrimSuffix(rate, "/s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid bandwidth limit %q: %w", rate, err)
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("bandwidth limit must be greater than zero: %q", rate)
+	}
+	return New(size), nil
+}
+
+// Rate returns the limit in bytes per second.
+func (l *Limiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	return l.rate
+}
+
+// wait blocks until the n bytes already read are within the rate.
+func (l *Limiter) wait(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(n) * time.Second / time.Duration(l.rate))
+	delay := l.next.Sub(now)
+	l.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// chunk returns the largest read for the limiter, allowing readers sharing a limiter to interleave.
+func (l *Limiter) chunk() int {
+	if l == nil {
+		return maxChunk
+	}
+	return int(max(min(l.rate/10, maxChunk), 1))
+}
+
+type reader struct {
+	rdr      io.Reader
+	limiters []*Limiter
+	chunk    int
+}
+
+// Reader wraps rdr so reads do not exceed the rate of any of the limiters.
+// Nil limiters are ignored, and rdr is returned unchanged when there are no limiters.
+func Reader(rdr io.Reader, limiters ...*Limiter) io.Reader {
+	lr := reader{
+		rdr:   rdr,
+		chunk: maxChunk,
+	}
+	for _, l := range limiters {
+		if l != nil {
+			lr.limiters = append(lr.limiters, l)
+			lr.chunk = min(lr.chunk, l.chunk())
+		}
+	}
+	if len(lr.limiters) == 0 {
+		return rdr
+	}
+	return &lr
+}
+
+func (lr *reader) Read(p []byte) (int, error) {
+	if len(p) > lr.chunk {
+		p = p[:lr.chunk]
+	}
+	n, err := lr.rdr.Read(p)
+	for _, l := range lr.limiters {
+		l.wait(n)
+	}
+	return n, err
+}
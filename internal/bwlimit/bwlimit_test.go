@@ -0,0 +1,107 @@
+package bwlimit
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	tt := []struct {
+		name      string
+		rate      string
+		expect    int64
+		expectErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:   "per second",
+			rate:   "50MiB/s",
+			expect: 50 * 1024 * 1024,
+		},
+		{
+			name:   "no suffix",
+			rate:   "10kB",
+			expect: 10000,
+		},
+		{
+			name:      "zero",
+			rate:      "0/s",
+			expectErr: true,
+		},
+		{
+			name:      "invalid",
+			rate:      "fast",
+			expectErr: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := Parse(tc.rate)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("parse did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if l.Rate() != tc.expect {
+				t.Errorf("unexpected rate, expected %d, received %d", tc.expect, l.Rate())
+			}
+		})
+	}
+}
+
+func TestReader(t *testing.T) {
+	t.Parallel()
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	t.Run("unlimited", func(t *testing.T) {
+		t.Parallel()
+		rdr := bytes.NewReader(data)
+		if Reader(rdr, nil) != io.Reader(rdr) {
+			t.Errorf("reader was wrapped without a limiter")
+		}
+	})
+	t.Run("single", func(t *testing.T) {
+		t.Parallel()
+		// 10k at 50k/s should take at least 200ms
+		l := New(50000)
+		start := time.Now()
+		out, err := io.ReadAll(Reader(bytes.NewReader(data), l))
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if !bytes.Equal(out, data) {
+			t.Errorf("data mismatch")
+		}
+		if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+			t.Errorf("read was not limited, took %s", elapsed)
+		}
+	})
+	t.Run("shared", func(t *testing.T) {
+		t.Parallel()
+		// two readers sharing a 100k/s limit take as long as a single reader at 50k/s
+		l := New(100000)
+		start := time.Now()
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Go(func() {
+				_, err := io.Copy(io.Discard, Reader(bytes.NewReader(data), l, New(1000000)))
+				if err != nil {
+					t.Errorf("failed to read: %v", err)
+				}
+			})
+		}
+		wg.Wait()
+		if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+			t.Errorf("shared read was not limited, took %s", elapsed)
+		}
+	})
+}
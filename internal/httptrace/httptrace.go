@@ -0,0 +1,268 @@
+// Package httptrace logs http requests and responses for debugging registry behavior.
+// Credentials are redacted from the logged headers, URLs, and bodies.
+package httptrace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const redacted = "[redacted]"
+
+var (
+	// requestIDHeaders are the response headers logged as the request ID, checked in order
+	requestIDHeaders = []string{
+		"X-Request-Id",
+		"Docker-Request-Id",
+		"X-Github-Request-Id",
+		"X-Amzn-Requestid",
+		"X-Ms-Request-Id",
+	}
+	// redactHeaders are replaced in the logged headers
+	redactHeaders = []string{
+		"Authorization",
+		"Cookie",
+		"Proxy-Authorization",
+		"Set-Cookie",
+		"X-Amz-Security-Token",
+	}
+	// redactFields are replaced in logged query parameters, form bodies, and json bodies, compared in lower case
+	redactFields = []string{
+		"access_token",
+		"client_secret",
+		"id_token",
+		"password",
+		"refresh_token",
+		"secret",
+		"sig",
+		"signature",
+		"token",
+		"x-amz-credential",
+		"x-amz-security-token",
+		"x-amz-signature",
+	}
+)
+
+type tracer struct {
+	log       *slog.Logger
+	bodyLimit int64
+	next      http.RoundTripper
+}
+
+// Opt is used to configure the trace middleware.
+type Opt func(*tracer)
+
+// WithBodyLimit logs request and response bodies that are no larger than limit bytes.
+// Bodies are not logged by default.
+func WithBodyLimit(limit int64) Opt {
+	return func(t *tracer) {
+		t.bodyLimit = limit
+	}
+}
+
+// New returns http middleware that logs each request to log.
+func New(log *slog.Logger, opts ...Opt) func(next http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		t := &tracer{log: log, next: next}
+		for _, opt := range opts {
+			opt(t)
+		}
+		return t
+	}
+}
+
+// NewFile returns http middleware that appends a json log of each request to a file.
+// The file remains open for the life of the process.
+func NewFile(filename string, opts ...Opt) (func(next http.RoundTripper) http.RoundTripper, error) {
+	//#nosec G302 G304 log location is user controlled
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open http log %s: %w", filename, err)
+	}
+	return New(slog.New(slog.NewJSONHandler(f, nil)), opts...), nil
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *tracer) RoundTrip(req *http.Request) (*http.Response, error) {
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", RedactURL(req.URL)),
+		slog.Any("reqHeaders", redactHeader(req.Header)),
+	}
+	if body, ok := t.reqBody(req); ok {
+		attrs = append(attrs, slog.String("reqBody", body))
+	}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+	if err != nil {
+		attrs = append(attrs, slog.String("err", err.Error()))
+		t.log.LogAttrs(req.Context(), slog.LevelInfo, "HTTP request failed", attrs...)
+		return resp, err
+	}
+	attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	for _, h := range requestIDHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			attrs = append(attrs, slog.String("requestID", v))
+			break
+		}
+	}
+	attrs = append(attrs, slog.Any("respHeaders", redactHeader(resp.Header)))
+	if body, ok := t.respBody(resp); ok {
+		attrs = append(attrs, slog.String("respBody", body))
+	}
+	t.log.LogAttrs(req.Context(), slog.LevelInfo, "HTTP request", attrs...)
+	return resp, nil
+}
+
+// reqBody returns a copy of the request body when it is within the limit.
+// Only bodies that can be replayed with GetBody are read.
+func (t *tracer) reqBody(req *http.Request) (string, bool) {
+	if t.bodyLimit <= 0 || req.GetBody == nil || req.ContentLength <= 0 || req.ContentLength > t.bodyLimit {
+		return "", false
+	}
+	rdr, err := req.GetBody()
+	if err != nil {
+		return "", false
+	}
+	defer rdr.Close()
+	b, err := io.ReadAll(io.LimitReader(rdr, t.bodyLimit))
+	if err != nil {
+		return "", false
+	}
+	return RedactBody(req.Header.Get("Content-Type"), b)
+}
+
+// respBody reads the start of the response body, and replaces the body so the full content is still returned to the caller.
+func (t *tracer) respBody(resp *http.Response) (string, bool) {
+	if t.bodyLimit <= 0 || resp.Body == nil || resp.Body == http.NoBody || resp.ContentLength == 0 || resp.ContentLength > t.bodyLimit {
+		return "", false
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, t.bodyLimit+1))
+	resp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(b), resp.Body), Closer: resp.Body}
+	if err != nil || int64(len(b)) > t.bodyLimit {
+		return "", false
+	}
+	return RedactBody(resp.Header.Get("Content-Type"), b)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// RedactURL returns the URL with any credentials and signatures in the query removed.
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	uc := *u
+	if uc.RawQuery != "" {
+		q := uc.Query()
+		for k := range q {
+			if isRedacted(k) {
+				q[k] = []string{redacted}
+			}
+		}
+		uc.RawQuery = q.Encode()
+	}
+	return uc.Redacted()
+}
+
+// SensitiveHeader returns true for headers that contain credentials.
+func SensitiveHeader(name string) bool {
+	return slices.Contains(redactHeaders, http.CanonicalHeaderKey(name))
+}
+
+func redactHeader(h http.Header) map[string]string {
+	result := map[string]string{}
+	for k, v := range h {
+		ck := http.CanonicalHeaderKey(k)
+		if SensitiveHeader(ck) {
+			// keep the auth scheme to show the type of authentication used
+			scheme, _, ok := strings.Cut(strings.Join(v, ", "), " ")
+			if ok && (ck == "Authorization" || ck == "Proxy-Authorization") {
+				result[k] = scheme + " " + redacted
+			} else {
+				result[k] = redacted
+			}
+			continue
+		}
+		result[k] = strings.Join(v, ", ")
+	}
+	return result
+}
+
+// RedactBody returns the body with any credentials redacted.
+// Binary bodies are not logged.
+func RedactBody(contentType string, b []byte) (string, bool) {
+	if !utf8.Valid(b) {
+		return "", false
+	}
+	mt, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mt == "application/x-www-form-urlencoded":
+		q, err := url.ParseQuery(string(b))
+		if err != nil {
+			return redacted, true
+		}
+		for k := range q {
+			if isRedacted(k) {
+				q[k] = []string{redacted}
+			}
+		}
+		return q.Encode(), true
+	case mt == "application/json" || strings.HasSuffix(mt, "+json") || json.Valid(b):
+		var v any
+		if err := json.Unmarshal(b, &v); err != nil {
+			return string(b), true
+		}
+		if !redactJSON(v) {
+			return string(b), true
+		}
+		out, err := json.Marshal(v)
+		if err != nil {
+			return redacted, true
+		}
+		return string(out), true
+	}
+	return string(b), true
+}
+
+// redactJSON replaces sensitive fields in decoded json, returning true when anything was changed.
+func redactJSON(v any) bool {
+	changed := false
+	switch vt := v.(type) {
+	case map[string]any:
+		for k, val := range vt {
+			if isRedacted(k) {
+				vt[k] = redacted
+				changed = true
+			} else if redactJSON(val) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, val := range vt {
+			if redactJSON(val) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+func isRedacted(field string) bool {
+	return slices.Contains(redactFields, strings.ToLower(field))
+}
@@ -0,0 +1,164 @@
+package httptrace
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+	blob := strings.Repeat("x", 2048)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Request-Id", "req-"+r.Method)
+		switch r.URL.Path {
+		case "/token":
+			_, _ = io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"token":"secret-token","expires_in":300}`))
+		case "/v2/repo/blobs/sha256:abc":
+			_, _ = w.Write([]byte(blob))
+		case "/v2/repo/manifests/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN"}]}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	tt := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		header     http.Header
+		expectResp string
+		expect     map[string]any
+		expectNot  []string
+	}{
+		{
+			name:   "auth header",
+			method: http.MethodGet,
+			path:   "/v2/",
+			header: http.Header{"Authorization": []string{"Bearer abc123"}},
+			expect: map[string]any{
+				"method":    "GET",
+				"status":    float64(200),
+				"requestID": "req-GET",
+			},
+			expectNot: []string{"abc123"},
+		},
+		{
+			name:       "token response",
+			method:     http.MethodPost,
+			path:       "/token",
+			body:       "grant_type=password&username=user&password=hunter2",
+			header:     http.Header{"Content-Type": []string{"application/x-www-form-urlencoded"}},
+			expectResp: `{"token":"secret-token","expires_in":300}`,
+			expect: map[string]any{
+				"reqBody":  "grant_type=password&password=%5Bredacted%5D&username=user",
+				"respBody": `{"expires_in":300,"token":"[redacted]"}`,
+			},
+			expectNot: []string{"hunter2", "secret-token"},
+		},
+		{
+			name:       "large body",
+			method:     http.MethodGet,
+			path:       "/v2/repo/blobs/sha256:abc",
+			expectResp: blob,
+			expectNot:  []string{"respBody", "xxxx"},
+		},
+		{
+			name:       "error body",
+			method:     http.MethodGet,
+			path:       "/v2/repo/manifests/missing",
+			expectResp: `{"errors":[{"code":"MANIFEST_UNKNOWN"}]}`,
+			expect: map[string]any{
+				"status":   float64(404),
+				"respBody": `{"errors":[{"code":"MANIFEST_UNKNOWN"}]}`,
+			},
+		},
+		{
+			name:   "signed url",
+			method: http.MethodGet,
+			path:   "/storage?X-Amz-Signature=abcdef&digest=sha256:abc",
+			expect: map[string]any{
+				"url": ts.URL + "/storage?X-Amz-Signature=%5Bredacted%5D&digest=sha256%3Aabc",
+			},
+			expectNot: []string{"abcdef"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			buf := &bytes.Buffer{}
+			log := slog.New(slog.NewJSONHandler(buf, nil))
+			hc := &http.Client{Transport: New(log, WithBodyLimit(1024))(http.DefaultTransport)}
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, body)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			for k, v := range tc.header {
+				req.Header[k] = v
+			}
+			resp, err := hc.Do(req)
+			if err != nil {
+				t.Fatalf("failed to run request: %v", err)
+			}
+			respBody, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if err != nil {
+				t.Fatalf("failed to read response: %v", err)
+			}
+			if string(respBody) != tc.expectResp {
+				t.Errorf("response body changed, expected %s, received %s", tc.expectResp, respBody)
+			}
+			entry := map[string]any{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("failed to parse log %s: %v", buf.String(), err)
+			}
+			for k, v := range tc.expect {
+				if entry[k] != v {
+					t.Errorf("unexpected %s, expected %v, received %v", k, v, entry[k])
+				}
+			}
+			for _, s := range tc.expectNot {
+				if strings.Contains(buf.String(), s) {
+					t.Errorf("log contains %s: %s", s, buf.String())
+				}
+			}
+			if _, ok := entry["duration"]; !ok {
+				t.Errorf("duration missing from log")
+			}
+		})
+	}
+
+	t.Run("auth scheme", func(t *testing.T) {
+		h := redactHeader(http.Header{"Authorization": []string{"Basic dXNlcjpwYXNz"}, "Accept": []string{"a", "b"}})
+		if h["Authorization"] != "Basic [redacted]" || h["Accept"] != "a, b" {
+			t.Errorf("unexpected headers: %v", h)
+		}
+	})
+	t.Run("no body limit", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		hc := &http.Client{Transport: New(slog.New(slog.NewJSONHandler(buf, nil)))(http.DefaultTransport)}
+		resp, err := hc.Post(ts.URL+"/token", "application/json", strings.NewReader(`{"password":"hunter2"}`))
+		if err != nil {
+			t.Fatalf("failed to run request: %v", err)
+		}
+		_ = resp.Body.Close()
+		if strings.Contains(buf.String(), "Body") {
+			t.Errorf("body logged without a limit: %s", buf.String())
+		}
+	})
+}
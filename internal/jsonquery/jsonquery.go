@@ -0,0 +1,404 @@
+// Package jsonquery selects values from JSON data with a jq or JSONPath style expression.
+//
+// An expression is a path of steps, optionally starting with "$" or ".":
+//
+//	.config.digest                         object field
+//	.annotations["org.opencontainers.image.title"]  quoted field name
+//	.layers[0], .layers[-1]                array index, negative values count from the end
+//	.manifests[], .manifests[*]            every entry of an array or object
+//	.manifests[?platform.os=="linux"]      array entries matching a filter
+//
+// A filter compares a path relative to each entry ("@" and a leading "." are optional)
+// with "==" or "!=" to a JSON literal, or checks that the path is set when no comparison is given.
+// Multiple conditions may be joined with "&&".
+package jsonquery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/regclient/regclient/types/errs"
+)
+
+// Query is a parsed expression.
+type Query struct {
+	steps []step
+}
+
+type stepKind int
+
+const (
+	stepField stepKind = iota
+	stepIndex
+	stepAll
+	stepFilter
+)
+
+type step struct {
+	kind   stepKind
+	field  string
+	index  int
+	filter []cond
+}
+
+type cond struct {
+	path  []step
+	op    string // empty to check the path is set, "==", or "!="
+	value any
+}
+
+// Parse converts an expression to a [Query].
+func Parse(expr string) (Query, error) {
+	p := parser{s: strings.TrimSpace(expr)}
+	p.skip("$")
+	steps, err := p.path(false)
+	if err != nil {
+		return Query{}, err
+	}
+	if p.pos < len(p.s) {
+		return Query{}, p.errorf("unexpected %q", p.s[p.pos:])
+	}
+	return Query{steps: steps}, nil
+}
+
+// Eval returns the values selected from data, which should be decoded JSON.
+func (q Query) Eval(data any) ([]any, error) {
+	return evalSteps(q.steps, []any{data})
+}
+
+// EvalJSON decodes raw JSON and returns the selected values.
+// Numbers are returned as [json.Number] to preserve their formatting.
+func (q Query) EvalJSON(raw []byte) ([]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var data any
+	if err := dec.Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to parse json: %w", err)
+	}
+	return q.Eval(data)
+}
+
+func evalSteps(steps []step, cur []any) ([]any, error) {
+	for _, s := range steps {
+		next := []any{}
+		for _, v := range cur {
+			switch s.kind {
+			case stepField:
+				switch vt := v.(type) {
+				case map[string]any:
+					next = append(next, vt[s.field])
+				case nil:
+					next = append(next, nil)
+				default:
+					return nil, fmt.Errorf("cannot select field %q from %s%.0w", s.field, typeName(v), errs.ErrNotFound)
+				}
+			case stepIndex:
+				switch vt := v.(type) {
+				case []any:
+					i := s.index
+					if i < 0 {
+						i += len(vt)
+					}
+					if i >= 0 && i < len(vt) {
+						next = append(next, vt[i])
+					} else {
+						next = append(next, nil)
+					}
+				case nil:
+					next = append(next, nil)
+				default:
+					return nil, fmt.Errorf("cannot select index %d from %s%.0w", s.index, typeName(v), errs.ErrNotFound)
+				}
+			case stepAll, stepFilter:
+				var entries []any
+				switch vt := v.(type) {
+				case []any:
+					entries = vt
+				case map[string]any:
+					for _, k := range slices.Sorted(maps.Keys(vt)) {
+						entries = append(entries, vt[k])
+					}
+				default:
+					return nil, fmt.Errorf("cannot iterate over %s%.0w", typeName(v), errs.ErrNotFound)
+				}
+				for _, e := range entries {
+					if s.kind == stepAll || matchConds(s.filter, e) {
+						next = append(next, e)
+					}
+				}
+			}
+		}
+		cur = next
+	}
+	return cur, nil
+}
+
+func matchConds(conds []cond, v any) bool {
+	for _, c := range conds {
+		found, err := evalSteps(c.path, []any{v})
+		if err != nil || len(found) != 1 {
+			return false
+		}
+		switch c.op {
+		case "":
+			if found[0] == nil || found[0] == false {
+				return false
+			}
+		case "==":
+			if !equal(found[0], c.value) {
+				return false
+			}
+		case "!=":
+			if equal(found[0], c.value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func equal(a, b any) bool {
+	an, aOK := a.(json.Number)
+	bn, bOK := b.(json.Number)
+	if aOK && bOK {
+		af, aErr := an.Float64()
+		bf, bErr := bn.Float64()
+		if aErr == nil && bErr == nil {
+			return af == bf
+		}
+		return an == bn
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case json.Number, float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("failed to parse query %q at position %d: %s%.0w", p.s, p.pos, fmt.Sprintf(format, args...), errs.ErrParsingFailed)
+}
+
+func (p *parser) peek() byte {
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *parser) skip(prefix string) bool {
+	if strings.HasPrefix(p.s[p.pos:], prefix) {
+		p.pos += len(prefix)
+		return true
+	}
+	return false
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// path parses steps until the end of the expression, or the end of a filter path.
+// The first field may be given without a leading ".".
+func (p *parser) path(inFilter bool) ([]step, error) {
+	steps := []step{}
+	if isIdent(p.peek()) {
+		steps = append(steps, step{kind: stepField, field: p.ident()})
+	}
+	for p.pos < len(p.s) {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			switch {
+			case p.peek() == '[':
+				// ".[...]" is the same as "[...]"
+			case p.peek() == '*' && !inFilter:
+				p.pos++
+				steps = append(steps, step{kind: stepAll})
+			case isIdent(p.peek()):
+				steps = append(steps, step{kind: stepField, field: p.ident()})
+			case p.pos == len(p.s) && len(steps) == 0:
+				// "." alone selects the entire document
+			default:
+				return nil, p.errorf("expected a field name")
+			}
+		case '[':
+			p.pos++
+			s, err := p.bracket(inFilter)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, s)
+		default:
+			if inFilter {
+				return steps, nil
+			}
+			return nil, p.errorf("unexpected %q", p.s[p.pos:])
+		}
+	}
+	return steps, nil
+}
+
+// bracket parses the content of "[...]" after the opening bracket.
+func (p *parser) bracket(inFilter bool) (step, error) {
+	var s step
+	switch c := p.peek(); {
+	case c == ']' && !inFilter:
+		s = step{kind: stepAll}
+	case c == '*' && !inFilter:
+		p.pos++
+		s = step{kind: stepAll}
+	case c == '?' && !inFilter:
+		p.pos++
+		conds, err := p.conds()
+		if err != nil {
+			return s, err
+		}
+		s = step{kind: stepFilter, filter: conds}
+	case c == '"' || c == '\'':
+		str, err := p.quoted()
+		if err != nil {
+			return s, err
+		}
+		s = step{kind: stepField, field: str}
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+			p.pos++
+		}
+		i, err := strconv.Atoi(p.s[start:p.pos])
+		if err != nil {
+			return s, p.errorf("invalid index %q", p.s[start:p.pos])
+		}
+		s = step{kind: stepIndex, index: i}
+	default:
+		return s, p.errorf("unexpected %q in brackets", p.s[p.pos:])
+	}
+	if !p.skip("]") {
+		return s, p.errorf("missing closing bracket")
+	}
+	return s, nil
+}
+
+// conds parses a filter, stopping before the closing bracket.
+func (p *parser) conds() ([]cond, error) {
+	conds := []cond{}
+	for {
+		p.skipSpace()
+		p.skip("@")
+		path, err := p.path(true)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return nil, p.errorf("missing filter path")
+		}
+		c := cond{path: path}
+		p.skipSpace()
+		for _, op := range []string{"==", "!="} {
+			if p.skip(op) {
+				c.op = op
+				p.skipSpace()
+				c.value, err = p.literal()
+				if err != nil {
+					return nil, err
+				}
+				p.skipSpace()
+				break
+			}
+		}
+		conds = append(conds, c)
+		if !p.skip("&&") {
+			return conds, nil
+		}
+	}
+}
+
+// literal parses a JSON value, or a single quoted string.
+func (p *parser) literal() (any, error) {
+	if p.peek() == '"' || p.peek() == '\'' {
+		return p.quoted()
+	}
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(" \t&]", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	lit := p.s[start:p.pos]
+	switch lit {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if _, err := strconv.ParseFloat(lit, 64); err != nil {
+		return nil, p.errorf("invalid value %q", lit)
+	}
+	return json.Number(lit), nil
+}
+
+// quoted parses a double quoted string with JSON escapes, or a single quoted string without escapes.
+func (p *parser) quoted() (string, error) {
+	q := p.s[p.pos]
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.s) && p.s[p.pos] != q {
+		if q == '"' && p.s[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.s) {
+		return "", p.errorf("unterminated string")
+	}
+	p.pos++
+	if q == '\'' {
+		return p.s[start+1 : p.pos-1], nil
+	}
+	var str string
+	if err := json.Unmarshal([]byte(p.s[start:p.pos]), &str); err != nil {
+		return "", p.errorf("invalid string %s", p.s[start:p.pos])
+	}
+	return str, nil
+}
+
+func (p *parser) ident() string {
+	start := p.pos
+	for p.pos < len(p.s) && isIdent(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
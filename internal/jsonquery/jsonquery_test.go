@@ -0,0 +1,185 @@
+package jsonquery
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/regclient/regclient/types/errs"
+)
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+	raw := []byte(`{
+		"schemaVersion": 2,
+		"config": {"digest": "sha256:c0", "size": 123},
+		"annotations": {"org.opencontainers.image.title": "example", "b": "second", "a": "first"},
+		"manifests": [
+			{"digest": "sha256:m1", "platform": {"os": "linux", "architecture": "amd64"}},
+			{"digest": "sha256:m2", "platform": {"os": "linux", "architecture": "arm64", "variant": "v8"}},
+			{"digest": "sha256:m3", "platform": {"os": "windows", "architecture": "amd64"}, "size": 10},
+			{"digest": "sha256:m4", "annotations": {"vnd.docker.reference.type": "attestation-manifest"}}
+		]
+	}`)
+	tt := []struct {
+		name      string
+		query     string
+		expect    []any
+		expectErr error
+	}{
+		{
+			name:   "field",
+			query:  ".config.digest",
+			expect: []any{"sha256:c0"},
+		},
+		{
+			name:   "jsonpath",
+			query:  "$.config.size",
+			expect: []any{json.Number("123")},
+		},
+		{
+			name:   "no leading dot",
+			query:  "config.digest",
+			expect: []any{"sha256:c0"},
+		},
+		{
+			name:   "quoted field",
+			query:  `.annotations["org.opencontainers.image.title"]`,
+			expect: []any{"example"},
+		},
+		{
+			name:   "single quoted field",
+			query:  `annotations['org.opencontainers.image.title']`,
+			expect: []any{"example"},
+		},
+		{
+			name:   "missing field",
+			query:  ".config.missing.value",
+			expect: []any{nil},
+		},
+		{
+			name:   "index",
+			query:  ".manifests[1].digest",
+			expect: []any{"sha256:m2"},
+		},
+		{
+			name:   "negative index",
+			query:  ".manifests[-1].digest",
+			expect: []any{"sha256:m4"},
+		},
+		{
+			name:   "index out of range",
+			query:  ".manifests[10]",
+			expect: []any{nil},
+		},
+		{
+			name:   "iterate",
+			query:  ".manifests[].digest",
+			expect: []any{"sha256:m1", "sha256:m2", "sha256:m3", "sha256:m4"},
+		},
+		{
+			name:   "iterate object sorted",
+			query:  ".annotations.*",
+			expect: []any{"first", "second", "example"},
+		},
+		{
+			name:   "filter",
+			query:  `.manifests[?platform.os=="linux"].digest`,
+			expect: []any{"sha256:m1", "sha256:m2"},
+		},
+		{
+			name:   "filter and",
+			query:  `.manifests[?@.platform.os == 'linux' && .platform.architecture != "amd64"].digest`,
+			expect: []any{"sha256:m2"},
+		},
+		{
+			name:   "filter exists",
+			query:  `.manifests[?platform.variant].digest`,
+			expect: []any{"sha256:m2"},
+		},
+		{
+			name:   "filter number",
+			query:  `.manifests[?size==10].digest`,
+			expect: []any{"sha256:m3"},
+		},
+		{
+			name:   "filter quoted field",
+			query:  `.manifests[?annotations["vnd.docker.reference.type"]=="attestation-manifest"].digest`,
+			expect: []any{"sha256:m4"},
+		},
+		{
+			name:   "filter no match",
+			query:  `.manifests[?platform.os=="darwin"].digest`,
+			expect: []any{},
+		},
+		{
+			name:   "number field",
+			query:  ".schemaVersion",
+			expect: []any{json.Number("2")},
+		},
+		{
+			name:      "field of string",
+			query:     ".config.digest.value",
+			expectErr: errs.ErrNotFound,
+		},
+		{
+			name:      "iterate string",
+			query:     ".config.digest[]",
+			expectErr: errs.ErrNotFound,
+		},
+		{
+			name:      "unclosed bracket",
+			query:     ".manifests[0",
+			expectErr: errs.ErrParsingFailed,
+		},
+		{
+			name:      "invalid literal",
+			query:     `.manifests[?platform.os==linux]`,
+			expectErr: errs.ErrParsingFailed,
+		},
+		{
+			name:      "unterminated string",
+			query:     `.annotations["title]`,
+			expectErr: errs.ErrParsingFailed,
+		},
+		{
+			name:      "trailing dot",
+			query:     ".config.",
+			expectErr: errs.ErrParsingFailed,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := Parse(tc.query)
+			var result []any
+			if err == nil {
+				result, err = q.EvalJSON(raw)
+			}
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tc.expect) {
+				t.Errorf("unexpected result, expected %v, received %v", tc.expect, result)
+			}
+		})
+	}
+	t.Run("document", func(t *testing.T) {
+		for _, query := range []string{"", ".", "$"} {
+			q, err := Parse(query)
+			if err != nil {
+				t.Fatalf("failed to parse %q: %v", query, err)
+			}
+			result, err := q.Eval("value")
+			if err != nil || len(result) != 1 || result[0] != "value" {
+				t.Errorf("unexpected result for %q: %v, %v", query, result, err)
+			}
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
@@ -33,6 +34,7 @@ import (
 	"github.com/regclient/regclient/internal/reqmeta"
 	"github.com/regclient/regclient/types"
 	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ping"
 	"github.com/regclient/regclient/types/warning"
 )
 
@@ -40,11 +42,21 @@ var (
 	defaultDelayInit, _ = time.ParseDuration("0.1s")
 	defaultDelayMax, _  = time.ParseDuration("30s")
 	warnRegexp          = regexp.MustCompile(`^299\s+-\s+"([^"]+)"`)
+	// requestIDHeaders are checked in order for the request ID of a failed response
+	requestIDHeaders = []string{
+		"X-Request-Id",
+		"Docker-Request-Id",
+		"X-Github-Request-Id",
+		"X-Amzn-Requestid",
+		"X-Ms-Request-Id",
+	}
 )
 
 const (
-	DefaultRetryLimit = 5 // number of times a request will be retried
-	backoffResetCount = 5 // number of successful requests needed to reduce the backoff
+	DefaultRetryLimit = 5           // number of times a request will be retried
+	backoffResetCount = 5           // number of successful requests needed to reduce the backoff
+	errBodyLimit      = 1024 * 64   // limit on the body read from a failed response
+	mirrorSkipTime    = time.Minute // how long to skip a mirror after a failed request
 )
 
 // Client is an HTTP client wrapper.
@@ -56,13 +68,18 @@ type Client struct {
 	rootCAPool    [][]byte                  // list of root CAs for configuring the http.Client transport
 	rootCADirs    []string                  // list of directories for additional root CAs
 	retryLimit    int                       // number of retries before failing a request, this applies to each host, and each request
+	retryAfterMax time.Duration             // maximum time to queue a request after 429 and 503 responses, 0 disables queueing
 	delayInit     time.Duration             // how long to initially delay requests on a failure
 	delayMax      time.Duration             // maximum time to delay a request
 	slog          *slog.Logger              // logging for tracing and failures
 	userAgent     string                    // user agent to specify in http request headers
+	middleware    []Middleware              // list of middleware to wrap around the http transport
 	mu            sync.Mutex                // mutex to prevent data races
 }
 
+// Middleware wraps an [http.RoundTripper] to modify requests or responses.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
 type clientHost struct {
 	config       *config.Host                // config entry
 	httpClient   *http.Client                // modified http client for registry specific settings
@@ -72,9 +89,12 @@ type clientHost struct {
 	backoffCur   int                         // current count of backoffs for this host
 	backoffLast  time.Time                   // time the last request was released, this may be in the future if there is a queue, or zero if no delay is needed
 	backoffReset int                         // count of successful requests when a backoff is experienced, once [backoffResetCount] is reached, [backoffCur] is reduced by one and this is reset to 0
+	queueStats   HostStats                   // statistics on requests delayed by backoffs and queueing
+	skipUntil    time.Time                   // when used as a mirror, skip this host until this time after a failure
 	reqFreq      time.Duration               // how long between submitting requests for this host
 	reqNext      time.Time                   // time to release the next request
 	throttle     *pqueue.Queue[reqmeta.Data] // limit concurrent requests to the host
+	err          error                       // error configuring the host, returned for every request
 	mu           sync.Mutex                  // mutex to prevent data races
 }
 
@@ -96,6 +116,8 @@ type Req struct {
 	ExpectLen   int64                         // expected size of the returned body
 	TransactLen int64                         // size of an overall transaction for the priority queue
 	IgnoreErr   bool                          // ignore http errors and do not trigger backoffs
+	NoRedirect  bool                          // return a redirect response instead of following the Location header
+	Scopes      []string                      // additional auth scopes to request
 }
 
 // Resp is used to handle the result of a request.
@@ -109,9 +131,20 @@ type Resp struct {
 	reader           io.Reader
 	readCur, readMax int64
 	retryCount       int
+	queueStart       time.Time // time the request was first queued by a 429 or 503 response
+	queueCount       int       // number of times the request has been queued
 	throttleDone     func()
 }
 
+// HostStats are statistics on delayed requests to a host.
+type HostStats struct {
+	RateLimited  int64         // RateLimited is the count of 429 and 503 responses.
+	Queued       int           // Queued is the count of requests currently waiting for a backoff or Retry-After delay.
+	QueuedTotal  int64         // QueuedTotal is the count of requests that have been delayed.
+	QueueWait    time.Duration // QueueWait is the combined time requests have been delayed.
+	QueueExpired int64         // QueueExpired is the count of requests that failed after reaching the queue deadline.
+}
+
 // Opts is used to configure client options.
 type Opts func(*Client)
 
@@ -196,6 +229,14 @@ func WithHTTPClient(hc *http.Client) Opts {
 	}
 }
 
+// WithMiddleware adds middleware to the http transport for every host.
+// The first middleware in the list is the outermost wrapper and sees each request first.
+func WithMiddleware(mw ...Middleware) Opts {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, mw...)
+	}
+}
+
 // WithRetryLimit restricts the number of retries (defaults to 5).
 func WithRetryLimit(rl int) Opts {
 	return func(c *Client) {
@@ -205,6 +246,16 @@ func WithRetryLimit(rl int) Opts {
 	}
 }
 
+// WithRetryAfterMax queues requests receiving a 429 or 503 response for up to the max duration.
+// Queued requests wait for the Retry-After header, or an exponential backoff when the header is missing, and are not counted against the retry limit.
+// Other requests to the same host are delayed until the Retry-After time.
+// Queueing is disabled by default.
+func WithRetryAfterMax(max time.Duration) Opts {
+	return func(c *Client) {
+		c.retryAfterMax = max
+	}
+}
+
 // WithLog injects a slog Logger configuration.
 func WithLog(slog *slog.Logger) Opts {
 	return func(c *Client) {
@@ -254,13 +305,24 @@ func (resp *Resp) next() error {
 		}
 	}
 	hosts = append(hosts, reqHost)
-	sort.Slice(hosts, sortHostsCmp(hosts, reqHost.config.Name))
+	// skip unhealthy mirrors, the upstream is always included as a fallback
+	hosts = slices.DeleteFunc(hosts, func(h *clientHost) bool {
+		if h == reqHost || !h.skipGet() {
+			return false
+		}
+		c.slog.Debug("Skipping unhealthy mirror",
+			slog.String("mirror", h.config.Name),
+			slog.String("host", reqHost.config.Name))
+		return true
+	})
+	sort.SliceStable(hosts, sortHostsCmp(hosts, reqHost.config.Name))
 	// loop over requests to mirrors and retries
 	curHost := 0
 	for {
 		backoff := false
 		dropHost := false
 		retryHost := false
+		queued := false
 		if len(hosts) == 0 {
 			if err != nil {
 				return err
@@ -295,6 +357,10 @@ func (resp *Resp) next() error {
 		// try each host in a closure to handle all the backoff/dropHost from one place
 		loopErr := func() error {
 			var err error
+			if h.err != nil {
+				dropHost = true
+				return h.err
+			}
 			if req.Method == "HEAD" && h.config.APIOpts != nil {
 				var disableHead bool
 				disableHead, err = strconv.ParseBool(h.config.APIOpts["disableHead"])
@@ -308,12 +374,19 @@ func (resp *Resp) next() error {
 			var u url.URL
 			if req.DirectURL != nil {
 				u = *req.DirectURL
+				// registries behind a base path may return locations relative to the root of the host
+				if h.config.BasePath != "" && u.Host == h.config.Hostname && strings.HasPrefix(u.Path, "/v2/") {
+					u.Path = "/" + h.config.BasePath + u.Path
+				}
 			} else {
 				u = url.URL{
 					Host:   h.config.Hostname,
 					Scheme: "https",
 				}
 				path := strings.Builder{}
+				if h.config.BasePath != "" {
+					path.WriteString("/" + h.config.BasePath)
+				}
 				path.WriteString("/v2")
 				if h.config.PathPrefix != "" && !req.NoPrefix {
 					path.WriteString("/" + h.config.PathPrefix)
@@ -346,11 +419,14 @@ func (resp *Resp) next() error {
 				c.slog.Debug("Sleeping for backoff",
 					slog.String("Host", h.config.Name),
 					slog.Duration("Duration", sleepTime))
+				done := h.queueAdd()
 				select {
 				case <-resp.ctx.Done():
+					done()
 					return errs.ErrCanceled
 				case <-time.After(sleepTime):
 				}
+				done()
 			}
 			var httpReq *http.Request
 			httpReq, err = http.NewRequestWithContext(resp.ctx, req.Method, u.String(), nil)
@@ -399,6 +475,9 @@ func (resp *Resp) next() error {
 					}
 					_ = hAuth.AddScope(h.config.Hostname, scope)
 				}
+				for _, scope := range req.Scopes {
+					_ = hAuth.AddScope(h.config.Hostname, scope)
+				}
 				// add auth headers
 				err = hAuth.UpdateRequest(httpReq)
 				if err != nil {
@@ -429,6 +508,11 @@ func (resp *Resp) next() error {
 
 			// send request
 			hc := h.getHTTPClient(req.Repository)
+			if req.NoRedirect {
+				hc.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+					return http.ErrUseLastResponse
+				}
+			}
 			//#nosec G704 inputs are user controlled and sanitized
 			resp.resp, err = hc.Do(httpReq)
 			if err != nil {
@@ -440,7 +524,27 @@ func (resp *Resp) next() error {
 			}
 
 			statusCode := resp.resp.StatusCode
-			if statusCode < 200 || statusCode >= 300 {
+			if statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable {
+				next, qErr := resp.queueSet()
+				if !next.IsZero() || qErr != nil {
+					errHTTP := HTTPErrorResp(resp.resp)
+					_ = resp.resp.Body.Close()
+					if qErr != nil {
+						dropHost = true
+						return fmt.Errorf("request failed: %w: %w", qErr, errHTTP)
+					}
+					queued = true
+					c.slog.Info("Request rate limited, queueing",
+						slog.String("URL", u.String()),
+						slog.Int("status", statusCode),
+						slog.Duration("delay", time.Until(next)))
+					return fmt.Errorf("request failed: %w", errHTTP)
+				}
+			}
+			// a conditional GET returns a 304 without a body when the content has not changed
+			notModified := statusCode == http.StatusNotModified && httpReq.Header.Get("If-None-Match") != ""
+			redirect := req.NoRedirect && statusCode >= 300 && statusCode < 400 && resp.resp.Header.Get("Location") != ""
+			if (statusCode < 200 || statusCode >= 300) && !notModified && !redirect {
 				switch statusCode {
 				case http.StatusUnauthorized:
 					// if auth can be done, retry same host without delay, otherwise drop/backoff
@@ -468,6 +572,9 @@ func (resp *Resp) next() error {
 				case http.StatusNotFound:
 					// if not found, drop mirror for this req, but other requests don't need backoff
 					dropHost = true
+				case http.StatusPreconditionFailed:
+					// conditional request failed, the content changed but other requests don't need backoff
+					dropHost = true
 				case http.StatusRequestedRangeNotSatisfiable:
 					// if range request error (blob push), drop mirror for this req, but other requests don't need backoff
 					dropHost = true
@@ -479,10 +586,9 @@ func (resp *Resp) next() error {
 					backoff = true
 					dropHost = true
 				}
-				errHTTP := HTTPError(resp.resp.StatusCode)
-				errBody, _ := io.ReadAll(resp.resp.Body)
+				errHTTP := HTTPErrorResp(resp.resp)
 				_ = resp.resp.Body.Close()
-				return fmt.Errorf("request failed: %w: %s", errHTTP, errBody)
+				return fmt.Errorf("request failed: %w", errHTTP)
 			}
 
 			resp.reader = resp.resp.Body
@@ -513,9 +619,24 @@ func (resp *Resp) next() error {
 		}()
 		// return on success
 		if loopErr == nil {
+			if h != reqHost {
+				h.skipSet(false)
+			}
 			resp.throttleDone = throttleDone
 			return nil
 		}
+		// skip a failing mirror on future requests, unless the request was canceled
+		if backoff && h != reqHost && resp.ctx.Err() == nil {
+			c.slog.Debug("Mirror request failed, skipping mirror",
+				slog.String("mirror", h.config.Name),
+				slog.Duration("duration", mirrorSkipTime),
+				slog.String("err", loopErr.Error()))
+			h.skipSet(true)
+		}
+		// queued requests do not count against the retry limit
+		if queued {
+			resp.retryCount--
+		}
 		// backoff, dropHost, and/or go to next host in the list
 		if backoff {
 			if req.IgnoreErr {
@@ -673,10 +794,8 @@ func (resp *Resp) backoffSet() error {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
 	// check rate limit header and use that directly if possible
-	if resp.resp != nil && resp.resp.Header.Get("Retry-After") != "" {
-		ras := resp.resp.Header.Get("Retry-After")
-		ra, _ := time.ParseDuration(ras + "s")
-		if ra > 0 {
+	if resp.resp != nil {
+		if ra := retryAfter(resp.resp.Header, time.Now()); ra > 0 {
 			next := time.Now().Add(ra)
 			if ch.backoffLast.Before(next) {
 				ch.backoffLast = next
@@ -697,6 +816,39 @@ func (resp *Resp) backoffSet() error {
 	return nil
 }
 
+// queueSet delays the host after a 429 or 503 response.
+// It returns the time to retry the request, or a zero time when queueing is disabled.
+// An error is returned when the queue deadline would be exceeded.
+func (resp *Resp) queueSet() (time.Time, error) {
+	c := resp.client
+	if c.retryAfterMax <= 0 {
+		return time.Time{}, nil
+	}
+	ch := c.getHost(resp.mirror)
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.queueStats.RateLimited++
+	now := time.Now()
+	if resp.queueStart.IsZero() {
+		resp.queueStart = now
+	}
+	resp.queueCount++
+	delay := retryAfter(resp.resp.Header, now)
+	if delay <= 0 {
+		delay = min(c.delayInit<<min(resp.queueCount, 16), c.delayMax)
+	}
+	next := now.Add(delay)
+	if ch.backoffLast.After(next) {
+		next = ch.backoffLast
+	}
+	if next.Sub(resp.queueStart) > c.retryAfterMax {
+		ch.queueStats.QueueExpired++
+		return time.Time{}, fmt.Errorf("queue deadline of %s exceeded%.0w", c.retryAfterMax, errs.ErrRetryLimitExceeded)
+	}
+	ch.backoffLast = next
+	return next, nil
+}
+
 func (resp *Resp) backoffReset() {
 	c := resp.client
 	ch := c.getHost(resp.mirror)
@@ -717,6 +869,69 @@ func (resp *Resp) backoffReset() {
 	}
 }
 
+// skipGet returns true if a mirror should be skipped after a recent failure.
+func (ch *clientHost) skipGet() bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return time.Now().Before(ch.skipUntil)
+}
+
+// skipSet marks a mirror as failed, or clears the failure after a successful request.
+func (ch *clientHost) skipSet(failed bool) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if failed {
+		ch.skipUntil = time.Now().Add(mirrorSkipTime)
+	} else {
+		ch.skipUntil = time.Time{}
+	}
+}
+
+// HostStats returns statistics on delayed requests to a host.
+func (c *Client) HostStats(host string) HostStats {
+	h := c.getHost(host)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.queueStats
+}
+
+// AuthInfo returns details of the auth for a host and repository from previous requests.
+// False is returned when the registry has not requested auth.
+func (c *Client) AuthInfo(host, repo string) (ping.Auth, bool) {
+	h := c.getHost(host)
+	return h.getAuth(repo).Info(h.config.Hostname)
+}
+
+// queueAdd tracks a request delayed for the host, the returned function is called when the delay is finished.
+func (ch *clientHost) queueAdd() func() {
+	start := time.Now()
+	ch.mu.Lock()
+	ch.queueStats.Queued++
+	ch.queueStats.QueuedTotal++
+	ch.mu.Unlock()
+	return func() {
+		ch.mu.Lock()
+		ch.queueStats.Queued--
+		ch.queueStats.QueueWait += time.Since(start)
+		ch.mu.Unlock()
+	}
+}
+
+// retryAfter returns the delay from a Retry-After header, in either seconds or an HTTP date.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	ras := header.Get("Retry-After")
+	if ras == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(ras); err == nil {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(ras); err == nil {
+		return t.Sub(now)
+	}
+	return 0
+}
+
 // getHost looks up or creates a clientHost for a given registry.
 func (c *Client) getHost(host string) *clientHost {
 	c.mu.Lock()
@@ -754,7 +969,8 @@ func (c *Client) getHost(host string) *clientHost {
 		h.httpClient.Transport = http.DefaultTransport.(*http.Transport).Clone()
 	}
 	// configure transport for insecure requests and root certs
-	if h.config.TLS == config.TLSInsecure || len(c.rootCAPool) > 0 || len(c.rootCADirs) > 0 || h.config.RegCert != "" || (h.config.ClientCert != "" && h.config.ClientKey != "") {
+	if h.config.TLS == config.TLSInsecure || len(c.rootCAPool) > 0 || len(c.rootCADirs) > 0 || h.config.RegCert != "" || (h.config.ClientCert != "" && h.config.ClientKey != "") ||
+		h.config.TLSMinVersion != 0 || h.config.TLSServerName != "" {
 		t, ok := h.httpClient.Transport.(*http.Transport)
 		if ok {
 			var tlsc *tls.Config
@@ -775,6 +991,12 @@ func (c *Client) getHost(host string) *clientHost {
 					tlsc.RootCAs = rootPool
 				}
 			}
+			if h.config.TLSMinVersion != 0 {
+				tlsc.MinVersion = uint16(h.config.TLSMinVersion)
+			}
+			if h.config.TLSServerName != "" {
+				tlsc.ServerName = h.config.TLSServerName
+			}
 			if h.config.ClientCert != "" && h.config.ClientKey != "" {
 				cert, err := tls.X509KeyPair([]byte(h.config.ClientCert), []byte(h.config.ClientKey))
 				if err != nil {
@@ -784,10 +1006,33 @@ func (c *Client) getHost(host string) *clientHost {
 					tlsc.Certificates = []tls.Certificate{cert}
 				}
 			}
+			// the transport may be shared with other hosts
+			t = t.Clone()
 			t.TLSClientConfig = tlsc
 			h.httpClient.Transport = t
 		}
 	}
+	// configure a host specific proxy
+	if h.config.Proxy != "" || len(h.config.NoProxy) > 0 {
+		t, ok := h.httpClient.Transport.(*http.Transport)
+		if ok {
+			pf, err := proxyFunc(h.config.Proxy, h.config.NoProxy)
+			if err != nil {
+				// requests fail rather than bypass the configured proxy
+				h.err = fmt.Errorf("failed to configure proxy for host %s: %w", h.config.Name, err)
+			} else {
+				t = t.Clone()
+				t.Proxy = pf
+				h.httpClient.Transport = t
+			}
+		}
+	}
+	// wrap the transport with any user provided middleware
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		if rt := c.middleware[i](h.httpClient.Transport); rt != nil {
+			h.httpClient.Transport = rt
+		}
+	}
 	// wrap the transport for logging and to handle warning headers
 	h.httpClient.Transport = &wrapTransport{c: c, orig: h.httpClient.Transport}
 
@@ -901,6 +1146,8 @@ func HTTPError(statusCode int) error {
 		return fmt.Errorf("%w [http %d]", errs.ErrHTTPUnauthorized, statusCode)
 	case 404:
 		return fmt.Errorf("%w [http %d]", errs.ErrNotFound, statusCode)
+	case 412:
+		return fmt.Errorf("%w [http %d]", errs.ErrConflict, statusCode)
 	case 429:
 		return fmt.Errorf("%w [http %d]", errs.ErrHTTPRateLimit, statusCode)
 	default:
@@ -908,6 +1155,33 @@ func HTTPError(statusCode int) error {
 	}
 }
 
+// HTTPErrorResp returns an [errs.RegistryError] for a failed response.
+// The response body is read to extract any registry error codes, but it is not closed.
+func HTTPErrorResp(resp *http.Response) error {
+	e := &errs.RegistryError{
+		StatusCode: resp.StatusCode,
+		Err:        HTTPError(resp.StatusCode),
+	}
+	for _, h := range requestIDHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			e.RequestID = v
+			break
+		}
+	}
+	if resp.Body != nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, errBodyLimit))
+		errList := struct {
+			Errors []errs.RegistryErrorDetail `json:"errors"`
+		}{}
+		if err := json.Unmarshal(body, &errList); err == nil && len(errList.Errors) > 0 {
+			e.Errors = errList.Errors
+		} else {
+			e.Body = strings.TrimSpace(string(body))
+		}
+	}
+	return e
+}
+
 func makeRootPool(rootCAPool [][]byte, rootCADirs []string, hostname string, hostcert string) (*x509.CertPool, error) {
 	pool, err := x509.SystemCertPool()
 	if err != nil {
@@ -963,6 +1237,7 @@ func makeRootPool(rootCAPool [][]byte, rootCADirs []string, hostname string, hos
 func sortHostsCmp(hosts []*clientHost, upstream string) func(i, j int) bool {
 	now := time.Now()
 	// sort by backoff first, then priority decending, then upstream name last
+	// mirrors with the same priority remain in the configured order when used with a stable sort
 	return func(i, j int) bool {
 		if now.Before(hosts[i].backoffLast) || now.Before(hosts[j].backoffLast) {
 			return hosts[i].backoffLast.Before(hosts[j].backoffLast)
@@ -970,6 +1245,6 @@ func sortHostsCmp(hosts []*clientHost, upstream string) func(i, j int) bool {
 		if hosts[i].config.Priority != hosts[j].config.Priority {
 			return hosts[i].config.Priority < hosts[j].config.Priority
 		}
-		return hosts[i].config.Name != upstream
+		return hosts[i].config.Name != upstream && hosts[j].config.Name == upstream
 	}
 }
@@ -3,6 +3,7 @@ package reghttp
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -11,6 +12,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -450,6 +453,10 @@ func TestRegHttp(t *testing.T) {
 			},
 			RespEntry: reqresp.RespEntry{
 				Status: http.StatusNotFound,
+				Body:   []byte(`{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`),
+				Headers: http.Header{
+					"X-Request-Id": []string{"req-missing"},
+				},
 			},
 		},
 		{
@@ -460,6 +467,7 @@ func TestRegHttp(t *testing.T) {
 			},
 			RespEntry: reqresp.RespEntry{
 				Status: http.StatusForbidden,
+				Body:   []byte("access denied"),
 			},
 		},
 		{
@@ -942,7 +950,7 @@ func TestRegHttp(t *testing.T) {
 	// create http client
 	delayInit, _ := time.ParseDuration("0.0005s")
 	delayMax, _ := time.ParseDuration("0.0010s")
-	hc := NewClient(
+	hcOpts := []Opts{
 		WithConfigHostFn(func(name string) *config.Host {
 			if configHosts[name] == nil {
 				configHosts[name] = config.HostNewName(name)
@@ -952,7 +960,8 @@ func TestRegHttp(t *testing.T) {
 		WithDelay(delayInit, delayMax),
 		WithRetryLimit(10),
 		WithUserAgent(useragent),
-	)
+	}
+	hc := NewClient(hcOpts...)
 
 	// test standard get
 	// test getting http response
@@ -1445,6 +1454,8 @@ func TestRegHttp(t *testing.T) {
 	})
 	// test the retry limit on a specific request with a long list of mirrors
 	t.Run("retry-limit", func(t *testing.T) {
+		// use a new client since mirrors that failed in previous tests are skipped
+		hcLimit := NewClient(hcOpts...)
 		getReq := &Req{
 			Host:       "mirror-limit." + tsHost,
 			Method:     "GET",
@@ -1452,7 +1463,7 @@ func TestRegHttp(t *testing.T) {
 			Path:       "manifests/tag-get",
 			Headers:    headers,
 		}
-		resp, err := hc.Do(ctx, getReq)
+		resp, err := hcLimit.Do(ctx, getReq)
 		if err == nil {
 			_ = resp.Close()
 			t.Fatalf("retry limit was not reached")
@@ -1477,6 +1488,19 @@ func TestRegHttp(t *testing.T) {
 		} else if !errors.Is(err, errs.ErrNotFound) {
 			t.Errorf("unexpected error, expected %v, received %v", errs.ErrNotFound, err)
 		}
+		var re *errs.RegistryError
+		if !errors.As(err, &re) {
+			t.Fatalf("error is not a registry error: %v", err)
+		}
+		if re.StatusCode != http.StatusNotFound {
+			t.Errorf("unexpected status, expected %d, received %d", http.StatusNotFound, re.StatusCode)
+		}
+		if re.RequestID != "req-missing" {
+			t.Errorf("unexpected request id, expected req-missing, received %s", re.RequestID)
+		}
+		if !re.HasCode(errs.ErrorCodeManifestUnknown) || re.HasCode(errs.ErrorCodeNameUnknown) {
+			t.Errorf("unexpected error codes: %v", re.Errors)
+		}
 	})
 	t.Run("Forbidden", func(t *testing.T) {
 		getReq := &Req{
@@ -1493,6 +1517,13 @@ func TestRegHttp(t *testing.T) {
 		} else if !errors.Is(err, errs.ErrHTTPUnauthorized) {
 			t.Errorf("unexpected error, expected %v, received %v", errs.ErrHTTPUnauthorized, err)
 		}
+		var re *errs.RegistryError
+		if !errors.As(err, &re) {
+			t.Fatalf("error is not a registry error: %v", err)
+		}
+		if re.StatusCode != http.StatusForbidden || len(re.Errors) != 0 || re.Body != "access denied" {
+			t.Errorf("unexpected registry error: %#v", re)
+		}
 	})
 	t.Run("Bad GW", func(t *testing.T) {
 		getReq := &Req{
@@ -1756,3 +1787,372 @@ func TestRegHttp(t *testing.T) {
 
 	// TODO: test various TLS configs (custom root for all hosts, custom root for one host, insecure)
 }
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen", r.Header.Get("X-Test"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(ts.Close)
+	tsURL, _ := url.Parse(ts.URL)
+	order := []string{}
+	mwHeader := func(val string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, val)
+				req = req.Clone(req.Context())
+				req.Header.Set("X-Test", req.Header.Get("X-Test")+val)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	hc := NewClient(
+		WithConfigHostFn(func(name string) *config.Host {
+			h := config.HostNewName(name)
+			h.TLS = config.TLSDisabled
+			return h
+		}),
+		WithMiddleware(mwHeader("a"), mwHeader("b")),
+	)
+	resp, err := hc.Do(ctx, &Req{
+		Host:       tsURL.Host,
+		Method:     "GET",
+		Repository: "project",
+		Path:       "manifests/latest",
+	})
+	if err != nil {
+		t.Fatalf("failed to run get: %v", err)
+	}
+	_ = resp.Close()
+	if seen := resp.HTTPResponse().Header.Get("X-Seen"); seen != "ab" {
+		t.Errorf("unexpected header, expected ab, received %s", seen)
+	}
+	if len(order) != 2 || order[0] != "a" || order[1] != "b" {
+		t.Errorf("unexpected middleware order: %v", order)
+	}
+}
+
+func TestRetryAfterQueue(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	var mu sync.Mutex
+	limited := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		limited[r.URL.Path]++
+		count := limited[r.URL.Path]
+		mu.Unlock()
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v2/unavailable/"):
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case count <= 3:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	tsURL, _ := url.Parse(ts.URL)
+	newClient := func(opts ...Opts) *Client {
+		opts = append([]Opts{
+			WithConfigHostFn(func(name string) *config.Host {
+				h := config.HostNewName(name)
+				h.TLS = config.TLSDisabled
+				return h
+			}),
+			WithDelay(time.Millisecond, time.Millisecond*5),
+			WithRetryLimit(1),
+		}, opts...)
+		return NewClient(opts...)
+	}
+	t.Run("disabled", func(t *testing.T) {
+		hc := newClient()
+		resp, err := hc.Do(ctx, &Req{Host: tsURL.Host, Method: "GET", Repository: "disabled", Path: "manifests/latest"})
+		if err == nil {
+			_ = resp.Close()
+			t.Fatalf("request did not fail")
+		}
+		if stats := hc.HostStats(tsURL.Host); stats.RateLimited != 0 {
+			t.Errorf("unexpected rate limit count, expected 0, received %d", stats.RateLimited)
+		}
+	})
+	t.Run("queued", func(t *testing.T) {
+		hc := newClient(WithRetryAfterMax(time.Second * 5))
+		resp, err := hc.Do(ctx, &Req{Host: tsURL.Host, Method: "GET", Repository: "queued", Path: "manifests/latest"})
+		if err != nil {
+			t.Fatalf("failed to run get: %v", err)
+		}
+		_ = resp.Close()
+		stats := hc.HostStats(tsURL.Host)
+		if stats.RateLimited != 3 {
+			t.Errorf("unexpected rate limit count, expected 3, received %d", stats.RateLimited)
+		}
+		if stats.QueuedTotal < 3 || stats.Queued != 0 || stats.QueueWait <= 0 {
+			t.Errorf("unexpected queue stats: %#v", stats)
+		}
+	})
+	t.Run("deadline", func(t *testing.T) {
+		hc := newClient(WithRetryAfterMax(time.Second))
+		resp, err := hc.Do(ctx, &Req{Host: tsURL.Host, Method: "GET", Repository: "unavailable", Path: "manifests/latest"})
+		if err == nil {
+			_ = resp.Close()
+			t.Fatalf("request did not fail")
+		}
+		if !errors.Is(err, errs.ErrRetryLimitExceeded) || !errors.Is(err, errs.ErrHTTPStatus) {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if stats := hc.HostStats(tsURL.Host); stats.QueueExpired != 1 {
+			t.Errorf("unexpected expired count, expected 1, received %d", stats.QueueExpired)
+		}
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tt := []struct {
+		name   string
+		header string
+		expect time.Duration
+	}{
+		{
+			name: "missing",
+		},
+		{
+			name:   "seconds",
+			header: "30",
+			expect: time.Second * 30,
+		},
+		{
+			name:   "date",
+			header: now.Add(time.Minute).Format(http.TimeFormat),
+			expect: time.Minute,
+		},
+		{
+			name:   "invalid",
+			header: "soon",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := http.Header{}
+			if tc.header != "" {
+				h.Set("Retry-After", tc.header)
+			}
+			if result := retryAfter(h, now); result != tc.expect {
+				t.Errorf("unexpected delay, expected %s, received %s", tc.expect, result)
+			}
+		})
+	}
+}
+
+func TestMirrorFallback(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	var mu sync.Mutex
+	counts := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/"), "/")
+		mu.Lock()
+		counts[prefix]++
+		mu.Unlock()
+		switch prefix {
+		case "mirror-down":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	configHosts := map[string]*config.Host{
+		"upstream." + tsHost: {
+			Name:       "upstream." + tsHost,
+			Hostname:   tsHost,
+			TLS:        config.TLSDisabled,
+			PathPrefix: "upstream",
+			Mirrors:    []string{"mirror-a." + tsHost, "mirror-b." + tsHost, "mirror-c." + tsHost},
+		},
+		"failover." + tsHost: {
+			Name:       "failover." + tsHost,
+			Hostname:   tsHost,
+			TLS:        config.TLSDisabled,
+			PathPrefix: "upstream",
+			Mirrors:    []string{"mirror-down." + tsHost, "mirror-c." + tsHost},
+		},
+	}
+	for _, name := range []string{"mirror-a", "mirror-b", "mirror-c", "mirror-down"} {
+		configHosts[name+"."+tsHost] = &config.Host{
+			Name:       name + "." + tsHost,
+			Hostname:   tsHost,
+			TLS:        config.TLSDisabled,
+			PathPrefix: name,
+		}
+	}
+	hc := NewClient(
+		WithConfigHostFn(func(name string) *config.Host {
+			if configHosts[name] == nil {
+				configHosts[name] = config.HostNewName(name)
+			}
+			return configHosts[name]
+		}),
+		WithDelay(time.Millisecond, time.Millisecond*5),
+	)
+	countGet := func(prefix string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return counts[prefix]
+	}
+	get := func(host string) {
+		t.Helper()
+		resp, err := hc.Do(ctx, &Req{Host: host, Method: "GET", Repository: "project", Path: "manifests/latest"})
+		if err != nil {
+			t.Fatalf("failed to run get: %v", err)
+		}
+		_ = resp.Close()
+	}
+	t.Run("order", func(t *testing.T) {
+		for range 3 {
+			get("upstream." + tsHost)
+		}
+		if countGet("mirror-a") != 3 || countGet("mirror-b") != 0 || countGet("upstream") != 0 {
+			t.Errorf("mirrors not used in order: %v", counts)
+		}
+	})
+	t.Run("skip failed mirror", func(t *testing.T) {
+		get("failover." + tsHost)
+		get("failover." + tsHost)
+		if countGet("mirror-down") != 1 {
+			t.Errorf("failed mirror was not skipped, request count %d", countGet("mirror-down"))
+		}
+		if countGet("mirror-c") != 2 {
+			t.Errorf("fallback mirror was not used, request count %d", countGet("mirror-c"))
+		}
+	})
+}
+
+func TestBasePath(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	paths := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(ts.Close)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	configHosts := map[string]*config.Host{
+		"alias.example.org": {
+			Name:       "alias.example.org",
+			Hostname:   tsHost,
+			TLS:        config.TLSDisabled,
+			BasePath:   "artifactory/api/docker/repo",
+			PathPrefix: "team",
+		},
+	}
+	hc := NewClient(
+		WithConfigHostFn(func(name string) *config.Host {
+			if configHosts[name] == nil {
+				configHosts[name] = config.HostNewName(name)
+			}
+			return configHosts[name]
+		}),
+		WithDelay(time.Millisecond, time.Millisecond*5),
+	)
+	tt := []struct {
+		name   string
+		req    Req
+		expect string
+	}{
+		{
+			name:   "repository",
+			req:    Req{Host: "alias.example.org", Method: "GET", Repository: "project", Path: "manifests/latest"},
+			expect: "/artifactory/api/docker/repo/v2/team/project/manifests/latest",
+		},
+		{
+			name:   "no prefix",
+			req:    Req{Host: "alias.example.org", Method: "GET", Path: "_catalog", NoPrefix: true},
+			expect: "/artifactory/api/docker/repo/v2/_catalog",
+		},
+		{
+			name:   "location relative to host",
+			req:    Req{Host: "alias.example.org", Method: "PUT", DirectURL: &url.URL{Scheme: "http", Host: tsHost, Path: "/v2/team/project/blobs/uploads/123"}},
+			expect: "/artifactory/api/docker/repo/v2/team/project/blobs/uploads/123",
+		},
+		{
+			name:   "location with base path",
+			req:    Req{Host: "alias.example.org", Method: "PUT", DirectURL: &url.URL{Scheme: "http", Host: tsHost, Path: "/artifactory/api/docker/repo/v2/team/project/blobs/uploads/123"}},
+			expect: "/artifactory/api/docker/repo/v2/team/project/blobs/uploads/123",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := hc.Do(ctx, &tc.req)
+			if err != nil {
+				t.Fatalf("failed to run request: %v", err)
+			}
+			_ = resp.Close()
+			if p := <-paths; p != tc.expect {
+				t.Errorf("unexpected path, expected %s, received %s", tc.expect, p)
+			}
+		})
+	}
+}
+
+func TestHostTLSTransport(t *testing.T) {
+	t.Parallel()
+	shared := &http.Transport{}
+	configHosts := map[string]*config.Host{
+		"a.example.org": {
+			Name:          "a.example.org",
+			Hostname:      "a.example.org",
+			TLSServerName: "registry.internal",
+			TLSMinVersion: tls.VersionTLS13,
+		},
+		"b.example.org": {
+			Name:     "b.example.org",
+			Hostname: "b.example.org",
+		},
+	}
+	hc := NewClient(
+		WithTransport(shared),
+		WithConfigHostFn(func(name string) *config.Host {
+			return configHosts[name]
+		}),
+	)
+	hostTLS := func(name string) *tls.Config {
+		t.Helper()
+		wt, ok := hc.getHost(name).httpClient.Transport.(*wrapTransport)
+		if !ok {
+			t.Fatalf("unexpected transport for %s", name)
+		}
+		ht, ok := wt.orig.(*http.Transport)
+		if !ok {
+			t.Fatalf("unexpected transport for %s", name)
+		}
+		return ht.TLSClientConfig
+	}
+	tlsA := hostTLS("a.example.org")
+	if tlsA == nil || tlsA.ServerName != "registry.internal" || tlsA.MinVersion != tls.VersionTLS13 {
+		t.Errorf("unexpected tls config for a.example.org: %v", tlsA)
+	}
+	if tlsB := hostTLS("b.example.org"); tlsB != nil && (tlsB.ServerName != "" || tlsB.MinVersion != 0) {
+		t.Errorf("unexpected tls config for b.example.org: server name %s, min version %d", tlsB.ServerName, tlsB.MinVersion)
+	}
+	// cloning the transport may add http/2 settings, but the host settings must not be included
+	if tlsS := shared.TLSClientConfig; tlsS != nil && (tlsS.ServerName != "" || tlsS.MinVersion != 0) {
+		t.Errorf("shared transport was modified: server name %s, min version %d", tlsS.ServerName, tlsS.MinVersion)
+	}
+}
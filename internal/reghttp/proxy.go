@@ -0,0 +1,82 @@
+package reghttp
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/regclient/regclient/config"
+)
+
+// proxyFunc returns a function for [http.Transport.Proxy] based on the host proxy settings.
+// An empty proxy falls back to the environment, while the noProxy list is always checked first.
+func proxyFunc(proxy string, noProxy []string) (func(*http.Request) (*url.URL, error), error) {
+	proxyURL, err := config.ProxyParse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if proxyBypass(req.URL.Host, noProxy) {
+			return nil, nil
+		}
+		if proxyURL != nil {
+			return proxyURL, nil
+		}
+		if proxy == config.ProxyDirect {
+			return nil, nil
+		}
+		return http.ProxyFromEnvironment(req)
+	}, nil
+}
+
+// proxyBypass returns true if the host matches an entry in the noProxy list.
+// Entries follow the NO_PROXY conventions: "*" matches everything,
+// a domain matches itself and any subdomains, and CIDRs match IP addresses.
+func proxyBypass(hostPort string, noProxy []string) bool {
+	if len(noProxy) == 0 {
+		return false
+	}
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		host = hostPort
+		port = ""
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	ip := net.ParseIP(host)
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		entryHost, entryPort, err := net.SplitHostPort(entry)
+		if err != nil {
+			entryHost = entry
+			entryPort = ""
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		entryHost = strings.Trim(entryHost, "[]")
+		if entryIP := net.ParseIP(entryHost); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		entryHost = strings.TrimPrefix(entryHost, "*")
+		if host == strings.TrimPrefix(entryHost, ".") || strings.HasSuffix(host, "."+strings.TrimPrefix(entryHost, ".")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package reghttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/regclient/regclient/config"
+)
+
+func TestProxyBypass(t *testing.T) {
+	t.Parallel()
+	tt := []struct {
+		name    string
+		host    string
+		noProxy []string
+		expect  bool
+	}{
+		{
+			name:   "empty",
+			host:   "registry.example.com",
+			expect: false,
+		},
+		{
+			name:    "wildcard",
+			host:    "registry.example.com",
+			noProxy: []string{"*"},
+			expect:  true,
+		},
+		{
+			name:    "exact",
+			host:    "registry.example.com:5000",
+			noProxy: []string{"registry.example.com"},
+			expect:  true,
+		},
+		{
+			name:    "domain",
+			host:    "registry.example.com",
+			noProxy: []string{".example.com"},
+			expect:  true,
+		},
+		{
+			name:    "domain without dot",
+			host:    "registry.example.com",
+			noProxy: []string{"example.com"},
+			expect:  true,
+		},
+		{
+			name:    "partial domain",
+			host:    "registry.notexample.com",
+			noProxy: []string{"example.com"},
+			expect:  false,
+		},
+		{
+			name:    "port match",
+			host:    "registry.example.com:5000",
+			noProxy: []string{"registry.example.com:5000"},
+			expect:  true,
+		},
+		{
+			name:    "port mismatch",
+			host:    "registry.example.com:5001",
+			noProxy: []string{"registry.example.com:5000"},
+			expect:  false,
+		},
+		{
+			name:    "cidr",
+			host:    "10.1.2.3:5000",
+			noProxy: []string{"10.0.0.0/8"},
+			expect:  true,
+		},
+		{
+			name:    "cidr mismatch",
+			host:    "192.168.1.2",
+			noProxy: []string{"10.0.0.0/8"},
+			expect:  false,
+		},
+		{
+			name:    "ipv6",
+			host:    "[::1]:5000",
+			noProxy: []string{"::1"},
+			expect:  true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			result := proxyBypass(tc.host, tc.noProxy)
+			if result != tc.expect {
+				t.Errorf("unexpected result, expected %t, received %t", tc.expect, result)
+			}
+		})
+	}
+}
+
+func TestProxy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	proxyBody := []byte("proxied")
+	directBody := []byte("direct")
+	tsProxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a proxied request includes the full URL to the upstream
+		if r.URL.Host == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(proxyBody)
+	}))
+	t.Cleanup(tsProxy.Close)
+	tsDirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(directBody)
+	}))
+	t.Cleanup(tsDirect.Close)
+	tsDirectURL, _ := url.Parse(tsDirect.URL)
+	tsDirectHost := tsDirectURL.Host
+	configHosts := map[string]*config.Host{
+		"proxy": {
+			Name:     "proxy",
+			Hostname: tsDirectHost,
+			TLS:      config.TLSDisabled,
+			Proxy:    tsProxy.URL,
+		},
+		"noproxy": {
+			Name:     "noproxy",
+			Hostname: tsDirectHost,
+			TLS:      config.TLSDisabled,
+			Proxy:    tsProxy.URL,
+			NoProxy:  []string{tsDirectURL.Hostname()},
+		},
+		"direct": {
+			Name:     "direct",
+			Hostname: tsDirectHost,
+			TLS:      config.TLSDisabled,
+			Proxy:    "direct",
+		},
+		"invalid": {
+			Name:     "invalid",
+			Hostname: tsDirectHost,
+			TLS:      config.TLSDisabled,
+			Proxy:    "proxy.example.org:3128",
+		},
+	}
+	hc := NewClient(
+		WithConfigHostFn(func(name string) *config.Host {
+			if configHosts[name] == nil {
+				configHosts[name] = config.HostNewName(name)
+			}
+			return configHosts[name]
+		}),
+	)
+	tt := []struct {
+		name      string
+		host      string
+		expect    []byte
+		expectErr bool
+	}{
+		{
+			name:   "proxy",
+			host:   "proxy",
+			expect: proxyBody,
+		},
+		{
+			name:   "noproxy",
+			host:   "noproxy",
+			expect: directBody,
+		},
+		{
+			name:   "direct",
+			host:   "direct",
+			expect: directBody,
+		},
+		{
+			name:      "invalid",
+			host:      "invalid",
+			expectErr: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := hc.Do(ctx, &Req{
+				Host:       tc.host,
+				Method:     "GET",
+				Repository: "project",
+				Path:       "manifests/latest",
+			})
+			if tc.expectErr {
+				if err == nil {
+					_ = resp.Close()
+					t.Errorf("request with an invalid proxy did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to run get: %v", err)
+			}
+			body, err := io.ReadAll(resp)
+			if err != nil {
+				t.Fatalf("failed to read body: %v", err)
+			}
+			_ = resp.Close()
+			if string(body) != string(tc.expect) {
+				t.Errorf("unexpected body, expected %s, received %s", tc.expect, body)
+			}
+		})
+	}
+}
@@ -17,6 +17,9 @@ package units
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 )
 
 var (
@@ -57,3 +60,42 @@ func HumanSize(size float64) string {
 func BytesSize(size float64) string {
 	return CustomSize("%5.3f%s", size, 1024.0, binaryAbbrs)
 }
+
+// ParseSize converts a human-readable size (eg. "50MiB", "1.5GB", "2048") to a number of bytes.
+// Decimal (kB, MB, ...) and binary (KiB, MiB, ...) units are supported, and units are case insensitive.
+func ParseSize(size string) (int64, error) {
+	size = strings.TrimSpace(size)
+	i := strings.IndexFunc(size, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	numStr, unit := size, ""
+	if i >= 0 {
+		numStr, unit = size[:i], strings.TrimSpace(size[i:])
+	}
+	num, err := strconv.ParseFloat(numStr, 64)
+	if err != nil || num < 0 {
+		return 0, fmt.Errorf("invalid size: %q", size)
+	}
+	mult := 1.0
+	if unit != "" {
+		found := false
+		for i := range decimapAbbrs {
+			if strings.EqualFold(unit, decimapAbbrs[i]) {
+				mult, found = math.Pow(1000, float64(i)), true
+				break
+			}
+			if strings.EqualFold(unit, binaryAbbrs[i]) {
+				mult, found = math.Pow(1024, float64(i)), true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid size unit: %q", size)
+		}
+	}
+	result := num * mult
+	if result >= math.MaxInt64 {
+		return 0, fmt.Errorf("size out of range: %q", size)
+	}
+	return int64(result), nil
+}
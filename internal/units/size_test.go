@@ -35,3 +35,76 @@ func TestHuman(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		size      string
+		result    int64
+		expectErr bool
+	}{
+		{
+			name:   "bytes",
+			size:   "2048",
+			result: 2048,
+		},
+		{
+			name:   "bytes unit",
+			size:   "10B",
+			result: 10,
+		},
+		{
+			name:   "decimal",
+			size:   "1.5MB",
+			result: 1500000,
+		},
+		{
+			name:   "binary",
+			size:   "50MiB",
+			result: 50 * 1024 * 1024,
+		},
+		{
+			name:   "case and space",
+			size:   " 2 kib ",
+			result: 2048,
+		},
+		{
+			name:      "empty",
+			size:      "",
+			expectErr: true,
+		},
+		{
+			name:      "unknown unit",
+			size:      "5XB",
+			expectErr: true,
+		},
+		{
+			name:      "negative",
+			size:      "-5MB",
+			expectErr: true,
+		},
+		{
+			name:      "overflow",
+			size:      "100YiB",
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseSize(tt.size)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("did not fail, received %d", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if result != tt.result {
+				t.Errorf("expected %d, received %d", tt.result, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+// Package urlhost matches the hosts of URLs against a list of allowed hosts
+package urlhost
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Match returns true when the host of any URL is in the list of hosts.
+// Hosts may include a port, otherwise any port is allowed.
+// Hosts are compared case insensitive.
+func Match(urls []string, hosts []string) bool {
+	for _, u := range urls {
+		parsed, err := url.Parse(u)
+		if err != nil || parsed.Host == "" {
+			continue
+		}
+		for _, host := range hosts {
+			if strings.EqualFold(parsed.Host, host) || strings.EqualFold(parsed.Hostname(), host) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package urlhost
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+	tt := []struct {
+		name   string
+		urls   []string
+		hosts  []string
+		expect bool
+	}{
+		{
+			name:   "match",
+			urls:   []string{"https://mcr.microsoft.com/v2/windows/blobs/sha256:1234"},
+			hosts:  []string{"mcr.microsoft.com"},
+			expect: true,
+		},
+		{
+			name:   "case insensitive",
+			urls:   []string{"https://MCR.microsoft.com/layer"},
+			hosts:  []string{"mcr.Microsoft.com"},
+			expect: true,
+		},
+		{
+			name:   "any port",
+			urls:   []string{"https://registry.example.org:5000/layer"},
+			hosts:  []string{"registry.example.org"},
+			expect: true,
+		},
+		{
+			name:   "port mismatch",
+			urls:   []string{"https://registry.example.org:5000/layer"},
+			hosts:  []string{"registry.example.org:443"},
+			expect: false,
+		},
+		{
+			name:   "second url",
+			urls:   []string{"https://example.com/layer", "https://registry.example.org/layer"},
+			hosts:  []string{"registry.example.org"},
+			expect: true,
+		},
+		{
+			name:   "no match",
+			urls:   []string{"https://example.com/layer"},
+			hosts:  []string{"registry.example.org"},
+			expect: false,
+		},
+		{
+			name:   "invalid url",
+			urls:   []string{"://example.com", "layer.tar"},
+			hosts:  []string{"example.com"},
+			expect: false,
+		},
+		{
+			name:   "no hosts",
+			urls:   []string{"https://example.com/layer"},
+			expect: false,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := Match(tc.urls, tc.hosts); result != tc.expect {
+				t.Errorf("unexpected result, expected %t, received %t", tc.expect, result)
+			}
+		})
+	}
+}
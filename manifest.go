@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"log/slog"
 
+	"github.com/opencontainers/go-digest"
+
 	"github.com/regclient/regclient/scheme"
 	"github.com/regclient/regclient/types/descriptor"
 	"github.com/regclient/regclient/types/errs"
 	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/mediatype"
 	"github.com/regclient/regclient/types/platform"
 	"github.com/regclient/regclient/types/ref"
 	"github.com/regclient/regclient/types/warning"
@@ -16,6 +19,7 @@ import (
 
 type manifestOpt struct {
 	d             descriptor.Descriptor
+	ifChanged     digest.Digest
 	platform      *platform.Platform
 	schemeOpts    []scheme.ManifestOpts
 	requireDigest bool
@@ -55,6 +59,41 @@ func WithManifestDesc(d descriptor.Descriptor) ManifestOpts {
 	}
 }
 
+// WithManifestForce allows a push to replace a tag matching the immutable tag patterns in the host config.
+// Without this option, replacing an immutable tag returns an error wrapping [errs.ErrTagImmutable].
+func WithManifestForce() ManifestOpts {
+	return func(opts *manifestOpt) {
+		opts.schemeOpts = append(opts.schemeOpts, scheme.WithManifestForce())
+	}
+}
+
+// WithManifestIfChanged only retrieves a manifest when the digest differs from d.
+// An error wrapping [errs.ErrNotModified] is returned by ManifestGet when the digest matches.
+// Registries receive a conditional request, avoiding the transfer of an unchanged manifest.
+// With [WithManifestPlatform], the digest is compared to the manifest list before resolving the platform.
+func WithManifestIfChanged(d digest.Digest) ManifestOpts {
+	return func(opts *manifestOpt) {
+		opts.ifChanged = d
+	}
+}
+
+// WithManifestIfMatch only pushes a manifest when the tag currently points to the digest.
+// This supports compare-and-swap updates of a tag, returning an error wrapping [errs.ErrConflict] when the tag has changed.
+// Registries that support conditional requests also receive an If-Match header, otherwise the tag is checked before the push.
+func WithManifestIfMatch(d digest.Digest) ManifestOpts {
+	return func(opts *manifestOpt) {
+		opts.schemeOpts = append(opts.schemeOpts, scheme.WithManifestIfMatch(d))
+	}
+}
+
+// WithManifestIfNoneMatch only pushes a manifest when the tag does not exist.
+// An error wrapping [errs.ErrConflict] is returned when the tag exists.
+func WithManifestIfNoneMatch() ManifestOpts {
+	return func(opts *manifestOpt) {
+		opts.schemeOpts = append(opts.schemeOpts, scheme.WithManifestIfNoneMatch())
+	}
+}
+
 // WithManifestPlatform resolves the platform specific manifest on Get and Head requests.
 // This causes an additional GET query to a registry when an Index or Manifest List is encountered.
 // This option is ignored if the retrieved manifest is not an Index or Manifest List.
@@ -86,6 +125,7 @@ func (rc *RegClient) ManifestDelete(ctx context.Context, r ref.Ref, opts ...Mani
 	if err != nil {
 		return err
 	}
+	defer rc.memo.manifestDrop(r)
 	return schemeAPI.ManifestDelete(ctx, r, opt.schemeOpts...)
 }
 
@@ -102,6 +142,9 @@ func (rc *RegClient) ManifestGet(ctx context.Context, r ref.Ref, opts ...Manifes
 		r = r.AddDigest(opt.d.Digest.String())
 		data, err := opt.d.GetData()
 		if err == nil {
+			if err := rc.pins.Check(r, opt.d.Digest); err != nil {
+				return nil, err
+			}
 			return manifest.New(
 				manifest.WithDesc(opt.d),
 				manifest.WithRaw(data),
@@ -117,10 +160,31 @@ func (rc *RegClient) ManifestGet(ctx context.Context, r ref.Ref, opts ...Manifes
 	if err != nil {
 		return nil, err
 	}
-	m, err := schemeAPI.ManifestGet(ctx, r)
+	m, ok := rc.memo.manifestGet(r, false)
+	if sc, okC := schemeAs[scheme.ManifestConditional](schemeAPI); !ok && okC && opt.ifChanged != "" {
+		m, err = sc.ManifestGetIfChanged(ctx, r, opt.ifChanged)
+		if err == nil {
+			rc.memo.manifestSet(r, m)
+		}
+	} else {
+		if !ok {
+			m, err = rc.manifestGetScheme(ctx, schemeAPI, r)
+		}
+		if err == nil && opt.ifChanged != "" && m.GetDescriptor().Digest == opt.ifChanged {
+			return nil, fmt.Errorf("manifest %s has not changed%.0w", r.CommonName(), errs.ErrNotModified)
+		}
+	}
 	if err != nil {
 		return m, err
 	}
+	if opt.d.Digest != "" {
+		if err := manifestCheckDesc(m, opt.d); err != nil {
+			return nil, err
+		}
+	}
+	if err := rc.pins.Check(r, m.GetDescriptor().Digest); err != nil {
+		return nil, err
+	}
 	if opt.platform != nil && !m.IsList() {
 		rc.slog.Debug("ignoring platform option, image is not an index",
 			slog.String("platform", opt.platform.String()),
@@ -133,14 +197,63 @@ func (rc *RegClient) ManifestGet(ctx context.Context, r ref.Ref, opts ...Manifes
 			return m, err
 		}
 		r = r.SetDigest(d.Digest.String())
-		m, err = schemeAPI.ManifestGet(ctx, r)
+		m, err = rc.manifestGetScheme(ctx, schemeAPI, r)
 		if err != nil {
 			return m, err
 		}
+		if err := manifestCheckDesc(m, *d); err != nil {
+			return nil, err
+		}
 	}
 	return m, err
 }
 
+// manifestGetScheme gets a manifest from the scheme, using the memoized manifest when available.
+func (rc *RegClient) manifestGetScheme(ctx context.Context, schemeAPI scheme.API, r ref.Ref) (manifest.Manifest, error) {
+	if m, ok := rc.memo.manifestGet(r, false); ok {
+		return m, nil
+	}
+	m, err := schemeAPI.ManifestGet(ctx, r)
+	if err != nil {
+		return m, err
+	}
+	rc.memo.manifestSet(r, m)
+	return m, nil
+}
+
+// manifestHeadScheme queries a manifest from the scheme, using the memoized manifest when available.
+func (rc *RegClient) manifestHeadScheme(ctx context.Context, schemeAPI scheme.API, r ref.Ref) (manifest.Manifest, error) {
+	if m, ok := rc.memo.manifestGet(r, true); ok {
+		return m, nil
+	}
+	m, err := schemeAPI.ManifestHead(ctx, r)
+	if err != nil {
+		return m, err
+	}
+	rc.memo.manifestSet(r, m)
+	return m, nil
+}
+
+// manifestCheckDesc verifies a pulled manifest matches the size and digest of the descriptor that referenced it.
+func manifestCheckDesc(m manifest.Manifest, d descriptor.Descriptor) error {
+	mDesc := m.GetDescriptor()
+	if mDesc.MediaType == mediatype.Docker1ManifestSigned {
+		// the signed schema1 digest and size are computed from the canonical json, not the body
+		return nil
+	}
+	raw, err := m.RawBody()
+	if err != nil {
+		return err
+	}
+	if d.Size > 0 && int64(len(raw)) != d.Size {
+		return fmt.Errorf("manifest size mismatch, descriptor %d, received %d: %s%.0w", d.Size, len(raw), d.Digest.String(), errs.ErrMismatch)
+	}
+	if d.Digest != "" && mDesc.Digest != d.Digest {
+		return fmt.Errorf("manifest digest mismatch, descriptor %s, received %s%.0w", d.Digest.String(), mDesc.Digest.String(), errs.ErrDigestMismatch)
+	}
+	return nil
+}
+
 // ManifestHead queries for the existence of a manifest and returns metadata (digest, media-type, size).
 func (rc *RegClient) ManifestHead(ctx context.Context, r ref.Ref, opts ...ManifestOpts) (manifest.Manifest, error) {
 	if !r.IsSet() {
@@ -158,10 +271,22 @@ func (rc *RegClient) ManifestHead(ctx context.Context, r ref.Ref, opts ...Manife
 	if err != nil {
 		return nil, err
 	}
-	m, err := schemeAPI.ManifestHead(ctx, r)
+	m, err := rc.manifestHeadScheme(ctx, schemeAPI, r)
 	if err != nil {
 		return m, err
 	}
+	// pinned tags require a digest to verify
+	if _, ok := rc.pins.Get(r); ok {
+		if m.GetDescriptor().Digest == "" {
+			m, err = rc.manifestGetScheme(ctx, schemeAPI, r)
+			if err != nil {
+				return m, err
+			}
+		}
+		if err := rc.pins.Check(r, m.GetDescriptor().Digest); err != nil {
+			return nil, err
+		}
+	}
 	if opt.platform != nil && !m.IsList() {
 		rc.slog.Debug("ignoring platform option, image is not an index",
 			slog.String("platform", opt.platform.String()),
@@ -170,20 +295,20 @@ func (rc *RegClient) ManifestHead(ctx context.Context, r ref.Ref, opts ...Manife
 	// this will loop to handle a nested index
 	for opt.platform != nil && m.IsList() {
 		if !m.IsSet() {
-			m, err = schemeAPI.ManifestGet(ctx, r)
+			m, err = rc.manifestGetScheme(ctx, schemeAPI, r)
 		}
 		d, err := manifest.GetPlatformDesc(m, opt.platform)
 		if err != nil {
 			return m, err
 		}
 		r = r.SetDigest(d.Digest.String())
-		m, err = schemeAPI.ManifestHead(ctx, r)
+		m, err = rc.manifestHeadScheme(ctx, schemeAPI, r)
 		if err != nil {
 			return m, err
 		}
 	}
 	if opt.requireDigest && m.GetDescriptor().Digest.String() == "" {
-		m, err = schemeAPI.ManifestGet(ctx, r)
+		m, err = rc.manifestGetScheme(ctx, schemeAPI, r)
 	}
 	return m, err
 }
@@ -202,5 +327,6 @@ func (rc *RegClient) ManifestPut(ctx context.Context, r ref.Ref, m manifest.Mani
 	if err != nil {
 		return err
 	}
+	defer rc.memo.manifestDrop(r.SetTag(r.Tag))
 	return schemeAPI.ManifestPut(ctx, r, m, opt.schemeOpts...)
 }
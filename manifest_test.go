@@ -6,11 +6,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -19,7 +21,9 @@ import (
 	"github.com/opencontainers/go-digest"
 
 	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/internal/copyfs"
 	"github.com/regclient/regclient/internal/reqresp"
+	"github.com/regclient/regclient/scheme"
 	"github.com/regclient/regclient/scheme/reg"
 	"github.com/regclient/regclient/types/descriptor"
 	"github.com/regclient/regclient/types/docker/schema2"
@@ -477,3 +481,465 @@ func TestManifest(t *testing.T) {
 		}
 	})
 }
+
+func TestManifestLimits(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	r, err := ref.New("ocidir://./testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	mh, err := New().ManifestHead(ctx, r)
+	if err != nil {
+		t.Fatalf("failed to head manifest: %v", err)
+	}
+	d := mh.GetDescriptor()
+	dLarge := d
+	dLarge.Size++
+	tt := []struct {
+		name      string
+		rcOpts    []Opt
+		mOpts     []ManifestOpts
+		expectErr error
+	}{
+		{
+			name: "default",
+		},
+		{
+			name:   "within limit",
+			rcOpts: []Opt{WithManifestMaxSize(d.Size)},
+		},
+		{
+			name:      "exceeds limit",
+			rcOpts:    []Opt{WithManifestMaxSize(d.Size - 1)},
+			expectErr: errs.ErrSizeLimitExceeded,
+		},
+		{
+			name:   "limit disabled",
+			rcOpts: []Opt{WithManifestMaxSize(-1)},
+		},
+		{
+			name:  "descriptor",
+			mOpts: []ManifestOpts{WithManifestDesc(d)},
+		},
+		{
+			name:      "descriptor size mismatch",
+			mOpts:     []ManifestOpts{WithManifestDesc(dLarge)},
+			expectErr: errs.ErrMismatch,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rc := New(tc.rcOpts...)
+			m, err := rc.ManifestGet(ctx, r, tc.mOpts...)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to get manifest: %v", err)
+			}
+			if m.GetDescriptor().Digest != d.Digest {
+				t.Errorf("unexpected digest, expected %s, received %s", d.Digest, m.GetDescriptor().Digest)
+			}
+		})
+	}
+}
+
+func TestManifestPutConditional(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	boolT := true
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "./testdata",
+		},
+		API: oConfig.ConfigAPI{
+			DeleteEnabled: &boolT,
+		},
+	})
+	ts := httptest.NewServer(regHandler)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	tempDir := t.TempDir()
+	err := copyfs.Copy(tempDir+"/testrepo", "testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to copyfs to tempdir: %v", err)
+	}
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+	rc := New(
+		WithConfigHost(config.Host{
+			Name:     tsHost,
+			Hostname: tsHost,
+			TLS:      config.TLSDisabled,
+		}),
+		WithRegOpts(reg.WithDelay(time.Millisecond*10, time.Millisecond*100)),
+	)
+	for _, base := range []string{tsHost + "/testrepo", "ocidir://" + tempDir + "/testrepo"} {
+		t.Run(base, func(t *testing.T) {
+			rV1, err := ref.New(base + ":v1")
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			rV2, err := ref.New(base + ":v2")
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			rNew, err := ref.New(base + ":conditional")
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			m1, err := rc.ManifestGet(ctx, rV1)
+			if err != nil {
+				t.Fatalf("failed to get manifest: %v", err)
+			}
+			m2, err := rc.ManifestGet(ctx, rV2)
+			if err != nil {
+				t.Fatalf("failed to get manifest: %v", err)
+			}
+			d1 := m1.GetDescriptor().Digest
+			d2 := m2.GetDescriptor().Digest
+			// create a new tag only if it does not exist
+			err = rc.ManifestPut(ctx, rNew, m1, WithManifestIfNoneMatch())
+			if err != nil {
+				t.Fatalf("failed to create new tag: %v", err)
+			}
+			err = rc.ManifestPut(ctx, rNew, m2, WithManifestIfNoneMatch())
+			if !errors.Is(err, errs.ErrConflict) {
+				t.Errorf("put on existing tag, expected %v, received %v", errs.ErrConflict, err)
+			}
+			// replace only when the tag has not moved
+			err = rc.ManifestPut(ctx, rNew, m2, WithManifestIfMatch(d2))
+			if !errors.Is(err, errs.ErrConflict) {
+				t.Errorf("put with stale digest, expected %v, received %v", errs.ErrConflict, err)
+			}
+			err = rc.ManifestPut(ctx, rNew, m2, WithManifestIfMatch(d1))
+			if err != nil {
+				t.Fatalf("failed to replace tag: %v", err)
+			}
+			mh, err := rc.ManifestHead(ctx, rNew, WithManifestRequireDigest())
+			if err != nil {
+				t.Fatalf("failed to head tag: %v", err)
+			}
+			if mh.GetDescriptor().Digest != d2 {
+				t.Errorf("tag not updated, expected %s, received %s", d2, mh.GetDescriptor().Digest)
+			}
+			err = rc.ManifestPut(ctx, rNew, m1, WithManifestIfMatch(d1))
+			if !errors.Is(err, errs.ErrConflict) {
+				t.Errorf("put after tag moved, expected %v, received %v", errs.ErrConflict, err)
+			}
+			// a missing tag does not match any digest
+			rMissing, err := ref.New(base + ":missing")
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			err = rc.ManifestPut(ctx, rMissing, m1, WithManifestIfMatch(d1))
+			if !errors.Is(err, errs.ErrConflict) {
+				t.Errorf("put on missing tag, expected %v, received %v", errs.ErrConflict, err)
+			}
+		})
+	}
+}
+
+func TestManifestGetConditional(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "./testdata",
+		},
+	})
+	var conditional atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			conditional.Add(1)
+		}
+		regHandler.ServeHTTP(w, r)
+	}))
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+	rcOpts := []Opt{
+		WithConfigHost(config.Host{
+			Name:     tsHost,
+			Hostname: tsHost,
+			TLS:      config.TLSDisabled,
+		}),
+		WithRegOpts(reg.WithDelay(time.Millisecond*10, time.Millisecond*100)),
+	}
+	// the audit logger wraps the scheme, which must pass through the conditional request
+	rcList := map[string]*RegClient{
+		"plain": New(rcOpts...),
+		"audit": New(append(rcOpts, WithAuditLogger(slog.New(slog.NewJSONHandler(io.Discard, nil))))...),
+	}
+	for name, rc := range rcList {
+		for _, base := range []string{tsHost + "/testrepo", "ocidir://testdata/testrepo"} {
+			t.Run(name+" "+base, func(t *testing.T) {
+				rV1, err := ref.New(base + ":v1")
+				if err != nil {
+					t.Fatalf("failed to parse ref: %v", err)
+				}
+				rV2, err := ref.New(base + ":v2")
+				if err != nil {
+					t.Fatalf("failed to parse ref: %v", err)
+				}
+				m1, err := rc.ManifestGet(ctx, rV1)
+				if err != nil {
+					t.Fatalf("failed to get manifest: %v", err)
+				}
+				d1 := m1.GetDescriptor().Digest
+				before := conditional.Load()
+				_, err = rc.ManifestGet(ctx, rV1, WithManifestIfChanged(d1))
+				if !errors.Is(err, errs.ErrNotModified) {
+					t.Errorf("unchanged manifest, expected %v, received %v", errs.ErrNotModified, err)
+				}
+				if rV1.Scheme == "reg" && conditional.Load() == before {
+					t.Errorf("conditional request was not sent")
+				}
+				m2, err := rc.ManifestGet(ctx, rV2, WithManifestIfChanged(d1))
+				if err != nil {
+					t.Fatalf("failed to get changed manifest: %v", err)
+				}
+				if m2.GetDescriptor().Digest == d1 || !m2.IsSet() {
+					t.Errorf("unexpected manifest returned: %v", m2.GetDescriptor())
+				}
+				mp, err := rc.ManifestGet(ctx, rV2, WithManifestIfChanged(d1), WithManifestPlatform(platform.Platform{OS: "linux", Architecture: "amd64"}))
+				if err != nil {
+					t.Fatalf("failed to get changed platform manifest: %v", err)
+				}
+				if mp.IsList() {
+					t.Errorf("platform was not resolved")
+				}
+			})
+		}
+	}
+}
+
+func TestManifestImmutable(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	boolT := true
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "./testdata",
+		},
+		API: oConfig.ConfigAPI{
+			DeleteEnabled: &boolT,
+		},
+	})
+	ts := httptest.NewServer(regHandler)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+	rc := New(
+		WithConfigHost(config.Host{
+			Name:     tsHost,
+			Hostname: tsHost,
+			TLS:      config.TLSDisabled,
+			ImmutableTags: []config.ImmutableTag{
+				{Tag: `^v\d+\.\d+\.\d+$`},
+				{Repo: `^testrepo$`, Tag: `^release$`},
+			},
+		}),
+		WithRegOpts(reg.WithDelay(time.Millisecond*10, time.Millisecond*100)),
+	)
+	rV1, err := ref.New(tsHost + "/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rV2, err := ref.New(tsHost + "/testrepo:v2")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	m1, err := rc.ManifestGet(ctx, rV1)
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	m2, err := rc.ManifestGet(ctx, rV2)
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	for _, tag := range []string{"v1.2.3", "release"} {
+		t.Run(tag, func(t *testing.T) {
+			r := rV1.SetTag(tag)
+			// creating the tag and pushing the same digest are allowed
+			err := rc.ManifestPut(ctx, r, m1)
+			if err != nil {
+				t.Fatalf("failed to create tag: %v", err)
+			}
+			err = rc.ManifestPut(ctx, r, m1)
+			if err != nil {
+				t.Fatalf("failed to push same digest: %v", err)
+			}
+			err = rc.ManifestPut(ctx, r, m2)
+			if !errors.Is(err, errs.ErrTagImmutable) {
+				t.Errorf("replace tag, expected %v, received %v", errs.ErrTagImmutable, err)
+			}
+			err = rc.ImageCopy(ctx, rV2, r)
+			if !errors.Is(err, errs.ErrTagImmutable) {
+				t.Errorf("copy to tag, expected %v, received %v", errs.ErrTagImmutable, err)
+			}
+			err = rc.TagDelete(ctx, r)
+			if !errors.Is(err, errs.ErrTagImmutable) {
+				t.Errorf("delete tag, expected %v, received %v", errs.ErrTagImmutable, err)
+			}
+			// force overrides the immutable setting
+			err = rc.ImageCopy(ctx, rV2, r, ImageWithForce())
+			if err != nil {
+				t.Fatalf("failed to force copy: %v", err)
+			}
+			err = rc.ManifestPut(ctx, r, m1, WithManifestForce())
+			if err != nil {
+				t.Fatalf("failed to force push: %v", err)
+			}
+			err = rc.TagDelete(ctx, r, scheme.WithTagDeleteForce())
+			if err != nil {
+				t.Fatalf("failed to force delete: %v", err)
+			}
+		})
+	}
+	t.Run("mutable", func(t *testing.T) {
+		r, err := ref.New(tsHost + "/other:release")
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		err = rc.ImageCopy(ctx, rV1, r)
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		err = rc.ImageCopy(ctx, rV2, r)
+		if err != nil {
+			t.Fatalf("failed to replace tag in other repo: %v", err)
+		}
+		err = rc.TagDelete(ctx, r)
+		if err != nil {
+			t.Fatalf("failed to delete tag in other repo: %v", err)
+		}
+	})
+}
+
+func TestManifestPins(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	rV1, err := ref.New("ocidir://./testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rV2 := rV1.SetTag("v2")
+	m1, err := New().ManifestHead(ctx, rV1, WithManifestRequireDigest())
+	if err != nil {
+		t.Fatalf("failed to head manifest: %v", err)
+	}
+	d1 := m1.GetDescriptor().Digest
+	// v2 is pinned to the digest of v1
+	rc := New(WithPins(config.Pins{
+		rV1.CommonName(): d1,
+		rV2.CommonName(): d1,
+	}))
+	plat, err := platform.Parse("linux/amd64")
+	if err != nil {
+		t.Fatalf("failed to parse platform: %v", err)
+	}
+	tt := []struct {
+		name      string
+		fn        func() error
+		expectErr error
+	}{
+		{
+			name: "get",
+			fn: func() error {
+				_, err := rc.ManifestGet(ctx, rV1)
+				return err
+			},
+		},
+		{
+			name: "get platform",
+			fn: func() error {
+				_, err := rc.ManifestGet(ctx, rV1, WithManifestPlatform(plat))
+				return err
+			},
+		},
+		{
+			name: "get digest",
+			fn: func() error {
+				_, err := rc.ManifestGet(ctx, rV2.SetDigest(d1.String()))
+				return err
+			},
+		},
+		{
+			name: "get mismatch",
+			fn: func() error {
+				_, err := rc.ManifestGet(ctx, rV2)
+				return err
+			},
+			expectErr: errs.ErrPinMismatch,
+		},
+		{
+			name: "get platform mismatch",
+			fn: func() error {
+				_, err := rc.ManifestGet(ctx, rV2, WithManifestPlatform(plat))
+				return err
+			},
+			expectErr: errs.ErrPinMismatch,
+		},
+		{
+			name: "head",
+			fn: func() error {
+				_, err := rc.ManifestHead(ctx, rV1)
+				return err
+			},
+		},
+		{
+			name: "head mismatch",
+			fn: func() error {
+				_, err := rc.ManifestHead(ctx, rV2)
+				return err
+			},
+			expectErr: errs.ErrPinMismatch,
+		},
+		{
+			name: "copy",
+			fn: func() error {
+				rTgt, err := ref.New("ocidir://" + tempDir + "/testrepo:v1")
+				if err != nil {
+					return err
+				}
+				return rc.ImageCopy(ctx, rV1, rTgt)
+			},
+		},
+		{
+			name: "copy mismatch",
+			fn: func() error {
+				rTgt, err := ref.New("ocidir://" + tempDir + "/testrepo:v2")
+				if err != nil {
+					return err
+				}
+				return rc.ImageCopy(ctx, rV2, rTgt)
+			},
+			expectErr: errs.ErrPinMismatch,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.fn()
+			if !errors.Is(err, tc.expectErr) {
+				t.Errorf("expected error %v, received %v", tc.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package regclient
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/ref"
+)
+
+// memo tracks manifests and blobs seen by the client, see [WithMemoize].
+// A nil memo is disabled and all methods are a noop.
+type memo struct {
+	mu        sync.Mutex
+	manifests map[string]memoManifest
+	blobs     map[string]descriptor.Descriptor
+}
+
+// memoManifest is the response to a manifest request.
+// The manifest is rebuilt on every lookup so callers cannot modify the memoized value.
+type memoManifest struct {
+	repo   string
+	desc   descriptor.Descriptor
+	header http.Header
+	raw    []byte // nil for a HEAD request
+}
+
+func newMemo() *memo {
+	return &memo{
+		manifests: map[string]memoManifest{},
+		blobs:     map[string]descriptor.Descriptor{},
+	}
+}
+
+// WithMemoize reuses the result of manifest and blob HEAD requests for the life of the client.
+// Repeated requests for the same tag, digest, or blob are answered without contacting the registry.
+// Pushing or deleting a manifest, tag, or blob with this client discards the affected entries,
+// but changes made by other clients are not seen until [RegClient.MemoClear] is called.
+// Only successful requests are memoized, a missing blob or manifest is queried again.
+// This is intended for short lived clients, or clients that call MemoClear at the start of each run.
+func WithMemoize() Opt {
+	return func(rc *RegClient) {
+		rc.memo = newMemo()
+	}
+}
+
+// MemoClear discards the manifests and blobs memoized by [WithMemoize].
+func (rc *RegClient) MemoClear() {
+	m := rc.memo
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.manifests)
+	clear(m.blobs)
+}
+
+// manifestGet returns a memoized manifest, only returning a HEAD response when head is set.
+func (m *memo) manifestGet(r ref.Ref, head bool) (manifest.Manifest, bool) {
+	if m == nil {
+		return nil, false
+	}
+	key, ok := memoManifestKey(r)
+	if !ok {
+		return nil, false
+	}
+	m.mu.Lock()
+	mm, ok := m.manifests[key]
+	m.mu.Unlock()
+	if !ok || (!head && mm.raw == nil) {
+		return nil, false
+	}
+	opts := []manifest.Opts{
+		manifest.WithRef(r),
+		manifest.WithDesc(mm.desc),
+		manifest.WithHeader(mm.header),
+	}
+	if mm.raw != nil {
+		opts = append(opts, manifest.WithRaw(bytes.Clone(mm.raw)))
+	}
+	man, err := manifest.New(opts...)
+	if err != nil {
+		return nil, false
+	}
+	return man, true
+}
+
+// manifestSet memoizes a manifest by the requested reference and by digest.
+// HEAD responses without a digest and responses that would replace a GET with a HEAD are ignored.
+func (m *memo) manifestSet(r ref.Ref, man manifest.Manifest) {
+	if m == nil || man == nil {
+		return
+	}
+	mm := memoManifest{
+		repo: r.SetTag("").CommonName(),
+		desc: man.GetDescriptor(),
+	}
+	if mm.desc.Digest == "" {
+		return
+	}
+	mm.header, _ = man.RawHeaders()
+	if man.IsSet() {
+		raw, err := man.RawBody()
+		if err != nil {
+			return
+		}
+		mm.raw = bytes.Clone(raw)
+	}
+	keys := []string{r.SetDigest(mm.desc.Digest.String()).CommonName()}
+	if key, ok := memoManifestKey(r); ok && key != keys[0] {
+		keys = append(keys, key)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		if prev, ok := m.manifests[key]; ok && prev.raw != nil && mm.raw == nil && prev.desc.Digest == mm.desc.Digest {
+			continue
+		}
+		m.manifests[key] = mm
+	}
+}
+
+// manifestDrop removes the memoized tag of a reference.
+// When the reference includes a digest, every entry in the repository for that digest is also removed.
+func (m *memo) manifestDrop(r ref.Ref) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r.Tag != "" {
+		delete(m.manifests, r.SetTag(r.Tag).CommonName())
+	}
+	if r.Digest != "" {
+		repo := r.SetTag("").CommonName()
+		for key, mm := range m.manifests {
+			if mm.repo == repo && mm.desc.Digest.String() == r.Digest {
+				delete(m.manifests, key)
+			}
+		}
+	}
+}
+
+// blobGet returns the memoized descriptor of a blob in the repository.
+func (m *memo) blobGet(r ref.Ref, d descriptor.Descriptor) (descriptor.Descriptor, bool) {
+	if m == nil || d.Digest == "" {
+		return descriptor.Descriptor{}, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bd, ok := m.blobs[memoBlobKey(r, d)]
+	return bd, ok
+}
+
+// blobSet memoizes a blob that exists in the repository.
+func (m *memo) blobSet(r ref.Ref, d descriptor.Descriptor) {
+	if m == nil || d.Digest == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blobs[memoBlobKey(r, d)] = d
+}
+
+// blobDrop removes a memoized blob.
+func (m *memo) blobDrop(r ref.Ref, d descriptor.Descriptor) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.blobs, memoBlobKey(r, d))
+}
+
+// memoManifestKey returns the key for the digest of the reference, or the tag when there is no digest.
+func memoManifestKey(r ref.Ref) (string, bool) {
+	if r.Digest != "" {
+		return r.SetDigest(r.Digest).CommonName(), true
+	}
+	if r.Tag != "" {
+		return r.SetTag(r.Tag).CommonName(), true
+	}
+	return "", false
+}
+
+func memoBlobKey(r ref.Ref, d descriptor.Descriptor) string {
+	return r.SetTag("").CommonName() + "@" + d.Digest.String()
+}
@@ -0,0 +1,167 @@
+package regclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/olareg/olareg"
+	oConfig "github.com/olareg/olareg/config"
+
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/types/ref"
+)
+
+func TestMemoize(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+		},
+	})
+	var mu sync.Mutex
+	reqs := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kind := ""
+		switch {
+		case strings.Contains(r.URL.Path, "/manifests/"):
+			kind = "manifest"
+		case strings.Contains(r.URL.Path, "/blobs/sha"):
+			kind = "blob"
+		}
+		if kind != "" {
+			mu.Lock()
+			reqs[r.Method+" "+kind]++
+			mu.Unlock()
+		}
+		regHandler.ServeHTTP(w, r)
+	}))
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+	rcHost := WithConfigHost(config.Host{
+		Name:     tsHost,
+		Hostname: tsHost,
+		TLS:      config.TLSDisabled,
+	})
+	rcPlain := New(rcHost)
+	rc := New(rcHost, WithMemoize())
+	rSrc, err := ref.New("ocidir://./testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rV1, err := ref.New(tsHost + "/memo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rV2 := rV1.SetTag("v2")
+	for _, tag := range []string{"v1", "v2"} {
+		if err := rcPlain.ImageCopy(ctx, rSrc.SetTag(tag), rV1.SetTag(tag)); err != nil {
+			t.Fatalf("failed to copy %s: %v", tag, err)
+		}
+	}
+	mV2, err := rcPlain.ManifestGet(ctx, rV2)
+	if err != nil {
+		t.Fatalf("failed to get v2: %v", err)
+	}
+	count := func(t *testing.T, expect map[string]int, fn func()) {
+		t.Helper()
+		mu.Lock()
+		clear(reqs)
+		mu.Unlock()
+		fn()
+		mu.Lock()
+		defer mu.Unlock()
+		for k, v := range expect {
+			if reqs[k] != v {
+				t.Errorf("unexpected %s requests, expected %d, received %d", k, v, reqs[k])
+			}
+		}
+		for k, v := range reqs {
+			if _, ok := expect[k]; !ok {
+				t.Errorf("unexpected %s requests, received %d", k, v)
+			}
+		}
+	}
+
+	count(t, map[string]int{"HEAD manifest": 1}, func() {
+		for range 3 {
+			if _, err := rc.ManifestHead(ctx, rV1); err != nil {
+				t.Errorf("failed to head v1: %v", err)
+			}
+		}
+	})
+	var dig string
+	count(t, map[string]int{"GET manifest": 1}, func() {
+		for range 3 {
+			m, err := rc.ManifestGet(ctx, rV1)
+			if err != nil {
+				t.Fatalf("failed to get v1: %v", err)
+			}
+			dig = m.GetDescriptor().Digest.String()
+		}
+		if _, err := rc.ManifestHead(ctx, rV1); err != nil {
+			t.Errorf("failed to head v1: %v", err)
+		}
+		m, err := rc.ManifestGet(ctx, rV1.SetDigest(dig))
+		if err != nil {
+			t.Fatalf("failed to get v1 digest: %v", err)
+		}
+		if !m.IsSet() || m.GetDescriptor().Digest.String() != dig {
+			t.Errorf("unexpected manifest for digest %s: %v", dig, m.GetDescriptor())
+		}
+	})
+	count(t, map[string]int{"HEAD blob": 1}, func() {
+		// olareg stores manifests as blobs in the repository
+		d := mV2.GetDescriptor()
+		for range 3 {
+			br, err := rc.BlobHead(ctx, rV2, d)
+			if err != nil {
+				t.Fatalf("failed to head blob: %v", err)
+			}
+			_ = br.Close()
+		}
+	})
+	count(t, map[string]int{"HEAD manifest": 2}, func() {
+		for range 2 {
+			if _, err := rc.ManifestHead(ctx, rV1.SetTag("missing")); err == nil {
+				t.Errorf("head of a missing tag did not fail")
+			}
+		}
+	})
+	t.Run("put", func(t *testing.T) {
+		count(t, map[string]int{"PUT manifest": 1, "GET manifest": 1}, func() {
+			if err := rc.ManifestPut(ctx, rV1, mV2); err != nil {
+				t.Fatalf("failed to put v1: %v", err)
+			}
+			m, err := rc.ManifestGet(ctx, rV1)
+			if err != nil {
+				t.Fatalf("failed to get v1: %v", err)
+			}
+			if m.GetDescriptor().Digest != mV2.GetDescriptor().Digest {
+				t.Errorf("stale manifest after put, expected %s, received %s", mV2.GetDescriptor().Digest, m.GetDescriptor().Digest)
+			}
+		})
+	})
+	t.Run("clear", func(t *testing.T) {
+		count(t, map[string]int{"HEAD manifest": 1}, func() {
+			if _, err := rc.ManifestHead(ctx, rV2); err != nil {
+				t.Errorf("failed to head v2: %v", err)
+			}
+		})
+		rc.MemoClear()
+		count(t, map[string]int{"HEAD manifest": 1}, func() {
+			if _, err := rc.ManifestHead(ctx, rV2); err != nil {
+				t.Errorf("failed to head v2: %v", err)
+			}
+		})
+	})
+}
@@ -3,16 +3,22 @@ package mod
 import (
 	"context"
 	"fmt"
+	"maps"
+	"path"
 	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/opencontainers/go-digest"
 
 	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/pkg/template"
 	"github.com/regclient/regclient/types/blob"
+	"github.com/regclient/regclient/types/errs"
+	v1 "github.com/regclient/regclient/types/oci/v1"
 	"github.com/regclient/regclient/types/platform"
 	"github.com/regclient/regclient/types/ref"
 )
@@ -125,6 +131,36 @@ func WithConfigEntrypoint(entrypoint []string) Opts {
 	}
 }
 
+// WithConfigExpose replaces the exposed ports in the config.
+// Each port is a number with an optional protocol, e.g. "8080" or "53/udp", and an empty list deletes every exposed port.
+func WithConfigExpose(ports []string) Opts {
+	return func(dc *dagConfig, dm *dagManifest) error {
+		exposed := map[string]struct{}{}
+		for _, port := range ports {
+			err := exposeValidate(port)
+			if err != nil {
+				return err
+			}
+			exposed[port] = struct{}{}
+		}
+		dc.stepsOCIConfig = append(dc.stepsOCIConfig, func(ctx context.Context, rc *regclient.RegClient, rSrc, rTgt ref.Ref, doc *dagOCIConfig) error {
+			oc := doc.oc.GetConfig()
+			if maps.Equal(exposed, oc.Config.ExposedPorts) {
+				return nil
+			}
+			oc.Config.ExposedPorts = nil
+			if len(exposed) > 0 {
+				oc.Config.ExposedPorts = maps.Clone(exposed)
+			}
+			doc.oc.SetConfig(oc)
+			doc.modified = true
+			doc.newDesc = doc.oc.GetDescriptor()
+			return nil
+		})
+		return nil
+	}
+}
+
 // WithConfigPlatform sets the platform in the config.
 func WithConfigPlatform(p platform.Platform) Opts {
 	return func(dc *dagConfig, dm *dagManifest) error {
@@ -243,45 +279,84 @@ func WithConfigTimestampMax(t time.Time) Opts {
 	})
 }
 
+// WithConfigUser sets the user in the config, e.g. "1000", "1000:1000", or "app:app".
+// An empty user deletes the value, running the image as root.
+func WithConfigUser(user string) Opts {
+	return func(dc *dagConfig, dm *dagManifest) error {
+		if strings.IndexFunc(user, unicode.IsSpace) >= 0 || strings.Count(user, ":") > 1 || strings.HasPrefix(user, ":") || strings.HasSuffix(user, ":") {
+			return fmt.Errorf("invalid user %q%.0w", user, errs.ErrParsingFailed)
+		}
+		dc.stepsOCIConfig = append(dc.stepsOCIConfig, func(ctx context.Context, rc *regclient.RegClient, rSrc, rTgt ref.Ref, doc *dagOCIConfig) error {
+			oc := doc.oc.GetConfig()
+			if oc.Config.User == user {
+				return nil
+			}
+			oc.Config.User = user
+			doc.oc.SetConfig(oc)
+			doc.modified = true
+			doc.newDesc = doc.oc.GetDescriptor()
+			return nil
+		})
+		return nil
+	}
+}
+
+// WithConfigWorkingDir sets the working directory in the config.
+// The directory must be an absolute path, and an empty string deletes the value.
+func WithConfigWorkingDir(dir string) Opts {
+	return func(dc *dagConfig, dm *dagManifest) error {
+		if dir != "" && !path.IsAbs(dir) {
+			return fmt.Errorf("working directory must be an absolute path: %s%.0w", dir, errs.ErrParsingFailed)
+		}
+		dc.stepsOCIConfig = append(dc.stepsOCIConfig, func(ctx context.Context, rc *regclient.RegClient, rSrc, rTgt ref.Ref, doc *dagOCIConfig) error {
+			oc := doc.oc.GetConfig()
+			if oc.Config.WorkingDir == dir {
+				return nil
+			}
+			oc.Config.WorkingDir = dir
+			doc.oc.SetConfig(oc)
+			doc.modified = true
+			doc.newDesc = doc.oc.GetDescriptor()
+			return nil
+		})
+		return nil
+	}
+}
+
 // WithEnv sets or deletes an environment variable from the image config.
 func WithEnv(name, value string) Opts {
+	return withEnvFn("env", name, func(ConfigTemplateData) (string, error) {
+		return value, nil
+	})
+}
+
+// WithEnvTemplate sets an environment variable in the image config from a Go template.
+// The template is expanded for each image config with [ConfigTemplateData], and an empty result deletes the variable.
+// The name may be prefixed with a platform list, the same as [WithEnv].
+func WithEnvTemplate(name, tmpl string) Opts {
+	return withEnvFn("env", name, func(data ConfigTemplateData) (string, error) {
+		return template.String(tmpl, data)
+	})
+}
+
+func withEnvFn(kind, name string, valueFn func(ConfigTemplateData) (string, error)) Opts {
 	return func(dc *dagConfig, dm *dagManifest) error {
-		// extract the list for platforms to update from the name
-		name = strings.TrimSpace(name)
-		platforms := []platform.Platform{}
-		if name[0] == '[' && strings.Index(name, "]") > 0 {
-			end := strings.Index(name, "]")
-			for entry := range strings.SplitSeq(name[1:end], ",") {
-				entry = strings.TrimSpace(entry)
-				if entry == "*" {
-					continue
-				}
-				p, err := platform.Parse(entry)
-				if err != nil {
-					return fmt.Errorf("failed to parse env platform %s: %w", entry, err)
-				}
-				platforms = append(platforms, p)
-			}
-			name = name[end+1:]
+		name, platforms, err := configPlatformPrefix(kind, name)
+		if err != nil {
+			return err
 		}
 		dc.stepsOCIConfig = append(dc.stepsOCIConfig, func(c context.Context, rc *regclient.RegClient, rSrc, rTgt ref.Ref, doc *dagOCIConfig) error {
 			// if platforms are listed, skip non-matching platforms
-			if len(platforms) > 0 {
-				p := doc.oc.GetConfig().Platform
-				found := false
-				for _, pe := range platforms {
-					if platform.Match(p, pe) {
-						found = true
-						break
-					}
-				}
-				if !found {
-					return nil
-				}
+			if !configPlatformMatch(doc, platforms) {
+				return nil
 			}
 			changed := false
 			found := false
 			oc := doc.oc.GetConfig()
+			value, err := valueFn(newConfigTemplateData(oc, name, envValue(oc.Config.Env, name)))
+			if err != nil {
+				return fmt.Errorf("failed to expand %s template for %s: %w", kind, name, err)
+			}
 			for i, kv := range oc.Config.Env {
 				kvSplit := strings.SplitN(kv, "=", 2)
 				if kvSplit[0] != name {
@@ -365,39 +440,30 @@ func WithExposeRm(port string) Opts {
 
 // WithLabel sets or deletes a label from the image config.
 func WithLabel(name, value string) Opts {
+	return withLabelFn("label", name, func(ConfigTemplateData) (string, error) {
+		return value, nil
+	})
+}
+
+// WithLabelTemplate sets a label in the image config from a Go template.
+// The template is expanded for each image config with [ConfigTemplateData], and an empty result deletes the label.
+// The name may be prefixed with a platform list, the same as [WithLabel].
+func WithLabelTemplate(name, tmpl string) Opts {
+	return withLabelFn("label", name, func(data ConfigTemplateData) (string, error) {
+		return template.String(tmpl, data)
+	})
+}
+
+func withLabelFn(kind, name string, valueFn func(ConfigTemplateData) (string, error)) Opts {
 	return func(dc *dagConfig, dm *dagManifest) error {
-		// extract the list for platforms to update from the name
-		name = strings.TrimSpace(name)
-		platforms := []platform.Platform{}
-		if name[0] == '[' && strings.Index(name, "]") > 0 {
-			end := strings.Index(name, "]")
-			for entry := range strings.SplitSeq(name[1:end], ",") {
-				entry = strings.TrimSpace(entry)
-				if entry == "*" {
-					continue
-				}
-				p, err := platform.Parse(entry)
-				if err != nil {
-					return fmt.Errorf("failed to parse label platform %s: %w", entry, err)
-				}
-				platforms = append(platforms, p)
-			}
-			name = name[end+1:]
+		name, platforms, err := configPlatformPrefix(kind, name)
+		if err != nil {
+			return err
 		}
 		dc.stepsOCIConfig = append(dc.stepsOCIConfig, func(c context.Context, rc *regclient.RegClient, rSrc, rTgt ref.Ref, doc *dagOCIConfig) error {
 			// if platforms are listed, skip non-matching platforms
-			if len(platforms) > 0 {
-				p := doc.oc.GetConfig().Platform
-				found := false
-				for _, pe := range platforms {
-					if platform.Match(p, pe) {
-						found = true
-						break
-					}
-				}
-				if !found {
-					return nil
-				}
+			if !configPlatformMatch(doc, platforms) {
+				return nil
 			}
 			changed := false
 			oc := doc.oc.GetConfig()
@@ -405,6 +471,10 @@ func WithLabel(name, value string) Opts {
 				oc.Config.Labels = map[string]string{}
 			}
 			cur, ok := oc.Config.Labels[name]
+			value, err := valueFn(newConfigTemplateData(oc, name, cur))
+			if err != nil {
+				return fmt.Errorf("failed to expand %s template for %s: %w", kind, name, err)
+			}
 			if value == "" && ok {
 				delete(oc.Config.Labels, name)
 				changed = true
@@ -470,3 +540,89 @@ func WithVolumeRm(volume string) Opts {
 		return nil
 	}
 }
+
+// ConfigTemplateData is the data available to the templates of [WithEnvTemplate] and [WithLabelTemplate].
+type ConfigTemplateData struct {
+	Name     string            // Name of the label or environment variable being set.
+	Value    string            // Value is the current value, empty when not set.
+	Labels   map[string]string // Labels in the image config.
+	Env      map[string]string // Env contains the environment variables in the image config.
+	Platform platform.Platform // Platform of the image config.
+}
+
+func newConfigTemplateData(oc v1.Image, name, value string) ConfigTemplateData {
+	data := ConfigTemplateData{
+		Name:     name,
+		Value:    value,
+		Labels:   map[string]string{},
+		Env:      map[string]string{},
+		Platform: oc.Platform,
+	}
+	maps.Copy(data.Labels, oc.Config.Labels)
+	for _, kv := range oc.Config.Env {
+		k, v, _ := strings.Cut(kv, "=")
+		data.Env[k] = v
+	}
+	return data
+}
+
+// envValue returns the value of an environment variable from a list of name=value entries.
+func envValue(env []string, name string) string {
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		if k == name {
+			return v
+		}
+	}
+	return ""
+}
+
+// configPlatformPrefix extracts the list of platforms to update from the name.
+// A "[*]" prefix, or no prefix, updates every platform.
+func configPlatformPrefix(kind, name string) (string, []platform.Platform, error) {
+	name = strings.TrimSpace(name)
+	platforms := []platform.Platform{}
+	if name != "" && name[0] == '[' && strings.Index(name, "]") > 0 {
+		end := strings.Index(name, "]")
+		for entry := range strings.SplitSeq(name[1:end], ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "*" {
+				continue
+			}
+			p, err := platform.Parse(entry)
+			if err != nil {
+				return name, nil, fmt.Errorf("failed to parse %s platform %s: %w", kind, entry, err)
+			}
+			platforms = append(platforms, p)
+		}
+		name = name[end+1:]
+	}
+	return name, platforms, nil
+}
+
+// configPlatformMatch returns true when the platform of the config matches the list, or the list is empty.
+func configPlatformMatch(doc *dagOCIConfig, platforms []platform.Platform) bool {
+	if len(platforms) == 0 {
+		return true
+	}
+	p := doc.oc.GetConfig().Platform
+	for _, pe := range platforms {
+		if platform.Match(p, pe) {
+			return true
+		}
+	}
+	return false
+}
+
+// exposeValidate verifies an exposed port is a port number with an optional tcp, udp, or sctp protocol.
+func exposeValidate(port string) error {
+	num, proto, hasProto := strings.Cut(port, "/")
+	if hasProto && proto != "tcp" && proto != "udp" && proto != "sctp" {
+		return fmt.Errorf("invalid protocol for exposed port %s%.0w", port, errs.ErrParsingFailed)
+	}
+	i, err := strconv.Atoi(num)
+	if err != nil || i < 1 || i > 65535 {
+		return fmt.Errorf("invalid exposed port %s%.0w", port, errs.ErrParsingFailed)
+	}
+	return nil
+}
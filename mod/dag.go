@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"slices"
 
 	"github.com/opencontainers/go-digest"
@@ -38,6 +39,15 @@ type dagConfig struct {
 	maxDataSize    int64
 	rTgt           ref.Ref
 	forceLayerWalk bool
+	layerEStargz   bool
+	// externalInclude selects the external layers to copy, all external layers are skipped when nil
+	externalInclude func(descriptor.Descriptor) bool
+	// referrerInclude selects the referrers to update, all referrers in the same repository are updated when nil
+	referrerInclude func(descriptor.Descriptor) bool
+	// digestTags copies digest tags to the new image digest
+	digestTags bool
+	// fileScrubCallback reports each file changed by WithFileContentScrub
+	fileScrubCallback func(FileScrub)
 }
 
 type dagManifest struct {
@@ -50,6 +60,7 @@ type dagManifest struct {
 	layers    []*dagLayer
 	manifests []*dagManifest
 	referrers []*dagManifest
+	refDesc   descriptor.Descriptor // descriptor from the referrers response, including the artifact type and annotations
 }
 
 type dagOCIConfig struct {
@@ -124,6 +135,7 @@ func dagGet(ctx context.Context, rc *regclient.RegClient, rSrc ref.Ref, d descri
 		return nil, fmt.Errorf("failed to get referrers: %w", err)
 	}
 	for _, desc := range rl.Descriptors {
+		refDesc := desc
 		// strip referrers metadata from descriptor (annotations and artifact type)
 		desc.ArtifactType = ""
 		if len(desc.Annotations) > 0 {
@@ -134,6 +146,7 @@ func dagGet(ctx context.Context, rc *regclient.RegClient, rSrc ref.Ref, d descri
 		if err != nil {
 			return nil, err
 		}
+		curMM.refDesc = refDesc
 		dm.referrers = append(dm.referrers, curMM)
 	}
 	return &dm, nil
@@ -198,6 +211,25 @@ func dagPut(ctx context.Context, rc *regclient.RegClient, mc dagConfig, rSrc, rT
 				changed = true
 			}
 		}
+		// update the attestations included in the index to reference the modified images
+		for i, child := range dm.manifests {
+			if child.mod == deleted || i >= len(ociI.Manifests) || ociI.Manifests[i].Annotations[dockerReferenceType] != dockerReferenceTypeAttestation {
+				continue
+			}
+			if mc.referrerInclude != nil && !mc.referrerInclude(ociI.Manifests[i]) {
+				continue
+			}
+			subject := slices.IndexFunc(dm.manifests, func(dmSubj *dagManifest) bool {
+				return dmSubj.origDesc.Digest.String() == ociI.Manifests[i].Annotations[dockerReferenceDigest]
+			})
+			if subject < 0 || dm.manifests[subject].mod == deleted || dm.manifests[subject].newDesc.Digest == "" ||
+				dm.manifests[subject].newDesc.Digest == dm.manifests[subject].origDesc.Digest {
+				continue
+			}
+			ociI.Manifests[i].Annotations = maps.Clone(ociI.Manifests[i].Annotations)
+			ociI.Manifests[i].Annotations[dockerReferenceDigest] = dm.manifests[subject].newDesc.Digest.String()
+			changed = true
+		}
 		// second pass in reverse to delete entries
 		for i := len(dm.manifests) - 1; i >= 0; i-- {
 			child := dm.manifests[i]
@@ -403,28 +435,42 @@ func dagPut(ctx context.Context, rc *regclient.RegClient, mc dagConfig, rSrc, rT
 	if dm.mod == replaced || dm.mod == added {
 		dm.newDesc = dm.m.GetDescriptor()
 	}
-	if ref.EqualRepository(rSrc, rTgt) {
-		// only update referrers when modifying a manifest in the same repository
-		for i := range dm.referrers {
-			if dm.referrers[i].mod == deleted || !(dm.mod == replaced || dm.mod == added || dm.referrers[i].mod == added) {
+	sameRepo := ref.EqualRepository(rSrc, rTgt)
+	if sameRepo || mc.referrerInclude != nil {
+		// referrers are only copied to a different repository when selected
+		referrers := []*dagManifest{}
+		for _, child := range dm.referrers {
+			if child.mod == deleted || (child.mod == unchanged && mc.referrerInclude != nil && !mc.referrerInclude(child.refDesc)) {
 				continue
 			}
-			sm, ok := dm.referrers[i].m.(manifest.Subjecter)
+			referrers = append(referrers, child)
+		}
+		for _, child := range referrers {
+			if !(dm.mod == replaced || dm.mod == added || child.mod == added) {
+				continue
+			}
+			sm, ok := child.m.(manifest.Subjecter)
 			if !ok {
-				return fmt.Errorf("referrer does not support subject field, mt=%s", dm.referrers[i].m.GetDescriptor().MediaType)
+				return fmt.Errorf("referrer does not support subject field, mt=%s", child.m.GetDescriptor().MediaType)
 			}
 			d := dm.m.GetDescriptor()
 			err = sm.SetSubject(&d)
 			if err != nil {
 				return fmt.Errorf("failed to set subject: %w", err)
 			}
-			if dm.referrers[i].mod == unchanged {
-				dm.referrers[i].mod = replaced
+			if child.mod == unchanged {
+				child.mod = replaced
 			}
-			dm.referrers[i].newDesc = dm.referrers[i].m.GetDescriptor()
+			child.newDesc = child.m.GetDescriptor()
 		}
 		// recursively push referrers
-		for _, child := range dm.referrers {
+		for _, child := range referrers {
+			if !sameRepo {
+				err = dagCopyLayers(ctx, rc, rSrc, rTgt, child)
+				if err != nil {
+					return err
+				}
+			}
 			err = dagPut(ctx, rc, mc, rSrc, rTgt, child)
 			if err != nil {
 				return err
@@ -450,6 +496,27 @@ func dagPut(ctx context.Context, rc *regclient.RegClient, mc dagConfig, rSrc, rT
 	return nil
 }
 
+// dagCopyLayers copies the layers of a referrer to a different repository.
+// Referrer layers are not modified, so they are skipped by [dagWalkLayers].
+func dagCopyLayers(ctx context.Context, rc *regclient.RegClient, rSrc, rTgt ref.Ref, dm *dagManifest) error {
+	for _, child := range dm.manifests {
+		err := dagCopyLayers(ctx, rc, rSrc, rTgt, child)
+		if err != nil {
+			return err
+		}
+	}
+	for _, layer := range dm.layers {
+		if len(layer.desc.URLs) > 0 {
+			continue
+		}
+		err := rc.BlobCopy(ctx, rSrc, rTgt, layer.desc)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func dagWalkManifests(dm *dagManifest, fn func(*dagManifest) (*dagManifest, error)) error {
 	if dm.manifests != nil {
 		for _, child := range dm.manifests {
@@ -2,13 +2,17 @@ package mod
 
 import (
 	"archive/tar"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -105,12 +109,31 @@ func WithLayerAddTar(rdr io.Reader, mt string, platforms []platform.Platform) Op
 
 // WithLayerCompression alters the media type and compression algorithm of the layers.
 func WithLayerCompression(algo archive.CompressType) Opts {
+	return layerCompression(algo, 0, false)
+}
+
+// WithLayerCompressionLevel alters the media type and compression algorithm of the layers, compressing with the level.
+// Layers already compressed with the algorithm are not modified, see [WithLayerRecompress] to change those layers.
+func WithLayerCompressionLevel(algo archive.CompressType, level int) Opts {
+	return layerCompression(algo, level, false)
+}
+
+// WithLayerRecompress compresses every layer with the algorithm and level, including layers already compressed with the algorithm.
+// A level of 0 uses the default level of the algorithm.
+func WithLayerRecompress(algo archive.CompressType, level int) Opts {
+	return layerCompression(algo, level, true)
+}
+
+func layerCompression(algo archive.CompressType, level int, recompress bool) Opts {
 	return func(dc *dagConfig, dm *dagManifest) error {
 		switch algo {
 		case archive.CompressNone, archive.CompressGzip, archive.CompressZstd:
 		default:
 			return fmt.Errorf("unsupported layer compression: %s", algo.String())
 		}
+		if err := archive.CompressLevelValidate(algo, level); err != nil {
+			return err
+		}
 		dc.stepsLayer = append(dc.stepsLayer, func(ctx context.Context, rc *regclient.RegClient, rSrc, rTgt ref.Ref, dl *dagLayer, rdr io.ReadCloser) (io.ReadCloser, error) {
 			if dl.mod == deleted {
 				return rdr, nil
@@ -125,12 +148,15 @@ func WithLayerCompression(algo archive.CompressType) Opts {
 				return nil, fmt.Errorf("failed to configure digest algorithm for changing layer compression: %w", err)
 			}
 			desc.Digest = ""
+			desc.Annotations = layerAnnotSeekableStrip(desc.Annotations)
 			switch algo {
 			case archive.CompressGzip:
-				switch desc.MediaType {
-				case mediatype.Docker2Layer, mediatype.Docker2LayerZstd:
+				switch {
+				case desc.MediaType == mediatype.Docker2Layer, desc.MediaType == mediatype.Docker2LayerZstd,
+					recompress && desc.MediaType == mediatype.Docker2LayerGzip:
 					desc.MediaType = mediatype.Docker2LayerGzip
-				case mediatype.OCI1Layer, mediatype.OCI1LayerZstd:
+				case desc.MediaType == mediatype.OCI1Layer, desc.MediaType == mediatype.OCI1LayerZstd,
+					recompress && desc.MediaType == mediatype.OCI1LayerGzip:
 					desc.MediaType = mediatype.OCI1LayerGzip
 				default:
 					return rdr, nil
@@ -147,7 +173,7 @@ func WithLayerCompression(algo archive.CompressType) Opts {
 					return nil, err
 				}
 				ucDigRdr := io.TeeReader(ucRdr, digUC.Hash())
-				cRdr, err := archive.Compress(ucDigRdr, algo)
+				cRdr, err := archive.Compress(ucDigRdr, algo, archive.CompressLevel(level))
 				if err != nil {
 					_ = rdr.Close()
 					return nil, err
@@ -168,10 +194,12 @@ func WithLayerCompression(algo archive.CompressType) Opts {
 				}, nil
 
 			case archive.CompressZstd:
-				switch desc.MediaType {
-				case mediatype.Docker2Layer, mediatype.Docker2LayerGzip:
+				switch {
+				case desc.MediaType == mediatype.Docker2Layer, desc.MediaType == mediatype.Docker2LayerGzip,
+					recompress && desc.MediaType == mediatype.Docker2LayerZstd:
 					desc.MediaType = mediatype.Docker2LayerZstd
-				case mediatype.OCI1Layer, mediatype.OCI1LayerGzip:
+				case desc.MediaType == mediatype.OCI1Layer, desc.MediaType == mediatype.OCI1LayerGzip,
+					recompress && desc.MediaType == mediatype.OCI1LayerZstd:
 					desc.MediaType = mediatype.OCI1LayerZstd
 				default:
 					return rdr, nil
@@ -188,7 +216,7 @@ func WithLayerCompression(algo archive.CompressType) Opts {
 					return nil, err
 				}
 				ucDigRdr := io.TeeReader(ucRdr, digUC.Hash())
-				cRdr, err := archive.Compress(ucDigRdr, algo)
+				cRdr, err := archive.Compress(ucDigRdr, algo, archive.CompressLevel(level))
 				if err != nil {
 					_ = rdr.Close()
 					return nil, err
@@ -321,6 +349,116 @@ func WithLayerDigestAlgo(algo digest.Algorithm) Opts {
 	}
 }
 
+// WithLayerEStargz converts layers to eStargz, a gzip compressed format with a TOC used by lazy pulling runtimes.
+// The conversion is performed after all other changes to the layer.
+// Unmodified layers that are already eStargz are skipped.
+func WithLayerEStargz() Opts {
+	return func(dc *dagConfig, dm *dagManifest) error {
+		dc.layerEStargz = true
+		dc.forceLayerWalk = true
+		return nil
+	}
+}
+
+// layerEStargz returns a reader with the layer converted to eStargz.
+// If rdr is nil, the layer is pulled from rSrc.
+func layerEStargz(ctx context.Context, rc *regclient.RegClient, rSrc ref.Ref, dl *dagLayer, rdr io.ReadCloser) (io.ReadCloser, error) {
+	desc := dl.desc
+	if dl.newDesc.MediaType != "" {
+		desc = dl.newDesc
+	}
+	if dl.mod == unchanged && desc.Annotations[archive.AnnotationEStargzTOCDigest] != "" &&
+		(desc.MediaType == mediatype.Docker2LayerGzip || desc.MediaType == mediatype.OCI1LayerGzip) {
+		return rdr, nil
+	}
+	switch desc.MediaType {
+	case mediatype.Docker2Layer, mediatype.Docker2LayerGzip, mediatype.Docker2LayerZstd:
+		desc.MediaType = mediatype.Docker2LayerGzip
+	case mediatype.OCI1Layer, mediatype.OCI1LayerGzip, mediatype.OCI1LayerZstd:
+		desc.MediaType = mediatype.OCI1LayerGzip
+	default:
+		return rdr, nil
+	}
+	if rdr == nil {
+		bRdr, err := rc.BlobGet(ctx, rSrc, dl.desc)
+		if err != nil {
+			return nil, err
+		}
+		rdr = bRdr
+	}
+	desc.Size = 0
+	err := desc.DigestAlgoPrefer(desc.DigestAlgo())
+	if err != nil {
+		_ = rdr.Close()
+		return nil, fmt.Errorf("failed to configure digest algorithm for eStargz layer: %w", err)
+	}
+	desc.Digest = ""
+	desc.Annotations = layerAnnotSeekableStrip(desc.Annotations)
+	if dl.mod == unchanged {
+		dl.mod = replaced
+	}
+	dl.newDesc = desc
+	ucRdr, err := archive.Decompress(rdr)
+	if err != nil {
+		_ = rdr.Close()
+		return nil, err
+	}
+	digRaw := desc.DigestAlgo().Digester() // raw/compressed digest
+	pr, pw := io.Pipe()
+	var info archive.EStargzInfo
+	var errConv error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		info, errConv = archive.EStargz(pw, ucRdr)
+		_ = pw.CloseWithError(errConv)
+	}()
+	closed := false
+	return readCloserFn{
+		Reader: io.TeeReader(pr, digRaw.Hash()),
+		closeFn: func() error {
+			if closed {
+				return nil
+			}
+			closed = true
+			err := rdr.Close()
+			_ = pr.Close()
+			<-done
+			if err != nil {
+				return err
+			}
+			if errConv != nil {
+				return errConv
+			}
+			dl.newDesc.Digest = digRaw.Digest()
+			dl.ucDigest = info.UncompressedDigest
+			if dl.newDesc.Annotations == nil {
+				dl.newDesc.Annotations = map[string]string{}
+			}
+			maps.Copy(dl.newDesc.Annotations, info.Annotations())
+			return nil
+		},
+	}, nil
+}
+
+// layerAnnotSeekableStrip returns the annotations without the values that describe the content of a seekable layer.
+func layerAnnotSeekableStrip(annotations map[string]string) map[string]string {
+	if !slices.ContainsFunc(archive.SeekableAnnotations, func(k string) bool {
+		_, ok := annotations[k]
+		return ok
+	}) {
+		return annotations
+	}
+	annotations = maps.Clone(annotations)
+	for _, k := range archive.SeekableAnnotations {
+		delete(annotations, k)
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+	return annotations
+}
+
 // WithLayerReproducible modifies the layer with reproducible options.
 // This currently configures users and groups with numeric ids.
 func WithLayerReproducible() Opts {
@@ -345,6 +483,320 @@ func WithLayerReproducible() Opts {
 	}
 }
 
+// WithLayerReslice replaces the layers of each image with a layer per top-level directory.
+// The layers are merged, applying whiteouts, and the resulting filesystem is split so
+// images that differ in a single directory share the layers for every other directory.
+// The mapping assigns a path and everything below it to a named layer, the longest matching path is used.
+// Top-level files and links are placed in a layer named "".
+// New layers are sorted by name, compressed with gzip, and file metadata is preserved.
+func WithLayerReslice(mapping map[string]string) Opts {
+	prefixes := map[string]string{}
+	for k, v := range mapping {
+		prefixes[strings.Trim(filepath.ToSlash(k), "/")] = v
+	}
+	return func(dc *dagConfig, dm *dagManifest) error {
+		dc.stepsManifest = append(dc.stepsManifest, func(ctx context.Context, rc *regclient.RegClient, rSrc, rTgt ref.Ref, dm *dagManifest) error {
+			if dm.mod == deleted || dm.m.IsList() || dm.config == nil || dm.config.oc == nil {
+				return nil
+			}
+			return layerReslice(ctx, rc, rSrc, rTgt, dm, prefixes)
+		})
+		return nil
+	}
+}
+
+// resliceEntry is a file in the merged filesystem, with the content saved in a spool file.
+type resliceEntry struct {
+	th     *tar.Header
+	offset int64
+	size   int64
+}
+
+func layerReslice(ctx context.Context, rc *regclient.RegClient, rSrc, rTgt ref.Ref, dm *dagManifest, prefixes map[string]string) error {
+	spool, err := os.CreateTemp("", "regclient-reslice-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = spool.Close()
+		//#nosec G703 tempfile location is user controlled
+		_ = os.Remove(spool.Name())
+	}()
+	var spoolLen int64
+	entries := map[string]*resliceEntry{}
+	// merge each layer into the filesystem
+	for _, dl := range dm.layers {
+		if dl.mod == deleted {
+			continue
+		}
+		if !slices.Contains(mtKnownTar, dl.desc.MediaType) || len(dl.desc.URLs) > 0 {
+			return fmt.Errorf("cannot reslice layer %s, media type %s%.0w", dl.desc.Digest.String(), dl.desc.MediaType, errs.ErrUnsupportedMediaType)
+		}
+		rLayer := rSrc
+		if dl.rSrc.IsSet() {
+			rLayer = dl.rSrc
+		} else if dl.mod == added {
+			rLayer = rTgt
+		}
+		br, err := rc.BlobGet(ctx, rLayer, dl.desc)
+		if err != nil {
+			return err
+		}
+		dr, err := archive.Decompress(br)
+		if err != nil {
+			_ = br.Close()
+			return err
+		}
+		// entries in the current layer are not hidden by whiteouts in the same layer
+		cur := map[string]bool{}
+		tr := tar.NewReader(dr)
+		for {
+			th, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				_ = br.Close()
+				return fmt.Errorf("failed to read layer %s: %w", dl.desc.Digest.String(), err)
+			}
+			name := strings.Trim(strings.TrimPrefix(th.Name, "./"), "/")
+			if name == "" || name == "." || th.Typeflag == tar.TypeXGlobalHeader {
+				continue
+			}
+			dir, base := path.Split(name)
+			dir = strings.TrimSuffix(dir, "/")
+			if base == ".wh..wh..opq" {
+				for k := range entries {
+					if strings.HasPrefix(k, dir+"/") && !cur[k] {
+						delete(entries, k)
+					}
+				}
+				continue
+			}
+			if wh, ok := strings.CutPrefix(base, ".wh."); ok {
+				whName := path.Join(dir, wh)
+				for k := range entries {
+					if (k == whName || strings.HasPrefix(k, whName+"/")) && !cur[k] {
+						delete(entries, k)
+					}
+				}
+				continue
+			}
+			// a new file replaces a directory and everything below it
+			if prev, ok := entries[name]; ok && prev.th.Typeflag == tar.TypeDir && th.Typeflag != tar.TypeDir {
+				for k := range entries {
+					if strings.HasPrefix(k, name+"/") {
+						delete(entries, k)
+					}
+				}
+			}
+			e := &resliceEntry{th: th, offset: -1}
+			switch th.Typeflag {
+			case tar.TypeReg:
+				n, err := io.Copy(spool, tr)
+				if err != nil {
+					_ = br.Close()
+					return fmt.Errorf("failed to spool %s: %w", th.Name, err)
+				}
+				e.offset, e.size = spoolLen, n
+				spoolLen += n
+			case tar.TypeLink:
+				// save the content of the link target, which may be changed by a later layer
+				target := strings.Trim(strings.TrimPrefix(th.Linkname, "./"), "/")
+				if te, ok := entries[target]; ok {
+					e.offset, e.size = te.offset, te.size
+				}
+				th.Linkname = target
+			}
+			th.Name = name
+			entries[name] = e
+			cur[name] = true
+		}
+		err = br.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	// split the filesystem into named layers
+	group := func(name string, e *resliceEntry) string {
+		best := -1
+		g := ""
+		for prefix, pg := range prefixes {
+			if (name == prefix || strings.HasPrefix(name, prefix+"/") || prefix == "") && len(prefix) > best {
+				best = len(prefix)
+				g = pg
+			}
+		}
+		if best >= 0 {
+			return g
+		}
+		top, _, found := strings.Cut(name, "/")
+		if !found && e.th.Typeflag != tar.TypeDir {
+			return ""
+		}
+		return top
+	}
+	groups := map[string]map[string]bool{}
+	for name, e := range entries {
+		g := group(name, e)
+		if groups[g] == nil {
+			groups[g] = map[string]bool{}
+		}
+		groups[g][name] = true
+		// include parent directories from other layers to preserve their metadata
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if pe, ok := entries[dir]; ok && pe.th.Typeflag == tar.TypeDir {
+				groups[g][dir] = true
+			}
+		}
+	}
+
+	mt := mediatype.OCI1LayerGzip
+	switch dm.m.GetDescriptor().MediaType {
+	case mediatype.Docker2Manifest, mediatype.Docker2ManifestList:
+		mt = mediatype.Docker2LayerGzip
+	}
+	newLayers := []*dagLayer{}
+	for _, g := range slices.Sorted(maps.Keys(groups)) {
+		names := slices.Sorted(maps.Keys(groups[g]))
+		desc := descriptor.Descriptor{MediaType: mt}
+		err = desc.DigestAlgoPrefer(dm.m.GetDescriptor().DigestAlgo())
+		if err != nil {
+			return fmt.Errorf("failed to configure digest algorithm for new layer: %w", err)
+		}
+		digUC := desc.DigestAlgo().Digester()
+		pr, pw := io.Pipe()
+		go func() {
+			_ = pw.CloseWithError(layerResliceWrite(pw, spool, entries, names, func(name string) string {
+				return group(name, entries[name])
+			}))
+		}()
+		cRdr, err := archive.Compress(io.TeeReader(pr, digUC.Hash()), archive.CompressGzip)
+		if err != nil {
+			_ = pr.Close()
+			return fmt.Errorf("failed to compress layer: %w", err)
+		}
+		descPut, err := rc.BlobPut(ctx, rTgt, desc, cRdr)
+		_ = cRdr.Close()
+		_ = pr.Close()
+		if err != nil {
+			return fmt.Errorf("failed to push layer to %s: %w", rTgt.CommonName(), err)
+		}
+		desc.Digest = descPut.Digest
+		desc.Size = descPut.Size
+		newLayers = append(newLayers, &dagLayer{
+			mod:      added,
+			desc:     desc,
+			newDesc:  desc,
+			ucDigest: digUC.Digest(),
+			rSrc:     rTgt,
+		})
+	}
+	// previously added layers are dropped, and the original layers are deleted
+	dm.layers = slices.DeleteFunc(dm.layers, func(dl *dagLayer) bool { return dl.mod == added })
+	for _, dl := range dm.layers {
+		dl.mod = deleted
+	}
+	dm.layers = append(dm.layers, newLayers...)
+	return nil
+}
+
+// layerResliceWrite writes the tar for a new layer containing the named entries.
+// Hard links are written as regular files unless the unchanged target is earlier in the same layer.
+func layerResliceWrite(w io.Writer, spool *os.File, entries map[string]*resliceEntry, names []string, group func(string) string) error {
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		e := entries[name]
+		th := *e.th
+		if th.Typeflag == tar.TypeDir {
+			th.Name = name + "/"
+		}
+		if th.Typeflag == tar.TypeLink && e.offset >= 0 {
+			te, ok := entries[th.Linkname]
+			if !ok || te.offset != e.offset || th.Linkname >= name || group(th.Linkname) != group(name) {
+				th.Typeflag = tar.TypeReg
+				th.Linkname = ""
+				th.Size = e.size
+			}
+		}
+		err := tw.WriteHeader(&th)
+		if err != nil {
+			return err
+		}
+		if th.Typeflag == tar.TypeReg && e.size > 0 {
+			_, err = io.Copy(tw, io.NewSectionReader(spool, e.offset, e.size))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Close()
+}
+
+const (
+	// AnnotationLayersRemoved is the comma separated list of layer digests removed by [WithLayerRm].
+	AnnotationLayersRemoved = "org.regclient.layers.removed"
+	// AnnotationLayersSource is the digest of the manifest before layers were removed by [WithLayerRm].
+	AnnotationLayersSource = "org.regclient.layers.source"
+)
+
+// WithLayerRm deletes every layer matching the filter from each image and artifact manifest.
+// Modified manifests are annotated with the removed layers and the original manifest digest.
+// Removing layers from an image also removes the matching config history and diff ids.
+func WithLayerRm(filter func(descriptor.Descriptor) bool) Opts {
+	return func(dc *dagConfig, dm *dagManifest) error {
+		dc.stepsManifest = append(dc.stepsManifest, func(c context.Context, rc *regclient.RegClient, rSrc, rTgt ref.Ref, dm *dagManifest) error {
+			if dm.mod == deleted || dm.m.IsList() {
+				return nil
+			}
+			removed := []string{}
+			for _, layer := range dm.layers {
+				if layer.mod == added || layer.mod == deleted || !filter(layer.desc) {
+					continue
+				}
+				layer.mod = deleted
+				removed = append(removed, layer.desc.Digest.String())
+			}
+			if len(removed) == 0 {
+				return nil
+			}
+			ma, ok := dm.m.(manifest.Annotator)
+			if !ok {
+				return fmt.Errorf("manifest does not support annotations: %s%.0w", dm.m.GetDescriptor().MediaType, errs.ErrUnsupportedMediaType)
+			}
+			err := ma.SetAnnotation(AnnotationLayersRemoved, strings.Join(removed, ","))
+			if err != nil {
+				return err
+			}
+			err = ma.SetAnnotation(AnnotationLayersSource, dm.origDesc.Digest.String())
+			if err != nil {
+				return err
+			}
+			if dm.mod == unchanged {
+				dm.mod = replaced
+			}
+			dm.newDesc = dm.m.GetDescriptor()
+			return nil
+		})
+		return nil
+	}
+}
+
+// WithLayerRmDigest deletes layers by digest, see [WithLayerRm].
+func WithLayerRmDigest(digests ...digest.Digest) Opts {
+	return WithLayerRm(func(d descriptor.Descriptor) bool {
+		return slices.Contains(digests, d.Digest)
+	})
+}
+
+// WithLayerRmMediaType deletes layers by media type, see [WithLayerRm].
+func WithLayerRmMediaType(mediaTypes ...string) Opts {
+	return WithLayerRm(func(d descriptor.Descriptor) bool {
+		return slices.Contains(mediaTypes, d.MediaType)
+	})
+}
+
 // WithLayerRmCreatedBy deletes a layer based on a regex of the created by field
 // in the config history for that layer.
 func WithLayerRmCreatedBy(re regexp.Regexp) Opts {
@@ -754,6 +1206,72 @@ func WithFileTarTimeMax(name string, t time.Time) Opts {
 	})
 }
 
+// FileScrub describes a file changed by [WithFileContentScrub].
+type FileScrub struct {
+	Layer   digest.Digest // Layer is the digest of the original layer.
+	Name    string        // Name of the file within the layer.
+	Matches int           // Matches is the number of replaced matches.
+}
+
+// WithFileContentScrub replaces the content matching a regular expression in files within the layers.
+// The glob is matched against the full path of the file without a leading slash using [path.Match],
+// and a glob without a slash matches the file name in any directory.
+// The replacement may reference submatches as described by [regexp.Regexp.Expand].
+// Only regular files are changed, and matching files are read into memory.
+// Layers with a changed file are regenerated, updating the layer digests and config diff IDs.
+func WithFileContentScrub(glob string, re *regexp.Regexp, replacement string) Opts {
+	glob = strings.TrimPrefix(filepath.ToSlash(glob), "/")
+	return func(dc *dagConfig, dm *dagManifest) error {
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("invalid file glob %s: %w", glob, err)
+		}
+		if re == nil {
+			return fmt.Errorf("regular expression is required to scrub %s%.0w", glob, errs.ErrParsingFailed)
+		}
+		dc.stepsLayerFile = append(dc.stepsLayerFile, func(c context.Context, rc *regclient.RegClient, rSrc, rTgt ref.Ref, dl *dagLayer, th *tar.Header, tr io.Reader) (*tar.Header, io.Reader, changes, error) {
+			if th.Typeflag != tar.TypeReg || th.Size <= 0 {
+				return th, tr, unchanged, nil
+			}
+			name := strings.TrimPrefix(strings.TrimPrefix(th.Name, "./"), "/")
+			match, _ := path.Match(glob, name)
+			if !match && !strings.Contains(glob, "/") {
+				match, _ = path.Match(glob, path.Base(name))
+			}
+			if !match {
+				return th, tr, unchanged, nil
+			}
+			content := make([]byte, th.Size)
+			_, err := io.ReadFull(tr, content)
+			if err != nil {
+				return th, tr, unchanged, fmt.Errorf("failed to read %s: %w", th.Name, err)
+			}
+			matches := len(re.FindAllIndex(content, -1))
+			if matches == 0 {
+				return th, bytes.NewReader(content), unchanged, nil
+			}
+			content = re.ReplaceAll(content, []byte(replacement))
+			th.Size = int64(len(content))
+			if dc.fileScrubCallback != nil {
+				dc.fileScrubCallback(FileScrub{
+					Layer:   dl.desc.Digest,
+					Name:    th.Name,
+					Matches: matches,
+				})
+			}
+			return th, bytes.NewReader(content), replaced, nil
+		})
+		return nil
+	}
+}
+
+// WithFileContentScrubCallback is called for each file changed by [WithFileContentScrub].
+func WithFileContentScrubCallback(fn func(FileScrub)) Opts {
+	return func(dc *dagConfig, dm *dagManifest) error {
+		dc.fileScrubCallback = fn
+		return nil
+	}
+}
+
 type readCloserFn struct {
 	io.Reader
 	closeFn func() error
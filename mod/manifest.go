@@ -9,7 +9,9 @@ import (
 	"github.com/opencontainers/go-digest"
 
 	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/internal/urlhost"
 	"github.com/regclient/regclient/types"
+	"github.com/regclient/regclient/types/descriptor"
 	"github.com/regclient/regclient/types/docker/schema2"
 	"github.com/regclient/regclient/types/errs"
 	"github.com/regclient/regclient/types/manifest"
@@ -565,68 +567,101 @@ func WithManifestToOCIReferrers() Opts {
 // WithExternalURLsRm strips external URLs from descriptors and adjusts media type to match.
 func WithExternalURLsRm() Opts {
 	return func(dc *dagConfig, dm *dagManifest) error {
-		dc.stepsManifest = append(dc.stepsManifest, func(ctx context.Context, rc *regclient.RegClient, rSrc, rTgt ref.Ref, dm *dagManifest) error {
-			if dm.mod == deleted {
-				return nil
-			}
-			changed := false
-			if dm.m.IsList() {
-				return nil
-			}
-			om := dm.m.GetOrig()
-			ociOM, err := manifest.OCIManifestFromAny(om)
-			if err != nil {
-				return err
-			}
-			// strip layers from image
-			for i := range ociOM.Layers {
-				if len(ociOM.Layers[i].URLs) > 0 {
-					ociOM.Layers[i].URLs = []string{}
-					mt := ociOM.Layers[i].MediaType
-					switch mt {
-					case mediatype.Docker2ForeignLayer:
-						mt = mediatype.Docker2LayerGzip
-					case mediatype.OCI1ForeignLayer:
-						mt = mediatype.OCI1Layer
-					case mediatype.OCI1ForeignLayerGzip:
-						mt = mediatype.OCI1LayerGzip
-					case mediatype.OCI1ForeignLayerZstd:
-						mt = mediatype.OCI1LayerZstd
-					}
-					ociOM.Layers[i].MediaType = mt
-					changed = true
-				}
-			}
-			// also strip from dag so other steps don't skip the external layer
-			for i, dl := range dm.layers {
-				if dl.mod == deleted {
-					continue
-				}
-				if dl.newDesc.Digest == "" && len(dl.desc.URLs) > 0 {
-					dl.newDesc = dl.desc
-				}
-				if len(dl.newDesc.URLs) > 0 {
-					dl.newDesc.URLs = []string{}
-					dm.layers[i] = dl
-				}
+		dc.stepsManifest = append(dc.stepsManifest, externalURLsRm(func(d descriptor.Descriptor) bool { return true }))
+		return nil
+	}
+}
+
+// WithExternalLayers sets the handling of external layers, which are foreign or non-distributable layers with URLs commonly found in Windows images.
+// By default, external layers are skipped ([regclient.ExternalSkip]).
+// With [regclient.ExternalCopy], the layers are copied from the source repository and the URLs are preserved.
+// With [regclient.ExternalEmbed], the layers are copied, the URLs are removed, and the media type is changed to a distributable layer.
+// When hosts are provided, only external layers with a URL on one of the hosts are copied or embedded, other external layers are skipped.
+func WithExternalLayers(policy regclient.ExternalPolicy, hosts ...string) Opts {
+	return func(dc *dagConfig, dm *dagManifest) error {
+		switch policy {
+		case regclient.ExternalSkip:
+			dc.externalInclude = nil
+			return nil
+		case regclient.ExternalCopy, regclient.ExternalEmbed:
+		default:
+			return fmt.Errorf("unknown external layer policy %q%.0w", policy, errs.ErrUnsupported)
+		}
+		dc.externalInclude = func(d descriptor.Descriptor) bool {
+			return len(hosts) == 0 || urlhost.Match(d.URLs, hosts)
+		}
+		if policy == regclient.ExternalEmbed {
+			dc.stepsManifest = append(dc.stepsManifest, externalURLsRm(dc.externalInclude))
+		}
+		return nil
+	}
+}
+
+// externalURLsRm returns a manifest step that strips external URLs from the layers matching the filter.
+func externalURLsRm(filter func(descriptor.Descriptor) bool) func(context.Context, *regclient.RegClient, ref.Ref, ref.Ref, *dagManifest) error {
+	return func(ctx context.Context, rc *regclient.RegClient, rSrc, rTgt ref.Ref, dm *dagManifest) error {
+		if dm.mod == deleted {
+			return nil
+		}
+		changed := false
+		if dm.m.IsList() {
+			return nil
+		}
+		om := dm.m.GetOrig()
+		ociOM, err := manifest.OCIManifestFromAny(om)
+		if err != nil {
+			return err
+		}
+		// strip layers from image
+		for i := range ociOM.Layers {
+			if len(ociOM.Layers[i].URLs) > 0 && filter(ociOM.Layers[i]) {
+				ociOM.Layers[i].URLs = []string{}
+				mt := ociOM.Layers[i].MediaType
+				switch mt {
+				case mediatype.Docker2ForeignLayer:
+					mt = mediatype.Docker2LayerGzip
+				case mediatype.OCI1ForeignLayer:
+					mt = mediatype.OCI1Layer
+				case mediatype.OCI1ForeignLayerGzip:
+					mt = mediatype.OCI1LayerGzip
+				case mediatype.OCI1ForeignLayerZstd:
+					mt = mediatype.OCI1LayerZstd
+				}
+				ociOM.Layers[i].MediaType = mt
+				changed = true
 			}
-			if !changed {
-				return nil
+		}
+		// also strip from dag so other steps don't skip the external layer
+		for i, dl := range dm.layers {
+			if dl.mod == deleted {
+				continue
 			}
-			err = manifest.OCIManifestToAny(ociOM, &om)
-			if err != nil {
-				return err
+			if len(dl.desc.URLs) > 0 && !filter(dl.desc) {
+				continue
 			}
-			err = dm.m.SetOrig(om)
-			if err != nil {
-				return err
+			if dl.newDesc.Digest == "" && len(dl.desc.URLs) > 0 {
+				dl.newDesc = dl.desc
 			}
-			dm.newDesc = dm.m.GetDescriptor()
-			if dm.mod == unchanged {
-				dm.mod = replaced
+			if len(dl.newDesc.URLs) > 0 {
+				dl.newDesc.URLs = []string{}
+				dm.layers[i] = dl
 			}
+		}
+		if !changed {
 			return nil
-		})
+		}
+		err = manifest.OCIManifestToAny(ociOM, &om)
+		if err != nil {
+			return err
+		}
+		err = dm.m.SetOrig(om)
+		if err != nil {
+			return err
+		}
+		dm.newDesc = dm.m.GetDescriptor()
+		if dm.mod == unchanged {
+			dm.mod = replaced
+		}
 		return nil
 	}
 }
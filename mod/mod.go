@@ -126,8 +126,8 @@ func Apply(ctx context.Context, rc *regclient.RegClient, rSrc ref.Ref, opts ...O
 			if dl.rSrc.IsSet() {
 				rSrc = dl.rSrc
 			}
-			if dl.mod == deleted || len(dl.desc.URLs) > 0 {
-				// skip deleted and external layers
+			if dl.mod == deleted || (len(dl.desc.URLs) > 0 && (dc.externalInclude == nil || !dc.externalInclude(dl.desc))) {
+				// skip deleted and excluded external layers
 				return dl, nil
 			}
 			// changes for the entire layer
@@ -295,6 +295,7 @@ func Apply(ctx context.Context, rc *regclient.RegClient, rSrc ref.Ref, opts ...O
 					rdr = fh
 					desc.Digest = digRaw.Digest()
 					desc.Size = l
+					desc.Annotations = layerAnnotSeekableStrip(desc.Annotations)
 					dl.newDesc = desc
 					dl.ucDigest = digUC.Digest()
 					if dl.mod == unchanged {
@@ -302,6 +303,14 @@ func Apply(ctx context.Context, rc *regclient.RegClient, rSrc ref.Ref, opts ...O
 					}
 				}
 			}
+			// convert to eStargz after all other changes to the layer
+			if dc.layerEStargz && dl.mod != deleted {
+				rdrNext, err := layerEStargz(ctx, rc, rSrc, dl, rdr)
+				if err != nil {
+					return nil, err
+				}
+				rdr = rdrNext
+			}
 			// if added or replaced, and reader not nil, push blob
 			if (dl.mod == added || dl.mod == replaced) && rdr != nil {
 				// push the blob and verify the results
@@ -343,6 +352,12 @@ func Apply(ctx context.Context, rc *regclient.RegClient, rSrc ref.Ref, opts ...O
 	if err != nil {
 		return rTgt, err
 	}
+	if dc.digestTags {
+		err = digestTagsCopy(ctx, rc, &dc, rSrc, rTgt, dm)
+		if err != nil {
+			return rTgt, err
+		}
+	}
 	if rTgt.Tag == "" || rTgt.Digest != "" {
 		rTgt = rTgt.AddDigest(dm.m.GetDescriptor().Digest.String())
 	}
@@ -1,15 +1,20 @@
 package mod
 
 import (
+	"archive/tar"
 	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,10 +27,14 @@ import (
 	"github.com/regclient/regclient/internal/copyfs"
 	"github.com/regclient/regclient/pkg/archive"
 	"github.com/regclient/regclient/scheme/reg"
+	"github.com/regclient/regclient/types/descriptor"
 	"github.com/regclient/regclient/types/errs"
 	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/mediatype"
+	v1 "github.com/regclient/regclient/types/oci/v1"
 	"github.com/regclient/regclient/types/platform"
 	"github.com/regclient/regclient/types/ref"
+	"github.com/regclient/regclient/types/referrer"
 )
 
 func TestMod(t *testing.T) {
@@ -320,6 +329,36 @@ func TestMod(t *testing.T) {
 			ref:      tTgtHost + "/testrepo:v1",
 			wantSame: true,
 		},
+		{
+			name: "Label Template",
+			opts: []Opts{
+				WithLabelTemplate("version", "{{ .Value }}-patched"),
+			},
+			ref: tTgtHost + "/testrepo:v1",
+		},
+		{
+			name: "Label Template Unchanged",
+			opts: []Opts{
+				WithLabelTemplate("[*]version", "{{ .Value }}"),
+			},
+			ref:      tTgtHost + "/testrepo:v1",
+			wantSame: true,
+		},
+		{
+			name: "Label Template Delete",
+			opts: []Opts{
+				WithLabelTemplate("version", `{{ if eq .Value "missing" }}{{ .Value }}{{ end }}`),
+			},
+			ref: tTgtHost + "/testrepo:v1",
+		},
+		{
+			name: "Label Template Error",
+			opts: []Opts{
+				WithLabelTemplate("version", "{{ .Missing }}"),
+			},
+			ref:     tTgtHost + "/testrepo:v1",
+			wantErr: fmt.Errorf(`failed to expand label template for version: template: out:1:3: executing "out" at <.Missing>: can't evaluate field Missing in type mod.ConfigTemplateData`),
+		},
 		{
 			name: "Label to Annotation",
 			opts: []Opts{
@@ -590,6 +629,43 @@ func TestMod(t *testing.T) {
 			},
 			ref: tTgtHost + "/testrepo:v1",
 		},
+		{
+			name: "Layer Compressed zstd level",
+			opts: []Opts{
+				WithLayerCompressionLevel(archive.CompressZstd, 19),
+			},
+			ref: tTgtHost + "/testrepo:v1",
+		},
+		{
+			name: "Layer Compressed gzip level unchanged",
+			opts: []Opts{
+				WithLayerCompressionLevel(archive.CompressGzip, 9),
+			},
+			ref:      tTgtHost + "/testrepo:v1",
+			wantSame: true,
+		},
+		{
+			name: "Layer Recompress gzip",
+			opts: []Opts{
+				WithLayerRecompress(archive.CompressGzip, 1),
+			},
+			ref: tTgtHost + "/testrepo:v1",
+		},
+		{
+			name: "Layer Recompress invalid level",
+			opts: []Opts{
+				WithLayerRecompress(archive.CompressGzip, 10),
+			},
+			ref:     tTgtHost + "/testrepo:v1",
+			wantErr: archive.ErrUnsupportedLevel,
+		},
+		{
+			name: "Layer eStargz",
+			opts: []Opts{
+				WithLayerEStargz(),
+			},
+			ref: tTgtHost + "/testrepo:v1",
+		},
 		{
 			name: "Layer Digest sha256",
 			opts: []Opts{
@@ -833,6 +909,28 @@ func TestMod(t *testing.T) {
 			ref:     r3amd.CommonName(),
 			wantErr: fmt.Errorf("layer not found"),
 		},
+		{
+			name: "Layer Remove by digest",
+			opts: []Opts{
+				WithLayerRmDigest(digest.Digest("sha256:5fcd3f90f6c7214b2f48d998385f38dd9f047fd219f03255f3c823c0e93f630a")),
+			},
+			ref: tTgtHost + "/testrepo:v3",
+		},
+		{
+			name: "Layer Remove by media type",
+			opts: []Opts{
+				WithLayerRmMediaType(mediatype.OCI1LayerGzip),
+			},
+			ref: r3amd.CommonName(),
+		},
+		{
+			name: "Layer Remove by media type missing",
+			opts: []Opts{
+				WithLayerRmMediaType(mediatype.Docker2LayerGzip),
+			},
+			ref:      tTgtHost + "/testrepo:v3",
+			wantSame: true,
+		},
 		{
 			name: "Manifest Digest sha256",
 			opts: []Opts{
@@ -936,6 +1034,75 @@ func TestMod(t *testing.T) {
 			},
 			ref: tTgtHost + "/testrepo:v1",
 		},
+		{
+			name: "Set Expose",
+			opts: []Opts{
+				WithConfigExpose([]string{"8080/tcp", "53/udp"}),
+			},
+			ref: tTgtHost + "/testrepo:v1",
+		},
+		{
+			name: "Remove Expose",
+			opts: []Opts{
+				WithConfigExpose([]string{}),
+			},
+			ref:      tTgtHost + "/testrepo:v1",
+			wantSame: true,
+		},
+		{
+			name: "Set Expose Invalid Port",
+			opts: []Opts{
+				WithConfigExpose([]string{"70000"}),
+			},
+			ref:     tTgtHost + "/testrepo:v1",
+			wantErr: errs.ErrParsingFailed,
+		},
+		{
+			name: "Set Expose Invalid Protocol",
+			opts: []Opts{
+				WithConfigExpose([]string{"8080/http"}),
+			},
+			ref:     tTgtHost + "/testrepo:v1",
+			wantErr: errs.ErrParsingFailed,
+		},
+		{
+			name: "Set User",
+			opts: []Opts{
+				WithConfigUser("1000:1000"),
+			},
+			ref: tTgtHost + "/testrepo:v1",
+		},
+		{
+			name: "Remove User",
+			opts: []Opts{
+				WithConfigUser(""),
+			},
+			ref:      tTgtHost + "/testrepo:v1",
+			wantSame: true,
+		},
+		{
+			name: "Set User Invalid",
+			opts: []Opts{
+				WithConfigUser("app user"),
+			},
+			ref:     tTgtHost + "/testrepo:v1",
+			wantErr: errs.ErrParsingFailed,
+		},
+		{
+			name: "Set Working Dir",
+			opts: []Opts{
+				WithConfigWorkingDir("/app"),
+			},
+			ref: tTgtHost + "/testrepo:v1",
+		},
+		{
+			name: "Set Working Dir Relative",
+			opts: []Opts{
+				WithConfigWorkingDir("app"),
+			},
+			ref:     tTgtHost + "/testrepo:v1",
+			wantErr: errs.ErrParsingFailed,
+		},
 		{
 			name: "Build arg rm",
 			opts: []Opts{
@@ -1089,3 +1256,1156 @@ func TestMod(t *testing.T) {
 		})
 	}
 }
+
+func TestModEStargz(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := regclient.New()
+	tempDir := t.TempDir()
+	rSrc, err := ref.New("ocidir://../testdata/testrepo:v3")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rTgt, err := ref.New("ocidir://" + tempDir + "/testrepo:estargz")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	// convert the image
+	rMod, err := Apply(ctx, rc, rSrc, WithRefTgt(rTgt), WithLayerEStargz())
+	if err != nil {
+		t.Fatalf("failed to convert to eStargz: %v", err)
+	}
+	getImage := func(t *testing.T, r ref.Ref) ([]descriptor.Descriptor, []digest.Digest) {
+		t.Helper()
+		m, err := rc.ManifestGet(ctx, r, regclient.WithManifestPlatform(platform.Platform{OS: "linux", Architecture: "amd64"}))
+		if err != nil {
+			t.Fatalf("failed to get manifest: %v", err)
+		}
+		if m.IsList() {
+			mi := m.(manifest.Indexer)
+			dl, err := mi.GetManifestList()
+			if err != nil {
+				t.Fatalf("failed to get manifest list: %v", err)
+			}
+			p := platform.Platform{OS: "linux", Architecture: "amd64"}
+			d, err := descriptor.DescriptorListSearch(dl, descriptor.MatchOpt{Platform: &p})
+			if err != nil {
+				t.Fatalf("failed to find platform: %v", err)
+			}
+			m, err = rc.ManifestGet(ctx, r, regclient.WithManifestDesc(d))
+			if err != nil {
+				t.Fatalf("failed to get manifest: %v", err)
+			}
+		}
+		mi := m.(manifest.Imager)
+		layers, err := mi.GetLayers()
+		if err != nil {
+			t.Fatalf("failed to get layers: %v", err)
+		}
+		dConf, err := mi.GetConfig()
+		if err != nil {
+			t.Fatalf("failed to get config: %v", err)
+		}
+		oc, err := rc.BlobGetOCIConfig(ctx, r, dConf)
+		if err != nil {
+			t.Fatalf("failed to get config: %v", err)
+		}
+		return layers, oc.GetConfig().RootFS.DiffIDs
+	}
+	layers, diffIDs := getImage(t, rMod)
+	if len(layers) == 0 || len(layers) != len(diffIDs) {
+		t.Fatalf("unexpected layer count %d, diffIDs %d", len(layers), len(diffIDs))
+	}
+	for i, l := range layers {
+		if l.MediaType != mediatype.OCI1LayerGzip {
+			t.Errorf("layer %d media type is %s", i, l.MediaType)
+		}
+		if l.Annotations[archive.AnnotationEStargzTOCDigest] == "" || l.Annotations[archive.AnnotationEStargzUncompressedSize] == "" {
+			t.Fatalf("layer %d is missing eStargz annotations: %v", i, l.Annotations)
+		}
+		br, err := rc.BlobGet(ctx, rMod, l)
+		if err != nil {
+			t.Fatalf("failed to get layer: %v", err)
+		}
+		vr, err := archive.SeekableVerifyReader(br, l.Annotations)
+		if err != nil {
+			t.Fatalf("failed to verify layer: %v", err)
+		}
+		dr, err := archive.Decompress(vr)
+		if err != nil {
+			t.Fatalf("failed to decompress layer: %v", err)
+		}
+		ucDig := digest.Canonical.Digester()
+		if _, err := io.Copy(ucDig.Hash(), dr); err != nil {
+			t.Errorf("layer %d failed verification: %v", i, err)
+		}
+		_ = vr.Close()
+		_ = br.Close()
+		if ucDig.Digest() != diffIDs[i] {
+			t.Errorf("layer %d diff ID mismatch, expected %s, received %s", i, ucDig.Digest(), diffIDs[i])
+		}
+	}
+	// converting again leaves the image unchanged
+	rMod2, err := Apply(ctx, rc, rMod, WithLayerEStargz())
+	if err != nil {
+		t.Fatalf("failed to convert to eStargz: %v", err)
+	}
+	layers2, _ := getImage(t, rMod2)
+	for i := range layers {
+		if layers[i].Digest != layers2[i].Digest {
+			t.Errorf("layer %d changed on second conversion", i)
+		}
+	}
+	// changing the compression removes the eStargz annotations
+	rMod3, err := Apply(ctx, rc, rMod, WithLayerCompression(archive.CompressZstd))
+	if err != nil {
+		t.Fatalf("failed to change compression: %v", err)
+	}
+	layers3, _ := getImage(t, rMod3)
+	for i, l := range layers3 {
+		if _, ok := l.Annotations[archive.AnnotationEStargzTOCDigest]; ok {
+			t.Errorf("layer %d has stale eStargz annotations after recompression", i)
+		}
+	}
+}
+
+func TestModConfigTemplate(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := regclient.New()
+	tempDir := t.TempDir()
+	rSrc, err := ref.New("ocidir://../testdata/testrepo:v3")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rTgt, err := ref.New("ocidir://" + tempDir + "/testrepo:template")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rMod, err := Apply(ctx, rc, rSrc, WithRefTgt(rTgt),
+		WithLabelTemplate("org.example.platform", "{{ .Platform.OS }}/{{ .Platform.Architecture }}"),
+		WithLabelTemplate("org.example.path", `{{ index .Env "PATH" }}`),
+		WithEnvTemplate("PATH", "{{ .Value }}:/opt/bin"),
+		WithEnvTemplate("[linux/amd64]ARCH_ONLY", "{{ .Name }}"),
+	)
+	if err != nil {
+		t.Fatalf("failed to apply templates: %v", err)
+	}
+	m, err := rc.ManifestGet(ctx, rMod)
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	mi, ok := m.(manifest.Indexer)
+	if !ok {
+		t.Fatalf("image is not an index")
+	}
+	dl, err := mi.GetManifestList()
+	if err != nil {
+		t.Fatalf("failed to get manifest list: %v", err)
+	}
+	if len(dl) < 2 {
+		t.Fatalf("expected multiple platforms, found %d", len(dl))
+	}
+	// every platform is updated using the values from its own config
+	for _, d := range dl {
+		if d.Platform == nil {
+			continue
+		}
+		t.Run(d.Platform.String(), func(t *testing.T) {
+			mc, err := rc.ManifestGet(ctx, rMod, regclient.WithManifestDesc(d))
+			if err != nil {
+				t.Fatalf("failed to get manifest: %v", err)
+			}
+			dConf, err := mc.(manifest.Imager).GetConfig()
+			if err != nil {
+				t.Fatalf("failed to get config: %v", err)
+			}
+			oc, err := rc.BlobGetOCIConfig(ctx, rMod, dConf)
+			if err != nil {
+				t.Fatalf("failed to get config: %v", err)
+			}
+			conf := oc.GetConfig()
+			expPlat := conf.OS + "/" + conf.Architecture
+			if conf.Config.Labels["org.example.platform"] != expPlat {
+				t.Errorf("unexpected platform label, expected %s, received %s", expPlat, conf.Config.Labels["org.example.platform"])
+			}
+			path, archOnly := "", ""
+			for _, kv := range conf.Config.Env {
+				k, v, _ := strings.Cut(kv, "=")
+				switch k {
+				case "PATH":
+					path = v
+				case "ARCH_ONLY":
+					archOnly = v
+				}
+			}
+			if !strings.HasSuffix(path, ":/opt/bin") {
+				t.Errorf("PATH was not updated: %s", path)
+			}
+			// the label template is applied before the env template
+			if conf.Config.Labels["org.example.path"]+":/opt/bin" != path {
+				t.Errorf("unexpected path label %s, PATH is %s", conf.Config.Labels["org.example.path"], path)
+			}
+			if expArch := conf.Architecture == "amd64"; expArch != (archOnly == "ARCH_ONLY") {
+				t.Errorf("unexpected ARCH_ONLY value on %s: %s", expPlat, archOnly)
+			}
+		})
+	}
+}
+
+func TestModExternalLayers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := regclient.New()
+	tempDir := t.TempDir()
+	rBase, err := ref.New("ocidir://../testdata/testrepo:v3")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rSrc, err := ref.New("ocidir://" + tempDir + "/src:ext")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	// create an image with a foreign layer
+	m, err := rc.ManifestGet(ctx, rBase, regclient.WithManifestPlatform(platform.Platform{OS: "linux", Architecture: "amd64"}))
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	err = rc.ImageCopy(ctx, rBase.SetDigest(m.GetDescriptor().Digest.String()), rSrc)
+	if err != nil {
+		t.Fatalf("failed to copy image: %v", err)
+	}
+	om, err := manifest.OCIManifestFromAny(m.GetOrig())
+	if err != nil || len(om.Layers) == 0 || om.Layers[0].MediaType != mediatype.OCI1LayerGzip {
+		t.Fatalf("unexpected manifest: %v", err)
+	}
+	extLayer := om.Layers[0]
+	om.Layers[0].MediaType = mediatype.OCI1ForeignLayerGzip
+	om.Layers[0].URLs = []string{"https://registry.example.org/layer.tar.gz"}
+	mSrc, err := manifest.New(manifest.WithOrig(om))
+	if err != nil {
+		t.Fatalf("failed to create manifest: %v", err)
+	}
+	err = rc.ManifestPut(ctx, rSrc, mSrc)
+	if err != nil {
+		t.Fatalf("failed to put manifest: %v", err)
+	}
+	tt := []struct {
+		name       string
+		opts       []Opts
+		expectErr  error
+		expectBlob bool
+		expectSame bool
+	}{
+		{
+			name:       "default",
+			expectSame: true,
+		},
+		{
+			name:       "skip",
+			opts:       []Opts{WithExternalLayers(regclient.ExternalSkip)},
+			expectSame: true,
+		},
+		{
+			name:       "copy",
+			opts:       []Opts{WithExternalLayers(regclient.ExternalCopy)},
+			expectBlob: true,
+			expectSame: true,
+		},
+		{
+			name:       "embed",
+			opts:       []Opts{WithExternalLayers(regclient.ExternalEmbed)},
+			expectBlob: true,
+		},
+		{
+			name:       "embed allowed host",
+			opts:       []Opts{WithExternalLayers(regclient.ExternalEmbed, "registry.example.org")},
+			expectBlob: true,
+		},
+		{
+			name:       "embed other host",
+			opts:       []Opts{WithExternalLayers(regclient.ExternalEmbed, "mcr.microsoft.com")},
+			expectSame: true,
+		},
+		{
+			name:      "invalid policy",
+			opts:      []Opts{WithExternalLayers("download")},
+			expectErr: errs.ErrUnsupported,
+		},
+	}
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rTgt, err := ref.New(fmt.Sprintf("ocidir://%s/tgt%d:ext", tempDir, i))
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			rMod, err := Apply(ctx, rc, rSrc, append(tc.opts, WithRefTgt(rTgt))...)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Errorf("mod did not fail")
+				} else if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to mod: %v", err)
+			}
+			mMod, err := rc.ManifestGet(ctx, rMod)
+			if err != nil {
+				t.Fatalf("failed to get manifest: %v", err)
+			}
+			if tc.expectSame != (mMod.GetDescriptor().Digest == mSrc.GetDescriptor().Digest) {
+				t.Errorf("unexpected digest %s, source %s", mMod.GetDescriptor().Digest, mSrc.GetDescriptor().Digest)
+			}
+			_, err = rc.BlobHead(ctx, rTgt, extLayer)
+			if tc.expectBlob && err != nil {
+				t.Errorf("external layer was not copied: %v", err)
+			} else if !tc.expectBlob && err == nil {
+				t.Errorf("external layer was copied")
+			}
+			if !tc.expectSame {
+				layers, err := mMod.(manifest.Imager).GetLayers()
+				if err != nil {
+					t.Fatalf("failed to get layers: %v", err)
+				}
+				if len(layers[0].URLs) > 0 || layers[0].MediaType != mediatype.OCI1LayerGzip {
+					t.Errorf("external layer was not embedded: %v", layers[0])
+				}
+			}
+		})
+	}
+}
+
+func TestModFileContentScrub(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := regclient.New()
+	tempDir := t.TempDir()
+	rBase, err := ref.New("ocidir://../testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rSrc, err := ref.New("ocidir://" + tempDir + "/src:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	// add a layer with a token in a config file
+	files := []struct {
+		name    string
+		content string
+	}{
+		{name: "etc/app/app.conf", content: "user=app\ntoken=abc123\nretry=3\ntoken=def456\n"},
+		{name: "etc/app/notes.txt", content: "token=abc123\n"},
+		{name: "etc/other.conf", content: "user=other\n"},
+	}
+	tarBuf := &bytes.Buffer{}
+	tw := tar.NewWriter(tarBuf)
+	for _, f := range files {
+		err = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: f.name, Mode: 0o644, Size: int64(len(f.content))})
+		if err != nil {
+			t.Fatalf("failed to write tar header: %v", err)
+		}
+		_, err = tw.Write([]byte(f.content))
+		if err != nil {
+			t.Fatalf("failed to write tar content: %v", err)
+		}
+	}
+	err = tw.Close()
+	if err != nil {
+		t.Fatalf("failed to close tar: %v", err)
+	}
+	m, err := rc.ManifestGet(ctx, rBase, regclient.WithManifestPlatform(platform.Platform{OS: "linux", Architecture: "amd64"}))
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	rSrc, err = Apply(ctx, rc, rBase.SetDigest(m.GetDescriptor().Digest.String()), WithRefTgt(rSrc), WithLayerAddTar(tarBuf, "", nil))
+	if err != nil {
+		t.Fatalf("failed to add layer: %v", err)
+	}
+
+	// scrub the token from conf files
+	rTgt := rSrc.SetTag("scrubbed")
+	scrubbed := []FileScrub{}
+	rMod, err := Apply(ctx, rc, rSrc, WithRefTgt(rTgt),
+		WithFileContentScrub("*.conf", regexp.MustCompile(`token=(\S+)`), "token=REDACTED"),
+		WithFileContentScrubCallback(func(fs FileScrub) { scrubbed = append(scrubbed, fs) }),
+	)
+	if err != nil {
+		t.Fatalf("failed to scrub: %v", err)
+	}
+	if len(scrubbed) != 1 || scrubbed[0].Name != "etc/app/app.conf" || scrubbed[0].Matches != 2 {
+		t.Errorf("unexpected scrub report: %v", scrubbed)
+	}
+	// verify the layer content and the config diff IDs
+	mMod, err := rc.ManifestGet(ctx, rMod)
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	mi := mMod.(manifest.Imager)
+	layers, err := mi.GetLayers()
+	if err != nil {
+		t.Fatalf("failed to get layers: %v", err)
+	}
+	dConf, err := mi.GetConfig()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+	oc, err := rc.BlobGetOCIConfig(ctx, rMod, dConf)
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+	diffIDs := oc.GetConfig().RootFS.DiffIDs
+	if len(diffIDs) != len(layers) {
+		t.Fatalf("diff IDs do not match the layers, %d != %d", len(diffIDs), len(layers))
+	}
+	br, err := rc.BlobGet(ctx, rMod, layers[len(layers)-1])
+	if err != nil {
+		t.Fatalf("failed to get layer: %v", err)
+	}
+	defer br.Close()
+	dr, err := archive.Decompress(br)
+	if err != nil {
+		t.Fatalf("failed to decompress layer: %v", err)
+	}
+	ucDig := digest.Canonical.Digester()
+	tr := tar.NewReader(io.TeeReader(dr, ucDig.Hash()))
+	expect := map[string]string{
+		"etc/app/app.conf":  "user=app\ntoken=REDACTED\nretry=3\ntoken=REDACTED\n",
+		"etc/app/notes.txt": "token=abc123\n",
+		"etc/other.conf":    "user=other\n",
+	}
+	for {
+		th, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("failed to read layer: %v", err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", th.Name, err)
+		}
+		if string(content) != expect[th.Name] {
+			t.Errorf("unexpected content in %s: %q", th.Name, content)
+		}
+		delete(expect, th.Name)
+	}
+	_, _ = io.Copy(io.Discard, dr)
+	if len(expect) > 0 {
+		t.Errorf("files missing from layer: %v", expect)
+	}
+	if ucDig.Digest() != diffIDs[len(diffIDs)-1] {
+		t.Errorf("diff ID mismatch, expected %s, received %s", ucDig.Digest(), diffIDs[len(diffIDs)-1])
+	}
+
+	// an image without matches is unchanged
+	rMod2, err := Apply(ctx, rc, rMod, WithFileContentScrub("etc/app/*.conf", regexp.MustCompile(`token=abc`), ""))
+	if err != nil {
+		t.Fatalf("failed to scrub: %v", err)
+	}
+	if rMod2.Digest != mMod.GetDescriptor().Digest.String() {
+		t.Errorf("digest changed without a match")
+	}
+	// an invalid glob fails
+	_, err = Apply(ctx, rc, rMod, WithFileContentScrub("[", regexp.MustCompile(`token`), ""))
+	if err == nil {
+		t.Errorf("invalid glob did not fail")
+	}
+}
+
+func TestModLayerRm(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := regclient.New()
+	tempDir := t.TempDir()
+	rSrc, err := ref.New("ocidir://../testdata/testrepo:v3")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rTgt, err := ref.New("ocidir://" + tempDir + "/tgt:v3")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	mSrc, err := rc.ManifestGet(ctx, rSrc, regclient.WithManifestPlatform(platform.Platform{OS: "linux", Architecture: "amd64"}))
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	layersSrc, err := mSrc.(manifest.Imager).GetLayers()
+	if err != nil || len(layersSrc) < 2 {
+		t.Fatalf("unexpected layers: %v", err)
+	}
+	rmLayer := layersSrc[1]
+	rMod, err := Apply(ctx, rc, rSrc.SetDigest(mSrc.GetDescriptor().Digest.String()), WithLayerRmDigest(rmLayer.Digest), WithRefTgt(rTgt))
+	if err != nil {
+		t.Fatalf("failed to mod: %v", err)
+	}
+	mMod, err := rc.ManifestGet(ctx, rMod)
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	layersMod, err := mMod.(manifest.Imager).GetLayers()
+	if err != nil {
+		t.Fatalf("failed to get layers: %v", err)
+	}
+	if len(layersMod) != len(layersSrc)-1 {
+		t.Errorf("unexpected layer count, expected %d, received %d", len(layersSrc)-1, len(layersMod))
+	}
+	for _, l := range layersMod {
+		if l.Digest == rmLayer.Digest {
+			t.Errorf("layer was not removed: %s", l.Digest)
+		}
+	}
+	annot, err := mMod.(manifest.Annotator).GetAnnotations()
+	if err != nil {
+		t.Fatalf("failed to get annotations: %v", err)
+	}
+	if annot[AnnotationLayersRemoved] != rmLayer.Digest.String() {
+		t.Errorf("unexpected %s annotation: %s", AnnotationLayersRemoved, annot[AnnotationLayersRemoved])
+	}
+	if annot[AnnotationLayersSource] != mSrc.GetDescriptor().Digest.String() {
+		t.Errorf("unexpected %s annotation: %s", AnnotationLayersSource, annot[AnnotationLayersSource])
+	}
+	conf, err := rc.ImageConfig(ctx, rMod)
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+	if len(conf.GetConfig().RootFS.DiffIDs) != len(layersMod) {
+		t.Errorf("diff ids were not updated, expected %d, received %d", len(layersMod), len(conf.GetConfig().RootFS.DiffIDs))
+	}
+	_, err = rc.BlobHead(ctx, rTgt, rmLayer)
+	if err == nil {
+		t.Errorf("removed layer was copied")
+	}
+}
+
+func TestModReferrers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := regclient.New()
+	tempDir := t.TempDir()
+	rBase, err := ref.New("ocidir://../testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rSrc, err := ref.New("ocidir://" + tempDir + "/src:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	m, err := rc.ManifestGet(ctx, rBase, regclient.WithManifestPlatform(platform.Platform{OS: "linux", Architecture: "amd64"}))
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	err = rc.ImageCopy(ctx, rBase.SetDigest(m.GetDescriptor().Digest.String()), rSrc)
+	if err != nil {
+		t.Fatalf("failed to copy image: %v", err)
+	}
+	// attach a signature, attestation, and sbom to the image
+	subject := m.GetDescriptor()
+	for _, at := range []string{artifactTypeCosignSig, artifactTypeInToto, "application/spdx+json"} {
+		layerData := []byte(at)
+		layerDesc, err := rc.BlobPut(ctx, rSrc, descriptor.Descriptor{MediaType: "application/octet-stream"}, bytes.NewReader(layerData))
+		if err != nil {
+			t.Fatalf("failed to put blob: %v", err)
+		}
+		_, err = rc.BlobPut(ctx, rSrc, descriptor.Descriptor{MediaType: mediatype.OCI1Empty, Digest: descriptor.EmptyDigest, Size: int64(len(descriptor.EmptyData))}, bytes.NewReader(descriptor.EmptyData))
+		if err != nil {
+			t.Fatalf("failed to put blob: %v", err)
+		}
+		mArt, err := manifest.New(manifest.WithOrig(v1.Manifest{
+			Versioned:    v1.ManifestSchemaVersion,
+			MediaType:    mediatype.OCI1Manifest,
+			ArtifactType: at,
+			Config: descriptor.Descriptor{
+				MediaType: mediatype.OCI1Empty,
+				Digest:    descriptor.EmptyDigest,
+				Size:      int64(len(descriptor.EmptyData)),
+			},
+			Layers:  []descriptor.Descriptor{layerDesc},
+			Subject: &subject,
+		}))
+		if err != nil {
+			t.Fatalf("failed to create artifact: %v", err)
+		}
+		err = rc.ManifestPut(ctx, rSrc.SetDigest(mArt.GetDescriptor().Digest.String()), mArt)
+		if err != nil {
+			t.Fatalf("failed to put artifact: %v", err)
+		}
+	}
+	tt := []struct {
+		name        string
+		opts        []Opts
+		otherRepo   bool
+		expectErr   error
+		expectTypes []string
+	}{
+		{
+			name:        "default",
+			expectTypes: []string{"application/spdx+json", artifactTypeCosignSig, artifactTypeInToto},
+		},
+		{
+			name:      "default other repo",
+			otherRepo: true,
+		},
+		{
+			name:        "all",
+			opts:        []Opts{WithReferrers(ReferrerAll)},
+			expectTypes: []string{"application/spdx+json", artifactTypeCosignSig, artifactTypeInToto},
+		},
+		{
+			name:        "attestations",
+			opts:        []Opts{WithReferrers(ReferrerAttestations)},
+			expectTypes: []string{"application/spdx+json", artifactTypeInToto},
+		},
+		{
+			name:        "unsigned",
+			opts:        []Opts{WithReferrers(ReferrerUnsigned)},
+			expectTypes: []string{"application/spdx+json"},
+		},
+		{
+			name: "none",
+			opts: []Opts{WithReferrers(ReferrerNone)},
+		},
+		{
+			name:        "attestations other repo",
+			opts:        []Opts{WithReferrers(ReferrerAttestations)},
+			otherRepo:   true,
+			expectTypes: []string{"application/spdx+json", artifactTypeInToto},
+		},
+		{
+			name:        "attestations rm",
+			opts:        []Opts{WithAttestationsRm()},
+			expectTypes: []string{artifactTypeCosignSig},
+		},
+		{
+			name:        "attestations rm other repo",
+			opts:        []Opts{WithReferrers(ReferrerAll), WithAttestationsRm()},
+			otherRepo:   true,
+			expectTypes: []string{artifactTypeCosignSig},
+		},
+		{
+			name:      "invalid policy",
+			opts:      []Opts{WithReferrers("signed")},
+			expectErr: errs.ErrUnsupported,
+		},
+	}
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rTgt := rSrc.SetTag("")
+			if tc.otherRepo {
+				rTgt, err = ref.New(fmt.Sprintf("ocidir://%s/tgt%d", tempDir, i))
+				if err != nil {
+					t.Fatalf("failed to parse ref: %v", err)
+				}
+			}
+			// each test uses a different annotation to change the digest
+			opts := append(tc.opts, WithRefTgt(rTgt), WithAnnotation("org.example.test", tc.name))
+			rMod, err := Apply(ctx, rc, rSrc, opts...)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Errorf("mod did not fail")
+				} else if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to mod: %v", err)
+			}
+			if rMod.Digest == subject.Digest.String() {
+				t.Fatalf("digest was not changed")
+			}
+			rl, err := rc.ReferrerList(ctx, rMod)
+			if err != nil {
+				t.Fatalf("failed to list referrers: %v", err)
+			}
+			types := []string{}
+			for _, d := range rl.Descriptors {
+				types = append(types, d.ArtifactType)
+				// verify the referrer content was pushed to the target
+				mRef, err := rc.ManifestGet(ctx, rMod.SetDigest(d.Digest.String()))
+				if err != nil {
+					t.Errorf("failed to get referrer: %v", err)
+					continue
+				}
+				layers, err := mRef.(manifest.Imager).GetLayers()
+				if err != nil || len(layers) != 1 {
+					t.Errorf("failed to get referrer layers: %v", err)
+					continue
+				}
+				_, err = rc.BlobHead(ctx, rMod, layers[0])
+				if err != nil {
+					t.Errorf("referrer layer missing: %v", err)
+				}
+			}
+			slices.Sort(types)
+			if !slices.Equal(types, tc.expectTypes) {
+				t.Errorf("unexpected referrers, expected %v, received %v", tc.expectTypes, types)
+			}
+			// referrers remain on the original image
+			rl, err = rc.ReferrerList(ctx, rSrc)
+			if err != nil {
+				t.Fatalf("failed to list referrers: %v", err)
+			}
+			if len(rl.Descriptors) != 3 {
+				t.Errorf("unexpected referrers on the source image: %v", rl.Descriptors)
+			}
+		})
+	}
+}
+
+func TestModAttestationsRm(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := regclient.New()
+	tempDir := t.TempDir()
+	rBase, err := ref.New("ocidir://../testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rSrc, err := ref.New("ocidir://" + tempDir + "/src:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	err = rc.ImageCopy(ctx, rBase, rSrc)
+	if err != nil {
+		t.Fatalf("failed to copy image: %v", err)
+	}
+	// add a buildkit style attestation manifest to the index
+	mSrc, err := rc.ManifestGet(ctx, rSrc)
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	ociI, err := manifest.OCIIndexFromAny(mSrc.GetOrig())
+	if err != nil {
+		t.Fatalf("failed to convert index: %v", err)
+	}
+	// the testdata includes build references with an unknown platform, which are also removed
+	platforms := []descriptor.Descriptor{}
+	for _, d := range ociI.Manifests {
+		if d.Platform != nil && d.Platform.OS != "unknown" {
+			platforms = append(platforms, d)
+		}
+	}
+	layerDesc, err := rc.BlobPut(ctx, rSrc, descriptor.Descriptor{MediaType: "application/vnd.in-toto+json"}, bytes.NewReader([]byte(`{"_type":"https://in-toto.io/Statement/v0.1"}`)))
+	if err != nil {
+		t.Fatalf("failed to put blob: %v", err)
+	}
+	_, err = rc.BlobPut(ctx, rSrc, descriptor.Descriptor{MediaType: mediatype.OCI1Empty, Digest: descriptor.EmptyDigest, Size: int64(len(descriptor.EmptyData))}, bytes.NewReader(descriptor.EmptyData))
+	if err != nil {
+		t.Fatalf("failed to put blob: %v", err)
+	}
+	mAtt, err := manifest.New(manifest.WithOrig(v1.Manifest{
+		Versioned: v1.ManifestSchemaVersion,
+		MediaType: mediatype.OCI1Manifest,
+		Config: descriptor.Descriptor{
+			MediaType: mediatype.OCI1Empty,
+			Digest:    descriptor.EmptyDigest,
+			Size:      int64(len(descriptor.EmptyData)),
+		},
+		Layers: []descriptor.Descriptor{layerDesc},
+	}))
+	if err != nil {
+		t.Fatalf("failed to create attestation: %v", err)
+	}
+	err = rc.ManifestPut(ctx, rSrc.SetDigest(mAtt.GetDescriptor().Digest.String()), mAtt)
+	if err != nil {
+		t.Fatalf("failed to put attestation: %v", err)
+	}
+	dAtt := mAtt.GetDescriptor()
+	dAtt.Platform = &platform.Platform{OS: "unknown", Architecture: "unknown"}
+	dAtt.Annotations = map[string]string{
+		dockerReferenceType:   dockerReferenceTypeAttestation,
+		dockerReferenceDigest: ociI.Manifests[0].Digest.String(),
+	}
+	ociI.Manifests = append(ociI.Manifests, dAtt)
+	mIdx, err := manifest.New(manifest.WithOrig(ociI))
+	if err != nil {
+		t.Fatalf("failed to create index: %v", err)
+	}
+	err = rc.ManifestPut(ctx, rSrc, mIdx)
+	if err != nil {
+		t.Fatalf("failed to put index: %v", err)
+	}
+
+	rTgt, err := ref.New("ocidir://" + tempDir + "/tgt:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rMod, err := Apply(ctx, rc, rSrc, WithRefTgt(rTgt), WithAttestationsRm())
+	if err != nil {
+		t.Fatalf("failed to mod: %v", err)
+	}
+	mMod, err := rc.ManifestGet(ctx, rMod)
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	dl, err := mMod.(manifest.Indexer).GetManifestList()
+	if err != nil {
+		t.Fatalf("failed to get manifest list: %v", err)
+	}
+	if len(dl) != len(platforms) {
+		t.Fatalf("unexpected number of manifests, expected %d, received %d", len(platforms), len(dl))
+	}
+	for i, d := range dl {
+		if d.Digest == dAtt.Digest || d.Annotations[dockerReferenceType] != "" {
+			t.Errorf("attestation was not removed: %v", d)
+		}
+		// the platform images are unchanged
+		if d.Digest != platforms[i].Digest {
+			t.Errorf("platform %d digest changed", i)
+		}
+	}
+	// an index without attestations is unchanged
+	rMod2, err := Apply(ctx, rc, rMod, WithAttestationsRm())
+	if err != nil {
+		t.Fatalf("failed to mod: %v", err)
+	}
+	if rMod2.Digest != "" && rMod2.Digest != mMod.GetDescriptor().Digest.String() {
+		t.Errorf("digest changed without attestations, %s", rMod2.CommonName())
+	}
+}
+
+func TestModDigestTags(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := regclient.New()
+	tempDir := t.TempDir()
+	rBase, err := ref.New("ocidir://../testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rSrc, err := ref.New("ocidir://" + tempDir + "/src:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	m, err := rc.ManifestGet(ctx, rBase, regclient.WithManifestPlatform(platform.Platform{OS: "linux", Architecture: "amd64"}))
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	err = rc.ImageCopy(ctx, rBase.SetDigest(m.GetDescriptor().Digest.String()), rSrc)
+	if err != nil {
+		t.Fatalf("failed to copy image: %v", err)
+	}
+	// push a signature, attestation, and sbom with the digest tags used by cosign
+	dig := m.GetDescriptor().Digest
+	_, err = rc.BlobPut(ctx, rSrc, descriptor.Descriptor{MediaType: mediatype.OCI1Empty, Digest: descriptor.EmptyDigest, Size: int64(len(descriptor.EmptyData))}, bytes.NewReader(descriptor.EmptyData))
+	if err != nil {
+		t.Fatalf("failed to put blob: %v", err)
+	}
+	suffixes := []string{".att", ".sbom", ".sig"}
+	for _, suffix := range suffixes {
+		layerDesc, err := rc.BlobPut(ctx, rSrc, descriptor.Descriptor{MediaType: "application/octet-stream"}, bytes.NewReader([]byte(suffix)))
+		if err != nil {
+			t.Fatalf("failed to put blob: %v", err)
+		}
+		mArt, err := manifest.New(manifest.WithOrig(v1.Manifest{
+			Versioned: v1.ManifestSchemaVersion,
+			MediaType: mediatype.OCI1Manifest,
+			Config: descriptor.Descriptor{
+				MediaType: mediatype.OCI1Empty,
+				Digest:    descriptor.EmptyDigest,
+				Size:      int64(len(descriptor.EmptyData)),
+			},
+			Layers: []descriptor.Descriptor{layerDesc},
+		}))
+		if err != nil {
+			t.Fatalf("failed to create artifact: %v", err)
+		}
+		err = rc.ManifestPut(ctx, rSrc.SetTag(referrer.DigestTag(dig, suffix)), mArt)
+		if err != nil {
+			t.Fatalf("failed to put artifact: %v", err)
+		}
+	}
+	tt := []struct {
+		name           string
+		opts           []Opts
+		otherRepo      bool
+		expectSuffixes []string
+	}{
+		{
+			name: "disabled",
+		},
+		{
+			name:           "default",
+			opts:           []Opts{WithDigestTags()},
+			expectSuffixes: suffixes,
+		},
+		{
+			name:      "default other repo",
+			opts:      []Opts{WithDigestTags()},
+			otherRepo: true,
+		},
+		{
+			name:           "attestations",
+			opts:           []Opts{WithDigestTags(), WithReferrers(ReferrerAttestations)},
+			expectSuffixes: []string{".att", ".sbom"},
+		},
+		{
+			name:           "unsigned other repo",
+			opts:           []Opts{WithDigestTags(), WithReferrers(ReferrerUnsigned)},
+			otherRepo:      true,
+			expectSuffixes: []string{".sbom"},
+		},
+	}
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rTgt := rSrc.SetTag("")
+			if tc.otherRepo {
+				rTgt, err = ref.New(fmt.Sprintf("ocidir://%s/tgt%d", tempDir, i))
+				if err != nil {
+					t.Fatalf("failed to parse ref: %v", err)
+				}
+			}
+			opts := append(tc.opts, WithRefTgt(rTgt), WithAnnotation("org.example.test", tc.name))
+			rMod, err := Apply(ctx, rc, rSrc, opts...)
+			if err != nil {
+				t.Fatalf("failed to mod: %v", err)
+			}
+			digNew := digest.Digest(rMod.Digest)
+			if digNew == dig {
+				t.Fatalf("digest was not changed")
+			}
+			tl, err := rc.TagList(ctx, rMod)
+			if err != nil {
+				t.Fatalf("failed to list tags: %v", err)
+			}
+			tags, err := tl.GetTags()
+			if err != nil {
+				t.Fatalf("failed to get tags: %v", err)
+			}
+			found := []string{}
+			for _, tag := range tags {
+				if suffix, ok := referrer.DigestTagMatch(digNew, tag); ok {
+					found = append(found, suffix)
+				}
+			}
+			slices.Sort(found)
+			if !slices.Equal(found, tc.expectSuffixes) {
+				t.Errorf("unexpected digest tags, expected %v, received %v", tc.expectSuffixes, found)
+			}
+		})
+	}
+}
+
+func TestReferrerPolicyParse(t *testing.T) {
+	t.Parallel()
+	for _, s := range []string{"all", "Attestations", "unsigned", "none"} {
+		if _, err := ReferrerPolicyParse(s); err != nil {
+			t.Errorf("failed to parse %s: %v", s, err)
+		}
+	}
+	if _, err := ReferrerPolicyParse("signed"); !errors.Is(err, errs.ErrParsingFailed) {
+		t.Errorf("unexpected error parsing an invalid policy: %v", err)
+	}
+}
+
+func TestModLayerReslice(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := regclient.New()
+	tempDir := t.TempDir()
+	rBase, err := ref.New("ocidir://../testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rSrc, err := ref.New("ocidir://" + tempDir + "/src:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	type tarFile struct {
+		name     string
+		typeflag byte
+		content  string
+		linkname string
+	}
+	mkTar := func(t *testing.T, files []tarFile) io.Reader {
+		t.Helper()
+		tarBuf := &bytes.Buffer{}
+		tw := tar.NewWriter(tarBuf)
+		for _, f := range files {
+			err := tw.WriteHeader(&tar.Header{Typeflag: f.typeflag, Name: f.name, Linkname: f.linkname, Mode: 0o644, Size: int64(len(f.content))})
+			if err != nil {
+				t.Fatalf("failed to write tar header: %v", err)
+			}
+			_, err = tw.Write([]byte(f.content))
+			if err != nil {
+				t.Fatalf("failed to write tar content: %v", err)
+			}
+		}
+		err := tw.Close()
+		if err != nil {
+			t.Fatalf("failed to close tar: %v", err)
+		}
+		return tarBuf
+	}
+	// readLayers returns the files in each layer of the image
+	readLayers := func(t *testing.T, r ref.Ref) []map[string]tarFile {
+		t.Helper()
+		m, err := rc.ManifestGet(ctx, r)
+		if err != nil {
+			t.Fatalf("failed to get manifest: %v", err)
+		}
+		layers, err := m.(manifest.Imager).GetLayers()
+		if err != nil {
+			t.Fatalf("failed to get layers: %v", err)
+		}
+		conf, err := rc.ImageConfig(ctx, r)
+		if err != nil {
+			t.Fatalf("failed to get config: %v", err)
+		}
+		diffIDs := conf.GetConfig().RootFS.DiffIDs
+		if len(diffIDs) != len(layers) {
+			t.Fatalf("diff IDs do not match the layers, %d != %d", len(diffIDs), len(layers))
+		}
+		result := []map[string]tarFile{}
+		for i, l := range layers {
+			br, err := rc.BlobGet(ctx, r, l)
+			if err != nil {
+				t.Fatalf("failed to get layer: %v", err)
+			}
+			dr, err := archive.Decompress(br)
+			if err != nil {
+				t.Fatalf("failed to decompress layer: %v", err)
+			}
+			ucDig := digest.Canonical.Digester()
+			tr := tar.NewReader(io.TeeReader(dr, ucDig.Hash()))
+			files := map[string]tarFile{}
+			for {
+				th, err := tr.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				} else if err != nil {
+					t.Fatalf("failed to read layer: %v", err)
+				}
+				content, err := io.ReadAll(tr)
+				if err != nil {
+					t.Fatalf("failed to read %s: %v", th.Name, err)
+				}
+				files[th.Name] = tarFile{name: th.Name, typeflag: th.Typeflag, content: string(content), linkname: th.Linkname}
+			}
+			_, _ = io.Copy(io.Discard, dr)
+			_ = br.Close()
+			if ucDig.Digest() != diffIDs[i] {
+				t.Errorf("diff ID mismatch on layer %d, expected %s, received %s", i, ucDig.Digest(), diffIDs[i])
+			}
+			result = append(result, files)
+		}
+		return result
+	}
+	m, err := rc.ManifestGet(ctx, rBase, regclient.WithManifestPlatform(platform.Platform{OS: "linux", Architecture: "amd64"}))
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	rSrc, err = Apply(ctx, rc, rBase.SetDigest(m.GetDescriptor().Digest.String()), WithRefTgt(rSrc),
+		WithLayerAddTar(mkTar(t, []tarFile{
+			{name: "app/", typeflag: tar.TypeDir},
+			{name: "app/a.txt", typeflag: tar.TypeReg, content: "a"},
+			{name: "app/b.txt", typeflag: tar.TypeLink, linkname: "app/a.txt"},
+			{name: "app/c.txt", typeflag: tar.TypeReg, content: "c"},
+			{name: "app/d.txt", typeflag: tar.TypeLink, linkname: "app/c.txt"},
+			{name: "opt/", typeflag: tar.TypeDir},
+			{name: "opt/lib/", typeflag: tar.TypeDir},
+			{name: "opt/lib/python/", typeflag: tar.TypeDir},
+			{name: "opt/lib/python/old.py", typeflag: tar.TypeReg, content: "old"},
+			{name: "opt/lib/python/x.py", typeflag: tar.TypeLink, linkname: "app/c.txt"},
+			{name: "top.txt", typeflag: tar.TypeReg, content: "top"},
+		}), "", nil),
+		WithLayerAddTar(mkTar(t, []tarFile{
+			{name: "app/.wh.a.txt", typeflag: tar.TypeReg},
+			{name: "app/c.txt", typeflag: tar.TypeReg, content: "c2"},
+			{name: "opt/lib/python/.wh..wh..opq", typeflag: tar.TypeReg},
+			{name: "opt/lib/python/new.py", typeflag: tar.TypeReg, content: "new"},
+		}), "", nil),
+	)
+	if err != nil {
+		t.Fatalf("failed to add layers: %v", err)
+	}
+	layersSrc := readLayers(t, rSrc)
+
+	rMod, err := Apply(ctx, rc, rSrc, WithRefTgt(rSrc.SetTag("reslice")), WithLayerReslice(map[string]string{"/opt/lib/python/": "python"}))
+	if err != nil {
+		t.Fatalf("failed to reslice: %v", err)
+	}
+	layersMod := readLayers(t, rMod)
+	// find the layers with the mapped, app, and top level files
+	var python, app, root map[string]tarFile
+	for _, files := range layersMod {
+		if _, ok := files["opt/lib/python/new.py"]; ok {
+			python = files
+		}
+		if _, ok := files["app/"]; ok {
+			app = files
+		}
+		if _, ok := files["top.txt"]; ok {
+			root = files
+		}
+	}
+	// the mapped layer includes the parent directories
+	expectPython := map[string]tarFile{
+		"opt/":                  {name: "opt/", typeflag: tar.TypeDir},
+		"opt/lib/":              {name: "opt/lib/", typeflag: tar.TypeDir},
+		"opt/lib/python/":       {name: "opt/lib/python/", typeflag: tar.TypeDir},
+		"opt/lib/python/new.py": {name: "opt/lib/python/new.py", typeflag: tar.TypeReg, content: "new"},
+	}
+	if !maps.Equal(python, expectPython) {
+		t.Errorf("unexpected python layer: %v", python)
+	}
+	expectApp := map[string]tarFile{
+		"app/":      {name: "app/", typeflag: tar.TypeDir},
+		"app/b.txt": {name: "app/b.txt", typeflag: tar.TypeReg, content: "a"},
+		"app/c.txt": {name: "app/c.txt", typeflag: tar.TypeReg, content: "c2"},
+		"app/d.txt": {name: "app/d.txt", typeflag: tar.TypeReg, content: "c"},
+	}
+	if !maps.Equal(app, expectApp) {
+		t.Errorf("unexpected app layer: %v", app)
+	}
+	if root == nil || root["top.txt"].content != "top" {
+		t.Errorf("top level file not found in root layer: %v", root)
+	}
+	// every file from the base image is included
+	srcNames := map[string]bool{}
+	for _, files := range layersSrc[:len(layersSrc)-2] {
+		for name, f := range files {
+			if f.typeflag != tar.TypeDir {
+				srcNames[strings.Trim(strings.TrimPrefix(name, "./"), "/")] = true
+			}
+		}
+	}
+	for _, files := range layersMod {
+		for name, f := range files {
+			if f.typeflag != tar.TypeDir {
+				delete(srcNames, strings.Trim(name, "/"))
+			}
+		}
+	}
+	if len(srcNames) > 0 {
+		t.Errorf("files missing after reslice: %v", srcNames)
+	}
+
+	// reslicing again does not change the image
+	rMod2, err := Apply(ctx, rc, rMod, WithLayerReslice(map[string]string{"opt/lib/python": "python"}))
+	if err != nil {
+		t.Fatalf("failed to reslice: %v", err)
+	}
+	mMod, err := rc.ManifestHead(ctx, rMod, regclient.WithManifestRequireDigest())
+	if err != nil {
+		t.Fatalf("failed to head manifest: %v", err)
+	}
+	if rMod2.Digest != mMod.GetDescriptor().Digest.String() {
+		t.Errorf("reslice is not deterministic, %s != %s", mMod.GetDescriptor().Digest, rMod2.Digest)
+	}
+	conf, err := rc.ImageConfig(ctx, rMod)
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+	nonEmpty := 0
+	for _, h := range conf.GetConfig().History {
+		if !h.EmptyLayer {
+			nonEmpty++
+		}
+	}
+	if nonEmpty != len(layersMod) {
+		t.Errorf("history does not match the layers, %d != %d", nonEmpty, len(layersMod))
+	}
+}
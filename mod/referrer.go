@@ -0,0 +1,224 @@
+package mod
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/ref"
+	"github.com/regclient/regclient/types/referrer"
+)
+
+// ReferrerPolicy defines which referrers follow an image when the modification changes the image digest.
+// Referrers that are not included remain attached to the original image.
+type ReferrerPolicy string
+
+const (
+	// ReferrerAll updates the subject of every referrer, including signatures which will no longer verify.
+	ReferrerAll ReferrerPolicy = "all"
+	// ReferrerAttestations updates the subject of attestations, SBOMs, and other artifacts, skipping signatures.
+	ReferrerAttestations ReferrerPolicy = "attestations"
+	// ReferrerUnsigned updates the subject of SBOMs and other unsigned artifacts, skipping signatures and attestations.
+	ReferrerUnsigned ReferrerPolicy = "unsigned"
+	// ReferrerNone leaves all referrers on the original image.
+	ReferrerNone ReferrerPolicy = "none"
+)
+
+const (
+	annotationInTotoPredicateType   = "in-toto.io/predicate-type"
+	annotationSigstorePredicateType = "dev.sigstore.bundle.predicateType"
+	artifactTypeCosignSig           = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	artifactTypeCycloneDX           = "application/vnd.cyclonedx+json"
+	artifactTypeDSSE                = "application/vnd.dsse.envelope.v1+json"
+	artifactTypeInToto              = "application/vnd.in-toto+json"
+	artifactTypeNotarySig           = "application/vnd.cncf.notary.signature"
+	artifactTypeSigstoreBundle      = "application/vnd.dev.sigstore.bundle"
+	artifactTypeSPDX                = "application/spdx+json"
+	artifactTypeSyft                = "application/vnd.syft+json"
+	dockerReferenceTypeAttestation  = "attestation-manifest"
+	// sigstorePredicateSign is the predicate type cosign sets on bundles containing a signature rather than an attestation.
+	sigstorePredicateSign = "https://sigstore.dev/cosign/sign/v1"
+)
+
+// ReferrerPolicyParse converts a string to a [ReferrerPolicy].
+func ReferrerPolicyParse(s string) (ReferrerPolicy, error) {
+	switch p := ReferrerPolicy(strings.ToLower(s)); p {
+	case ReferrerAll, ReferrerAttestations, ReferrerUnsigned, ReferrerNone:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown referrer policy %q, expected all, attestations, unsigned, or none%.0w", s, errs.ErrParsingFailed)
+}
+
+// WithReferrers selects the referrers that are updated with the new subject when an image is modified.
+// This includes OCI referrers and the attestations buildkit includes in an index.
+// When the target is a different repository, the selected referrers are also copied to the target.
+// Without this option, every referrer is updated when the image is modified within the same repository,
+// and referrers are not copied to a different repository.
+func WithReferrers(policy ReferrerPolicy) Opts {
+	return func(dc *dagConfig, dm *dagManifest) error {
+		switch policy {
+		case ReferrerAll:
+			dc.referrerInclude = func(d descriptor.Descriptor) bool { return true }
+		case ReferrerAttestations:
+			dc.referrerInclude = func(d descriptor.Descriptor) bool { return !referrerIsSignature(d) }
+		case ReferrerUnsigned:
+			dc.referrerInclude = func(d descriptor.Descriptor) bool { return !referrerIsSignature(d) && !referrerIsAttestation(d) }
+		case ReferrerNone:
+			dc.referrerInclude = func(d descriptor.Descriptor) bool { return false }
+		default:
+			return fmt.Errorf("unknown referrer policy %q%.0w", policy, errs.ErrUnsupported)
+		}
+		return nil
+	}
+}
+
+// WithDigestTags copies digest tags, like the "sha256-<hex>.sig" tags created by cosign, to the new digest when the image digest changes.
+// Digest tags are selected with the [WithReferrers] policy, the ".sig" suffix is a signature and ".att" is an attestation.
+// Without a policy, every digest tag is copied within the same repository, and digest tags are not copied to a different repository.
+// The digest tags of the original image are not removed.
+func WithDigestTags() Opts {
+	return func(dc *dagConfig, dm *dagManifest) error {
+		dc.digestTags = true
+		return nil
+	}
+}
+
+// WithAttestationsRm removes build attestations and SBOMs from an index and from the referrers of the image.
+// This includes the attestation manifests buildkit adds to an index with an "unknown/unknown" platform,
+// and in-toto attestations and SBOMs pushed as referrers.
+// Removed referrers are not copied to the target, and remain attached to the original image in the source repository.
+func WithAttestationsRm() Opts {
+	return func(dc *dagConfig, dm *dagManifest) error {
+		dc.stepsManifest = append(dc.stepsManifest, func(c context.Context, rc *regclient.RegClient, rSrc, rTgt ref.Ref, dm *dagManifest) error {
+			if dm.mod == deleted {
+				return nil
+			}
+			if mi, ok := dm.m.(manifest.Indexer); ok {
+				ml, err := mi.GetManifestList()
+				if err != nil {
+					return fmt.Errorf("failed to get manifest list: %w", err)
+				}
+				changed := false
+				mlI := 0
+				for _, childDM := range dm.manifests {
+					if childDM.mod == added {
+						continue
+					}
+					if mlI >= len(ml) {
+						return fmt.Errorf("could not find descriptor, index=%d, digest=%s", mlI, dm.origDesc.Digest.String())
+					}
+					desc := ml[mlI]
+					mlI++
+					if childDM.mod == deleted || !indexIsAttestation(desc) {
+						continue
+					}
+					childDM.mod = deleted
+					changed = true
+				}
+				if changed && dm.mod == unchanged {
+					dm.mod = replaced
+				}
+			}
+			for _, child := range dm.referrers {
+				if child.mod != added && (referrerIsAttestation(child.refDesc) || referrerIsSBOM(child.refDesc)) {
+					child.mod = deleted
+				}
+			}
+			return nil
+		})
+		return nil
+	}
+}
+
+// digestTagDesc returns a descriptor used to select a digest tag with the referrer policy.
+func digestTagDesc(suffix string) descriptor.Descriptor {
+	switch suffix {
+	case ".sig":
+		return descriptor.Descriptor{ArtifactType: artifactTypeCosignSig}
+	case ".att":
+		return descriptor.Descriptor{ArtifactType: artifactTypeDSSE}
+	}
+	return descriptor.Descriptor{}
+}
+
+// digestTagsCopy copies the selected digest tags of the original image to the new digest.
+func digestTagsCopy(ctx context.Context, rc *regclient.RegClient, dc *dagConfig, rSrc, rTgt ref.Ref, dm *dagManifest) error {
+	dOrig := dm.origDesc.Digest
+	dNew := dm.m.GetDescriptor().Digest
+	if dOrig == "" || dOrig == dNew {
+		return nil
+	}
+	if dc.referrerInclude == nil && !ref.EqualRepository(rSrc, rTgt) {
+		return nil
+	}
+	tl, err := rc.TagList(ctx, rSrc)
+	if err != nil {
+		return fmt.Errorf("failed to list digest tags: %w", err)
+	}
+	tags, err := tl.GetTags()
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		suffix, ok := referrer.DigestTagMatch(dOrig, tag)
+		// the fallback tag is handled with referrers
+		if !ok || suffix == "" {
+			continue
+		}
+		if dc.referrerInclude != nil && !dc.referrerInclude(digestTagDesc(suffix)) {
+			continue
+		}
+		tagNew := referrer.DigestTag(dNew, suffix)
+		err = rc.ImageCopy(ctx, rSrc.SetTag(tag), rTgt.SetTag(tagNew))
+		if err != nil {
+			return fmt.Errorf("failed to copy digest tag %s: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// referrerIsSignature returns true for signature artifacts, which are only valid for the original subject.
+func referrerIsSignature(d descriptor.Descriptor) bool {
+	switch {
+	case d.ArtifactType == artifactTypeCosignSig, d.ArtifactType == artifactTypeNotarySig:
+		return true
+	case strings.HasPrefix(d.ArtifactType, artifactTypeSigstoreBundle):
+		predicate := d.Annotations[annotationSigstorePredicateType]
+		return predicate == "" || predicate == sigstorePredicateSign
+	}
+	return false
+}
+
+// indexIsAttestation returns true for attestation and SBOM entries in an index.
+func indexIsAttestation(d descriptor.Descriptor) bool {
+	if d.Platform != nil && d.Platform.OS == "unknown" && d.Platform.Architecture == "unknown" {
+		return true
+	}
+	return referrerIsAttestation(d) || referrerIsSBOM(d)
+}
+
+// referrerIsSBOM returns true for SPDX, CycloneDX, and Syft SBOMs.
+func referrerIsSBOM(d descriptor.Descriptor) bool {
+	switch d.ArtifactType {
+	case artifactTypeCycloneDX, artifactTypeSPDX, artifactTypeSyft:
+		return true
+	}
+	return false
+}
+
+// referrerIsAttestation returns true for in-toto attestations, which include the digest of the original subject.
+func referrerIsAttestation(d descriptor.Descriptor) bool {
+	switch {
+	case d.ArtifactType == artifactTypeInToto, d.ArtifactType == artifactTypeDSSE:
+		return true
+	case d.Annotations[annotationInTotoPredicateType] != "", d.Annotations[dockerReferenceType] == dockerReferenceTypeAttestation:
+		return true
+	case strings.HasPrefix(d.ArtifactType, artifactTypeSigstoreBundle):
+		return !referrerIsSignature(d)
+	}
+	return false
+}
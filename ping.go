@@ -2,7 +2,11 @@ package regclient
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"strings"
 
+	"github.com/regclient/regclient/types/errs"
 	"github.com/regclient/regclient/types/ping"
 	"github.com/regclient/regclient/types/ref"
 )
@@ -16,3 +20,44 @@ func (rc *RegClient) Ping(ctx context.Context, r ref.Ref) (ping.Result, error) {
 
 	return schemeAPI.Ping(ctx, r)
 }
+
+// PingAuth verifies access to a registry and returns details of the auth used.
+// When r includes a repository, access is requested for the actions (e.g. "pull" and "push") on that repository.
+// This is only supported for registries.
+func (rc *RegClient) PingAuth(ctx context.Context, r ref.Ref, actions ...string) (ping.Auth, error) {
+	if r.Scheme != "reg" {
+		return ping.Auth{}, fmt.Errorf("auth details are only available for registries: %s%.0w", r.CommonName(), errs.ErrUnsupported)
+	}
+	return rc.regScheme.PingAuth(ctx, r, actions...)
+}
+
+// AuthCheck verifies the registry grants the actions (e.g. "pull" and "push") on the repository in r.
+// All actions are requested in a single token, which is reused by later requests to the repository.
+// Registries that do not return a token that can be inspected are only verified to accept the login.
+// An error wrapping [errs.ErrHTTPUnauthorized] is returned when an action is denied.
+// Other schemes do not require auth and always succeed.
+func (rc *RegClient) AuthCheck(ctx context.Context, r ref.Ref, actions ...string) error {
+	if r.Scheme != "reg" {
+		return nil
+	}
+	info, err := rc.PingAuth(ctx, r, actions...)
+	if err != nil {
+		return err
+	}
+	if r.Repository == "" || info.Type != "bearer" || info.Access == nil {
+		rc.slog.Debug("Registry access cannot be verified from the token",
+			slog.String("ref", r.CommonName()),
+			slog.String("auth", info.Type))
+		return nil
+	}
+	denied := []string{}
+	for _, action := range actions {
+		if !info.Allowed("repository", r.Repository, action) {
+			denied = append(denied, action)
+		}
+	}
+	if len(denied) > 0 {
+		return fmt.Errorf("%s access denied to %s%.0w", strings.Join(denied, ","), r.CommonName(), errs.ErrHTTPUnauthorized)
+	}
+	return nil
+}
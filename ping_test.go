@@ -0,0 +1,184 @@
+package regclient
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/olareg/olareg"
+	oConfig "github.com/olareg/olareg/config"
+
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/scheme/reg"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ping"
+	"github.com/regclient/regclient/types/ref"
+)
+
+func TestAuthCheck(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "./testdata",
+		},
+	})
+	t.Cleanup(func() { _ = regHandler.Close() })
+	var tokenCount atomic.Int64
+	var tsURL string
+	// pushes are only allowed to repositories ending in "-rw"
+	allowed := func(repo, action string) bool {
+		return action == "pull" || strings.HasSuffix(repo, "-rw")
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			tokenCount.Add(1)
+			user, pass, ok := r.BasicAuth()
+			if !ok || user != "testuser" || pass != "testpass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			access := []ping.AuthAccess{}
+			for _, scope := range r.URL.Query()["scope"] {
+				parts := strings.Split(scope, ":")
+				if len(parts) != 3 {
+					continue
+				}
+				actions := []string{}
+				for _, action := range strings.Split(parts[2], ",") {
+					if allowed(parts[1], action) {
+						actions = append(actions, action)
+					}
+				}
+				access = append(access, ping.AuthAccess{Type: parts[0], Name: parts[1], Actions: actions})
+			}
+			claims, _ := json.Marshal(map[string]any{"sub": user, "access": access})
+			token := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+				base64.RawURLEncoding.EncodeToString(claims) + ".sig"
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{"token": token, "expires_in": 300})
+			return
+		}
+		// verify the token grants access to the repository for the request
+		unauth := func() {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+tsURL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+		authHeader, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			unauth()
+			return
+		}
+		parts := strings.Split(authHeader, ".")
+		if len(parts) != 3 {
+			unauth()
+			return
+		}
+		claimsRaw, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		claims := struct {
+			Access []ping.AuthAccess `json:"access"`
+		}{}
+		_ = json.Unmarshal(claimsRaw, &claims)
+		if repo, _, found := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/"), "/"); found {
+			action := "pull"
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				action = "push"
+			}
+			if !(ping.Auth{Access: claims.Access}).Allowed("repository", repo, action) {
+				unauth()
+				return
+			}
+		}
+		regHandler.ServeHTTP(w, r)
+	}))
+	tsURL = ts.URL
+	t.Cleanup(ts.Close)
+	tsParsed, _ := url.Parse(ts.URL)
+	tsHost := tsParsed.Host
+	newRC := func() *RegClient {
+		return New(
+			WithConfigHost(config.Host{Name: tsHost, TLS: config.TLSDisabled, User: "testuser", Pass: "testpass"}),
+			WithRegOpts(reg.WithDelay(time.Millisecond*10, time.Millisecond*100), reg.WithRetryLimit(2)),
+		)
+	}
+	rSrc, err := ref.New(tsHost + "/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rRW, err := ref.New(tsHost + "/copy-rw:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rRO, err := ref.New(tsHost + "/copy-ro:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+
+	t.Run("ping auth", func(t *testing.T) {
+		rc := newRC()
+		info, err := rc.PingAuth(ctx, rRO, "pull", "push")
+		if err != nil {
+			t.Fatalf("failed to ping: %v", err)
+		}
+		if info.Type != "bearer" || info.User != "testuser" || info.Subject != "testuser" {
+			t.Errorf("unexpected auth info: %v", info)
+		}
+		if !info.Allowed("repository", "copy-ro", "pull") || info.Allowed("repository", "copy-ro", "push") {
+			t.Errorf("unexpected access: %v", info.Access)
+		}
+		if !slices.Contains(info.Scopes, "repository:copy-ro:pull,push") {
+			t.Errorf("scope missing: %v", info.Scopes)
+		}
+	})
+	t.Run("check", func(t *testing.T) {
+		rc := newRC()
+		err := rc.AuthCheck(ctx, rRW, "pull", "push")
+		if err != nil {
+			t.Errorf("failed to check rw repo: %v", err)
+		}
+		err = rc.AuthCheck(ctx, rRO, "pull", "push")
+		if !errors.Is(err, errs.ErrHTTPUnauthorized) {
+			t.Errorf("check of ro repo did not fail: %v", err)
+		}
+		err = rc.AuthCheck(ctx, rRO, "pull")
+		if err != nil {
+			t.Errorf("failed to check pull on ro repo: %v", err)
+		}
+	})
+	t.Run("copy denied", func(t *testing.T) {
+		rc := newRC()
+		err := rc.ImageCopy(ctx, rSrc, rRO, ImageWithAuthCheck())
+		if !errors.Is(err, errs.ErrHTTPUnauthorized) {
+			t.Errorf("copy to ro repo did not fail: %v", err)
+		}
+	})
+	t.Run("copy", func(t *testing.T) {
+		rc := newRC()
+		err := rc.AuthCheck(ctx, rSrc, "pull")
+		if err != nil {
+			t.Fatalf("failed to check source: %v", err)
+		}
+		err = rc.AuthCheck(ctx, rRW, "pull", "push")
+		if err != nil {
+			t.Fatalf("failed to check target: %v", err)
+		}
+		before := tokenCount.Load()
+		err = rc.ImageCopy(ctx, rSrc, rRW)
+		if err != nil {
+			t.Fatalf("failed to copy: %v", err)
+		}
+		if after := tokenCount.Load(); after != before {
+			t.Errorf("copy requested %d additional tokens", after-before)
+		}
+	})
+}
@@ -32,15 +32,57 @@ var compressHeaders = map[CompressType][]byte{
 	CompressZstd:  []byte("\x28\xB5\x2F\xFD"),
 }
 
-func Compress(r io.Reader, oComp CompressType) (io.ReadCloser, error) {
+// CompressOpts configures options for Compress
+type CompressOpts func(*compressOpts)
+
+type compressOpts struct {
+	level int
+}
+
+// CompressLevel sets the compression level, 0 uses the default level of the algorithm.
+// Gzip supports levels 1 through 9, and zstd supports levels 1 through 22.
+func CompressLevel(level int) CompressOpts {
+	return func(co *compressOpts) {
+		co.level = level
+	}
+}
+
+// CompressLevelValidate returns an error when the level is not supported by the compression type.
+func CompressLevelValidate(oComp CompressType, level int) error {
+	if level == 0 {
+		return nil
+	}
+	switch oComp {
+	case CompressGzip:
+		if level < gzip.BestSpeed || level > gzip.BestCompression {
+			return fmt.Errorf("gzip compression level %d is not between %d and %d%.0w", level, gzip.BestSpeed, gzip.BestCompression, ErrUnsupportedLevel)
+		}
+	case CompressZstd:
+		if level < 1 || level > 22 {
+			return fmt.Errorf("zstd compression level %d is not between 1 and 22%.0w", level, ErrUnsupportedLevel)
+		}
+	default:
+		return fmt.Errorf("compression level is not supported with %s%.0w", oComp.String(), ErrUnsupportedLevel)
+	}
+	return nil
+}
+
+func Compress(r io.Reader, oComp CompressType, opts ...CompressOpts) (io.ReadCloser, error) {
+	co := compressOpts{}
+	for _, opt := range opts {
+		opt(&co)
+	}
+	if err := CompressLevelValidate(oComp, co.level); err != nil {
+		return nil, err
+	}
 	switch oComp {
 	// note, bzip2 compression is not supported
 	case CompressGzip:
-		return writeToRead(r, newGzipWriter)
+		return writeToRead(r, newGzipWriter(co.level))
 	case CompressXz:
 		return writeToRead(r, xz.NewWriter)
 	case CompressZstd:
-		return writeToRead(r, newZstdWriter)
+		return writeToRead(r, newZstdWriter(co.level))
 	case CompressNone:
 		return io.NopCloser(r), nil
 	default:
@@ -48,14 +90,24 @@ func Compress(r io.Reader, oComp CompressType) (io.ReadCloser, error) {
 	}
 }
 
-// newGzipWriter generates a writer and an always nil error.
-func newGzipWriter(w io.Writer) (io.WriteCloser, error) {
-	return gzip.NewWriter(w), nil
+// newGzipWriter returns a function to generate a writer at the requested level.
+func newGzipWriter(level int) func(io.Writer) (io.WriteCloser, error) {
+	return func(w io.Writer) (io.WriteCloser, error) {
+		if level == 0 {
+			return gzip.NewWriter(w), nil
+		}
+		return gzip.NewWriterLevel(w, level)
+	}
 }
 
-// newZstdWriter generates a writer with the default options.
-func newZstdWriter(w io.Writer) (io.WriteCloser, error) {
-	return zstd.NewWriter(w)
+// newZstdWriter returns a function to generate a writer at the requested level.
+func newZstdWriter(level int) func(io.Writer) (io.WriteCloser, error) {
+	return func(w io.Writer) (io.WriteCloser, error) {
+		if level == 0 {
+			return zstd.NewWriter(w)
+		}
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	}
 }
 
 // writeToRead uses a pipe + goroutine + copy to switch from a writer to a reader.
@@ -2,6 +2,7 @@ package archive
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"strings"
 	"testing"
@@ -68,6 +69,52 @@ func TestRoundtrip(t *testing.T) {
 	}
 }
 
+func TestCompressLevel(t *testing.T) {
+	t.Parallel()
+	content := bytes.Repeat([]byte("hello world, "), 1000)
+	tt := []struct {
+		name      string
+		algo      CompressType
+		level     int
+		expectErr error
+	}{
+		{name: "gzip default", algo: CompressGzip},
+		{name: "gzip fast", algo: CompressGzip, level: 1},
+		{name: "gzip best", algo: CompressGzip, level: 9},
+		{name: "gzip invalid", algo: CompressGzip, level: 10, expectErr: ErrUnsupportedLevel},
+		{name: "zstd fast", algo: CompressZstd, level: 1},
+		{name: "zstd best", algo: CompressZstd, level: 22},
+		{name: "zstd invalid", algo: CompressZstd, level: 23, expectErr: ErrUnsupportedLevel},
+		{name: "none", algo: CompressNone, level: 1, expectErr: ErrUnsupportedLevel},
+		{name: "xz", algo: CompressXz, level: 1, expectErr: ErrUnsupportedLevel},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cr, err := Compress(bytes.NewReader(content), tc.algo, CompressLevel(tc.level))
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to compress: %v", err)
+			}
+			dr, err := Decompress(cr)
+			if err != nil {
+				t.Fatalf("failed to decompress: %v", err)
+			}
+			out, err := io.ReadAll(dr)
+			if err != nil {
+				t.Fatalf("failed to ReadAll: %v", err)
+			}
+			if !bytes.Equal(content, out) {
+				t.Errorf("output mismatch")
+			}
+		})
+	}
+}
+
 func FuzzRoundTrip(f *testing.F) {
 	f.Add(int(CompressNone), "hello world")
 	f.Fuzz(func(t *testing.T, comp int, s string) {
@@ -5,6 +5,8 @@ import "errors"
 var (
 	// ErrNotImplemented used for routines that need to be developed still
 	ErrNotImplemented = errors.New("this archive routine is not implemented yet")
+	// ErrUnsupportedLevel used for compression levels that are not supported by the compression type
+	ErrUnsupportedLevel = errors.New("unsupported compression level")
 	// ErrUnknownType used for unknown compression types
 	ErrUnknownType = errors.New("unknown compression type")
 	// ErrXzUnsupported because there isn't a Go package for this and I'm
@@ -0,0 +1,530 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/types/errs"
+)
+
+// Annotations on layer descriptors for seekable layer formats.
+const (
+	// AnnotationEStargzTOCDigest is the digest of the eStargz TOC JSON.
+	AnnotationEStargzTOCDigest = "containerd.io/snapshot/stargz/toc.digest"
+	// AnnotationEStargzUncompressedSize is the size of the uncompressed eStargz layer.
+	AnnotationEStargzUncompressedSize = "io.containers.estargz.uncompressed-size"
+	// AnnotationZstdChunkedManifestChecksum is the digest of the compressed zstd:chunked manifest.
+	AnnotationZstdChunkedManifestChecksum = "io.github.containers.zstd-chunked.manifest-checksum"
+	// AnnotationZstdChunkedManifestPosition is the location of the zstd:chunked manifest in the layer.
+	AnnotationZstdChunkedManifestPosition = "io.github.containers.zstd-chunked.manifest-position"
+	// AnnotationZstdChunkedTarSplitChecksum is the digest of the compressed zstd:chunked tar-split data.
+	AnnotationZstdChunkedTarSplitChecksum = "io.github.containers.zstd-chunked.tarsplit-checksum"
+	// AnnotationZstdChunkedTarSplitPosition is the location of the zstd:chunked tar-split data in the layer.
+	AnnotationZstdChunkedTarSplitPosition = "io.github.containers.zstd-chunked.tarsplit-position"
+)
+
+// SeekableAnnotations are the layer annotations that describe the content of an eStargz or zstd:chunked layer.
+// These become invalid when the content of the layer is changed.
+var SeekableAnnotations = []string{
+	AnnotationEStargzTOCDigest,
+	AnnotationEStargzUncompressedSize,
+	AnnotationZstdChunkedManifestChecksum,
+	AnnotationZstdChunkedManifestPosition,
+	AnnotationZstdChunkedTarSplitChecksum,
+	AnnotationZstdChunkedTarSplitPosition,
+}
+
+const (
+	estargzTOCName            = "stargz.index.json"
+	estargzLandmarkNoPrefetch = ".no.prefetch.landmark"
+	estargzLandmarkPrefetch   = ".prefetch.landmark"
+	estargzLandmarkContent    = 0xf
+	estargzFooterSize         = 51
+	estargzChunkSize          = 4 << 20
+	estargzTOCVersion         = 1
+	estargzXattrPrefix        = "SCHILY.xattr."
+	estargzEntryChunk         = "chunk"
+	estargzEntryReg           = "reg"
+	estargzFooterMagic        = "STARGZ"
+)
+
+// estargzTOC is the JSON TOC of an eStargz layer.
+type estargzTOC struct {
+	Version int                `json:"version"`
+	Entries []*estargzTOCEntry `json:"entries"`
+}
+
+// estargzTOCEntry is an entry in the eStargz TOC.
+type estargzTOCEntry struct {
+	Name        string            `json:"name"`
+	Type        string            `json:"type"`
+	Size        int64             `json:"size,omitempty"`
+	ModTime3339 string            `json:"modtime,omitempty"`
+	LinkName    string            `json:"linkName,omitempty"`
+	Mode        int64             `json:"mode,omitempty"`
+	UID         int               `json:"uid,omitempty"`
+	GID         int               `json:"gid,omitempty"`
+	Uname       string            `json:"userName,omitempty"`
+	Gname       string            `json:"groupName,omitempty"`
+	Offset      int64             `json:"offset,omitempty"`
+	DevMajor    int               `json:"devMajor,omitempty"`
+	DevMinor    int               `json:"devMinor,omitempty"`
+	Xattrs      map[string][]byte `json:"xattrs,omitempty"`
+	Digest      string            `json:"digest,omitempty"`
+	ChunkOffset int64             `json:"chunkOffset,omitempty"`
+	ChunkSize   int64             `json:"chunkSize,omitempty"`
+	ChunkDigest string            `json:"chunkDigest,omitempty"`
+}
+
+// EStargzInfo describes a layer created by [EStargz].
+type EStargzInfo struct {
+	TOCDigest          digest.Digest // digest of the TOC JSON
+	UncompressedDigest digest.Digest // digest of the uncompressed layer, used for the diff ID
+	UncompressedSize   int64         // size of the uncompressed layer
+}
+
+// Annotations returns the layer descriptor annotations for the eStargz layer.
+func (info EStargzInfo) Annotations() map[string]string {
+	return map[string]string{
+		AnnotationEStargzTOCDigest:        info.TOCDigest.String(),
+		AnnotationEStargzUncompressedSize: strconv.FormatInt(info.UncompressedSize, 10),
+	}
+}
+
+// EStargz converts an uncompressed tar stream to an eStargz layer written to w.
+// eStargz is a gzip compressed tar, where each file is separately compressed and indexed in a TOC for lazy pulling.
+// Landmark and TOC files from an existing eStargz layer are replaced.
+func EStargz(w io.Writer, r io.Reader) (EStargzInfo, error) {
+	info := EStargzInfo{}
+	ew := &estargzWriter{w: w, ucDig: digest.Canonical.Digester()}
+	tw := tar.NewWriter(ew)
+	toc := estargzTOC{Version: estargzTOCVersion}
+	// without a list of prioritized files, indicate that nothing should be prefetched
+	err := ew.addEntry(tw, &toc, &tar.Header{
+		Name:     estargzLandmarkNoPrefetch,
+		Typeflag: tar.TypeReg,
+		Mode:     0o644,
+		Size:     1,
+	}, bytes.NewReader([]byte{estargzLandmarkContent}))
+	if err != nil {
+		return info, err
+	}
+	tr := tar.NewReader(r)
+	for {
+		th, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return info, err
+		}
+		switch strings.TrimPrefix(path.Clean("/"+th.Name), "/") {
+		case estargzTOCName, estargzLandmarkNoPrefetch, estargzLandmarkPrefetch:
+			continue
+		}
+		err = ew.addEntry(tw, &toc, th, tr)
+		if err != nil {
+			return info, err
+		}
+	}
+	// flush the padding of the last file before starting the TOC
+	if err := tw.Flush(); err != nil {
+		return info, err
+	}
+	if err := ew.closeGz(); err != nil {
+		return info, err
+	}
+	tocOff := ew.n
+	tocJSON, err := json.MarshalIndent(toc, "", "\t")
+	if err != nil {
+		return info, err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:     estargzTOCName,
+		Typeflag: tar.TypeReg,
+		Size:     int64(len(tocJSON)),
+	})
+	if err != nil {
+		return info, err
+	}
+	if _, err := tw.Write(tocJSON); err != nil {
+		return info, err
+	}
+	if err := tw.Close(); err != nil {
+		return info, err
+	}
+	if err := ew.closeGz(); err != nil {
+		return info, err
+	}
+	if _, err := w.Write(estargzFooter(tocOff)); err != nil {
+		return info, err
+	}
+	info.TOCDigest = digest.FromBytes(tocJSON)
+	info.UncompressedDigest = ew.ucDig.Digest()
+	info.UncompressedSize = ew.ucSize
+	return info, nil
+}
+
+// estargzWriter compresses the tar stream, starting a new gzip stream for each chunk of file content.
+type estargzWriter struct {
+	w      io.Writer
+	gz     *gzip.Writer
+	n      int64 // compressed bytes written to w
+	ucDig  digest.Digester
+	ucSize int64
+}
+
+func (ew *estargzWriter) Write(p []byte) (int, error) {
+	if ew.gz == nil {
+		ew.gz = gzip.NewWriter(&estargzCounter{w: ew.w, n: &ew.n})
+	}
+	n, err := ew.gz.Write(p)
+	ew.ucSize += int64(n)
+	_, _ = ew.ucDig.Hash().Write(p[:n])
+	return n, err
+}
+
+func (ew *estargzWriter) closeGz() error {
+	if ew.gz == nil {
+		return nil
+	}
+	err := ew.gz.Close()
+	ew.gz = nil
+	return err
+}
+
+// addEntry writes a tar entry, placing each chunk of file content in a new gzip stream.
+func (ew *estargzWriter) addEntry(tw *tar.Writer, toc *estargzTOC, th *tar.Header, rdr io.Reader) error {
+	ent, err := estargzEntry(th)
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(th); err != nil {
+		return err
+	}
+	if ent.Type != estargzEntryReg || th.Size == 0 {
+		toc.Entries = append(toc.Entries, ent)
+		return nil
+	}
+	fileDig := digest.Canonical.Digester()
+	fileRdr := io.TeeReader(rdr, fileDig.Hash())
+	regEnt := ent
+	var written int64
+	for written < th.Size {
+		if err := ew.closeGz(); err != nil {
+			return err
+		}
+		chunkSize := int64(estargzChunkSize)
+		if remain := th.Size - written; remain < chunkSize {
+			chunkSize = remain
+		} else {
+			ent.ChunkSize = chunkSize
+		}
+		ent.Offset = ew.n
+		ent.ChunkOffset = written
+		chunkDig := digest.Canonical.Digester()
+		if _, err := io.CopyN(tw, io.TeeReader(fileRdr, chunkDig.Hash()), chunkSize); err != nil {
+			return err
+		}
+		ent.ChunkDigest = chunkDig.Digest().String()
+		toc.Entries = append(toc.Entries, ent)
+		written += chunkSize
+		ent = &estargzTOCEntry{Name: th.Name, Type: estargzEntryChunk}
+	}
+	regEnt.Digest = fileDig.Digest().String()
+	return nil
+}
+
+// estargzCounter counts the compressed bytes written.
+type estargzCounter struct {
+	w io.Writer
+	n *int64
+}
+
+func (c *estargzCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+// estargzEntry converts a tar header to a TOC entry.
+func estargzEntry(th *tar.Header) (*estargzTOCEntry, error) {
+	ent := &estargzTOCEntry{
+		Name:     th.Name,
+		Mode:     th.Mode,
+		UID:      th.Uid,
+		GID:      th.Gid,
+		Uname:    th.Uname,
+		Gname:    th.Gname,
+		LinkName: th.Linkname,
+	}
+	if !th.ModTime.IsZero() {
+		ent.ModTime3339 = th.ModTime.UTC().Round(time.Second).Format(time.RFC3339)
+	}
+	for k, v := range th.PAXRecords {
+		if name, ok := strings.CutPrefix(k, estargzXattrPrefix); ok {
+			if ent.Xattrs == nil {
+				ent.Xattrs = map[string][]byte{}
+			}
+			ent.Xattrs[name] = []byte(v)
+		}
+	}
+	switch th.Typeflag {
+	case tar.TypeReg:
+		ent.Type = estargzEntryReg
+		ent.Size = th.Size
+	case tar.TypeDir:
+		ent.Type = "dir"
+	case tar.TypeSymlink:
+		ent.Type = "symlink"
+	case tar.TypeLink:
+		ent.Type = "hardlink"
+	case tar.TypeChar:
+		ent.Type = "char"
+		ent.DevMajor = int(th.Devmajor)
+		ent.DevMinor = int(th.Devminor)
+	case tar.TypeBlock:
+		ent.Type = "block"
+		ent.DevMajor = int(th.Devmajor)
+		ent.DevMinor = int(th.Devminor)
+	case tar.TypeFifo:
+		ent.Type = "fifo"
+	default:
+		return nil, fmt.Errorf("unsupported tar type %c for %s%.0w", th.Typeflag, th.Name, errs.ErrUnsupported)
+	}
+	return ent, nil
+}
+
+// estargzFooter returns an empty gzip stream with the offset of the TOC in the extra header field.
+// The stream is written directly since the footer must be exactly 51 bytes, using a stored deflate block.
+func estargzFooter(tocOff int64) []byte {
+	subfield := fmt.Sprintf("%016x%s", tocOff, estargzFooterMagic)
+	buf := make([]byte, 0, estargzFooterSize)
+	// gzip header with the FEXTRA flag, zero mtime, and an unknown OS
+	buf = append(buf, 0x1f, 0x8b, 0x08, 0x04, 0, 0, 0, 0, 0, 0xff)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(4+len(subfield)))
+	buf = append(buf, 'S', 'G')
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(subfield)))
+	buf = append(buf, subfield...)
+	// final stored deflate block with no data
+	buf = append(buf, 0x01, 0x00, 0x00, 0xff, 0xff)
+	// crc32 and size of the empty content
+	buf = append(buf, 0, 0, 0, 0, 0, 0, 0, 0)
+	return buf
+}
+
+// SeekableVerifyReader wraps the reader of a compressed layer to verify the eStargz TOC or zstd:chunked manifest.
+// The annotations are from the layer descriptor, and the reader is returned unchanged if there are no seekable annotations.
+// When verification fails, the final read returns an error wrapping [errs.ErrDigestMismatch] instead of [io.EOF].
+// Close releases resources from the verification without closing the source reader.
+func SeekableVerifyReader(rdr io.Reader, annotations map[string]string) (io.ReadCloser, error) {
+	sv := &seekableVerify{rdr: rdr}
+	if tocDig, ok := annotations[AnnotationEStargzTOCDigest]; ok {
+		expect, err := digest.Parse(tocDig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", AnnotationEStargzTOCDigest, err)
+		}
+		pr, pw := io.Pipe()
+		sv.pw = pw
+		sv.done = make(chan error, 1)
+		go func() {
+			err := estargzVerify(pr, expect)
+			// drain the pipe so the reader is never blocked
+			_, _ = io.Copy(io.Discard, pr)
+			sv.done <- err
+		}()
+	}
+	for _, keys := range [][2]string{
+		{AnnotationZstdChunkedManifestChecksum, AnnotationZstdChunkedManifestPosition},
+		{AnnotationZstdChunkedTarSplitChecksum, AnnotationZstdChunkedTarSplitPosition},
+	} {
+		checksum, ok := annotations[keys[0]]
+		if !ok {
+			continue
+		}
+		rd, err := newRangeDigest(checksum, annotations[keys[1]])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", keys[0], err)
+		}
+		sv.ranges = append(sv.ranges, rd)
+	}
+	return sv, nil
+}
+
+type seekableVerify struct {
+	rdr      io.Reader
+	pw       *io.PipeWriter
+	done     chan error
+	ranges   []*rangeDigest
+	off      int64
+	finished bool
+	err      error
+}
+
+func (sv *seekableVerify) Read(p []byte) (int, error) {
+	if sv.finished {
+		return 0, cmpErr(sv.err, io.EOF)
+	}
+	n, err := sv.rdr.Read(p)
+	if n > 0 {
+		if sv.pw != nil {
+			_, _ = sv.pw.Write(p[:n])
+		}
+		for _, rd := range sv.ranges {
+			rd.write(sv.off, p[:n])
+		}
+		sv.off += int64(n)
+	}
+	if errors.Is(err, io.EOF) {
+		sv.finished = true
+		sv.err = sv.verify()
+		return n, cmpErr(sv.err, io.EOF)
+	}
+	return n, err
+}
+
+func (sv *seekableVerify) verify() error {
+	errList := []error{}
+	if sv.pw != nil {
+		_ = sv.pw.Close()
+		errList = append(errList, <-sv.done)
+		sv.pw = nil
+	}
+	for _, rd := range sv.ranges {
+		errList = append(errList, rd.verify())
+	}
+	return errors.Join(errList...)
+}
+
+func (sv *seekableVerify) Close() error {
+	if sv.pw != nil {
+		_ = sv.pw.CloseWithError(io.ErrClosedPipe)
+		<-sv.done
+		sv.pw = nil
+	}
+	return nil
+}
+
+// estargzVerify verifies the TOC digest and the digest of each file in an eStargz layer.
+func estargzVerify(r io.Reader, expect digest.Digest) error {
+	dr, err := Decompress(r)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(dr)
+	fileDigests := map[string]digest.Digest{}
+	var tocJSON []byte
+	for {
+		th, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(path.Clean("/"+th.Name), "/")
+		if name == estargzTOCName {
+			tocJSON, err = io.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if th.Typeflag == tar.TypeReg {
+			dig := digest.Canonical.Digester()
+			if _, err := io.Copy(dig.Hash(), tr); err != nil {
+				return err
+			}
+			fileDigests[name] = dig.Digest()
+		}
+	}
+	if tocJSON == nil {
+		return fmt.Errorf("eStargz TOC not found%.0w", errs.ErrNotFound)
+	}
+	if tocDig := expect.Algorithm().FromBytes(tocJSON); tocDig != expect {
+		return fmt.Errorf("eStargz TOC digest %s, expected %s%.0w", tocDig.String(), expect.String(), errs.ErrDigestMismatch)
+	}
+	toc := estargzTOC{}
+	if err := json.Unmarshal(tocJSON, &toc); err != nil {
+		return fmt.Errorf("failed to parse eStargz TOC: %w", err)
+	}
+	for _, ent := range toc.Entries {
+		if ent.Type != estargzEntryReg || ent.Digest == "" {
+			continue
+		}
+		name := strings.TrimPrefix(path.Clean("/"+ent.Name), "/")
+		if fileDigests[name].String() != ent.Digest {
+			return fmt.Errorf("eStargz file %s has digest %s, TOC lists %s%.0w", name, fileDigests[name].String(), ent.Digest, errs.ErrDigestMismatch)
+		}
+	}
+	return nil
+}
+
+// rangeDigest computes the digest of a range of bytes in the raw layer.
+type rangeDigest struct {
+	expect digest.Digest
+	start  int64
+	end    int64
+	dig    digest.Digester
+}
+
+// newRangeDigest parses a checksum and a zstd:chunked position of the form "offset:length:uncompressedLength:type".
+func newRangeDigest(checksum, position string) (*rangeDigest, error) {
+	expect, err := digest.Parse(checksum)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Split(position, ":")
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid position %q", position)
+	}
+	start, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid position %q: %w", position, err)
+	}
+	length, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid position %q: %w", position, err)
+	}
+	return &rangeDigest{
+		expect: expect,
+		start:  start,
+		end:    start + length,
+		dig:    expect.Algorithm().Digester(),
+	}, nil
+}
+
+func (rd *rangeDigest) write(off int64, p []byte) {
+	end := off + int64(len(p))
+	if end <= rd.start || off >= rd.end {
+		return
+	}
+	lo := max(rd.start-off, 0)
+	hi := min(rd.end-off, int64(len(p)))
+	_, _ = rd.dig.Hash().Write(p[lo:hi])
+}
+
+func (rd *rangeDigest) verify() error {
+	if dig := rd.dig.Digest(); dig != rd.expect {
+		return fmt.Errorf("zstd:chunked checksum %s, expected %s%.0w", dig.String(), rd.expect.String(), errs.ErrDigestMismatch)
+	}
+	return nil
+}
+
+// cmpErr returns err if set, or the fallback.
+func cmpErr(err, fallback error) error {
+	if err != nil {
+		return err
+	}
+	return fallback
+}
@@ -0,0 +1,248 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/types/errs"
+)
+
+func TestEStargz(t *testing.T) {
+	t.Parallel()
+	large := bytes.Repeat([]byte("0123456789abcdef"), (estargzChunkSize/16)+4)
+	files := map[string][]byte{
+		"dir/small.txt": []byte("hello world"),
+		"dir/large.bin": large,
+		"empty":         {},
+	}
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, th := range []*tar.Header{
+		{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "dir/small.txt", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "dir/large.bin", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "dir/link", Typeflag: tar.TypeSymlink, Linkname: "small.txt"},
+		{Name: "empty", Typeflag: tar.TypeReg, Mode: 0o644},
+	} {
+		if th.Typeflag == tar.TypeReg {
+			th.Size = int64(len(files[th.Name]))
+		}
+		if err := tw.WriteHeader(th); err != nil {
+			t.Fatalf("failed to write header: %v", err)
+		}
+		if _, err := tw.Write(files[th.Name]); err != nil {
+			t.Fatalf("failed to write content: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar: %v", err)
+	}
+	out := &bytes.Buffer{}
+	info, err := EStargz(out, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to convert: %v", err)
+	}
+	layer := out.Bytes()
+
+	t.Run("content", func(t *testing.T) {
+		gr, err := gzip.NewReader(bytes.NewReader(layer))
+		if err != nil {
+			t.Fatalf("failed to decompress: %v", err)
+		}
+		uc, err := io.ReadAll(gr)
+		if err != nil {
+			t.Fatalf("failed to decompress: %v", err)
+		}
+		if digest.FromBytes(uc) != info.UncompressedDigest || int64(len(uc)) != info.UncompressedSize {
+			t.Errorf("uncompressed digest or size mismatch")
+		}
+		if info.Annotations()[AnnotationEStargzUncompressedSize] != strconv.Itoa(len(uc)) {
+			t.Errorf("unexpected size annotation: %v", info.Annotations())
+		}
+		tr := tar.NewReader(bytes.NewReader(uc))
+		found := map[string]bool{}
+		for {
+			th, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				t.Fatalf("failed to read tar: %v", err)
+			}
+			found[th.Name] = true
+			if expect, ok := files[th.Name]; ok {
+				b, err := io.ReadAll(tr)
+				if err != nil {
+					t.Fatalf("failed to read %s: %v", th.Name, err)
+				}
+				if !bytes.Equal(b, expect) {
+					t.Errorf("content mismatch for %s", th.Name)
+				}
+			}
+		}
+		for _, name := range []string{estargzLandmarkNoPrefetch, "dir/", "dir/small.txt", "dir/large.bin", "dir/link", "empty", estargzTOCName} {
+			if !found[name] {
+				t.Errorf("missing entry %s", name)
+			}
+		}
+	})
+
+	t.Run("footer", func(t *testing.T) {
+		footer := layer[len(layer)-estargzFooterSize:]
+		gr, err := gzip.NewReader(bytes.NewReader(footer))
+		if err != nil {
+			t.Fatalf("failed to read footer: %v", err)
+		}
+		extra := gr.Extra
+		if len(extra) != 4+16+len(estargzFooterMagic) || string(extra[:2]) != "SG" || string(extra[20:]) != estargzFooterMagic {
+			t.Fatalf("invalid footer extra field: %q", extra)
+		}
+		tocOff, err := strconv.ParseInt(string(extra[4:20]), 16, 64)
+		if err != nil {
+			t.Fatalf("failed to parse toc offset: %v", err)
+		}
+		gr, err = gzip.NewReader(bytes.NewReader(layer[tocOff:]))
+		if err != nil {
+			t.Fatalf("failed to read toc: %v", err)
+		}
+		tr := tar.NewReader(gr)
+		th, err := tr.Next()
+		if err != nil || th.Name != estargzTOCName {
+			t.Fatalf("toc entry not found: %v", err)
+		}
+		tocJSON, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("failed to read toc: %v", err)
+		}
+		if digest.FromBytes(tocJSON) != info.TOCDigest {
+			t.Errorf("toc digest mismatch")
+		}
+		toc := estargzTOC{}
+		if err := json.Unmarshal(tocJSON, &toc); err != nil {
+			t.Fatalf("failed to parse toc: %v", err)
+		}
+		chunks := 0
+		for _, ent := range toc.Entries {
+			if ent.Name != "dir/large.bin" {
+				continue
+			}
+			chunks++
+			// each chunk starts a new gzip stream
+			gr, err := gzip.NewReader(bytes.NewReader(layer[ent.Offset:]))
+			if err != nil {
+				t.Fatalf("failed to read chunk at %d: %v", ent.Offset, err)
+			}
+			gr.Multistream(false)
+			b, err := io.ReadAll(gr)
+			if err != nil {
+				t.Fatalf("failed to read chunk: %v", err)
+			}
+			expect := large[ent.ChunkOffset:]
+			if int64(len(expect)) > estargzChunkSize {
+				expect = expect[:estargzChunkSize]
+			}
+			if !bytes.HasPrefix(b, expect) || digest.FromBytes(expect).String() != ent.ChunkDigest {
+				t.Errorf("chunk mismatch at offset %d", ent.ChunkOffset)
+			}
+		}
+		if chunks != 2 {
+			t.Errorf("unexpected number of chunks: %d", chunks)
+		}
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		vr, err := SeekableVerifyReader(bytes.NewReader(layer), info.Annotations())
+		if err != nil {
+			t.Fatalf("failed to create verifier: %v", err)
+		}
+		if _, err := io.ReadAll(vr); err != nil {
+			t.Errorf("verify failed: %v", err)
+		}
+		if err := vr.Close(); err != nil {
+			t.Errorf("close failed: %v", err)
+		}
+		vr, err = SeekableVerifyReader(bytes.NewReader(layer), map[string]string{
+			AnnotationEStargzTOCDigest: digest.FromString("bad").String(),
+		})
+		if err != nil {
+			t.Fatalf("failed to create verifier: %v", err)
+		}
+		if _, err := io.ReadAll(vr); !errors.Is(err, errs.ErrDigestMismatch) {
+			t.Errorf("verify did not fail with a digest mismatch: %v", err)
+		}
+		// closing before the end of the layer does not block
+		vr, err = SeekableVerifyReader(bytes.NewReader(layer), info.Annotations())
+		if err != nil {
+			t.Fatalf("failed to create verifier: %v", err)
+		}
+		if _, err := vr.Read(make([]byte, 10)); err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if err := vr.Close(); err != nil {
+			t.Errorf("close failed: %v", err)
+		}
+	})
+}
+
+func TestSeekableVerifyZstdChunked(t *testing.T) {
+	t.Parallel()
+	layer := bytes.Repeat([]byte("zstd:chunked test data "), 1000)
+	manifest := layer[1000:1500]
+	tt := []struct {
+		name      string
+		annot     map[string]string
+		expectErr error
+	}{
+		{
+			name:  "none",
+			annot: map[string]string{},
+		},
+		{
+			name: "valid",
+			annot: map[string]string{
+				AnnotationZstdChunkedManifestChecksum: digest.FromBytes(manifest).String(),
+				AnnotationZstdChunkedManifestPosition: fmt.Sprintf("1000:500:%d:1", 2000),
+			},
+		},
+		{
+			name: "mismatch",
+			annot: map[string]string{
+				AnnotationZstdChunkedManifestChecksum: digest.FromBytes(manifest).String(),
+				AnnotationZstdChunkedManifestPosition: "1001:500:2000:1",
+			},
+			expectErr: errs.ErrDigestMismatch,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			vr, err := SeekableVerifyReader(bytes.NewReader(layer), tc.annot)
+			if err != nil {
+				t.Fatalf("failed to create verifier: %v", err)
+			}
+			defer vr.Close()
+			// use a small buffer to split the range across reads
+			out := &bytes.Buffer{}
+			_, err = io.CopyBuffer(out, struct{ io.Reader }{vr}, make([]byte, 77))
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify failed: %v", err)
+			}
+			if !bytes.Equal(out.Bytes(), layer) {
+				t.Errorf("content changed by verify")
+			}
+		})
+	}
+}
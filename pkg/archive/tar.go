@@ -10,6 +10,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -20,13 +21,38 @@ type TarOpts func(*tarOpts)
 type tarOpts struct {
 	// allowRelative bool // allow relative paths outside of target folder
 	compress string
+	whiteout WhiteoutMode
 }
 
+// WhiteoutMode configures how whiteout files in a layer are handled by [Extract].
+type WhiteoutMode int
+
+const (
+	// WhiteoutKeep extracts whiteout files as regular files.
+	WhiteoutKeep WhiteoutMode = iota
+	// WhiteoutSkip ignores whiteout files.
+	WhiteoutSkip
+	// WhiteoutApply deletes the files in the extract path that are hidden by whiteout files.
+	WhiteoutApply
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
 // TarCompressGzip option to use gzip compression on tar files
 func TarCompressGzip(to *tarOpts) {
 	to.compress = "gzip"
 }
 
+// TarWhiteout option to configure the handling of whiteout files when extracting a tar
+func TarWhiteout(mode WhiteoutMode) TarOpts {
+	return func(to *tarOpts) {
+		to.whiteout = mode
+	}
+}
+
 // TarUncompressed option to tar (noop)
 func TarUncompressed(to *tarOpts) {
 }
@@ -110,6 +136,7 @@ func Extract(ctx context.Context, path string, r io.Reader, opts ...TarOpts) err
 	}
 
 	// verify path exists
+	path = filepath.Clean(path)
 	fi, err := os.Stat(path)
 	if err != nil {
 		return err
@@ -124,6 +151,8 @@ func Extract(ctx context.Context, path string, r io.Reader, opts ...TarOpts) err
 		return err
 	}
 
+	// track extracted files to avoid removing them with an opaque whiteout
+	extracted := map[string]bool{}
 	rt := tar.NewReader(rd)
 	for {
 		hdr, err := rt.Next()
@@ -135,6 +164,18 @@ func Extract(ctx context.Context, path string, r io.Reader, opts ...TarOpts) err
 		}
 		// join a cleaned version of the filename with the path
 		fn := filepath.Join(path, filepath.Clean("/"+hdr.Name))
+		if base := filepath.Base(fn); strings.HasPrefix(base, whiteoutPrefix) && to.whiteout != WhiteoutKeep {
+			if to.whiteout == WhiteoutApply {
+				err = extractWhiteout(path, fn, extracted)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		for dir := fn; dir != path && !extracted[dir]; dir = filepath.Dir(dir) {
+			extracted[dir] = true
+		}
 		switch hdr.Typeflag {
 		case tar.TypeDir:
 			if hdr.Mode < 0 || hdr.Mode > math.MaxUint32 {
@@ -146,6 +187,10 @@ func Extract(ctx context.Context, path string, r io.Reader, opts ...TarOpts) err
 			}
 		case tar.TypeReg:
 			// TODO: configure file mode, creation timestamp, etc
+			err = os.MkdirAll(filepath.Dir(fn), 0o755)
+			if err != nil {
+				return err
+			}
 			//#nosec G304 filename is limited to provided path directory
 			fh, err := os.Create(fn)
 			if err != nil {
@@ -168,3 +213,34 @@ func Extract(ctx context.Context, path string, r io.Reader, opts ...TarOpts) err
 
 	return nil
 }
+
+// extractWhiteout removes the files hidden by a whiteout file, skipping any files extracted from the same layer.
+func extractWhiteout(path, fn string, extracted map[string]bool) error {
+	dir, base := filepath.Split(fn)
+	dir = filepath.Clean(dir)
+	if base == whiteoutOpaque {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		for _, entry := range entries {
+			target := filepath.Join(dir, entry.Name())
+			if extracted[target] {
+				continue
+			}
+			err = os.RemoveAll(target)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	target := filepath.Join(dir, strings.TrimPrefix(base, whiteoutPrefix))
+	if target == path || extracted[target] {
+		return nil
+	}
+	return os.RemoveAll(target)
+}
@@ -0,0 +1,110 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractWhiteout(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	// build a layer with a whiteout, an opaque whiteout, and new files
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, entry := range []struct {
+		name string
+		dir  bool
+	}{
+		{name: "keep/", dir: true},
+		{name: "keep/.wh.old.txt"},
+		{name: "keep/new.txt"},
+		{name: "opq/sub/new.txt"},
+		{name: "opq/.wh..wh..opq"},
+	} {
+		th := &tar.Header{Name: entry.name, Mode: 0o644, Typeflag: tar.TypeReg}
+		content := []byte{}
+		if entry.dir {
+			th.Mode = 0o755
+			th.Typeflag = tar.TypeDir
+		} else if !strings.HasPrefix(filepath.Base(entry.name), whiteoutPrefix) {
+			content = []byte(entry.name)
+		}
+		th.Size = int64(len(content))
+		if err := tw.WriteHeader(th); err != nil {
+			t.Fatalf("failed to write header: %v", err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatalf("failed to write content: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close tar: %v", err)
+	}
+	layer := buf.Bytes()
+
+	// setup files from a lower layer
+	setup := func(t *testing.T) string {
+		dir := t.TempDir()
+		for _, name := range []string{"keep/old.txt", "keep/other.txt", "opq/lower.txt"} {
+			fn := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(fn), 0o755); err != nil {
+				t.Fatalf("failed to create dir: %v", err)
+			}
+			if err := os.WriteFile(fn, []byte(name), 0o644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+		}
+		return dir
+	}
+	tt := []struct {
+		name     string
+		mode     WhiteoutMode
+		exists   []string
+		notExist []string
+	}{
+		{
+			name:     "keep",
+			mode:     WhiteoutKeep,
+			exists:   []string{"keep/.wh.old.txt", "keep/old.txt", "keep/new.txt", "opq/lower.txt", "opq/sub/new.txt", "opq/.wh..wh..opq"},
+			notExist: []string{},
+		},
+		{
+			name:     "skip",
+			mode:     WhiteoutSkip,
+			exists:   []string{"keep/old.txt", "keep/new.txt", "opq/lower.txt", "opq/sub/new.txt"},
+			notExist: []string{"keep/.wh.old.txt", "opq/.wh..wh..opq"},
+		},
+		{
+			name:     "apply",
+			mode:     WhiteoutApply,
+			exists:   []string{"keep/other.txt", "keep/new.txt", "opq/sub/new.txt"},
+			notExist: []string{"keep/.wh.old.txt", "keep/old.txt", "opq/lower.txt", "opq/.wh..wh..opq"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := setup(t)
+			err := Extract(ctx, dir, bytes.NewReader(layer), TarWhiteout(tc.mode))
+			if err != nil {
+				t.Fatalf("failed to extract: %v", err)
+			}
+			for _, name := range tc.exists {
+				if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+					t.Errorf("missing file %s: %v", name, err)
+				}
+			}
+			for _, name := range tc.notExist {
+				if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("file was not removed %s: %v", name, err)
+				}
+			}
+		})
+	}
+}
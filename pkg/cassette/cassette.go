@@ -0,0 +1,260 @@
+// Package cassette records http interactions with a registry and replays them without network access.
+//
+// A cassette is used as http middleware, for example with regclient.WithHTTPMiddleware(c.Middleware).
+// In record mode, every request is sent to the registry and the response is saved.
+// In replay mode, requests are answered from the saved responses, and requests that were not recorded fail.
+//
+// Credentials are not saved: sensitive headers are removed, and tokens in auth responses are redacted.
+// Registry API responses under "/v2/" are saved unchanged to preserve their digests.
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/regclient/regclient/internal/httptrace"
+	"github.com/regclient/regclient/types/errs"
+)
+
+// Mode selects between recording and replaying interactions.
+type Mode int
+
+const (
+	// ModeReplay answers requests from the saved interactions.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the registry and saves each interaction.
+	ModeRecord
+)
+
+// Cassette contains a list of recorded http interactions.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+	filename     string
+	mode         Mode
+	mu           sync.Mutex
+	used         []bool // interactions already returned in replay mode
+}
+
+// Interaction is a single request and the response or error returned.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+	Err      string   `json:"err,omitempty"` // error returned by the transport instead of a response
+}
+
+// Request is the recorded http request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+}
+
+// Response is the recorded http response.
+type Response struct {
+	StatusCode   int         `json:"statusCode,omitempty"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"bodyEncoding,omitempty"` // "base64" for binary content, empty for text
+}
+
+// Opt is used to configure a cassette.
+type Opt func(*Cassette)
+
+// WithMode sets the mode of the cassette, defaulting to [ModeReplay].
+func WithMode(m Mode) Opt {
+	return func(c *Cassette) {
+		c.mode = m
+	}
+}
+
+// New returns a cassette saved in filename.
+// In replay mode, the interactions are loaded from the file.
+// In record mode, the file is written by [Cassette.Save].
+func New(filename string, opts ...Opt) (*Cassette, error) {
+	c := &Cassette{
+		Interactions: []Interaction{},
+		filename:     filename,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.mode != ModeReplay {
+		return c, nil
+	}
+	//#nosec G304 cassette location is user controlled
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette %s: %w", filename, err)
+	}
+	err = json.Unmarshal(b, c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", filename, err)
+	}
+	c.used = make([]bool, len(c.Interactions))
+	return c, nil
+}
+
+// Middleware wraps the http transport to record or replay requests.
+func (c *Cassette) Middleware(next http.RoundTripper) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if c.mode == ModeRecord {
+			return c.record(next, req)
+		}
+		return c.replay(req)
+	})
+}
+
+// Save writes the recorded interactions to the file.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	//#nosec G306 cassettes do not contain credentials
+	err = os.WriteFile(c.filename, append(b, '\n'), 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write cassette %s: %w", c.filename, err)
+	}
+	return nil
+}
+
+func (c *Cassette) record(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	i := Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    httptrace.RedactURL(req.URL),
+			Header: saveHeader(req.Header),
+		},
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		i.Err = err.Error()
+		c.add(i)
+		return resp, err
+	}
+	// the full body is read to save it, and replaced for the caller
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return resp, err
+	}
+	i.Response = Response{
+		StatusCode: resp.StatusCode,
+		Header:     saveHeader(resp.Header),
+	}
+	switch {
+	case !utf8.Valid(body):
+		i.Response.Body = base64.StdEncoding.EncodeToString(body)
+		i.Response.BodyEncoding = "base64"
+	case !strings.Contains(req.URL.Path, "/v2/"):
+		// auth responses may include tokens
+		i.Response.Body, _ = httptrace.RedactBody(resp.Header.Get("Content-Type"), body)
+	default:
+		i.Response.Body = string(body)
+	}
+	c.add(i)
+	return resp, nil
+}
+
+func (c *Cassette) add(i Interaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, i)
+}
+
+// replay returns the first unused interaction matching the method and URL.
+// When every match has been used, the last match is returned again to handle retries.
+func (c *Cassette) replay(req *http.Request) (*http.Response, error) {
+	u := httptrace.RedactURL(req.URL)
+	c.mu.Lock()
+	found := -1
+	for idx, i := range c.Interactions {
+		if i.Request.Method != req.Method || i.Request.URL != u {
+			continue
+		}
+		found = idx
+		if !c.used[idx] {
+			break
+		}
+	}
+	if found >= 0 {
+		c.used[found] = true
+	}
+	c.mu.Unlock()
+	if found < 0 {
+		return nil, fmt.Errorf("no recorded response for %s %s%.0w", req.Method, u, errs.ErrNotFound)
+	}
+	i := c.Interactions[found]
+	if i.Err != "" {
+		return nil, fmt.Errorf("recorded error for %s %s: %s", req.Method, u, i.Err)
+	}
+	body := []byte(i.Response.Body)
+	if i.Response.BodyEncoding == "base64" {
+		var err error
+		body, err = base64.StdEncoding.DecodeString(i.Response.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode recorded body for %s %s: %w", req.Method, u, err)
+		}
+	}
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Response.StatusCode, http.StatusText(i.Response.StatusCode)),
+		StatusCode:    i.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        i.Response.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	if req.Method == http.MethodHead {
+		resp.ContentLength = -1
+		if cl, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+			resp.ContentLength = cl
+		}
+	}
+	return resp, nil
+}
+
+// saveHeader returns a copy of the header without credentials.
+// Signatures are removed from redirects to match the redacted URL of the next request.
+func saveHeader(h http.Header) http.Header {
+	result := http.Header{}
+	for k, v := range h {
+		if httptrace.SensitiveHeader(k) {
+			continue
+		}
+		if http.CanonicalHeaderKey(k) == "Location" && len(v) == 1 {
+			if u, err := url.Parse(v[0]); err == nil && u.RawQuery != "" {
+				v = []string{httptrace.RedactURL(u)}
+			}
+		}
+		result[k] = v
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,204 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olareg/olareg"
+	oConfig "github.com/olareg/olareg/config"
+
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/scheme/reg"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ref"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   "../../testdata",
+		},
+	})
+	ts := httptest.NewServer(regHandler)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+	filename := filepath.Join(t.TempDir(), "cassette.json")
+	r, err := ref.New(tsHost + "/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	// run returns the tags, manifest, and config of the image
+	run := func(c *Cassette) ([]string, []byte, []byte, error) {
+		rc := regclient.New(
+			regclient.WithConfigHost(config.Host{Name: tsHost, TLS: config.TLSDisabled}),
+			regclient.WithHTTPMiddleware(c.Middleware),
+		)
+		tl, err := rc.TagList(ctx, r)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		tags, err := tl.GetTags()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		m, err := rc.ManifestGet(ctx, r)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		raw, err := m.RawBody()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		conf, err := rc.ImageConfig(ctx, r)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		confRaw, err := conf.RawBody()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return tags, raw, confRaw, nil
+	}
+
+	cRec, err := New(filename, WithMode(ModeRecord))
+	if err != nil {
+		t.Fatalf("failed to create cassette: %v", err)
+	}
+	recTags, recManifest, recConf, err := run(cRec)
+	if err != nil {
+		t.Fatalf("failed to record: %v", err)
+	}
+	if len(cRec.Interactions) == 0 {
+		t.Fatalf("no interactions recorded")
+	}
+	if err := cRec.Save(); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	// stop the registry to verify requests are not sent during the replay
+	ts.Close()
+
+	cPlay, err := New(filename)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %v", err)
+	}
+	playTags, playManifest, playConf, err := run(cPlay)
+	if err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	if !slices.Equal(recTags, playTags) {
+		t.Errorf("tags differ, recorded %v, replayed %v", recTags, playTags)
+	}
+	if string(recManifest) != string(playManifest) {
+		t.Errorf("manifest differs, recorded %s, replayed %s", recManifest, playManifest)
+	}
+	if string(recConf) != string(playConf) {
+		t.Errorf("config differs, recorded %s, replayed %s", recConf, playConf)
+	}
+
+	rMissing := r.SetTag("missing")
+	rc := regclient.New(
+		regclient.WithConfigHost(config.Host{Name: tsHost, TLS: config.TLSDisabled}),
+		regclient.WithHTTPMiddleware(cPlay.Middleware),
+		regclient.WithRegOpts(reg.WithDelay(time.Millisecond*10, time.Millisecond*50)),
+	)
+	_, err = rc.ManifestHead(ctx, rMissing)
+	if err == nil {
+		t.Errorf("unrecorded request did not fail")
+	}
+
+	_, err = New(filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("loading a missing cassette did not fail: %v", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Set-Cookie", "session=secret-cookie")
+			_, _ = w.Write([]byte(`{"token":"secret-token"}`))
+		case "/redirect":
+			w.Header().Set("Location", "/storage?X-Amz-Signature=secret-sig&digest=sha256:abc")
+			w.WriteHeader(http.StatusTemporaryRedirect)
+		case "/storage":
+			_, _ = w.Write([]byte{0x00, 0xff, 0x01})
+		}
+	}))
+	t.Cleanup(ts.Close)
+	filename := filepath.Join(t.TempDir(), "cassette.json")
+	cRec, err := New(filename, WithMode(ModeRecord))
+	if err != nil {
+		t.Fatalf("failed to create cassette: %v", err)
+	}
+	get := func(c *Cassette, path string) (string, error) {
+		hc := &http.Client{Transport: c.Middleware(http.DefaultTransport)}
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Authorization", "Basic secret-auth")
+		resp, err := hc.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		return string(b), err
+	}
+	for _, path := range []string{"/token", "/redirect"} {
+		if _, err := get(cRec, path); err != nil {
+			t.Fatalf("failed to record %s: %v", path, err)
+		}
+	}
+	if err := cRec.Save(); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read cassette: %v", err)
+	}
+	for _, secret := range []string{"secret-cookie", "secret-token", "secret-sig", "secret-auth"} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("cassette contains %s: %s", secret, string(b))
+		}
+	}
+	ts.Close()
+
+	cPlay, err := New(filename)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %v", err)
+	}
+	out, err := get(cPlay, "/token")
+	if err != nil || out != `{"token":"[redacted]"}` {
+		t.Errorf("unexpected token response: %s, %v", out, err)
+	}
+	out, err = get(cPlay, "/redirect")
+	if err != nil || out != string([]byte{0x00, 0xff, 0x01}) {
+		t.Errorf("unexpected redirect response: %v, %v", []byte(out), err)
+	}
+	_, err = get(cPlay, "/missing")
+	if !errors.Is(err, errs.ErrNotFound) {
+		t.Errorf("unexpected error for an unrecorded request: %v", err)
+	}
+}
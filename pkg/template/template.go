@@ -76,7 +76,7 @@ func Writer(out io.Writer, tmpl string, data any, opts ...Opt) error {
 // String converts a template to a string
 func String(tmpl string, data any, opts ...Opt) (string, error) {
 	var sb strings.Builder
-	err := Writer(&sb, tmpl, data)
+	err := Writer(&sb, tmpl, data, opts...)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,119 @@
+// Package traceotel adapts an OpenTelemetry TracerProvider for use with regclient.
+//
+// The returned provider is passed to regclient.WithTracerProvider:
+//
+//	rc := regclient.New(regclient.WithTracerProvider(traceotel.New(otel.GetTracerProvider())))
+//
+// Spans are created for each registry operation, and the W3C trace context is added to every HTTP request.
+package traceotel
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/regclient/regclient/types/trace"
+)
+
+type provider struct {
+	tp   oteltrace.TracerProvider
+	prop propagation.TextMapPropagator
+}
+
+// Opts define options for [New].
+type Opts func(*provider)
+
+// WithPropagator sets the propagator used to add the trace context to HTTP requests.
+// The default is [propagation.TraceContext], use otel.GetTextMapPropagator() for the global propagator.
+func WithPropagator(prop propagation.TextMapPropagator) Opts {
+	return func(p *provider) {
+		p.prop = prop
+	}
+}
+
+// New returns a [trace.TracerProvider] that creates spans with an OpenTelemetry TracerProvider.
+// The returned value also implements [trace.Injector].
+func New(tp oteltrace.TracerProvider, opts ...Opts) trace.TracerProvider {
+	p := &provider{
+		tp:   tp,
+		prop: propagation.TraceContext{},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Tracer returns a named [trace.Tracer].
+func (p *provider) Tracer(name string) trace.Tracer {
+	return tracer{t: p.tp.Tracer(name)}
+}
+
+// Inject adds the trace context from ctx to the request headers.
+func (p *provider) Inject(ctx context.Context, header http.Header) {
+	if p.prop == nil {
+		return
+	}
+	p.prop.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+type tracer struct {
+	t oteltrace.Tracer
+}
+
+// Start creates a span with the attributes converted from [slog.Attr] values.
+func (t tracer) Start(ctx context.Context, spanName string, attrs ...slog.Attr) (context.Context, trace.Span) {
+	ctx, span := t.t.Start(ctx, spanName, oteltrace.WithAttributes(attrList("", attrs)...))
+	return ctx, spanWrap{s: span}
+}
+
+type spanWrap struct {
+	s oteltrace.Span
+}
+
+// RecordError records the error and sets the status of the span.
+func (sw spanWrap) RecordError(err error) {
+	sw.s.RecordError(err)
+	sw.s.SetStatus(codes.Error, err.Error())
+}
+
+// End completes the span.
+func (sw spanWrap) End() {
+	sw.s.End()
+}
+
+// attrList converts slog attributes, flattening groups with a "." separated key.
+func attrList(prefix string, attrs []slog.Attr) []attribute.KeyValue {
+	kvList := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		key := a.Key
+		if prefix != "" && key != "" {
+			key = prefix + "." + key
+		} else if key == "" {
+			// inline a group without a key
+			key = prefix
+		}
+		v := a.Value.Resolve()
+		switch v.Kind() {
+		case slog.KindString:
+			kvList = append(kvList, attribute.String(key, v.String()))
+		case slog.KindInt64:
+			kvList = append(kvList, attribute.Int64(key, v.Int64()))
+		case slog.KindFloat64:
+			kvList = append(kvList, attribute.Float64(key, v.Float64()))
+		case slog.KindBool:
+			kvList = append(kvList, attribute.Bool(key, v.Bool()))
+		case slog.KindGroup:
+			kvList = append(kvList, attrList(key, v.Group())...)
+		default:
+			// uint64, durations, times, and other values are converted to a string
+			kvList = append(kvList, attribute.String(key, v.String()))
+		}
+	}
+	return kvList
+}
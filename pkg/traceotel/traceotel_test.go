@@ -0,0 +1,134 @@
+package traceotel
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/regclient/regclient/types/trace"
+)
+
+type testProvider struct {
+	embedded.TracerProvider
+	spans []*testSpan
+}
+
+type testTracer struct {
+	embedded.Tracer
+	tp *testProvider
+}
+
+type testSpan struct {
+	noop.Span
+	name   string
+	attrs  []attribute.KeyValue
+	sc     oteltrace.SpanContext
+	err    error
+	status codes.Code
+	ended  bool
+}
+
+func (tp *testProvider) Tracer(name string, opts ...oteltrace.TracerOption) oteltrace.Tracer {
+	return &testTracer{tp: tp}
+}
+
+func (tt *testTracer) Start(ctx context.Context, spanName string, opts ...oteltrace.SpanStartOption) (context.Context, oteltrace.Span) {
+	conf := oteltrace.NewSpanStartConfig(opts...)
+	span := &testSpan{
+		name:  spanName,
+		attrs: conf.Attributes(),
+		sc: oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+			TraceID:    oteltrace.TraceID{1},
+			SpanID:     oteltrace.SpanID{byte(len(tt.tp.spans) + 1)},
+			TraceFlags: oteltrace.FlagsSampled,
+		}),
+	}
+	tt.tp.spans = append(tt.tp.spans, span)
+	return oteltrace.ContextWithSpan(ctx, span), span
+}
+
+func (ts *testSpan) SpanContext() oteltrace.SpanContext {
+	return ts.sc
+}
+
+func (ts *testSpan) RecordError(err error, opts ...oteltrace.EventOption) {
+	ts.err = err
+}
+
+func (ts *testSpan) SetStatus(code codes.Code, description string) {
+	ts.status = code
+}
+
+func (ts *testSpan) End(opts ...oteltrace.SpanEndOption) {
+	ts.ended = true
+}
+
+func TestTraceOTel(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	otp := &testProvider{}
+	tp := New(otp)
+	inject, ok := tp.(trace.Injector)
+	if !ok {
+		t.Fatalf("provider does not implement the injector")
+	}
+	t.Run("attributes", func(t *testing.T) {
+		_, span := tp.Tracer("test").Start(ctx, "attrs",
+			slog.String("ref", "registry.example.org/repo:v1"),
+			slog.Int64("size", 42),
+			slog.Bool("dryrun", true),
+			slog.Duration("wait", time.Second),
+			slog.Group("req", slog.String("method", "GET")),
+		)
+		span.End()
+		expect := []attribute.KeyValue{
+			attribute.String("ref", "registry.example.org/repo:v1"),
+			attribute.Int64("size", 42),
+			attribute.Bool("dryrun", true),
+			attribute.String("wait", "1s"),
+			attribute.String("req.method", "GET"),
+		}
+		received := otp.spans[len(otp.spans)-1]
+		if !slices.Equal(received.attrs, expect) {
+			t.Errorf("unexpected attributes, expected %v, received %v", expect, received.attrs)
+		}
+		if !received.ended {
+			t.Errorf("span did not end")
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		errTest := errors.New("test failure")
+		_, span := tp.Tracer("test").Start(ctx, "error")
+		trace.End(span, errTest)
+		received := otp.spans[len(otp.spans)-1]
+		if !errors.Is(received.err, errTest) || received.status != codes.Error || !received.ended {
+			t.Errorf("unexpected span, error %v, status %v, ended %t", received.err, received.status, received.ended)
+		}
+	})
+	t.Run("inject", func(t *testing.T) {
+		spanCtx, span := tp.Tracer("test").Start(ctx, "inject")
+		defer span.End()
+		header := http.Header{}
+		inject.Inject(spanCtx, header)
+		// the third span created by the test provider
+		expect := "00-01000000000000000000000000000000-0300000000000000-01"
+		if header.Get("Traceparent") != expect {
+			t.Errorf("unexpected traceparent, expected %s, received %s", expect, header.Get("Traceparent"))
+		}
+		header = http.Header{}
+		inject.Inject(ctx, header)
+		if len(header) != 0 {
+			t.Errorf("headers added without a span: %v", header)
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package regclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/types"
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/mediatype"
+	v1 "github.com/regclient/regclient/types/oci/v1"
+	"github.com/regclient/regclient/types/ref"
+)
+
+const (
+	// PromoteArtifactType is the artifact type of the promotion record pushed by [RegClient.ImagePromote].
+	PromoteArtifactType = "application/vnd.regclient.promotion.v1+json"
+	// PromoteAnnotationSource is the annotation key for the source reference of a promotion.
+	PromoteAnnotationSource = "org.regclient.promote.source"
+	// PromoteAnnotationDigest is the annotation key for the digest of the promoted image.
+	PromoteAnnotationDigest = "org.regclient.promote.digest"
+	// PromoteAnnotationTarget is the annotation key for the target reference of a promotion.
+	PromoteAnnotationTarget = "org.regclient.promote.target"
+)
+
+// ImageWithPromoteDigest requires the source of ImagePromote to resolve to the digest.
+func ImageWithPromoteDigest(d string) ImageOpts {
+	return func(opts *imageOpt) {
+		opts.promoteDigest = d
+	}
+}
+
+// ImageWithPromoteForce allows ImagePromote to overwrite a target tag that points to a different digest.
+// This also replaces tags matching the immutable tag patterns in the host config.
+func ImageWithPromoteForce() ImageOpts {
+	return func(opts *imageOpt) {
+		opts.promoteForce = true
+	}
+}
+
+// ImageWithPromoteRecord pushes a promotion record to the target as a referrer of the promoted image.
+// The record includes the provided annotations, along with the source, target, digest, and time of the promotion.
+func ImageWithPromoteRecord(annotations map[string]string) ImageOpts {
+	return func(opts *imageOpt) {
+		opts.promoteRecord = true
+		if opts.promoteAnnot == nil {
+			opts.promoteAnnot = map[string]string{}
+		}
+		maps.Copy(opts.promoteAnnot, annotations)
+	}
+}
+
+// ImagePromote copies an image to a target tag, refusing to change an existing tag.
+// The source is resolved to a digest before the copy, and [ImageWithPromoteDigest] verifies that digest.
+// If the target tag exists with a different digest, an error wrapping [errs.ErrTagExists] is returned unless [ImageWithPromoteForce] is set.
+// Other options are passed through to [RegClient.ImageCopy].
+func (rc *RegClient) ImagePromote(ctx context.Context, refSrc ref.Ref, refTgt ref.Ref, opts ...ImageOpts) error {
+	var opt imageOpt
+	for _, optFn := range opts {
+		optFn(&opt)
+	}
+	if refTgt.Tag == "" || refTgt.Digest != "" {
+		return fmt.Errorf("promotion target must be a tag without a digest, %s%.0w", refTgt.CommonName(), errs.ErrInvalidReference)
+	}
+	// resolve and pin the source digest
+	mSrc, err := rc.ManifestHead(ctx, refSrc, WithManifestRequireDigest())
+	if err != nil {
+		return fmt.Errorf("failed to resolve promotion source %s: %w", refSrc.CommonName(), err)
+	}
+	dig := mSrc.GetDescriptor().Digest
+	if opt.promoteDigest != "" {
+		expect, err := digest.Parse(opt.promoteDigest)
+		if err != nil {
+			return err
+		}
+		if expect != dig {
+			return fmt.Errorf("promotion source %s has digest %s, expected %s%.0w", refSrc.CommonName(), dig.String(), expect.String(), errs.ErrDigestMismatch)
+		}
+	}
+	refSrcDig := refSrc.SetDigest(dig.String())
+	// check the target tag
+	mTgt, err := rc.ManifestHead(ctx, refTgt, WithManifestRequireDigest())
+	if err != nil && !errors.Is(err, errs.ErrNotFound) && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to check promotion target %s: %w", refTgt.CommonName(), err)
+	}
+	if err != nil || mTgt.GetDescriptor().Digest != dig {
+		if err == nil {
+			if !opt.promoteForce {
+				return fmt.Errorf("promotion target %s has digest %s, refusing to replace with %s%.0w",
+					refTgt.CommonName(), mTgt.GetDescriptor().Digest.String(), dig.String(), errs.ErrTagExists)
+			}
+			rc.slog.Info("Replacing promotion target",
+				slog.String("target", refTgt.CommonName()),
+				slog.String("old", mTgt.GetDescriptor().Digest.String()),
+				slog.String("new", dig.String()))
+		}
+		err = rc.ImageCopy(ctx, refSrcDig, refTgt, opts...)
+		if err != nil {
+			return err
+		}
+	}
+	if opt.promoteRecord {
+		return rc.imagePromoteRecord(ctx, refSrc, refTgt, mSrc.GetDescriptor(), opt.promoteAnnot)
+	}
+	return nil
+}
+
+// imagePromoteRecord pushes an artifact to the target repository referring to the promoted image.
+func (rc *RegClient) imagePromoteRecord(ctx context.Context, refSrc, refTgt ref.Ref, d descriptor.Descriptor, annotations map[string]string) error {
+	annot := map[string]string{}
+	maps.Copy(annot, annotations)
+	annot[types.AnnotationCreated] = time.Now().UTC().Format(time.RFC3339)
+	annot[PromoteAnnotationSource] = refSrc.CommonName()
+	annot[PromoteAnnotationTarget] = refTgt.CommonName()
+	annot[PromoteAnnotationDigest] = d.Digest.String()
+	err := rc.artifactPut(ctx, refTgt, PromoteArtifactType, d, annot)
+	if err != nil {
+		return fmt.Errorf("failed to push promotion record: %w", err)
+	}
+	return nil
+}
+
+// artifactPut pushes an artifact with an empty config and layer, and the annotations, to the repository of r as a referrer of the subject.
+func (rc *RegClient) artifactPut(ctx context.Context, r ref.Ref, artifactType string, subject descriptor.Descriptor, annot map[string]string) error {
+	emptyDesc := descriptor.Descriptor{
+		MediaType: mediatype.OCI1Empty,
+		Digest:    descriptor.EmptyDigest,
+		Size:      int64(len(descriptor.EmptyData)),
+	}
+	_, err := rc.BlobPut(ctx, r, emptyDesc, bytes.NewReader(descriptor.EmptyData))
+	if err != nil {
+		return fmt.Errorf("failed to push artifact config: %w", err)
+	}
+	m, err := manifest.New(manifest.WithOrig(v1.Manifest{
+		Versioned:    v1.ManifestSchemaVersion,
+		MediaType:    mediatype.OCI1Manifest,
+		ArtifactType: artifactType,
+		Config:       emptyDesc,
+		Layers:       []descriptor.Descriptor{emptyDesc},
+		Annotations:  annot,
+		Subject: &descriptor.Descriptor{
+			MediaType: subject.MediaType,
+			Digest:    subject.Digest,
+			Size:      subject.Size,
+		},
+	}))
+	if err != nil {
+		return err
+	}
+	return rc.ManifestPut(ctx, r.SetDigest(m.GetDescriptor().Digest.String()), m, WithManifestChild())
+}
@@ -0,0 +1,123 @@
+package regclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/regclient/regclient/scheme"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ref"
+)
+
+func TestImagePromote(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	rc := New()
+	rV1, err := ref.New("ocidir://./testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rV2 := rV1.SetTag("v2")
+	rTgt, err := ref.New("ocidir://" + tempDir + "/testrepo:prod")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	mV1, err := rc.ManifestHead(ctx, rV1, WithManifestRequireDigest())
+	if err != nil {
+		t.Fatalf("failed to head v1: %v", err)
+	}
+	digV1 := mV1.GetDescriptor().Digest
+	mV2, err := rc.ManifestHead(ctx, rV2, WithManifestRequireDigest())
+	if err != nil {
+		t.Fatalf("failed to head v2: %v", err)
+	}
+	digV2 := mV2.GetDescriptor().Digest
+
+	tt := []struct {
+		name      string
+		src       ref.Ref
+		tgt       ref.Ref
+		opts      []ImageOpts
+		expectErr error
+		expectDig string
+	}{
+		{
+			name:      "digest target",
+			src:       rV1,
+			tgt:       rTgt.SetDigest(digV1.String()),
+			expectErr: errs.ErrInvalidReference,
+		},
+		{
+			name:      "digest mismatch",
+			src:       rV1,
+			tgt:       rTgt,
+			opts:      []ImageOpts{ImageWithPromoteDigest(digV2.String())},
+			expectErr: errs.ErrDigestMismatch,
+		},
+		{
+			name:      "promote v1",
+			src:       rV1,
+			tgt:       rTgt,
+			opts:      []ImageOpts{ImageWithPromoteDigest(digV1.String())},
+			expectDig: digV1.String(),
+		},
+		{
+			name:      "promote v1 again",
+			src:       rV1,
+			tgt:       rTgt,
+			opts:      []ImageOpts{ImageWithPromoteRecord(map[string]string{"org.example.approver": "test"})},
+			expectDig: digV1.String(),
+		},
+		{
+			name:      "promote v2 without force",
+			src:       rV2,
+			tgt:       rTgt,
+			expectErr: errs.ErrTagExists,
+		},
+		{
+			name:      "promote v2 with force",
+			src:       rV2,
+			tgt:       rTgt,
+			opts:      []ImageOpts{ImageWithPromoteForce()},
+			expectDig: digV2.String(),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := rc.ImagePromote(ctx, tc.src, tc.tgt, tc.opts...)
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Fatalf("did not receive expected error: %v", tc.expectErr)
+				}
+				if !errors.Is(err, tc.expectErr) {
+					t.Fatalf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to promote: %v", err)
+			}
+			m, err := rc.ManifestHead(ctx, tc.tgt, WithManifestRequireDigest())
+			if err != nil {
+				t.Fatalf("failed to head target: %v", err)
+			}
+			if m.GetDescriptor().Digest.String() != tc.expectDig {
+				t.Errorf("unexpected digest, expected %s, received %s", tc.expectDig, m.GetDescriptor().Digest.String())
+			}
+		})
+	}
+	// verify the promotion record
+	rl, err := rc.ReferrerList(ctx, rTgt.SetDigest(digV1.String()), scheme.WithReferrerAT(PromoteArtifactType))
+	if err != nil {
+		t.Fatalf("failed to list referrers: %v", err)
+	}
+	if len(rl.Descriptors) != 1 {
+		t.Fatalf("unexpected number of promotion records: %d", len(rl.Descriptors))
+	}
+	annot := rl.Descriptors[0].Annotations
+	if annot[PromoteAnnotationSource] != rV1.CommonName() || annot[PromoteAnnotationDigest] != digV1.String() || annot["org.example.approver"] != "test" {
+		t.Errorf("unexpected annotations on promotion record: %v", annot)
+	}
+}
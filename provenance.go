@@ -0,0 +1,94 @@
+package regclient
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/regclient/regclient/internal/version"
+	"github.com/regclient/regclient/scheme"
+	"github.com/regclient/regclient/types"
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/ref"
+)
+
+const (
+	// ProvenanceArtifactType is the artifact type of the provenance record pushed by [ImageWithProvenance].
+	ProvenanceArtifactType = "application/vnd.regclient.provenance.v1+json"
+	// ProvenanceAnnotationSource is the annotation key for the source reference of a copy.
+	ProvenanceAnnotationSource = "org.regclient.provenance.source"
+	// ProvenanceAnnotationRegistry is the annotation key for the source registry of a copy.
+	ProvenanceAnnotationRegistry = "org.regclient.provenance.registry"
+	// ProvenanceAnnotationDigest is the annotation key for the digest of the source manifest of a copy.
+	ProvenanceAnnotationDigest = "org.regclient.provenance.digest"
+	// ProvenanceAnnotationVersion is the annotation key for the version of regclient that ran the copy.
+	ProvenanceAnnotationVersion = "org.regclient.provenance.version"
+)
+
+// ImageWithProvenance pushes a provenance record to the target of [RegClient.ImageCopy] as a referrer of the copied image.
+// The record annotations include the source reference, registry, and digest, the time of the copy, and the regclient version.
+// The record is not pushed when the target already has a record from the same source registry and digest, or with [ImageWithDryRun].
+// Records are pushed to the repository from [ImageWithReferrerTgt] when set.
+func ImageWithProvenance() ImageOpts {
+	return func(opts *imageOpt) {
+		opts.provenance = true
+	}
+}
+
+// imageCopyProvenance pushes a provenance record referring to the copied image in the target.
+func (rc *RegClient) imageCopyProvenance(ctx context.Context, refSrc, refTgt ref.Ref, opt *imageOpt) error {
+	mSrc, err := rc.ManifestHead(ctx, refSrc, WithManifestRequireDigest())
+	if err != nil {
+		return fmt.Errorf("failed to get provenance source %s: %w", refSrc.CommonName(), err)
+	}
+	mTgt, err := rc.ManifestHead(ctx, refTgt, WithManifestRequireDigest())
+	if err != nil {
+		return fmt.Errorf("failed to get provenance subject %s: %w", refTgt.CommonName(), err)
+	}
+	sDig := mSrc.GetDescriptor().Digest
+	subject := mTgt.GetDescriptor()
+	refSubject := refTgt.SetDigest(subject.Digest.String())
+	refRecord := refTgt
+	rOpts := []scheme.ReferrerOpts{
+		scheme.WithReferrerMatchOpt(descriptor.MatchOpt{
+			ArtifactType: ProvenanceArtifactType,
+			Annotations: map[string]string{
+				ProvenanceAnnotationRegistry: refSrc.Registry,
+				ProvenanceAnnotationDigest:   sDig.String(),
+			},
+		}),
+	}
+	if opt.referrerTgt.IsSet() {
+		refRecord = opt.referrerTgt
+		rOpts = append(rOpts, scheme.WithReferrerSource(opt.referrerTgt))
+	}
+	rl, err := rc.ReferrerList(ctx, refSubject, rOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to list referrers of %s: %w", refSubject.CommonName(), err)
+	}
+	if len(rl.Descriptors) > 0 {
+		rc.slog.Debug("Provenance record exists",
+			slog.String("source", refSrc.CommonName()),
+			slog.String("target", refSubject.CommonName()))
+		return nil
+	}
+	info := version.GetInfo()
+	ver := info.VCSTag
+	if ver == "" {
+		ver = info.VCSRef
+	}
+	annot := map[string]string{
+		types.AnnotationCreated:      time.Now().UTC().Format(time.RFC3339),
+		ProvenanceAnnotationSource:   refSrc.CommonName(),
+		ProvenanceAnnotationRegistry: refSrc.Registry,
+		ProvenanceAnnotationDigest:   sDig.String(),
+		ProvenanceAnnotationVersion:  ver,
+	}
+	err = rc.artifactPut(ctx, refRecord, ProvenanceArtifactType, subject, annot)
+	if err != nil {
+		return fmt.Errorf("failed to push provenance record: %w", err)
+	}
+	opt.reportAdd(func(r *ImageCopyReport) { r.ManifestsPushed++ })
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/opencontainers/go-digest"
+
 	"github.com/regclient/regclient/scheme"
 	"github.com/regclient/regclient/types/errs"
 	"github.com/regclient/regclient/types/platform"
@@ -53,5 +55,41 @@ func (rc *RegClient) ReferrerList(ctx context.Context, rSubject ref.Ref, opts ..
 	if err != nil {
 		return referrer.ReferrerList{}, err
 	}
-	return schemeAPI.ReferrerList(ctx, rSubject, opts...)
+	rl, err := schemeAPI.ReferrerList(ctx, rSubject, opts...)
+	if err != nil || config.Depth < 2 {
+		return rl, err
+	}
+	// nested referrers are queried from the same source without the filters
+	nestedOpts := []scheme.ReferrerOpts{}
+	if config.SrcRepo.IsSet() {
+		nestedOpts = append(nestedOpts, scheme.WithReferrerSource(config.SrcRepo))
+	}
+	err = referrerListNested(ctx, schemeAPI, &rl, rSubject, nestedOpts, config.Depth-1)
+	return rl, err
+}
+
+// referrerListNested populates the referrers of each descriptor in rl, recursing until depth is reached.
+func referrerListNested(ctx context.Context, schemeAPI scheme.API, rl *referrer.ReferrerList, rSubject ref.Ref, opts []scheme.ReferrerOpts, depth int) error {
+	if depth < 1 {
+		return nil
+	}
+	for _, d := range rl.Descriptors {
+		rNested := rSubject.SetDigest(d.Digest.String())
+		rlNested, err := schemeAPI.ReferrerList(ctx, rNested, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to list referrers of %s: %w", rNested.CommonName(), err)
+		}
+		if len(rlNested.Descriptors) == 0 {
+			continue
+		}
+		err = referrerListNested(ctx, schemeAPI, &rlNested, rNested, opts, depth-1)
+		if err != nil {
+			return err
+		}
+		if rl.Referrers == nil {
+			rl.Referrers = map[digest.Digest]referrer.ReferrerList{}
+		}
+		rl.Referrers[d.Digest] = rlNested
+	}
+	return nil
 }
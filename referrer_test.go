@@ -17,6 +17,9 @@ import (
 	"github.com/regclient/regclient/internal/copyfs"
 	"github.com/regclient/regclient/scheme"
 	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/mediatype"
+	v1 "github.com/regclient/regclient/types/oci/v1"
 	"github.com/regclient/regclient/types/ref"
 )
 
@@ -202,3 +205,110 @@ func TestReferrerList(t *testing.T) {
 		})
 	}
 }
+
+func TestReferrerListDepth(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	err := copyfs.Copy(tempDir+"/testrepo", "./testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to copy testrepo to tempDir: %v", err)
+	}
+	rc := New()
+	r, err := ref.New("ocidir://" + tempDir + "/testrepo:v2")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	// add a signature to the sbom
+	rl, err := rc.ReferrerList(ctx, r, scheme.WithReferrerMatchOpt(descriptor.MatchOpt{ArtifactType: "application/example.sbom"}))
+	if err != nil || len(rl.Descriptors) != 1 {
+		t.Fatalf("failed to find sbom: %v", err)
+	}
+	sbomDesc := rl.Descriptors[0]
+	sigM, err := manifest.New(manifest.WithOrig(v1.Manifest{
+		Versioned:    v1.ManifestSchemaVersion,
+		MediaType:    mediatype.OCI1Manifest,
+		ArtifactType: "application/example.signature",
+		Config: descriptor.Descriptor{
+			MediaType: mediatype.OCI1Empty,
+			Digest:    descriptor.EmptyDigest,
+			Size:      int64(len(descriptor.EmptyData)),
+		},
+		Layers: []descriptor.Descriptor{
+			{
+				MediaType: mediatype.OCI1Empty,
+				Digest:    descriptor.EmptyDigest,
+				Size:      int64(len(descriptor.EmptyData)),
+			},
+		},
+		Subject: &sbomDesc,
+	}))
+	if err != nil {
+		t.Fatalf("failed to create signature: %v", err)
+	}
+	err = rc.ManifestPut(ctx, r.SetDigest(sigM.GetDescriptor().Digest.String()), sigM)
+	if err != nil {
+		t.Fatalf("failed to push signature: %v", err)
+	}
+	tt := []struct {
+		name         string
+		opts         []scheme.ReferrerOpts
+		count        int
+		expectNested bool
+	}{
+		{
+			name:  "default",
+			count: 2,
+		},
+		{
+			name:  "depth 1",
+			opts:  []scheme.ReferrerOpts{scheme.WithReferrerDepth(1)},
+			count: 2,
+		},
+		{
+			name:         "depth 2",
+			opts:         []scheme.ReferrerOpts{scheme.WithReferrerDepth(2)},
+			count:        2,
+			expectNested: true,
+		},
+		{
+			name: "depth 2 filtered",
+			opts: []scheme.ReferrerOpts{
+				scheme.WithReferrerDepth(2),
+				scheme.WithReferrerMatchOpt(descriptor.MatchOpt{ArtifactType: "application/example.sbom"}),
+			},
+			count:        1,
+			expectNested: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rl, err := rc.ReferrerList(ctx, r, tc.opts...)
+			if err != nil {
+				t.Fatalf("failed to list referrers: %v", err)
+			}
+			if len(rl.Descriptors) != tc.count {
+				t.Errorf("unexpected number of referrers, expected %d, received %d", tc.count, len(rl.Descriptors))
+			}
+			if !tc.expectNested {
+				if len(rl.Referrers) > 0 {
+					t.Errorf("unexpected nested referrers: %v", rl.Referrers)
+				}
+				return
+			}
+			if len(rl.Referrers) != 1 {
+				t.Fatalf("unexpected nested referrers: %v", rl.Referrers)
+			}
+			rlSBOM, ok := rl.Referrers[sbomDesc.Digest]
+			if !ok {
+				t.Fatalf("nested referrers missing sbom")
+			}
+			if len(rlSBOM.Descriptors) != 1 || rlSBOM.Descriptors[0].Digest != sigM.GetDescriptor().Digest {
+				t.Errorf("unexpected nested referrers of the sbom: %v", rlSBOM.Descriptors)
+			}
+			if rlSBOM.Subject.Digest != sbomDesc.Digest.String() {
+				t.Errorf("unexpected nested subject, expected %s, received %s", sbomDesc.Digest, rlSBOM.Subject.Digest)
+			}
+		})
+	}
+}
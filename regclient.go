@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"net/http"
 	"time"
 
 	"github.com/regclient/regclient/config"
@@ -12,6 +14,7 @@ import (
 	"github.com/regclient/regclient/scheme"
 	"github.com/regclient/regclient/scheme/ocidir"
 	"github.com/regclient/regclient/scheme/reg"
+	"github.com/regclient/regclient/types/trace"
 )
 
 const (
@@ -29,11 +32,19 @@ const (
 
 // RegClient is used to access OCI distribution-spec registries.
 type RegClient struct {
+	auditLog    *slog.Logger
+	blobFlights *blobFlightList
 	hosts       map[string]*config.Host
 	hostDefault *config.Host
+	manifestMax int64
+	memo        *memo
+	pins        config.Pins
 	regOpts     []reg.Opts
+	regScheme   *reg.Reg
 	schemes     map[string]scheme.API
 	slog        *slog.Logger
+	tracer      trace.Tracer
+	traceInject trace.Injector
 	userAgent   string
 }
 
@@ -43,11 +54,12 @@ type Opt func(*RegClient)
 // New returns a registry client.
 func New(opts ...Opt) *RegClient {
 	rc := RegClient{
-		hosts:     map[string]*config.Host{},
-		userAgent: DefaultUserAgent,
-		regOpts:   []reg.Opts{},
-		schemes:   map[string]scheme.API{},
-		slog:      slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})),
+		blobFlights: newBlobFlightList(),
+		hosts:       map[string]*config.Host{},
+		userAgent:   DefaultUserAgent,
+		regOpts:     []reg.Opts{},
+		schemes:     map[string]scheme.API{},
+		slog:        slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})),
 	}
 
 	info := version.GetInfo()
@@ -65,6 +77,11 @@ func New(opts ...Opt) *RegClient {
 	}
 
 	// configure regOpts
+	if rc.tracer != nil && rc.traceInject != nil {
+		rc.regOpts = append(rc.regOpts, reg.WithHTTPMiddleware(func(next http.RoundTripper) http.RoundTripper {
+			return traceTransport{next: next, inject: rc.traceInject}
+		}))
+	}
 	hostList := []*config.Host{}
 	for _, h := range rc.hosts {
 		hostList = append(hostList, h)
@@ -75,12 +92,28 @@ func New(opts ...Opt) *RegClient {
 		reg.WithSlog(rc.slog),
 		reg.WithUserAgent(rc.userAgent),
 	)
+	ociOpts := []ocidir.Opts{
+		ocidir.WithSlog(rc.slog),
+	}
+	if rc.manifestMax != 0 {
+		rc.regOpts = append(rc.regOpts, reg.WithManifestMaxPull(rc.manifestMax))
+		ociOpts = append(ociOpts, ocidir.WithManifestMax(rc.manifestMax))
+	}
 
 	// setup scheme's
-	rc.schemes["reg"] = reg.New(rc.regOpts...)
-	rc.schemes["ocidir"] = ocidir.New(
-		ocidir.WithSlog(rc.slog),
-	)
+	rc.regScheme = reg.New(rc.regOpts...)
+	rc.schemes["reg"] = rc.regScheme
+	rc.schemes["ocidir"] = ocidir.New(ociOpts...)
+	if rc.auditLog != nil {
+		for name, api := range rc.schemes {
+			rc.schemes[name] = &schemeAudit{schemeWrap: schemeWrap{API: api}, name: name, log: rc.auditLog, hosts: rc.hosts}
+		}
+	}
+	if rc.tracer != nil {
+		for name, api := range rc.schemes {
+			rc.schemes[name] = &schemeTrace{schemeWrap: schemeWrap{API: api}, name: name, tracer: rc.tracer}
+		}
+	}
 
 	rc.slog.Debug("regclient initialized",
 		slog.String("VCSRef", info.VCSRef),
@@ -89,6 +122,12 @@ func New(opts ...Opt) *RegClient {
 	return &rc
 }
 
+// HostStats returns statistics on requests to a registry host that were delayed by backoffs or rate limits.
+// See [reg.WithRetryAfterMax] to queue rate limited requests.
+func (rc *RegClient) HostStats(host string) reg.HostStats {
+	return rc.regScheme.HostStats(host)
+}
+
 // WithBlobLimit sets the max size for chunked blob uploads which get stored in memory.
 //
 // Deprecated: replace with WithRegOpts(reg.WithBlobLimit(limit)), see [WithRegOpts] and [reg.WithBlobLimit].
@@ -172,6 +211,34 @@ func WithDockerCredsFile(fname string) Opt {
 	}
 }
 
+// WithHTTPMiddleware wraps the http transport used by the reg scheme.
+// Middleware can add request signing, custom headers, audit logging, or tracing.
+// The first middleware in the list is the outermost wrapper.
+func WithHTTPMiddleware(mw ...func(next http.RoundTripper) http.RoundTripper) Opt {
+	return WithRegOpts(reg.WithHTTPMiddleware(mw...))
+}
+
+// WithManifestMaxSize sets the largest manifest in bytes that will be pulled from a registry or read from an OCI Layout.
+// The limit is enforced while the manifest is read, so an oversized response is never fully buffered.
+// A negative value disables the limit, and zero keeps the default of 8MiB.
+func WithManifestMaxSize(bytes int64) Opt {
+	return func(rc *RegClient) {
+		rc.manifestMax = bytes
+	}
+}
+
+// WithPins fails any manifest request that resolves a pinned tag to a different digest.
+// The error wraps [errs.ErrPinMismatch], see [config.PinsLoadFile] to load pins from a file.
+// This may be repeated to add more pins.
+func WithPins(pins config.Pins) Opt {
+	return func(rc *RegClient) {
+		if rc.pins == nil {
+			rc.pins = config.Pins{}
+		}
+		maps.Copy(rc.pins, pins)
+	}
+}
+
 // WithRegOpts passes through opts to the reg scheme.
 func WithRegOpts(opts ...reg.Opts) Opt {
 	return func(rc *RegClient) {
@@ -235,6 +302,7 @@ func (rc *RegClient) hostLoad(src string, hosts []config.Host) {
 		}
 		tls, _ := configHost.TLS.MarshalText()
 		rc.slog.Debug("Loading config",
+			slog.String("basePath", configHost.BasePath),
 			slog.Int64("blobChunk", configHost.BlobChunk),
 			slog.Int64("blobMax", configHost.BlobMax),
 			slog.String("helper", configHost.CredHelper),
@@ -2,11 +2,18 @@ package regclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/regclient/regclient/scheme"
 	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ref"
 	"github.com/regclient/regclient/types/repo"
 )
 
@@ -25,9 +32,217 @@ func (rc *RegClient) RepoList(ctx context.Context, hostname string, opts ...sche
 	if err != nil {
 		return nil, err
 	}
-	rl, ok := schemeAPI.(repoLister)
+	rl, ok := schemeAs[repoLister](schemeAPI)
 	if !ok {
 		return nil, errs.ErrNotImplemented
 	}
 	return rl.RepoList(ctx, hostname, opts...)
 }
+
+// RepoCopyResult is the result of copying a single tag with [RegClient.RepoCopy].
+type RepoCopyResult struct {
+	Tag      string        // Tag that was copied.
+	Err      error         // Err is set when the copy failed or was not run.
+	Start    time.Time     // Start is the time the copy began.
+	Duration time.Duration // Duration is the time taken by the copy.
+}
+
+// RepoCopyReport contains the results of [RegClient.RepoCopy], in the order of the source tag listing.
+type RepoCopyReport struct {
+	Results []RepoCopyResult
+}
+
+// Errors returns the number of tags that failed to copy.
+func (rr RepoCopyReport) Errors() int {
+	count := 0
+	for _, res := range rr.Results {
+		if res.Err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Err returns the joined errors of all failed tags, or nil if every tag was copied.
+func (rr RepoCopyReport) Err() error {
+	errList := []error{}
+	for _, res := range rr.Results {
+		if res.Err != nil {
+			errList = append(errList, fmt.Errorf("copy of tag %s failed: %w", res.Tag, res.Err))
+		}
+	}
+	return errors.Join(errList...)
+}
+
+type repoCopyOpt struct {
+	imageOpts    []ImageOpts
+	parallel     int
+	skipExisting bool
+	stopOnError  bool
+	tagAllow     []string
+	tagDeny      []string
+}
+
+// RepoCopyOpts define options for [RegClient.RepoCopy].
+type RepoCopyOpts func(*repoCopyOpt)
+
+// RepoCopyWithImageOpts passes options to the [RegClient.ImageCopy] of each tag.
+func RepoCopyWithImageOpts(opts ...ImageOpts) RepoCopyOpts {
+	return func(opt *repoCopyOpt) {
+		opt.imageOpts = append(opt.imageOpts, opts...)
+	}
+}
+
+// RepoCopyWithParallel sets the number of tags copied concurrently, defaults to 4.
+// A value less than 1 copies every tag concurrently.
+func RepoCopyWithParallel(n int) RepoCopyOpts {
+	return func(opt *repoCopyOpt) {
+		opt.parallel = n
+	}
+}
+
+// RepoCopyWithReferrers includes the referrers of each tag, see [ImageWithReferrers].
+func RepoCopyWithReferrers(rOpts ...scheme.ReferrerOpts) RepoCopyOpts {
+	return func(opt *repoCopyOpt) {
+		opt.imageOpts = append(opt.imageOpts, ImageWithReferrers(rOpts...))
+	}
+}
+
+// RepoCopyWithSkipExisting skips tags that already exist in the target repository.
+func RepoCopyWithSkipExisting() RepoCopyOpts {
+	return func(opt *repoCopyOpt) {
+		opt.skipExisting = true
+	}
+}
+
+// RepoCopyWithStopOnError skips any tags that have not started copying after the first failure.
+// Skipped tags return an error wrapping [errs.ErrCanceled].
+func RepoCopyWithStopOnError() RepoCopyOpts {
+	return func(opt *repoCopyOpt) {
+		opt.stopOnError = true
+	}
+}
+
+// RepoCopyWithTagAllow only copies tags matching at least one of the regular expressions.
+// Expressions are anchored to match the full tag.
+func RepoCopyWithTagAllow(exps ...string) RepoCopyOpts {
+	return func(opt *repoCopyOpt) {
+		opt.tagAllow = append(opt.tagAllow, exps...)
+	}
+}
+
+// RepoCopyWithTagDeny skips tags matching any of the regular expressions.
+// Expressions are anchored to match the full tag.
+func RepoCopyWithTagDeny(exps ...string) RepoCopyOpts {
+	return func(opt *repoCopyOpt) {
+		opt.tagDeny = append(opt.tagDeny, exps...)
+	}
+}
+
+// RepoCopy copies every tag in the source repository to the target repository.
+// Tags are copied concurrently, and blobs shared between tags are only copied once.
+// The returned report includes the result of each tag, and the error is the joined errors of any failed tags.
+// An error is returned without a report when the tags cannot be listed or a filter is invalid.
+func (rc *RegClient) RepoCopy(ctx context.Context, rSrc, rTgt ref.Ref, opts ...RepoCopyOpts) (RepoCopyReport, error) {
+	opt := repoCopyOpt{
+		parallel: 4,
+	}
+	for _, optFn := range opts {
+		optFn(&opt)
+	}
+	rSrc = rSrc.SetTag("")
+	rTgt = rTgt.SetTag("")
+	if !rSrc.IsSetRepo() || !rTgt.IsSetRepo() {
+		return RepoCopyReport{}, fmt.Errorf("source and target repository must be set: %s, %s%.0w", rSrc.CommonName(), rTgt.CommonName(), errs.ErrInvalidReference)
+	}
+	allow, err := repoCopyCompile(opt.tagAllow)
+	if err != nil {
+		return RepoCopyReport{}, err
+	}
+	deny, err := repoCopyCompile(opt.tagDeny)
+	if err != nil {
+		return RepoCopyReport{}, err
+	}
+	tl, err := rc.TagList(ctx, rSrc)
+	if err != nil {
+		return RepoCopyReport{}, fmt.Errorf("failed to list tags in %s: %w", rSrc.CommonName(), err)
+	}
+	tags, err := tl.GetTags()
+	if err != nil {
+		return RepoCopyReport{}, fmt.Errorf("failed to list tags in %s: %w", rSrc.CommonName(), err)
+	}
+	existing := []string{}
+	if opt.skipExisting {
+		tlTgt, err := rc.TagList(ctx, rTgt)
+		if err != nil && !errors.Is(err, errs.ErrNotFound) && !errors.Is(err, fs.ErrNotExist) {
+			return RepoCopyReport{}, fmt.Errorf("failed to list tags in %s: %w", rTgt.CommonName(), err)
+		}
+		if err == nil {
+			existing, err = tlTgt.GetTags()
+			if err != nil {
+				return RepoCopyReport{}, fmt.Errorf("failed to list tags in %s: %w", rTgt.CommonName(), err)
+			}
+		}
+	}
+	report := RepoCopyReport{
+		Results: []RepoCopyResult{},
+	}
+	for _, tag := range tags {
+		if (len(allow) > 0 && !slices.ContainsFunc(allow, func(exp *regexp.Regexp) bool { return exp.MatchString(tag) })) ||
+			slices.ContainsFunc(deny, func(exp *regexp.Regexp) bool { return exp.MatchString(tag) }) ||
+			slices.Contains(existing, tag) {
+			continue
+		}
+		report.Results = append(report.Results, RepoCopyResult{Tag: tag})
+	}
+	parallel := opt.parallel
+	if parallel < 1 {
+		parallel = max(len(report.Results), 1)
+	}
+	// blobs are tracked across every tag to avoid copying the same blob concurrently
+	seen := newImageSeenList()
+	ops := make([]BatchOp, len(report.Results))
+	for i, res := range report.Results {
+		ops[i] = BatchOpFunc(rSrc.SetTag(res.Tag), func(ctx context.Context) error {
+			iOpt := imageOpt{
+				seen:    seen,
+				finalFn: []func(context.Context) error{},
+			}
+			for _, optFn := range opt.imageOpts {
+				optFn(&iOpt)
+			}
+			err := rc.imageCopy(ctx, rSrc.SetTag(res.Tag), rTgt.SetTag(res.Tag), &iOpt)
+			if err != nil {
+				rc.slog.Warn("Failed to copy tag",
+					slog.String("source", rSrc.SetTag(res.Tag).CommonName()),
+					slog.String("target", rTgt.SetTag(res.Tag).CommonName()),
+					slog.String("err", err.Error()))
+			}
+			return err
+		})
+	}
+	bOpts := []BatchOpts{BatchWithWorkers(parallel)}
+	if opt.stopOnError {
+		bOpts = append(bOpts, BatchWithStopOnError())
+	}
+	br, _ := rc.Batch(ctx, ops, bOpts...)
+	for i, bRes := range br.Results {
+		report.Results[i].Err = bRes.Err
+		report.Results[i].Start = bRes.Start
+		report.Results[i].Duration = bRes.Duration
+	}
+	return report, report.Err()
+}
+
+// repoCopyCompile compiles a list of anchored regular expressions.
+func repoCopyCompile(exps []string) ([]*regexp.Regexp, error) {
+	ret := make([]*regexp.Regexp, 0, len(exps))
+	for _, exp := range exps {
+		re, err := regexp.Compile("^" + exp + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag filter %q: %w", exp, err)
+		}
+		ret = append(ret, re)
+	}
+	return ret, nil
+}
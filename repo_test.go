@@ -3,13 +3,22 @@ package regclient
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/opencontainers/go-digest"
+
 	"github.com/regclient/regclient/scheme/reg"
+	"github.com/regclient/regclient/types/blob"
 	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ref"
 )
 
 func TestRepoList(t *testing.T) {
@@ -26,3 +35,144 @@ func TestRepoList(t *testing.T) {
 		t.Errorf("RepoList unexpected error on hostname with a path: expected %v, received %v", errs.ErrParsingFailed, err)
 	}
 }
+
+func TestRepoCopy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	rc := New()
+	rSrc, err := ref.New("ocidir://./testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rMissing, err := ref.New("ocidir://./testdata/missing")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	errHook := errors.New("hook failure")
+	tt := []struct {
+		name       string
+		src        ref.Ref
+		tgt        string
+		opts       []RepoCopyOpts
+		expectTags []string
+		expectErrs []error
+		expectErr  error
+	}{
+		{
+			name:       "allow and deny",
+			src:        rSrc,
+			opts:       []RepoCopyOpts{RepoCopyWithTagAllow("v[0-9]+", "a[0-9]"), RepoCopyWithTagDeny("v3", "a3")},
+			expectTags: []string{"a1", "a2", "v1", "v2"},
+		},
+		{
+			name:       "skip existing",
+			src:        rSrc,
+			tgt:        "allow-and-deny",
+			opts:       []RepoCopyOpts{RepoCopyWithTagAllow("v[0-9]+", "a[0-9]"), RepoCopyWithSkipExisting()},
+			expectTags: []string{"a3", "v3"},
+		},
+		{
+			name: "stop on error",
+			src:  rSrc,
+			opts: []RepoCopyOpts{
+				RepoCopyWithTagAllow("v[0-9]+"),
+				RepoCopyWithParallel(1),
+				RepoCopyWithStopOnError(),
+				RepoCopyWithImageOpts(ImageWithBlobReaderHook(func(br *blob.BReader) (*blob.BReader, error) {
+					return nil, errHook
+				})),
+			},
+			expectTags: []string{"v1", "v2", "v3"},
+			expectErrs: []error{errHook, errs.ErrCanceled, errs.ErrCanceled},
+		},
+		{
+			name:       "referrers",
+			src:        rSrc,
+			opts:       []RepoCopyOpts{RepoCopyWithTagAllow("v2"), RepoCopyWithReferrers(), RepoCopyWithParallel(1)},
+			expectTags: []string{"v2"},
+		},
+		{
+			name:      "invalid filter",
+			src:       rSrc,
+			opts:      []RepoCopyOpts{RepoCopyWithTagAllow("v[")},
+			expectErr: fmt.Errorf(`invalid tag filter "v[": error parsing regexp: missing closing ]: ` + "`[$`"),
+		},
+		{
+			name:      "missing repo",
+			src:       rMissing,
+			expectErr: fs.ErrNotExist,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tgt := tc.tgt
+			if tgt == "" {
+				tgt = strings.ReplaceAll(tc.name, " ", "-")
+			}
+			rTgt, err := ref.New("ocidir://" + tempDir + "/" + tgt)
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			// track blob pulls to verify each blob is only copied once
+			var mu sync.Mutex
+			pulled := map[digest.Digest]int{}
+			hook := ImageWithBlobReaderHook(func(br *blob.BReader) (*blob.BReader, error) {
+				mu.Lock()
+				pulled[br.GetDescriptor().Digest]++
+				mu.Unlock()
+				return br, nil
+			})
+			report, err := rc.RepoCopy(ctx, tc.src, rTgt, append([]RepoCopyOpts{RepoCopyWithImageOpts(hook)}, tc.opts...)...)
+			if len(tc.expectErrs) > 0 {
+				if len(report.Results) != len(tc.expectErrs) {
+					t.Fatalf("unexpected number of results, expected %d, received %d", len(tc.expectErrs), len(report.Results))
+				}
+				for i, res := range report.Results {
+					if res.Tag != tc.expectTags[i] || !errors.Is(res.Err, tc.expectErrs[i]) {
+						t.Errorf("unexpected result for %s, expected %s: %v, received %v", res.Tag, tc.expectTags[i], tc.expectErrs[i], res.Err)
+					}
+				}
+				if err == nil || report.Errors() != len(tc.expectErrs) {
+					t.Errorf("unexpected errors, received %d: %v", report.Errors(), err)
+				}
+				return
+			}
+			if tc.expectErr != nil {
+				if err == nil {
+					t.Fatalf("copy did not fail")
+				}
+				if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to copy repo: %v", err)
+			}
+			if report.Errors() != 0 {
+				t.Errorf("unexpected errors: %v", report.Err())
+			}
+			tags := []string{}
+			for _, res := range report.Results {
+				tags = append(tags, res.Tag)
+			}
+			if !slices.Equal(tags, tc.expectTags) {
+				t.Errorf("unexpected tags, expected %v, received %v", tc.expectTags, tags)
+			}
+			for _, tag := range tc.expectTags {
+				if _, err := rc.ManifestHead(ctx, rTgt.SetTag(tag)); err != nil {
+					t.Errorf("tag %s was not copied: %v", tag, err)
+				}
+			}
+			for dig, count := range pulled {
+				if count > 1 {
+					t.Errorf("blob %s was copied %d times", dig, count)
+				}
+			}
+			if len(pulled) == 0 {
+				t.Errorf("no blobs were copied")
+			}
+		})
+	}
+}
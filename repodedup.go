@@ -0,0 +1,178 @@
+package regclient
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/types/ref"
+)
+
+// RepoDedupRepo is the storage used by a single repository in a [RepoDedupReport].
+type RepoDedupRepo struct {
+	Repo   string // Repo is the name of the repository.
+	Size   int64  // Size of every unique manifest and blob referenced by a tag in the repository.
+	Shared int64  // Shared is the size of the blobs also found in another repository.
+	Err    error  // Err is set when the repository could not be walked.
+}
+
+// RepoDedupBlob is a layer found in multiple repositories.
+type RepoDedupBlob struct {
+	Digest    digest.Digest // Digest of the layer.
+	MediaType string        // MediaType of the layer from the referencing manifest.
+	Size      int64         // Size of the layer.
+	Repos     []string      // Repos containing the layer.
+}
+
+// Savings returns the size of the redundant copies of the layer when each repository stores the layer separately.
+func (b RepoDedupBlob) Savings() int64 {
+	return b.Size * int64(max(len(b.Repos)-1, 0))
+}
+
+// RepoDedupReport contains the blobs shared between repositories from [RegClient.RepoDedup].
+type RepoDedupReport struct {
+	Repos []RepoDedupRepo // Repos in the requested order.
+	Size  int64           // Size is the sum of the size of each repository.
+	Dedup int64           // Dedup is the size of every unique manifest and blob across all repositories.
+	Blobs []RepoDedupBlob // Blobs are the layers found in more than one repository, sorted by the savings.
+}
+
+// Errors returns the number of repositories that could not be walked.
+func (rd RepoDedupReport) Errors() int {
+	count := 0
+	for _, repo := range rd.Repos {
+		if repo.Err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Err returns the joined errors of all repositories that could not be walked, or nil if every repository was included.
+func (rd RepoDedupReport) Err() error {
+	errList := []error{}
+	for _, repo := range rd.Repos {
+		if repo.Err != nil {
+			errList = append(errList, fmt.Errorf("dedup of repository %s failed: %w", repo.Repo, repo.Err))
+		}
+	}
+	return errors.Join(errList...)
+}
+
+type repoDedupOpt struct {
+	blobLimit int
+	parallel  int
+}
+
+// RepoDedupOpts define options for [RegClient.RepoDedup].
+type RepoDedupOpts func(*repoDedupOpt)
+
+// RepoDedupWithBlobLimit sets the number of shared layers included in the report, defaults to 20.
+// A value less than 0 includes every shared layer.
+func RepoDedupWithBlobLimit(n int) RepoDedupOpts {
+	return func(opt *repoDedupOpt) {
+		opt.blobLimit = n
+	}
+}
+
+// RepoDedupWithParallel sets the number of repositories walked concurrently, defaults to 4.
+func RepoDedupWithParallel(n int) RepoDedupOpts {
+	return func(opt *repoDedupOpt) {
+		opt.parallel = n
+	}
+}
+
+// RepoDedup reports the layers shared between repositories, identifying candidates for a common base image.
+// Every tag in each repository is walked as described in [RegClient.RepoUsage].
+// Manifests found in multiple repositories are only pulled once.
+// Repositories that cannot be walked are included in the report with an error, and the returned error is the joined errors of those repositories.
+func (rc *RegClient) RepoDedup(ctx context.Context, repos []ref.Ref, opts ...RepoDedupOpts) (RepoDedupReport, error) {
+	opt := repoDedupOpt{
+		blobLimit: 20,
+		parallel:  4,
+	}
+	for _, optFn := range opts {
+		optFn(&opt)
+	}
+	report := RepoDedupReport{
+		Repos: make([]RepoDedupRepo, len(repos)),
+		Blobs: []RepoDedupBlob{},
+	}
+	walker := newRepoWalker(rc)
+	repoContent := make([]map[digest.Digest]repoWalkDesc, len(repos))
+	ops := make([]BatchOp, len(repos))
+	for i, r := range repos {
+		r = r.SetTag("")
+		report.Repos[i].Repo = r.CommonName()
+		ops[i] = BatchOpFunc(r, func(ctx context.Context) error {
+			defer rc.Close(ctx, r)
+			tags, tagContent, err := walker.walkTags(ctx, r, 1)
+			if err != nil {
+				return err
+			}
+			errList := []error{}
+			content := map[digest.Digest]repoWalkDesc{}
+			for j, tc := range tagContent {
+				if tags[j].Err != nil {
+					errList = append(errList, fmt.Errorf("failed to walk tag %s: %w", tags[j].Tag, tags[j].Err))
+				}
+				for _, wd := range tc {
+					content[wd.desc.Digest] = wd
+				}
+			}
+			repoContent[i] = content
+			return errors.Join(errList...)
+		})
+	}
+	br, _ := rc.Batch(ctx, ops, BatchWithWorkers(opt.parallel))
+	for i, res := range br.Results {
+		report.Repos[i].Err = res.Err
+	}
+
+	// find the repositories containing each digest
+	refs := map[digest.Digest]*RepoDedupBlob{}
+	isLayer := map[digest.Digest]bool{}
+	for i, content := range repoContent {
+		for d, wd := range content {
+			report.Repos[i].Size += wd.desc.Size
+			if _, ok := refs[d]; !ok {
+				refs[d] = &RepoDedupBlob{
+					Digest:    d,
+					MediaType: wd.desc.MediaType,
+					Size:      wd.desc.Size,
+					Repos:     []string{},
+				}
+				report.Dedup += wd.desc.Size
+			}
+			refs[d].Repos = append(refs[d].Repos, report.Repos[i].Repo)
+			if wd.kind == repoWalkLayer {
+				isLayer[d] = true
+			}
+		}
+		report.Size += report.Repos[i].Size
+	}
+	for i, content := range repoContent {
+		for d, wd := range content {
+			if len(refs[d].Repos) > 1 {
+				report.Repos[i].Shared += wd.desc.Size
+			}
+		}
+	}
+	for d := range isLayer {
+		if len(refs[d].Repos) > 1 {
+			slices.Sort(refs[d].Repos)
+			report.Blobs = append(report.Blobs, *refs[d])
+		}
+	}
+	slices.SortFunc(report.Blobs, func(a, b RepoDedupBlob) int {
+		return cmp.Or(cmp.Compare(b.Savings(), a.Savings()), cmp.Compare(a.Digest, b.Digest))
+	})
+	if opt.blobLimit >= 0 && len(report.Blobs) > opt.blobLimit {
+		report.Blobs = report.Blobs[:opt.blobLimit]
+	}
+	return report, report.Err()
+}
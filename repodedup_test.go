@@ -0,0 +1,66 @@
+package regclient
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/regclient/regclient/types/ref"
+)
+
+func TestRepoDedup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := New()
+	tempDir := t.TempDir()
+	rSrc, err := ref.New("ocidir://./testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rCopy, err := ref.New("ocidir://" + tempDir + "/copy")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rMissing, err := ref.New("ocidir://./testdata/missing")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	err = rc.ImageCopy(ctx, rSrc.SetTag("v1"), rCopy.SetTag("v1"))
+	if err != nil {
+		t.Fatalf("failed to copy image: %v", err)
+	}
+	_ = rc.Close(ctx, rCopy)
+
+	report, err := rc.RepoDedup(ctx, []ref.Ref{rSrc, rCopy}, RepoDedupWithBlobLimit(2), RepoDedupWithParallel(2))
+	if err != nil {
+		t.Fatalf("failed to run dedup: %v", err)
+	}
+	if len(report.Repos) != 2 {
+		t.Fatalf("unexpected repo count: %d", len(report.Repos))
+	}
+	copyRepo := report.Repos[1]
+	if copyRepo.Size == 0 || copyRepo.Shared != copyRepo.Size {
+		t.Errorf("copied repository should be fully shared, size %d, shared %d", copyRepo.Size, copyRepo.Shared)
+	}
+	if report.Dedup != report.Repos[0].Size || report.Size != report.Repos[0].Size+copyRepo.Size {
+		t.Errorf("unexpected sizes, total %d, dedup %d, repos %d and %d", report.Size, report.Dedup, report.Repos[0].Size, copyRepo.Size)
+	}
+	if len(report.Blobs) != 2 {
+		t.Fatalf("unexpected shared blob count: %d", len(report.Blobs))
+	}
+	expectRepos := []string{rSrc.CommonName(), rCopy.CommonName()}
+	slices.Sort(expectRepos)
+	for _, b := range report.Blobs {
+		if !slices.Equal(b.Repos, expectRepos) || b.Savings() != b.Size {
+			t.Errorf("unexpected shared blob: %v", b)
+		}
+	}
+	if report.Blobs[0].Savings() < report.Blobs[1].Savings() {
+		t.Errorf("blobs are not sorted by savings")
+	}
+
+	report, err = rc.RepoDedup(ctx, []ref.Ref{rSrc, rMissing})
+	if err == nil || report.Errors() != 1 || report.Repos[1].Err == nil {
+		t.Errorf("missing repository did not fail: %v", err)
+	}
+}
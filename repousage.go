@@ -0,0 +1,286 @@
+package regclient
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/ref"
+)
+
+// RepoUsageTag is the storage used by a single tag in a [RepoUsageReport].
+type RepoUsageTag struct {
+	Tag    string        // Tag is the name of the tag.
+	Digest digest.Digest // Digest of the tagged manifest.
+	Size   int64         // Size of every manifest and blob referenced by the tag.
+	Unique int64         // Unique is the size not shared with any other tag, the storage freed by deleting the tag.
+	Err    error         // Err is set when the tag could not be walked.
+}
+
+// RepoUsageBlob is a blob included in a [RepoUsageReport].
+type RepoUsageBlob struct {
+	Digest    digest.Digest // Digest of the blob.
+	MediaType string        // MediaType of the blob from the referencing manifest.
+	Size      int64         // Size of the blob.
+	Tags      []string      // Tags referencing the blob.
+}
+
+// RepoUsageReport contains the storage used by a repository from [RegClient.RepoUsage].
+// Sizes are deduplicated, content shared between tags is only counted once.
+type RepoUsageReport struct {
+	Repo      string          // Repo is the name of the repository.
+	Size      int64           // Size of every unique manifest and blob referenced by a tag.
+	Manifests int             // Manifests is the count of unique manifests.
+	Blobs     int             // Blobs is the count of unique config and layer blobs.
+	Tags      []RepoUsageTag  // Tags in the order of the tag listing.
+	Layers    []RepoUsageBlob // Layers are the largest layers, sorted by size.
+}
+
+// Errors returns the number of tags that could not be walked.
+func (ru RepoUsageReport) Errors() int {
+	count := 0
+	for _, tag := range ru.Tags {
+		if tag.Err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Err returns the joined errors of all tags that could not be walked, or nil if every tag was included.
+func (ru RepoUsageReport) Err() error {
+	errList := []error{}
+	for _, tag := range ru.Tags {
+		if tag.Err != nil {
+			errList = append(errList, fmt.Errorf("usage of tag %s failed: %w", tag.Tag, tag.Err))
+		}
+	}
+	return errors.Join(errList...)
+}
+
+type repoUsageOpt struct {
+	layerLimit int
+	parallel   int
+}
+
+// RepoUsageOpts define options for [RegClient.RepoUsage].
+type RepoUsageOpts func(*repoUsageOpt)
+
+// RepoUsageWithLayerLimit sets the number of the largest layers included in the report, defaults to 10.
+// A value less than 0 includes every layer.
+func RepoUsageWithLayerLimit(n int) RepoUsageOpts {
+	return func(opt *repoUsageOpt) {
+		opt.layerLimit = n
+	}
+}
+
+// RepoUsageWithParallel sets the number of tags walked concurrently, defaults to 4.
+func RepoUsageWithParallel(n int) RepoUsageOpts {
+	return func(opt *repoUsageOpt) {
+		opt.parallel = n
+	}
+}
+
+// RepoUsage reports the storage used by every tag in a repository.
+// Each tagged manifest is walked, including the child manifests of an index, to sum the size of the manifests, configs, and layers.
+// Untagged manifests and external layers are not included.
+// Tags that cannot be walked are included in the report with an error, and the returned error is the joined errors of those tags.
+// An error is returned without a report when the tags cannot be listed.
+func (rc *RegClient) RepoUsage(ctx context.Context, r ref.Ref, opts ...RepoUsageOpts) (RepoUsageReport, error) {
+	opt := repoUsageOpt{
+		layerLimit: 10,
+		parallel:   4,
+	}
+	for _, optFn := range opts {
+		optFn(&opt)
+	}
+	r = r.SetTag("")
+	if !r.IsSetRepo() {
+		return RepoUsageReport{}, fmt.Errorf("repository must be set: %s%.0w", r.CommonName(), errs.ErrInvalidReference)
+	}
+	usageTags, tagContent, err := newRepoWalker(rc).walkTags(ctx, r, opt.parallel)
+	if err != nil {
+		return RepoUsageReport{}, err
+	}
+	report := RepoUsageReport{
+		Repo:   r.CommonName(),
+		Tags:   usageTags,
+		Layers: []RepoUsageBlob{},
+	}
+
+	// count the tags referencing each digest to find the unique and total sizes
+	refs := map[digest.Digest]*RepoUsageBlob{}
+	isLayer := map[digest.Digest]bool{}
+	for i, content := range tagContent {
+		for _, wd := range content {
+			if _, ok := refs[wd.desc.Digest]; !ok {
+				refs[wd.desc.Digest] = &RepoUsageBlob{
+					Digest:    wd.desc.Digest,
+					MediaType: wd.desc.MediaType,
+					Size:      wd.desc.Size,
+					Tags:      []string{},
+				}
+				report.Size += wd.desc.Size
+				switch wd.kind {
+				case repoWalkManifest:
+					report.Manifests++
+				case repoWalkLayer:
+					isLayer[wd.desc.Digest] = true
+					report.Blobs++
+				default:
+					report.Blobs++
+				}
+			}
+			if !slices.Contains(refs[wd.desc.Digest].Tags, report.Tags[i].Tag) {
+				refs[wd.desc.Digest].Tags = append(refs[wd.desc.Digest].Tags, report.Tags[i].Tag)
+			}
+		}
+	}
+	for i, content := range tagContent {
+		for _, wd := range content {
+			report.Tags[i].Size += wd.desc.Size
+			if len(refs[wd.desc.Digest].Tags) == 1 {
+				report.Tags[i].Unique += wd.desc.Size
+			}
+		}
+	}
+	for d := range isLayer {
+		report.Layers = append(report.Layers, *refs[d])
+	}
+	slices.SortFunc(report.Layers, func(a, b RepoUsageBlob) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), cmp.Compare(a.Digest, b.Digest))
+	})
+	if opt.layerLimit >= 0 && len(report.Layers) > opt.layerLimit {
+		report.Layers = report.Layers[:opt.layerLimit]
+	}
+	return report, report.Err()
+}
+
+type repoWalkKind int
+
+const (
+	repoWalkManifest repoWalkKind = iota
+	repoWalkConfig
+	repoWalkLayer
+)
+
+// repoWalkDesc is a descriptor found walking a manifest.
+type repoWalkDesc struct {
+	desc descriptor.Descriptor
+	kind repoWalkKind
+}
+
+// repoWalker finds the content referenced by manifests, caching the result of each manifest digest.
+type repoWalker struct {
+	rc    *RegClient
+	mu    sync.Mutex
+	cache map[digest.Digest][]repoWalkDesc
+}
+
+func newRepoWalker(rc *RegClient) *repoWalker {
+	return &repoWalker{
+		rc:    rc,
+		cache: map[digest.Digest][]repoWalkDesc{},
+	}
+}
+
+// walkTags lists and walks every tag in a repository.
+// The content of each tag is returned in the order of the tag listing, errors walking a tag are set on the returned tag.
+func (w *repoWalker) walkTags(ctx context.Context, r ref.Ref, parallel int) ([]RepoUsageTag, [][]repoWalkDesc, error) {
+	tl, err := w.rc.TagList(ctx, r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tags in %s: %w", r.CommonName(), err)
+	}
+	tags, err := tl.GetTags()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tags in %s: %w", r.CommonName(), err)
+	}
+	usageTags := make([]RepoUsageTag, len(tags))
+	tagContent := make([][]repoWalkDesc, len(tags))
+	ops := make([]BatchOp, len(tags))
+	for i, tag := range tags {
+		usageTags[i].Tag = tag
+		ops[i] = BatchOpFunc(r.SetTag(tag), func(ctx context.Context) error {
+			m, err := w.rc.ManifestHead(ctx, r.SetTag(tag), WithManifestRequireDigest())
+			if err != nil {
+				return err
+			}
+			usageTags[i].Digest = m.GetDescriptor().Digest
+			tagContent[i], err = w.walk(ctx, r, m.GetDescriptor())
+			return err
+		})
+	}
+	br, _ := w.rc.Batch(ctx, ops, BatchWithWorkers(parallel))
+	for i, res := range br.Results {
+		usageTags[i].Err = res.Err
+	}
+	return usageTags, tagContent, nil
+}
+
+// walk returns the manifest and every child manifest, config, and layer it references, without duplicates.
+func (w *repoWalker) walk(ctx context.Context, r ref.Ref, d descriptor.Descriptor) ([]repoWalkDesc, error) {
+	w.mu.Lock()
+	if content, ok := w.cache[d.Digest]; ok {
+		w.mu.Unlock()
+		return content, nil
+	}
+	w.mu.Unlock()
+	m, err := w.rc.ManifestGet(ctx, r.SetDigest(d.Digest.String()))
+	if err != nil {
+		return nil, err
+	}
+	mDesc := m.GetDescriptor()
+	content := []repoWalkDesc{{desc: mDesc, kind: repoWalkManifest}}
+	seen := map[digest.Digest]bool{mDesc.Digest: true}
+	add := func(wd repoWalkDesc) {
+		if seen[wd.desc.Digest] {
+			return
+		}
+		seen[wd.desc.Digest] = true
+		content = append(content, wd)
+	}
+	if mi, ok := m.(manifest.Indexer); ok {
+		children, err := mi.GetManifestList()
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			childContent, err := w.walk(ctx, r, child)
+			if err != nil {
+				return nil, fmt.Errorf("failed to walk %s: %w", child.Digest.String(), err)
+			}
+			for _, wd := range childContent {
+				add(wd)
+			}
+		}
+	}
+	if mi, ok := m.(manifest.Imager); ok {
+		conf, err := mi.GetConfig()
+		if err == nil && conf.Digest != "" {
+			add(repoWalkDesc{desc: conf, kind: repoWalkConfig})
+		}
+		layers, err := mi.GetLayers()
+		if err != nil {
+			return nil, err
+		}
+		for _, layer := range layers {
+			// external layers are not stored in the repository
+			if len(layer.URLs) > 0 {
+				continue
+			}
+			add(repoWalkDesc{desc: layer, kind: repoWalkLayer})
+		}
+	}
+	w.mu.Lock()
+	w.cache[d.Digest] = content
+	w.mu.Unlock()
+	return content, nil
+}
@@ -0,0 +1,65 @@
+package regclient
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/regclient/regclient/types/ref"
+)
+
+func TestRepoUsage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rc := New()
+	r, err := ref.New("ocidir://./testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	tl, err := rc.TagList(ctx, r)
+	if err != nil {
+		t.Fatalf("failed to list tags: %v", err)
+	}
+	tags, _ := tl.GetTags()
+	report, err := rc.RepoUsage(ctx, r, RepoUsageWithLayerLimit(3), RepoUsageWithParallel(2))
+	if err != nil {
+		t.Fatalf("failed to get usage: %v", err)
+	}
+	if len(report.Tags) != len(tags) {
+		t.Errorf("unexpected tag count, expected %d, received %d", len(tags), len(report.Tags))
+	}
+	if report.Manifests == 0 || report.Blobs == 0 {
+		t.Errorf("content was not counted, manifests %d, blobs %d", report.Manifests, report.Blobs)
+	}
+	sum := int64(0)
+	for _, tag := range report.Tags {
+		if tag.Digest == "" || tag.Size == 0 {
+			t.Errorf("tag %s was not walked", tag.Tag)
+		}
+		if tag.Unique > tag.Size || tag.Size > report.Size {
+			t.Errorf("tag %s has an invalid size %d, unique %d, total %d", tag.Tag, tag.Size, tag.Unique, report.Size)
+		}
+		sum += tag.Size
+	}
+	if report.Size >= sum {
+		t.Errorf("size was not deduplicated, total %d, sum of tags %d", report.Size, sum)
+	}
+	if len(report.Layers) != 3 {
+		t.Fatalf("unexpected layer count: %d", len(report.Layers))
+	}
+	if !slices.IsSortedFunc(report.Layers, func(a, b RepoUsageBlob) int { return int(b.Size - a.Size) }) {
+		t.Errorf("layers are not sorted by size")
+	}
+	if !slices.Contains(report.Layers[0].Tags, "v3") {
+		t.Errorf("largest layer is missing tag v3: %v", report.Layers[0].Tags)
+	}
+
+	rMissing, err := ref.New("ocidir://./testdata/missing")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	_, err = rc.RepoUsage(ctx, rMissing)
+	if err == nil {
+		t.Errorf("usage of a missing repository did not fail")
+	}
+}
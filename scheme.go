@@ -3,10 +3,18 @@ package regclient
 import (
 	"context"
 	"fmt"
+	"net/url"
 
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/internal/pqueue"
+	"github.com/regclient/regclient/internal/reqmeta"
 	"github.com/regclient/regclient/scheme"
+	"github.com/regclient/regclient/types/descriptor"
 	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/manifest"
 	"github.com/regclient/regclient/types/ref"
+	"github.com/regclient/regclient/types/repo"
 )
 
 func (rc *RegClient) schemeGet(scheme string) (scheme.API, error) {
@@ -25,9 +33,99 @@ func (rc *RegClient) Close(ctx context.Context, r ref.Ref) error {
 		return err
 	}
 	// verify Closer api is defined, noop if missing
-	sc, ok := schemeAPI.(scheme.Closer)
+	sc, ok := schemeAs[scheme.Closer](schemeAPI)
 	if !ok {
 		return nil
 	}
 	return sc.Close(ctx, r)
 }
+
+// schemeWrap is embedded by wrappers around a [scheme.API].
+// Optional interfaces are passed through to the underlying scheme.
+// Since the wrapper implements every optional interface, use [schemeAs] to check if the underlying scheme supports it.
+type schemeWrap struct {
+	scheme.API
+}
+
+// schemeUnwrapper is implemented by wrappers around a [scheme.API].
+type schemeUnwrapper interface {
+	unwrap() scheme.API
+}
+
+func (sw schemeWrap) unwrap() scheme.API {
+	return sw.API
+}
+
+// schemeAs returns the scheme as an optional interface.
+// The returned value includes any wrappers, but ok is only true when the underlying scheme implements the interface.
+func schemeAs[T any](api scheme.API) (T, bool) {
+	t, ok := api.(T)
+	for cur := api; ok; {
+		sw, isWrap := cur.(schemeUnwrapper)
+		if !isWrap {
+			break
+		}
+		cur = sw.unwrap()
+		_, ok = cur.(T)
+	}
+	return t, ok
+}
+
+func (sw schemeWrap) RepoList(ctx context.Context, hostname string, opts ...scheme.RepoOpts) (*repo.RepoList, error) {
+	rl, ok := sw.API.(repoLister)
+	if !ok {
+		return nil, errs.ErrNotImplemented
+	}
+	return rl.RepoList(ctx, hostname, opts...)
+}
+
+func (sw schemeWrap) BlobGetURL(ctx context.Context, r ref.Ref, d descriptor.Descriptor) (*url.URL, error) {
+	su, ok := sw.API.(scheme.BlobURLGetter)
+	if !ok {
+		return nil, fmt.Errorf("blob url is not supported by scheme %s%.0w", r.Scheme, errs.ErrUnsupported)
+	}
+	return su.BlobGetURL(ctx, r, d)
+}
+
+func (sw schemeWrap) ManifestGetIfChanged(ctx context.Context, r ref.Ref, d digest.Digest) (manifest.Manifest, error) {
+	mc, ok := sw.API.(scheme.ManifestConditional)
+	if !ok {
+		return nil, fmt.Errorf("conditional requests are not supported by scheme %s%.0w", r.Scheme, errs.ErrUnsupported)
+	}
+	return mc.ManifestGetIfChanged(ctx, r, d)
+}
+
+func (sw schemeWrap) TagDeleteWithOpts(ctx context.Context, r ref.Ref, opts ...scheme.TagDeleteOpts) error {
+	td, ok := sw.API.(scheme.TagDeleter)
+	if !ok {
+		return fmt.Errorf("tag delete options are not supported by scheme %s%.0w", r.Scheme, errs.ErrUnsupported)
+	}
+	return td.TagDeleteWithOpts(ctx, r, opts...)
+}
+
+func (sw schemeWrap) Close(ctx context.Context, r ref.Ref) error {
+	sc, ok := sw.API.(scheme.Closer)
+	if !ok {
+		return nil
+	}
+	return sc.Close(ctx, r)
+}
+
+func (sw schemeWrap) GCLock(r ref.Ref) {
+	if gl, ok := sw.API.(scheme.GCLocker); ok {
+		gl.GCLock(r)
+	}
+}
+
+func (sw schemeWrap) GCUnlock(r ref.Ref) {
+	if gl, ok := sw.API.(scheme.GCLocker); ok {
+		gl.GCUnlock(r)
+	}
+}
+
+func (sw schemeWrap) Throttle(r ref.Ref, put bool) []*pqueue.Queue[reqmeta.Data] {
+	if t, ok := sw.API.(scheme.Throttler); ok {
+		return t.Throttle(r, put)
+	}
+	return nil
+}
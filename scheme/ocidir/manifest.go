@@ -18,6 +18,7 @@ import (
 
 	"github.com/opencontainers/go-digest"
 
+	"github.com/regclient/regclient/internal/limitread"
 	"github.com/regclient/regclient/scheme"
 	"github.com/regclient/regclient/types/errs"
 	"github.com/regclient/regclient/types/manifest"
@@ -128,12 +129,24 @@ func (o *OCIDir) manifestGet(_ context.Context, r ref.Ref) (manifest.Manifest, e
 		return nil, fmt.Errorf("failed to open manifest: %w", err)
 	}
 	defer fd.Close()
-	mb, err := io.ReadAll(fd)
+	var rdr io.Reader = fd
+	if o.manifestMax > 0 {
+		if fi, err := fd.Stat(); err == nil && fi.Size() > o.manifestMax {
+			return nil, fmt.Errorf("manifest too large, received %d, limit %d: %s%.0w", fi.Size(), o.manifestMax, r.CommonName(), errs.ErrSizeLimitExceeded)
+		}
+		rdr = &limitread.LimitRead{
+			Reader: fd,
+			Limit:  o.manifestMax,
+		}
+	}
+	mb, err := io.ReadAll(rdr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read manifest: %w", err)
 	}
 	if desc.Size == 0 {
 		desc.Size = int64(len(mb))
+	} else if desc.Size != int64(len(mb)) {
+		return nil, fmt.Errorf("manifest size mismatch, expected %d, received %d: %s%.0w", desc.Size, len(mb), r.CommonName(), errs.ErrMismatch)
 	}
 	o.slog.Debug("retrieved manifest",
 		slog.String("ref", r.CommonName()),
@@ -225,6 +238,29 @@ func (o *OCIDir) manifestPut(ctx context.Context, r ref.Ref, m manifest.Manifest
 	if err != nil {
 		return err
 	}
+	if config.IfMatch != "" || config.IfNoneMatch {
+		// the index is locked, so the check and update are atomic
+		if r.Tag == "" || r.Digest != "" {
+			return fmt.Errorf("conditional manifest put requires a tag without a digest: %s%.0w", r.CommonName(), errs.ErrMissingTag)
+		}
+		index, err := o.readIndex(r, true)
+		if err != nil {
+			return fmt.Errorf("unable to read oci index: %w", err)
+		}
+		cur, err := indexGet(index, r)
+		if err != nil && !errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		if config.IfNoneMatch && cur.Digest != "" {
+			return fmt.Errorf("tag %s exists with digest %s%.0w", r.CommonName(), cur.Digest.String(), errs.ErrConflict)
+		}
+		if config.IfMatch != "" && cur.Digest != config.IfMatch {
+			if cur.Digest == "" {
+				return fmt.Errorf("tag %s does not exist, expected digest %s%.0w", r.CommonName(), config.IfMatch.String(), errs.ErrConflict)
+			}
+			return fmt.Errorf("tag %s has digest %s, expected digest %s%.0w", r.CommonName(), cur.Digest.String(), config.IfMatch.String(), errs.ErrConflict)
+		}
+	}
 	desc := m.GetDescriptor()
 	if err = desc.Digest.Validate(); err != nil {
 		return fmt.Errorf("invalid digest for manifest: %s: %w", string(desc.Digest), err)
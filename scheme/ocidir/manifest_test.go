@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"os"
 	"path"
@@ -13,6 +14,7 @@ import (
 	"github.com/opencontainers/go-digest"
 
 	"github.com/regclient/regclient/internal/copyfs"
+	"github.com/regclient/regclient/types/errs"
 	"github.com/regclient/regclient/types/manifest"
 	"github.com/regclient/regclient/types/mediatype"
 	v1 "github.com/regclient/regclient/types/oci/v1"
@@ -236,3 +238,38 @@ func TestManifest(t *testing.T) {
 		t.Errorf("could not query manifest after pushing dup tag")
 	}
 }
+
+func TestManifestLimits(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	err := copyfs.Copy(filepath.Join(tempDir, "testrepo"), "../../testdata/testrepo")
+	if err != nil {
+		t.Fatalf("failed to setup tempDir: %v", err)
+	}
+	r, err := ref.New("ocidir://" + tempDir + "/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	_, err = New(WithManifestMax(16)).ManifestGet(ctx, r)
+	if !errors.Is(err, errs.ErrSizeLimitExceeded) {
+		t.Errorf("manifest over the limit did not fail: %v", err)
+	}
+	// modify the size in the index
+	o := New()
+	index, err := o.readIndex(r, true)
+	if err != nil {
+		t.Fatalf("failed to read index: %v", err)
+	}
+	for i := range index.Manifests {
+		index.Manifests[i].Size++
+	}
+	err = o.writeIndex(r, index, true)
+	if err != nil {
+		t.Fatalf("failed to write index: %v", err)
+	}
+	_, err = o.ManifestGet(ctx, r)
+	if !errors.Is(err, errs.ErrMismatch) {
+		t.Errorf("manifest with a size mismatch did not fail: %v", err)
+	}
+}
@@ -28,12 +28,14 @@ const (
 	aOCIRefName     = "org.opencontainers.image.ref.name"
 	aCtrdImageName  = "io.containerd.image.name"
 	defThrottle     = 3
+	defManifestMax  = 1024 * 1024 * 8
 )
 
 // OCIDir is used for accessing OCI Image Layouts defined as a directory
 type OCIDir struct {
 	slog        *slog.Logger
 	gc          bool
+	manifestMax int64
 	modRefs     map[string]*ociGC
 	throttle    map[string]*pqueue.Queue[reqmeta.Data]
 	throttleDef int
@@ -46,9 +48,10 @@ type ociGC struct {
 }
 
 type ociConf struct {
-	gc       bool
-	slog     *slog.Logger
-	throttle int
+	gc          bool
+	manifestMax int64
+	slog        *slog.Logger
+	throttle    int
 }
 
 // Opts are used for passing options to ocidir
@@ -57,9 +60,10 @@ type Opts func(*ociConf)
 // New creates a new OCIDir with options
 func New(opts ...Opts) *OCIDir {
 	conf := ociConf{
-		slog:     slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})),
-		gc:       true,
-		throttle: defThrottle,
+		slog:        slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})),
+		gc:          true,
+		manifestMax: defManifestMax,
+		throttle:    defThrottle,
 	}
 	for _, opt := range opts {
 		opt(&conf)
@@ -67,6 +71,7 @@ func New(opts ...Opts) *OCIDir {
 	return &OCIDir{
 		slog:        conf.slog,
 		gc:          conf.gc,
+		manifestMax: conf.manifestMax,
 		modRefs:     map[string]*ociGC{},
 		throttle:    map[string]*pqueue.Queue[reqmeta.Data]{},
 		throttleDef: conf.throttle,
@@ -81,6 +86,14 @@ func WithGC(gc bool) Opts {
 	}
 }
 
+// WithManifestMax sets the largest manifest that will be read, defaults to 8MiB.
+// A value less than or equal to zero disables the limit.
+func WithManifestMax(pull int64) Opts {
+	return func(c *ociConf) {
+		c.manifestMax = pull
+	}
+}
+
 // WithSlog provides a slog logger.
 // By default logging is disabled.
 func WithSlog(slog *slog.Logger) Opts {
@@ -16,10 +16,11 @@ import (
 
 // Verify OCIDir implements various interfaces.
 var (
-	_ scheme.API       = (*OCIDir)(nil)
-	_ scheme.Closer    = (*OCIDir)(nil)
-	_ scheme.GCLocker  = (*OCIDir)(nil)
-	_ scheme.Throttler = (*OCIDir)(nil)
+	_ scheme.API        = (*OCIDir)(nil)
+	_ scheme.Closer     = (*OCIDir)(nil)
+	_ scheme.GCLocker   = (*OCIDir)(nil)
+	_ scheme.TagDeleter = (*OCIDir)(nil)
+	_ scheme.Throttler  = (*OCIDir)(nil)
 )
 
 func TestIndex(t *testing.T) {
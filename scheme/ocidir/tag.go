@@ -15,11 +15,25 @@ import (
 	"github.com/regclient/regclient/types/tag"
 )
 
-// TagDelete removes a tag from the repository
+// TagDelete removes a tag from the repository.
 func (o *OCIDir) TagDelete(ctx context.Context, r ref.Ref) error {
+	return o.TagDeleteWithOpts(ctx, r)
+}
+
+// TagDeleteWithOpts removes a tag from the repository.
+// Tombstone options are ignored since the tag is always removed from the index directly.
+func (o *OCIDir) TagDeleteWithOpts(ctx context.Context, r ref.Ref, opts ...scheme.TagDeleteOpts) error {
+	var config scheme.TagDeleteConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
 	o.mu.Lock()
 	defer o.mu.Unlock()
-	return o.tagDelete(ctx, r)
+	err := o.tagDelete(ctx, r)
+	if err == nil && config.Method != nil {
+		*config.Method = scheme.TagDeleteDirect
+	}
+	return err
 }
 
 func (o *OCIDir) tagDelete(_ context.Context, r ref.Ref) error {
@@ -43,7 +43,7 @@ func (reg *Reg) BlobDelete(ctx context.Context, r ref.Ref, d descriptor.Descript
 		return fmt.Errorf("failed to delete blob, digest %s, ref %s: %w", d.Digest.String(), r.CommonName(), err)
 	}
 	if resp.HTTPResponse().StatusCode != 202 {
-		return fmt.Errorf("failed to delete blob, digest %s, ref %s: %w", d.Digest.String(), r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return fmt.Errorf("failed to delete blob, digest %s, ref %s: %w", d.Digest.String(), r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 	return nil
 }
@@ -87,7 +87,7 @@ func (reg *Reg) BlobGet(ctx context.Context, r ref.Ref, d descriptor.Descriptor)
 		return nil, fmt.Errorf("failed to get blob, digest %s, ref %s: %w", d.Digest.String(), r.CommonName(), err)
 	}
 	if resp.HTTPResponse().StatusCode != 200 {
-		return nil, fmt.Errorf("failed to get blob, digest %s, ref %s: %w", d.Digest.String(), r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return nil, fmt.Errorf("failed to get blob, digest %s, ref %s: %w", d.Digest.String(), r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 
 	b := blob.NewReader(
@@ -99,6 +99,34 @@ func (reg *Reg) BlobGet(ctx context.Context, r ref.Ref, d descriptor.Descriptor)
 	return b, nil
 }
 
+// BlobGetURL returns the URL the registry redirects to for downloading a blob, without downloading the blob.
+// Registries that serve blobs directly return an error wrapping [errs.ErrUnsupported].
+func (reg *Reg) BlobGetURL(ctx context.Context, r ref.Ref, d descriptor.Descriptor) (*url.URL, error) {
+	req := &reghttp.Req{
+		MetaKind:   reqmeta.Head,
+		Host:       r.Registry,
+		Method:     "GET",
+		Repository: r.Repository,
+		Path:       "blobs/" + d.Digest.String(),
+		NoRedirect: true,
+	}
+	resp, err := reg.reghttp.Do(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob url, digest %s, ref %s: %w", d.Digest.String(), r.CommonName(), err)
+	}
+	// the body is closed without reading to avoid downloading the blob
+	defer resp.Close()
+	httpResp := resp.HTTPResponse()
+	if httpResp.StatusCode < 300 || httpResp.StatusCode >= 400 {
+		return nil, fmt.Errorf("registry did not redirect the blob request, digest %s, ref %s, status %d%.0w", d.Digest.String(), r.CommonName(), httpResp.StatusCode, errs.ErrUnsupported)
+	}
+	u, err := httpResp.Request.URL.Parse(httpResp.Header.Get("Location"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse blob redirect, digest %s, ref %s: %w", d.Digest.String(), r.CommonName(), err)
+	}
+	return u, nil
+}
+
 // BlobHead is used to verify if a blob exists and is accessible
 func (reg *Reg) BlobHead(ctx context.Context, r ref.Ref, d descriptor.Descriptor) (blob.Reader, error) {
 	// build/send request
@@ -137,7 +165,7 @@ func (reg *Reg) BlobHead(ctx context.Context, r ref.Ref, d descriptor.Descriptor
 	}
 	defer resp.Close()
 	if resp.HTTPResponse().StatusCode != 200 {
-		return nil, fmt.Errorf("failed to request blob head, digest %s, ref %s: %w", d.Digest.String(), r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return nil, fmt.Errorf("failed to request blob head, digest %s, ref %s: %w", d.Digest.String(), r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 
 	b := blob.NewReader(
@@ -251,7 +279,7 @@ func (reg *Reg) blobGetUploadURL(ctx context.Context, r ref.Ref, d descriptor.De
 	}
 	defer resp.Close()
 	if resp.HTTPResponse().StatusCode != 202 {
-		return nil, fmt.Errorf("failed to send blob post, ref %s: %w", r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return nil, fmt.Errorf("failed to send blob post, ref %s: %w", r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 
 	// if min size header received, check/adjust host settings
@@ -362,7 +390,7 @@ func (reg *Reg) blobMount(ctx context.Context, rTgt ref.Ref, d descriptor.Descri
 		}
 	}
 	// all other responses unhandled
-	return nil, "", fmt.Errorf("failed to mount blob, digest %s, ref %s: %w", d.Digest.String(), rTgt.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+	return nil, "", fmt.Errorf("failed to mount blob, digest %s, ref %s: %w", d.Digest.String(), rTgt.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 }
 
 func (reg *Reg) blobPutUploadFull(ctx context.Context, r ref.Ref, d descriptor.Descriptor, putURL *url.URL, rdr io.Reader) error {
@@ -417,7 +445,7 @@ func (reg *Reg) blobPutUploadFull(ctx context.Context, r ref.Ref, d descriptor.D
 	defer resp.Close()
 	// 201 follows distribution-spec, 204 is listed as possible in the Docker registry spec
 	if resp.HTTPResponse().StatusCode != 201 && resp.HTTPResponse().StatusCode != 204 {
-		return fmt.Errorf("failed to send blob (put), digest %s, ref %s: %w", d.Digest.String(), r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return fmt.Errorf("failed to send blob (put), digest %s, ref %s: %w", d.Digest.String(), r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 	return nil
 }
@@ -536,7 +564,7 @@ func (reg *Reg) blobPutUploadChunked(ctx context.Context, r ref.Ref, d descripto
 				retryCur++
 				statusResp, statusErr := reg.blobUploadStatus(ctx, r, &chunkURL)
 				if retryCur > retryLimit || statusErr != nil {
-					return d, fmt.Errorf("failed to send blob (chunk), ref %s: http status: %w", r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+					return d, fmt.Errorf("failed to send blob (chunk), ref %s: http status: %w", r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 				}
 				httpResp = statusResp
 			} else {
@@ -604,7 +632,7 @@ func (reg *Reg) blobPutUploadChunked(ctx context.Context, r ref.Ref, d descripto
 	defer resp.Close()
 	// 201 follows distribution-spec, 204 is listed as possible in the Docker registry spec
 	if resp.HTTPResponse().StatusCode != 201 && resp.HTTPResponse().StatusCode != 204 {
-		return d, fmt.Errorf("failed to send blob (chunk digest), digest %s, ref %s: %w", dOut, r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return d, fmt.Errorf("failed to send blob (chunk digest), digest %s, ref %s: %w", dOut, r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 
 	return d, nil
@@ -629,7 +657,7 @@ func (reg *Reg) blobUploadCancel(ctx context.Context, r ref.Ref, putURL *url.URL
 	}
 	defer resp.Close()
 	if resp.HTTPResponse().StatusCode != 202 {
-		return fmt.Errorf("failed to cancel upload %s: %w", r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return fmt.Errorf("failed to cancel upload %s: %w", r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 	return nil
 }
@@ -650,7 +678,7 @@ func (reg *Reg) blobUploadStatus(ctx context.Context, r ref.Ref, putURL *url.URL
 	}
 	defer resp.Close()
 	if resp.HTTPResponse().StatusCode != 204 {
-		return resp.HTTPResponse(), fmt.Errorf("failed to get upload status: %w", reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return resp.HTTPResponse(), fmt.Errorf("failed to get upload status: %w", reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 	return resp.HTTPResponse(), nil
 }
@@ -1562,3 +1562,74 @@ func TestBlobPut(t *testing.T) {
 
 	// TODO: test failed mount (blobGetUploadURL)
 }
+
+func TestBlobGetURL(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	blobBody := []byte("blob url test")
+	d := descriptor.Descriptor{Digest: digest.FromBytes(blobBody), Size: int64(len(blobBody))}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/proj/redirect/blobs/" + d.Digest.String():
+			w.Header().Set("Location", "/storage/blob?X-Amz-Signature=abc")
+			w.WriteHeader(http.StatusTemporaryRedirect)
+		case "/v2/proj/direct/blobs/" + d.Digest.String():
+			_, _ = w.Write(blobBody)
+		case "/storage/blob":
+			t.Errorf("redirect was followed")
+			_, _ = w.Write(blobBody)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	reg := New(
+		WithConfigHosts([]*config.Host{{Name: tsHost, Hostname: tsHost, TLS: config.TLSDisabled}}),
+		WithDelay(time.Millisecond*5, time.Millisecond*10),
+	)
+	tt := []struct {
+		name      string
+		repo      string
+		expectURL string
+		expectErr error
+	}{
+		{
+			name:      "redirect",
+			repo:      "proj/redirect",
+			expectURL: ts.URL + "/storage/blob?X-Amz-Signature=abc",
+		},
+		{
+			name:      "direct",
+			repo:      "proj/direct",
+			expectErr: errs.ErrUnsupported,
+		},
+		{
+			name:      "missing",
+			repo:      "proj/missing",
+			expectErr: errs.ErrNotFound,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := ref.New(tsHost + "/" + tc.repo)
+			if err != nil {
+				t.Fatalf("failed to parse ref: %v", err)
+			}
+			u, err := reg.BlobGetURL(ctx, r, d)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to get blob url: %v", err)
+			}
+			if u.String() != tc.expectURL {
+				t.Errorf("unexpected url, expected %s, received %s", tc.expectURL, u.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package reg
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Faults configures failures injected into registry requests to test retry and verification settings.
+// Each rate is the fraction of requests, from 0 to 1, affected by that fault.
+type Faults struct {
+	ErrorRate    float64       // respond with a 500, 502, or 504 without sending the request
+	TruncateRate float64       // end the response body early
+	CorruptRate  float64       // change a byte in the response body, failing the digest verification
+	SlowRate     float64       // delay the response
+	SlowDelay    time.Duration // delay for slow responses, defaults to 1 second
+	Seed         uint64        // seed for repeatable faults, a random seed is used when 0
+}
+
+// faultErrorCodes are the retryable status codes returned by the ErrorRate.
+var faultErrorCodes = []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout}
+
+// WithFaults injects failures into registry requests for resilience testing.
+// This must never be enabled in production.
+func WithFaults(f Faults) Opts {
+	return func(r *Reg) {
+		r.faults = &f
+	}
+}
+
+type faultTransport struct {
+	f    Faults
+	next http.RoundTripper
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func (f Faults) middleware(log *slog.Logger) func(next http.RoundTripper) http.RoundTripper {
+	if f.SlowDelay <= 0 {
+		f.SlowDelay = time.Second
+	}
+	seed := f.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	if log != nil {
+		log.Warn("Fault injection is enabled, registry requests will fail",
+			slog.Float64("errorRate", f.ErrorRate),
+			slog.Float64("truncateRate", f.TruncateRate),
+			slog.Float64("corruptRate", f.CorruptRate),
+			slog.Float64("slowRate", f.SlowRate),
+			slog.Uint64("seed", seed))
+	}
+	//#nosec G404 faults do not need a secure random source, and a fixed seed makes them repeatable
+	src := rand.New(rand.NewPCG(seed, seed))
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &faultTransport{f: f, next: next, rand: src}
+	}
+}
+
+// hit returns true for the fraction of calls set by rate.
+func (t *faultTransport) hit(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rand.Float64() < rate
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.hit(t.f.SlowRate) {
+		timer := time.NewTimer(t.f.SlowDelay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+	if t.hit(t.f.ErrorRate) {
+		t.mu.Lock()
+		code := faultErrorCodes[t.rand.IntN(len(faultErrorCodes))]
+		t.mu.Unlock()
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		body := `{"errors":[{"code":"UNKNOWN","message":"injected fault"}]}`
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+			StatusCode:    code,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.Body == nil || resp.StatusCode < 200 || resp.StatusCode >= 300 || req.Method == http.MethodHead {
+		return resp, err
+	}
+	// the fault is applied near the middle of the body when the length is known
+	offset := int64(0)
+	if resp.ContentLength > 0 {
+		offset = resp.ContentLength / 2
+	}
+	switch {
+	case t.hit(t.f.TruncateRate):
+		resp.Body = &faultBody{rc: resp.Body, truncate: true, offset: offset}
+	case t.hit(t.f.CorruptRate):
+		resp.Body = &faultBody{rc: resp.Body, offset: offset}
+	}
+	return resp, nil
+}
+
+// faultBody truncates or corrupts the body at the offset.
+type faultBody struct {
+	rc       io.ReadCloser
+	truncate bool
+	offset   int64
+	read     int64
+}
+
+func (b *faultBody) Read(p []byte) (int, error) {
+	if b.truncate {
+		if b.read >= b.offset {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if int64(len(p)) > b.offset-b.read {
+			p = p[:b.offset-b.read]
+		}
+	}
+	n, err := b.rc.Read(p)
+	if !b.truncate && b.offset >= b.read && b.offset < b.read+int64(n) {
+		p[b.offset-b.read] ^= 0xff
+	}
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *faultBody) Close() error {
+	return b.rc.Close()
+}
@@ -0,0 +1,117 @@
+package reg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ref"
+)
+
+func TestFaults(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	blobBody := []byte(strings.Repeat("fault injection test blob\n", 100))
+	blobDigest := digest.FromBytes(blobBody)
+	var blobReqs atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/proj/blobs/"+blobDigest.String() {
+			blobReqs.Add(1)
+			_, _ = w.Write(blobBody)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(ts.Close)
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	r, err := ref.New(tsHost + "/proj")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	d := descriptor.Descriptor{Digest: blobDigest, Size: int64(len(blobBody))}
+
+	tt := []struct {
+		name      string
+		faults    Faults
+		expectErr error
+		expectReq bool
+		minTime   time.Duration
+	}{
+		{
+			name:      "none",
+			faults:    Faults{},
+			expectReq: true,
+		},
+		{
+			name:      "error",
+			faults:    Faults{ErrorRate: 1},
+			expectErr: errs.ErrHTTPStatus,
+		},
+		{
+			name:      "truncate",
+			faults:    Faults{TruncateRate: 1},
+			expectErr: io.ErrUnexpectedEOF,
+			expectReq: true,
+		},
+		{
+			name:      "corrupt",
+			faults:    Faults{CorruptRate: 1},
+			expectErr: errs.ErrDigestMismatch,
+			expectReq: true,
+		},
+		{
+			name:      "slow",
+			faults:    Faults{SlowRate: 1, SlowDelay: time.Millisecond * 50},
+			expectReq: true,
+			minTime:   time.Millisecond * 50,
+		},
+		{
+			name:      "retried error",
+			faults:    Faults{ErrorRate: 0.5, Seed: 2},
+			expectReq: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			reg := New(
+				WithConfigHosts([]*config.Host{{Name: tsHost, Hostname: tsHost, TLS: config.TLSDisabled}}),
+				WithDelay(time.Millisecond*5, time.Millisecond*10),
+				WithRetryLimit(3),
+				WithFaults(tc.faults),
+			)
+			before := blobReqs.Load()
+			start := time.Now()
+			br, err := reg.BlobGet(ctx, r, d)
+			if err == nil {
+				_, err = io.ReadAll(br)
+				_ = br.Close()
+			}
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("unexpected error, expected %v, received %v", tc.expectErr, err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if sent := blobReqs.Load() > before; sent != tc.expectReq {
+				t.Errorf("unexpected request to the registry, expected %t", tc.expectReq)
+			}
+			if tc.minTime > 0 && time.Since(start) < tc.minTime {
+				t.Errorf("request was not delayed, took %s", time.Since(start))
+			}
+		})
+	}
+}
@@ -78,7 +78,7 @@ func (reg *Reg) ManifestDelete(ctx context.Context, r ref.Ref, opts ...scheme.Ma
 	}
 	defer resp.Close()
 	if resp.HTTPResponse().StatusCode != 202 {
-		return fmt.Errorf("failed to delete manifest %s: %w", r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return fmt.Errorf("failed to delete manifest %s: %w", r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 
 	return nil
@@ -86,6 +86,38 @@ func (reg *Reg) ManifestDelete(ctx context.Context, r ref.Ref, opts ...scheme.Ma
 
 // ManifestGet retrieves a manifest from the registry
 func (reg *Reg) ManifestGet(ctx context.Context, r ref.Ref) (manifest.Manifest, error) {
+	return reg.manifestGet(ctx, r, "")
+}
+
+// ManifestGetIfChanged retrieves a manifest with a conditional request,
+// returning an error wrapping [errs.ErrNotModified] when the manifest digest matches d.
+// The If-None-Match header uses the ETag from a previous response for the same digest, or the quoted digest,
+// which registries commonly return as the ETag.
+func (reg *Reg) ManifestGetIfChanged(ctx context.Context, r ref.Ref, d digest.Digest) (manifest.Manifest, error) {
+	if r.Digest != "" || d == "" {
+		m, err := reg.ManifestGet(ctx, r)
+		if err == nil && d != "" && m.GetDescriptor().Digest == d {
+			return nil, fmt.Errorf("manifest %s has not changed%.0w", r.CommonName(), errs.ErrNotModified)
+		}
+		return m, err
+	}
+	etag, err := reg.cacheETag.Get(r.SetDigest(d.String()))
+	if err != nil {
+		etag = `"` + d.String() + `"`
+	}
+	m, err := reg.manifestGet(ctx, r, etag)
+	if err != nil {
+		return nil, err
+	}
+	// registries without conditional request support return the full manifest
+	if m.GetDescriptor().Digest == d {
+		return nil, fmt.Errorf("manifest %s has not changed%.0w", r.CommonName(), errs.ErrNotModified)
+	}
+	return m, nil
+}
+
+// manifestGet retrieves a manifest, sending an If-None-Match header when ifNoneMatch is set.
+func (reg *Reg) manifestGet(ctx context.Context, r ref.Ref, ifNoneMatch string) (manifest.Manifest, error) {
 	var tagOrDigest string
 	if r.Digest != "" {
 		rCache := r.SetDigest(r.Digest)
@@ -111,6 +143,9 @@ func (reg *Reg) ManifestGet(ctx context.Context, r ref.Ref) (manifest.Manifest,
 			mediatype.OCI1Artifact,
 		},
 	}
+	if ifNoneMatch != "" {
+		headers.Set("If-None-Match", ifNoneMatch)
+	}
 	req := &reghttp.Req{
 		MetaKind:   reqmeta.Manifest,
 		Host:       r.Registry,
@@ -124,8 +159,11 @@ func (reg *Reg) ManifestGet(ctx context.Context, r ref.Ref) (manifest.Manifest,
 		return nil, fmt.Errorf("failed to get manifest %s: %w", r.CommonName(), err)
 	}
 	defer resp.Close()
+	if ifNoneMatch != "" && resp.HTTPResponse().StatusCode == http.StatusNotModified {
+		return nil, fmt.Errorf("manifest %s has not changed%.0w", r.CommonName(), errs.ErrNotModified)
+	}
 	if resp.HTTPResponse().StatusCode != 200 {
-		return nil, fmt.Errorf("failed to get manifest %s: %w", r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return nil, fmt.Errorf("failed to get manifest %s: %w", r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 
 	// limit length
@@ -133,9 +171,12 @@ func (reg *Reg) ManifestGet(ctx context.Context, r ref.Ref) (manifest.Manifest,
 	if size > 0 && reg.manifestMaxPull > 0 && int64(size) > reg.manifestMaxPull {
 		return nil, fmt.Errorf("manifest too large, received %d, limit %d: %s%.0w", size, reg.manifestMaxPull, r.CommonName(), errs.ErrSizeLimitExceeded)
 	}
-	rdr := &limitread.LimitRead{
-		Reader: resp,
-		Limit:  reg.manifestMaxPull,
+	var rdr io.Reader = resp
+	if reg.manifestMaxPull > 0 {
+		rdr = &limitread.LimitRead{
+			Reader: resp,
+			Limit:  reg.manifestMaxPull,
+		}
 	}
 
 	// read manifest
@@ -154,9 +195,24 @@ func (reg *Reg) ManifestGet(ctx context.Context, r ref.Ref) (manifest.Manifest,
 	}
 	rCache := r.SetDigest(m.GetDescriptor().Digest.String())
 	reg.cacheMan.Set(rCache, m)
+	reg.etagSet(r, m)
 	return m, nil
 }
 
+// etagSet saves the ETag of a manifest pulled by tag for later conditional requests.
+func (reg *Reg) etagSet(r ref.Ref, m manifest.Manifest) {
+	if r.Digest != "" || m.GetDescriptor().Digest == "" {
+		return
+	}
+	header, err := m.RawHeaders()
+	if err != nil {
+		return
+	}
+	if etag := header.Get("ETag"); etag != "" {
+		reg.cacheETag.Set(r.SetDigest(m.GetDescriptor().Digest.String()), etag)
+	}
+}
+
 // ManifestHead returns metadata on the manifest from the registry
 func (reg *Reg) ManifestHead(ctx context.Context, r ref.Ref) (manifest.Manifest, error) {
 	// build the request
@@ -199,13 +255,18 @@ func (reg *Reg) ManifestHead(ctx context.Context, r ref.Ref) (manifest.Manifest,
 	}
 	defer resp.Close()
 	if resp.HTTPResponse().StatusCode != 200 {
-		return nil, fmt.Errorf("failed to request manifest head %s: %w", r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return nil, fmt.Errorf("failed to request manifest head %s: %w", r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 
-	return manifest.New(
+	m, err := manifest.New(
 		manifest.WithRef(r),
 		manifest.WithHeader(resp.HTTPResponse().Header),
 	)
+	if err != nil {
+		return nil, err
+	}
+	reg.etagSet(r, m)
+	return m, nil
 }
 
 // ManifestPut uploads a manifest to a registry
@@ -220,6 +281,10 @@ func (reg *Reg) ManifestPut(ctx context.Context, r ref.Ref, m manifest.Manifest,
 			slog.String("ref", r.Reference))
 		return errs.ErrMissingTag
 	}
+	config := scheme.ManifestConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
 	// dedup warnings
 	if w := warning.FromContext(ctx); w == nil {
 		ctx = warning.NewContext(ctx, &warning.Warning{Hook: warning.DefaultHook()})
@@ -255,6 +320,24 @@ func (reg *Reg) ManifestPut(ctx context.Context, r ref.Ref, m manifest.Manifest,
 	headers := http.Header{
 		"Content-Type": []string{manifest.GetMediaType(m)},
 	}
+	if r.Tag != "" && r.Digest == "" && !config.Force {
+		err = reg.manifestPutImmutable(ctx, r, m.GetDescriptor().Digest)
+		if err != nil {
+			return err
+		}
+	}
+	if config.IfMatch != "" || config.IfNoneMatch {
+		// verify the current tag before the push, for registries that ignore conditional headers
+		err = reg.manifestPutCheck(ctx, r, config)
+		if err != nil {
+			return err
+		}
+		if config.IfMatch != "" {
+			headers.Set("If-Match", `"`+config.IfMatch.String()+`"`)
+		} else {
+			headers.Set("If-None-Match", "*")
+		}
+	}
 	q := url.Values{}
 	if tagOrDigest == r.Tag && m.GetDescriptor().Digest.Algorithm() != digest.Canonical {
 		// TODO(bmitch): EXPERIMENTAL support for pushing tags with a digest: <https://github.com/opencontainers/distribution-spec/pull/600>
@@ -283,7 +366,7 @@ func (reg *Reg) ManifestPut(ctx context.Context, r ref.Ref, m manifest.Manifest,
 		return fmt.Errorf("failed to close request: %w", err)
 	}
 	if resp.HTTPResponse().StatusCode != 201 {
-		return fmt.Errorf("failed to put manifest %s: %w", r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return fmt.Errorf("failed to put manifest %s: %w", r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 	// if Docker-Content-Digest header was returned, verify the digest matches
 	if dig := resp.HTTPResponse().Header.Get("Docker-Content-Digest"); dig != "" && dig != m.GetDescriptor().Digest.String() {
@@ -327,7 +410,7 @@ func (reg *Reg) ManifestPut(ctx context.Context, r ref.Ref, m manifest.Manifest,
 			return fmt.Errorf("failed to close request: %w", err)
 		}
 		if resp.HTTPResponse().StatusCode != 201 {
-			return fmt.Errorf("failed to put manifest %s: %w", r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+			return fmt.Errorf("failed to put manifest %s: %w", r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 		}
 		// if Docker-Content-Digest header was returned, verify the digest matches
 		if dig := resp.HTTPResponse().Header.Get("Docker-Content-Digest"); dig != "" && dig != m.GetDescriptor().Digest.String() {
@@ -358,3 +441,60 @@ func (reg *Reg) ManifestPut(ctx context.Context, r ref.Ref, m manifest.Manifest,
 
 	return nil
 }
+
+// manifestPutCheck verifies the current digest of a tag matches the conditions of a push.
+func (reg *Reg) manifestPutCheck(ctx context.Context, r ref.Ref, config scheme.ManifestConfig) error {
+	if r.Tag == "" || r.Digest != "" {
+		return fmt.Errorf("conditional manifest put requires a tag without a digest: %s%.0w", r.CommonName(), errs.ErrMissingTag)
+	}
+	cur, err := reg.manifestTagDigest(ctx, r)
+	if err != nil {
+		return err
+	}
+	if config.IfNoneMatch && cur != "" {
+		return fmt.Errorf("tag %s exists with digest %s%.0w", r.CommonName(), cur.String(), errs.ErrConflict)
+	}
+	if config.IfMatch != "" && cur != config.IfMatch {
+		if cur == "" {
+			return fmt.Errorf("tag %s does not exist, expected digest %s%.0w", r.CommonName(), config.IfMatch.String(), errs.ErrConflict)
+		}
+		return fmt.Errorf("tag %s has digest %s, expected digest %s%.0w", r.CommonName(), cur.String(), config.IfMatch.String(), errs.ErrConflict)
+	}
+	return nil
+}
+
+// manifestPutImmutable verifies a push does not replace a tag matching the host immutable tag patterns.
+// Pushing the same digest or creating a new tag is allowed.
+func (reg *Reg) manifestPutImmutable(ctx context.Context, r ref.Ref, d digest.Digest) error {
+	immutable, err := reg.hostGet(r.Registry).IsImmutableTag(r.Repository, r.Tag)
+	if err != nil {
+		return fmt.Errorf("failed to check immutable tags for %s: %w", r.CommonName(), err)
+	}
+	if !immutable {
+		return nil
+	}
+	cur, err := reg.manifestTagDigest(ctx, r)
+	if err != nil {
+		return err
+	}
+	if cur != "" && cur != d {
+		return fmt.Errorf("tag %s is immutable with digest %s, refusing to replace with %s%.0w", r.CommonName(), cur.String(), d.String(), errs.ErrTagImmutable)
+	}
+	return nil
+}
+
+// manifestTagDigest returns the digest of the tag, or an empty digest when the tag is not found.
+func (reg *Reg) manifestTagDigest(ctx context.Context, r ref.Ref) (digest.Digest, error) {
+	m, err := reg.ManifestHead(ctx, r)
+	if err == nil && m.GetDescriptor().Digest == "" {
+		// some registries do not return the digest on a head request
+		m, err = reg.ManifestGet(ctx, r)
+	}
+	if errors.Is(err, errs.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check current manifest %s: %w", r.CommonName(), err)
+	}
+	return m.GetDescriptor().Digest, nil
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/regclient/regclient/config"
 	"github.com/regclient/regclient/internal/reqresp"
+	"github.com/regclient/regclient/scheme"
 	"github.com/regclient/regclient/types/descriptor"
 	"github.com/regclient/regclient/types/docker/schema2"
 	"github.com/regclient/regclient/types/errs"
@@ -41,6 +42,9 @@ func TestManifest(t *testing.T) {
 	missingTag := "missing"
 	putTag256 := "put256"
 	putTag512 := "put512"
+	casTag := "cas"
+	etagTag := "etag"
+	etag := `W/"etag-value"`
 	digest1 := digest.FromString("example1")
 	digest2 := digest.FromString("example2")
 	m := schema2.Manifest{
@@ -69,6 +73,39 @@ func TestManifest(t *testing.T) {
 	mLen := len(mBody)
 	ctx := context.Background()
 	rrs := []reqresp.ReqResp{
+		{
+			ReqEntry: reqresp.ReqEntry{
+				Name:   "Get etag not modified",
+				Method: "GET",
+				Path:   "/v2" + repoPath + "/manifests/" + etagTag,
+				Headers: http.Header{
+					"If-None-Match": []string{etag},
+				},
+			},
+			RespEntry: reqresp.RespEntry{
+				Status: http.StatusNotModified,
+				Headers: http.Header{
+					"ETag": []string{etag},
+				},
+			},
+		},
+		{
+			ReqEntry: reqresp.ReqEntry{
+				Name:   "Get etag",
+				Method: "GET",
+				Path:   "/v2" + repoPath + "/manifests/" + etagTag,
+			},
+			RespEntry: reqresp.RespEntry{
+				Status: http.StatusOK,
+				Headers: http.Header{
+					"Content-Length":        {fmt.Sprintf("%d", mLen)},
+					"Content-Type":          []string{mediatype.Docker2Manifest},
+					"Docker-Content-Digest": []string{mDigest256.String()},
+					"ETag":                  []string{etag},
+				},
+				Body: mBody,
+			},
+		},
 		{
 			ReqEntry: reqresp.ReqEntry{
 				Name:   "Get tag",
@@ -236,6 +273,36 @@ func TestManifest(t *testing.T) {
 				Status: http.StatusNotFound,
 			},
 		},
+		{
+			ReqEntry: reqresp.ReqEntry{
+				Name:   "Head cas tag",
+				Method: "HEAD",
+				Path:   "/v2" + repoPath + "/manifests/" + casTag,
+			},
+			RespEntry: reqresp.RespEntry{
+				Status: http.StatusOK,
+				Headers: http.Header{
+					"Content-Length":        {fmt.Sprintf("%d", mLen)},
+					"Content-Type":          []string{mediatype.Docker2Manifest},
+					"Docker-Content-Digest": []string{digest1.String()},
+				},
+			},
+		},
+		{
+			ReqEntry: reqresp.ReqEntry{
+				Name:   "Put cas tag modified",
+				Method: "PUT",
+				Path:   "/v2" + repoPath + "/manifests/" + casTag,
+				Headers: http.Header{
+					"Content-Type": []string{mediatype.Docker2Manifest},
+					"If-Match":     []string{`"` + digest1.String() + `"`},
+				},
+				Body: mBody,
+			},
+			RespEntry: reqresp.RespEntry{
+				Status: http.StatusPreconditionFailed,
+			},
+		},
 		{
 			ReqEntry: reqresp.ReqEntry{
 				Name:   "Put tag 256",
@@ -574,6 +641,46 @@ func TestManifest(t *testing.T) {
 			t.Fatalf("Failed running ManifestHead (cache): %v", err)
 		}
 	})
+	t.Run("Get If Changed", func(t *testing.T) {
+		regETag := New(
+			WithConfigHosts(rcHosts),
+			WithSlog(log),
+			WithDelay(delayInit, delayMax),
+		)
+		etagRef, err := ref.New(tsURL.Host + repoPath + ":" + etagTag)
+		if err != nil {
+			t.Fatalf("Failed creating ref: %v", err)
+		}
+		// the first request does not have a cached etag and the registry ignores the quoted digest
+		_, err = regETag.ManifestGetIfChanged(ctx, etagRef, mDigest256)
+		if !errors.Is(err, errs.ErrNotModified) {
+			t.Fatalf("unexpected error, expected %v, received %v", errs.ErrNotModified, err)
+		}
+		cached, err := regETag.cacheETag.Get(etagRef.SetDigest(mDigest256.String()))
+		if err != nil || cached != etag {
+			t.Fatalf("etag not cached, expected %s, received %s, %v", etag, cached, err)
+		}
+		// the second request sends the cached etag and receives a 304
+		_, err = regETag.ManifestGetIfChanged(ctx, etagRef, mDigest256)
+		if !errors.Is(err, errs.ErrNotModified) {
+			t.Fatalf("unexpected error, expected %v, received %v", errs.ErrNotModified, err)
+		}
+		mGet, err := regETag.ManifestGetIfChanged(ctx, etagRef, digest1)
+		if err != nil {
+			t.Fatalf("Failed running ManifestGetIfChanged: %v", err)
+		}
+		if mGet.GetDescriptor().Digest != mDigest256 {
+			t.Errorf("Unexpected digest: %s", mGet.GetDescriptor().Digest.String())
+		}
+		_, err = regETag.ManifestGetIfChanged(ctx, etagRef.SetDigest(mDigest256.String()), mDigest256)
+		if !errors.Is(err, errs.ErrNotModified) {
+			t.Fatalf("unexpected error for digest ref, expected %v, received %v", errs.ErrNotModified, err)
+		}
+		mGet, err = regETag.ManifestGetIfChanged(ctx, etagRef, "")
+		if err != nil || mGet.GetDescriptor().Digest != mDigest256 {
+			t.Errorf("Failed running ManifestGetIfChanged without a digest: %v", err)
+		}
+	})
 	// TODO: get manifest that is larger than Content-Length header
 	t.Run("Size Limit", func(t *testing.T) {
 		bigRef, err := ref.New(tsURL.Host + repoPath + ":" + bigTag)
@@ -703,6 +810,36 @@ func TestManifest(t *testing.T) {
 		}
 	})
 
+	t.Run("PUT if match", func(t *testing.T) {
+		putRef, err := ref.New(tsURL.Host + repoPath + ":" + casTag)
+		if err != nil {
+			t.Fatalf("failed creating ref: %v", err)
+		}
+		mm, err := manifest.New(manifest.WithRaw(mBody))
+		if err != nil {
+			t.Fatalf("failed to create manifest: %v", err)
+		}
+		// tag does not match the expected digest before the put
+		err = reg.ManifestPut(ctx, putRef, mm, scheme.WithManifestIfMatch(digest2))
+		if !errors.Is(err, errs.ErrConflict) {
+			t.Errorf("unexpected error, expected %v, received %v", errs.ErrConflict, err)
+		}
+		err = reg.ManifestPut(ctx, putRef, mm, scheme.WithManifestIfNoneMatch())
+		if !errors.Is(err, errs.ErrConflict) {
+			t.Errorf("unexpected error, expected %v, received %v", errs.ErrConflict, err)
+		}
+		// registry rejects the conditional request
+		err = reg.ManifestPut(ctx, putRef, mm, scheme.WithManifestIfMatch(digest1))
+		if !errors.Is(err, errs.ErrConflict) {
+			t.Errorf("unexpected error, expected %v, received %v", errs.ErrConflict, err)
+		}
+		// the tag is required
+		err = reg.ManifestPut(ctx, putRef.SetDigest(mDigest256.String()), mm, scheme.WithManifestIfMatch(digest1))
+		if !errors.Is(err, errs.ErrMissingTag) {
+			t.Errorf("unexpected error, expected %v, received %v", errs.ErrMissingTag, err)
+		}
+	})
+
 	t.Run("PUT size limit", func(t *testing.T) {
 		putRef, err := ref.New(tsURL.Host + repoPath + ":" + putTag256)
 		if err != nil {
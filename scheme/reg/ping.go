@@ -3,6 +3,7 @@ package reg
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/regclient/regclient/internal/reghttp"
 	"github.com/regclient/regclient/internal/reqmeta"
@@ -32,8 +33,35 @@ func (reg *Reg) Ping(ctx context.Context, r ref.Ref) (ping.Result, error) {
 
 	if resp.HTTPResponse().StatusCode != 200 {
 		return ret, fmt.Errorf("failed to ping registry %s: %w",
-			r.Registry, reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+			r.Registry, reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 
 	return ret, nil
 }
+
+// PingAuth queries the /v2/ API of the registry and returns details of the auth used.
+// When r includes a repository, a token is requested for the actions on that repository.
+// Registries may grant a subset of the requested actions, see [ping.Auth.Allowed].
+func (reg *Reg) PingAuth(ctx context.Context, r ref.Ref, actions ...string) (ping.Auth, error) {
+	req := &reghttp.Req{
+		MetaKind:  reqmeta.Query,
+		Host:      r.Registry,
+		NoMirrors: true,
+		Method:    "GET",
+		Path:      "",
+	}
+	if r.Repository != "" && len(actions) > 0 {
+		req.Scopes = []string{"repository:" + r.Repository + ":" + strings.Join(actions, ",")}
+	}
+	resp, err := reg.reghttp.Do(ctx, req)
+	if err != nil {
+		return ping.Auth{}, fmt.Errorf("failed to ping registry %s: %w", r.Registry, err)
+	}
+	defer resp.Close()
+	if resp.HTTPResponse().StatusCode != 200 {
+		return ping.Auth{}, fmt.Errorf("failed to ping registry %s: %w",
+			r.Registry, reghttp.HTTPErrorResp(resp.HTTPResponse()))
+	}
+	info, _ := reg.reghttp.AuthInfo(r.Registry, "")
+	return info, nil
+}
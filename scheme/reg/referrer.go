@@ -145,7 +145,7 @@ func (reg *Reg) referrerListByAPIPage(ctx context.Context, r ref.Ref, config sch
 	}
 	defer resp.Close()
 	if resp.HTTPResponse().StatusCode != 200 {
-		return rl, nil, fmt.Errorf("failed to get referrers %s: %w", r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return rl, nil, fmt.Errorf("failed to get referrers %s: %w", r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 
 	// read manifest
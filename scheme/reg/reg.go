@@ -30,6 +30,9 @@ const (
 	defaultManifestMaxPull = 1024 * 1024 * 8
 	// defaultManifestMaxPush limits the largest manifest that will be pushed
 	defaultManifestMaxPush = 1024 * 1024 * 4
+	// etagCacheAge and etagCacheCount limit the ETags saved for conditional manifest requests
+	etagCacheAge   = time.Hour
+	etagCacheCount = 1000
 	// paramBlobDigestAlgo specifies the query parameter to request a specific digest algorithm.
 	// TODO(bmitch): EXPERIMENTAL field, registry support and OCI spec update needed
 	paramBlobDigestAlgo = "digest-algorithm"
@@ -53,6 +56,8 @@ type Reg struct {
 	manifestMaxPush int64
 	cacheMan        *cache.Cache[ref.Ref, manifest.Manifest]
 	cacheRL         *cache.Cache[ref.Ref, referrer.ReferrerList]
+	cacheETag       *cache.Cache[ref.Ref, string] // ETag of each manifest digest in a repository
+	faults          *Faults                       // failures injected for resilience testing
 	muHost          sync.Mutex
 	muRefTag        sync.Mutex
 }
@@ -84,14 +89,28 @@ func New(opts ...Opts) *Reg {
 		hosts:           map[string]*config.Host{},
 		features:        map[featureKey]*featureVal{},
 	}
+	ce := cache.New[ref.Ref, string](cache.WithAge(etagCacheAge), cache.WithCount(etagCacheCount))
+	r.cacheETag = &ce
 	r.reghttpOpts = append(r.reghttpOpts, reghttp.WithConfigHostFn(r.hostGet))
 	for _, opt := range opts {
 		opt(&r)
 	}
+	if r.faults != nil {
+		// faults are the innermost middleware to be seen by any user provided middleware
+		r.reghttpOpts = append(r.reghttpOpts, reghttp.WithMiddleware(r.faults.middleware(r.slog)))
+	}
 	r.reghttp = reghttp.NewClient(r.reghttpOpts...)
 	return &r
 }
 
+// HostStats are statistics on delayed requests to a registry host.
+type HostStats = reghttp.HostStats
+
+// HostStats returns statistics on requests to a registry host that were delayed by backoffs or a Retry-After header.
+func (reg *Reg) HostStats(host string) HostStats {
+	return reg.reghttp.HostStats(host)
+}
+
 // Throttle is used to limit concurrency
 func (reg *Reg) Throttle(r ref.Ref, put bool) []*pqueue.Queue[reqmeta.Data] {
 	tList := []*pqueue.Queue[reqmeta.Data]{}
@@ -234,6 +253,17 @@ func WithHTTPClient(hc *http.Client) Opts {
 	}
 }
 
+// WithHTTPMiddleware wraps the http transport for each host with the provided middleware.
+// This can be used to sign requests, inject headers, or trace requests.
+// The first middleware in the list is the outermost wrapper.
+func WithHTTPMiddleware(mw ...func(next http.RoundTripper) http.RoundTripper) Opts {
+	return func(r *Reg) {
+		for _, m := range mw {
+			r.reghttpOpts = append(r.reghttpOpts, reghttp.WithMiddleware(m))
+		}
+	}
+}
+
 // WithManifestMax sets the push and pull limits for manifests
 func WithManifestMax(push, pull int64) Opts {
 	return func(r *Reg) {
@@ -242,6 +272,14 @@ func WithManifestMax(push, pull int64) Opts {
 	}
 }
 
+// WithManifestMaxPull sets the pull limit for manifests without changing the push limit.
+// A value less than or equal to zero disables the limit.
+func WithManifestMaxPull(pull int64) Opts {
+	return func(r *Reg) {
+		r.manifestMaxPull = pull
+	}
+}
+
 // WithRetryLimit restricts the number of retries (defaults to 5)
 func WithRetryLimit(l int) Opts {
 	return func(r *Reg) {
@@ -249,6 +287,15 @@ func WithRetryLimit(l int) Opts {
 	}
 }
 
+// WithRetryAfterMax queues requests receiving a 429 or 503 response for up to the max duration.
+// Queued requests wait for the Retry-After header and are not counted against the retry limit.
+// See [Reg.HostStats] for statistics on queued requests.
+func WithRetryAfterMax(max time.Duration) Opts {
+	return func(r *Reg) {
+		r.reghttpOpts = append(r.reghttpOpts, reghttp.WithRetryAfterMax(max))
+	}
+}
+
 // WithSlog injects a slog Logger configuration
 func WithSlog(slog *slog.Logger) Opts {
 	return func(r *Reg) {
@@ -4,8 +4,9 @@ import "github.com/regclient/regclient/scheme"
 
 // Verify Reg implements various interfaces.
 var (
-	_ scheme.API       = (*Reg)(nil)
-	_ scheme.Throttler = (*Reg)(nil)
+	_ scheme.API        = (*Reg)(nil)
+	_ scheme.TagDeleter = (*Reg)(nil)
+	_ scheme.Throttler  = (*Reg)(nil)
 )
 
 func stringSliceCmp(a, b []string) bool {
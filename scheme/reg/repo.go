@@ -51,7 +51,7 @@ func (reg *Reg) RepoList(ctx context.Context, hostname string, opts ...scheme.Re
 	}
 	defer resp.Close()
 	if resp.HTTPResponse().StatusCode != 200 {
-		return nil, fmt.Errorf("failed to list repositories for %s: %w", hostname, reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return nil, fmt.Errorf("failed to list repositories for %s: %w", hostname, reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 
 	respBody, err := io.ReadAll(resp)
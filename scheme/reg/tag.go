@@ -37,12 +37,36 @@ import (
 
 // TagDelete removes a tag from a repository.
 // It first attempts the newer OCI API to delete by tag name (not widely supported).
-// If the OCI API fails, it falls back to pushing a unique empty manifest and deleting that.
+// If the OCI API fails, it falls back to pushing a unique tombstone manifest and deleting that.
 func (reg *Reg) TagDelete(ctx context.Context, r ref.Ref) error {
+	return reg.TagDeleteWithOpts(ctx, r)
+}
+
+// TagDeleteWithOpts removes a tag from a repository, see [Reg.TagDelete].
+// Options may customize or disable the tombstone fallback.
+func (reg *Reg) TagDeleteWithOpts(ctx context.Context, r ref.Ref, opts ...scheme.TagDeleteOpts) error {
+	var config scheme.TagDeleteConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
 	var tempManifest manifest.Manifest
 	if r.Tag == "" {
 		return errs.ErrMissingTag
 	}
+	if !config.Force {
+		immutable, err := reg.hostGet(r.Registry).IsImmutableTag(r.Repository, r.Tag)
+		if err != nil {
+			return fmt.Errorf("failed to check immutable tags for %s: %w", r.CommonName(), err)
+		}
+		if immutable {
+			return fmt.Errorf("tag %s is immutable, refusing to delete%.0w", r.CommonName(), errs.ErrTagImmutable)
+		}
+	}
+	switch config.TombstoneMediaType {
+	case "", mediatype.OCI1Manifest, mediatype.Docker2Manifest:
+	default:
+		return fmt.Errorf("unsupported tombstone media type %s%.0w", config.TombstoneMediaType, errs.ErrUnsupportedMediaType)
+	}
 	// dedup warnings
 	if w := warning.FromContext(ctx); w == nil {
 		ctx = warning.NewContext(ctx, &warning.Warning{Hook: warning.DefaultHook()})
@@ -64,9 +88,18 @@ func (reg *Reg) TagDelete(ctx context.Context, r ref.Ref) error {
 		defer resp.Close()
 	}
 	if err == nil && resp != nil && resp.HTTPResponse().StatusCode == 202 {
+		if config.Method != nil {
+			*config.Method = scheme.TagDeleteDirect
+		}
 		return nil
 	}
-	// ignore errors, fallback to creating a temporary manifest to replace the tag and deleting that manifest
+	if config.NoFallback {
+		if err == nil && resp != nil {
+			err = fmt.Errorf("unexpected status %d", resp.HTTPResponse().StatusCode)
+		}
+		return fmt.Errorf("tag delete API failed for %s, tombstone fallback disabled: %w%.0w", r.CommonName(), err, errs.ErrUnsupportedAPI)
+	}
+	// ignore errors, fallback to creating a tombstone manifest to replace the tag and deleting that manifest
 
 	// lookup the current manifest media type
 	curManifest, err := reg.ManifestHead(ctx, r)
@@ -118,8 +151,12 @@ func (reg *Reg) TagDelete(ctx context.Context, r ref.Ref) error {
 	}
 	confDigest := digester.Digest()
 
-	// create manifest with config, matching the original tag manifest type
-	switch manifest.GetMediaType(curManifest) {
+	// create manifest with config, matching the original tag manifest type unless overridden
+	mt := manifest.GetMediaType(curManifest)
+	if config.TombstoneMediaType != "" {
+		mt = config.TombstoneMediaType
+	}
+	switch mt {
 	case mediatype.OCI1Manifest, mediatype.OCI1ManifestList:
 		tempManifest, err = manifest.New(manifest.WithOrig(v1.Manifest{
 			Versioned: v1.ManifestSchemaVersion,
@@ -136,6 +173,7 @@ func (reg *Reg) TagDelete(ctx context.Context, r ref.Ref) error {
 					Digest:    descriptor.EmptyDigest,
 				},
 			},
+			Annotations: config.TombstoneAnnotations,
 		}))
 		if err != nil {
 			return err
@@ -155,12 +193,13 @@ func (reg *Reg) TagDelete(ctx context.Context, r ref.Ref) error {
 					Digest:    descriptor.EmptyDigest,
 				},
 			},
+			Annotations: config.TombstoneAnnotations,
 		}))
 		if err != nil {
 			return err
 		}
 	}
-	reg.slog.Debug("Sending dummy manifest to replace tag",
+	reg.slog.Debug("Sending tombstone manifest to replace tag",
 		slog.String("ref", r.Reference))
 
 	// push empty layer
@@ -172,23 +211,26 @@ func (reg *Reg) TagDelete(ctx context.Context, r ref.Ref) error {
 	// push config
 	_, err = reg.BlobPut(ctx, r, descriptor.Descriptor{Digest: confDigest, Size: int64(len(confB))}, bytes.NewReader(confB))
 	if err != nil {
-		return fmt.Errorf("failed sending dummy config to delete %s: %w", r.CommonName(), err)
+		return fmt.Errorf("failed sending tombstone config to delete %s: %w", r.CommonName(), err)
 	}
 
 	// push manifest to tag
-	err = reg.ManifestPut(ctx, r, tempManifest)
+	err = reg.ManifestPut(ctx, r, tempManifest, scheme.WithManifestForce())
 	if err != nil {
-		return fmt.Errorf("failed sending dummy manifest to delete %s: %w", r.CommonName(), err)
+		return fmt.Errorf("failed sending tombstone manifest to delete %s: %w", r.CommonName(), err)
 	}
 
 	// delete manifest by digest
 	r = r.AddDigest(tempManifest.GetDescriptor().Digest.String())
-	reg.slog.Debug("Deleting dummy manifest",
+	reg.slog.Debug("Deleting tombstone manifest",
 		slog.String("ref", r.Reference),
 		slog.String("digest", r.Digest))
 	err = reg.ManifestDelete(ctx, r)
 	if err != nil {
-		return fmt.Errorf("failed deleting dummy manifest for %s: %w", r.CommonName(), err)
+		return fmt.Errorf("failed deleting tombstone manifest for %s: %w", r.CommonName(), err)
+	}
+	if config.Method != nil {
+		*config.Method = scheme.TagDeleteTombstone
 	}
 
 	return nil
@@ -274,7 +316,7 @@ func (reg *Reg) tagListOCI(ctx context.Context, r ref.Ref, config scheme.TagConf
 	}
 	defer resp.Close()
 	if resp.HTTPResponse().StatusCode != 200 {
-		return nil, fmt.Errorf("failed to list tags for %s: %w", r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return nil, fmt.Errorf("failed to list tags for %s: %w", r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 	respBody, err := io.ReadAll(resp)
 	if err != nil {
@@ -317,7 +359,7 @@ func (reg *Reg) tagListLink(ctx context.Context, r ref.Ref, _ scheme.TagConfig,
 	}
 	defer resp.Close()
 	if resp.HTTPResponse().StatusCode != 200 {
-		return nil, fmt.Errorf("failed to list tags for %s: %w", r.CommonName(), reghttp.HTTPError(resp.HTTPResponse().StatusCode))
+		return nil, fmt.Errorf("failed to list tags for %s: %w", r.CommonName(), reghttp.HTTPErrorResp(resp.HTTPResponse()))
 	}
 	respBody, err := io.ReadAll(resp)
 	if err != nil {
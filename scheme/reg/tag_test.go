@@ -354,10 +354,38 @@ func TestTag(t *testing.T) {
 		if err != nil {
 			t.Errorf("failed creating delRef: %v", err)
 		}
-		err = reg.TagDelete(ctx, delRef)
+		var method scheme.TagDeleteMethod
+		err = reg.TagDeleteWithOpts(ctx, delRef, scheme.WithTagDeleteMethod(&method))
 		if err != nil {
 			t.Fatalf("failed to delete tag: %v", err)
 		}
+		if method != scheme.TagDeleteDirect {
+			t.Errorf("unexpected delete method: %s", method)
+		}
+	})
+
+	// delete tag without the fallback
+	t.Run("Delete No Fallback", func(t *testing.T) {
+		delRef, err := ref.New(tsURL.Host + repoPath + ":" + delFallbackTag)
+		if err != nil {
+			t.Errorf("failed creating delRef: %v", err)
+		}
+		err = reg.TagDeleteWithOpts(ctx, delRef, scheme.WithTagDeleteNoFallback())
+		if !errors.Is(err, errs.ErrUnsupportedAPI) {
+			t.Fatalf("unexpected error: expected %v, received %v", errs.ErrUnsupportedAPI, err)
+		}
+	})
+
+	// delete tag with an unsupported tombstone media type
+	t.Run("Delete Tombstone Invalid", func(t *testing.T) {
+		delRef, err := ref.New(tsURL.Host + repoPath + ":" + delFallbackTag)
+		if err != nil {
+			t.Errorf("failed creating delRef: %v", err)
+		}
+		err = reg.TagDeleteWithOpts(ctx, delRef, scheme.WithTagDeleteTombstone(mediatype.OCI1ManifestList, nil))
+		if !errors.Is(err, errs.ErrUnsupportedMediaType) {
+			t.Fatalf("unexpected error: expected %v, received %v", errs.ErrUnsupportedMediaType, err)
+		}
 	})
 
 	// delete tag with fallback manifest delete
@@ -366,9 +394,15 @@ func TestTag(t *testing.T) {
 		if err != nil {
 			t.Errorf("failed creating delRef: %v", err)
 		}
-		err = reg.TagDelete(ctx, delRef)
+		var method scheme.TagDeleteMethod
+		err = reg.TagDeleteWithOpts(ctx, delRef,
+			scheme.WithTagDeleteMethod(&method),
+			scheme.WithTagDeleteTombstone(mediatype.Docker2Manifest, map[string]string{"org.example.deleted": "true"}))
 		if err != nil {
 			t.Fatalf("failed to delete tag: %v", err)
 		}
+		if method != scheme.TagDeleteTombstone {
+			t.Errorf("unexpected delete method: %s", method)
+		}
 	})
 }
@@ -4,6 +4,9 @@ package scheme
 import (
 	"context"
 	"io"
+	"net/url"
+
+	"github.com/opencontainers/go-digest"
 
 	"github.com/regclient/regclient/internal/pqueue"
 	"github.com/regclient/regclient/internal/reqmeta"
@@ -50,6 +53,18 @@ type API interface {
 	TagList(ctx context.Context, r ref.Ref, opts ...TagOpts) (*tag.List, error)
 }
 
+// BlobURLGetter is used to indicate the scheme can return the download URL of a blob.
+type BlobURLGetter interface {
+	// BlobGetURL returns the URL a blob is redirected to, without downloading the blob.
+	BlobGetURL(ctx context.Context, r ref.Ref, d descriptor.Descriptor) (*url.URL, error)
+}
+
+// TagDeleter is used to indicate the scheme supports options when deleting a tag.
+type TagDeleter interface {
+	// TagDeleteWithOpts removes a tag from the repository.
+	TagDeleteWithOpts(ctx context.Context, r ref.Ref, opts ...TagDeleteOpts) error
+}
+
 // Closer is used to check if a scheme implements the Close API.
 type Closer interface {
 	Close(ctx context.Context, r ref.Ref) error
@@ -64,6 +79,12 @@ type GCLocker interface {
 	GCUnlock(r ref.Ref)
 }
 
+// ManifestConditional is used to indicate the scheme supports conditional manifest requests.
+type ManifestConditional interface {
+	// ManifestGetIfChanged retrieves a manifest, returning an error wrapping errs.ErrNotModified when the digest matches.
+	ManifestGetIfChanged(ctx context.Context, r ref.Ref, d digest.Digest) (manifest.Manifest, error)
+}
+
 // Throttler is used to indicate the scheme implements Throttle.
 type Throttler interface {
 	Throttle(r ref.Ref, put bool) []*pqueue.Queue[reqmeta.Data]
@@ -74,6 +95,9 @@ type ManifestConfig struct {
 	CheckReferrers bool
 	Child          bool // used when pushing a child of a manifest list, skips indexing in ocidir
 	Manifest       manifest.Manifest
+	IfMatch        digest.Digest // when pushing a tag, the tag must currently point to this digest
+	IfNoneMatch    bool          // when pushing a tag, the tag must not exist
+	Force          bool          // replace tags matching the host immutable tag patterns
 }
 
 // ManifestOpts is used to set options on manifest APIs.
@@ -104,8 +128,30 @@ func WithManifest(m manifest.Manifest) ManifestOpts {
 	}
 }
 
+// WithManifestIfMatch is used when pushing a manifest to only replace a tag that points to the digest.
+func WithManifestIfMatch(d digest.Digest) ManifestOpts {
+	return func(mc *ManifestConfig) {
+		mc.IfMatch = d
+	}
+}
+
+// WithManifestIfNoneMatch is used when pushing a manifest to only create a tag that does not exist.
+func WithManifestIfNoneMatch() ManifestOpts {
+	return func(mc *ManifestConfig) {
+		mc.IfNoneMatch = true
+	}
+}
+
+// WithManifestForce allows a push to replace a tag that matches the host immutable tag patterns.
+func WithManifestForce() ManifestOpts {
+	return func(mc *ManifestConfig) {
+		mc.Force = true
+	}
+}
+
 // ReferrerConfig is used by schemes to import [ReferrerOpts].
 type ReferrerConfig struct {
+	Depth    int                 // levels of referrers to include, values less than 2 only include direct referrers
 	MatchOpt descriptor.MatchOpt // filter/sort results
 	Platform string              // get referrers for a specific platform
 	SrcRepo  ref.Ref             // repo used to query referrers
@@ -114,6 +160,16 @@ type ReferrerConfig struct {
 // ReferrerOpts is used to set options on referrer APIs.
 type ReferrerOpts func(*ReferrerConfig)
 
+// WithReferrerDepth includes referrers of referrers up to depth levels, returned in [referrer.ReferrerList.Referrers].
+// A depth of 1 (the default) only returns the direct referrers to the subject.
+// The [descriptor.MatchOpt] filters are only applied to the direct referrers.
+// Note that this is implemented by [regclient.ReferrerList] and not the individual scheme implementations.
+func WithReferrerDepth(depth int) ReferrerOpts {
+	return func(config *ReferrerConfig) {
+		config.Depth = depth
+	}
+}
+
 // WithReferrerMatchOpt filters results using [descriptor.MatchOpt].
 func WithReferrerMatchOpt(mo descriptor.MatchOpt) ReferrerOpts {
 	return func(config *ReferrerConfig) {
@@ -225,3 +281,57 @@ func WithTagLast(last string) TagOpts {
 		t.Last = last
 	}
 }
+
+// TagDeleteConfig is used by schemes to import [TagDeleteOpts].
+type TagDeleteConfig struct {
+	Force                bool
+	Method               *TagDeleteMethod
+	NoFallback           bool
+	TombstoneAnnotations map[string]string
+	TombstoneMediaType   string
+}
+
+// TagDeleteMethod indicates how a tag was deleted.
+type TagDeleteMethod string
+
+const (
+	// TagDeleteDirect is used when the tag was deleted with the tag delete API.
+	TagDeleteDirect TagDeleteMethod = "direct"
+	// TagDeleteTombstone is used when a unique tombstone manifest was pushed to the tag and deleted by digest.
+	TagDeleteTombstone TagDeleteMethod = "tombstone"
+)
+
+// TagDeleteOpts is used to set options on [TagDeleter].
+type TagDeleteOpts func(*TagDeleteConfig)
+
+// WithTagDeleteForce allows a tag matching the host immutable tag patterns to be deleted.
+func WithTagDeleteForce() TagDeleteOpts {
+	return func(t *TagDeleteConfig) {
+		t.Force = true
+	}
+}
+
+// WithTagDeleteMethod returns the method used to delete a tag.
+func WithTagDeleteMethod(m *TagDeleteMethod) TagDeleteOpts {
+	return func(t *TagDeleteConfig) {
+		t.Method = m
+	}
+}
+
+// WithTagDeleteNoFallback disables pushing a tombstone manifest when the registry does not support the tag delete API.
+// An error wrapping errs.ErrUnsupportedAPI is returned instead.
+func WithTagDeleteNoFallback() TagDeleteOpts {
+	return func(t *TagDeleteConfig) {
+		t.NoFallback = true
+	}
+}
+
+// WithTagDeleteTombstone customizes the tombstone manifest pushed when the registry does not support the tag delete API.
+// The media type may be an OCI or Docker image manifest, and defaults to match the current manifest.
+// Annotations are added to the tombstone manifest.
+func WithTagDeleteTombstone(mediaType string, annotations map[string]string) TagDeleteOpts {
+	return func(t *TagDeleteConfig) {
+		t.TombstoneMediaType = mediaType
+		t.TombstoneAnnotations = annotations
+	}
+}
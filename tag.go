@@ -16,7 +16,11 @@ import (
 // 1. Make a manifest, for this we put a few labels and timestamps to be unique.
 // 2. Push that manifest to the tag.
 // 3. Delete the digest for that new manifest that is only used by that tag.
-func (rc *RegClient) TagDelete(ctx context.Context, r ref.Ref) error {
+//
+// The tombstone manifest may be customized or disabled with [scheme.WithTagDeleteTombstone] and [scheme.WithTagDeleteNoFallback].
+// Use [scheme.WithTagDeleteMethod] to see which method was used.
+// Options are ignored by schemes that do not implement [scheme.TagDeleter].
+func (rc *RegClient) TagDelete(ctx context.Context, r ref.Ref, opts ...scheme.TagDeleteOpts) error {
 	if !r.IsSet() {
 		return fmt.Errorf("ref is not set: %s%.0w", r.CommonName(), errs.ErrInvalidReference)
 	}
@@ -24,6 +28,10 @@ func (rc *RegClient) TagDelete(ctx context.Context, r ref.Ref) error {
 	if err != nil {
 		return err
 	}
+	defer rc.memo.manifestDrop(r.SetTag(r.Tag))
+	if td, ok := schemeAs[scheme.TagDeleter](schemeAPI); ok {
+		return td.TagDeleteWithOpts(ctx, r, opts...)
+	}
 	return schemeAPI.TagDelete(ctx, r)
 }
 
@@ -0,0 +1,275 @@
+// Package testkit runs an in-memory OCI registry for integration tests with regclient.
+//
+// The registry is served by olareg, optionally seeded from a directory of OCI Layouts or an ocidir.
+// Options simulate registry behaviors that are difficult to reproduce, like rate limits, flaky responses, and missing APIs.
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/olareg/olareg"
+	oConfig "github.com/olareg/olareg/config"
+
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/scheme/reg"
+	"github.com/regclient/regclient/types/ref"
+)
+
+// API identifies a registry API that may be disabled.
+type API int
+
+const (
+	// APIReferrers is the OCI referrers API, clients fall back to the referrers tag schema.
+	APIReferrers API = iota
+	// APITagList is the tag listing API, requests return a 404.
+	APITagList
+	// APIBlobMount is the cross repository blob mount, mount requests start a normal upload.
+	APIBlobMount
+)
+
+// Registry is a running test registry.
+type Registry struct {
+	Host string // host and port of the registry, used as the registry in a ref
+	conf registryConfig
+
+	server   *httptest.Server
+	olareg   *olareg.Server
+	ready    atomic.Bool // set after seeding to enable the fault options
+	requests atomic.Int64
+	mu       sync.Mutex
+	rlStart  time.Time // start of the current rate limit window
+	rlCount  int       // requests in the current rate limit window
+}
+
+type registryConfig struct {
+	seedDir   string
+	ociDirs   map[string]string // repository to ocidir path
+	delete    bool
+	rlLimit   int
+	rlWindow  time.Duration
+	flakyN    int
+	flakyCode int
+	disabled  map[API]bool
+}
+
+// Opt is used to configure the test registry.
+type Opt func(*registryConfig)
+
+// WithSeedDir loads repositories from a directory of OCI Layouts, with one layout per repository.
+// Changes to the registry are only made in memory.
+func WithSeedDir(dir string) Opt {
+	return func(rc *registryConfig) {
+		rc.seedDir = dir
+	}
+}
+
+// WithOCIDir copies every tagged image from an OCI Layout into a repository.
+func WithOCIDir(repo, dir string) Opt {
+	return func(rc *registryConfig) {
+		rc.ociDirs[repo] = dir
+	}
+}
+
+// WithDelete enables the manifest, tag, and blob delete APIs.
+func WithDelete() Opt {
+	return func(rc *registryConfig) {
+		rc.delete = true
+	}
+}
+
+// WithRateLimit returns a 429 with a Retry-After header after limit requests within the window.
+// Responses include the RateLimit-Limit and RateLimit-Remaining headers used by Docker Hub.
+func WithRateLimit(limit int, window time.Duration) Opt {
+	return func(rc *registryConfig) {
+		rc.rlLimit = limit
+		rc.rlWindow = window
+	}
+}
+
+// WithFlaky fails the first request, and every nth request after it, with the http status code, e.g. http.StatusBadGateway.
+// A value of 1 fails every request.
+func WithFlaky(n int, status int) Opt {
+	return func(rc *registryConfig) {
+		rc.flakyN = n
+		rc.flakyCode = status
+	}
+}
+
+// WithoutAPI disables registry APIs to test client fallbacks.
+func WithoutAPI(apis ...API) Opt {
+	return func(rc *registryConfig) {
+		for _, api := range apis {
+			rc.disabled[api] = true
+		}
+	}
+}
+
+// New starts a registry that is stopped when the test completes.
+// The test fails when the registry cannot be seeded.
+// Requests made to seed the registry are not counted, and are not affected by the rate limit or failure options.
+func New(t testing.TB, opts ...Opt) *Registry {
+	t.Helper()
+	r := &Registry{
+		conf: registryConfig{
+			ociDirs:  map[string]string{},
+			disabled: map[API]bool{},
+		},
+	}
+	for _, opt := range opts {
+		opt(&r.conf)
+	}
+	boolT, boolF := true, false
+	oConf := oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+			RootDir:   r.conf.seedDir,
+		},
+	}
+	if r.conf.delete {
+		oConf.API.DeleteEnabled = &boolT
+		oConf.API.Blob.DeleteEnabled = &boolT
+	}
+	if r.conf.disabled[APIReferrers] {
+		oConf.API.Referrer.Enabled = &boolF
+	}
+	r.olareg = olareg.New(oConf)
+	r.server = httptest.NewServer(http.HandlerFunc(r.serveHTTP))
+	u, _ := url.Parse(r.server.URL)
+	r.Host = u.Host
+	t.Cleanup(func() {
+		r.server.Close()
+		_ = r.olareg.Close()
+	})
+	if len(r.conf.ociDirs) > 0 {
+		if err := r.seedOCIDirs(context.Background()); err != nil {
+			t.Fatalf("failed to seed registry: %v", err)
+		}
+	}
+	r.ready.Store(true)
+	return r
+}
+
+// ConfigHost returns the regclient host configuration for the registry.
+func (r *Registry) ConfigHost() config.Host {
+	return config.Host{
+		Name: r.Host,
+		TLS:  config.TLSDisabled,
+	}
+}
+
+// RegClient returns a client configured for the registry.
+// Retry delays are shortened to keep tests fast, and may be overridden with opts.
+func (r *Registry) RegClient(opts ...regclient.Opt) *regclient.RegClient {
+	rcOpts := []regclient.Opt{
+		regclient.WithConfigHost(r.ConfigHost()),
+		regclient.WithRegOpts(reg.WithDelay(time.Millisecond*10, time.Millisecond*100)),
+	}
+	return regclient.New(append(rcOpts, opts...)...)
+}
+
+// Ref returns a reference to a repository, tag, or digest on the registry, e.g. "repo:tag".
+func (r *Registry) Ref(s string) (ref.Ref, error) {
+	return ref.New(r.Host + "/" + s)
+}
+
+// Requests returns the number of requests received, excluding requests used to seed the registry.
+func (r *Registry) Requests() int {
+	return int(r.requests.Load())
+}
+
+// seedOCIDirs copies the images from each ocidir before the fault options are applied.
+func (r *Registry) seedOCIDirs(ctx context.Context) error {
+	rc := regclient.New(regclient.WithConfigHost(r.ConfigHost()))
+	for repo, dir := range r.conf.ociDirs {
+		src, err := ref.New("ocidir://" + dir)
+		if err != nil {
+			return err
+		}
+		tgt, err := r.Ref(repo)
+		if err != nil {
+			return err
+		}
+		tl, err := rc.TagList(ctx, src)
+		if err != nil {
+			return fmt.Errorf("failed to list tags in %s: %w", dir, err)
+		}
+		tags, err := tl.GetTags()
+		if err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			err = rc.ImageCopy(ctx, src.SetTag(tag), tgt.SetTag(tag))
+			if err != nil {
+				return fmt.Errorf("failed to copy %s:%s: %w", dir, tag, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Registry) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if !r.ready.Load() {
+		r.olareg.ServeHTTP(w, req)
+		return
+	}
+	n := r.requests.Add(1)
+	if r.conf.rlLimit > 0 {
+		if retry, ok := r.rateLimit(w); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(retry))
+			writeError(w, http.StatusTooManyRequests, "TOOMANYREQUESTS", "rate limit exceeded")
+			return
+		}
+	}
+	if r.conf.flakyN > 0 && (n-1)%int64(r.conf.flakyN) == 0 {
+		writeError(w, r.conf.flakyCode, "UNAVAILABLE", "simulated failure")
+		return
+	}
+	if r.conf.disabled[APITagList] && req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/tags/list") {
+		writeError(w, http.StatusNotFound, "UNSUPPORTED", "tag listing is disabled")
+		return
+	}
+	if r.conf.disabled[APIBlobMount] && req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/blobs/uploads/") && req.URL.Query().Has("mount") {
+		q := req.URL.Query()
+		q.Del("mount")
+		q.Del("from")
+		req.URL.RawQuery = q.Encode()
+	}
+	r.olareg.ServeHTTP(w, req)
+}
+
+// rateLimit counts the request in the current window, returning the seconds to wait when the limit is exceeded.
+func (r *Registry) rateLimit(w http.ResponseWriter) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if now.Sub(r.rlStart) >= r.conf.rlWindow {
+		r.rlStart = now
+		r.rlCount = 0
+	}
+	r.rlCount++
+	remain := max(r.conf.rlLimit-r.rlCount, 0)
+	w.Header().Set("RateLimit-Limit", fmt.Sprintf("%d;w=%d", r.conf.rlLimit, int(math.Ceil(r.conf.rlWindow.Seconds()))))
+	w.Header().Set("RateLimit-Remaining", fmt.Sprintf("%d;w=%d", remain, int(math.Ceil(r.conf.rlWindow.Seconds()))))
+	if r.rlCount > r.conf.rlLimit {
+		return int(math.Ceil(r.conf.rlWindow.Seconds() - now.Sub(r.rlStart).Seconds())), false
+	}
+	return 0, true
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, `{"errors":[{"code":%q,"message":%q}]}`, code, message)
+}
@@ -0,0 +1,143 @@
+package testkit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/types/errs"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	t.Run("seed dir", func(t *testing.T) {
+		t.Parallel()
+		r := New(t, WithSeedDir("../testdata"))
+		rc := r.RegClient()
+		rRef, err := r.Ref("testrepo")
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		tl, err := rc.TagList(ctx, rRef)
+		if err != nil {
+			t.Fatalf("failed to list tags: %v", err)
+		}
+		tags, _ := tl.GetTags()
+		if !slices.Contains(tags, "v1") {
+			t.Errorf("missing tag v1: %v", tags)
+		}
+		if r.Requests() == 0 {
+			t.Errorf("requests were not counted")
+		}
+	})
+	t.Run("ocidir", func(t *testing.T) {
+		t.Parallel()
+		r := New(t, WithOCIDir("copy/repo", "../testdata/testrepo"))
+		if r.Requests() != 0 {
+			t.Errorf("seeding requests were counted: %d", r.Requests())
+		}
+		rc := r.RegClient()
+		rRef, _ := r.Ref("copy/repo:v2")
+		_, err := rc.ManifestHead(ctx, rRef, regclient.WithManifestRequireDigest())
+		if err != nil {
+			t.Errorf("failed to get seeded manifest: %v", err)
+		}
+	})
+	t.Run("delete", func(t *testing.T) {
+		t.Parallel()
+		for _, enabled := range []bool{false, true} {
+			opts := []Opt{WithSeedDir("../testdata")}
+			if enabled {
+				opts = append(opts, WithDelete())
+			}
+			r := New(t, opts...)
+			rRef, _ := r.Ref("testrepo:v3")
+			err := r.RegClient().TagDelete(ctx, rRef)
+			if enabled && err != nil {
+				t.Errorf("failed to delete tag: %v", err)
+			} else if !enabled && err == nil {
+				t.Errorf("delete succeeded without WithDelete")
+			}
+		}
+	})
+	t.Run("rate limit", func(t *testing.T) {
+		t.Parallel()
+		r := New(t, WithRateLimit(2, time.Minute))
+		for i := range 3 {
+			resp, err := http.Get("http://" + r.Host + "/v2/")
+			if err != nil {
+				t.Fatalf("failed to send request: %v", err)
+			}
+			_ = resp.Body.Close()
+			expect := http.StatusOK
+			if i == 2 {
+				expect = http.StatusTooManyRequests
+				if resp.Header.Get("Retry-After") == "" {
+					t.Errorf("missing Retry-After header")
+				}
+			}
+			if resp.StatusCode != expect {
+				t.Errorf("request %d, expected status %d, received %d", i, expect, resp.StatusCode)
+			}
+			if resp.Header.Get("RateLimit-Limit") != "2;w=60" {
+				t.Errorf("unexpected RateLimit-Limit header: %s", resp.Header.Get("RateLimit-Limit"))
+			}
+		}
+	})
+	t.Run("flaky", func(t *testing.T) {
+		t.Parallel()
+		r := New(t, WithSeedDir("../testdata"), WithFlaky(2, http.StatusBadGateway))
+		rRef, _ := r.Ref("testrepo:v1")
+		// the client retries the failed requests
+		_, err := r.RegClient().ManifestGet(ctx, rRef)
+		if err != nil {
+			t.Errorf("failed to get manifest: %v", err)
+		}
+		if r.Requests() < 2 {
+			t.Errorf("expected failed requests to be retried, received %d requests", r.Requests())
+		}
+	})
+	t.Run("without tag list", func(t *testing.T) {
+		t.Parallel()
+		r := New(t, WithSeedDir("../testdata"), WithoutAPI(APITagList))
+		rRef, _ := r.Ref("testrepo")
+		_, err := r.RegClient().TagList(ctx, rRef)
+		if !errors.Is(err, errs.ErrNotFound) {
+			t.Errorf("tag list did not fail: %v", err)
+		}
+	})
+	t.Run("without referrers", func(t *testing.T) {
+		t.Parallel()
+		r := New(t, WithSeedDir("../testdata"), WithoutAPI(APIReferrers, APIBlobMount))
+		rRef, _ := r.Ref("testrepo:v1")
+		rc := r.RegClient()
+		m, err := rc.ManifestHead(ctx, rRef, regclient.WithManifestRequireDigest())
+		if err != nil {
+			t.Fatalf("failed to head manifest: %v", err)
+		}
+		resp, err := http.Get("http://" + r.Host + "/v2/testrepo/referrers/" + m.GetDescriptor().Digest.String())
+		if err != nil {
+			t.Fatalf("failed to send request: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("referrers API was not disabled, status %d", resp.StatusCode)
+		}
+		// the fallback to the tag schema is used by the client
+		_, err = rc.ReferrerList(ctx, rRef)
+		if err != nil {
+			t.Errorf("failed to list referrers: %v", err)
+		}
+		// copies without a blob mount upload every blob
+		rTgt, _ := r.Ref("testcopy:v1")
+		err = rc.ImageCopy(ctx, rRef, rTgt)
+		if err != nil {
+			t.Errorf("failed to copy image: %v", err)
+		}
+	})
+}
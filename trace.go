@@ -0,0 +1,225 @@
+package regclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/scheme"
+	"github.com/regclient/regclient/types/blob"
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/ping"
+	"github.com/regclient/regclient/types/ref"
+	"github.com/regclient/regclient/types/referrer"
+	"github.com/regclient/regclient/types/repo"
+	"github.com/regclient/regclient/types/tag"
+	"github.com/regclient/regclient/types/trace"
+)
+
+// tracerName is the instrumentation name passed to the [trace.TracerProvider].
+const tracerName = "github.com/regclient/regclient"
+
+// WithTracerProvider creates spans for registry operations using the provided [trace.TracerProvider].
+// When the provider implements [trace.Injector], the trace context is added to the headers of each HTTP request.
+// See the github.com/regclient/regclient/pkg/traceotel package to use an OpenTelemetry TracerProvider.
+func WithTracerProvider(tp trace.TracerProvider) Opt {
+	return func(rc *RegClient) {
+		if tp == nil {
+			return
+		}
+		rc.tracer = tp.Tracer(tracerName)
+		rc.traceInject, _ = tp.(trace.Injector)
+	}
+}
+
+// traceTransport adds the trace context to the headers of each request.
+type traceTransport struct {
+	next   http.RoundTripper
+	inject trace.Injector
+}
+
+func (tt traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a round tripper should not modify the request
+	req = req.Clone(req.Context())
+	tt.inject.Inject(req.Context(), req.Header)
+	return tt.next.RoundTrip(req)
+}
+
+// traceBlobReader ends the span of a blob request when the blob is closed, including the time to read the blob.
+type traceBlobReader struct {
+	br      *blob.BReader
+	span    trace.Span
+	readErr error
+	once    sync.Once
+}
+
+func (tr *traceBlobReader) Read(p []byte) (int, error) {
+	n, err := tr.br.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		tr.readErr = err
+	}
+	return n, err
+}
+
+func (tr *traceBlobReader) Close() error {
+	err := tr.br.Close()
+	tr.once.Do(func() {
+		trace.End(tr.span, errors.Join(tr.readErr, err))
+	})
+	return err
+}
+
+// traceStart creates a span for an operation in the regclient package.
+func (rc *RegClient) traceStart(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, trace.Span) {
+	return trace.Start(ctx, rc.tracer, "regclient."+name, attrs...)
+}
+
+// schemeTrace wraps a [scheme.API] to create a span for each call.
+type schemeTrace struct {
+	schemeWrap
+	name   string
+	tracer trace.Tracer
+}
+
+func (st *schemeTrace) start(ctx context.Context, method string, r ref.Ref, attrs ...slog.Attr) (context.Context, trace.Span) {
+	attrs = append([]slog.Attr{
+		slog.String("scheme", st.name),
+		slog.String("ref", r.CommonName()),
+	}, attrs...)
+	return st.tracer.Start(ctx, "regclient.scheme."+method, attrs...)
+}
+
+func (st *schemeTrace) BlobDelete(ctx context.Context, r ref.Ref, d descriptor.Descriptor) error {
+	ctx, span := st.start(ctx, "BlobDelete", r, slog.String("digest", d.Digest.String()))
+	err := st.API.BlobDelete(ctx, r, d)
+	trace.End(span, err)
+	return err
+}
+
+func (st *schemeTrace) BlobGet(ctx context.Context, r ref.Ref, d descriptor.Descriptor) (blob.Reader, error) {
+	ctx, span := st.start(ctx, "BlobGet", r, slog.String("digest", d.Digest.String()), slog.Int64("size", d.Size))
+	br, err := st.API.BlobGet(ctx, r, d)
+	if err != nil {
+		trace.End(span, err)
+		return br, err
+	}
+	// the span ends when the caller closes the blob
+	return blob.NewReader(
+		blob.WithDesc(br.GetDescriptor()),
+		blob.WithHeader(br.RawHeaders()),
+		blob.WithRef(r),
+		blob.WithReader(&traceBlobReader{br: br, span: span}),
+	), nil
+}
+
+func (st *schemeTrace) BlobGetURL(ctx context.Context, r ref.Ref, d descriptor.Descriptor) (*url.URL, error) {
+	ctx, span := st.start(ctx, "BlobGetURL", r, slog.String("digest", d.Digest.String()))
+	u, err := st.schemeWrap.BlobGetURL(ctx, r, d)
+	trace.End(span, err)
+	return u, err
+}
+
+func (st *schemeTrace) BlobHead(ctx context.Context, r ref.Ref, d descriptor.Descriptor) (blob.Reader, error) {
+	ctx, span := st.start(ctx, "BlobHead", r, slog.String("digest", d.Digest.String()))
+	br, err := st.API.BlobHead(ctx, r, d)
+	trace.End(span, err)
+	return br, err
+}
+
+func (st *schemeTrace) BlobMount(ctx context.Context, refSrc ref.Ref, refTgt ref.Ref, d descriptor.Descriptor) error {
+	ctx, span := st.start(ctx, "BlobMount", refTgt, slog.String("source", refSrc.CommonName()), slog.String("digest", d.Digest.String()))
+	err := st.API.BlobMount(ctx, refSrc, refTgt, d)
+	trace.End(span, err)
+	return err
+}
+
+func (st *schemeTrace) BlobPut(ctx context.Context, r ref.Ref, d descriptor.Descriptor, rdr io.Reader) (descriptor.Descriptor, error) {
+	ctx, span := st.start(ctx, "BlobPut", r, slog.String("digest", d.Digest.String()), slog.Int64("size", d.Size))
+	dOut, err := st.API.BlobPut(ctx, r, d, rdr)
+	trace.End(span, err)
+	return dOut, err
+}
+
+func (st *schemeTrace) ManifestDelete(ctx context.Context, r ref.Ref, opts ...scheme.ManifestOpts) error {
+	ctx, span := st.start(ctx, "ManifestDelete", r)
+	err := st.API.ManifestDelete(ctx, r, opts...)
+	trace.End(span, err)
+	return err
+}
+
+func (st *schemeTrace) ManifestGet(ctx context.Context, r ref.Ref) (manifest.Manifest, error) {
+	ctx, span := st.start(ctx, "ManifestGet", r)
+	m, err := st.API.ManifestGet(ctx, r)
+	trace.End(span, err)
+	return m, err
+}
+
+func (st *schemeTrace) ManifestGetIfChanged(ctx context.Context, r ref.Ref, d digest.Digest) (manifest.Manifest, error) {
+	ctx, span := st.start(ctx, "ManifestGetIfChanged", r, slog.String("digest", d.String()))
+	m, err := st.schemeWrap.ManifestGetIfChanged(ctx, r, d)
+	trace.End(span, err)
+	return m, err
+}
+
+func (st *schemeTrace) ManifestHead(ctx context.Context, r ref.Ref) (manifest.Manifest, error) {
+	ctx, span := st.start(ctx, "ManifestHead", r)
+	m, err := st.API.ManifestHead(ctx, r)
+	trace.End(span, err)
+	return m, err
+}
+
+func (st *schemeTrace) ManifestPut(ctx context.Context, r ref.Ref, m manifest.Manifest, opts ...scheme.ManifestOpts) error {
+	ctx, span := st.start(ctx, "ManifestPut", r, slog.String("digest", m.GetDescriptor().Digest.String()))
+	err := st.API.ManifestPut(ctx, r, m, opts...)
+	trace.End(span, err)
+	return err
+}
+
+func (st *schemeTrace) Ping(ctx context.Context, r ref.Ref) (ping.Result, error) {
+	ctx, span := st.start(ctx, "Ping", r)
+	result, err := st.API.Ping(ctx, r)
+	trace.End(span, err)
+	return result, err
+}
+
+func (st *schemeTrace) ReferrerList(ctx context.Context, r ref.Ref, opts ...scheme.ReferrerOpts) (referrer.ReferrerList, error) {
+	ctx, span := st.start(ctx, "ReferrerList", r)
+	rl, err := st.API.ReferrerList(ctx, r, opts...)
+	trace.End(span, err)
+	return rl, err
+}
+
+func (st *schemeTrace) TagDelete(ctx context.Context, r ref.Ref) error {
+	ctx, span := st.start(ctx, "TagDelete", r)
+	err := st.API.TagDelete(ctx, r)
+	trace.End(span, err)
+	return err
+}
+
+func (st *schemeTrace) TagDeleteWithOpts(ctx context.Context, r ref.Ref, opts ...scheme.TagDeleteOpts) error {
+	ctx, span := st.start(ctx, "TagDelete", r)
+	err := st.schemeWrap.TagDeleteWithOpts(ctx, r, opts...)
+	trace.End(span, err)
+	return err
+}
+
+func (st *schemeTrace) TagList(ctx context.Context, r ref.Ref, opts ...scheme.TagOpts) (*tag.List, error) {
+	ctx, span := st.start(ctx, "TagList", r)
+	tl, err := st.API.TagList(ctx, r, opts...)
+	trace.End(span, err)
+	return tl, err
+}
+
+func (st *schemeTrace) RepoList(ctx context.Context, hostname string, opts ...scheme.RepoOpts) (*repo.RepoList, error) {
+	ctx, span := st.tracer.Start(ctx, "regclient.scheme.RepoList", slog.String("scheme", st.name), slog.String("registry", hostname))
+	result, err := st.schemeWrap.RepoList(ctx, hostname, opts...)
+	trace.End(span, err)
+	return result, err
+}
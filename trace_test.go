@@ -0,0 +1,195 @@
+package regclient
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/olareg/olareg"
+	oConfig "github.com/olareg/olareg/config"
+
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/types/ref"
+	"github.com/regclient/regclient/types/trace"
+)
+
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+type testSpan struct {
+	name   string
+	parent *testSpan
+	err    error
+	ended  bool
+}
+
+type testSpanKey struct{}
+
+func (tt *testTracer) Tracer(name string) trace.Tracer {
+	return tt
+}
+
+func (tt *testTracer) Start(ctx context.Context, spanName string, attrs ...slog.Attr) (context.Context, trace.Span) {
+	span := &testSpan{name: spanName}
+	if parent, ok := ctx.Value(testSpanKey{}).(*testSpan); ok {
+		span.parent = parent
+	}
+	tt.mu.Lock()
+	tt.spans = append(tt.spans, span)
+	tt.mu.Unlock()
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func (tt *testTracer) Inject(ctx context.Context, header http.Header) {
+	if span, ok := ctx.Value(testSpanKey{}).(*testSpan); ok {
+		header.Set("X-Test-Span", span.name)
+	}
+}
+
+func (ts *testSpan) RecordError(err error) {
+	ts.err = err
+}
+
+func (ts *testSpan) End() {
+	ts.ended = true
+}
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	tracer := &testTracer{}
+	rc := New(WithTracerProvider(tracer))
+	rSrc, err := ref.New("ocidir://./testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rTgt, err := ref.New("ocidir://" + tempDir + "/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	err = rc.ImageCopy(ctx, rSrc, rTgt)
+	if err != nil {
+		t.Fatalf("failed to copy: %v", err)
+	}
+	_, err = rc.ManifestGet(ctx, rSrc.SetTag("missing"))
+	if err == nil {
+		t.Fatalf("manifest get on a missing tag did not fail")
+	}
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	names := []string{}
+	for _, span := range tracer.spans {
+		if !span.ended {
+			t.Errorf("span was not ended: %s", span.name)
+		}
+		names = append(names, span.name)
+	}
+	for _, expect := range []string{"regclient.ImageCopy", "regclient.scheme.ManifestGet", "regclient.scheme.ManifestPut", "regclient.scheme.BlobPut"} {
+		if !slices.Contains(names, expect) {
+			t.Errorf("missing span %s in %v", expect, names)
+		}
+	}
+	copySpan := tracer.spans[0]
+	if copySpan.name != "regclient.ImageCopy" || copySpan.err != nil {
+		t.Errorf("unexpected first span: %s, err %v", copySpan.name, copySpan.err)
+	}
+	for _, span := range tracer.spans[1:] {
+		if span.name == "regclient.scheme.ManifestPut" && span.parent == nil {
+			t.Errorf("manifest put span is missing a parent")
+		}
+	}
+	last := tracer.spans[len(tracer.spans)-1]
+	if last.name != "regclient.scheme.ManifestGet" || last.err == nil {
+		t.Errorf("last span did not record an error: %s", last.name)
+	}
+}
+
+func TestTraceHTTP(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	regHandler := olareg.New(oConfig.Config{
+		Storage: oConfig.ConfigStorage{
+			StoreType: oConfig.StoreMem,
+		},
+	})
+	var mu sync.Mutex
+	reqSpans := map[string]string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		reqSpans[r.Method+" "+r.URL.Path] = r.Header.Get("X-Test-Span")
+		mu.Unlock()
+		regHandler.ServeHTTP(w, r)
+	}))
+	tsURL, _ := url.Parse(ts.URL)
+	tsHost := tsURL.Host
+	t.Cleanup(func() {
+		ts.Close()
+		_ = regHandler.Close()
+	})
+	tracer := &testTracer{}
+	rc := New(
+		WithConfigHost(config.Host{
+			Name:     tsHost,
+			Hostname: tsHost,
+			TLS:      config.TLSDisabled,
+		}),
+		WithTracerProvider(tracer),
+	)
+	rSrc, err := ref.New("ocidir://./testdata/testrepo:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	rTgt, err := ref.New(tsHost + "/trace:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	if err := rc.ImageCopy(ctx, rSrc, rTgt); err != nil {
+		t.Fatalf("failed to copy: %v", err)
+	}
+	m, err := rc.ManifestGet(ctx, rTgt)
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	t.Run("propagate", func(t *testing.T) {
+		mu.Lock()
+		defer mu.Unlock()
+		span := reqSpans["GET /v2/trace/manifests/v1"]
+		if span != "regclient.scheme.ManifestGet" {
+			t.Errorf("unexpected span in the manifest request: %q", span)
+		}
+	})
+	t.Run("blob get", func(t *testing.T) {
+		d := m.GetDescriptor()
+		br, err := rc.BlobGet(ctx, rTgt, d)
+		if err != nil {
+			t.Fatalf("failed to get blob: %v", err)
+		}
+		tracer.mu.Lock()
+		span := tracer.spans[len(tracer.spans)-1]
+		tracer.mu.Unlock()
+		if span.name != "regclient.scheme.BlobGet" || span.ended {
+			t.Errorf("blob get span ended before the blob was read: %s", span.name)
+		}
+		if _, err := io.Copy(io.Discard, br); err != nil {
+			t.Fatalf("failed to read blob: %v", err)
+		}
+		if err := br.Close(); err != nil {
+			t.Fatalf("failed to close blob: %v", err)
+		}
+		if !span.ended || span.err != nil {
+			t.Errorf("blob get span did not end after close, ended %t, err %v", span.ended, span.err)
+		}
+		if br.GetDescriptor().Digest != d.Digest {
+			t.Errorf("unexpected descriptor, expected %s, received %s", d.Digest, br.GetDescriptor().Digest)
+		}
+	})
+}
@@ -2,8 +2,10 @@
 package descriptor
 
 import (
+	"cmp"
 	"fmt"
 	"maps"
+	"slices"
 	"sort"
 	"strings"
 	"text/tabwriter"
@@ -317,6 +319,60 @@ func DescriptorListFilter(dl []Descriptor, opt MatchOpt) []Descriptor {
 	return ret
 }
 
+// DescriptorListSort returns a copy of the list in a canonical order, allowing a regenerated index to be reproducible regardless of the input order.
+// When opt.SortAnnotation is set, descriptors are first sorted by that annotation, with opt.SortDesc reversing that order, and descriptors without the annotation are sorted last.
+// Descriptors are then sorted by platform, with descriptors without a platform sorted last, and then by digest, media type, and size.
+// Other fields in opt are ignored.
+func DescriptorListSort(dl []Descriptor, opt MatchOpt) []Descriptor {
+	ret := slices.Clone(dl)
+	slices.SortStableFunc(ret, func(a, b Descriptor) int {
+		if opt.SortAnnotation != "" {
+			aVal, aOK := a.Annotations[opt.SortAnnotation]
+			bVal, bOK := b.Annotations[opt.SortAnnotation]
+			switch {
+			case aOK && !bOK:
+				return -1
+			case !aOK && bOK:
+				return 1
+			case aOK && bOK && aVal != bVal:
+				if opt.SortDesc {
+					return strings.Compare(bVal, aVal)
+				}
+				return strings.Compare(aVal, bVal)
+			}
+		}
+		if c := platformCompare(a.Platform, b.Platform); c != 0 {
+			return c
+		}
+		return cmp.Or(
+			strings.Compare(a.Digest.String(), b.Digest.String()),
+			strings.Compare(a.MediaType, b.MediaType),
+			cmp.Compare(a.Size, b.Size),
+		)
+	})
+	return ret
+}
+
+// platformCompare orders platforms by each field, sorting a nil platform last.
+func platformCompare(a, b *platform.Platform) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return cmp.Or(
+		strings.Compare(a.OS, b.OS),
+		strings.Compare(a.Architecture, b.Architecture),
+		strings.Compare(a.Variant, b.Variant),
+		strings.Compare(a.OSVersion, b.OSVersion),
+		slices.Compare(a.OSFeatures, b.OSFeatures),
+		slices.Compare(a.Features, b.Features),
+	)
+}
+
 // DescriptorListSearch returns the first descriptor from the list matching the search options.
 func DescriptorListSearch(dl []Descriptor, opt MatchOpt) (Descriptor, error) {
 	if opt.ArtifactType != "" || opt.SortAnnotation != "" || len(opt.Annotations) > 0 {
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"slices"
 	"testing"
 
 	// crypto libraries included for go-digest
@@ -812,6 +813,101 @@ func TestListSearch(t *testing.T) {
 	}
 }
 
+func TestListSort(t *testing.T) {
+	t.Parallel()
+	digA := digest.FromString("a")
+	digB := digest.FromString("b")
+	dAMD64 := Descriptor{
+		MediaType: mediatype.OCI1Manifest,
+		Size:      1234,
+		Digest:    digB,
+		Platform:  &platform.Platform{OS: "linux", Architecture: "amd64"},
+	}
+	dARM64 := Descriptor{
+		MediaType: mediatype.OCI1Manifest,
+		Size:      1234,
+		Digest:    digA,
+		Platform:  &platform.Platform{OS: "linux", Architecture: "arm64"},
+	}
+	dARMv7 := Descriptor{
+		MediaType: mediatype.OCI1Manifest,
+		Size:      1234,
+		Digest:    digA,
+		Platform:  &platform.Platform{OS: "linux", Architecture: "arm", Variant: "v7"},
+	}
+	dWin1 := Descriptor{
+		MediaType: mediatype.OCI1Manifest,
+		Size:      1234,
+		Digest:    digB,
+		Platform:  &platform.Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.17763.5458"},
+	}
+	dWin2 := Descriptor{
+		MediaType: mediatype.OCI1Manifest,
+		Size:      1234,
+		Digest:    digA,
+		Platform:  &platform.Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.20348.2322"},
+	}
+	dNoPlatA := Descriptor{
+		MediaType:   mediatype.OCI1Manifest,
+		Size:        1234,
+		Digest:      digA,
+		Annotations: map[string]string{"version": "2"},
+	}
+	dNoPlatB := Descriptor{
+		MediaType:   mediatype.OCI1Manifest,
+		Size:        1234,
+		Digest:      digB,
+		Annotations: map[string]string{"version": "1"},
+	}
+	tt := []struct {
+		name   string
+		dl     []Descriptor
+		opt    MatchOpt
+		expect []Descriptor
+	}{
+		{
+			name:   "empty",
+			dl:     []Descriptor{},
+			expect: []Descriptor{},
+		},
+		{
+			name:   "platform",
+			dl:     []Descriptor{dNoPlatB, dWin2, dAMD64, dNoPlatA, dARMv7, dWin1, dARM64},
+			expect: []Descriptor{dAMD64, dARMv7, dARM64, dWin1, dWin2, dNoPlatB, dNoPlatA},
+		},
+		{
+			name:   "annotation",
+			dl:     []Descriptor{dAMD64, dNoPlatA, dARM64, dNoPlatB},
+			opt:    MatchOpt{SortAnnotation: "version"},
+			expect: []Descriptor{dNoPlatB, dNoPlatA, dAMD64, dARM64},
+		},
+		{
+			name:   "annotation desc",
+			dl:     []Descriptor{dAMD64, dNoPlatB, dARM64, dNoPlatA},
+			opt:    MatchOpt{SortAnnotation: "version", SortDesc: true},
+			expect: []Descriptor{dNoPlatA, dNoPlatB, dAMD64, dARM64},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			orig := slices.Clone(tc.dl)
+			result := DescriptorListSort(tc.dl, tc.opt)
+			if !slices.EqualFunc(orig, tc.dl, func(a, b Descriptor) bool { return a.Equal(b) }) {
+				t.Errorf("input list was modified")
+			}
+			if !slices.EqualFunc(tc.expect, result, func(a, b Descriptor) bool { return a.Equal(b) }) {
+				t.Errorf("unexpected order, expected %v, received %v", tc.expect, result)
+			}
+			// the result does not depend on the input order
+			slices.Reverse(tc.dl)
+			result = DescriptorListSort(tc.dl, tc.opt)
+			if !slices.EqualFunc(tc.expect, result, func(a, b Descriptor) bool { return a.Equal(b) }) {
+				t.Errorf("unexpected order from reversed input, expected %v, received %v", tc.expect, result)
+			}
+		})
+	}
+}
+
 func TestMatchOptMerge(t *testing.T) {
 	tt := []struct {
 		name    string
@@ -16,6 +16,8 @@ var (
 	ErrBackoffLimit = errors.New("backoff limit reached")
 	// ErrCanceled if the context was canceled
 	ErrCanceled = errors.New("context was canceled")
+	// ErrConflict when a conditional update finds the current content does not match the expected value
+	ErrConflict = errors.New("conflict")
 	// ErrDigestMismatch if the expected digest wasn't received
 	ErrDigestMismatch = errors.New("digest mismatch")
 	// ErrEmptyChallenge indicates an issue with the received challenge in the WWW-Authenticate header
@@ -58,10 +60,14 @@ var (
 	ErrNotFound = errors.New("not found")
 	// ErrNotImplemented returned when method has not been implemented yet
 	ErrNotImplemented = errors.New("not implemented")
+	// ErrNotModified returned by a conditional request when the content has not changed
+	ErrNotModified = errors.New("not modified")
 	// ErrNotRetryable indicates the process cannot be retried
 	ErrNotRetryable = errors.New("not retryable")
 	// ErrParsingFailed when a string cannot be parsed
 	ErrParsingFailed = errors.New("parsing failed")
+	// ErrPinMismatch when a pinned reference resolves to a different digest, this also wraps ErrDigestMismatch
+	ErrPinMismatch = fmt.Errorf("digest does not match pin%.0w", ErrDigestMismatch)
 	// ErrRetryNeeded indicates a request needs to be retried
 	ErrRetryNeeded = errors.New("retry needed")
 	// ErrRetryLimitExceeded indicates too many retries have occurred
@@ -70,6 +76,10 @@ var (
 	ErrShortRead = errors.New("short read")
 	// ErrSizeLimitExceeded if contents exceed the size limit
 	ErrSizeLimitExceeded = errors.New("size limit exceeded")
+	// ErrTagExists when a tag already exists and would be replaced
+	ErrTagExists = errors.New("tag exists")
+	// ErrTagImmutable when a tag matches an immutable tag pattern and the request would replace or delete it
+	ErrTagImmutable = errors.New("tag is immutable")
 	// ErrUnavailable when a requested value is not available
 	ErrUnavailable = errors.New("unavailable")
 	// ErrUnsupported indicates the request was unsupported
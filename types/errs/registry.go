@@ -0,0 +1,86 @@
+package errs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrorCode is a code returned by a registry in the body of a failed request.
+type ErrorCode string
+
+// error codes defined by the OCI distribution-spec
+const (
+	ErrorCodeBlobUnknown         ErrorCode = "BLOB_UNKNOWN"
+	ErrorCodeBlobUploadInvalid   ErrorCode = "BLOB_UPLOAD_INVALID"
+	ErrorCodeBlobUploadUnknown   ErrorCode = "BLOB_UPLOAD_UNKNOWN"
+	ErrorCodeDenied              ErrorCode = "DENIED"
+	ErrorCodeDigestInvalid       ErrorCode = "DIGEST_INVALID"
+	ErrorCodeManifestBlobUnknown ErrorCode = "MANIFEST_BLOB_UNKNOWN"
+	ErrorCodeManifestInvalid     ErrorCode = "MANIFEST_INVALID"
+	ErrorCodeManifestUnknown     ErrorCode = "MANIFEST_UNKNOWN"
+	ErrorCodeNameInvalid         ErrorCode = "NAME_INVALID"
+	ErrorCodeNameUnknown         ErrorCode = "NAME_UNKNOWN"
+	ErrorCodeSizeInvalid         ErrorCode = "SIZE_INVALID"
+	ErrorCodeTooManyRequests     ErrorCode = "TOOMANYREQUESTS"
+	ErrorCodeUnauthorized        ErrorCode = "UNAUTHORIZED"
+	ErrorCodeUnsupported         ErrorCode = "UNSUPPORTED"
+)
+
+// RegistryErrorDetail is a single entry from the errors list in a registry response.
+type RegistryErrorDetail struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message,omitempty"`
+	Detail  any       `json:"detail,omitempty"`
+}
+
+// RegistryError is returned when a registry responds to a request with an error status.
+// Err is the error for the HTTP status (e.g. [ErrNotFound] or [ErrHTTPRateLimit]), allowing [errors.Is] to be used on the result.
+// Use [errors.As] to access the status, request ID, and error codes.
+type RegistryError struct {
+	StatusCode int                   // StatusCode is the HTTP status of the response.
+	RequestID  string                // RequestID is the request identifier returned by the registry, if any.
+	Errors     []RegistryErrorDetail // Errors are the parsed errors from the response body.
+	Body       string                // Body is the response body when it could not be parsed as a list of errors.
+	Err        error                 // Err is the error for the HTTP status.
+}
+
+func (e *RegistryError) Error() string {
+	msg := ""
+	if e.Err != nil {
+		msg = e.Err.Error()
+	} else {
+		msg = fmt.Sprintf("http %d", e.StatusCode)
+	}
+	if len(e.Errors) > 0 {
+		details := make([]string, 0, len(e.Errors))
+		for _, d := range e.Errors {
+			if d.Message != "" {
+				details = append(details, string(d.Code)+": "+d.Message)
+			} else {
+				details = append(details, string(d.Code))
+			}
+		}
+		msg += ": " + strings.Join(details, ", ")
+	} else if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	if e.RequestID != "" {
+		msg += " [request id " + e.RequestID + "]"
+	}
+	return msg
+}
+
+func (e *RegistryError) Unwrap() error {
+	return e.Err
+}
+
+// HasCode returns true if the registry returned any of the error codes.
+func (e *RegistryError) HasCode(codes ...ErrorCode) bool {
+	for _, d := range e.Errors {
+		if slices.Contains(codes, d.Code) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package manifest
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	digest "github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/types"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/ref"
+)
+
+// GetBaseImage returns the base image reference and digest from the annotations.
+// The digest is empty when only the base image name is annotated.
+func GetBaseImage(m Manifest) (ref.Ref, digest.Digest, error) {
+	annot, err := getAnnotations(m)
+	if err != nil {
+		return ref.Ref{}, "", err
+	}
+	name, ok := annot[types.AnnotationBaseImageName]
+	if !ok || name == "" {
+		return ref.Ref{}, "", fmt.Errorf("annotation %s is missing%.0w", types.AnnotationBaseImageName, errs.ErrMissingAnnotation)
+	}
+	r, err := ref.New(name)
+	if err != nil {
+		return ref.Ref{}, "", fmt.Errorf("failed to parse annotation %s: %w", types.AnnotationBaseImageName, err)
+	}
+	var dig digest.Digest
+	if s, ok := annot[types.AnnotationBaseImageDigest]; ok && s != "" {
+		dig, err = digest.Parse(s)
+		if err != nil {
+			return r, "", fmt.Errorf("failed to parse annotation %s: %w", types.AnnotationBaseImageDigest, err)
+		}
+	}
+	return r, dig, nil
+}
+
+// SetBaseImage sets the base image reference and digest annotations.
+// The digest defaults to the digest of the reference, and is required.
+// A reference without a registry or repository removes both annotations.
+func SetBaseImage(m Manifest, r ref.Ref, dig digest.Digest) error {
+	if !r.IsSetRepo() {
+		return setAnnotations(m, map[string]string{
+			types.AnnotationBaseImageName:   "",
+			types.AnnotationBaseImageDigest: "",
+		})
+	}
+	if r.Tag == "" && r.Digest == "" {
+		return fmt.Errorf("base image %s%.0w", r.CommonName(), errs.ErrMissingTagOrDigest)
+	}
+	if dig == "" && r.Digest != "" {
+		dig = digest.Digest(r.Digest)
+	}
+	if dig == "" {
+		return fmt.Errorf("base image %s%.0w", r.CommonName(), errs.ErrMissingDigest)
+	}
+	if err := dig.Validate(); err != nil {
+		return fmt.Errorf("invalid base image digest %s: %w", dig.String(), err)
+	}
+	return setAnnotations(m, map[string]string{
+		types.AnnotationBaseImageName:   r.CommonName(),
+		types.AnnotationBaseImageDigest: dig.String(),
+	})
+}
+
+// GetCreated returns the created time from the annotations.
+func GetCreated(m Manifest) (time.Time, error) {
+	s, err := getAnnotation(m, types.AnnotationCreated)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse annotation %s: %w", types.AnnotationCreated, err)
+	}
+	return t, nil
+}
+
+// SetCreated sets the created annotation using the RFC 3339 format.
+// A zero time removes the annotation.
+func SetCreated(m Manifest, t time.Time) error {
+	val := ""
+	if !t.IsZero() {
+		val = t.UTC().Format(time.RFC3339)
+	}
+	return setAnnotations(m, map[string]string{types.AnnotationCreated: val})
+}
+
+// GetSource returns the URL of the source code from the annotations.
+func GetSource(m Manifest) (string, error) {
+	return getAnnotation(m, types.AnnotationSource)
+}
+
+// SetSource sets the source annotation, which must be an absolute URL.
+// An empty string removes the annotation.
+func SetSource(m Manifest, source string) error {
+	if source != "" {
+		u, err := url.Parse(source)
+		if err != nil {
+			return fmt.Errorf("failed to parse source %s: %w", source, err)
+		}
+		if !u.IsAbs() || u.Host == "" {
+			return fmt.Errorf("source must be an absolute URL: %s%.0w", source, errs.ErrParsingFailed)
+		}
+	}
+	return setAnnotations(m, map[string]string{types.AnnotationSource: source})
+}
+
+// GetRevision returns the source control revision from the annotations.
+func GetRevision(m Manifest) (string, error) {
+	return getAnnotation(m, types.AnnotationRevision)
+}
+
+// SetRevision sets the source control revision annotation, which may not contain whitespace.
+// An empty string removes the annotation.
+func SetRevision(m Manifest, revision string) error {
+	if strings.IndexFunc(revision, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return fmt.Errorf("revision may not contain whitespace: %q%.0w", revision, errs.ErrParsingFailed)
+	}
+	return setAnnotations(m, map[string]string{types.AnnotationRevision: revision})
+}
+
+func getAnnotations(m Manifest) (map[string]string, error) {
+	ma, ok := m.(Annotator)
+	if !ok {
+		return nil, fmt.Errorf("annotations not supported for media type %s%.0w", m.GetDescriptor().MediaType, errs.ErrUnsupportedMediaType)
+	}
+	return ma.GetAnnotations()
+}
+
+func getAnnotation(m Manifest, key string) (string, error) {
+	annot, err := getAnnotations(m)
+	if err != nil {
+		return "", err
+	}
+	val, ok := annot[key]
+	if !ok || val == "" {
+		return "", fmt.Errorf("annotation %s is missing%.0w", key, errs.ErrMissingAnnotation)
+	}
+	return val, nil
+}
+
+// setAnnotations sets each annotation in key order, an empty value deletes the annotation.
+func setAnnotations(m Manifest, annot map[string]string) error {
+	ma, ok := m.(Annotator)
+	if !ok {
+		return fmt.Errorf("annotations not supported for media type %s%.0w", m.GetDescriptor().MediaType, errs.ErrUnsupportedMediaType)
+	}
+	for _, key := range slices.Sorted(maps.Keys(annot)) {
+		err := ma.SetAnnotation(key, annot[key])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package manifest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+
+	"github.com/regclient/regclient/types"
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/docker/schema1"
+	"github.com/regclient/regclient/types/errs"
+	"github.com/regclient/regclient/types/mediatype"
+	v1 "github.com/regclient/regclient/types/oci/v1"
+	"github.com/regclient/regclient/types/ref"
+)
+
+func TestAnnotations(t *testing.T) {
+	t.Parallel()
+	baseDig := digest.FromString("base image")
+	baseRef, err := ref.New("registry.example.org/base:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	created := time.Date(2024, time.March, 4, 5, 6, 7, 0, time.UTC)
+	newM := func(t *testing.T) Manifest {
+		t.Helper()
+		m, err := New(WithOrig(v1.Manifest{
+			Versioned: v1.ManifestSchemaVersion,
+			MediaType: mediatype.OCI1Manifest,
+			Config: descriptor.Descriptor{
+				MediaType: mediatype.OCI1ImageConfig,
+				Digest:    digest.FromString("config"),
+				Size:      6,
+			},
+			Layers: []descriptor.Descriptor{},
+		}))
+		if err != nil {
+			t.Fatalf("failed to create manifest: %v", err)
+		}
+		return m
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		m := newM(t)
+		if _, _, err := GetBaseImage(m); !errors.Is(err, errs.ErrMissingAnnotation) {
+			t.Errorf("base image: expected missing annotation, received %v", err)
+		}
+		if _, err := GetCreated(m); !errors.Is(err, errs.ErrMissingAnnotation) {
+			t.Errorf("created: expected missing annotation, received %v", err)
+		}
+		if _, err := GetSource(m); !errors.Is(err, errs.ErrMissingAnnotation) {
+			t.Errorf("source: expected missing annotation, received %v", err)
+		}
+		if _, err := GetRevision(m); !errors.Is(err, errs.ErrMissingAnnotation) {
+			t.Errorf("revision: expected missing annotation, received %v", err)
+		}
+	})
+
+	t.Run("set and get", func(t *testing.T) {
+		m := newM(t)
+		origDig := m.GetDescriptor().Digest
+		if err := SetBaseImage(m, baseRef, baseDig); err != nil {
+			t.Fatalf("failed to set base image: %v", err)
+		}
+		if err := SetCreated(m, created.In(time.FixedZone("test", 3600))); err != nil {
+			t.Fatalf("failed to set created: %v", err)
+		}
+		if err := SetSource(m, "https://github.com/regclient/regclient"); err != nil {
+			t.Fatalf("failed to set source: %v", err)
+		}
+		if err := SetRevision(m, "0123abcd"); err != nil {
+			t.Fatalf("failed to set revision: %v", err)
+		}
+		if m.GetDescriptor().Digest == origDig {
+			t.Errorf("digest was not updated")
+		}
+		annot, err := m.(Annotator).GetAnnotations()
+		if err != nil {
+			t.Fatalf("failed to get annotations: %v", err)
+		}
+		if annot[types.AnnotationCreated] != "2024-03-04T05:06:07Z" {
+			t.Errorf("unexpected created annotation: %s", annot[types.AnnotationCreated])
+		}
+		r, d, err := GetBaseImage(m)
+		if err != nil {
+			t.Fatalf("failed to get base image: %v", err)
+		}
+		if r.CommonName() != baseRef.CommonName() || d != baseDig {
+			t.Errorf("unexpected base image, expected %s@%s, received %s@%s", baseRef.CommonName(), baseDig, r.CommonName(), d)
+		}
+		c, err := GetCreated(m)
+		if err != nil || !c.Equal(created) {
+			t.Errorf("unexpected created, expected %s, received %s, %v", created, c, err)
+		}
+		s, err := GetSource(m)
+		if err != nil || s != "https://github.com/regclient/regclient" {
+			t.Errorf("unexpected source %s, %v", s, err)
+		}
+		rev, err := GetRevision(m)
+		if err != nil || rev != "0123abcd" {
+			t.Errorf("unexpected revision %s, %v", rev, err)
+		}
+		// empty values remove the annotations
+		if err := SetBaseImage(m, ref.Ref{}, ""); err != nil {
+			t.Errorf("failed to remove base image: %v", err)
+		}
+		if err := SetCreated(m, time.Time{}); err != nil {
+			t.Errorf("failed to remove created: %v", err)
+		}
+		if err := SetSource(m, ""); err != nil {
+			t.Errorf("failed to remove source: %v", err)
+		}
+		if err := SetRevision(m, ""); err != nil {
+			t.Errorf("failed to remove revision: %v", err)
+		}
+		if m.GetDescriptor().Digest != origDig {
+			t.Errorf("digest did not return to the original value")
+		}
+	})
+
+	t.Run("base digest from ref", func(t *testing.T) {
+		m := newM(t)
+		if err := SetBaseImage(m, baseRef.SetDigest(baseDig.String()), ""); err != nil {
+			t.Fatalf("failed to set base image: %v", err)
+		}
+		_, d, err := GetBaseImage(m)
+		if err != nil || d != baseDig {
+			t.Errorf("unexpected base digest %s, %v", d, err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		m := newM(t)
+		tt := []struct {
+			name   string
+			set    func() error
+			expErr error
+		}{
+			{
+				name:   "base without digest",
+				set:    func() error { return SetBaseImage(m, baseRef, "") },
+				expErr: errs.ErrMissingDigest,
+			},
+			{
+				name:   "base without tag",
+				set:    func() error { return SetBaseImage(m, baseRef.SetTag(""), baseDig) },
+				expErr: errs.ErrMissingTagOrDigest,
+			},
+			{
+				name:   "base invalid digest",
+				set:    func() error { return SetBaseImage(m, baseRef, "sha256:abc") },
+				expErr: digest.ErrDigestInvalidLength,
+			},
+			{
+				name:   "relative source",
+				set:    func() error { return SetSource(m, "github.com/regclient/regclient") },
+				expErr: errs.ErrParsingFailed,
+			},
+			{
+				name:   "revision with space",
+				set:    func() error { return SetRevision(m, "abc def") },
+				expErr: errs.ErrParsingFailed,
+			},
+		}
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				err := tc.set()
+				if !errors.Is(err, tc.expErr) {
+					t.Errorf("expected error %v, received %v", tc.expErr, err)
+				}
+			})
+		}
+		annot, _ := m.(Annotator).GetAnnotations()
+		if len(annot) > 0 {
+			t.Errorf("annotations were set on invalid input: %v", annot)
+		}
+	})
+
+	t.Run("invalid annotation", func(t *testing.T) {
+		m := newM(t)
+		ma := m.(Annotator)
+		_ = ma.SetAnnotation(types.AnnotationBaseImageName, "registry.example.org/base:v1")
+		_ = ma.SetAnnotation(types.AnnotationBaseImageDigest, "invalid")
+		_ = ma.SetAnnotation(types.AnnotationCreated, "yesterday")
+		if _, _, err := GetBaseImage(m); err == nil {
+			t.Errorf("invalid base digest did not fail")
+		}
+		if _, err := GetCreated(m); err == nil {
+			t.Errorf("invalid created did not fail")
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		m, err := New(WithOrig(schema1.Manifest{}))
+		if err != nil {
+			t.Fatalf("failed to create manifest: %v", err)
+		}
+		if _, _, err := GetBaseImage(m); !errors.Is(err, errs.ErrUnsupportedMediaType) {
+			t.Errorf("expected unsupported media type, received %v", err)
+		}
+		if err := SetRevision(m, "abc"); !errors.Is(err, errs.ErrUnsupportedMediaType) {
+			t.Errorf("expected unsupported media type, received %v", err)
+		}
+	})
+}
@@ -4,6 +4,7 @@ package ping
 import (
 	"io/fs"
 	"net/http"
+	"time"
 )
 
 // Result is the response to a ping request.
@@ -11,3 +12,36 @@ type Result struct {
 	Header http.Header // Header is defined for responses from a registry.
 	Stat   fs.FileInfo // Stat is defined for responses from an ocidir.
 }
+
+// Auth describes the authentication used with a registry.
+// Fields from the token are only set when the registry returns a JWT that can be parsed.
+type Auth struct {
+	Type    string       `json:"type,omitempty"`    // Type is the auth scheme, e.g. "basic" or "bearer", empty when the registry did not request auth.
+	User    string       `json:"user,omitempty"`    // User is the login sent to the registry or token server.
+	Subject string       `json:"subject,omitempty"` // Subject is the user identified in the token.
+	Scopes  []string     `json:"scopes,omitempty"`  // Scopes are the scopes requested for the token.
+	Access  []AuthAccess `json:"access,omitempty"`  // Access lists the actions granted in the token.
+	Expires time.Time    `json:"expires,omitzero"`  // Expires is when the token expires.
+}
+
+// AuthAccess is a resource and the actions granted on it.
+type AuthAccess struct {
+	Type    string   `json:"type"`    // Type of the resource, e.g. "repository".
+	Name    string   `json:"name"`    // Name of the resource, e.g. the repository name.
+	Actions []string `json:"actions"` // Actions granted, e.g. "pull" or "push".
+}
+
+// Allowed returns true if the token grants the action on the resource.
+func (a Auth) Allowed(resType, name, action string) bool {
+	for _, access := range a.Access {
+		if access.Type != resType || access.Name != name {
+			continue
+		}
+		for _, act := range access.Actions {
+			if act == action || act == "*" {
+				return true
+			}
+		}
+	}
+	return false
+}
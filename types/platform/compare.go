@@ -136,8 +136,10 @@ func Match(a, b Platform) bool {
 	return comp.Match(b)
 }
 
+// osVerCompatible compares the major, minor, and build of the Windows OS version.
+// The update revision is ignored, and an unknown version on either side is treated as compatible.
 func osVerCompatible(host, target string) bool {
-	if host == "" {
+	if host == "" || target == "" {
 		return true
 	}
 	vHost := osVerSemver(host)
@@ -105,6 +105,24 @@ func TestCompare(t *testing.T) {
 			expectCompat: false,
 			expectBetter: false,
 		},
+		{
+			name:         "windows host undef",
+			host:         Platform{OS: "windows", Architecture: "amd64"},
+			target:       Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.20348.2113"},
+			prev:         Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.17763.2114"},
+			expectMatch:  false,
+			expectCompat: true,
+			expectBetter: true,
+		},
+		{
+			name:         "windows target undef",
+			host:         Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.20348.2113"},
+			target:       Platform{OS: "windows", Architecture: "amd64"},
+			prev:         Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.20348.1906"},
+			expectMatch:  false,
+			expectCompat: true,
+			expectBetter: false,
+		},
 		{
 			name:         "darwin compatible",
 			host:         Platform{OS: "darwin", Architecture: "amd64", Variant: "v2"},
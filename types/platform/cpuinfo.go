@@ -8,7 +8,10 @@
 package platform
 
 import (
+	"bufio"
+	"io"
 	"runtime"
+	"strings"
 	"sync"
 )
 
@@ -28,3 +31,59 @@ func cpuVariant() string {
 	})
 	return cpuVariantValue
 }
+
+// cpuInfoValue returns the value of the first field matching pattern in /proc/cpuinfo formatted content.
+// For SMP SoC, parsing the first core is enough.
+func cpuInfoValue(rdr io.Reader, pattern string) string {
+	scanner := bufio.NewScanner(rdr)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), pattern) {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// armVariant converts the "CPU architecture" and "model name" fields reported by the Linux kernel to an ARM variant.
+// An empty string is returned when the variant is unknown.
+func armVariant(goarch, cpuArch, model string) string {
+	// handle edge case for Raspberry Pi ARMv6 devices (which due to a kernel quirk, report "CPU architecture: 7")
+	// https://www.raspberrypi.org/forums/viewtopic.php?t=12614
+	if goarch == "arm" && cpuArch == "7" && strings.HasPrefix(strings.ToLower(model), "armv6-compatible") {
+		cpuArch = "6"
+	}
+
+	switch strings.ToLower(cpuArch) {
+	case "8", "aarch64":
+		return "v8"
+	case "7", "7m", "?(12)", "?(13)", "?(14)", "?(15)", "?(16)", "?(17)":
+		return "v7"
+	case "6", "6tej":
+		return "v6"
+	case "5", "5t", "5te", "5tej":
+		return "v5"
+	case "4", "4t":
+		return "v4"
+	case "3":
+		return "v3"
+	}
+	return ""
+}
+
+// armVariantFallback returns the minimum variant the running binary supports when the CPU cannot be queried.
+// On ARM32 this is based on the GOARM value used to build the binary (e.g. "7" or "6,softfloat").
+func armVariantFallback(goarch, goarm string) string {
+	switch goarch {
+	case "arm64":
+		return "v8"
+	case "arm":
+		ver, _, _ := strings.Cut(goarm, ",")
+		switch ver {
+		case "5", "6", "7":
+			return "v" + ver
+		}
+		return "v7"
+	}
+	return ""
+}
@@ -3,10 +3,9 @@
 package platform
 
 import (
-	"bufio"
 	"os"
 	"runtime"
-	"strings"
+	"runtime/debug"
 )
 
 func lookupCPUVariant() string {
@@ -22,35 +21,25 @@ func lookupCPUVariant() string {
 		return ""
 	}
 
-	variant := getCPUInfo("Cpu architecture")
-
-	// handle edge case for Raspberry Pi ARMv6 devices (which due to a kernel quirk, report "CPU architecture: 7")
-	// https://www.raspberrypi.org/forums/viewtopic.php?t=12614
-	if runtime.GOARCH == "arm" && variant == "7" {
-		model := getCPUInfo("model name")
-		if strings.HasPrefix(strings.ToLower(model), "armv6-compatible") {
-			variant = "6"
-		}
+	variant := armVariant(runtime.GOARCH, getCPUInfo("Cpu architecture"), getCPUInfo("model name"))
+	if variant == "" {
+		variant = armVariantFallback(runtime.GOARCH, goarm())
 	}
+	return variant
+}
 
-	switch strings.ToLower(variant) {
-	case "8", "aarch64":
-		variant = "v8"
-	case "7", "7m", "?(12)", "?(13)", "?(14)", "?(15)", "?(16)", "?(17)":
-		variant = "v7"
-	case "6", "6tej":
-		variant = "v6"
-	case "5", "5t", "5te", "5tej":
-		variant = "v5"
-	case "4", "4t":
-		variant = "v4"
-	case "3":
-		variant = "v3"
-	default:
-		variant = ""
+// goarm returns the GOARM value used to build the running binary.
+func goarm() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
 	}
-
-	return variant
+	for _, setting := range bi.Settings {
+		if setting.Key == "GOARM" {
+			return setting.Value
+		}
+	}
+	return ""
 }
 
 // For Linux, the kernel has already detected the ABI, ISA and Features.
@@ -67,16 +56,5 @@ func getCPUInfo(pattern string) (info string) {
 	}
 	defer cpuinfo.Close()
 
-	// Start to Parse the Cpuinfo line by line. For SMP SoC, we parse
-	// the first core is enough.
-	scanner := bufio.NewScanner(cpuinfo)
-	for scanner.Scan() {
-		newline := scanner.Text()
-		list := strings.Split(newline, ":")
-
-		if len(list) > 1 && strings.EqualFold(strings.TrimSpace(list[0]), pattern) {
-			return strings.TrimSpace(list[1])
-		}
-	}
-	return ""
+	return cpuInfoValue(cpuinfo, pattern)
 }
@@ -0,0 +1,123 @@
+package platform
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCPUInfoValue(t *testing.T) {
+	t.Parallel()
+	cpuinfo := `processor	: 0
+model name	: ARMv6-compatible processor rev 7 (v6l)
+BogoMIPS	: 697.95
+CPU architecture: 7
+
+processor	: 1
+CPU architecture: 8
+`
+	tt := []struct {
+		name    string
+		pattern string
+		expect  string
+	}{
+		{
+			name:    "first match",
+			pattern: "CPU architecture",
+			expect:  "7",
+		},
+		{
+			name:    "case insensitive",
+			pattern: "Cpu architecture",
+			expect:  "7",
+		},
+		{
+			name:    "tab separated",
+			pattern: "model name",
+			expect:  "ARMv6-compatible processor rev 7 (v6l)",
+		},
+		{
+			name:    "missing",
+			pattern: "Features",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			result := cpuInfoValue(strings.NewReader(cpuinfo), tc.pattern)
+			if result != tc.expect {
+				t.Errorf("unexpected value, expected %q, received %q", tc.expect, result)
+			}
+		})
+	}
+}
+
+func TestARMVariant(t *testing.T) {
+	t.Parallel()
+	tt := []struct {
+		name    string
+		goarch  string
+		cpuArch string
+		model   string
+		goarm   string
+		expect  string
+	}{
+		{
+			name:    "arm64",
+			goarch:  "arm64",
+			cpuArch: "8",
+			expect:  "v8",
+		},
+		{
+			name:    "arm64 aarch64",
+			goarch:  "arm64",
+			cpuArch: "AArch64",
+			expect:  "v8",
+		},
+		{
+			name:    "arm v7",
+			goarch:  "arm",
+			cpuArch: "7",
+			model:   "ARMv7 Processor rev 4 (v7l)",
+			expect:  "v7",
+		},
+		{
+			name:    "arm on arm64 kernel",
+			goarch:  "arm",
+			cpuArch: "8",
+			expect:  "v8",
+		},
+		{
+			name:    "raspberry pi v6",
+			goarch:  "arm",
+			cpuArch: "7",
+			model:   "ARMv6-compatible processor rev 7 (v6l)",
+			expect:  "v6",
+		},
+		{
+			name:   "arm fallback goarm",
+			goarch: "arm",
+			goarm:  "6,softfloat",
+			expect: "v6",
+		},
+		{
+			name:   "arm fallback default",
+			goarch: "arm",
+			expect: "v7",
+		},
+		{
+			name:   "arm64 fallback",
+			goarch: "arm64",
+			expect: "v8",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			result := armVariant(tc.goarch, tc.cpuArch, tc.model)
+			if result == "" {
+				result = armVariantFallback(tc.goarch, tc.goarm)
+			}
+			if result != tc.expect {
+				t.Errorf("unexpected variant, expected %q, received %q", tc.expect, result)
+			}
+		})
+	}
+}
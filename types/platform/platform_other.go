@@ -4,7 +4,7 @@ package platform
 
 import "runtime"
 
-// Local retrieves the local platform details
+// Local retrieves the local platform details, including the CPU variant.
 func Local() Platform {
 	plat := Platform{
 		OS:           runtime.GOOS,
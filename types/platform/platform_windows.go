@@ -7,17 +7,37 @@ import (
 	"runtime"
 
 	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
 )
 
-// Local retrieves the local platform details
+// Local retrieves the local platform details.
+// The OSVersion includes the update build revision when available (e.g. 10.0.20348.2113), matching the os.version of Windows images.
 func Local() Platform {
 	major, minor, build := windows.RtlGetNtVersionNumbers()
+	osVer := fmt.Sprintf("%d.%d.%d", major, minor, build)
+	if ubr, ok := windowsUBR(); ok {
+		osVer = fmt.Sprintf("%s.%d", osVer, ubr)
+	}
 	plat := Platform{
 		OS:           runtime.GOOS,
 		Architecture: runtime.GOARCH,
 		Variant:      cpuVariant(),
-		OSVersion:    fmt.Sprintf("%d.%d.%d", major, minor, build),
+		OSVersion:    osVer,
 	}
 	plat.normalize()
 	return plat
 }
+
+// windowsUBR returns the update build revision from the registry.
+func windowsUBR() (uint64, bool) {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows NT\CurrentVersion`, registry.QUERY_VALUE)
+	if err != nil {
+		return 0, false
+	}
+	defer k.Close()
+	ubr, _, err := k.GetIntegerValue("UBR")
+	if err != nil {
+		return 0, false
+	}
+	return ubr, true
+}
@@ -26,11 +26,15 @@ var (
 	hostPartS = `(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)`
 	portS     = `(?:` + regexp.QuoteMeta(`:`) + `[0-9]+)`
 	ipv6PartS = `(?:[0-9a-fA-F]{1,4}:){0,7}[0-9a-fA-F]{1,4}`
+	ipv4S     = `(?:[0-9]{1,3}` + regexp.QuoteMeta(`.`) + `){3}[0-9]{1,3}`
 	ipv6S     = `(?:` + regexp.QuoteMeta(`[`) + `(?:` +
 		ipv6PartS + `|` + // uncompressed
 		regexp.QuoteMeta(`::`) + ipv6PartS + `|` + // prefix compressed
 		ipv6PartS + regexp.QuoteMeta(`::`) + ipv6PartS + `|` + // middle compressed
-		ipv6PartS + regexp.QuoteMeta(`::`) + // suffix compressed
+		ipv6PartS + regexp.QuoteMeta(`::`) + `|` + // suffix compressed
+		regexp.QuoteMeta(`::`) + `|` + // unspecified
+		`(?:` + ipv6PartS + `)?` + regexp.QuoteMeta(`::`) + `(?:[0-9a-fA-F]{1,4}:){0,5}` + ipv4S + `|` + // compressed with embedded IPv4
+		`(?:[0-9a-fA-F]{1,4}:){6}` + ipv4S + // uncompressed with embedded IPv4
 		`)` + regexp.QuoteMeta(`]`) + `)`
 	localhostS  = `localhost`
 	hostDomainS = `(?:` + hostPartS + `(?:(?:` + regexp.QuoteMeta(`.`) + hostPartS + `)+` + regexp.QuoteMeta(`.`) + `?|` + regexp.QuoteMeta(`.`) + `))`
@@ -49,6 +53,8 @@ var (
 		`(` + repoPartS + `(?:` + regexp.QuoteMeta(`/`) + repoPartS + `)*)` +
 		`(?:` + regexp.QuoteMeta(`:`) + `(` + tagS + `))?` +
 		`(?:` + regexp.QuoteMeta(`@`) + `(` + digestS + `))?$`)
+	repoRE   = regexp.MustCompile(`^` + repoPartS + `(?:` + regexp.QuoteMeta(`/`) + repoPartS + `)*$`)
+	pathRE   = regexp.MustCompile(`^` + pathS + `$`)
 	ocidirRE = regexp.MustCompile(`^(` + pathS + `)` +
 		`(?:` + regexp.QuoteMeta(`:`) + `(` + tagS + `))?` +
 		`(?:` + regexp.QuoteMeta(`@`) + `(` + digestS + `))?$`)
@@ -66,8 +72,76 @@ type Ref struct {
 	Path       string // Path is the directory of the OCI Layout for "ocidir".
 }
 
+// Normalized reports the defaults applied when parsing a "reg" reference.
+type Normalized struct {
+	Registry      bool   // Registry is true when the registry was not included and "docker.io" was added.
+	RegistryAlias string // RegistryAlias is the Docker Hub hostname that was replaced with "docker.io".
+	Library       bool   // Library is true when "library/" was added to a Docker Hub repository.
+	Tag           bool   // Tag is true when neither a tag nor digest was included and "latest" was added.
+}
+
+// Changed returns true when any default was applied.
+func (n Normalized) Changed() bool {
+	return n.Registry || n.RegistryAlias != "" || n.Library || n.Tag
+}
+
+// List returns a description of each default that was applied.
+func (n Normalized) List() []string {
+	list := []string{}
+	if n.Registry {
+		list = append(list, "added registry "+dockerRegistry)
+	}
+	if n.RegistryAlias != "" {
+		list = append(list, "replaced registry "+n.RegistryAlias+" with "+dockerRegistry)
+	}
+	if n.Library {
+		list = append(list, "added repository prefix "+dockerLibrary+"/")
+	}
+	if n.Tag {
+		list = append(list, "added tag latest")
+	}
+	return list
+}
+
+// NormalizeOpts configures [NewNormalized].
+type NormalizeOpts func(*normalizeConfig)
+
+type normalizeConfig struct {
+	strict bool
+}
+
+// WithNormalizeStrict rejects references that depend on any default, like an implicit registry or tag.
+func WithNormalizeStrict() NormalizeOpts {
+	return func(nc *normalizeConfig) {
+		nc.strict = true
+	}
+}
+
 // New returns a reference based on the scheme (defaulting to "reg").
 func New(parse string) (Ref, error) {
+	r, _, err := newRef(parse)
+	return r, err
+}
+
+// NewNormalized returns a reference along with a report of the defaults applied.
+// Docker style shorthand, like "alpine" for "docker.io/library/alpine:latest", is accepted unless [WithNormalizeStrict] is set.
+func NewNormalized(parse string, opts ...NormalizeOpts) (Ref, Normalized, error) {
+	nc := normalizeConfig{}
+	for _, opt := range opts {
+		opt(&nc)
+	}
+	r, n, err := newRef(parse)
+	if err != nil {
+		return r, n, err
+	}
+	if nc.strict && n.Changed() {
+		return Ref{}, n, fmt.Errorf("%w \"%s\", strict parsing does not allow shorthand: %s", errs.ErrInvalidReference, parse, strings.Join(n.List(), ", "))
+	}
+	return r, n, nil
+}
+
+func newRef(parse string) (Ref, Normalized, error) {
+	n := Normalized{}
 	scheme := ""
 	tail := parse
 	matchScheme := schemeRE.FindStringSubmatch(parse)
@@ -85,9 +159,9 @@ func New(parse string) (Ref, error) {
 		matchRef := refRE.FindStringSubmatch(tail)
 		if len(matchRef) < 5 {
 			if refRE.FindStringSubmatch(strings.ToLower(tail)) != nil {
-				return Ref{}, fmt.Errorf("%w \"%s\", repo must be lowercase", errs.ErrInvalidReference, tail)
+				return Ref{}, n, fmt.Errorf("%w \"%s\", repo must be lowercase", errs.ErrInvalidReference, tail)
 			}
-			return Ref{}, fmt.Errorf("%w \"%s\"", errs.ErrInvalidReference, tail)
+			return Ref{}, n, fmt.Errorf("%w \"%s\"", errs.ErrInvalidReference, tail)
 		}
 		ret.Registry = matchRef[1]
 		ret.Repository = matchRef[2]
@@ -101,23 +175,29 @@ func New(parse string) (Ref, error) {
 			ret.Repository = strings.Join(repoPath[1:], "/")
 		}
 		switch ret.Registry {
-		case "", dockerRegistryDNS, dockerRegistryLegacy:
+		case "":
+			ret.Registry = dockerRegistry
+			n.Registry = true
+		case dockerRegistryDNS, dockerRegistryLegacy:
+			n.RegistryAlias = ret.Registry
 			ret.Registry = dockerRegistry
 		}
 		if ret.Registry == dockerRegistry && !strings.Contains(ret.Repository, "/") {
 			ret.Repository = dockerLibrary + "/" + ret.Repository
+			n.Library = true
 		}
 		if ret.Tag == "" && ret.Digest == "" {
 			ret.Tag = "latest"
+			n.Tag = true
 		}
 		if ret.Repository == "" {
-			return Ref{}, fmt.Errorf("%w \"%s\"", errs.ErrInvalidReference, tail)
+			return Ref{}, n, fmt.Errorf("%w \"%s\"", errs.ErrInvalidReference, tail)
 		}
 
 	case "ocidir", "ocifile":
 		matchPath := ocidirRE.FindStringSubmatch(tail)
 		if len(matchPath) < 2 || matchPath[1] == "" {
-			return Ref{}, fmt.Errorf("%w, invalid path for scheme \"%s\": %s", errs.ErrInvalidReference, scheme, tail)
+			return Ref{}, n, fmt.Errorf("%w, invalid path for scheme \"%s\": %s", errs.ErrInvalidReference, scheme, tail)
 		}
 		ret.Path = matchPath[1]
 		if len(matchPath) > 2 && matchPath[2] != "" {
@@ -128,9 +208,9 @@ func New(parse string) (Ref, error) {
 		}
 
 	default:
-		return Ref{}, fmt.Errorf("%w, unknown scheme \"%s\" in \"%s\"", errs.ErrInvalidReference, scheme, parse)
+		return Ref{}, n, fmt.Errorf("%w, unknown scheme \"%s\" in \"%s\"", errs.ErrInvalidReference, scheme, parse)
 	}
-	return ret, nil
+	return ret, n, nil
 }
 
 // NewHost returns a Reg for a registry hostname or equivalent.
@@ -279,6 +359,120 @@ func (r Ref) ToReg() Ref {
 	return r
 }
 
+// SetRegistry returns a ref with the registry replaced.
+// Docker Hub hostnames are normalized, matching the result of [New].
+// The reference value will be reset.
+func (r Ref) SetRegistry(registry string) (Ref, error) {
+	if r.Scheme != "reg" {
+		return r, fmt.Errorf("%w, registry cannot be set for scheme \"%s\"", errs.ErrInvalidReference, r.Scheme)
+	}
+	r.Registry = registry
+	return r.normalize()
+}
+
+// SetRepoPrefix returns a ref with the leading path oldPrefix of the repository replaced with newPrefix.
+// The prefix must match entire path components, and an empty newPrefix removes the oldPrefix.
+// The reference value will be reset.
+func (r Ref) SetRepoPrefix(oldPrefix, newPrefix string) (Ref, error) {
+	if r.Scheme != "reg" {
+		return r, fmt.Errorf("%w, repository prefix cannot be set for scheme \"%s\"", errs.ErrInvalidReference, r.Scheme)
+	}
+	oldPrefix = strings.Trim(oldPrefix, "/")
+	rest := r.Repository
+	if oldPrefix != "" {
+		var ok bool
+		if rest == oldPrefix {
+			rest = ""
+		} else if rest, ok = strings.CutPrefix(rest, oldPrefix+"/"); !ok {
+			return r, fmt.Errorf("%w, repository \"%s\" does not start with \"%s\"", errs.ErrInvalidReference, r.Repository, oldPrefix)
+		}
+	}
+	r.Repository = joinPath(newPrefix, rest)
+	if r.Repository == "" {
+		return r, fmt.Errorf("%w, repository is empty after replacing \"%s\"", errs.ErrInvalidReference, oldPrefix)
+	}
+	return r.normalize()
+}
+
+// AddPathPrefix returns a ref with prefix added to the start of the repository, or the path for "ocidir".
+// The reference value will be reset.
+func (r Ref) AddPathPrefix(prefix string) (Ref, error) {
+	switch r.Scheme {
+	case "reg":
+		r.Repository = joinPath(prefix, r.Repository)
+	case "ocidir", "ocifile":
+		r.Path = joinDir(prefix, r.Path)
+	default:
+		return r, fmt.Errorf("%w, unknown scheme \"%s\"", errs.ErrInvalidReference, r.Scheme)
+	}
+	return r.normalize()
+}
+
+// JoinPath returns a ref with each element appended to the repository, or the path for "ocidir".
+// Empty elements are skipped, and the reference value will be reset.
+func (r Ref) JoinPath(elem ...string) (Ref, error) {
+	switch r.Scheme {
+	case "reg":
+		r.Repository = joinPath(append([]string{r.Repository}, elem...)...)
+	case "ocidir", "ocifile":
+		r.Path = joinDir(append([]string{r.Path}, elem...)...)
+	default:
+		return r, fmt.Errorf("%w, unknown scheme \"%s\"", errs.ErrInvalidReference, r.Scheme)
+	}
+	return r.normalize()
+}
+
+// normalize validates a modified ref, applies the Docker Hub defaults, and resets the reference value.
+// An empty repository is allowed for refs created with [NewHost].
+func (r Ref) normalize() (Ref, error) {
+	switch r.Scheme {
+	case "reg":
+		if !registryRE.MatchString(r.Registry) {
+			return r, fmt.Errorf("%w, invalid registry \"%s\"", errs.ErrInvalidReference, r.Registry)
+		}
+		if r.Repository != "" && !repoRE.MatchString(r.Repository) {
+			return r, fmt.Errorf("%w, invalid repository \"%s\"", errs.ErrInvalidReference, r.Repository)
+		}
+		switch r.Registry {
+		case dockerRegistryDNS, dockerRegistryLegacy:
+			r.Registry = dockerRegistry
+		}
+		if r.Registry == dockerRegistry && r.Repository != "" && !strings.Contains(r.Repository, "/") {
+			r.Repository = dockerLibrary + "/" + r.Repository
+		}
+	case "ocidir", "ocifile":
+		if !pathRE.MatchString(r.Path) {
+			return r, fmt.Errorf("%w, invalid path \"%s\"", errs.ErrInvalidReference, r.Path)
+		}
+	}
+	r.Reference = r.CommonName()
+	return r, nil
+}
+
+// joinPath joins the non-empty elements with a "/", trimming any leading or trailing "/" from each.
+func joinPath(elem ...string) string {
+	parts := []string{}
+	for _, e := range elem {
+		if e = strings.Trim(e, "/"); e != "" {
+			parts = append(parts, e)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// joinDir joins elements like [joinPath], preserving the leading "/" of an absolute path.
+func joinDir(elem ...string) string {
+	for _, e := range elem {
+		if e != "" {
+			if strings.HasPrefix(e, "/") {
+				return "/" + joinPath(elem...)
+			}
+			break
+		}
+	}
+	return joinPath(elem...)
+}
+
 // EqualRegistry compares the registry between two references.
 func EqualRegistry(a, b Ref) bool {
 	if a.Scheme != b.Scheme {
@@ -254,6 +254,61 @@ func TestNew(t *testing.T) {
 			path:       "",
 			wantE:      nil,
 		},
+		{
+			name:       "ipv6 unspecified address registry",
+			ref:        "[::]:5000/image:v42",
+			scheme:     "reg",
+			registry:   "[::]:5000",
+			repository: "image",
+			tag:        "v42",
+			digest:     "",
+			path:       "",
+			wantE:      nil,
+		},
+		{
+			name:       "ipv6 mapped ipv4 address registry",
+			ref:        "[::ffff:192.168.0.10]:5000/project/image:v42",
+			scheme:     "reg",
+			registry:   "[::ffff:192.168.0.10]:5000",
+			repository: "project/image",
+			tag:        "v42",
+			digest:     "",
+			path:       "",
+			wantE:      nil,
+		},
+		{
+			name:       "ipv6 uncompressed embedded ipv4 registry",
+			ref:        "[0:0:0:0:0:ffff:10.0.0.1]/image",
+			scheme:     "reg",
+			registry:   "[0:0:0:0:0:ffff:10.0.0.1]",
+			repository: "image",
+			tag:        "latest",
+			digest:     "",
+			path:       "",
+			wantE:      nil,
+		},
+		{
+			name:       "Uppercase registry with port",
+			ref:        "Registry.Example.COM:5000/image:v42",
+			scheme:     "reg",
+			registry:   "Registry.Example.COM:5000",
+			repository: "image",
+			tag:        "v42",
+			digest:     "",
+			path:       "",
+			wantE:      nil,
+		},
+		{
+			name:       "Registry under a path",
+			ref:        "registry.example.com/artifactory/api/docker/repo/image:v42",
+			scheme:     "reg",
+			registry:   "registry.example.com",
+			repository: "artifactory/api/docker/repo/image",
+			tag:        "v42",
+			digest:     "",
+			path:       "",
+			wantE:      nil,
+		},
 		{
 			name:       "Port registry digest",
 			ref:        "registry:5000/group/image@" + testDigest,
@@ -367,6 +422,11 @@ func TestNew(t *testing.T) {
 			ref:   "[1::2::3]/project/image:tag",
 			wantE: errs.ErrInvalidReference,
 		},
+		{
+			name:  "invalid ipv6 embedded ipv4 position",
+			ref:   "[10.0.0.1::1]/project/image:tag",
+			wantE: errs.ErrInvalidReference,
+		},
 		{
 			name:  "invalid ipv6 missing brackets",
 			ref:   "::1/project/image:tag",
@@ -697,6 +757,101 @@ func TestNewHost(t *testing.T) {
 	}
 }
 
+func TestNewNormalized(t *testing.T) {
+	t.Parallel()
+	tt := []struct {
+		name    string
+		ref     string
+		strict  bool
+		expect  string
+		expectN Normalized
+		expectE error
+	}{
+		{
+			name:    "Docker library",
+			ref:     "alpine",
+			expect:  "docker.io/library/alpine:latest",
+			expectN: Normalized{Registry: true, Library: true, Tag: true},
+		},
+		{
+			name:    "Docker project",
+			ref:     "regclient/regctl:edge",
+			expect:  "docker.io/regclient/regctl:edge",
+			expectN: Normalized{Registry: true},
+		},
+		{
+			name:    "Docker legacy",
+			ref:     "index.docker.io/library/alpine@" + testDigest,
+			expect:  "docker.io/library/alpine@" + testDigest,
+			expectN: Normalized{RegistryAlias: "index.docker.io"},
+		},
+		{
+			name:   "fully qualified",
+			ref:    "docker.io/library/alpine:3",
+			strict: true,
+			expect: "docker.io/library/alpine:3",
+		},
+		{
+			name:    "other registry",
+			ref:     "registry.example.org/repo",
+			expect:  "registry.example.org/repo:latest",
+			expectN: Normalized{Tag: true},
+		},
+		{
+			name:   "ocidir",
+			ref:    "ocidir://path/to/dir",
+			strict: true,
+			expect: "ocidir://path/to/dir",
+		},
+		{
+			name:    "strict library",
+			ref:     "alpine:3",
+			strict:  true,
+			expectN: Normalized{Registry: true, Library: true},
+			expectE: errs.ErrInvalidReference,
+		},
+		{
+			name:    "strict tag",
+			ref:     "registry.example.org/repo",
+			strict:  true,
+			expectN: Normalized{Tag: true},
+			expectE: errs.ErrInvalidReference,
+		},
+		{
+			name:    "invalid",
+			ref:     "Invalid",
+			expectE: errs.ErrInvalidReference,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := []NormalizeOpts{}
+			if tc.strict {
+				opts = append(opts, WithNormalizeStrict())
+			}
+			r, n, err := NewNormalized(tc.ref, opts...)
+			if n != tc.expectN {
+				t.Errorf("normalized mismatch, expected %v, received %v", tc.expectN, n)
+			}
+			if tc.expectE != nil {
+				if !errors.Is(err, tc.expectE) {
+					t.Errorf("expected error %v, received %v", tc.expectE, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", tc.ref, err)
+			}
+			if r.CommonName() != tc.expect {
+				t.Errorf("ref mismatch, expected %s, received %s", tc.expect, r.CommonName())
+			}
+			if n.Changed() != (len(n.List()) > 0) {
+				t.Errorf("changed does not match list: %v", n.List())
+			}
+		})
+	}
+}
+
 func TestCommon(t *testing.T) {
 	t.Parallel()
 	tt := []struct {
@@ -1021,6 +1176,146 @@ func TestSetAndAdd(t *testing.T) {
 	}
 }
 
+func TestCompose(t *testing.T) {
+	t.Parallel()
+	tt := []struct {
+		name    string
+		ref     string
+		host    bool // parse ref with NewHost
+		fn      func(Ref) (Ref, error)
+		expect  string
+		expectE error
+	}{
+		{
+			name:   "set registry",
+			ref:    "alpine:3",
+			fn:     func(r Ref) (Ref, error) { return r.SetRegistry("mirror.example.org:5000") },
+			expect: "mirror.example.org:5000/library/alpine:3",
+		},
+		{
+			name:   "set registry docker hub",
+			ref:    "registry.example.org/alpine:3",
+			fn:     func(r Ref) (Ref, error) { return r.SetRegistry("index.docker.io") },
+			expect: "docker.io/library/alpine:3",
+		},
+		{
+			name:    "set registry invalid",
+			ref:     "alpine:3",
+			fn:      func(r Ref) (Ref, error) { return r.SetRegistry("invalid/host") },
+			expectE: errs.ErrInvalidReference,
+		},
+		{
+			name:    "set registry ocidir",
+			ref:     "ocidir://path:v1",
+			fn:      func(r Ref) (Ref, error) { return r.SetRegistry("registry.example.org") },
+			expectE: errs.ErrInvalidReference,
+		},
+		{
+			name:   "set repo prefix",
+			ref:    "registry.example.org/team/app/api:v1",
+			fn:     func(r Ref) (Ref, error) { return r.SetRepoPrefix("team/app", "mirror/") },
+			expect: "registry.example.org/mirror/api:v1",
+		},
+		{
+			name:   "remove repo prefix",
+			ref:    "registry.example.org/team/app:v1",
+			fn:     func(r Ref) (Ref, error) { return r.SetRepoPrefix("team", "") },
+			expect: "registry.example.org/app:v1",
+		},
+		{
+			name:   "remove library prefix on docker hub",
+			ref:    "alpine:3",
+			fn:     func(r Ref) (Ref, error) { return r.SetRepoPrefix("library", "") },
+			expect: "docker.io/library/alpine:3",
+		},
+		{
+			name:    "repo prefix partial component",
+			ref:     "registry.example.org/team/app:v1",
+			fn:      func(r Ref) (Ref, error) { return r.SetRepoPrefix("te", "mirror") },
+			expectE: errs.ErrInvalidReference,
+		},
+		{
+			name:    "repo prefix empty result",
+			ref:     "registry.example.org/team:v1",
+			fn:      func(r Ref) (Ref, error) { return r.SetRepoPrefix("team", "") },
+			expectE: errs.ErrInvalidReference,
+		},
+		{
+			name:   "add path prefix",
+			ref:    "registry.example.org/app@" + testDigest,
+			fn:     func(r Ref) (Ref, error) { return r.AddPathPrefix("/mirror/") },
+			expect: "registry.example.org/mirror/app@" + testDigest,
+		},
+		{
+			name:    "add path prefix invalid",
+			ref:     "registry.example.org/app:v1",
+			fn:      func(r Ref) (Ref, error) { return r.AddPathPrefix("Mirror") },
+			expectE: errs.ErrInvalidReference,
+		},
+		{
+			name:   "add path prefix ocidir",
+			ref:    "ocidir://app:v1",
+			fn:     func(r Ref) (Ref, error) { return r.AddPathPrefix("/var/lib/oci") },
+			expect: "ocidir:///var/lib/oci/app:v1",
+		},
+		{
+			name:   "join host",
+			ref:    "registry.example.org",
+			host:   true,
+			fn:     func(r Ref) (Ref, error) { return r.JoinPath("mirror", "", "team/app") },
+			expect: "registry.example.org/mirror/team/app",
+		},
+		{
+			name:   "join docker hub",
+			ref:    "docker.io",
+			host:   true,
+			fn:     func(r Ref) (Ref, error) { return r.JoinPath("alpine") },
+			expect: "docker.io/library/alpine",
+		},
+		{
+			name:   "join ocidir",
+			ref:    "ocidir:///tmp/oci",
+			host:   true,
+			fn:     func(r Ref) (Ref, error) { return r.JoinPath("app") },
+			expect: "ocidir:///tmp/oci/app",
+		},
+		{
+			name:    "join invalid",
+			ref:     "registry.example.org",
+			host:    true,
+			fn:      func(r Ref) (Ref, error) { return r.JoinPath("app:v1") },
+			expectE: errs.ErrInvalidReference,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var r Ref
+			var err error
+			if tc.host {
+				r, err = NewHost(tc.ref)
+			} else {
+				r, err = New(tc.ref)
+			}
+			if err != nil {
+				t.Fatalf("failed to parse %s: %v", tc.ref, err)
+			}
+			r, err = tc.fn(r)
+			if tc.expectE != nil {
+				if !errors.Is(err, tc.expectE) {
+					t.Errorf("expected error %v, received %v", tc.expectE, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if r.CommonName() != tc.expect || r.Reference != tc.expect {
+				t.Errorf("ref mismatch, expected %s, received %s, reference %s", tc.expect, r.CommonName(), r.Reference)
+			}
+		})
+	}
+}
+
 func TestToReg(t *testing.T) {
 	t.Parallel()
 	tt := []struct {
@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"slices"
 	"sort"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/opencontainers/go-digest"
@@ -26,6 +27,9 @@ type ReferrerList struct {
 	Annotations map[string]string       `json:"annotations,omitempty"` // annotations extracted from Index
 	Manifest    manifest.Manifest       `json:"-"`                     // returned OCI Index
 	Tags        []string                `json:"-"`                     // tags matched when fetching referrers
+	// Referrers contains the referrers of each descriptor, keyed by the descriptor digest.
+	// This is only populated when a depth is requested, and descriptors without referrers are not included.
+	Referrers map[digest.Digest]ReferrerList `json:"referrers,omitempty"`
 }
 
 // Add appends an entry to rl.Manifest, used to modify the client managed Index
@@ -120,15 +124,9 @@ func (rl ReferrerList) MarshalPretty() ([]byte, error) {
 	}
 	fmt.Fprintf(tw, "\t\n")
 	fmt.Fprintf(tw, "Referrers:\t\n")
-	for _, d := range rl.Descriptors {
-		fmt.Fprintf(tw, "\t\n")
-		if rRef.IsSet() {
-			fmt.Fprintf(tw, "  Name:\t%s\n", rRef.SetDigest(d.Digest.String()).CommonName())
-		}
-		err := d.MarshalPrettyTW(tw, "  ")
-		if err != nil {
-			return []byte{}, err
-		}
+	err := rl.marshalPrettyDescriptors(tw, rRef, "  ")
+	if err != nil {
+		return []byte{}, err
 	}
 	if len(rl.Annotations) > 0 {
 		fmt.Fprintf(tw, "Annotations:\t\n")
@@ -142,10 +140,32 @@ func (rl ReferrerList) MarshalPretty() ([]byte, error) {
 			fmt.Fprintf(tw, "  %s:\t%s\n", name, val)
 		}
 	}
-	err := tw.Flush()
+	err = tw.Flush()
 	return buf.Bytes(), err
 }
 
+// marshalPrettyDescriptors outputs each descriptor followed by any nested referrers with an increased indent.
+func (rl ReferrerList) marshalPrettyDescriptors(tw *tabwriter.Writer, rRef ref.Ref, prefix string) error {
+	for _, d := range rl.Descriptors {
+		fmt.Fprintf(tw, "\t\n")
+		if rRef.IsSet() {
+			fmt.Fprintf(tw, "%sName:\t%s\n", prefix, rRef.SetDigest(d.Digest.String()).CommonName())
+		}
+		err := d.MarshalPrettyTW(tw, prefix)
+		if err != nil {
+			return err
+		}
+		if rlNested, ok := rl.Referrers[d.Digest]; ok {
+			fmt.Fprintf(tw, "%sReferrers:\t\n", prefix)
+			err = rlNested.marshalPrettyDescriptors(tw, rRef, prefix+"    ")
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // FallbackTag returns the ref that should be used when the registry does not support the referrers API
 func FallbackTag(r ref.Ref) (ref.Ref, error) {
 	dig, err := digest.Parse(r.Digest)
@@ -158,3 +178,21 @@ func FallbackTag(r ref.Ref) (ref.Ref, error) {
 	rOut := r.SetTag(fmt.Sprintf("%.32s-%.64s", algo, hash))
 	return rOut, nil
 }
+
+// DigestTag returns a tag for an artifact of the subject digest, like the "sha256-<hex>.sig" tags created by cosign.
+// The suffix is appended to the "<algorithm>-<hex>" prefix.
+func DigestTag(dig digest.Digest, suffix string) string {
+	return fmt.Sprintf("%s-%s%s", dig.Algorithm(), dig.Encoded(), suffix)
+}
+
+// DigestTagMatch returns the suffix of a digest tag when the tag is for the subject digest.
+// The fallback tag of the subject, which may truncate the digest, matches with an empty suffix.
+func DigestTagMatch(dig digest.Digest, tag string) (string, bool) {
+	if suffix, ok := strings.CutPrefix(tag, fmt.Sprintf("%s-%s", dig.Algorithm(), dig.Encoded())); ok {
+		return suffix, true
+	}
+	if tag == fmt.Sprintf("%.32s-%.64s", dig.Algorithm(), dig.Encoded()) {
+		return "", true
+	}
+	return "", false
+}
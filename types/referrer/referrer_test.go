@@ -440,6 +440,75 @@ func TestFallback(t *testing.T) {
 	}
 }
 
+func TestDigestTag(t *testing.T) {
+	t.Parallel()
+	dig256 := digest.SHA256.FromString("test")
+	dig512 := digest.SHA512.FromString("test")
+	fb256 := fmt.Sprintf("%s-%s", dig256.Algorithm(), dig256.Hex())
+	tt := []struct {
+		name         string
+		dig          digest.Digest
+		tag          string
+		expectMatch  bool
+		expectSuffix string
+	}{
+		{
+			name:         "sig",
+			dig:          dig256,
+			tag:          fb256 + ".sig",
+			expectMatch:  true,
+			expectSuffix: ".sig",
+		},
+		{
+			name:         "sbom",
+			dig:          dig256,
+			tag:          fb256 + ".sbom",
+			expectMatch:  true,
+			expectSuffix: ".sbom",
+		},
+		{
+			name:        "fallback",
+			dig:         dig256,
+			tag:         fb256,
+			expectMatch: true,
+		},
+		{
+			name:        "truncated fallback",
+			dig:         dig512,
+			tag:         fmt.Sprintf("%s-%.64s", dig512.Algorithm(), dig512.Hex()),
+			expectMatch: true,
+		},
+		{
+			name:         "sha512 att",
+			dig:          dig512,
+			tag:          fmt.Sprintf("%s-%s.att", dig512.Algorithm(), dig512.Hex()),
+			expectMatch:  true,
+			expectSuffix: ".att",
+		},
+		{
+			name: "other digest",
+			dig:  digest.SHA256.FromString("other"),
+			tag:  fb256 + ".sig",
+		},
+		{
+			name: "not a digest tag",
+			dig:  dig256,
+			tag:  "latest",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			suffix, ok := DigestTagMatch(tc.dig, tc.tag)
+			if ok != tc.expectMatch || suffix != tc.expectSuffix {
+				t.Fatalf("unexpected match, expected %t %q, received %t %q", tc.expectMatch, tc.expectSuffix, ok, suffix)
+			}
+			if ok && tc.tag != fmt.Sprintf("%.32s-%.64s", tc.dig.Algorithm(), tc.dig.Encoded()) && DigestTag(tc.dig, suffix) != tc.tag {
+				t.Errorf("unexpected digest tag, expected %s, received %s", tc.tag, DigestTag(tc.dig, suffix))
+			}
+		})
+	}
+}
+
 func TestMarshal(t *testing.T) {
 	t.Parallel()
 	rl := &ReferrerList{
@@ -496,4 +565,26 @@ func TestMarshal(t *testing.T) {
 	if !strings.Contains(out, "Annotations:") {
 		t.Errorf("empty response is missing an annotations line: %s", out)
 	}
+	if strings.Count(out, "Referrers:") != 1 {
+		t.Errorf("response without nested referrers has multiple referrers lines: %s", out)
+	}
+
+	// nested referrers are included under their descriptor
+	rl.Referrers = map[digest.Digest]ReferrerList{
+		dOCIImg.Digest: {
+			Subject:     rSubj.SetDigest(dOCIImg.Digest.String()),
+			Descriptors: []descriptor.Descriptor{dOCIImgAT},
+		},
+	}
+	outB, err = rl.MarshalPretty()
+	if err != nil {
+		t.Fatalf("failed to marshal nested referrer list: %v", err)
+	}
+	out = string(outB)
+	if strings.Count(out, "Referrers:") != 2 {
+		t.Errorf("nested response is missing a referrers line: %s", out)
+	}
+	if strings.Count(out, "Digest:") != 3 {
+		t.Errorf("nested response is missing a nested descriptor: %s", out)
+	}
 }
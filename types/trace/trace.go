@@ -0,0 +1,59 @@
+// Package trace defines the interfaces used to trace regclient operations.
+//
+// These interfaces avoid a direct dependency on a tracing library.
+// An OpenTelemetry TracerProvider can be used with the adapter in the
+// github.com/regclient/regclient/pkg/traceotel package.
+package trace
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// TracerProvider returns a named [Tracer].
+type TracerProvider interface {
+	Tracer(name string) Tracer
+}
+
+// Tracer creates spans.
+type Tracer interface {
+	// Start creates a span and a context containing the span.
+	// The returned context should be used for any requests made within the span.
+	Start(ctx context.Context, spanName string, attrs ...slog.Attr) (context.Context, Span)
+}
+
+// Injector is optionally implemented by a [TracerProvider] to propagate the trace context in HTTP requests.
+type Injector interface {
+	// Inject adds the trace context from ctx to the request headers.
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span tracks a single operation.
+type Span interface {
+	// RecordError is called when the operation fails.
+	RecordError(err error)
+	// End completes the span.
+	End()
+}
+
+// Start creates a span using tracer, or returns a no-op span when tracer is nil.
+func Start(ctx context.Context, tracer Tracer, spanName string, attrs ...slog.Attr) (context.Context, Span) {
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return tracer.Start(ctx, spanName, attrs...)
+}
+
+// End records a non-nil error and ends the span.
+func End(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+type noopSpan struct{}
+
+func (noopSpan) RecordError(err error) {}
+func (noopSpan) End()                  {}